@@ -0,0 +1,193 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/airplay"
+	"github.com/ericdaugherty/itunesexport-go/pkg/export"
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+const AirPlayUsageMessage = `usage: %v airplay [<flags>] <playlist name>
+
+Plays the playlist with the given name on an AirPlay receiver such as an Apple TV, streaming
+the music files from this computer, until the playlist ends or is interrupted. Receivers
+that only play audio streams, such as the AirPort Express or HomePod, are not supported.
+
+Flags:
+    -library <file path>        Path to iTunes Music Library XML File.
+    -device <name>              Name or host[:port] of the receiver. Defaults to the only receiver on the network.
+    -list                       List the receivers on the network instead of playing.
+    -address <address>          Address the music files are streamed from. Defaults to :0, a free port.
+    -musicPath <dir path>       Music Folder on this machine, if the library was copied from another one.
+    -musicPathOrig <dir path>   Music Folder of the library replaced by -musicPath. Defaults to the Music Folder of the library.
+`
+
+// airplayDiscoveryTimeout is how long the airplay command waits for receivers to answer.
+const airplayDiscoveryTimeout = 2 * time.Second
+
+// runAirPlay runs the airplay command with the given arguments. It returns false if the
+// playlist could not be played.
+func runAirPlay(args []string) bool {
+	var (
+		libraryPath   string
+		deviceName    string
+		list          bool
+		address       string
+		musicPath     string
+		musicPathOrig string
+	)
+
+	flags := flag.NewFlagSet("airplay", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	flags.StringVar(&libraryPath, "library", "", "")
+	flags.StringVar(&deviceName, "device", "", "")
+	flags.BoolVar(&list, "list", false, "")
+	flags.StringVar(&address, "address", ":0", "")
+	flags.StringVar(&musicPath, "musicPath", "", "")
+	flags.StringVar(&musicPathOrig, "musicPathOrig", "", "")
+
+	err := flags.Parse(args)
+	if err == nil && !list && flags.NArg() != 1 {
+		err = fmt.Errorf("exactly one playlist name is required")
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, AirPlayUsageMessage, "itunesexport")
+		fmt.Fprintf(os.Stderr, UsageErrorMessage, err)
+		return false
+	}
+
+	ctx, cancel := exportContext()
+	defer cancel()
+
+	var devices []airplay.Device
+	if list || !strings.Contains(deviceName, ":") {
+		devices, err = airplay.Discover(ctx, airplayDiscoveryTimeout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to find AirPlay receivers: %v\n", err)
+			return false
+		}
+	}
+	if list {
+		for _, device := range devices {
+			fmt.Printf("%v (%v)\n", device.Name, device.Address)
+		}
+		return true
+	}
+	device, err := selectAirPlayDevice(devices, deviceName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return false
+	}
+
+	if libraryPath == "" {
+		libraryPath, err = defaultLibraryPath()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return false
+		}
+	}
+	library, err := itunes.LoadLibraryContext(ctx, filepath.Clean(libraryPath))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return false
+	}
+	playlist, ok := library.PlaylistMap[flags.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unable to find matching playlist for name: %q\n", flags.Arg(0))
+		return false
+	}
+
+	s := &server{library: library}
+	if musicPath != "" {
+		if musicPathOrig == "" {
+			musicPathOrig = itunes.LocationPath(library.MusicFolder)
+		}
+		s.defaults = export.ExportSettings{NewMusicPath: musicPath, OriginalMusicPath: musicPathOrig}
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to stream the music files: %v\n", err)
+		return false
+	}
+	defer listener.Close()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/audio/", s.handleAudio)
+	go http.Serve(listener, mux)
+
+	ip, err := localIP(device.Address)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to reach the receiver: %v\n", err)
+		return false
+	}
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	base := "http://" + net.JoinHostPort(ip, port)
+
+	tracks := airplayTracks(playlist.Tracks(library))
+	fmt.Printf("Playing %v tracks of %v on %v. Press Ctrl-C to stop.\n", len(tracks), playlist.Name, device.Name)
+	player := airplay.NewPlayer(device.Address)
+	for i, track := range tracks {
+		url := track.Location
+		if track.TrackType != "URL" {
+			url = base + audioPath(&track)
+		}
+		fmt.Printf("%v/%v: %v - %v\n", i+1, len(tracks), track.Artist, track.Title())
+		err := player.Play(ctx, url)
+		if err == nil {
+			err = player.Wait(ctx)
+		}
+		if ctx.Err() != nil {
+			fmt.Println("Stopped.")
+			return true
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error playing on %v: %v\n", device.Name, err)
+			return false
+		}
+	}
+	return true
+}
+
+// selectAirPlayDevice returns the receiver with the given name or host[:port], or the only
+// receiver if name is empty.
+func selectAirPlayDevice(devices []airplay.Device, name string) (airplay.Device, error) {
+	if strings.Contains(name, ":") {
+		return airplay.Device{Name: name, Address: name}, nil
+	}
+	if name == "" && len(devices) == 1 {
+		return devices[0], nil
+	}
+	var names []string
+	for _, device := range devices {
+		if name != "" && strings.EqualFold(device.Name, name) {
+			return device, nil
+		}
+		names = append(names, device.Name)
+	}
+	switch {
+	case len(devices) == 0:
+		return airplay.Device{}, fmt.Errorf("No AirPlay receivers found on the network")
+	case name == "":
+		return airplay.Device{}, fmt.Errorf("Select the AirPlay receiver with -device: %v", strings.Join(names, ", "))
+	}
+	return airplay.Device{}, fmt.Errorf("Unknown AirPlay receiver: %v. Receivers found: %v", name, strings.Join(names, ", "))
+}
+
+// airplayTracks returns the tracks of a playlist that can be played.
+func airplayTracks(tracks []itunes.Track) []itunes.Track {
+	var playable []itunes.Track
+	for _, track := range tracks {
+		if track.Location != "" {
+			playable = append(playable, track)
+		}
+	}
+	return playable
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/airplay"
+)
+
+func TestSelectAirPlayDevice(t *testing.T) {
+	devices := []airplay.Device{{Name: "Bedroom", Address: "192.168.1.30:7000"}, {Name: "Living Room", Address: "192.168.1.31:7000"}}
+
+	device, err := selectAirPlayDevice(devices, "living room")
+	if err != nil || device.Address != "192.168.1.31:7000" {
+		t.Fatalf("unexpected device %v: %v", device, err)
+	}
+	device, err = selectAirPlayDevice(nil, "192.168.1.40:7000")
+	if err != nil || device.Address != "192.168.1.40:7000" {
+		t.Fatalf("unexpected device %v: %v", device, err)
+	}
+	device, err = selectAirPlayDevice(devices[:1], "")
+	if err != nil || device.Name != "Bedroom" {
+		t.Fatalf("unexpected device %v: %v", device, err)
+	}
+	if _, err := selectAirPlayDevice(devices, ""); err == nil {
+		t.Fatal("expected an error selecting one of several receivers without a name")
+	}
+	if _, err := selectAirPlayDevice(devices, "Kitchen"); err == nil {
+		t.Fatal("expected an error for an unknown receiver")
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// apiPlaylist is the JSON representation of a playlist returned by the API.
+type apiPlaylist struct {
+	Id         string `json:"id"`
+	Name       string `json:"name"`
+	ParentId   string `json:"parentId,omitempty"`
+	Folder     bool   `json:"folder"`
+	Builtin    bool   `json:"builtin"`
+	TrackCount int    `json:"trackCount"`
+}
+
+// apiTrack is the JSON representation of a track returned by the API.
+type apiTrack struct {
+	Id           int    `json:"id"`
+	PersistentId string `json:"persistentId"`
+	Name         string `json:"name"`
+	Artist       string `json:"artist"`
+	AlbumArtist  string `json:"albumArtist,omitempty"`
+	Album        string `json:"album"`
+	Genre        string `json:"genre,omitempty"`
+	TrackNumber  int    `json:"trackNumber,omitempty"`
+	DiscNumber   int    `json:"discNumber,omitempty"`
+	Year         int    `json:"year,omitempty"`
+	TotalTime    int    `json:"totalTime"`
+	Location     string `json:"location"`
+}
+
+// apiExportRequest is the body of POST /api/exports.
+type apiExportRequest struct {
+	Playlists      []string `json:"playlists"`
+	Output         string   `json:"output"`
+	Type           string   `json:"type"`
+	Copy           string   `json:"copy"`
+	IncludeFolders bool     `json:"includeFolders"`
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// handleApiPlaylists serves GET /api/playlists.
+func (s *server) handleApiPlaylists(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeApiError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	playlists := make([]apiPlaylist, 0, len(s.library.Playlists))
+	for _, playlist := range s.library.Playlists {
+		playlists = append(playlists, apiPlaylist{
+			Id:         playlist.PlaylistPersistentId,
+			Name:       playlist.Name,
+			ParentId:   playlist.ParentPersistentId,
+			Folder:     playlist.Folder,
+			Builtin:    playlist.DistinguishedKind != 0 || playlist.Master,
+			TrackCount: len(playlist.PlaylistItems),
+		})
+	}
+	writeApiResponse(w, http.StatusOK, playlists)
+}
+
+// handleApiPlaylistTracks serves GET /api/playlists/<id>/tracks.
+func (s *server) handleApiPlaylistTracks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeApiError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/playlists/")
+	if !strings.HasSuffix(path, "/tracks") {
+		writeApiError(w, http.StatusNotFound, "not found")
+		return
+	}
+	playlist, ok := s.library.PlaylistIdMap[strings.TrimSuffix(path, "/tracks")]
+	if !ok {
+		writeApiError(w, http.StatusNotFound, "unknown playlist")
+		return
+	}
+
+	tracks := []apiTrack{}
+	for _, track := range playlist.Tracks(s.library) {
+		tracks = append(tracks, apiTrack{
+			Id:           track.TrackId,
+			PersistentId: track.PersistentId,
+			Name:         track.Name,
+			Artist:       track.Artist,
+			AlbumArtist:  track.AlbumArtist,
+			Album:        track.Album,
+			Genre:        track.Genre,
+			TrackNumber:  track.TrackNumber,
+			DiscNumber:   track.DiscNumber,
+			Year:         track.Year,
+			TotalTime:    track.TotalTime,
+			Location:     track.Location,
+		})
+	}
+	writeApiResponse(w, http.StatusOK, tracks)
+}
+
+// handleApiExports serves GET /api/exports (list jobs) and POST /api/exports (start a job).
+func (s *server) handleApiExports(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeApiResponse(w, http.StatusOK, s.jobSnapshots())
+	case http.MethodPost:
+		var request apiExportRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeApiError(w, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+			return
+		}
+		if request.Type == "" {
+			request.Type = "M3U"
+		}
+		if request.Copy == "" {
+			request.Copy = "NONE"
+		}
+
+		job, err := s.startExport(request.Playlists, request.Output, request.Type, request.Copy, request.IncludeFolders)
+		if err != nil {
+			writeApiError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		snapshot, _ := s.jobSnapshot(job.ID)
+		w.Header().Set("Location", fmt.Sprintf("/api/exports/%v", job.ID))
+		writeApiResponse(w, http.StatusAccepted, snapshot)
+	default:
+		writeApiError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handleApiExport serves GET /api/exports/<id>.
+func (s *server) handleApiExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeApiError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/exports/"))
+	if err != nil {
+		writeApiError(w, http.StatusNotFound, "unknown export")
+		return
+	}
+	job, ok := s.jobSnapshot(id)
+	if !ok {
+		writeApiError(w, http.StatusNotFound, "unknown export")
+		return
+	}
+	writeApiResponse(w, http.StatusOK, job)
+}
+
+func writeApiResponse(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		fmt.Printf("Error writing API response: %v\n", err)
+	}
+}
+
+func writeApiError(w http.ResponseWriter, status int, message string) {
+	writeApiResponse(w, status, apiError{Error: message})
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApiPlaylistsAndTracks(t *testing.T) {
+	s := &server{library: testServerLibrary()}
+	handler := s.handler()
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/api/playlists", nil))
+	var playlists []apiPlaylist
+	if err := json.Unmarshal(recorder.Body.Bytes(), &playlists); err != nil {
+		t.Fatal(err)
+	}
+	if len(playlists) != 1 || playlists[0].Id != "BA9D3C2EAB361B84" || playlists[0].TrackCount != 1 {
+		t.Fatalf("unexpected playlists: %+v", playlists)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/api/playlists/BA9D3C2EAB361B84/tracks", nil))
+	var tracks []apiTrack
+	if err := json.Unmarshal(recorder.Body.Bytes(), &tracks); err != nil {
+		t.Fatal(err)
+	}
+	if len(tracks) != 1 || tracks[0].Name != "Some Song" {
+		t.Fatalf("unexpected tracks: %+v", tracks)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/api/playlists/UNKNOWN/tracks", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %v", recorder.Code)
+	}
+}
+
+func TestApiExport(t *testing.T) {
+	outputDir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(outputDir)
+
+	s := &server{library: testServerLibrary()}
+	handler := s.handler()
+
+	body := `{"playlists": ["BA9D3C2EAB361B84"], "output": ` + jsonQuote(outputDir) + `}`
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("POST", "/api/exports", strings.NewReader(body)))
+	if recorder.Code != http.StatusAccepted || recorder.Header().Get("Location") != "/api/exports/1" {
+		t.Fatalf("unexpected response: %v %v", recorder.Code, recorder.Body.String())
+	}
+
+	var job exportJob
+	deadline := time.Now().Add(5 * time.Second)
+	for job.Status != JobFinished {
+		if job.Status == JobFailed || time.Now().After(deadline) {
+			t.Fatalf("export did not finish: %+v", job)
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		recorder = httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/api/exports/1", nil))
+		if err := json.Unmarshal(recorder.Body.Bytes(), &job); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	assertPathExists(t, filepath.Join(outputDir, "My Playlist.m3u"))
+}
+
+func jsonQuote(s string) string {
+	quoted, _ := json.Marshal(s)
+	return string(quoted)
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+const CheckUsageMessage = `usage: %v check [-library <file path>] [-format <CSV|JSON>] [-report <file path>] [-ignore <file path>]
+
+Reports tracks of the library whose file is missing, empty or stored on another volume
+than the Music Folder of the library. "doctor" is an alias of check.
+
+Flags:
+    -library <file path>        Path to iTunes Music Library XML File.
+    -format <CSV|JSON>          Format of the report. Defaults to CSV.
+    -report <file path>         File the report is written to. Defaults to standard output.
+    -ignore <file path>         File listing tracks known to be broken, which are not reported, like -ignore of exports.
+`
+
+// runCheck runs the check command with the given arguments. It returns false if the
+// library could not be checked or problems were found.
+func runCheck(args []string) bool {
+	var (
+		libraryPath string
+		format      string
+		reportPath  string
+		ignoreFile  string
+	)
+
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	flags.StringVar(&libraryPath, "library", "", "")
+	flags.StringVar(&format, "format", "CSV", "")
+	flags.StringVar(&reportPath, "report", "", "")
+	flags.StringVar(&ignoreFile, "ignore", "", "")
+
+	err := flags.Parse(args)
+	format = strings.ToUpper(format)
+	if err == nil && format != "CSV" && format != "JSON" {
+		err = fmt.Errorf("Unknown report format: %v", format)
+	}
+	if err == nil && flags.NArg() > 0 {
+		err = fmt.Errorf("Unexpected paramter %v", flags.Arg(0))
+	}
+	var ignore *itunes.IgnoreList
+	if err == nil && ignoreFile != "" {
+		ignore, err = itunes.LoadIgnoreList(ignoreFile)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, CheckUsageMessage, "itunesexport")
+		fmt.Fprintf(os.Stderr, UsageErrorMessage, err)
+		return false
+	}
+
+	if libraryPath == "" {
+		libraryPath, err = defaultLibraryPath()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return false
+		}
+	}
+
+	ctx, cancel := exportContext()
+	defer cancel()
+
+	library, err := itunes.LoadLibraryContext(ctx, filepath.Clean(libraryPath))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return false
+	}
+
+	problems, err := itunes.CheckTracks(ctx, library)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error checking library: %v\n", err)
+		return false
+	}
+	if ignore != nil {
+		problems = reportedProblems(library, problems, ignore)
+	}
+
+	var report io.Writer = os.Stdout
+	if reportPath != "" {
+		file, err := os.Create(reportPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return false
+		}
+		defer file.Close()
+		report = file
+	}
+
+	if format == "JSON" {
+		err = writeCheckJSON(report, problems)
+	} else {
+		err = writeCheckCSV(report, problems)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing report: %v\n", err)
+		return false
+	}
+
+	fmt.Fprintf(os.Stderr, "Checked %v tracks, %v problems found.\n", len(library.Tracks), len(problems))
+	return len(problems) == 0
+}
+
+// reportedProblems returns the problems of the tracks not on the ignore list.
+func reportedProblems(library *itunes.Library, problems []itunes.TrackProblem, ignore *itunes.IgnoreList) []itunes.TrackProblem {
+	var reported []itunes.TrackProblem
+	for _, problem := range problems {
+		if !ignore.Ignores(library.Tracks[strconv.Itoa(problem.TrackId)]) {
+			reported = append(reported, problem)
+		}
+	}
+	return reported
+}
+
+func writeCheckCSV(w io.Writer, problems []itunes.TrackProblem) error {
+	writer := csv.NewWriter(w)
+	writer.Write(itunes.TrackProblemFields)
+	for _, problem := range problems {
+		writer.Write(problem.Fields())
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func writeCheckJSON(w io.Writer, problems []itunes.TrackProblem) error {
+	if problems == nil {
+		problems = []itunes.TrackProblem{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(problems)
+}
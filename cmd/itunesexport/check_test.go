@@ -0,0 +1,42 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	outputDir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(outputDir)
+
+	musicFile, _ := prepareMusicFile(t)
+	defer os.Remove(musicFile)
+
+	itunesDbFile := prepareItunesDbFile(t, filepath.ToSlash(musicFile))
+	defer os.Remove(itunesDbFile)
+
+	reportFile := filepath.Join(outputDir, "report.csv")
+	if !runCheck([]string{"-library", itunesDbFile, "-report", reportFile}) {
+		t.Fatal("expected library without problems")
+	}
+
+	os.Remove(musicFile)
+	if runCheck([]string{"-library", itunesDbFile, "-report", reportFile}) {
+		t.Fatal("expected missing track to be reported")
+	}
+	report, err := ioutil.ReadFile(reportFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(report)), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[1], ","+musicFile+",missing") {
+		t.Fatalf("unexpected report %q", report)
+	}
+
+	if runCheck([]string{"-format", "XML"}) {
+		t.Fatal("expected unknown format to be rejected")
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/export"
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+// config is the JSON configuration file given with -config.
+type config struct {
+	// Playlists are the settings of single playlists by their name.
+	Playlists map[string]playlistConfig `json:"playlists"`
+	// Replace are the replacements of text in the names of the copied files and playlists,
+	// e.g. ":" by " -".
+	Replace map[string]string `json:"replace"`
+}
+
+// playlistConfig are the settings of a single playlist in the configuration file.
+type playlistConfig struct {
+	// Dir is the directory of the output the playlist and its music files are written to,
+	// "/" for the root.
+	Dir  string `json:"dir"`
+	Type string `json:"type"`
+	Copy string `json:"copy"`
+	// Audiobooks is how the audiobooks of the playlist are exported: EXCLUDE, INCLUDE or ONLY.
+	Audiobooks string `json:"audiobooks"`
+	// Videos is how the videos of the playlist are exported: EXCLUDE, INCLUDE or ONLY.
+	Videos string `json:"videos"`
+	// Profiles, if set, are the only profiles the playlist is exported with.
+	Profiles []string `json:"profiles"`
+}
+
+// loadConfig reads the configuration file at name.
+func loadConfig(name string) (*config, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var config config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("unable to read config file %v: %v", name, err)
+	}
+	for name, playlist := range config.Playlists {
+		if err := playlist.settings().Validate(); err != nil {
+			return nil, fmt.Errorf("invalid settings of playlist %q in config file: %v", name, err)
+		}
+	}
+	if _, ok := config.Replace[""]; ok {
+		return nil, fmt.Errorf("invalid replacement in config file: the text to replace is empty")
+	}
+	return &config, nil
+}
+
+func (playlist playlistConfig) settings() export.PlaylistSettings {
+	return export.PlaylistSettings{Dir: playlist.Dir, ExportType: playlist.Type, CopyType: playlist.Copy, Audiobooks: playlist.Audiobooks, Videos: playlist.Videos}
+}
+
+// playlistSettings returns the export settings of the playlists configured.
+func (config *config) playlistSettings() map[string]export.PlaylistSettings {
+	settings := make(map[string]export.PlaylistSettings)
+	for name, playlist := range config.Playlists {
+		settings[name] = playlist.settings()
+	}
+	return settings
+}
+
+// profilePlaylists returns playlists without the playlists configured for other profiles
+// than profile.
+func (config *config) profilePlaylists(playlists []itunes.Playlist, profile string) []itunes.Playlist {
+	var included []itunes.Playlist
+	for _, playlist := range playlists {
+		profiles := config.Playlists[playlist.Name].Profiles
+		if len(profiles) > 0 && !containsFold(profiles, profile) {
+			export.Log(export.LogEntry{Level: export.LOG_INFO, Message: fmt.Sprintf("Skipping Playlist %v, it is only exported with -profile %v.\n", playlist.Name, strings.Join(profiles, ", ")), Playlist: playlist.Name})
+			continue
+		}
+		included = append(included, playlist)
+	}
+	return included
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
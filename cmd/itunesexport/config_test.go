@@ -0,0 +1,56 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+func TestConfigPlaylists(t *testing.T) {
+	dir, err := ioutil.TempDir("", "itunesexport-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "config.json")
+	content := `{"playlists": {"Road Trip": {"dir": "/", "copy": "FLAT", "profiles": ["Kenwood"]}}, "replace": {":": " -"}}`
+	if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := loadConfig(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings := config.playlistSettings()["Road Trip"]; settings.Dir != "/" || settings.CopyType != "FLAT" {
+		t.Fatalf("unexpected playlist settings %+v", settings)
+	}
+	if config.Replace[":"] != " -" {
+		t.Fatalf("unexpected replacements %v", config.Replace)
+	}
+
+	playlists := []itunes.Playlist{{Name: "Road Trip"}, {Name: "Jazz"}}
+	if included := config.profilePlaylists(playlists, "kenwood"); len(included) != 2 {
+		t.Fatalf("expected both playlists with the profile, got %v", included)
+	}
+	if included := config.profilePlaylists(playlists, ""); len(included) != 1 || included[0].Name != "Jazz" {
+		t.Fatalf("expected only Jazz without the profile, got %v", included)
+	}
+
+	if err := ioutil.WriteFile(name, []byte(`{"playlists": {"Jazz": {"type": "MP3"}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadConfig(name); err == nil {
+		t.Fatal("expected error for unknown playlist type")
+	}
+
+	if err := ioutil.WriteFile(name, []byte(`{"replace": {"": "_"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadConfig(name); err == nil {
+		t.Fatal("expected error for replacing an empty text")
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/daap"
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+// daapDiscoveryTimeout is how long shares announced on the network are waited for.
+const daapDiscoveryTimeout = 2 * time.Second
+
+// loadDAAPLibrary reads the library from the DAAP share with the name announced on the
+// network, or at the address host[:port].
+func loadDAAPLibrary(ctx context.Context, share string) (*itunes.Library, error) {
+	password := daapPassword
+	if password == "" {
+		password = os.Getenv("DAAP_PASSWORD")
+	}
+
+	shares, err := daap.Discover(ctx, daapDiscoveryTimeout)
+	if err != nil {
+		fmt.Printf("Unable to discover DAAP shares: %v\n", err)
+	}
+	address := share
+	for _, discovered := range shares {
+		if strings.EqualFold(discovered.Name, share) {
+			address = discovered.Address
+			break
+		}
+	}
+
+	client, err := daap.Open(ctx, address, password)
+	if err != nil {
+		if len(shares) > 0 {
+			fmt.Println("DAAP shares found on the network:")
+			for _, discovered := range shares {
+				fmt.Printf("    %v (%v)\n", discovered.Name, discovered.Address)
+			}
+		}
+		return nil, err
+	}
+	defer client.Close()
+	return client.LoadLibrary(ctx)
+}
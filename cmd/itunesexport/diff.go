@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+const DiffUsageMessage = `usage: %v diff [-format <TEXT|JSON>] <old library file> <new library file>
+
+Reports the playlists added, removed and renamed between two iTunes Music Library XML
+files and the tracks added to and removed from each playlist. Exits with status 1 if
+the libraries differ.
+
+Flags:
+    -format <TEXT|JSON>         Format of the report. Defaults to TEXT.
+`
+
+// runDiff runs the diff command with the given arguments. It returns false if the
+// libraries could not be compared or differ.
+func runDiff(args []string) bool {
+	var format string
+
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	flags.StringVar(&format, "format", "TEXT", "")
+
+	err := flags.Parse(args)
+	format = strings.ToUpper(format)
+	if err == nil && format != "TEXT" && format != "JSON" {
+		err = fmt.Errorf("Unknown report format: %v", format)
+	}
+	if err == nil && flags.NArg() != 2 {
+		err = fmt.Errorf("exactly two library files are required")
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, DiffUsageMessage, "itunesexport")
+		fmt.Fprintf(os.Stderr, UsageErrorMessage, err)
+		return false
+	}
+
+	ctx, cancel := exportContext()
+	defer cancel()
+
+	var libraries [2]*itunes.Library
+	for i, libraryPath := range flags.Args() {
+		libraries[i], err = itunes.LoadLibraryContext(ctx, filepath.Clean(libraryPath))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return false
+		}
+	}
+
+	diff := itunes.DiffLibraries(libraries[0], libraries[1])
+	if format == "JSON" {
+		err = writeDiffJSON(os.Stdout, diff)
+	} else {
+		err = writeDiffText(os.Stdout, diff)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing report: %v\n", err)
+		return false
+	}
+	return diff.Empty()
+}
+
+func writeDiffText(w io.Writer, diff itunes.LibraryDiff) error {
+	var out strings.Builder
+	for _, playlist := range diff.AddedPlaylists {
+		fmt.Fprintf(&out, "Added playlist %v (%v tracks)\n", playlist.Name, len(playlist.AddedTracks))
+	}
+	for _, playlist := range diff.RemovedPlaylists {
+		fmt.Fprintf(&out, "Removed playlist %v (%v tracks)\n", playlist.Name, len(playlist.RemovedTracks))
+	}
+	for _, playlist := range diff.RenamedPlaylists {
+		fmt.Fprintf(&out, "Renamed playlist %v to %v\n", playlist.OldName, playlist.Name)
+	}
+	for _, playlists := range [][]itunes.PlaylistDiff{diff.RenamedPlaylists, diff.ChangedPlaylists} {
+		for _, playlist := range playlists {
+			if len(playlist.AddedTracks) == 0 && len(playlist.RemovedTracks) == 0 {
+				continue
+			}
+			fmt.Fprintf(&out, "Changed playlist %v:\n", playlist.Name)
+			for _, track := range playlist.AddedTracks {
+				fmt.Fprintf(&out, "  + %v - %v\n", track.Artist, track.Name)
+			}
+			for _, track := range playlist.RemovedTracks {
+				fmt.Fprintf(&out, "  - %v - %v\n", track.Artist, track.Name)
+			}
+		}
+	}
+	if diff.Empty() {
+		out.WriteString("No differences found.\n")
+	}
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+func writeDiffJSON(w io.Writer, diff itunes.LibraryDiff) error {
+	for _, playlists := range []*[]itunes.PlaylistDiff{&diff.AddedPlaylists, &diff.RemovedPlaylists, &diff.RenamedPlaylists, &diff.ChangedPlaylists} {
+		if *playlists == nil {
+			*playlists = []itunes.PlaylistDiff{}
+		}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(diff)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+func TestWriteDiffText(t *testing.T) {
+	diff := itunes.LibraryDiff{
+		AddedPlaylists:   []itunes.PlaylistDiff{{Name: "Road Trip", AddedTracks: []itunes.DiffTrack{{Name: "One", Artist: "U2"}}}},
+		RenamedPlaylists: []itunes.PlaylistDiff{{Name: "Chill Out", OldName: "Chill"}},
+		ChangedPlaylists: []itunes.PlaylistDiff{{Name: "Favorites", RemovedTracks: []itunes.DiffTrack{{Name: "Two", Artist: "Blur"}}}},
+	}
+
+	var out bytes.Buffer
+	if err := writeDiffText(&out, diff); err != nil {
+		t.Fatal(err)
+	}
+	expected := "Added playlist Road Trip (1 tracks)\nRenamed playlist Chill to Chill Out\nChanged playlist Favorites:\n  - Blur - Two\n"
+	if out.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, out.String())
+	}
+
+	out.Reset()
+	if err := writeDiffJSON(&out, itunes.LibraryDiff{}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `"addedPlaylists": []`) {
+		t.Fatalf("expected empty lists in JSON, got %v", out.String())
+	}
+}
+
+func TestDiffSameLibrary(t *testing.T) {
+	musicFile, _ := prepareMusicFile(t)
+	defer os.Remove(musicFile)
+	itunesDbFile := prepareItunesDbFile(t, musicFile)
+	defer os.Remove(itunesDbFile)
+
+	if !runDiff([]string{itunesDbFile, itunesDbFile}) {
+		t.Fatal("expected a library not to differ from itself")
+	}
+	if runDiff([]string{itunesDbFile}) {
+		t.Fatal("expected a single library to be rejected")
+	}
+}
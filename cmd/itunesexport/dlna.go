@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/dlna"
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+const DLNAUsageMessage = `usage: %v serve-dlna [<flags>] [<playlist name>...]
+
+Serves the playlists with the given names, or all playlists which are not built into
+iTunes, as a DLNA media server on the local network until interrupted.
+
+Flags:
+    -library <file path>        Path to iTunes Music Library XML File.
+    -address <address>          Address to serve on. Defaults to :8200.
+    -name <name>                Name of the media server on the network. Defaults to "iTunes".
+    -musicPath <dir path>       Music Folder on this machine, if the library was copied from another one.
+    -musicPathOrig <dir path>   Music Folder of the library replaced by -musicPath. Defaults to the Music Folder of the library.
+`
+
+// runDLNA runs the serve-dlna command with the given arguments. It returns false if the
+// media server could not be started.
+func runDLNA(args []string) bool {
+	var (
+		libraryPath   string
+		address       string
+		name          string
+		musicPath     string
+		musicPathOrig string
+	)
+
+	flags := flag.NewFlagSet("serve-dlna", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	flags.StringVar(&libraryPath, "library", "", "")
+	flags.StringVar(&address, "address", ":8200", "")
+	flags.StringVar(&name, "name", "iTunes", "")
+	flags.StringVar(&musicPath, "musicPath", "", "")
+	flags.StringVar(&musicPathOrig, "musicPathOrig", "", "")
+
+	if err := flags.Parse(args); err != nil {
+		fmt.Fprintf(os.Stderr, DLNAUsageMessage, "itunesexport")
+		fmt.Fprintf(os.Stderr, UsageErrorMessage, err)
+		return false
+	}
+
+	var err error
+	if libraryPath == "" {
+		libraryPath, err = defaultLibraryPath()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return false
+		}
+	}
+
+	ctx, cancel := exportContext()
+	defer cancel()
+
+	library, err := itunes.LoadLibraryContext(ctx, filepath.Clean(libraryPath))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return false
+	}
+
+	var playlists []itunes.Playlist
+	if flags.NArg() == 0 {
+		for _, playlist := range library.Playlists {
+			if playlist.DistinguishedKind == 0 && !playlist.Master && playlist.Name != "Library" {
+				playlists = append(playlists, playlist)
+			}
+		}
+	}
+	for _, playlistName := range flags.Args() {
+		playlist, ok := library.PlaylistMap[playlistName]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Unable to find matching playlist for name: %q\n", playlistName)
+			return false
+		}
+		playlists = append(playlists, playlist)
+	}
+
+	server := dlna.NewServer(name, library, playlists)
+	if musicPath != "" {
+		if musicPathOrig == "" {
+			musicPathOrig = itunes.LocationPath(library.MusicFolder)
+		}
+		server.TrackPath = func(track *itunes.Track) string {
+			return strings.Replace(itunes.LocationPath(track.Location), filepath.FromSlash(musicPathOrig), musicPath, 1)
+		}
+	}
+
+	fmt.Printf("Serving %v playlists as DLNA media server %q on %v. Press Ctrl-C to stop.\n", len(playlists), name, address)
+	if err := server.ListenAndServe(ctx, address); err != nil {
+		fmt.Fprintf(os.Stderr, "Error running DLNA media server: %v\n", err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"sync"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/export"
+)
+
+// exportStats counts the events of an export for the -postHook command.
+type exportStats struct {
+	mutex            sync.Mutex
+	handler          export.EventHandler
+	playlistsWritten int
+	playlistsSkipped int
+	filesCopied      int
+	bytesCopied      int64
+	errors           int
+}
+
+func (stats *exportStats) HandleEvent(event export.Event) {
+	stats.mutex.Lock()
+	switch event.Type {
+	case export.EVENT_PLAYLIST_WRITTEN:
+		stats.playlistsWritten++
+	case export.EVENT_PLAYLIST_SKIPPED:
+		stats.playlistsSkipped++
+	case export.EVENT_FILE_FINISHED:
+		stats.filesCopied++
+		stats.bytesCopied += event.Bytes
+	case export.EVENT_ERROR:
+		stats.errors++
+	}
+	stats.mutex.Unlock()
+
+	if stats.handler != nil {
+		stats.handler.HandleEvent(event)
+	}
+}
+
+// env returns the environment variables describing the finished export to the -postHook
+// command.
+func (stats *exportStats) env(exportErr error) []string {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+
+	errorMessage := ""
+	if exportErr != nil {
+		errorMessage = exportErr.Error()
+	}
+	return []string{
+		"ITUNESEXPORT_SUCCESS=" + strconv.FormatBool(exportErr == nil),
+		"ITUNESEXPORT_ERROR=" + errorMessage,
+		"ITUNESEXPORT_PLAYLISTS_WRITTEN=" + strconv.Itoa(stats.playlistsWritten),
+		"ITUNESEXPORT_PLAYLISTS_SKIPPED=" + strconv.Itoa(stats.playlistsSkipped),
+		"ITUNESEXPORT_FILES_COPIED=" + strconv.Itoa(stats.filesCopied),
+		"ITUNESEXPORT_BYTES_COPIED=" + strconv.FormatInt(stats.bytesCopied, 10),
+		"ITUNESEXPORT_ERRORS=" + strconv.Itoa(stats.errors),
+	}
+}
+
+// hookEnv returns the environment variables describing the export to both hook commands.
+func hookEnv(hook string) []string {
+	return []string{
+		"ITUNESEXPORT_HOOK=" + hook,
+		"ITUNESEXPORT_OUTPUT=" + outputPath,
+		"ITUNESEXPORT_LIBRARY=" + libraryPath,
+		"ITUNESEXPORT_PLAYLISTS=" + strconv.Itoa(len(exportSettings.Playlists)),
+	}
+}
+
+// runHook runs command with the shell of the system, adding env to the environment.
+func runHook(ctx context.Context, command string, env []string) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd.exe", "/c", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "/bin/sh", "-c", command)
+	}
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
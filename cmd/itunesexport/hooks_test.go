@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/export"
+)
+
+func TestExportStats(t *testing.T) {
+	var forwarded int
+	stats := &exportStats{handler: export.EventHandlerFunc(func(event export.Event) { forwarded++ })}
+	stats.HandleEvent(export.Event{Type: export.EVENT_FILE_FINISHED, Bytes: 100})
+	stats.HandleEvent(export.Event{Type: export.EVENT_FILE_FINISHED, Bytes: 50})
+	stats.HandleEvent(export.Event{Type: export.EVENT_PLAYLIST_WRITTEN})
+	stats.HandleEvent(export.Event{Type: export.EVENT_ERROR})
+	if forwarded != 4 {
+		t.Fatalf("expected 4 events to be forwarded, got %v", forwarded)
+	}
+
+	env := strings.Join(stats.env(errors.New("failed")), "\n")
+	for _, expected := range []string{"ITUNESEXPORT_SUCCESS=false", "ITUNESEXPORT_ERROR=failed", "ITUNESEXPORT_FILES_COPIED=2",
+		"ITUNESEXPORT_BYTES_COPIED=150", "ITUNESEXPORT_PLAYLISTS_WRITTEN=1", "ITUNESEXPORT_PLAYLISTS_SKIPPED=0", "ITUNESEXPORT_ERRORS=1"} {
+		if !strings.Contains(env, expected+"\n") && !strings.HasSuffix(env, expected) {
+			t.Fatalf("expected %v in %q", expected, env)
+		}
+	}
+}
+
+func TestRunHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the hook uses a POSIX shell command")
+	}
+	dir, err := ioutil.TempDir("", "itunesexport-hook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "hook.txt")
+	if err := runHook(context.Background(), `echo "$ITUNESEXPORT_HOOK $ITUNESEXPORT_SUCCESS" > "`+file+`"`, []string{"ITUNESEXPORT_HOOK=post", "ITUNESEXPORT_SUCCESS=true"}); err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "post true\n" {
+		t.Fatalf("unexpected output of the hook %q", content)
+	}
+
+	if err := runHook(context.Background(), "exit 3", nil); err == nil {
+		t.Fatal("expected the failing hook to return an error")
+	}
+}
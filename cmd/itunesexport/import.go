@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+const ImportUsageMessage = `usage: %v import -output <file path> <playlist directory>
+
+Builds an iTunes Music Library XML file of the M3U and M3U8 playlists in the directory,
+e.g. to import them into iTunes or Music with File > Library > Import Playlist. Playlists
+in subdirectories are put into playlist folders. The tracks are described by the tags of
+their MP3, FLAC and M4A files and by the extended M3U information of the playlists.
+
+Flags:
+    -output <file path>         Library XML file to write.
+`
+
+// runImport runs the import command with the given arguments. It returns false if the
+// library could not be written.
+func runImport(args []string) bool {
+	var outputPath string
+
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	flags.StringVar(&outputPath, "output", "", "")
+
+	err := flags.Parse(args)
+	if err == nil && (outputPath == "" || flags.NArg() != 1) {
+		err = fmt.Errorf("-output and exactly one playlist directory are required")
+	}
+	if err != nil {
+		fmt.Printf(ImportUsageMessage, "itunesexport")
+		fmt.Printf(UsageErrorMessage, err)
+		return false
+	}
+
+	ctx, cancel := exportContext()
+	defer cancel()
+
+	fmt.Println("Importing playlists from", flags.Arg(0))
+	library, err := itunes.ImportPlaylists(ctx, flags.Arg(0))
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+
+	err = writeLibraryFile(outputPath, func(w io.Writer) error {
+		return itunes.WriteLibrary(w, library)
+	})
+	if err != nil {
+		fmt.Printf("Error writing library: %v\n", err)
+		return false
+	}
+
+	fmt.Printf("Wrote %v playlists with %v tracks to %v.\n", len(library.Playlists)-1, len(library.Tracks), outputPath)
+	return true
+}
+
+// writeLibraryFile writes the named file, removing it again if write fails.
+func writeLibraryFile(name string, write func(io.Writer) error) error {
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		os.Remove(name)
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+func TestImportExportedPlaylists(t *testing.T) {
+	outputDir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(outputDir)
+
+	musicFile, musicFileName := prepareMusicFile(t)
+	defer os.Remove(musicFile)
+
+	itunesDbFile := prepareItunesDbFile(t, filepath.ToSlash(musicFile))
+	defer os.Remove(itunesDbFile)
+
+	realArgs := os.Args
+	defer func() { os.Args = realArgs }()
+	os.Args = []string{"itunesexport", "-library", itunesDbFile, "-output", outputDir, "-includeAll", "-copy", "PLAYLIST", "-type", "EXT", "-noCache"}
+	main()
+
+	libraryFile := filepath.Join(outputDir, "Library.xml")
+	if !runImport([]string{"-output", libraryFile, outputDir}) {
+		t.Fatal("expected playlists to be imported")
+	}
+
+	library, err := itunes.LoadLibrary(libraryFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	playlist, ok := library.PlaylistMap["My Playlist"]
+	if !ok {
+		t.Fatalf("expected My Playlist to be imported, got %+v", library.Playlists)
+	}
+	tracks := playlist.Tracks(library)
+	if len(tracks) != 1 || itunes.LocationPath(tracks[0].Location) != filepath.Join(outputDir, "My Playlist", musicFileName) {
+		t.Fatalf("unexpected tracks %+v", tracks)
+	}
+
+	if runImport([]string{outputDir}) {
+		t.Fatal("expected missing -output to be rejected")
+	}
+}
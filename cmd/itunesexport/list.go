@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+const ListUsageMessage = `usage: %v list [-library <file path>] [-format <TEXT|JSON>]
+
+Lists the playlists of the library with their persistent ids, which select them with
+-playlistId independent of their names.
+
+Flags:
+    -library <file path>        Path to iTunes Music Library XML File.
+    -format <TEXT|JSON>         Format of the list. Defaults to TEXT.
+`
+
+// listedPlaylist is a playlist as listed by the list command.
+type listedPlaylist struct {
+	Id string `json:"id"`
+	// Path is the name of the playlist prefixed with the names of its folders.
+	Path   string `json:"path"`
+	Folder bool   `json:"folder,omitempty"`
+	Tracks int    `json:"tracks"`
+}
+
+// runList runs the list command with the given arguments. It returns false if the library
+// could not be listed.
+func runList(args []string) bool {
+	var (
+		libraryPath string
+		format      string
+	)
+
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	flags.StringVar(&libraryPath, "library", "", "")
+	flags.StringVar(&format, "format", "TEXT", "")
+
+	err := flags.Parse(args)
+	format = strings.ToUpper(format)
+	if err == nil && format != "TEXT" && format != "JSON" {
+		err = fmt.Errorf("Unknown list format: %v", format)
+	}
+	if err == nil && flags.NArg() > 0 {
+		err = fmt.Errorf("Unexpected paramter %v", flags.Arg(0))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, ListUsageMessage, "itunesexport")
+		fmt.Fprintf(os.Stderr, UsageErrorMessage, err)
+		return false
+	}
+
+	if libraryPath == "" {
+		libraryPath, err = defaultLibraryPath()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return false
+		}
+	}
+
+	ctx, cancel := exportContext()
+	defer cancel()
+
+	library, err := itunes.LoadLibraryContext(ctx, filepath.Clean(libraryPath))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return false
+	}
+
+	playlists := listPlaylists(library)
+	if format == "JSON" {
+		err = writeListJSON(os.Stdout, playlists)
+	} else {
+		err = writeListText(os.Stdout, playlists)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing list: %v\n", err)
+		return false
+	}
+	return true
+}
+
+// listPlaylists returns the playlists of library in the order of the library.
+func listPlaylists(library *itunes.Library) []listedPlaylist {
+	playlists := []listedPlaylist{}
+	for _, playlist := range library.Playlists {
+		playlists = append(playlists, listedPlaylist{
+			Id:     playlist.PlaylistPersistentId,
+			Path:   playlistFolderPath(library, playlist),
+			Folder: playlist.Folder,
+			Tracks: len(playlist.PlaylistItems),
+		})
+	}
+	return playlists
+}
+
+// playlistFolderPath returns the name of playlist prefixed with the names of its folders,
+// e.g. "Mixes/Road Trip".
+func playlistFolderPath(library *itunes.Library, playlist itunes.Playlist) string {
+	name := playlist.Name
+	for seen := 0; playlist.ParentPersistentId != "" && seen < len(library.Playlists); seen++ {
+		parent, ok := library.PlaylistIdMap[playlist.ParentPersistentId]
+		if !ok {
+			break
+		}
+		name = parent.Name + "/" + name
+		playlist = parent
+	}
+	return name
+}
+
+func writeListText(w io.Writer, playlists []listedPlaylist) error {
+	var out strings.Builder
+	for _, playlist := range playlists {
+		if playlist.Folder {
+			fmt.Fprintf(&out, "%v  %v/\n", playlist.Id, playlist.Path)
+		} else {
+			fmt.Fprintf(&out, "%v  %v (%v tracks)\n", playlist.Id, playlist.Path, playlist.Tracks)
+		}
+	}
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+func writeListJSON(w io.Writer, playlists []listedPlaylist) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(playlists)
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+func TestListPlaylists(t *testing.T) {
+	library := &itunes.Library{
+		Playlists: []itunes.Playlist{
+			{Name: "Mixes", PlaylistPersistentId: "F01DE2", Folder: true},
+			{Name: "Road Trip", PlaylistPersistentId: "BA9D3C2EAB361B84", ParentPersistentId: "F01DE2",
+				PlaylistItems: []itunes.PlaylistItem{{TrackId: 1}, {TrackId: 2}}},
+		},
+	}
+	library.BuildPlaylistMaps()
+
+	var out bytes.Buffer
+	if err := writeListText(&out, listPlaylists(library)); err != nil {
+		t.Fatal(err)
+	}
+	expected := "F01DE2  Mixes/\nBA9D3C2EAB361B84  Mixes/Road Trip (2 tracks)\n"
+	if out.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, out.String())
+	}
+}
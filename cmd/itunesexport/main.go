@@ -0,0 +1,1460 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/export"
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+const (
+	UsageMessage = `usage: %v [<flags>] [include <playlist name>...] [exclude <playlist name>...]
+
+Specify one of the -include<All|AllWithBuiltin|PlaylistWithRegex|Kinds> flags or use 
+the include parameter with playlist names or -playlistId to specify the playlist to export.
+
+Usage of exclude parameter will override any playlist included using the flag 
+or parameter.
+
+Use "itunesexport verify" to check an existing export, "itunesexport check"
+to find tracks of the library whose files are missing, "itunesexport serve-dlna"
+to share playlists with TVs and receivers on the network, "itunesexport import"
+to build a library XML file from a folder of M3U playlists, "itunesexport diff"
+to compare the playlists of two library XML files, "itunesexport list" to list
+the playlists with their persistent ids, "itunesexport rollback" to restore
+the playlists of a previous export, "itunesexport site" to write a static
+website of the library, "itunesexport airplay" to play a playlist on an
+AirPlay receiver and "itunesexport service" to export on a schedule as a
+service of the operating system.
+
+Flags:
+    -library <file path>        Path to iTunes Music Library XML File.
+    -daap <share>               Read the library from a DAAP share (name announced on the network or host[:port]).
+    -daapPassword <pass>        Password of the DAAP share. Defaults to the DAAP_PASSWORD environment variable.
+    -output <file path>         Path where the playlists should be written. A path ending in .zip writes a zip archive,
+                                sftp://[user@]host[:port]/path writes to a server using SFTP.
+                                webdav://[user[:password]@]host/path or webdavs://... writes to a WebDAV server.
+                                s3://bucket/prefix writes to Amazon S3 or compatible object storage.
+                                smb://[user@]server/share/path writes to a SMB/CIFS share without mounting it.
+                                mtp: or mtp:<device name> copies to the Music folder of an attached phone (Linux).
+                                adb:<device path>, e.g. adb:/sdcard/Music, pushes to an Android device using adb.
+                                rockbox:<mount point> copies to a player running Rockbox, e.g. rockbox:/media/IPOD.
+                                - writes the playlist to standard output, if exactly one playlist is selected.
+    -s3Endpoint <URL>           Endpoint of S3 compatible object storage, e.g. MinIO or Backblaze B2.
+    -rockboxDatabase            Turn on the database auto update of the Rockbox player to add the copied music.
+    -type <M3U|EXT|M3U8|WPL|ZPL|HLS|XSPF|RSS|APPLESCRIPT|BEETS> Type of playlist file to write.  Defaults to M3U
+                                EXT = M3U Extended, M3U8 = M3U Extended as .m3u8 file, WPL = Windows Playlist, ZPL = Zune Playlist
+                                HLS = HTTP Live Streaming media playlist (RFC 8216) as .m3u8 file, for web servers
+                                XSPF = XML Shareable Playlist Format, read by VLC and many video players
+                                RSS = podcast feed of the tracks, linking to the copies below -feedURL
+                                APPLESCRIPT = script recreating the playlist in the Music app of macOS
+                                BEETS = smartplaylist plugin configuration of beets, matching the music files
+    -extinfTemplate <template>  Text players show for the tracks of EXT and M3U8 playlists, with the placeholders of
+                                -copyTemplate, e.g. "{artist} - {title} ({album})". Defaults to "{artist} - {title}".
+    -albumDirectives            Write #EXTALB, #EXTART and #EXTGENRE lines to EXT and M3U8 playlists before each run of
+                                tracks of the same album, for players that show them.
+    -profile <PROFILE>          Use the settings known to work with a device, see README. -type, -copy and the flags
+                                below given as well override the settings of the profile...
+        kenwood                 Kenwood car stereos.
+        pioneer                 Pioneer car stereos.
+        vwRNS                   Volkswagen RNS navigation systems.
+        genericFat32            Other devices reading a FAT32 formatted USB stick or SD card.
+    -config <file path>         JSON configuration file with settings of single playlists and character replacements,
+                                see README.
+    -ignore <file path>         File listing tracks known to be broken, one persistent id or music file path per line,
+                                which are left out without warnings.
+    -pathStyle <STYLE>          How copied music files are written to the playlists...
+        ABSOLUTE                (default) The full path in the output.
+        RELATIVE                Relative to the playlist file.
+        RELATIVEBACKSLASH       Relative to the playlist file, with backslashes.
+    -encoding <UTF8|UTF8BOM|CP1252> Character encoding of the playlist files. Defaults to UTF8.
+    -nameCase <KEEP|LOWER|UPPER> Case of the names of the copied files and folders and the playlist files. Defaults to KEEP.
+    -maxFilenameLength <count>  Shorten the names of copied files and folders and replace characters FAT can not store.
+    -maxFolderDepth <count>     Join the innermost folders of copied files nested deeper than count.
+    -maxFilesPerDir <count>     Move the copied files and folders into subfolders like A-M and N-Z where a folder
+                                would have more than count entries.
+    -stripSymbols               Leave emoji, other symbols, zero-width and control characters out of the names of
+                                copied files and playlists.
+    -maxPlaylistEntries <count> Leave out the tracks of a playlist after the first count.
+    -minDuration <duration>     Leave out the tracks shorter than the duration, e.g. 30s.
+    -maxDuration <duration>     Leave out the tracks longer than the duration, e.g. 10m for DJ mixes.
+    -maxFileSize <size>         Leave out the tracks with music files larger than the size, e.g. 100MB.
+    -cleanOnly                  Replace explicit tracks with their clean versions, leaving them out if there are none.
+    -minBitrate <kbit/s>        Leave out the tracks with a lower bit rate, e.g. 192.
+    -codec <codecs>             Only export the tracks of the given comma separated codecs: mp3, aac, alac, flac,
+                                wav, aiff, or lossy and lossless for all of them.
+    -audiobooks <MODE>          How audiobooks (.m4b and Audible files, or the genre Audiobooks) are exported...
+        EXCLUDE                 (default) Leave them out of the playlists.
+        INCLUDE                 Export them like music.
+        ONLY                    Export only them, with a playlist for every book of the selected playlists in the
+                                folder Audiobooks, its parts in order. -copy copies them to
+                                <author>/<book>/<chapter> <title> unless -copyTemplate is given.
+    -videos <MODE>              How videos (movies, TV shows, music videos and home videos) are exported...
+        EXCLUDE                 (default) Leave them out of the playlists.
+        INCLUDE                 Export them like music.
+        ONLY                    Export only them, e.g. with -copy VIDEO -type XSPF.
+    -podcasts                   Export only podcast episodes, with a playlist for every show of the selected playlists
+                                in the folder Podcasts, oldest episode first. -copy copies them to
+                                <show>/<date> <title> unless -copyTemplate is given.
+    -feedURL <URL>              URL the output is hosted at, e.g. https://home.example.com/music. The episodes of RSS
+                                playlists link to the copied files below it. With -podcasts, an RSS feed of every show
+                                is also written next to its playlist.
+    -shortNames                 Give copied files, folders and playlists DOS 8.3 names, listed in NAMES.TXT.
+    -includeAll                 Include all user defined playlists.
+    -includeAllWithBuiltin      Include All playlists, including iTunes defined playlists
+    -includePlaylistWithRegex   Include all playlists matching the provided regular expression
+    -playlistId <ids>           Include the playlists with the given comma separated persistent ids, which stay the
+                                same when a playlist is renamed. "itunesexport list" shows them.
+    -includeKinds <kinds>       Include the iTunes defined playlists of the given comma separated kinds, e.g. Genius,Purchased.
+                                Combined with -includeAll they are included in addition to the user defined playlists.
+    -excludeKinds <kinds>       Exclude the iTunes defined playlists of the given kinds, e.g. with -includeAllWithBuiltin.
+                                Kinds are Genius, GeniusMixes, Downloaded, DownloadedMusic, Purchased, Music, Movies,
+                                TVShows, Podcasts, Audiobooks, iTunesU, iTunesDJ, MusicVideos, HomeVideos or a number.
+    -autoPlaylists <kinds>      Generate playlists of the given comma separated kinds from the tracks of the selected
+                                playlists...
+        GENRE                   A playlist for every genre, in the folder Genres.
+        DECADE                  A playlist for every decade of the year of the tracks, e.g. 1980s, in the folder Decades.
+        YEAR                    A playlist for every year, e.g. 2024, in the folder Years.
+        RATING                  A playlist for every star rating, e.g. 5 Star, in the folder Ratings.
+        MOSTPLAYED              The playlist Top 100 Most Played.
+        RECENTLYADDED           The playlist Recently Added (90 days), relative to the date of the library.
+    -artistPlaylists <options>  Generate a playlist of the best rated and most played tracks of every artist of the
+                                selected playlists with at least minTracks tracks, e.g. minTracks=15. Defaults to 10.
+    -folderPlaylists            Also write a playlist for every selected playlist folder with the tracks of all
+                                playlists in it, like iTunes plays a folder.
+    -randomFill <size>          Generate a "Random Fill" playlist of tracks sampled at random from the -from playlists
+                                filling the size, e.g. 16GB. Exports only it if no playlists are selected.
+    -from <playlists>           Comma separated names of the playlists -randomFill samples from.
+    -seed <number>              Seed of -randomFill, to sample the same tracks again. Defaults to a new seed every run.
+    -combine <combinations>     Comma separated playlists combined from others, e.g. "Gym=Workout+Running-Chill".
+                                + adds the tracks of a playlist, - removes them and & keeps only those in it.
+    -copy <COPY TYPE>           Copy the music tracks as well, according the the COPY TYPE scheme...
+        NONE                    (default) The music files will not be copied.	                            
+        PLAYLIST                Copies the music into a folder for each playlist.
+        ITUNES                  Copies using the itunes music/<Artist>/<Album>/<Track> structure.
+                                Albums go into the folder of their album artist, compilations into Compilations.
+        FLAT                    Copies all the music into the output folder.
+        STORE                   Copies each music file once into .store, named by its checksum, and links it
+                                into a folder for each playlist.
+        VIDEO                   Copies videos into the folders media servers expect: Movies/<title> (<year>),
+                                TV Shows/<series>/Season <season>, Music Videos/<artist> and Home Videos.
+    -copyTemplate <template>    Copy the music tracks to the path given by the template instead of a COPY TYPE, e.g.
+                                "{albumartist}/{album}/{cd}/{track} {title}". Placeholders are {artist}, {albumartist},
+                                {album}, {title}, {composer}, {work}, {movement}, {movementnumber}, {genre}, {year},
+                                {track}, {disc}, {cd} (CD2 for multi-disc albums, else left out), {disctrack} (2-07
+                                for multi-disc albums, else 07), {chapter} (like {disctrack} with three digits, 2-007),
+                                {show} (album or artist of podcasts), {date} (release date, 2024-05-02), {series},
+                                {season} and {episode} (of TV shows), {playlist}, {filename} and {ext}. A fallback
+                                for empty values follows a |, e.g. {album|Unknown Album}.
+    -strictTemplate             Check the paths of the copy template for all tracks before copying and stop if
+                                placeholders are empty or several music files would be copied to the same path.
+    -playlistInFolder           With -copy PLAYLIST, write each playlist file into the folder of its music, with
+                                entries relative to it, for players that only find playlists next to the music.
+    -musicPath <new path>       Base path to the music files. This will override the Music Folder path from iTunes.
+	-musicPathOrig <path>       When using -musicPath this allows you to override the Music Folder value that is replaced.
+	-includeFolders             Playlists within folders will include the full path in the name.
+    -overwrite <POLICY>         What to do when a playlist or music file already exists in the output...
+        (default)               Playlist files are rewritten, existing music files are kept.
+        ALWAYS                  Always overwrite existing files.
+        NEVER                   Never overwrite existing files.
+        IFNEWER                 Overwrite if the source (music file or library) is newer than the existing file.
+        IFSIZEDIFFERS           Overwrite if the size of the existing file differs from the source.
+        PROMPT                  Ask for every existing file.
+    -validate                   Check every WPL, ZPL, XSPF or RSS playlist file for well-formedness before it is written.
+    -incremental                Only rewrite playlists (and copy their music) that changed since the last incremental export.
+    -changes                    Print the tracks and playlists added, removed and renamed since the previous export
+                                with -changes before writing anything.
+    -confirm                    Like -changes, but ask before an export that removes playlists or tracks.
+    -checksums                  List the SHA-256 checksums of the playlists and copied music in SHA256SUMS.
+    -ids                        Map the persistent ids of the playlists and tracks to their files in itunes-ids.json.
+    -tolerant                   Repair invalid characters and entities in the library instead of failing.
+    -noCache                    Always parse the library instead of using the cached copy of the previous run.
+    -parallel <count>           Number of playlists to export concurrently. Defaults to 1.
+    -copyInOrder                Export one playlist at a time, so music files are copied in playlist order, which
+                                car stereos indexing files in directory order play them in. Overrides -parallel.
+    -reorderDirectories         After copying, rewrite the directories of the music so the entries of files copied
+                                by earlier exports are in playlist order as well. Implies -copyInOrder.
+    -sortDirectories            After copying, rewrite the directories of the playlists and music so their entries
+                                are sorted by name, folders first, like fatsort, for players playing in FAT order.
+    -precheck                   Open the music files of all tracks to be copied before copying any, and stop with a
+                                list of the files that can not be read.
+    -retries <count>            Retry copying a music file the given number of times if writing it fails, e.g. because
+                                of a network problem. Defaults to 0.
+    -retryWait <duration>       Time to wait before the first retry, doubled for every further retry. Defaults to 1s.
+    -bwlimit <rate>             Limit the rate music files are copied at, e.g. 10MB/s or 512KB/s, shared by all
+                                parallel copies. Defaults to no limit.
+    -reserve <size>             Keep the given space free on the output, e.g. 500MB, leaving out the tracks exported
+                                last that do not fit anymore.
+    -playlistVersions <count>   Keep the given number of previous versions of each playlist file in the .backups
+                                folder of the output when it is overwritten with changes. Defaults to 0.
+    -snapshots                  Keep a snapshot of the playlist files of every export in the .snapshots folder of the
+                                output, so "itunesexport rollback" can restore them.
+    -events <FORMAT>            Write the progress of the export as events to standard error...
+        JSONL                   One JSON object per line.
+    -logFormat <FORMAT>         How the messages are written to standard output...
+        TEXT                    As plain text (default).
+        JSON                    One JSON object per message.
+    -preHook <command>          Run the command before exporting, e.g. to mount the drive. The export is
+                                cancelled if it fails. ITUNESEXPORT_OUTPUT and others describe the export.
+    -postHook <command>         Run the command after exporting, whether it succeeded or not. ITUNESEXPORT_SUCCESS,
+                                ITUNESEXPORT_FILES_COPIED and others describe the result.
+    -notify                     Show a desktop notification summarizing the result when the export finishes.
+    -syslog                     Write the messages to syslog (and so journald) or the Windows Event Log as well.
+    -timeout <duration>         Stop the export after the given time, e.g. 30m. Ctrl-C stops the export as well.
+    -serve <address>            Start the web interface on the given address (e.g. :8080) instead of exporting. It
+                                also streams the playlists to network players as M3U playlists.
+    -plex <URL>                 Create the playlists on the Plex Media Server at the URL (e.g. http://server:32400)
+                                instead of exporting, matching the tracks with its music libraries.
+    -plexToken <token>          Authentication token for -plex. Defaults to the PLEX_TOKEN environment variable.
+    -jellyfin <URL>             Create the playlists on the Jellyfin or Emby server at the URL (e.g. http://server:8096).
+    -jellyfinToken <key>        API key for -jellyfin. Defaults to the JELLYFIN_TOKEN environment variable.
+    -jellyfinUser <name>        User the playlists are created for. Required if the server has several users.
+    -subsonic <URL>             Create the playlists on the Subsonic API server (Navidrome, Airsonic) at the URL.
+    -subsonicUser <name>        User for -subsonic.
+    -subsonicPassword <pass>    Password for -subsonic. Defaults to the SUBSONIC_PASSWORD environment variable.
+    -sonos <speaker>            Create the playlists in the Sonos household of the speaker (address or URL).
+    -sonosShare <share>         Share of the Sonos music library (e.g. //nas/music) the Music Folder is stored on.
+    -spotify                    Authorize in the browser and create the playlists in your Spotify account.
+    -spotifyClientId <id>       Client id of your Spotify app. Defaults to the SPOTIFY_CLIENT_ID environment variable.
+    -youtube                    Authorize in the browser and create the playlists in your YouTube Music account.
+    -youtubeClientId <id>       Client id of your Google OAuth app. Defaults to the YOUTUBE_CLIENT_ID environment variable.
+    -youtubeClientSecret <key>  Client secret of the app. Defaults to the YOUTUBE_CLIENT_SECRET environment variable.
+    -appleMusicKey <file>       MusicKit private key (.p8) to create the playlists in your Apple Music library with.
+    -appleMusicKeyId <id>       Key id of -appleMusicKey.
+    -appleMusicTeamId <id>      Apple Developer team id of -appleMusicKey.
+    -appleMusicUserToken <key>  Music user token of your Apple ID. Defaults to the APPLE_MUSIC_USER_TOKEN environment variable.
+    -listenbrainz <token>       Submit the play counts of the library as listens with the ListenBrainz user token.
+    -listenbrainzURL <URL>      ListenBrainz API server. Defaults to https://api.listenbrainz.org.
+`
+	UsageErrorMessage = `Unable to parse command line parameters.
+%v
+`
+	ModeUnknown = 0
+	ModeInclude = 1
+	ModeExclude = 2
+)
+
+// compile passing -ldflags "-X main.Build <build number>"
+// Must be var not const so it can be set by build flags.
+var Version string = "DEV"
+
+var (
+	commandLineError        = false
+	commandLineErrorMessage = ""
+
+	libraryPath                    string
+	daapShare                      string
+	daapPassword                   string
+	rockboxDatabase                bool
+	outputPath                     string
+	s3Endpoint                     string
+	exportType                     string
+	profile                        string
+	configFile                     string
+	ignoreFile                     string
+	exportConfig                   *config
+	pathStyle                      string
+	playlistEncoding               string
+	nameCase                       string
+	maxFilenameLength              int
+	maxFolderDepth                 int
+	maxFilesPerDir                 int
+	stripSymbols                   bool
+	maxPlaylistEntries             int
+	minDuration                    time.Duration
+	maxDuration                    time.Duration
+	maxFileSize                    string
+	cleanOnly                      bool
+	minBitrate                     int
+	codecs                         string
+	audiobooks                     string
+	videos                         string
+	podcasts                       bool
+	feedURL                        string
+	shortNames                     bool
+	changes                        bool
+	checksums                      bool
+	ids                            bool
+	confirm                        bool
+	strictTemplate                 bool
+	precheck                       bool
+	includeAllPlaylists            bool
+	includeAllWithBuiltinPlaylists bool
+	includePlaylistNames           []string
+	includePlaylistIds             string
+	includePlaylistWithRegex       string
+	excludePlaylistNames           []string
+	includeKindNames               string
+	autoPlaylistNames              string
+	autoPlaylistKinds              []string
+	artistPlaylistFlag             string
+	artistPlaylistOptions          *itunes.ArtistPlaylistOptions
+	folderPlaylists                bool
+	randomFill                     string
+	randomFillSize                 int64
+	randomFillFrom                 string
+	randomFillSeed                 int64
+	combine                        string
+	combinations                   []itunes.Combination
+	excludeKindNames               string
+	includeKinds                   []int
+	excludeKinds                   []int
+	copyType                       string
+	copyTemplate                   string
+	extinfTemplate                 string
+	albumDirectives                bool
+	musicPath                      string
+	musicPathOrig                  string
+	includeFolders                 bool
+	playlistInFolder               bool
+	overwrite                      string
+	parallel                       int
+	copyInOrder                    bool
+	reorderDirectories             bool
+	sortDirectories                bool
+	retries                        int
+	retryWait                      time.Duration
+	bandwidthLimit                 string
+	reserve                        string
+	playlistVersions               int
+	snapshots                      bool
+	noCache                        bool
+	incremental                    bool
+	serveAddress                   string
+	timeout                        time.Duration
+	eventsFormat                   string
+	logFormat                      string
+	preHook                        string
+	postHook                       string
+	notifyDesktop                  bool
+	systemLogging                  bool
+	tolerant                       bool
+	validate                       bool
+	plexURL                        string
+	plexToken                      string
+	jellyfinURL                    string
+	jellyfinToken                  string
+	jellyfinUser                   string
+	subsonicURL                    string
+	subsonicUser                   string
+	subsonicPassword               string
+	sonosSpeaker                   string
+	sonosShare                     string
+	spotify                        bool
+	spotifyClientId                string
+	youtube                        bool
+	youtubeClientId                string
+	youtubeClientSecret            string
+	appleMusicKey                  string
+	appleMusicKeyId                string
+	appleMusicTeamId               string
+	appleMusicUserToken            string
+	listenBrainzToken              string
+	listenBrainzURL                string
+
+	exportSettings export.ExportSettings
+	// playlistOutput is the standard output the playlist of -output - is written to.
+	playlistOutput io.Writer
+)
+
+func main() {
+
+	export.Version = Version
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "verify":
+			if !runVerify(os.Args[2:]) {
+				os.Exit(1)
+			}
+			return
+		case "check", "doctor":
+			if !runCheck(os.Args[2:]) {
+				os.Exit(1)
+			}
+			return
+		case "serve-dlna":
+			if !runDLNA(os.Args[2:]) {
+				os.Exit(1)
+			}
+			return
+		case "import":
+			if !runImport(os.Args[2:]) {
+				os.Exit(1)
+			}
+			return
+		case "diff":
+			if !runDiff(os.Args[2:]) {
+				os.Exit(1)
+			}
+			return
+		case "list":
+			if !runList(os.Args[2:]) {
+				os.Exit(1)
+			}
+			return
+		case "rollback":
+			if !runRollback(os.Args[2:]) {
+				os.Exit(1)
+			}
+			return
+		case "site":
+			if !runSite(os.Args[2:]) {
+				os.Exit(1)
+			}
+			return
+		case "airplay":
+			if !runAirPlay(os.Args[2:]) {
+				os.Exit(1)
+			}
+			return
+		case "service":
+			if !runService(os.Args[2:]) {
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	flags := flag.NewFlagSet("flags", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+
+	flags.StringVar(&libraryPath, "library", "", "")
+	flags.StringVar(&daapShare, "daap", "", "")
+	flags.StringVar(&daapPassword, "daapPassword", "", "")
+	flags.BoolVar(&rockboxDatabase, "rockboxDatabase", false, "")
+	flags.StringVar(&outputPath, "output", "", "")
+	flags.StringVar(&s3Endpoint, "s3Endpoint", "", "")
+	flags.StringVar(&exportType, "type", "M3U", "")
+	flags.StringVar(&profile, "profile", "", "")
+	flags.StringVar(&configFile, "config", "", "")
+	flags.StringVar(&ignoreFile, "ignore", "", "")
+	flags.StringVar(&pathStyle, "pathStyle", "ABSOLUTE", "")
+	flags.StringVar(&playlistEncoding, "encoding", "UTF8", "")
+	flags.StringVar(&nameCase, "nameCase", "KEEP", "")
+	flags.IntVar(&maxFilenameLength, "maxFilenameLength", 0, "")
+	flags.IntVar(&maxFolderDepth, "maxFolderDepth", 0, "")
+	flags.IntVar(&maxFilesPerDir, "maxFilesPerDir", 0, "")
+	flags.BoolVar(&stripSymbols, "stripSymbols", false, "")
+	flags.IntVar(&maxPlaylistEntries, "maxPlaylistEntries", 0, "")
+	flags.DurationVar(&minDuration, "minDuration", 0, "")
+	flags.DurationVar(&maxDuration, "maxDuration", 0, "")
+	flags.StringVar(&maxFileSize, "maxFileSize", "0", "")
+	flags.BoolVar(&cleanOnly, "cleanOnly", false, "")
+	flags.IntVar(&minBitrate, "minBitrate", 0, "")
+	flags.StringVar(&codecs, "codec", "", "")
+	flags.StringVar(&audiobooks, "audiobooks", "EXCLUDE", "")
+	flags.StringVar(&videos, "videos", "EXCLUDE", "")
+	flags.BoolVar(&podcasts, "podcasts", false, "")
+	flags.StringVar(&feedURL, "feedURL", "", "")
+	flags.BoolVar(&shortNames, "shortNames", false, "")
+	flags.BoolVar(&changes, "changes", false, "")
+	flags.BoolVar(&checksums, "checksums", false, "")
+	flags.BoolVar(&ids, "ids", false, "")
+	flags.BoolVar(&confirm, "confirm", false, "")
+	flags.BoolVar(&strictTemplate, "strictTemplate", false, "")
+	flags.BoolVar(&precheck, "precheck", false, "")
+	flags.BoolVar(&includeAllPlaylists, "includeAll", false, "")
+	flags.BoolVar(&includeAllWithBuiltinPlaylists, "includeAllWithBuiltin", false, "")
+	flags.StringVar(&includePlaylistWithRegex, "includePlaylistWithRegex", "", "")
+	flags.StringVar(&includePlaylistIds, "playlistId", "", "")
+	flags.StringVar(&includeKindNames, "includeKinds", "", "")
+	flags.StringVar(&autoPlaylistNames, "autoPlaylists", "", "")
+	flags.StringVar(&artistPlaylistFlag, "artistPlaylists", "", "")
+	flags.BoolVar(&folderPlaylists, "folderPlaylists", false, "")
+	flags.StringVar(&randomFill, "randomFill", "", "")
+	flags.StringVar(&randomFillFrom, "from", "", "")
+	flags.Int64Var(&randomFillSeed, "seed", 0, "")
+	flags.StringVar(&combine, "combine", "", "")
+	flags.StringVar(&excludeKindNames, "excludeKinds", "", "")
+	flags.StringVar(&copyType, "copy", "NONE", "")
+	flags.StringVar(&copyTemplate, "copyTemplate", "", "")
+	flags.StringVar(&extinfTemplate, "extinfTemplate", "", "")
+	flags.BoolVar(&albumDirectives, "albumDirectives", false, "")
+	flags.StringVar(&musicPath, "musicPath", "", "")
+	flags.StringVar(&musicPathOrig, "musicPathOrig", "", "")
+	flags.BoolVar(&includeFolders, "includeFolders", false, "")
+	flags.BoolVar(&playlistInFolder, "playlistInFolder", false, "")
+	flags.StringVar(&overwrite, "overwrite", "", "")
+	flags.IntVar(&parallel, "parallel", 1, "")
+	flags.BoolVar(&copyInOrder, "copyInOrder", false, "")
+	flags.BoolVar(&reorderDirectories, "reorderDirectories", false, "")
+	flags.BoolVar(&sortDirectories, "sortDirectories", false, "")
+	flags.IntVar(&retries, "retries", 0, "")
+	flags.DurationVar(&retryWait, "retryWait", time.Second, "")
+	flags.StringVar(&bandwidthLimit, "bwlimit", "0", "")
+	flags.StringVar(&reserve, "reserve", "0", "")
+	flags.IntVar(&playlistVersions, "playlistVersions", 0, "")
+	flags.BoolVar(&snapshots, "snapshots", false, "")
+	flags.BoolVar(&noCache, "noCache", false, "")
+	flags.BoolVar(&incremental, "incremental", false, "")
+	flags.StringVar(&serveAddress, "serve", "", "")
+	flags.DurationVar(&timeout, "timeout", 0, "")
+	flags.StringVar(&eventsFormat, "events", "", "")
+	flags.StringVar(&logFormat, "logFormat", "TEXT", "")
+	flags.StringVar(&preHook, "preHook", "", "")
+	flags.StringVar(&postHook, "postHook", "", "")
+	flags.BoolVar(&notifyDesktop, "notify", false, "")
+	flags.BoolVar(&systemLogging, "syslog", false, "")
+	flags.BoolVar(&tolerant, "tolerant", false, "")
+	flags.BoolVar(&validate, "validate", false, "")
+	flags.StringVar(&plexURL, "plex", "", "")
+	flags.StringVar(&plexToken, "plexToken", "", "")
+	flags.StringVar(&jellyfinURL, "jellyfin", "", "")
+	flags.StringVar(&jellyfinToken, "jellyfinToken", "", "")
+	flags.StringVar(&jellyfinUser, "jellyfinUser", "", "")
+	flags.StringVar(&subsonicURL, "subsonic", "", "")
+	flags.StringVar(&subsonicUser, "subsonicUser", "", "")
+	flags.StringVar(&subsonicPassword, "subsonicPassword", "", "")
+	flags.StringVar(&sonosSpeaker, "sonos", "", "")
+	flags.StringVar(&sonosShare, "sonosShare", "", "")
+	flags.BoolVar(&spotify, "spotify", false, "")
+	flags.StringVar(&spotifyClientId, "spotifyClientId", "", "")
+	flags.BoolVar(&youtube, "youtube", false, "")
+	flags.StringVar(&youtubeClientId, "youtubeClientId", "", "")
+	flags.StringVar(&youtubeClientSecret, "youtubeClientSecret", "", "")
+	flags.StringVar(&appleMusicKey, "appleMusicKey", "", "")
+	flags.StringVar(&appleMusicKeyId, "appleMusicKeyId", "", "")
+	flags.StringVar(&appleMusicTeamId, "appleMusicTeamId", "", "")
+	flags.StringVar(&appleMusicUserToken, "appleMusicUserToken", "", "")
+	flags.StringVar(&listenBrainzToken, "listenbrainz", "", "")
+	flags.StringVar(&listenBrainzURL, "listenbrainzURL", "https://api.listenbrainz.org", "")
+
+	err := flags.Parse(os.Args[1:])
+	if err != nil {
+		commandLineError = true
+		commandLineErrorMessage = err.Error()
+	}
+
+	// With -output -, the playlist is written to standard output and all messages to
+	// standard error.
+	playlistOutput = os.Stdout
+	if outputPath == "-" {
+		os.Stdout = os.Stderr
+	}
+
+	logFormatType, err := export.ParseLogFormat(logFormat)
+	if err != nil {
+		commandLineError = true
+		commandLineErrorMessage = fmt.Sprintf("%v\n", err.Error())
+	}
+	export.SetLogFormat(logFormatType, nil)
+	itunes.Logf = func(format string, args ...interface{}) {
+		export.Logf(export.LOG_INFO, format, args...)
+	}
+	export.SetLogHandler(nil)
+	if systemLogging {
+		handler, err := systemLog()
+		if err != nil {
+			logError(err, "Unable to write to the system log: %v\n", err)
+		} else {
+			export.SetLogHandler(handler)
+		}
+	}
+
+	export.Logf(export.LOG_INFO, "\niTunes Export (Go Version %v)\nSee http://www.ericdaugherty.com/dev/itunesexport/ for detailed instructions.\n\n", Version)
+
+	// Flags given explicitly override the settings of the profile.
+	setFlags := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+	override := func(name string) bool { return profile == "" || setFlags[name] }
+
+	if profile != "" {
+		err = export.ApplyProfile(&exportSettings, profile)
+		if err != nil {
+			commandLineError = true
+			commandLineErrorMessage = fmt.Sprintf("%v\n", err.Error())
+		}
+	}
+
+	exportConfig = nil
+	exportSettings.PlaylistSettings = nil
+	exportSettings.Replacements = nil
+	if configFile != "" {
+		exportConfig, err = loadConfig(configFile)
+		if err != nil {
+			commandLineError = true
+			commandLineErrorMessage = fmt.Sprintf("%v\n", err.Error())
+		} else {
+			exportSettings.PlaylistSettings = exportConfig.playlistSettings()
+			exportSettings.Replacements = exportConfig.Replace
+		}
+	}
+
+	exportSettings.Ignore = nil
+	if ignoreFile != "" {
+		exportSettings.Ignore, err = itunes.LoadIgnoreList(ignoreFile)
+		if err != nil {
+			commandLineError = true
+			commandLineErrorMessage = fmt.Sprintf("Unable to read ignore file: %v\n", err.Error())
+		}
+	}
+
+	if override("type") {
+		err = export.ParseExportType(&exportSettings, exportType)
+		if err != nil {
+			commandLineError = true
+			commandLineErrorMessage = fmt.Sprintf("%v\n", err.Error())
+		}
+	}
+
+	includeKinds, err = itunes.ParsePlaylistKinds(includeKindNames)
+	if err != nil {
+		commandLineError = true
+		commandLineErrorMessage = fmt.Sprintf("%v\n", err.Error())
+	}
+
+	excludeKinds, err = itunes.ParsePlaylistKinds(excludeKindNames)
+	if err != nil {
+		commandLineError = true
+		commandLineErrorMessage = fmt.Sprintf("%v\n", err.Error())
+	}
+
+	autoPlaylistKinds, err = itunes.ParseAutoPlaylists(autoPlaylistNames)
+	if err != nil {
+		commandLineError = true
+		commandLineErrorMessage = fmt.Sprintf("%v\n", err.Error())
+	}
+
+	artistPlaylistOptions = nil
+	if artistPlaylistFlag != "" {
+		options, err := itunes.ParseArtistPlaylistOptions(artistPlaylistFlag)
+		if err != nil {
+			commandLineError = true
+			commandLineErrorMessage = fmt.Sprintf("%v\n", err.Error())
+		}
+		artistPlaylistOptions = &options
+	}
+
+	randomFillSize = 0
+	if randomFill != "" {
+		randomFillSize, err = export.ParseSize(randomFill)
+		if err != nil {
+			commandLineError = true
+			commandLineErrorMessage = fmt.Sprintf("%v\n", err.Error())
+		} else if randomFillFrom == "" {
+			commandLineError = true
+			commandLineErrorMessage = "-randomFill requires -from with the playlists to sample from.\n"
+		}
+	}
+
+	combinations, err = itunes.ParseCombinations(combine)
+	if err != nil {
+		commandLineError = true
+		commandLineErrorMessage = fmt.Sprintf("%v\n", err.Error())
+	}
+
+	if override("copy") {
+		err = export.ParseCopyType(&exportSettings, copyType)
+		if err != nil {
+			commandLineError = true
+			commandLineErrorMessage = fmt.Sprintf("%v\n", err.Error())
+		}
+	}
+
+	if copyTemplate != "" {
+		err = export.ParseCopyTemplate(&exportSettings, copyTemplate)
+		if err != nil {
+			commandLineError = true
+			commandLineErrorMessage = fmt.Sprintf("%v\n", err.Error())
+		}
+	}
+
+	exportSettings.AlbumDirectives = albumDirectives
+	if extinfTemplate != "" {
+		err = export.ParseExtinfTemplate(&exportSettings, extinfTemplate)
+		if err != nil {
+			commandLineError = true
+			commandLineErrorMessage = fmt.Sprintf("%v\n", err.Error())
+		}
+	}
+
+	if override("pathStyle") {
+		err = export.ParsePathStyle(&exportSettings, pathStyle)
+		if err != nil {
+			commandLineError = true
+			commandLineErrorMessage = fmt.Sprintf("%v\n", err.Error())
+		}
+	}
+
+	if override("encoding") {
+		err = export.ParseEncoding(&exportSettings, playlistEncoding)
+		if err != nil {
+			commandLineError = true
+			commandLineErrorMessage = fmt.Sprintf("%v\n", err.Error())
+		}
+	}
+
+	err = export.ParseNameCase(&exportSettings, nameCase)
+	if err != nil {
+		commandLineError = true
+		commandLineErrorMessage = fmt.Sprintf("%v\n", err.Error())
+	}
+
+	if override("maxFilenameLength") {
+		exportSettings.MaxFilenameLength = maxFilenameLength
+	}
+	if override("maxFolderDepth") {
+		exportSettings.MaxFolderDepth = maxFolderDepth
+	}
+	exportSettings.MaxFilesPerDir = maxFilesPerDir
+	if override("stripSymbols") {
+		exportSettings.StripSymbols = stripSymbols
+	}
+	if override("maxPlaylistEntries") {
+		exportSettings.MaxPlaylistEntries = maxPlaylistEntries
+	}
+
+	err = export.ParseOverwrite(&exportSettings, overwrite)
+	if err != nil {
+		commandLineError = true
+		commandLineErrorMessage = fmt.Sprintf("%v\n", err.Error())
+	}
+
+	if parallel < 1 {
+		commandLineError = true
+		commandLineErrorMessage = "-parallel must be at least 1\n"
+	}
+	exportSettings.Parallel = parallel
+	exportSettings.CopyInOrder = copyInOrder
+	exportSettings.ReorderDirectories = reorderDirectories
+	exportSettings.SortDirectories = sortDirectories
+	if reorderDirectories && sortDirectories {
+		commandLineError = true
+		commandLineErrorMessage = "-reorderDirectories and -sortDirectories can not be used together\n"
+	}
+	if retries < 0 {
+		commandLineError = true
+		commandLineErrorMessage = "-retries must not be negative\n"
+	}
+	exportSettings.Retries = retries
+	exportSettings.RetryWait = retryWait
+	exportSettings.MinDuration = minDuration
+	exportSettings.MaxDuration = maxDuration
+	exportSettings.CleanOnly = cleanOnly
+	exportSettings.MinBitrate = minBitrate
+	err = export.ParseCodecs(&exportSettings, codecs)
+	if err != nil {
+		commandLineError = true
+		commandLineErrorMessage = fmt.Sprintf("%v\n", err.Error())
+	}
+	err = export.ParseAudiobooks(&exportSettings, audiobooks)
+	if err != nil {
+		commandLineError = true
+		commandLineErrorMessage = fmt.Sprintf("%v\n", err.Error())
+	}
+	if exportSettings.Audiobooks == export.AUDIOBOOKS_ONLY && copyTemplate == "" &&
+		exportSettings.CopyType != export.COPY_NONE && exportSettings.CopyType != export.COPY_STORE {
+		export.ParseCopyTemplate(&exportSettings, export.AudiobookCopyTemplate)
+	}
+	err = export.ParseVideos(&exportSettings, videos)
+	if err != nil {
+		commandLineError = true
+		commandLineErrorMessage = fmt.Sprintf("%v\n", err.Error())
+	}
+	exportSettings.Podcasts = podcasts
+	exportSettings.FeedURL = feedURL
+	if podcasts && copyTemplate == "" && exportSettings.CopyType != export.COPY_NONE && exportSettings.CopyType != export.COPY_STORE {
+		export.ParseCopyTemplate(&exportSettings, export.PodcastCopyTemplate)
+	}
+	if podcasts && exportSettings.Audiobooks == export.AUDIOBOOKS_ONLY {
+		commandLineError = true
+		commandLineErrorMessage = "-podcasts and -audiobooks ONLY can not be used together\n"
+	}
+	if precheck && exportSettings.CopyType == export.COPY_NONE && exportSettings.PlaylistSettings == nil {
+		commandLineError = true
+		commandLineErrorMessage = "-precheck requires the tracks to be copied with -copy\n"
+	}
+	if strictTemplate && exportSettings.CopyType != export.COPY_TEMPLATE && exportSettings.CopyType != export.COPY_VIDEO {
+		commandLineError = true
+		commandLineErrorMessage = "-strictTemplate requires -copyTemplate or -copy VIDEO\n"
+	}
+	if exportSettings.ExportType == export.RSS && (feedURL == "" || exportSettings.CopyType == export.COPY_NONE) {
+		commandLineError = true
+		commandLineErrorMessage = "-type RSS requires -feedURL and the tracks to be copied with -copy\n"
+	}
+	if feedURL != "" && exportSettings.ExportType != export.RSS && (!podcasts || exportSettings.CopyType == export.COPY_NONE) {
+		commandLineError = true
+		commandLineErrorMessage = "-feedURL requires -type RSS or -podcasts and the tracks to be copied with -copy\n"
+	}
+	if exportSettings.ExportType == export.BEETS && exportSettings.CopyType != export.COPY_NONE && exportSettings.PathStyle != export.PATH_STYLE_ABSOLUTE {
+		commandLineError = true
+		commandLineErrorMessage = "-type BEETS requires -pathStyle ABSOLUTE, beets queries match absolute paths\n"
+	}
+	err = export.ParseMaxFileSize(&exportSettings, maxFileSize)
+	if err != nil {
+		commandLineError = true
+		commandLineErrorMessage = fmt.Sprintf("%v\n", err.Error())
+	}
+	err = export.ParseBandwidthLimit(&exportSettings, bandwidthLimit)
+	if err != nil {
+		commandLineError = true
+		commandLineErrorMessage = fmt.Sprintf("%v\n", err.Error())
+	}
+	err = export.ParseReserve(&exportSettings, reserve)
+	if err != nil {
+		commandLineError = true
+		commandLineErrorMessage = fmt.Sprintf("%v\n", err.Error())
+	}
+	exportSettings.PlaylistVersions = playlistVersions
+	exportSettings.Snapshots = snapshots
+	exportSettings.Incremental = incremental
+	exportSettings.Validate = validate
+	exportSettings.ShortNames = shortNames
+	exportSettings.Changes = changes
+	exportSettings.Checksums = checksums
+	exportSettings.Ids = ids
+	exportSettings.Confirm = confirm
+	exportSettings.StrictTemplate = strictTemplate
+	exportSettings.Precheck = precheck
+
+	exportSettings.Events = nil
+	switch strings.ToUpper(eventsFormat) {
+	case "":
+	case "JSONL":
+		exportSettings.Events = export.NewJSONLinesEventHandler(os.Stderr)
+	default:
+		commandLineError = true
+		commandLineErrorMessage = "Unknown events format: " + eventsFormat + "\n"
+	}
+
+	if daapShare != "" && exportSettings.CopyType != export.COPY_NONE {
+		commandLineError = true
+		commandLineErrorMessage = "-copy can not be used with -daap, the music files of a DAAP share can not be copied\n"
+	}
+
+	if serveAddress != "" && exportSettings.Overwrite == export.OVERWRITE_PROMPT {
+		commandLineError = true
+		commandLineErrorMessage = "-overwrite PROMPT can not be used with -serve\n"
+	}
+
+	if logFormatType == export.LOG_FORMAT_JSON && (exportSettings.Overwrite == export.OVERWRITE_PROMPT || confirm) {
+		commandLineError = true
+		commandLineErrorMessage = "-overwrite PROMPT and -confirm can not be used with -logFormat JSON\n"
+	}
+
+	if serveAddress != "" && confirm {
+		commandLineError = true
+		commandLineErrorMessage = "-confirm can not be used with -serve\n"
+	}
+
+	var mode = ModeUnknown
+	for _, flagValue := range flags.Args() {
+		switch flagValue {
+		case "include":
+			mode = ModeInclude
+		case "exclude":
+			mode = ModeExclude
+		default:
+			switch mode {
+			case ModeUnknown:
+				commandLineError = true
+				commandLineErrorMessage = fmt.Sprintf("Unexpected paramter %v\n", flagValue)
+			case ModeInclude:
+				includePlaylistNames = append(includePlaylistNames, flagValue)
+			case ModeExclude:
+				excludePlaylistNames = append(excludePlaylistNames, flagValue)
+			}
+		}
+	}
+
+	if commandLineError {
+		fmt.Printf(UsageMessage, "itunesexport")
+		fmt.Printf(UsageErrorMessage, commandLineErrorMessage)
+		return
+	}
+
+	if libraryPath == "" && daapShare == "" {
+		libraryPath, err = defaultLibraryPath()
+		if err != nil {
+			logError(err, "%v\n", err)
+			return
+		}
+	}
+	libraryPath = filepath.Clean(libraryPath)
+
+	ctx, cancel := exportContext()
+	defer cancel()
+
+	export.Logf(export.LOG_INFO, "Include: %v, Exclude %v ", includePlaylistNames, excludePlaylistNames)
+
+	var library *itunes.Library
+	if daapShare != "" {
+		export.Logf(export.LOG_INFO, "Loading Library from DAAP share: %v\n", daapShare)
+		library, err = loadDAAPLibrary(ctx, daapShare)
+	} else {
+		export.Logf(export.LOG_INFO, "Loading Library: %v\n", libraryPath)
+		library, err = loadLibrary(ctx, libraryPath)
+		if err != nil && tolerant && ctx.Err() == nil {
+			export.Log(export.LogEntry{Level: export.LOG_WARNING, Message: fmt.Sprintf("Unable to parse library, retrying in tolerant mode: %v\n", err), Path: libraryPath, Error: err.Error()})
+			library, err = loadLibraryTolerant(ctx, libraryPath)
+		}
+	}
+	if err != nil {
+		logError(err, "%v\n", err)
+		return
+	}
+	exportSettings.Library = library
+	export.Logf(export.LOG_INFO, "Library loaded successfully with %v playlists and %v tracks.\n", len(library.Playlists), len(library.Tracks))
+
+	if musicPath != "" {
+		if musicPathOrig != "" {
+			exportSettings.OriginalMusicPath = musicPathOrig
+		} else {
+			exportSettings.OriginalMusicPath = itunes.LocationPath(library.MusicFolder)
+		}
+	}
+	exportSettings.NewMusicPath = musicPath
+	exportSettings.IncludeFolders = includeFolders
+	exportSettings.PlaylistInFolder = playlistInFolder
+
+	if serveAddress != "" {
+		// The web interface handles Ctrl-C itself by terminating.
+		cancel()
+		err = serve(serveAddress, library, exportSettings)
+		if err != nil {
+			logError(err, "Error running web interface: %v\n", err)
+		}
+		return
+	}
+
+	if listenBrainzToken != "" {
+		err = submitListens(ctx, library)
+		if err != nil {
+			logError(err, "Error submitting listens to ListenBrainz: %v\n", err)
+		}
+		return
+	}
+
+	service, serviceName, err := remoteService(ctx, library)
+	if err != nil {
+		logError(err, "%v\n", err)
+		return
+	}
+	if service != nil {
+		err = pushPlaylists(ctx, service, serviceName, library, selectPlaylists(library))
+		if err != nil {
+			logError(err, "Error pushing playlists to %v: %v\n", serviceName, err)
+		}
+		return
+	}
+
+	exportSettings.OutputPath = outputPath
+	exportSettings.Output = nil
+	exportSettings.Playlists = selectPlaylists(exportSettings.Library)
+	if exportConfig != nil {
+		exportSettings.Playlists = exportConfig.profilePlaylists(exportSettings.Playlists, profile)
+	}
+
+	if preHook != "" {
+		if err := runHook(ctx, preHook, hookEnv("pre")); err != nil {
+			logError(err, "Error running -preHook, nothing is exported: %v\n", err)
+			return
+		}
+	}
+	stats := &exportStats{handler: exportSettings.Events}
+	exportSettings.Events = stats
+
+	export.Logf(export.LOG_INFO, "Exporting %v playlists...\n", len(exportSettings.Playlists))
+	switch {
+	case strings.HasPrefix(strings.ToLower(outputPath), "sftp://"):
+		err = exportToSFTP(ctx, outputPath, library)
+	case strings.HasPrefix(strings.ToLower(outputPath), "webdav://"), strings.HasPrefix(strings.ToLower(outputPath), "webdavs://"):
+		err = exportToWebDAV(ctx, outputPath, library)
+	case strings.HasPrefix(strings.ToLower(outputPath), "s3://"):
+		err = exportToS3(ctx, outputPath, library)
+	case strings.HasPrefix(strings.ToLower(outputPath), "smb://"):
+		err = exportToSMB(ctx, outputPath, library)
+	case strings.HasPrefix(strings.ToLower(outputPath), "mtp:"):
+		err = exportToMTP(ctx, outputPath[len("mtp:"):], library)
+	case strings.HasPrefix(strings.ToLower(outputPath), "adb:"):
+		err = exportToADB(ctx, outputPath[len("adb:"):], library)
+	case strings.HasPrefix(strings.ToLower(outputPath), "rockbox:"):
+		err = exportToRockbox(ctx, outputPath[len("rockbox:"):], library)
+	case strings.EqualFold(filepath.Ext(outputPath), ".zip"):
+		err = exportToZip(ctx, outputPath, library)
+	case outputPath == "-":
+		err = exportToStdout(ctx, library)
+	default:
+		err = export.ExportPlaylistsContext(ctx, &exportSettings, library)
+	}
+	if err != nil {
+		logError(err, "Error Exporting Playlist: %v\n", err)
+	}
+	if notifyDesktop {
+		notifyResult(stats, err)
+	}
+
+	if postHook != "" {
+		// The post hook runs after a cancelled export as well, e.g. to unmount the drive.
+		if hookErr := runHook(context.Background(), postHook, append(hookEnv("post"), stats.env(err)...)); hookErr != nil {
+			logError(hookErr, "Error running -postHook: %v\n", hookErr)
+		}
+	}
+}
+
+// exportToStdout writes the single playlist selected to standard output, e.g. to pipe it
+// into another program. The music files are referenced where they are in the library.
+func exportToStdout(ctx context.Context, library *itunes.Library) error {
+	count := 0
+	for _, playlist := range exportSettings.Playlists {
+		if !playlist.Container() {
+			count++
+		}
+	}
+	if count != 1 {
+		return fmt.Errorf("-output - requires exactly one playlist, %v are selected", count)
+	}
+	if exportSettings.CopyType != export.COPY_NONE {
+		return errors.New("-output - can not copy music files")
+	}
+
+	exportSettings.Output = export.NewWriterFS(playlistOutput)
+	exportSettings.OutputPath = ""
+	// Only the playlist is written, not the files recording the export.
+	exportSettings.Incremental = false
+	exportSettings.Changes = false
+	exportSettings.Confirm = false
+	exportSettings.Checksums = false
+	exportSettings.Ids = false
+	exportSettings.ShortNames = false
+	exportSettings.Snapshots = false
+	return export.ExportPlaylistsContext(ctx, &exportSettings, library)
+}
+
+// exportToZip writes the export into the zip archive at archivePath. Copied music files
+// are referenced relative to the root of the archive.
+func exportToZip(ctx context.Context, archivePath string, library *itunes.Library) error {
+	archive, err := os.Create(archivePath)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	zipFS := export.NewZipFS(archive)
+	exportSettings.Output = zipFS
+	exportSettings.OutputPath = ""
+
+	if err := export.ExportPlaylistsContext(ctx, &exportSettings, library); err != nil {
+		return err
+	}
+	if err := zipFS.Close(); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+// exportToSFTP writes the export to the directory of an sftp:// URL. Copied music files are
+// referenced by their path on the server, or relative to the export directory if it is
+// relative to the home directory.
+func exportToSFTP(ctx context.Context, target string, library *itunes.Library) error {
+	targetURL, err := url.Parse(target)
+	if err != nil {
+		return err
+	}
+
+	sftpFS, err := export.NewSFTPFS(targetURL)
+	if err != nil {
+		return err
+	}
+	defer sftpFS.Close()
+
+	exportSettings.Output = sftpFS
+	exportSettings.OutputPath = targetURL.Path
+	if strings.HasPrefix(targetURL.Path, "/~") {
+		exportSettings.OutputPath = ""
+	}
+
+	if err := export.ExportPlaylistsContext(ctx, &exportSettings, library); err != nil {
+		return err
+	}
+	return sftpFS.Close()
+}
+
+// exportToWebDAV writes the export to the collection of a webdav:// or webdavs:// URL. Copied
+// music files are referenced relative to the collection.
+func exportToWebDAV(ctx context.Context, target string, library *itunes.Library) error {
+	targetURL, err := url.Parse(target)
+	if err != nil {
+		return err
+	}
+
+	webDAVFS, err := export.NewWebDAVFS(targetURL)
+	if err != nil {
+		return err
+	}
+
+	exportSettings.Output = webDAVFS
+	exportSettings.OutputPath = ""
+	return export.ExportPlaylistsContext(ctx, &exportSettings, library)
+}
+
+// exportToS3 writes the export below the prefix of an s3://bucket/prefix URL. Copied music
+// files are referenced relative to the prefix.
+func exportToS3(ctx context.Context, target string, library *itunes.Library) error {
+	targetURL, err := url.Parse(target)
+	if err != nil {
+		return err
+	}
+
+	s3FS, err := export.NewS3FS(targetURL, s3Endpoint)
+	if err != nil {
+		return err
+	}
+
+	exportSettings.Output = s3FS
+	exportSettings.OutputPath = ""
+	return export.ExportPlaylistsContext(ctx, &exportSettings, library)
+}
+
+// exportToSMB writes the export to the directory of an smb:// URL. Copied music files are
+// referenced relative to the directory.
+func exportToSMB(ctx context.Context, target string, library *itunes.Library) error {
+	targetURL, err := url.Parse(target)
+	if err != nil {
+		return err
+	}
+
+	smbFS, err := export.NewSMBFS(targetURL)
+	if err != nil {
+		return err
+	}
+	defer smbFS.Close()
+
+	exportSettings.Output = smbFS
+	exportSettings.OutputPath = ""
+
+	if err := export.ExportPlaylistsContext(ctx, &exportSettings, library); err != nil {
+		return err
+	}
+	return smbFS.Close()
+}
+
+// exportToMTP copies the playlists and their music to the Music folder of the attached MTP
+// device whose name contains deviceName. The playlists reference the music relative to the
+// Music folder, which the players of the device understand.
+func exportToMTP(ctx context.Context, deviceName string, library *itunes.Library) error {
+	devices, err := export.FindMTPDevices()
+	if err != nil {
+		return err
+	}
+
+	var matching []export.MTPDevice
+	for _, device := range devices {
+		if strings.Contains(strings.ToLower(device.Name), strings.ToLower(deviceName)) {
+			matching = append(matching, device)
+		}
+	}
+	switch len(matching) {
+	case 0:
+		return errors.New("no MTP device found, connect and unlock the device and allow file transfer")
+	case 1:
+	default:
+		names := make([]string, len(matching))
+		for i, device := range matching {
+			names[i] = device.Name
+		}
+		return errors.New("several MTP devices found, choose one with -output mtp:<device name>: " + strings.Join(names, ", "))
+	}
+
+	musicFolder, err := matching[0].MusicFolder()
+	if err != nil {
+		return err
+	}
+	export.Log(export.LogEntry{Level: export.LOG_INFO, Message: fmt.Sprintf("Copying to %v on %v\n", musicFolder, matching[0].Name), Path: musicFolder})
+
+	if exportSettings.CopyType == export.COPY_NONE {
+		exportSettings.CopyType = export.COPY_PLAYLIST
+	}
+	// The device mounts the storage elsewhere, so the playlists refer to the music relative
+	// to themselves.
+	exportSettings.PathStyle = export.PATH_STYLE_RELATIVE
+	exportSettings.Output = export.NewMTPFS(musicFolder)
+	exportSettings.OutputPath = ""
+	return export.ExportPlaylistsContext(ctx, &exportSettings, library)
+}
+
+// exportToADB pushes the playlists and their music to the directory devicePath of an Android
+// device using adb. Unless -overwrite is given, music files already on the device are only
+// pushed again if their size differs.
+func exportToADB(ctx context.Context, devicePath string, library *itunes.Library) error {
+	if devicePath == "" {
+		return errors.New("missing device path, e.g. -output adb:/sdcard/Music")
+	}
+	adb, err := export.FindADB()
+	if err != nil {
+		return err
+	}
+
+	if exportSettings.CopyType == export.COPY_NONE {
+		exportSettings.CopyType = export.COPY_PLAYLIST
+	}
+	if overwrite == "" {
+		exportSettings.Overwrite = export.OVERWRITE_IF_SIZE_DIFFERS
+	}
+	exportSettings.Output = export.NewADBFS(adb, devicePath)
+	exportSettings.OutputPath = ""
+	return export.ExportPlaylistsContext(ctx, &exportSettings, library)
+}
+
+// exportToRockbox copies the music to the Music folder of the Rockbox device mounted at root,
+// by artist and album unless -copy is given, and writes M3U8 playlists unless -type is given
+// to its Playlists folder, referencing the music by its path on the device.
+func exportToRockbox(ctx context.Context, root string, library *itunes.Library) error {
+	if err := export.CheckRockbox(root); err != nil {
+		return err
+	}
+
+	if exportSettings.CopyType == export.COPY_NONE {
+		exportSettings.CopyType = export.COPY_ITUNES
+	}
+	if exportSettings.ExportType == export.M3U {
+		export.ParseExportType(&exportSettings, export.M3U8)
+	}
+	exportSettings.Output = export.NewDirFS(root)
+	exportSettings.OutputPath = "/"
+	exportSettings.MusicDir = export.RockboxMusicDir
+	exportSettings.PlaylistDir = export.RockboxPlaylistDir
+	if err := export.ExportPlaylistsContext(ctx, &exportSettings, library); err != nil {
+		return err
+	}
+
+	if rockboxDatabase {
+		if err := export.EnableRockboxDatabaseUpdate(root); err != nil {
+			return err
+		}
+		export.Logf(export.LOG_INFO, "The Rockbox database will be updated when the device starts.\n")
+	}
+	return nil
+}
+
+// logError writes a message about err at error level.
+func logError(err error, format string, args ...interface{}) {
+	export.Log(export.LogEntry{Level: export.LOG_ERROR, Message: fmt.Sprintf(format, args...), Error: err.Error()})
+}
+
+// exportContext returns the context for loading and exporting. It is cancelled on Ctrl-C
+// and, if -timeout is set, once the timeout has expired.
+func exportContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	go func() {
+		select {
+		case <-signals:
+			export.Logf(export.LOG_WARNING, "\nInterrupted, stopping...\n")
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(signals)
+	}()
+	return ctx, cancel
+}
+
+// maxRepairsShown limits the number of repairs listed after a library was loaded in tolerant mode.
+const maxRepairsShown = 20
+
+// loadLibraryTolerant loads the library in tolerant mode and lists the repairs made.
+func loadLibraryTolerant(ctx context.Context, libraryPath string) (*itunes.Library, error) {
+	library, repairs, err := itunes.LoadLibraryTolerant(ctx, libraryPath)
+	for i, repair := range repairs {
+		if i == maxRepairsShown {
+			export.Logf(export.LOG_WARNING, "... and %v more repairs\n", len(repairs)-maxRepairsShown)
+			break
+		}
+		export.Logf(export.LOG_WARNING, "Repaired library %v\n", repair)
+	}
+	return library, err
+}
+
+// loadLibrary loads the library, using the library cache unless disabled.
+// If only some playlists are selected by name or regular expression and the library is
+// not cached, only the tracks of the selected playlists are loaded. Such a partial library
+// is not written to the cache. -cleanOnly needs all tracks, as the clean versions of the
+// explicit tracks may be part of other playlists.
+func loadLibrary(ctx context.Context, libraryPath string) (*itunes.Library, error) {
+	partial := serveAddress == "" && !includeAllPlaylists && !includeAllWithBuiltinPlaylists && randomFillSize == 0 && !folderPlaylists && len(combinations) == 0 &&
+		!cleanOnly
+
+	cacheDir := ""
+	if !noCache {
+		var err error
+		cacheDir, err = itunes.DefaultCacheDir()
+		if err != nil {
+			export.Log(export.LogEntry{Level: export.LOG_WARNING, Message: fmt.Sprintf("Unable to determine cache directory, not using library cache: %v\n", err), Error: err.Error()})
+		}
+	}
+
+	switch {
+	case partial && cacheDir != "":
+		if library, err := itunes.CachedLibrary(libraryPath, cacheDir); err == nil {
+			return library, nil
+		}
+		return itunes.LoadLibraryForPlaylistsContext(ctx, libraryPath, parsePlaylists)
+	case partial:
+		return itunes.LoadLibraryForPlaylistsContext(ctx, libraryPath, parsePlaylists)
+	case cacheDir != "":
+		return itunes.LoadLibraryCachedContext(ctx, libraryPath, cacheDir)
+	default:
+		return itunes.LoadLibraryContext(ctx, libraryPath)
+	}
+}
+
+// selectPlaylists returns the playlists selected on the command line followed by the playlists
+// of their folders, the auto and artist playlists generated from their tracks, the combined
+// playlists and the random fill playlist.
+// For a random fill without selected playlists, the auto and artist playlists are generated
+// from the whole library, so they can be sampled from, but are not exported.
+// With -audiobooks ONLY or -podcasts, a playlist for every audiobook or podcast show of the
+// selected playlists is returned instead.
+func selectPlaylists(library *itunes.Library) []itunes.Playlist {
+	playlists := parsePlaylists(library)
+	selected := playlists
+	if folderPlaylists {
+		playlists = append(playlists, itunes.FolderPlaylists(library, selected)...)
+	}
+	if exportSettings.Audiobooks == export.AUDIOBOOKS_ONLY {
+		// The audiobooks of the selected playlists are exported with a playlist per book.
+		return itunes.AudiobookPlaylists(library, selected)
+	}
+	if exportSettings.Podcasts {
+		return itunes.PodcastPlaylists(library, selected)
+	}
+	source := selected
+	if randomFillSize > 0 && len(selected) == 0 {
+		source = library.Playlists
+	}
+	var generated []itunes.Playlist
+	if len(autoPlaylistKinds) > 0 {
+		generated = append(generated, itunes.AutoPlaylists(library, source, autoPlaylistKinds)...)
+	}
+	if artistPlaylistOptions != nil {
+		generated = append(generated, itunes.ArtistPlaylists(library, source, *artistPlaylistOptions)...)
+	}
+	if len(selected) > 0 {
+		playlists = append(playlists, generated...)
+	}
+	if len(combinations) > 0 {
+		combined, missing := itunes.Combine(library, combinations)
+		for _, name := range missing {
+			export.Log(export.LogEntry{Level: export.LOG_WARNING, Message: fmt.Sprintf("Unable to find matching playlist for name: %q. Combining it as empty playlist.\n", name), Playlist: name})
+		}
+		playlists = append(playlists, combined...)
+	}
+	if randomFillSize > 0 {
+		playlists = append(playlists, randomFillPlaylist(library))
+	}
+	return playlists
+}
+
+// randomFillPlaylist returns a playlist of tracks sampled at random from the playlists named
+// by -from, filling the -randomFill size. Without -seed, every run samples different tracks.
+func randomFillPlaylist(library *itunes.Library) itunes.Playlist {
+	var source []itunes.Playlist
+	for _, name := range strings.Split(randomFillFrom, ",") {
+		name = strings.TrimSpace(name)
+		if playlist, ok := library.PlaylistMap[name]; ok {
+			source = append(source, playlist)
+		} else {
+			export.Log(export.LogEntry{Level: export.LOG_WARNING, Message: fmt.Sprintf("Unable to find matching playlist for name: %q. Not sampling from it.\n", name), Playlist: name})
+		}
+	}
+	seed := randomFillSeed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	playlist := itunes.RandomFill(library, source, randomFillSize, seed, exportSettings.IncludesTrack)
+	export.Log(export.LogEntry{Level: export.LOG_INFO, Message: fmt.Sprintf("Filled %v with %v tracks at random using -seed %v.\n", itunes.RandomFillName, len(playlist.PlaylistItems), seed), Playlist: itunes.RandomFillName})
+	return playlist
+}
+
+func parsePlaylists(library *itunes.Library) []itunes.Playlist {
+	var playlists []itunes.Playlist
+
+	if includeAllPlaylists {
+		for _, playlist := range library.Playlists {
+			if playlist.DistinguishedKind == 0 && playlist.Name != "Library" || containsKind(includeKinds, playlist.DistinguishedKind) {
+				playlists = append(playlists, playlist)
+			}
+		}
+	} else if includeAllWithBuiltinPlaylists {
+		playlists = library.Playlists
+	} else if len(includePlaylistWithRegex) > 0 {
+		for _, playlist := range library.Playlists {
+			match, _ := regexp.MatchString(includePlaylistWithRegex, playlist.Name)
+			if match {
+				playlists = append(playlists, playlist)
+			}
+		}
+	} else if len(includePlaylistNames) > 0 || includePlaylistIds != "" {
+		for _, playlistName := range includePlaylistNames {
+			playlist, ok := library.PlaylistMap[playlistName]
+			if ok {
+				playlists = append(playlists, playlist)
+			} else {
+				export.Log(export.LogEntry{Level: export.LOG_WARNING, Message: fmt.Sprintf("Unable to find matching playlist for name: %q. Skipping Playlist.\n", playlistName), Playlist: playlistName})
+			}
+		}
+		for _, playlistId := range strings.Split(includePlaylistIds, ",") {
+			playlistId = strings.ToUpper(strings.TrimSpace(playlistId))
+			if playlistId == "" {
+				continue
+			}
+			playlist, ok := library.PlaylistIdMap[playlistId]
+			if ok {
+				playlists = append(playlists, playlist)
+			} else {
+				export.Logf(export.LOG_WARNING, "Unable to find matching playlist for persistent id: %v. Skipping Playlist.\n", playlistId)
+			}
+		}
+	} else if len(includeKinds) > 0 {
+		for _, playlist := range library.Playlists {
+			if containsKind(includeKinds, playlist.DistinguishedKind) {
+				playlists = append(playlists, playlist)
+			}
+		}
+	}
+
+	var filteredPlaylists []itunes.Playlist
+	for _, playlist := range playlists {
+		remove := containsKind(excludeKinds, playlist.DistinguishedKind)
+		for _, removePlaylistName := range excludePlaylistNames {
+			if playlist.Name == removePlaylistName {
+				remove = true
+				break
+			}
+		}
+		if !remove {
+			filteredPlaylists = append(filteredPlaylists, playlist)
+		}
+	}
+
+	return filteredPlaylists
+
+}
+
+// containsKind reports whether kinds contains the distinguished playlist kind.
+func containsKind(kinds []int, kind int) bool {
+	for _, k := range kinds {
+		if k == kind && kind != itunes.KIND_NONE {
+			return true
+		}
+	}
+	return false
+}
@@ -127,7 +127,7 @@ func prepareMusicFile(t *testing.T) (string, string) {
 }
 
 func prepareItunesDbFile(t *testing.T, musicFilePath string) string {
-	itunesDbContent := readFile(t, "fixture/example-itunes-db.xml")
+	itunesDbContent := readFile(t, "../../fixture/example-itunes-db.xml")
 	itunesDbContentAdjusted := strings.ReplaceAll(string(itunesDbContent), "REPLACE_ME_EXAMPLE_SONG_LOCATION", "file://"+musicFilePath)
 
 	itunesDbFile := createTempFile(t, "testItunesDb_*.xml")
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/export"
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+func TestIncludeAllPlaylists(t *testing.T) {
+	resetGlobalVars()
+
+	library := &itunes.Library{
+		Playlists: []itunes.Playlist{
+			{Name: "Foo"},
+			{Name: "Bar"},
+			{Name: "Library", DistinguishedKind: 0},
+		},
+	}
+
+	includeAllPlaylists = true
+	playlists := parsePlaylists(library)
+
+	if len(playlists) != 2 {
+		t.Fatal("wrong playlist size")
+	}
+	if !(playlists[0].Name == "Foo" && playlists[1].Name == "Bar") {
+		t.Fatal("Unexpected playlist names")
+	}
+}
+
+func TestIncludeAllWinBuiltinPlaylists(t *testing.T) {
+	resetGlobalVars()
+
+	library := &itunes.Library{
+		Playlists: []itunes.Playlist{
+			{Name: "Foo"},
+			{Name: "Bar"},
+			{Name: "Library", DistinguishedKind: 0},
+		},
+	}
+
+	includeAllWithBuiltinPlaylists = true
+	playlists := parsePlaylists(library)
+
+	if len(playlists) != 3 {
+		t.Fatal("wrong playlist size")
+	}
+}
+
+func TestIncludePlaylistNames(t *testing.T) {
+	resetGlobalVars()
+
+	library := &itunes.Library{
+		PlaylistMap: map[string]itunes.Playlist{
+			"Foo": {Name: "Foo"},
+			"Bar": {Name: "Bar"},
+		},
+	}
+
+	includePlaylistNames = []string{"Bar"}
+	playlists := parsePlaylists(library)
+
+	if len(playlists) != 1 && playlists[0].Name != "Bar" {
+		t.Fatal("unexpected playlist")
+	}
+}
+
+func TestIncludePlaylistIds(t *testing.T) {
+	resetGlobalVars()
+	defer resetGlobalVars()
+
+	library := &itunes.Library{
+		Playlists: []itunes.Playlist{
+			{Name: "Foo", PlaylistPersistentId: "F00F00F00F00F00F"},
+			{Name: "Renamed", PlaylistPersistentId: "BA9D3C2EAB361B84"},
+		},
+	}
+	library.BuildPlaylistMaps()
+
+	includePlaylistIds = "ba9d3c2eab361b84, 0000000000000000"
+	playlists := parsePlaylists(library)
+	if len(playlists) != 1 || playlists[0].Name != "Renamed" {
+		t.Fatalf("unexpected playlists %v", playlists)
+	}
+}
+
+func TestPlaylistViaRegex(t *testing.T) {
+	resetGlobalVars()
+
+	library := &itunes.Library{
+		Playlists: []itunes.Playlist{
+			{Name: "Foo"},
+			{Name: "Bar"},
+			{Name: "Buzz"},
+		},
+	}
+
+	includePlaylistWithRegex = "^B+"
+	playlists := parsePlaylists(library)
+
+	if len(playlists) != 2 {
+		t.Fatal("unexpected playlist size")
+	}
+	if playlists[0].Name != "Bar" && playlists[1].Name != "Buzz" {
+		t.Fatal("unexpected playlists")
+	}
+}
+
+func TestExcludePlaylists(t *testing.T) {
+	resetGlobalVars()
+
+	library := &itunes.Library{
+		Playlists: []itunes.Playlist{
+			{Name: "Foo"},
+			{Name: "Bar"},
+			{Name: "Library", DistinguishedKind: 0},
+		},
+	}
+
+	includeAllPlaylists = true
+	excludePlaylistNames = []string{"Bar"}
+	playlists := parsePlaylists(library)
+
+	if len(playlists) != 1 {
+		t.Fatal("wrong playlist size")
+	}
+	if !(playlists[0].Name == "Foo") {
+		t.Fatal("Unexpected playlist names")
+	}
+}
+
+func resetGlobalVars() {
+	includeAllPlaylists = false
+	includeAllWithBuiltinPlaylists = false
+	includePlaylistNames = []string{}
+	includePlaylistIds = ""
+	includePlaylistWithRegex = ""
+	excludePlaylistNames = nil
+	includeKinds = nil
+	excludeKinds = nil
+	autoPlaylistKinds = nil
+	artistPlaylistOptions = nil
+	folderPlaylists = false
+	randomFillSize = 0
+	randomFillFrom = ""
+	randomFillSeed = 0
+	combinations = nil
+	exportSettings.Audiobooks = export.AUDIOBOOKS_EXCLUDE
+	exportSettings.Podcasts = false
+}
+
+func TestPlaylistKinds(t *testing.T) {
+	resetGlobalVars()
+
+	library := &itunes.Library{
+		Playlists: []itunes.Playlist{
+			{Name: "Foo"},
+			{Name: "Genius", DistinguishedKind: itunes.KIND_GENIUS},
+			{Name: "Purchased", DistinguishedKind: itunes.KIND_PURCHASED},
+		},
+	}
+
+	includeAllPlaylists = true
+	includeKinds = []int{itunes.KIND_GENIUS}
+	playlists := parsePlaylists(library)
+	if len(playlists) != 2 || playlists[0].Name != "Foo" || playlists[1].Name != "Genius" {
+		t.Fatalf("unexpected playlists %v", playlists)
+	}
+
+	resetGlobalVars()
+	includeKinds = []int{itunes.KIND_PURCHASED}
+	playlists = parsePlaylists(library)
+	if len(playlists) != 1 || playlists[0].Name != "Purchased" {
+		t.Fatalf("unexpected playlists %v", playlists)
+	}
+
+	resetGlobalVars()
+	includeAllWithBuiltinPlaylists = true
+	excludeKinds = []int{itunes.KIND_GENIUS, itunes.KIND_PURCHASED}
+	playlists = parsePlaylists(library)
+	if len(playlists) != 1 || playlists[0].Name != "Foo" {
+		t.Fatalf("unexpected playlists %v", playlists)
+	}
+}
+
+func TestSelectPlaylistsWithAutoPlaylists(t *testing.T) {
+	resetGlobalVars()
+
+	newLibrary := func() *itunes.Library {
+		library := &itunes.Library{
+			Tracks: map[string]itunes.Track{
+				"1": {TrackId: 1, Name: "Song", Artist: "Unknown", Genre: "Jazz"},
+			},
+			Playlists: []itunes.Playlist{
+				{Name: "Foo", PlaylistId: 1, PlaylistPersistentId: "F00", PlaylistItems: []itunes.PlaylistItem{{TrackId: 1}}},
+			},
+		}
+		library.BuildPlaylistMaps()
+		return library
+	}
+
+	includeAllPlaylists = true
+	autoPlaylistKinds = []string{"GENRE"}
+	playlists := selectPlaylists(newLibrary())
+	if len(playlists) != 3 || playlists[0].Name != "Foo" || playlists[1].Name != "Genres" || playlists[2].Name != "Jazz" {
+		t.Fatalf("unexpected playlists %v", playlists)
+	}
+
+	autoPlaylistKinds = nil
+	artistPlaylistOptions = &itunes.ArtistPlaylistOptions{MinTracks: 1}
+	playlists = selectPlaylists(newLibrary())
+	if len(playlists) != 3 || playlists[1].Name != "Artists" || playlists[2].Name != "Unknown" {
+		t.Fatalf("unexpected playlists %v", playlists)
+	}
+}
+
+func TestSelectPlaylistsWithRandomFill(t *testing.T) {
+	resetGlobalVars()
+	defer resetGlobalVars()
+
+	library := &itunes.Library{
+		Tracks: map[string]itunes.Track{
+			"1": {TrackId: 1, Name: "Song", Size: 4 << 20, Rating: 100},
+			"2": {TrackId: 2, Name: "Other Song", Size: 4 << 20, Rating: 60},
+		},
+		Playlists: []itunes.Playlist{
+			{Name: "Library", Master: true, PlaylistId: 1, PlaylistPersistentId: "A11", PlaylistItems: []itunes.PlaylistItem{{TrackId: 1}, {TrackId: 2}}},
+		},
+	}
+	library.BuildPlaylistMaps()
+
+	autoPlaylistKinds = []string{"RATING"}
+	randomFillSize = 6 << 20
+	randomFillFrom = "5 Star"
+	randomFillSeed = 1
+	playlists := selectPlaylists(library)
+	if len(playlists) != 1 || playlists[0].Name != itunes.RandomFillName {
+		t.Fatalf("expected only the random fill playlist, got %v", playlists)
+	}
+	if items := playlists[0].PlaylistItems; len(items) != 1 || items[0].TrackId != 1 {
+		t.Fatalf("expected the 5 star track, got %v", items)
+	}
+}
+
+func TestSelectPlaylistsWithAudiobooks(t *testing.T) {
+	resetGlobalVars()
+	defer resetGlobalVars()
+
+	library := &itunes.Library{
+		Tracks: map[string]itunes.Track{
+			"1": {TrackId: 1, Name: "Song", Location: "file:///Music/Song.mp3"},
+			"2": {TrackId: 2, Name: "Part 2", Album: "Dune", TrackNumber: 2, Location: "file:///Books/Dune%202.m4b"},
+			"3": {TrackId: 3, Name: "Part 1", Album: "Dune", TrackNumber: 1, Location: "file:///Books/Dune%201.m4b"},
+		},
+		Playlists: []itunes.Playlist{
+			{Name: "Foo", PlaylistId: 1, PlaylistPersistentId: "F00", PlaylistItems: []itunes.PlaylistItem{{TrackId: 1}, {TrackId: 2}, {TrackId: 3}}},
+		},
+	}
+	library.BuildPlaylistMaps()
+
+	includeAllPlaylists = true
+	exportSettings.Audiobooks = export.AUDIOBOOKS_ONLY
+	playlists := selectPlaylists(library)
+	if len(playlists) != 2 || playlists[0].Name != "Audiobooks" || playlists[1].Name != "Dune" {
+		t.Fatalf("expected only a playlist for the book, got %v", playlists)
+	}
+	if items := playlists[1].PlaylistItems; len(items) != 2 || items[0].TrackId != 3 || items[1].TrackId != 2 {
+		t.Fatalf("expected the parts of the book in order, got %v", items)
+	}
+}
+
+func TestSelectPlaylistsWithPodcasts(t *testing.T) {
+	resetGlobalVars()
+	defer resetGlobalVars()
+
+	library := &itunes.Library{
+		Tracks: map[string]itunes.Track{
+			"1": {TrackId: 1, Name: "Song"},
+			"2": {TrackId: 2, Name: "Pilot", Album: "The Show", Podcast: true},
+		},
+		Playlists: []itunes.Playlist{
+			{Name: "Podcasts", PlaylistId: 1, PlaylistPersistentId: "F00", PlaylistItems: []itunes.PlaylistItem{{TrackId: 1}, {TrackId: 2}}},
+		},
+	}
+	library.BuildPlaylistMaps()
+
+	includeAllPlaylists = true
+	exportSettings.Podcasts = true
+	playlists := selectPlaylists(library)
+	if len(playlists) != 2 || playlists[1].Name != "The Show" || len(playlists[1].PlaylistItems) != 1 {
+		t.Fatalf("expected only a playlist for the show, got %v", playlists)
+	}
+}
+
+func TestCleanOnlyWithSelectedPlaylist(t *testing.T) {
+	resetGlobalVars()
+	defer resetGlobalVars()
+	outputDir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(outputDir)
+
+	// The clean version is only part of another playlist than the one exported.
+	library := &itunes.Library{
+		Tracks: map[string]itunes.Track{
+			"1": {TrackId: 1, Name: "Song", Artist: "Artist", Explicit: true, Location: "file:///music/explicit.mp3"},
+			"2": {TrackId: 2, Name: "Song (Clean)", Artist: "Artist", Clean: true, Location: "file:///music/clean.mp3"},
+		},
+		Playlists: []itunes.Playlist{
+			{Name: "P", PlaylistPersistentId: "AAAAAAAAAAAAAAA1", PlaylistItems: []itunes.PlaylistItem{{TrackId: 1}}},
+			{Name: "Clean", PlaylistPersistentId: "AAAAAAAAAAAAAAA2", PlaylistItems: []itunes.PlaylistItem{{TrackId: 2}}},
+		},
+	}
+	libraryFile := filepath.Join(outputDir, "Library.xml")
+	file, err := os.Create(libraryFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = itunes.WriteLibrary(file, library)
+	file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	realArgs := os.Args
+	defer func() { os.Args = realArgs }()
+	os.Args = []string{"itunesexport", "-library", libraryFile, "-output", outputDir, "-noCache", "-cleanOnly", "include", "P"}
+	main()
+
+	if content := readFile(t, filepath.Join(outputDir, "P.m3u")); !strings.Contains(content, "clean.mp3") {
+		t.Fatalf("expected the clean version in place of the explicit track, got %q", content)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// notifyResult shows a desktop notification summarizing the export.
+func notifyResult(stats *exportStats, exportErr error) {
+	if err := notify("iTunes Export", stats.summary(exportErr)); err != nil {
+		logError(err, "Unable to show the notification: %v\n", err)
+	}
+}
+
+// summary returns a one line summary of the export for a notification.
+func (stats *exportStats) summary(exportErr error) string {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+
+	if exportErr != nil {
+		return fmt.Sprintf("Export failed after %v playlists: %v", stats.playlistsWritten, exportErr)
+	}
+	summary := fmt.Sprintf("Exported %v playlists", stats.playlistsWritten)
+	if stats.filesCopied > 0 {
+		summary += fmt.Sprintf(", copied %v files (%.1f MB)", stats.filesCopied, float64(stats.bytesCopied)/(1024*1024))
+	}
+	if stats.errors > 0 {
+		summary += fmt.Sprintf(", %v errors", stats.errors)
+	}
+	return summary + "."
+}
+
+// appleScriptString returns s as an AppleScript string literal.
+func appleScriptString(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	return `"` + strings.Replace(s, `"`, `\"`, -1) + `"`
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/export"
+)
+
+func TestExportSummary(t *testing.T) {
+	stats := &exportStats{}
+	if summary := stats.summary(nil); summary != "Exported 0 playlists." {
+		t.Fatalf("unexpected summary %q", summary)
+	}
+
+	stats.HandleEvent(export.Event{Type: export.EVENT_PLAYLIST_WRITTEN})
+	stats.HandleEvent(export.Event{Type: export.EVENT_FILE_FINISHED, Bytes: 3 * 1024 * 1024})
+	stats.HandleEvent(export.Event{Type: export.EVENT_ERROR})
+	if summary := stats.summary(nil); summary != "Exported 1 playlists, copied 1 files (3.0 MB), 1 errors." {
+		t.Fatalf("unexpected summary %q", summary)
+	}
+	if summary := stats.summary(errors.New("disk full")); summary != "Export failed after 1 playlists: disk full" {
+		t.Fatalf("unexpected summary %q", summary)
+	}
+}
+
+func TestAppleScriptString(t *testing.T) {
+	if s := appleScriptString(`Say "Hi" \o/`); s != `"Say \"Hi\" \\o/"` {
+		t.Fatalf("unexpected AppleScript string %v", s)
+	}
+}
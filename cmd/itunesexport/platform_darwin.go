@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+func defaultLibraryPath() (string, error) {
+	return fmt.Sprintf("/Users/%v/Music/iTunes/iTunes Music Library.xml", os.Getenv("USER")), nil
+}
+
+// notify shows a notification in the Notification Center.
+func notify(title, message string) error {
+	script := fmt.Sprintf("display notification %v with title %v", appleScriptString(message), appleScriptString(title))
+	return exec.Command("osascript", "-e", script).Run()
+}
+
+// serviceLabel returns the launchd label of the service name.
+func serviceLabel(name string) string {
+	return "com.ericdaugherty." + name
+}
+
+// servicePlistPath returns the path of the LaunchAgent of the service name.
+func servicePlistPath(name string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "Library", "LaunchAgents", serviceLabel(name)+".plist"), nil
+}
+
+// installService writes a LaunchAgent running the executable with args and loads it. The
+// output of the exports is written to ~/Library/Logs.
+func installService(name string, args []string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	plistPath, err := servicePlistPath(name)
+	if err != nil {
+		return err
+	}
+	data, err := launchdPlist(serviceLabel(name), executable, args, filepath.Join(home, "Library", "Logs", name+".log"))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(plistPath), 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(plistPath, data, 0644); err != nil {
+		return err
+	}
+	return launchctl("load", "-w", plistPath)
+}
+
+// uninstallService unloads the LaunchAgent of the service name and removes it.
+func uninstallService(name string) error {
+	plistPath, err := servicePlistPath(name)
+	if err != nil {
+		return err
+	}
+	if err := launchctl("unload", "-w", plistPath); err != nil {
+		return err
+	}
+	return os.Remove(plistPath)
+}
+
+// launchctl runs launchctl with args.
+func launchctl(args ...string) error {
+	output, err := exec.Command("launchctl", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("launchctl %v: %v: %v", strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// runAsService runs run until launchd stops the job.
+func runAsService(name string, run func(ctx context.Context)) error {
+	runUntilStopped(run)
+	return nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// as iTunes does not nativly run under Linux, 
+// we assume the drive was mounted to this path
+const DefaultLinuxDrive = "/mnt/itunes"
+
+func defaultLibraryPath() (string, error) {
+	return defaultLibraryPathInternal(execCmd)
+}
+
+
+func defaultLibraryPathInternal(cmdExecFunc func(command string) (string, error)) (string, error) {
+	if (os.Getenv("WSLENV") != "") {
+		return determineWslDefaultLibraryPath(cmdExecFunc)
+	} else {
+		return DefaultLinuxDrive, nil
+	}
+}
+
+func determineWslDefaultLibraryPath(execCmdFunc func(command string) (string, error)) (string, error) {
+	homeDrive, err := execCmdFunc("echo %HOMEDRIVE%")
+	if err != nil {
+		return "", err
+	}
+	homeDrive = strings.ToLower(strings.TrimRight(homeDrive, ":"))
+
+	homePath, err := execCmdFunc("echo %HOMEPATH%")
+	if err != nil {
+		return "", err
+	}
+	homePath = strings.ReplaceAll(homePath, "\\", "/")
+
+	return fmt.Sprintf("/mnt/%v%v/Music/iTunes/iTunes Music Library.xml", homeDrive, homePath), nil
+}
+
+func execCmd(command string) (string, error) {
+	result, err := exec.Command("cmd.exe", "/c", command).Output()
+	if (err != nil) {
+		return "", err;
+	}
+	return strings.TrimSpace(string(result)), nil
+}
+
+// notify shows a notification with libnotify.
+func notify(title, message string) error {
+	return exec.Command("notify-send", "--app-name=iTunes Export", title, message).Run()
+}
+
+// serviceUnitPath returns the path of the systemd user unit of the service name.
+func serviceUnitPath(name string) (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "systemd", "user", name+".service"), nil
+}
+
+// installService writes a systemd user unit running the executable with args, then enables
+// and starts it.
+func installService(name string, args []string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	unitPath, err := serviceUnitPath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(unitPath), 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(unitPath, []byte(systemdUnit(executable, args)), 0644); err != nil {
+		return err
+	}
+	if err := systemctl("daemon-reload"); err != nil {
+		return err
+	}
+	return systemctl("enable", "--now", name+".service")
+}
+
+// uninstallService stops and disables the systemd user unit of the service name and
+// removes it.
+func uninstallService(name string) error {
+	unitPath, err := serviceUnitPath(name)
+	if err != nil {
+		return err
+	}
+	if err := systemctl("disable", "--now", name+".service"); err != nil {
+		return err
+	}
+	if err := os.Remove(unitPath); err != nil {
+		return err
+	}
+	return systemctl("daemon-reload")
+}
+
+// systemctl runs systemctl for the units of the user.
+func systemctl(args ...string) error {
+	output, err := exec.Command("systemctl", append([]string{"--user"}, args...)...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("systemctl %v: %v: %v", strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// runAsService runs run until systemd stops the service.
+func runAsService(name string, run func(ctx context.Context)) error {
+	runUntilStopped(run)
+	return nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+func defaultLibraryPath() (string, error) {
+	return fmt.Sprintf("%v%v\\Music\\iTunes\\iTunes Music Library.xml", os.Getenv("HOMEDRIVE"), os.Getenv("HOMEPATH")), nil
+}
+
+// toastScript shows a toast notification with the title and message of the environment,
+// which avoids quoting them for PowerShell.
+const toastScript = `
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+$template = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $template.GetElementsByTagName('text')
+$text.Item(0).AppendChild($template.CreateTextNode($env:ITUNESEXPORT_TITLE)) > $null
+$text.Item(1).AppendChild($template.CreateTextNode($env:ITUNESEXPORT_MESSAGE)) > $null
+$app = '{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\WindowsPowerShell\v1.0\powershell.exe'
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier($app).Show([Windows.UI.Notifications.ToastNotification]::new($template))
+`
+
+// notify shows a toast notification.
+func notify(title, message string) error {
+	cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", toastScript)
+	cmd.Env = append(os.Environ(), "ITUNESEXPORT_TITLE="+title, "ITUNESEXPORT_MESSAGE="+message)
+	return cmd.Run()
+}
+
+// installService registers a service of the service manager running the executable with
+// args when Windows starts, and starts it. The Event Log source used by -syslog is
+// registered as well. Both require administrator rights.
+func installService(name string, args []string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	manager, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer manager.Disconnect()
+
+	service, err := manager.CreateService(name, executable, mgr.Config{
+		DisplayName: "iTunes Export",
+		Description: "Exports the playlists of the iTunes library on a schedule.",
+		StartType:   mgr.StartAutomatic,
+	}, args...)
+	if err != nil {
+		return err
+	}
+	defer service.Close()
+
+	// The source is already registered if the service was installed before.
+	eventlog.InstallAsEventCreate(eventSource, eventlog.Error|eventlog.Warning|eventlog.Info)
+	return service.Start()
+}
+
+// uninstallService stops the service name and removes it from the service manager.
+func uninstallService(name string) error {
+	manager, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer manager.Disconnect()
+
+	service, err := manager.OpenService(name)
+	if err != nil {
+		return err
+	}
+	defer service.Close()
+
+	// A service which is not running can not be stopped, but is removed all the same.
+	service.Control(svc.Stop)
+	if err := service.Delete(); err != nil {
+		return err
+	}
+	eventlog.Remove(eventSource)
+	return nil
+}
+
+// runAsService runs run under the service manager until the service is stopped or, when
+// started from a console, until Ctrl-C.
+func runAsService(name string, run func(ctx context.Context)) error {
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		return err
+	}
+	if !isService {
+		runUntilStopped(run)
+		return nil
+	}
+	return svc.Run(name, exportService(run))
+}
+
+// exportService runs the scheduled exports as a service of the service manager.
+type exportService func(ctx context.Context)
+
+func (run exportService) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		run(ctx)
+		close(done)
+	}()
+
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	for {
+		select {
+		case <-done:
+			cancel()
+			return false, 1
+		case request := <-requests:
+			switch request.Cmd {
+			case svc.Interrogate:
+				status <- request.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending}
+				cancel()
+				<-done
+				return false, 0
+			}
+		}
+	}
+}
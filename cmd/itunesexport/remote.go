@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/export"
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+	"github.com/ericdaugherty/itunesexport-go/pkg/remote"
+)
+
+// remoteService returns the service selected on the command line to push the playlists of
+// the library to, or nil to export to files.
+func remoteService(ctx context.Context, library *itunes.Library) (remote.Service, string, error) {
+	if plexURL != "" {
+		token := plexToken
+		if token == "" {
+			token = os.Getenv("PLEX_TOKEN")
+		}
+		plex, err := remote.NewPlex(plexURL, token)
+		return plex, "Plex", err
+	}
+	if jellyfinURL != "" {
+		token := jellyfinToken
+		if token == "" {
+			token = os.Getenv("JELLYFIN_TOKEN")
+		}
+		jellyfin, err := remote.NewJellyfin(jellyfinURL, token, jellyfinUser)
+		return jellyfin, "Jellyfin", err
+	}
+	if subsonicURL != "" {
+		password := subsonicPassword
+		if password == "" {
+			password = os.Getenv("SUBSONIC_PASSWORD")
+		}
+		subsonic, err := remote.NewSubsonic(subsonicURL, subsonicUser, password)
+		return subsonic, "Subsonic", err
+	}
+	if sonosSpeaker != "" {
+		musicFolder := musicPathOrig
+		if musicFolder == "" {
+			musicFolder = itunes.LocationPath(library.MusicFolder)
+		}
+		sonos, err := remote.NewSonos(sonosSpeaker, sonosShare, musicFolder)
+		return sonos, "Sonos", err
+	}
+	if spotify {
+		clientId := spotifyClientId
+		if clientId == "" {
+			clientId = os.Getenv("SPOTIFY_CLIENT_ID")
+		}
+		if clientId == "" {
+			return nil, "", errors.New("-spotify requires -spotifyClientId or SPOTIFY_CLIENT_ID")
+		}
+		token, err := remote.SpotifyOAuth(clientId).Authorize(ctx, os.Stdout)
+		if err != nil {
+			return nil, "", fmt.Errorf("Unable to authorize with Spotify: %v", err)
+		}
+		return remote.NewSpotify(token), "Spotify", nil
+	}
+	if youtube {
+		clientId, clientSecret := youtubeClientId, youtubeClientSecret
+		if clientId == "" {
+			clientId = os.Getenv("YOUTUBE_CLIENT_ID")
+		}
+		if clientSecret == "" {
+			clientSecret = os.Getenv("YOUTUBE_CLIENT_SECRET")
+		}
+		if clientId == "" || clientSecret == "" {
+			return nil, "", errors.New("-youtube requires -youtubeClientId and -youtubeClientSecret or YOUTUBE_CLIENT_ID and YOUTUBE_CLIENT_SECRET")
+		}
+		token, err := remote.YouTubeOAuth(clientId, clientSecret).Authorize(ctx, os.Stdout)
+		if err != nil {
+			return nil, "", fmt.Errorf("Unable to authorize with YouTube: %v", err)
+		}
+		return remote.NewYouTube(token), "YouTube Music", nil
+	}
+	if appleMusicKey != "" {
+		userToken := appleMusicUserToken
+		if userToken == "" {
+			userToken = os.Getenv("APPLE_MUSIC_USER_TOKEN")
+		}
+		if appleMusicKeyId == "" || appleMusicTeamId == "" || userToken == "" {
+			return nil, "", errors.New("-appleMusicKey requires -appleMusicKeyId, -appleMusicTeamId and -appleMusicUserToken or APPLE_MUSIC_USER_TOKEN")
+		}
+		key, err := ioutil.ReadFile(appleMusicKey)
+		if err != nil {
+			return nil, "", fmt.Errorf("Unable to read MusicKit private key: %v", err)
+		}
+		developerToken, err := remote.AppleMusicDeveloperToken(key, appleMusicKeyId, appleMusicTeamId, time.Now())
+		if err != nil {
+			return nil, "", fmt.Errorf("Unable to create Apple Music developer token: %v", err)
+		}
+		return remote.NewAppleMusic(developerToken, userToken), "Apple Music", nil
+	}
+	return nil, "", nil
+}
+
+// pushPlaylists recreates the playlists on the service and lists the tracks which were not
+// found on the service.
+func pushPlaylists(ctx context.Context, service remote.Service, serviceName string, library *itunes.Library, playlists []itunes.Playlist) error {
+	export.Logf(export.LOG_INFO, "Pushing %v playlists to %v...\n", len(playlists), serviceName)
+	results, err := remote.PushPlaylists(ctx, service, library, playlists)
+	for _, result := range results {
+		if len(result.Unmatched) == 0 {
+			export.Log(export.LogEntry{Level: export.LOG_INFO, Message: fmt.Sprintf("Playlist %v: %v tracks\n", result.Playlist, result.Matched), Playlist: result.Playlist})
+			continue
+		}
+		export.Log(export.LogEntry{Level: export.LOG_WARNING, Message: fmt.Sprintf("Playlist %v: %v tracks, %v tracks not found:\n", result.Playlist, result.Matched, len(result.Unmatched)), Playlist: result.Playlist})
+		for _, track := range result.Unmatched {
+			export.Log(export.LogEntry{Level: export.LOG_WARNING, Message: fmt.Sprintf("    %v - %v (%v)\n", track.Artist, track.Name, track.Album), Playlist: result.Playlist, Track: track.Name})
+		}
+	}
+	if err != nil {
+		return err
+	}
+	export.Logf(export.LOG_INFO, "Push Complete.\n")
+	return nil
+}
+
+// submitListens imports the play history of the library into ListenBrainz.
+func submitListens(ctx context.Context, library *itunes.Library) error {
+	listens := remote.Listens(library)
+	export.Logf(export.LOG_INFO, "Submitting %v listens to ListenBrainz...\n", len(listens))
+	listenBrainz := remote.NewListenBrainz(strings.TrimSuffix(listenBrainzURL, "/"), listenBrainzToken)
+	err := listenBrainz.SubmitListens(ctx, listens, func(submitted int) {
+		export.Logf(export.LOG_INFO, "Submitted %v of %v listens.\n", submitted, len(listens))
+	})
+	if err != nil {
+		return err
+	}
+	export.Logf(export.LOG_INFO, "Submit Complete.\n")
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/export"
+)
+
+const RollbackUsageMessage = `usage: %v rollback -output <file path> [-to <snapshot>] [-recopy] [-list]
+
+Restores the playlist files of an export made with -snapshots to a previous export.
+
+Flags:
+    -output <file path>         Directory of the export to roll back.
+    -to <snapshot>              Snapshot to restore, as shown by -list. Defaults to the export before the latest.
+    -recopy                     Copy music files of the snapshot that are missing in the output again from their
+                                source.
+    -list                       List the snapshots of the output instead of rolling back.
+`
+
+// runRollback runs the rollback command with the given arguments. It returns false if the
+// export could not be rolled back.
+func runRollback(args []string) bool {
+	var (
+		outputPath string
+		snapshotId string
+		recopy     bool
+		list       bool
+	)
+
+	flags := flag.NewFlagSet("rollback", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	flags.StringVar(&outputPath, "output", "", "")
+	flags.StringVar(&snapshotId, "to", "", "")
+	flags.BoolVar(&recopy, "recopy", false, "")
+	flags.BoolVar(&list, "list", false, "")
+
+	err := flags.Parse(args)
+	if err == nil && (outputPath == "" || flags.NArg() > 0) {
+		err = fmt.Errorf("-output is required and no other parameters are allowed")
+	}
+	if err != nil {
+		fmt.Printf(RollbackUsageMessage, "itunesexport")
+		fmt.Printf(UsageErrorMessage, err)
+		return false
+	}
+
+	fsys := export.NewDirFS(outputPath)
+	if list {
+		snapshots, err := export.LoadSnapshots(fsys)
+		if err != nil {
+			fmt.Printf("Error reading snapshots: %v\n", err)
+			return false
+		}
+		for _, snapshot := range snapshots {
+			fmt.Printf("%v  %v (%v playlists)\n", snapshot.Id, snapshot.Time.Local().Format("2006-01-02 15:04:05"), len(snapshot.Playlists))
+		}
+		return true
+	}
+
+	ctx, cancel := exportContext()
+	defer cancel()
+
+	result, err := export.Rollback(ctx, fsys, snapshotId, recopy)
+	if err != nil {
+		fmt.Printf("Error rolling back export: %v\n", err)
+		return false
+	}
+	for _, source := range result.Missing {
+		fmt.Printf("Unable to copy missing music file again, source not found: %v\n", source)
+	}
+	fmt.Printf("Rolled back to snapshot %v: %v playlists restored, %v removed, %v music files copied again.\n",
+		result.Snapshot, result.Restored, result.Removed, result.Recopied)
+	return len(result.Missing) == 0
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRollback(t *testing.T) {
+	outputDir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(outputDir)
+
+	musicFile, _ := prepareMusicFile(t)
+	defer os.Remove(musicFile)
+
+	itunesDbFile := prepareItunesDbFile(t, filepath.ToSlash(musicFile))
+	defer os.Remove(itunesDbFile)
+
+	realArgs := os.Args
+	defer func() { os.Args = realArgs }()
+	os.Args = []string{"itunesexport", "-library", itunesDbFile, "-output", outputDir, "-includeAll", "-noCache", "-snapshots"}
+	main()
+	playlistFile := filepath.Join(outputDir, "My Playlist.m3u")
+	exported := readFile(t, playlistFile)
+	main()
+
+	if err := ioutil.WriteFile(playlistFile, []byte("broken"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !runRollback([]string{"-output", outputDir}) {
+		t.Fatal("expected the export to be rolled back")
+	}
+	if content := readFile(t, playlistFile); content != exported {
+		t.Fatalf("expected the exported playlist to be restored, got %q", content)
+	}
+	if !runRollback([]string{"-output", outputDir, "-list"}) {
+		t.Fatal("expected the snapshots to be listed")
+	}
+	if runRollback([]string{"-output", outputDir, "-to", "unknown"}) {
+		t.Fatal("expected unknown snapshot to be rejected")
+	}
+	if runRollback(nil) {
+		t.Fatal("expected missing -output to be rejected")
+	}
+}
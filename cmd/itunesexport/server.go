@@ -0,0 +1,353 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/export"
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+const (
+	JobQueued   = "queued"
+	JobRunning  = "running"
+	JobFinished = "finished"
+	JobFailed   = "failed"
+)
+
+const pageHeader = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  {{if .Refresh}}<meta http-equiv="refresh" content="2">{{end}}
+  <title>iTunes Export</title>
+  <style>
+    body { font-family: sans-serif; margin: 2em; }
+    table { border-collapse: collapse; }
+    td, th { padding: 0.2em 0.8em; text-align: left; }
+    tr:nth-child(even) { background: #f0f0f0; }
+    fieldset { margin-bottom: 1em; }
+  </style>
+</head>
+<body>
+<h1><a href="/">iTunes Export</a></h1>
+`
+
+const pageFooter = `<p><small>iTunes Export (Go Version {{.Version}})</small></p>
+</body>
+</html>
+`
+
+var indexTemplate = template.Must(template.New("index").Parse(pageHeader + `
+<form method="post" action="/export">
+<fieldset>
+  <legend>Export</legend>
+  <p>Output path: <input type="text" name="output" size="60" value="{{.Defaults.OutputPath}}"></p>
+  <p>Type:
+    <select name="type">
+      <option value="M3U">M3U</option>
+      <option value="EXT">M3U Extended</option>
+      <option value="WPL">Windows Playlist</option>
+      <option value="ZPL">Zune Playlist</option>
+    </select>
+    Copy:
+    <select name="copy">
+      <option value="NONE">None</option>
+      <option value="PLAYLIST">Playlist folders</option>
+      <option value="ITUNES">iTunes structure</option>
+      <option value="FLAT">Flat</option>
+    </select>
+    <label><input type="checkbox" name="includeFolders" value="true"{{if .Defaults.IncludeFolders}} checked{{end}}> Include folders</label>
+  </p>
+  <p><input type="submit" value="Export selected playlists"></p>
+</fieldset>
+{{if .Jobs}}
+<h2>Exports</h2>
+<table>
+  <tr><th>#</th><th>Started</th><th>Playlists</th><th>Status</th></tr>
+  {{range .Jobs}}
+  <tr><td><a href="/job?id={{.ID}}">{{.ID}}</a></td><td>{{.Started.Format "2006-01-02 3:04PM"}}</td><td>{{len .Playlists}}</td><td>{{.Status}}</td></tr>
+  {{end}}
+</table>
+{{end}}
+<h2>Playlists</h2>
+<table>
+  <tr><th></th><th>Name</th><th>Tracks</th><th></th></tr>
+  {{range .Playlists}}
+  <tr>
+    <td><input type="checkbox" name="playlist" value="{{.PlaylistPersistentId}}"></td>
+    <td><a href="/playlist?id={{.PlaylistPersistentId}}">{{.Name}}</a></td>
+    <td>{{len .PlaylistItems}}</td>
+    <td><a href="/playlist.m3u?id={{.PlaylistPersistentId}}">M3U</a></td>
+  </tr>
+  {{end}}
+</table>
+</form>
+` + pageFooter))
+
+var playlistTemplate = template.Must(template.New("playlist").Funcs(template.FuncMap{"duration": formatDuration}).Parse(pageHeader + `
+<h2>{{.Playlist.Name}}</h2>
+<p><a href="/playlist.m3u?id={{.Playlist.PlaylistPersistentId}}">Play as M3U playlist</a> |
+<a href="/cast?id={{.Playlist.PlaylistPersistentId}}">Cast to a Chromecast</a></p>
+<table>
+  <tr><th>Name</th><th>Artist</th><th>Album</th><th>Time</th></tr>
+  {{range .Tracks}}
+  <tr><td>{{.Name}}</td><td>{{.Artist}}</td><td>{{.Album}}</td><td>{{duration .TotalTime}}</td></tr>
+  {{end}}
+</table>
+` + pageFooter))
+
+var jobTemplate = template.Must(template.New("job").Parse(pageHeader + `
+<h2>Export #{{.Job.ID}}</h2>
+<p>Status: <b>{{.Job.Status}}</b></p>
+<p>Output: {{.Job.Output}} ({{.Job.Type}}, copy {{.Job.Copy}})</p>
+<p><progress max="{{.Job.Total}}" value="{{.Job.Done}}"></progress> {{.Job.Done}} of {{.Job.Total}} playlists</p>
+{{if .Job.Current}}<p>Last exported: {{.Job.Current}}</p>{{end}}
+{{if .Job.Error}}<p>Error: {{.Job.Error}}</p>{{end}}
+` + pageFooter))
+
+// exportJob tracks a single export started from the web interface or the API.
+type exportJob struct {
+	ID        int       `json:"id"`
+	Playlists []string  `json:"playlists"`
+	Output    string    `json:"output"`
+	Type      string    `json:"type"`
+	Copy      string    `json:"copy"`
+	Status    string    `json:"status"`
+	Started   time.Time `json:"started"`
+	Finished  time.Time `json:"finished"`
+	Done      int       `json:"done"`
+	Total     int       `json:"total"`
+	Current   string    `json:"current,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+type server struct {
+	library  *itunes.Library
+	defaults export.ExportSettings
+
+	// exportMutex ensures only one export runs at a time.
+	exportMutex sync.Mutex
+
+	mutex sync.Mutex
+	jobs  []*exportJob
+}
+
+// serve starts the web interface on address and blocks until the server fails.
+func serve(address string, library *itunes.Library, defaults export.ExportSettings) error {
+	s := &server{library: library, defaults: defaults}
+
+	fmt.Printf("Starting web interface on %v\n", address)
+	return http.ListenAndServe(address, s.handler())
+}
+
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleIndex)
+	mux.HandleFunc("/playlist", s.handlePlaylist)
+	mux.HandleFunc("/export", s.handleExport)
+	mux.HandleFunc("/job", s.handleJob)
+	mux.HandleFunc("/api/playlists", s.handleApiPlaylists)
+	mux.HandleFunc("/api/playlists/", s.handleApiPlaylistTracks)
+	mux.HandleFunc("/api/exports", s.handleApiExports)
+	mux.HandleFunc("/api/exports/", s.handleApiExport)
+	mux.HandleFunc("/metrics", s.handleMetrics)
+	mux.HandleFunc("/audio/", s.handleAudio)
+	mux.HandleFunc("/playlist.m3u", s.handlePlaylistM3U)
+	mux.HandleFunc("/cast", s.handleCast)
+	return mux
+}
+
+func (s *server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	var playlists []itunes.Playlist
+	for _, playlist := range s.library.Playlists {
+		if !playlist.Container() {
+			playlists = append(playlists, playlist)
+		}
+	}
+
+	s.render(w, indexTemplate, map[string]interface{}{
+		"Defaults":  s.defaults,
+		"Playlists": playlists,
+		"Jobs":      s.jobSnapshots(),
+	})
+}
+
+func (s *server) handlePlaylist(w http.ResponseWriter, r *http.Request) {
+	playlist, ok := s.library.PlaylistIdMap[r.URL.Query().Get("id")]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.render(w, playlistTemplate, map[string]interface{}{
+		"Playlist": playlist,
+		"Tracks":   playlist.Tracks(s.library),
+	})
+}
+
+func (s *server) handleExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	job, err := s.startExport(r.Form["playlist"], r.FormValue("output"), r.FormValue("type"), r.FormValue("copy"), r.FormValue("includeFolders") == "true")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/job?id=%v", job.ID), http.StatusSeeOther)
+}
+
+func (s *server) handleJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	job, ok := s.jobSnapshot(id)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.render(w, jobTemplate, map[string]interface{}{
+		"Job":     job,
+		"Refresh": job.Status == JobQueued || job.Status == JobRunning,
+	})
+}
+
+func (s *server) render(w http.ResponseWriter, tmpl *template.Template, data map[string]interface{}) {
+	data["Version"] = Version
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmpl.Execute(w, data); err != nil {
+		fmt.Printf("Error rendering page: %v\n", err)
+	}
+}
+
+// startExport validates the requested export and queues it. Exports run one at a time
+// in the background; the returned job can be polled for progress.
+func (s *server) startExport(playlistIds []string, output, exportType, copyType string, includeFolders bool) (*exportJob, error) {
+	settings := s.defaults
+	settings.Library = s.library
+	settings.OutputPath = output
+	settings.IncludeFolders = includeFolders
+	settings.Playlists = nil
+
+	if output == "" {
+		return nil, errors.New("no output path specified")
+	}
+	if err := export.ParseExportType(&settings, exportType); err != nil {
+		return nil, err
+	}
+	if err := export.ParseCopyType(&settings, copyType); err != nil {
+		return nil, err
+	}
+	for _, id := range playlistIds {
+		playlist, ok := s.library.PlaylistIdMap[id]
+		if !ok {
+			return nil, fmt.Errorf("unknown playlist %q", id)
+		}
+		settings.Playlists = append(settings.Playlists, playlist)
+	}
+	if len(settings.Playlists) == 0 {
+		return nil, errors.New("no playlists selected")
+	}
+
+	s.mutex.Lock()
+	job := &exportJob{
+		ID:        len(s.jobs) + 1,
+		Playlists: playlistIds,
+		Output:    output,
+		Type:      exportType,
+		Copy:      copyType,
+		Status:    JobQueued,
+		Started:   time.Now(),
+	}
+	s.jobs = append(s.jobs, job)
+	s.mutex.Unlock()
+
+	settings.Progress = func(done, total int, playlistName string) {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		job.Done = done
+		job.Total = total
+		job.Current = playlistName
+	}
+
+	go s.runExport(job, &settings)
+	return job, nil
+}
+
+func (s *server) runExport(job *exportJob, settings *export.ExportSettings) {
+	s.exportMutex.Lock()
+	defer s.exportMutex.Unlock()
+
+	s.mutex.Lock()
+	job.Status = JobRunning
+	s.mutex.Unlock()
+
+	err := export.ExportPlaylists(settings, s.library)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	job.Finished = time.Now()
+	if err != nil {
+		job.Status = JobFailed
+		job.Error = err.Error()
+	} else {
+		job.Status = JobFinished
+	}
+}
+
+// handleMetrics serves GET /metrics for Prometheus.
+func (s *server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := export.WriteMetrics(w); err != nil {
+		fmt.Printf("Error writing metrics: %v\n", err)
+	}
+}
+
+// formatDuration formats a track time in milliseconds as m:ss.
+func formatDuration(milliseconds int) string {
+	seconds := milliseconds / 1000
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+// jobSnapshots returns copies of all jobs, newest first.
+func (s *server) jobSnapshots() []exportJob {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	jobs := make([]exportJob, 0, len(s.jobs))
+	for i := len(s.jobs) - 1; i >= 0; i-- {
+		jobs = append(jobs, *s.jobs[i])
+	}
+	return jobs
+}
+
+func (s *server) jobSnapshot(id int) (exportJob, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if id < 1 || id > len(s.jobs) {
+		return exportJob{}, false
+	}
+	return *s.jobs[id-1], true
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+func testServerLibrary() *itunes.Library {
+	library := &itunes.Library{
+		Tracks: map[string]itunes.Track{
+			"1": {TrackId: 1, Name: "Some Song", Artist: "Some Artist", TotalTime: 210000},
+		},
+		Playlists: []itunes.Playlist{
+			{Name: "My Playlist", PlaylistPersistentId: "BA9D3C2EAB361B84", PlaylistItems: []itunes.PlaylistItem{{TrackId: 1}}},
+		},
+	}
+	library.PlaylistMap = map[string]itunes.Playlist{"My Playlist": library.Playlists[0]}
+	library.PlaylistIdMap = map[string]itunes.Playlist{"BA9D3C2EAB361B84": library.Playlists[0]}
+	return library
+}
+
+func TestServerListsPlaylistsAndTracks(t *testing.T) {
+	s := &server{library: testServerLibrary()}
+	handler := s.handler()
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), "My Playlist") {
+		t.Fatalf("index does not list playlist: %v %v", recorder.Code, recorder.Body.String())
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/playlist?id=BA9D3C2EAB361B84", nil))
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), "Some Song") || !strings.Contains(recorder.Body.String(), "3:30") {
+		t.Fatalf("playlist page does not list track: %v %v", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestServerExport(t *testing.T) {
+	outputDir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(outputDir)
+
+	s := &server{library: testServerLibrary()}
+	handler := s.handler()
+
+	form := url.Values{
+		"playlist": {"BA9D3C2EAB361B84"},
+		"output":   {outputDir},
+		"type":     {"EXT"},
+		"copy":     {"NONE"},
+	}
+	request := httptest.NewRequest("POST", "/export", strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusSeeOther || recorder.Header().Get("Location") != "/job?id=1" {
+		t.Fatalf("unexpected response: %v %v", recorder.Code, recorder.Header())
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, _ := s.jobSnapshot(1)
+		if job.Status == JobFinished {
+			break
+		}
+		if job.Status == JobFailed || time.Now().After(deadline) {
+			t.Fatalf("export did not finish: %+v", job)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assertPathExists(t, filepath.Join(outputDir, "My Playlist.m3u"))
+}
+
+func TestServerRejectsInvalidExport(t *testing.T) {
+	s := &server{library: testServerLibrary()}
+
+	if _, err := s.startExport(nil, "/tmp", "M3U", "NONE", false); err == nil {
+		t.Fatal("expected error for missing playlists")
+	}
+	if _, err := s.startExport([]string{"BA9D3C2EAB361B84"}, "/tmp", "FOO", "NONE", false); err == nil {
+		t.Fatal("expected error for unknown export type")
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	s := &server{library: testServerLibrary()}
+
+	recorder := httptest.NewRecorder()
+	s.handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.Contains(recorder.Body.String(), "itunesexport_exports_total") {
+		t.Fatalf("unexpected metrics response: %v", recorder.Body.String())
+	}
+}
+
+func TestServerStreamsPlaylists(t *testing.T) {
+	musicFile, _ := prepareMusicFile(t)
+	defer os.Remove(musicFile)
+
+	library := testServerLibrary()
+	track := library.Tracks["1"]
+	track.Location = "file://" + filepath.ToSlash(musicFile)
+	library.Tracks["1"] = track
+	handler := (&server{library: library}).handler()
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "http://music.local:8080/playlist.m3u?id=BA9D3C2EAB361B84", nil))
+	expected := "#EXTM3U\n#EXTINF:210,Some Artist - Some Song\nhttp://music.local:8080/audio/1.mp3\n"
+	if recorder.Code != http.StatusOK || recorder.Body.String() != expected {
+		t.Fatalf("unexpected playlist: %v %q", recorder.Code, recorder.Body.String())
+	}
+
+	recorder = httptest.NewRecorder()
+	request := httptest.NewRequest("GET", "/audio/1.mp3", nil)
+	request.Header.Set("Range", "bytes=1-")
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusPartialContent || recorder.Body.String() != FileContent[1:] || recorder.Header().Get("Content-Type") != "audio/mpeg" {
+		t.Fatalf("unexpected audio response: %v %v %q", recorder.Code, recorder.Header(), recorder.Body.String())
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/audio/2.mp3", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Fatalf("expected unknown track to be not found, got %v", recorder.Code)
+	}
+}
+
+func TestServerCastsPlaylists(t *testing.T) {
+	library := testServerLibrary()
+	track := library.Tracks["1"]
+	track.Location = "file:///music/Some%20Song.mp3"
+	library.Tracks["1"] = track
+	s := &server{library: library}
+
+	recorder := httptest.NewRecorder()
+	s.handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/cast?id=unknown", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Fatalf("expected unknown playlist to be not found, got %v", recorder.Code)
+	}
+
+	media := s.castMedia(library.PlaylistIdMap["BA9D3C2EAB361B84"], "http://192.168.1.10:8080")
+	if len(media) != 1 || media[0].URL != "http://192.168.1.10:8080/audio/1.mp3" || media[0].ContentType != "audio/mpeg" ||
+		media[0].Title != "Some Song" || media[0].Artist != "Some Artist" {
+		t.Fatalf("unexpected media: %+v", media)
+	}
+}
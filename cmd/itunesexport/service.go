@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	plist "howett.net/plist"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/export"
+)
+
+const ServiceUsageMessage = `usage: %v service install|uninstall|run [-every <duration>] [-name <name>] [-dir <path>] [-- <export flags>]
+
+Runs the export as a service of the operating system, so it is repeated on a schedule and keeps
+running after a reboot. The export flags follow --, e.g.
+    itunesexport service install -every 6h -- -library /music/Library.xml -output /mnt/usb -includeAll
+
+Commands:
+    install                     Registers the service with systemd (as a user unit) on Linux, launchd (as a
+                                LaunchAgent) on macOS or the service manager on Windows and starts it.
+    uninstall                   Stops the service and removes it.
+    run                         Exports now and then every -every until stopped. The installed service runs this.
+
+Flags:
+    -every <duration>           Time between two exports, e.g. 6h (default 24h).
+    -name <name>                Name of the service (default itunesexport).
+    -dir <path>                 Directory relative paths of the export flags are resolved in. install records the
+                                current directory, as service managers start services elsewhere.
+`
+
+// DefaultServiceName is the name of the service unless -name is given.
+const DefaultServiceName = "itunesexport"
+
+// runService runs the service command with the given arguments. It returns false if the
+// service could not be installed, uninstalled or run.
+func runService(args []string) bool {
+	var (
+		every time.Duration
+		name  string
+		dir   string
+	)
+
+	flags := flag.NewFlagSet("service", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	flags.DurationVar(&every, "every", 24*time.Hour, "")
+	flags.StringVar(&name, "name", DefaultServiceName, "")
+	flags.StringVar(&dir, "dir", "", "")
+
+	var command string
+	var err error
+	if len(args) > 0 {
+		command = args[0]
+		err = flags.Parse(args[1:])
+	}
+	if err == nil {
+		err = checkServiceArgs(command, every, name, flags.Args())
+	}
+	if err != nil {
+		fmt.Printf(ServiceUsageMessage, "itunesexport")
+		fmt.Printf(UsageErrorMessage, err)
+		return false
+	}
+
+	switch command {
+	case "install":
+		dir, err = serviceDir(dir)
+		if err == nil {
+			err = installService(name, serviceCommand(every, dir, flags.Args()))
+		}
+		if err == nil {
+			fmt.Printf("Installed and started service %v, exporting every %v.\n", name, every)
+		}
+	case "uninstall":
+		err = uninstallService(name)
+		if err == nil {
+			fmt.Printf("Stopped and removed service %v.\n", name)
+		}
+	case "run":
+		err = runAsService(name, func(ctx context.Context) {
+			exportEvery(ctx, every, dir, flags.Args())
+		})
+	}
+	if err != nil {
+		fmt.Printf("Error running service %v: %v\n", command, err)
+		return false
+	}
+	return true
+}
+
+// checkServiceArgs returns an error if the command or its flags are invalid.
+func checkServiceArgs(command string, every time.Duration, name string, exportArgs []string) error {
+	switch command {
+	case "install", "run":
+		if len(exportArgs) == 0 {
+			return errors.New("the export flags are required after --")
+		}
+	case "uninstall":
+		if len(exportArgs) > 0 {
+			return errors.New("uninstall takes no export flags")
+		}
+	default:
+		return errors.New("install, uninstall or run is required")
+	}
+	if every < time.Minute {
+		return errors.New("-every must be at least 1m")
+	}
+	if name == "" || strings.ContainsAny(name, `/\ `) {
+		return errors.New("-name must not be empty or contain slashes or spaces")
+	}
+	return nil
+}
+
+// serviceDir returns the absolute path of the directory the installed service exports in,
+// the current directory unless dir is given.
+func serviceDir(dir string) (string, error) {
+	if dir == "" {
+		return os.Getwd()
+	}
+	return filepath.Abs(dir)
+}
+
+// serviceCommand returns the arguments the installed service is started with. The exports
+// run in dir, so relative paths of exportArgs resolve as they did when installing.
+func serviceCommand(every time.Duration, dir string, exportArgs []string) []string {
+	return append([]string{"service", "run", "-every", every.String(), "-dir", dir, "--"}, exportArgs...)
+}
+
+// exportEvery runs an export with exportArgs in a new process in dir now and then every
+// interval, until ctx is done. Every export starts with fresh settings and a fresh library
+// this way. An empty dir runs the exports in the current directory.
+func exportEvery(ctx context.Context, every time.Duration, dir string, exportArgs []string) {
+	executable, err := os.Executable()
+	if err != nil {
+		logError(err, "Unable to find the executable to export with: %v\n", err)
+		return
+	}
+
+	for {
+		export.Logf(export.LOG_INFO, "Starting scheduled export.\n")
+		if err := scheduledExport(ctx, executable, dir, exportArgs).Run(); err != nil && ctx.Err() == nil {
+			logError(err, "Scheduled export failed: %v\n", err)
+		}
+		export.Logf(export.LOG_INFO, "Next export at %v.\n", time.Now().Add(every).Format("2006-01-02 15:04:05"))
+
+		timer := time.NewTimer(every)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// scheduledExport returns the command running an export with exportArgs in dir.
+func scheduledExport(ctx context.Context, executable string, dir string, exportArgs []string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, executable, exportArgs...)
+	cmd.Dir = dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd
+}
+
+// runUntilStopped runs run with a context that is cancelled on Ctrl-C or when the service
+// manager stops the process.
+func runUntilStopped(run func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		select {
+		case <-signals:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	run(ctx)
+}
+
+// systemdUnit returns a systemd unit running the executable with args.
+func systemdUnit(executable string, args []string) string {
+	var command []string
+	for _, arg := range append([]string{executable}, args...) {
+		command = append(command, systemdQuote(arg))
+	}
+	return fmt.Sprintf(`[Unit]
+Description=iTunes Export
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+ExecStart=%v
+Restart=on-failure
+
+[Install]
+WantedBy=default.target
+`, strings.Join(command, " "))
+}
+
+// systemdQuote quotes arg for the command line of a systemd unit, which expands % and $.
+func systemdQuote(arg string) string {
+	arg = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "%", "%%", "$", "$$").Replace(arg)
+	return `"` + arg + `"`
+}
+
+// launchdJob is a launchd job definition as written to a property list.
+type launchdJob struct {
+	Label             string   `plist:"Label"`
+	ProgramArguments  []string `plist:"ProgramArguments"`
+	RunAtLoad         bool     `plist:"RunAtLoad"`
+	KeepAlive         bool     `plist:"KeepAlive"`
+	StandardOutPath   string   `plist:"StandardOutPath"`
+	StandardErrorPath string   `plist:"StandardErrorPath"`
+}
+
+// launchdPlist returns the property list of a launchd job named label running the
+// executable with args, which writes its output to logFile.
+func launchdPlist(label string, executable string, args []string, logFile string) ([]byte, error) {
+	job := launchdJob{
+		Label:             label,
+		ProgramArguments:  append([]string{executable}, args...),
+		RunAtLoad:         true,
+		KeepAlive:         true,
+		StandardOutPath:   logFile,
+		StandardErrorPath: logFile,
+	}
+	return plist.MarshalIndent(job, plist.XMLFormat, "\t")
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	plist "howett.net/plist"
+)
+
+func TestCheckServiceArgs(t *testing.T) {
+	exportArgs := []string{"-output", "/mnt/usb", "-includeAll"}
+	if err := checkServiceArgs("install", 6*time.Hour, DefaultServiceName, exportArgs); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkServiceArgs("uninstall", 24*time.Hour, DefaultServiceName, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkServiceArgs("run", 24*time.Hour, DefaultServiceName, nil); err == nil {
+		t.Fatal("expected an error for run without export flags")
+	}
+	if err := checkServiceArgs("start", 24*time.Hour, DefaultServiceName, exportArgs); err == nil {
+		t.Fatal("expected an error for an unknown command")
+	}
+	if err := checkServiceArgs("install", time.Second, DefaultServiceName, exportArgs); err == nil {
+		t.Fatal("expected an error for -every below a minute")
+	}
+	if err := checkServiceArgs("install", time.Hour, "my/export", exportArgs); err == nil {
+		t.Fatal("expected an error for a name with a slash")
+	}
+}
+
+func TestSystemdUnit(t *testing.T) {
+	args := serviceCommand(6*time.Hour, "/home/me", []string{"-output", "/mnt/My Music", "-copyTemplate", "{artist}/100%"})
+	unit := systemdUnit("/usr/local/bin/itunesexport", args)
+
+	expected := `ExecStart="/usr/local/bin/itunesexport" "service" "run" "-every" "6h0m0s" "-dir" "/home/me" "--" "-output" "/mnt/My Music" "-copyTemplate" "{artist}/100%%"`
+	if !strings.Contains(unit, expected+"\n") {
+		t.Fatalf("expected %v in the unit, got\n%v", expected, unit)
+	}
+	if !strings.Contains(unit, "WantedBy=default.target") {
+		t.Fatalf("expected the unit to be started with the user session, got\n%v", unit)
+	}
+}
+
+func TestLaunchdPlist(t *testing.T) {
+	args := serviceCommand(time.Hour, "/Users/me", []string{"-output", "/Volumes/USB"})
+	data, err := launchdPlist("com.ericdaugherty.itunesexport", "/usr/local/bin/itunesexport", args, "/Users/me/Library/Logs/itunesexport.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var job launchdJob
+	if _, err := plist.Unmarshal(data, &job); err != nil {
+		t.Fatal(err)
+	}
+	if job.Label != "com.ericdaugherty.itunesexport" || !job.RunAtLoad || !job.KeepAlive {
+		t.Fatalf("expected a job started at load and kept alive, got %+v", job)
+	}
+	expected := []string{"/usr/local/bin/itunesexport", "service", "run", "-every", "1h0m0s", "-dir", "/Users/me", "--", "-output", "/Volumes/USB"}
+	if strings.Join(job.ProgramArguments, " ") != strings.Join(expected, " ") {
+		t.Fatalf("expected arguments %v, got %v", expected, job.ProgramArguments)
+	}
+}
+
+func TestServiceDir(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir, err := serviceDir(""); err != nil || dir != wd {
+		t.Fatalf("expected the current directory %v, got %v, %v", wd, dir, err)
+	}
+	if dir, err := serviceDir("exports"); err != nil || dir != filepath.Join(wd, "exports") {
+		t.Fatalf("expected exports below the current directory, got %v, %v", dir, err)
+	}
+
+	// The recorded directory is where the scheduled exports run, so -output out stays relative to it.
+	args := serviceCommand(time.Hour, wd, []string{"-output", "out"})
+	if strings.Join(args[:6], " ") != "service run -every 1h0m0s -dir "+wd {
+		t.Fatalf("expected the directory in the service command, got %v", args)
+	}
+	cmd := scheduledExport(context.Background(), "itunesexport", wd, []string{"-output", "out"})
+	if cmd.Dir != wd {
+		t.Fatalf("expected the export to run in %v, got %q", wd, cmd.Dir)
+	}
+}
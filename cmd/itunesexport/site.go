@@ -0,0 +1,312 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"flag"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+const SiteUsageMessage = `usage: %v site -output <directory> [-library <file path>] [-audioURL <URL>]
+
+Writes a static HTML site of the library, with pages for every playlist, artist and album,
+that can be hosted on any web server.
+
+Flags:
+    -output <directory>         Directory the site is written to.
+    -library <file path>        Path to iTunes Music Library XML File.
+    -audioURL <URL>             URL the Music Folder of the library is hosted at, e.g. https://example.com/music.
+                                The tracks link to their music files below it. Defaults to no links.
+`
+
+var siteTemplates = template.Must(template.New("site").Funcs(template.FuncMap{"duration": formatDuration}).Parse(`
+{{define "header"}}<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>{{.Title}}</title>
+  <style>
+    body { font-family: sans-serif; margin: 2em; }
+    table { border-collapse: collapse; }
+    td, th { padding: 0.2em 0.8em; text-align: left; }
+    tr:nth-child(even) { background: #f0f0f0; }
+  </style>
+</head>
+<body>
+<p><a href="{{.Root}}index.html">Library</a> | <a href="{{.Root}}playlists.html">Playlists</a> |
+<a href="{{.Root}}artists.html">Artists</a> | <a href="{{.Root}}albums.html">Albums</a></p>
+<h1>{{.Title}}</h1>
+{{end}}
+{{define "footer"}}<p><small>iTunes Export (Go Version {{.Version}})</small></p>
+</body>
+</html>
+{{end}}
+{{define "groups"}}{{template "header" .}}<table>
+  <tr><th>Name</th><th>{{.CountTitle}}</th></tr>
+  {{range .Groups}}
+  <tr><td><a href="{{$.Root}}{{.Page}}">{{.Name}}</a></td><td>{{.Count}}</td></tr>
+  {{end}}
+</table>
+{{template "footer" .}}{{end}}
+{{define "tracks"}}{{template "header" .}}<table>
+  <tr><th>Name</th><th>Artist</th><th>Album</th><th>Time</th></tr>
+  {{range .Tracks}}
+  <tr>
+    <td>{{if .Audio}}<a href="{{.Audio}}">{{.Track.Name}}</a>{{else}}{{.Track.Name}}{{end}}</td>
+    <td><a href="{{$.Root}}{{.ArtistPage}}">{{.Track.Artist}}</a></td>
+    <td>{{if .Track.Album}}<a href="{{$.Root}}{{.AlbumPage}}">{{.Track.Album}}</a>{{end}}</td>
+    <td>{{duration .Track.TotalTime}}</td>
+  </tr>
+  {{end}}
+</table>
+{{template "footer" .}}{{end}}
+`))
+
+// sitePage is the data of a page of the site.
+type sitePage struct {
+	// Root is the relative path of the page to the root of the site, e.g. "../".
+	Root    string
+	Title   string
+	Version string
+	Groups  []siteGroup
+	// CountTitle is the title of the counts of the groups. Defaults to Tracks.
+	CountTitle string
+	Tracks     []siteTrack
+}
+
+// siteGroup links to a page of the site, e.g. of the tracks of a playlist.
+type siteGroup struct {
+	Name  string
+	Page  string
+	Count int
+}
+
+type siteTrack struct {
+	Track      itunes.Track
+	ArtistPage string
+	AlbumPage  string
+	// Audio is the URL of the music file, if the site links to it.
+	Audio string
+}
+
+// runSite runs the site command with the given arguments. It returns false if the site
+// could not be written.
+func runSite(args []string) bool {
+	var (
+		outputPath  string
+		libraryPath string
+		audioURL    string
+	)
+
+	flags := flag.NewFlagSet("site", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	flags.StringVar(&outputPath, "output", "", "")
+	flags.StringVar(&libraryPath, "library", "", "")
+	flags.StringVar(&audioURL, "audioURL", "", "")
+
+	err := flags.Parse(args)
+	if err == nil && (outputPath == "" || flags.NArg() > 0) {
+		err = fmt.Errorf("-output is required and no other parameters are allowed")
+	}
+	if err != nil {
+		fmt.Printf(SiteUsageMessage, "itunesexport")
+		fmt.Printf(UsageErrorMessage, err)
+		return false
+	}
+
+	if libraryPath == "" {
+		libraryPath, err = defaultLibraryPath()
+		if err != nil {
+			fmt.Println(err)
+			return false
+		}
+	}
+
+	ctx, cancel := exportContext()
+	defer cancel()
+
+	fmt.Println("Loading Library:", filepath.Clean(libraryPath))
+	library, err := itunes.LoadLibraryContext(ctx, filepath.Clean(libraryPath))
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+
+	pages, err := writeSite(outputPath, library, audioURL)
+	if err != nil {
+		fmt.Printf("Error writing site: %v\n", err)
+		return false
+	}
+	fmt.Printf("Wrote %v pages to %v.\n", pages, outputPath)
+	return true
+}
+
+// writeSite writes the pages of the site of library to dir and returns their number.
+// Unless audioURL is empty, the tracks link to their music files below it.
+func writeSite(dir string, library *itunes.Library, audioURL string) (int, error) {
+	site := &siteWriter{dir: dir, library: library, audioURL: strings.TrimSuffix(audioURL, "/")}
+
+	var playlists []siteGroup
+	for _, playlist := range library.Playlists {
+		if playlist.Container() || playlist.Master {
+			continue
+		}
+		page := "playlists/" + sitePageName("playlist", playlist.PlaylistPersistentId)
+		tracks := site.tracks(playlist.Tracks(library))
+		if err := site.write(page, "tracks", sitePage{Title: playlist.Name, Tracks: tracks}); err != nil {
+			return site.pages, err
+		}
+		playlists = append(playlists, siteGroup{Name: playlistFolderPath(library, playlist), Page: page, Count: len(tracks)})
+	}
+
+	artists := make(map[string][]itunes.Track)
+	albums := make(map[string][]itunes.Track)
+	for _, track := range library.Tracks {
+		artists[track.Artist] = append(artists[track.Artist], track)
+		if track.Album != "" {
+			albums[siteAlbumName(track)] = append(albums[siteAlbumName(track)], track)
+		}
+	}
+	artistGroups, err := site.groups("artists/", artists, "artist")
+	if err != nil {
+		return site.pages, err
+	}
+	albumGroups, err := site.groups("albums/", albums, "album")
+	if err != nil {
+		return site.pages, err
+	}
+
+	indexes := []struct {
+		page, title string
+		groups      []siteGroup
+	}{
+		{"playlists.html", "Playlists", playlists},
+		{"artists.html", "Artists", artistGroups},
+		{"albums.html", "Albums", albumGroups},
+	}
+	var index []siteGroup
+	for _, list := range indexes {
+		if err := site.write(list.page, "groups", sitePage{Title: list.title, Groups: list.groups}); err != nil {
+			return site.pages, err
+		}
+		index = append(index, siteGroup{Name: list.title, Page: list.page, Count: len(list.groups)})
+	}
+	if err := site.write("index.html", "groups", sitePage{Title: "Library", Groups: index, CountTitle: "Entries"}); err != nil {
+		return site.pages, err
+	}
+	return site.pages, nil
+}
+
+type siteWriter struct {
+	dir      string
+	library  *itunes.Library
+	audioURL string
+	pages    int
+}
+
+// groups writes a page for each group of tracks, sorted by name, into dir of the site.
+func (site *siteWriter) groups(dir string, tracks map[string][]itunes.Track, kind string) ([]siteGroup, error) {
+	var groups []siteGroup
+	for name, groupTracks := range tracks {
+		sort.Slice(groupTracks, func(i, j int) bool {
+			a, b := groupTracks[i], groupTracks[j]
+			if a.Album != b.Album {
+				return strings.ToLower(a.Album) < strings.ToLower(b.Album)
+			}
+			if a.DiscNumber != b.DiscNumber {
+				return a.DiscNumber < b.DiscNumber
+			}
+			if a.TrackNumber != b.TrackNumber {
+				return a.TrackNumber < b.TrackNumber
+			}
+			return a.Name < b.Name
+		})
+		title := name
+		if title == "" {
+			title = "Unknown " + strings.Title(kind)
+		}
+		group := siteGroup{Name: title, Page: dir + sitePageName(kind, name), Count: len(groupTracks)}
+		if err := site.write(group.Page, "tracks", sitePage{Title: title, Tracks: site.tracks(groupTracks)}); err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return strings.ToLower(groups[i].Name) < strings.ToLower(groups[j].Name)
+	})
+	return groups, nil
+}
+
+func (site *siteWriter) tracks(tracks []itunes.Track) []siteTrack {
+	siteTracks := make([]siteTrack, 0, len(tracks))
+	for _, track := range tracks {
+		siteTracks = append(siteTracks, siteTrack{
+			Track:      track,
+			ArtistPage: "artists/" + sitePageName("artist", track.Artist),
+			AlbumPage:  "albums/" + sitePageName("album", siteAlbumName(track)),
+			Audio:      site.audio(track),
+		})
+	}
+	return siteTracks
+}
+
+// audio returns the URL of the music file of track below audioURL, or an empty string if
+// the file is not in the Music Folder of the library.
+func (site *siteWriter) audio(track itunes.Track) string {
+	if site.audioURL == "" || track.Location == "" || site.library.MusicFolder == "" {
+		return ""
+	}
+	relative, err := filepath.Rel(itunes.LocationPath(site.library.MusicFolder), itunes.LocationPath(track.Location))
+	if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return ""
+	}
+	return site.audioURL + "/" + (&url.URL{Path: filepath.ToSlash(relative)}).EscapedPath()
+}
+
+// write writes the page of the site with the named template.
+func (site *siteWriter) write(page, name string, data sitePage) error {
+	data.Root = strings.Repeat("../", strings.Count(page, "/"))
+	data.Version = Version
+	if data.CountTitle == "" {
+		data.CountTitle = "Tracks"
+	}
+	var content bytes.Buffer
+	if err := siteTemplates.ExecuteTemplate(&content, name, data); err != nil {
+		return err
+	}
+	fileName := filepath.Join(site.dir, filepath.FromSlash(page))
+	if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+		return err
+	}
+	site.pages++
+	return ioutil.WriteFile(fileName, content.Bytes(), 0644)
+}
+
+// siteAlbumName returns the name of the album of track with its artist, e.g. "Album - Artist".
+func siteAlbumName(track itunes.Track) string {
+	artist := track.AlbumArtist
+	if artist == "" && track.Compilation {
+		artist = "Various Artists"
+	} else if artist == "" {
+		artist = track.Artist
+	}
+	if artist == "" {
+		return track.Album
+	}
+	return track.Album + " - " + artist
+}
+
+// sitePageName returns the file name of the page of the named playlist, artist or album.
+// Names are hashed, as they may contain any character.
+func sitePageName(kind, name string) string {
+	return fmt.Sprintf("%x", sha1.Sum([]byte(kind+":"+name)))[:16] + ".html"
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+func TestWriteSite(t *testing.T) {
+	outputDir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(outputDir)
+
+	library := &itunes.Library{
+		MusicFolder: "file://localhost/music/",
+		Tracks: map[string]itunes.Track{
+			"1": {TrackId: 1, Name: "Some Song", Artist: "Some Artist", Album: "Some Album", TotalTime: 210000,
+				Location: "file://localhost/music/Some%20Artist/Some%20Song.mp3"},
+			"2": {TrackId: 2, Name: "Elsewhere", Artist: "Other Artist", Location: "file://localhost/other/Elsewhere.mp3"},
+		},
+		Playlists: []itunes.Playlist{
+			{Name: "Library", Master: true, PlaylistItems: []itunes.PlaylistItem{{TrackId: 1}, {TrackId: 2}}},
+			{Name: "Mixes", PlaylistPersistentId: "F01DE2", Folder: true},
+			{Name: "Road Trip", PlaylistPersistentId: "BA9D3C2EAB361B84", ParentPersistentId: "F01DE2",
+				PlaylistItems: []itunes.PlaylistItem{{TrackId: 1}, {TrackId: 2}}},
+		},
+	}
+	library.BuildPlaylistMaps()
+
+	pages, err := writeSite(outputDir, library, "https://example.com/music/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The index, three lists, a playlist, two artists and an album.
+	if pages != 8 {
+		t.Fatalf("expected 8 pages, got %v", pages)
+	}
+
+	read := func(page string) string {
+		content, err := ioutil.ReadFile(filepath.Join(outputDir, filepath.FromSlash(page)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(content)
+	}
+	if index := read("playlists.html"); !strings.Contains(index, "Mixes/Road Trip") || strings.Contains(index, ">Library</a></td>") {
+		t.Fatalf("unexpected playlists page %v", index)
+	}
+	playlist := read("playlists/" + sitePageName("playlist", "BA9D3C2EAB361B84"))
+	for _, expected := range []string{
+		`<a href="https://example.com/music/Some%20Artist/Some%20Song.mp3">Some Song</a>`,
+		`<a href="../albums/` + sitePageName("album", "Some Album - Some Artist") + `">Some Album</a>`,
+		`<td>Elsewhere</td>`,
+		`<td>3:30</td>`,
+	} {
+		if !strings.Contains(playlist, expected) {
+			t.Fatalf("expected playlist page to contain %v, got %v", expected, playlist)
+		}
+	}
+	if artist := read("artists/" + sitePageName("artist", "Other Artist")); !strings.Contains(artist, "Elsewhere") {
+		t.Fatalf("unexpected artist page %v", artist)
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/cast"
+	"github.com/ericdaugherty/itunesexport-go/pkg/dlna"
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+// castDiscoveryTimeout is how long the web interface waits for Cast devices to answer.
+const castDiscoveryTimeout = 2 * time.Second
+
+var castTemplate = template.Must(template.New("cast").Parse(pageHeader + `
+<h2>Cast {{.Playlist.Name}}</h2>
+{{if .Message}}<p>{{.Message}}</p>
+<p><a href="/playlist?id={{.Playlist.PlaylistPersistentId}}">Back to the playlist</a></p>
+{{else if .Devices}}
+<form method="post" action="/cast">
+  <input type="hidden" name="id" value="{{.Playlist.PlaylistPersistentId}}">
+  <select name="device">
+    {{range .Devices}}<option value="{{.Address}}">{{.Name}}</option>{{end}}
+  </select>
+  <input type="submit" value="Play">
+</form>
+{{else}}
+<p>No Chromecast or Google speakers found on the network.</p>
+{{end}}
+` + pageFooter))
+
+// handleCast serves GET /cast?id=<persistent id>, which lists the Cast devices on the
+// network, and POST /cast, which plays the playlist on the selected device.
+func (s *server) handleCast(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	playlist, ok := s.library.PlaylistIdMap[r.FormValue("id")]
+	if !ok || playlist.Container() {
+		http.NotFound(w, r)
+		return
+	}
+	data := map[string]interface{}{"Playlist": playlist}
+
+	switch r.Method {
+	case http.MethodGet:
+		devices, err := cast.Discover(r.Context(), castDiscoveryTimeout)
+		if err != nil {
+			data["Message"] = fmt.Sprintf("Unable to find Cast devices: %v", err)
+		}
+		data["Devices"] = devices
+	case http.MethodPost:
+		device := r.FormValue("device")
+		base, err := serverURL(r, device)
+		if err == nil {
+			media := s.castMedia(playlist, base)
+			err = cast.Play(r.Context(), device, media)
+			if err == nil {
+				data["Message"] = fmt.Sprintf("Playing %v tracks.", len(media))
+			}
+		}
+		if err != nil {
+			data["Message"] = fmt.Sprintf("Unable to play the playlist: %v", err)
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.render(w, castTemplate, data)
+}
+
+// castMedia returns the tracks of playlist to play on a Cast device, streamed from the
+// server at base, at most cast.MaxQueueItems.
+func (s *server) castMedia(playlist itunes.Playlist, base string) []cast.Media {
+	var media []cast.Media
+	for _, track := range playlist.Tracks(s.library) {
+		if track.Location == "" || len(media) == cast.MaxQueueItems {
+			continue
+		}
+		url := track.Location
+		if track.TrackType != "URL" {
+			url = base + audioPath(&track)
+		}
+		media = append(media, cast.Media{
+			URL:         url,
+			ContentType: dlna.MimeType(strings.ToLower(filepath.Ext(itunes.LocationPath(track.Location)))),
+			Title:       track.Title(),
+			Artist:      track.Artist,
+			Album:       track.Album,
+		})
+	}
+	return media
+}
+
+// serverURL returns the URL of the server as reachable from the device at address: the
+// address of the network interface towards the device with the port of the request.
+func serverURL(r *http.Request, address string) (string, error) {
+	_, port, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		port = "80"
+	}
+	ip, err := localIP(address)
+	if err != nil {
+		return "", err
+	}
+	return "http://" + net.JoinHostPort(ip, port), nil
+}
+
+// localIP returns the IP address of the network interface towards the device at address.
+func localIP(address string) (string, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+	// Connecting a UDP socket sends nothing, but selects the interface.
+	conn, err := net.Dial("udp", net.JoinHostPort(host, "9"))
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP.String(), nil
+}
+
+// handleAudio serves GET /audio/<track id>.<extension>, the music file of a track of the
+// library, with range requests for seeking.
+func (s *server) handleAudio(w http.ResponseWriter, r *http.Request) {
+	name := path.Base(r.URL.Path)
+	track, ok := s.library.Tracks[strings.TrimSuffix(name, path.Ext(name))]
+	if !ok || track.Location == "" || track.TrackType == "URL" {
+		http.NotFound(w, r)
+		return
+	}
+
+	file, err := os.Open(s.trackPath(&track))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if contentType := dlna.MimeType(strings.ToLower(path.Ext(name))); contentType != "application/octet-stream" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	http.ServeContent(w, r, name, info.ModTime(), file)
+}
+
+// handlePlaylistM3U serves GET /playlist.m3u?id=<persistent id>, an extended M3U playlist
+// whose entries stream the tracks from /audio/, so network players can play it.
+func (s *server) handlePlaylistM3U(w http.ResponseWriter, r *http.Request) {
+	playlist, ok := s.library.PlaylistIdMap[r.URL.Query().Get("id")]
+	if !ok || playlist.Container() {
+		http.NotFound(w, r)
+		return
+	}
+
+	base := "http://" + r.Host
+	if r.TLS != nil {
+		base = "https://" + r.Host
+	}
+	var out strings.Builder
+	out.WriteString("#EXTM3U\n")
+	for _, track := range playlist.Tracks(s.library) {
+		if track.Location == "" {
+			continue
+		}
+		fmt.Fprintf(&out, "#EXTINF:%v,%v - %v\n", track.TotalTime/1000, track.Artist, track.Title())
+		if track.TrackType == "URL" {
+			fmt.Fprintf(&out, "%v\n", track.Location)
+		} else {
+			fmt.Fprintf(&out, "%v%v\n", base, audioPath(&track))
+		}
+	}
+
+	w.Header().Set("Content-Type", "audio/x-mpegurl; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", playlist.SafeName()+".m3u"))
+	fmt.Fprint(w, out.String())
+}
+
+// audioPath returns the path track is streamed from.
+func audioPath(track *itunes.Track) string {
+	return "/audio/" + strconv.Itoa(track.TrackId) + strings.ToLower(filepath.Ext(itunes.LocationPath(track.Location)))
+}
+
+// trackPath returns the path of the music file of track on this computer, moved from the
+// original to the new music path of the defaults if set.
+func (s *server) trackPath(track *itunes.Track) string {
+	location := itunes.LocationPath(track.Location)
+	if s.defaults.NewMusicPath != "" {
+		location = strings.Replace(location, filepath.FromSlash(s.defaults.OriginalMusicPath), s.defaults.NewMusicPath, 1)
+	}
+	return location
+}
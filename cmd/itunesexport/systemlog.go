@@ -0,0 +1,29 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"log/syslog"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/export"
+)
+
+// systemLog returns a handler writing the messages to syslog, which journald reads on
+// systemd based systems as well.
+func systemLog() (export.LogHandler, error) {
+	writer, err := syslog.New(syslog.LOG_INFO|syslog.LOG_USER, "itunesexport")
+	if err != nil {
+		return nil, err
+	}
+	return export.LogHandlerFunc(func(entry export.LogEntry) {
+		switch entry.Level {
+		case export.LOG_ERROR:
+			writer.Err(entry.Message)
+		case export.LOG_WARNING:
+			writer.Warning(entry.Message)
+		default:
+			writer.Info(entry.Message)
+		}
+	}), nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"golang.org/x/sys/windows/svc/eventlog"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/export"
+)
+
+// eventSource is the source the messages are written to the Event Log with.
+const eventSource = "itunesexport"
+
+// eventId is the id of every event written to the Event Log.
+const eventId = 1
+
+// systemLog returns a handler writing the messages to the Windows Event Log.
+func systemLog() (export.LogHandler, error) {
+	log, err := eventlog.Open(eventSource)
+	if err != nil {
+		return nil, err
+	}
+	return export.LogHandlerFunc(func(entry export.LogEntry) {
+		switch entry.Level {
+		case export.LOG_ERROR:
+			log.Error(eventId, entry.Message)
+		case export.LOG_WARNING:
+			log.Warning(eventId, entry.Message)
+		default:
+			log.Info(eventId, entry.Message)
+		}
+	}), nil
+}
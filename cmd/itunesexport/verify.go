@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/export"
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+const VerifyUsageMessage = `usage: %v verify -output <file path> [-library <file path>] [-checksum]
+
+Checks that every file referenced by the playlists in the output directory exists.
+
+Flags:
+    -output <file path>         Directory of the export to verify.
+    -library <file path>        Also report playlists that are no longer in the library or whose tracks changed.
+    -checksum                   Compare copied music files to their source in the library. Uses the default
+                                library unless -library is set.
+`
+
+// runVerify runs the verify command with the given arguments. It returns false if the
+// export could not be verified or problems were found.
+func runVerify(args []string) bool {
+	var (
+		outputPath  string
+		libraryPath string
+		checksum    bool
+	)
+
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	flags.StringVar(&outputPath, "output", "", "")
+	flags.StringVar(&libraryPath, "library", "", "")
+	flags.BoolVar(&checksum, "checksum", false, "")
+
+	err := flags.Parse(args)
+	if err == nil && (outputPath == "" || flags.NArg() > 0) {
+		err = fmt.Errorf("-output is required and no other parameters are allowed")
+	}
+	if err != nil {
+		fmt.Printf(VerifyUsageMessage, "itunesexport")
+		fmt.Printf(UsageErrorMessage, err)
+		return false
+	}
+
+	ctx, cancel := exportContext()
+	defer cancel()
+
+	var library *itunes.Library
+	if checksum && libraryPath == "" {
+		libraryPath, err = defaultLibraryPath()
+		if err != nil {
+			fmt.Println(err)
+			return false
+		}
+	}
+	if libraryPath != "" {
+		fmt.Println("Loading Library:", filepath.Clean(libraryPath))
+		library, err = itunes.LoadLibraryContext(ctx, filepath.Clean(libraryPath))
+		if err != nil {
+			fmt.Println(err)
+			return false
+		}
+	}
+
+	report, err := export.VerifyExport(ctx, outputPath, library, checksum)
+	if err != nil {
+		fmt.Printf("Error verifying export: %v\n", err)
+		return false
+	}
+
+	for _, problem := range report.Problems {
+		if problem.Location != "" {
+			fmt.Printf("%v: %v: %v\n", problem.Playlist, problem.Problem, problem.Location)
+		} else {
+			fmt.Printf("%v: %v\n", problem.Playlist, problem.Problem)
+		}
+	}
+	fmt.Printf("Verified %v playlists referencing %v files, %v problems found.\n", report.Playlists, report.Files, len(report.Problems))
+	return len(report.Problems) == 0
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	outputDir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(outputDir)
+
+	musicFile, musicFileName := prepareMusicFile(t)
+	defer os.Remove(musicFile)
+
+	itunesDbFile := prepareItunesDbFile(t, filepath.ToSlash(musicFile))
+	defer os.Remove(itunesDbFile)
+
+	realArgs := os.Args
+	defer func() { os.Args = realArgs }()
+	os.Args = []string{"itunesexport", "-library", itunesDbFile, "-output", outputDir, "-includeAll", "-copy", "PLAYLIST", "-noCache"}
+	main()
+
+	if !runVerify([]string{"-output", outputDir, "-library", itunesDbFile, "-checksum"}) {
+		t.Fatal("expected export to verify successfully")
+	}
+
+	os.Remove(filepath.Join(outputDir, "My Playlist", musicFileName))
+	if runVerify([]string{"-output", outputDir}) {
+		t.Fatal("expected missing music file to be reported")
+	}
+	if runVerify(nil) {
+		t.Fatal("expected missing -output to be rejected")
+	}
+}
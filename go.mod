@@ -2,4 +2,10 @@ module github.com/ericdaugherty/itunesexport-go
 
 go 1.15
 
-require howett.net/plist v0.0.0-20201203080718-1454fab16a06
+require (
+	github.com/geoffgarside/ber v1.2.0 // indirect
+	github.com/hirochachacha/go-smb2 v1.1.0
+	golang.org/x/crypto v0.0.0-20220525230936-793ad666bf5e
+	golang.org/x/sys v0.7.0
+	howett.net/plist v0.0.0-20201203080718-1454fab16a06
+)
@@ -0,0 +1,182 @@
+// Package airplay plays music on AirPlay receivers which play media from URLs, such as the
+// Apple TV, using the HTTP requests of the original AirPlay protocol. Audio-only receivers,
+// which only accept a stream of encoded audio (RAOP), are not supported.
+package airplay
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/mdns"
+)
+
+// airplayService is the Bonjour service type of AirPlay receivers.
+const airplayService = "_airplay._tcp.local."
+
+// startTimeout is how long a receiver may take to start playing a URL.
+const startTimeout = 30 * time.Second
+
+// Device is an AirPlay receiver announced on the local network.
+type Device struct {
+	Name string
+	// Address is the host:port of the receiver.
+	Address string
+}
+
+// Discover queries the local network for AirPlay receivers with Bonjour and returns the
+// receivers which answered within timeout, sorted by name.
+func Discover(ctx context.Context, timeout time.Duration) ([]Device, error) {
+	instances, err := mdns.Browse(ctx, airplayService, timeout)
+	if err != nil {
+		return nil, err
+	}
+	var devices []Device
+	for _, instance := range instances {
+		devices = append(devices, Device{Name: instance.Name, Address: instance.Address})
+	}
+	return devices, nil
+}
+
+// Player plays URLs on an AirPlay receiver, one at a time.
+type Player struct {
+	address string
+	session string
+	client  *http.Client
+	// pollInterval is how often the playback position is requested.
+	pollInterval time.Duration
+}
+
+// NewPlayer returns a player for the receiver at address, e.g. 192.168.1.30:7000. The port
+// defaults to 7000.
+func NewPlayer(address string) *Player {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "7000")
+	}
+	// Receivers identify the session by its id and stop playing when the connection closes,
+	// so the connection is kept open between requests.
+	id := make([]byte, 16)
+	rand.Read(id)
+	return &Player{
+		address:      address,
+		session:      fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:]),
+		client:       &http.Client{Transport: &http.Transport{MaxIdleConnsPerHost: 1}},
+		pollInterval: time.Second,
+	}
+}
+
+// Play starts playing the media at url from its beginning, replacing the current media.
+func (player *Player) Play(ctx context.Context, url string) error {
+	body := "Content-Location: " + url + "\nStart-Position: 0\n"
+	response, err := player.request(ctx, "POST", "/play", "text/parameters", body)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	return nil
+}
+
+// Wait waits until the receiver finished playing the current media. If ctx is done first,
+// playing is stopped.
+func (player *Player) Wait(ctx context.Context) error {
+	started := false
+	deadline := time.Now().Add(startTimeout)
+	for {
+		duration, position, err := player.scrub(ctx)
+		if err != nil {
+			return player.stopped(ctx, err)
+		}
+		if duration > 0 {
+			started = true
+			if position >= duration-player.pollInterval.Seconds() {
+				return nil
+			}
+		} else if started {
+			// The receiver reports no media once it finished playing.
+			return nil
+		} else if time.Now().After(deadline) {
+			return fmt.Errorf("the receiver did not start playing within %v", startTimeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return player.stopped(ctx, ctx.Err())
+		case <-time.After(player.pollInterval):
+		}
+	}
+}
+
+// Stop stops playing.
+func (player *Player) Stop() error {
+	response, err := player.request(context.Background(), "POST", "/stop", "", "")
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	return nil
+}
+
+// stopped stops playing if ctx is done and returns the error of ctx, else err.
+func (player *Player) stopped(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		player.Stop()
+		return ctx.Err()
+	}
+	return err
+}
+
+// scrub returns the duration and playback position of the current media in seconds.
+func (player *Player) scrub(ctx context.Context) (duration, position float64, err error) {
+	response, err := player.request(ctx, "GET", "/scrub", "", "")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer response.Body.Close()
+
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		value, _ := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		switch strings.TrimSpace(parts[0]) {
+		case "duration":
+			duration = value
+		case "position":
+			position = value
+		}
+	}
+	return duration, position, scanner.Err()
+}
+
+func (player *Player) request(ctx context.Context, method, path, contentType, body string) (*http.Response, error) {
+	request, err := http.NewRequest(method, "http://"+player.address+path, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request = request.WithContext(ctx)
+	request.Header.Set("User-Agent", "MediaControl/1.0")
+	request.Header.Set("X-Apple-Session-ID", player.session)
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+	response, err := player.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		if response.StatusCode == http.StatusUnauthorized || response.StatusCode == http.StatusForbidden {
+			return nil, fmt.Errorf("the receiver requires a password or confirmation code, which is not supported")
+		}
+		return nil, fmt.Errorf("%v %v: the receiver responded with %v", method, path, response.Status)
+	}
+	return response, nil
+}
@@ -0,0 +1,78 @@
+package airplay
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPlayAndWait(t *testing.T) {
+	var (
+		mutex    sync.Mutex
+		played   []string
+		sessions = make(map[string]bool)
+		polls    int
+	)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		sessions[r.Header.Get("X-Apple-Session-ID")] = true
+		switch r.Method + " " + r.URL.Path {
+		case "POST /play":
+			body, _ := ioutil.ReadAll(r.Body)
+			played = append(played, string(body))
+			polls = 0
+		case "GET /scrub":
+			// Not started, playing, then finished.
+			polls++
+			switch polls {
+			case 1:
+				fmt.Fprint(w, "duration: 0.000000\nposition: 0.000000\n")
+			case 2:
+				fmt.Fprint(w, "duration: 210.000000\nposition: 12.500000\n")
+			default:
+				fmt.Fprint(w, "duration: 0.000000\nposition: 0.000000\n")
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer receiver.Close()
+
+	player := NewPlayer(strings.TrimPrefix(receiver.URL, "http://"))
+	player.pollInterval = time.Millisecond
+	ctx := context.Background()
+	for _, url := range []string{"http://server/audio/1.mp3", "http://server/audio/2.m4a"} {
+		if err := player.Play(ctx, url); err != nil {
+			t.Fatal(err)
+		}
+		if err := player.Wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(played) != 2 || played[1] != "Content-Location: http://server/audio/2.m4a\nStart-Position: 0\n" {
+		t.Fatalf("unexpected play requests: %q", played)
+	}
+	if polls != 3 || len(sessions) != 1 {
+		t.Fatalf("unexpected polls %v or sessions %v", polls, sessions)
+	}
+}
+
+func TestPlayRequiringPassword(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer receiver.Close()
+
+	err := NewPlayer(strings.TrimPrefix(receiver.URL, "http://")).Play(context.Background(), "http://server/audio/1.mp3")
+	if err == nil || !strings.Contains(err.Error(), "password") {
+		t.Fatalf("expected password error, got %v", err)
+	}
+}
@@ -0,0 +1,348 @@
+// Package cast plays music on Chromecast devices and Google speaker groups with the Cast
+// V2 protocol, using the Default Media Receiver app of the device.
+package cast
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/mdns"
+)
+
+// castService is the Bonjour service type of Cast devices and speaker groups.
+const castService = "_googlecast._tcp.local."
+
+// defaultMediaReceiver is the id of the receiver app playing media from URLs.
+const defaultMediaReceiver = "CC1AD845"
+
+// MaxQueueItems is the maximum number of tracks loaded into the queue of a device, as
+// messages to devices are limited to 64 KB.
+const MaxQueueItems = 100
+
+// Namespaces of the messages of the Cast V2 protocol.
+const (
+	namespaceConnection = "urn:x-cast:com.google.cast.tp.connection"
+	namespaceHeartbeat  = "urn:x-cast:com.google.cast.tp.heartbeat"
+	namespaceReceiver   = "urn:x-cast:com.google.cast.receiver"
+	namespaceMedia      = "urn:x-cast:com.google.cast.media"
+)
+
+const (
+	senderId   = "sender-0"
+	receiverId = "receiver-0"
+)
+
+// Device is a Cast device or speaker group announced on the local network.
+type Device struct {
+	// Name is the name of the device shown in the Google Home app.
+	Name string
+	// Address is the host:port of the device.
+	Address string
+}
+
+// Media is a track played on a device.
+type Media struct {
+	// URL is the URL the device loads the music file from.
+	URL         string
+	ContentType string
+	Title       string
+	Artist      string
+	Album       string
+}
+
+// Discover queries the local network for Cast devices and speaker groups with Bonjour and
+// returns the devices which answered within timeout, sorted by name.
+func Discover(ctx context.Context, timeout time.Duration) ([]Device, error) {
+	instances, err := mdns.Browse(ctx, castService, timeout)
+	if err != nil {
+		return nil, err
+	}
+	var devices []Device
+	for _, instance := range instances {
+		name := instance.Text["fn"]
+		if name == "" {
+			name = instance.Name
+		}
+		devices = append(devices, Device{Name: name, Address: instance.Address})
+	}
+	return devices, nil
+}
+
+// Play starts playing the tracks, at most MaxQueueItems, on the device at address, e.g.
+// 192.168.1.20:8009. It returns once the device has loaded the queue; the device keeps
+// playing after that.
+func Play(ctx context.Context, address string, tracks []Media) error {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "8009")
+	}
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	// Cast devices identify themselves with certificates signed by Google for the device,
+	// not for a host name, so the certificate is not verified.
+	conn, err := tls.DialWithDialer(dialer, "tcp", address, &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return play(ctx, conn, tracks)
+}
+
+// play launches the Default Media Receiver on the device connected to conn and loads the
+// tracks into its queue.
+func play(ctx context.Context, conn net.Conn, tracks []Media) error {
+	if len(tracks) == 0 {
+		return errors.New("no tracks to play")
+	}
+	if len(tracks) > MaxQueueItems {
+		tracks = tracks[:MaxQueueItems]
+	}
+	deadline := time.Now().Add(30 * time.Second)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	conn.SetDeadline(deadline)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Now())
+		case <-done:
+		}
+	}()
+
+	session := &session{conn: conn}
+	if err := session.send(receiverId, namespaceConnection, map[string]interface{}{"type": "CONNECT"}); err != nil {
+		return err
+	}
+	if err := session.send(receiverId, namespaceReceiver, map[string]interface{}{"type": "LAUNCH", "appId": defaultMediaReceiver, "requestId": 1}); err != nil {
+		return err
+	}
+
+	var transportId string
+	for transportId == "" {
+		message, err := session.receive()
+		if err != nil {
+			return ctxErr(ctx, err)
+		}
+		if message.namespace != namespaceReceiver {
+			continue
+		}
+		var status struct {
+			Type   string
+			Reason string
+			Status struct {
+				Applications []struct {
+					AppId       string
+					TransportId string
+				}
+			}
+		}
+		if err := json.Unmarshal([]byte(message.payload), &status); err != nil {
+			return err
+		}
+		switch status.Type {
+		case "LAUNCH_ERROR":
+			return fmt.Errorf("unable to start playing on the device: %v", status.Reason)
+		case "RECEIVER_STATUS":
+			for _, application := range status.Status.Applications {
+				if application.AppId == defaultMediaReceiver {
+					transportId = application.TransportId
+				}
+			}
+		}
+	}
+
+	if err := session.send(transportId, namespaceConnection, map[string]interface{}{"type": "CONNECT"}); err != nil {
+		return err
+	}
+	var items []interface{}
+	for _, track := range tracks {
+		items = append(items, map[string]interface{}{
+			"autoplay": true,
+			"media": map[string]interface{}{
+				"contentId":   track.URL,
+				"contentType": track.ContentType,
+				"streamType":  "BUFFERED",
+				"metadata": map[string]interface{}{
+					"metadataType": 3,
+					"title":        track.Title,
+					"artist":       track.Artist,
+					"albumName":    track.Album,
+				},
+			},
+		})
+	}
+	load := map[string]interface{}{"type": "QUEUE_LOAD", "requestId": 2, "items": items, "startIndex": 0, "repeatMode": "REPEAT_OFF"}
+	if err := session.send(transportId, namespaceMedia, load); err != nil {
+		return err
+	}
+
+	for {
+		message, err := session.receive()
+		if err != nil {
+			return ctxErr(ctx, err)
+		}
+		if message.namespace != namespaceMedia {
+			continue
+		}
+		var response struct {
+			Type      string
+			RequestId int
+			Reason    string
+		}
+		if err := json.Unmarshal([]byte(message.payload), &response); err != nil {
+			return err
+		}
+		switch response.Type {
+		case "MEDIA_STATUS":
+			if response.RequestId == 2 {
+				return nil
+			}
+		case "LOAD_FAILED", "LOAD_CANCELLED", "INVALID_REQUEST":
+			reason := response.Reason
+			if reason == "" {
+				reason = strings.ToLower(strings.Replace(response.Type, "_", " ", -1))
+			}
+			return fmt.Errorf("the device could not load the playlist: %v", reason)
+		}
+	}
+}
+
+// ctxErr returns the error of ctx, if it is done, else err.
+func ctxErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// session exchanges the messages of the Cast V2 protocol with a device.
+type session struct {
+	conn io.ReadWriter
+}
+
+// castMessage is a message of the Cast V2 protocol with a string payload.
+type castMessage struct {
+	source      string
+	destination string
+	namespace   string
+	payload     string
+}
+
+func (session *session) send(destination, namespace string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return writeMessage(session.conn, castMessage{source: senderId, destination: destination, namespace: namespace, payload: string(data)})
+}
+
+// receive returns the next message for the sender, answering heartbeats of the device.
+func (session *session) receive() (castMessage, error) {
+	for {
+		message, err := readMessage(session.conn)
+		if err != nil {
+			return message, err
+		}
+		if message.namespace == namespaceHeartbeat && strings.Contains(message.payload, `"PING"`) {
+			pong := castMessage{source: senderId, destination: message.source, namespace: namespaceHeartbeat, payload: `{"type":"PONG"}`}
+			if err := writeMessage(session.conn, pong); err != nil {
+				return message, err
+			}
+			continue
+		}
+		return message, nil
+	}
+}
+
+// writeMessage writes message as CastMessage protocol buffer, preceded by its length.
+func writeMessage(w io.Writer, message castMessage) error {
+	// protocol_version CASTV2_1_0 and payload_type STRING are 0, but required.
+	encoded := []byte{1<<3 | 0, 0}
+	encoded = appendField(encoded, 2, message.source)
+	encoded = appendField(encoded, 3, message.destination)
+	encoded = appendField(encoded, 4, message.namespace)
+	encoded = append(encoded, 5<<3|0, 0)
+	encoded = appendField(encoded, 6, message.payload)
+
+	frame := make([]byte, 4, 4+len(encoded))
+	binary.BigEndian.PutUint32(frame, uint32(len(encoded)))
+	_, err := w.Write(append(frame, encoded...))
+	return err
+}
+
+// appendField appends the string field with the number to the encoded protocol buffer.
+func appendField(encoded []byte, number int, value string) []byte {
+	encoded = append(encoded, byte(number<<3|2))
+	encoded = appendVarint(encoded, uint64(len(value)))
+	return append(encoded, value...)
+}
+
+func appendVarint(encoded []byte, value uint64) []byte {
+	for value >= 0x80 {
+		encoded = append(encoded, byte(value)|0x80)
+		value >>= 7
+	}
+	return append(encoded, byte(value))
+}
+
+// readMessage reads a CastMessage protocol buffer preceded by its length.
+func readMessage(r io.Reader) (castMessage, error) {
+	var message castMessage
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return message, err
+	}
+	length := binary.BigEndian.Uint32(header)
+	if length > 64*1024 {
+		return message, errors.New("cast message too long")
+	}
+	encoded := make([]byte, length)
+	if _, err := io.ReadFull(r, encoded); err != nil {
+		return message, err
+	}
+
+	for len(encoded) > 0 {
+		key, n := binary.Uvarint(encoded)
+		if n <= 0 {
+			return message, errors.New("invalid cast message")
+		}
+		encoded = encoded[n:]
+		switch key & 7 {
+		case 0:
+			if _, n = binary.Uvarint(encoded); n <= 0 {
+				return message, errors.New("invalid cast message")
+			}
+			encoded = encoded[n:]
+		case 2:
+			size, n := binary.Uvarint(encoded)
+			if n <= 0 || uint64(len(encoded)-n) < size {
+				return message, errors.New("invalid cast message")
+			}
+			value := string(encoded[n : n+int(size)])
+			encoded = encoded[n+int(size):]
+			switch key >> 3 {
+			case 2:
+				message.source = value
+			case 3:
+				message.destination = value
+			case 4:
+				message.namespace = value
+			case 6:
+				message.payload = value
+			}
+		default:
+			return message, errors.New("unsupported field type " + strconv.Itoa(int(key&7)) + " in cast message")
+		}
+	}
+	return message, nil
+}
@@ -0,0 +1,99 @@
+package cast
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestMessageRoundTrip(t *testing.T) {
+	message := castMessage{source: "sender-0", destination: "receiver-0", namespace: namespaceReceiver, payload: strings.Repeat("x", 300)}
+	var buffer bytes.Buffer
+	if err := writeMessage(&buffer, message); err != nil {
+		t.Fatal(err)
+	}
+	read, err := readMessage(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read != message {
+		t.Fatalf("expected %+v, got %+v", message, read)
+	}
+}
+
+// fakeDevice answers the messages of play like a device running the Default Media Receiver
+// and returns the items of the queue loaded.
+func fakeDevice(t *testing.T, conn net.Conn, items chan<- []interface{}) {
+	defer conn.Close()
+	reply := func(namespace, destination, payload string) {
+		if err := writeMessage(conn, castMessage{source: "receiver-0", destination: destination, namespace: namespace, payload: payload}); err != nil {
+			t.Error(err)
+		}
+	}
+	for {
+		message, err := readMessage(conn)
+		if err != nil {
+			return
+		}
+		var request struct {
+			Type  string
+			Items []interface{}
+		}
+		json.Unmarshal([]byte(message.payload), &request)
+		switch request.Type {
+		case "LAUNCH":
+			reply(namespaceHeartbeat, message.source, `{"type":"PING"}`)
+			reply(namespaceReceiver, message.source, `{"type":"RECEIVER_STATUS","status":{"applications":[{"appId":"CC1AD845","transportId":"web-1"}]}}`)
+		case "PONG":
+		case "QUEUE_LOAD":
+			if message.destination != "web-1" {
+				t.Errorf("expected the queue to be loaded on the transport of the app, got %v", message.destination)
+			}
+			items <- request.Items
+			reply(namespaceMedia, message.source, `{"type":"MEDIA_STATUS","requestId":2,"status":[]}`)
+		}
+	}
+}
+
+func TestPlay(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	items := make(chan []interface{}, 1)
+	go func() {
+		if device, err := listener.Accept(); err == nil {
+			fakeDevice(t, device, items)
+		}
+	}()
+	client, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tracks := []Media{{URL: "http://192.168.1.2:8080/audio/1.mp3", ContentType: "audio/mpeg", Title: "Some Song", Artist: "Some Artist"}}
+	if err := play(context.Background(), client, tracks); err != nil {
+		t.Fatal(err)
+	}
+	client.Close()
+
+	loaded := <-items
+	if len(loaded) != 1 || !strings.Contains(toJSON(t, loaded[0]), `"contentId":"http://192.168.1.2:8080/audio/1.mp3"`) {
+		t.Fatalf("unexpected queue %v", loaded)
+	}
+	if err := play(context.Background(), client, nil); err == nil {
+		t.Fatal("expected error without tracks")
+	}
+}
+
+func toJSON(t *testing.T, value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
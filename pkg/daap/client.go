@@ -0,0 +1,241 @@
+package daap
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+// DefaultPort is the port DAAP shares listen on unless announced otherwise.
+const DefaultPort = 3689
+
+// trackMeta are the fields requested for the tracks of a share.
+const trackMeta = "dmap.itemid,dmap.itemname,dmap.persistentid,daap.songartist,daap.songalbumartist,daap.songalbum," +
+	"daap.songcomposer,daap.songgenre,daap.songtime,daap.songsize,daap.songtracknumber,daap.songtrackcount," +
+	"daap.songdiscnumber,daap.songdisccount,daap.songyear,daap.songbitrate,daap.songsamplerate," +
+	"daap.songformat,daap.songuserrating,daap.songdateadded,daap.songdatemodified,com.apple.itunes.mediakind"
+
+// playlistMeta are the fields requested for the playlists of a share.
+const playlistMeta = "dmap.itemid,dmap.itemname,dmap.persistentid,dmap.parentcontainerid,daap.baseplaylist," +
+	"com.apple.itunes.smart-playlist"
+
+// Client is a session with a DAAP share.
+type Client struct {
+	address  string
+	password string
+	client   *http.Client
+	session  int64
+}
+
+// Open logs in to the share at address, host[:port], with the password or "" if the share
+// is not protected.
+func Open(ctx context.Context, address, password string) (*Client, error) {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, strconv.Itoa(DefaultPort))
+	}
+	client := &Client{address: address, password: password, client: &http.Client{Timeout: 5 * time.Minute}}
+	login, err := client.get(ctx, "/login", nil, "mlog")
+	if err != nil {
+		return nil, err
+	}
+	client.session = login.int("mlid")
+	return client, nil
+}
+
+// Close logs out of the share.
+func (client *Client) Close() error {
+	_, err := client.get(context.Background(), "/logout", nil, "")
+	return err
+}
+
+// get requests the path and returns the fields of the response chunk with the tag, or nil
+// if tag is empty.
+func (client *Client) get(ctx context.Context, path string, query url.Values, tag string) (fields, error) {
+	if query == nil {
+		query = url.Values{}
+	}
+	if client.session != 0 {
+		query.Set("session-id", strconv.FormatInt(client.session, 10))
+	}
+	requestURL := "http://" + client.address + path
+	if len(query) > 0 {
+		requestURL += "?" + query.Encode()
+	}
+	request, err := http.NewRequest(http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	request = request.WithContext(ctx)
+	request.Header.Set("Client-DAAP-Version", "3.0")
+	request.Header.Set("Viewer-Only-Client", "1")
+	if client.password != "" {
+		request.SetBasicAuth("itunesexport", client.password)
+	}
+
+	response, err := client.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusUnauthorized {
+		return nil, fmt.Errorf("DAAP share %v requires a password", client.address)
+	}
+	if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusNoContent {
+		return nil, fmt.Errorf("DAAP share %v responded %v to %v", client.address, response.Status, path)
+	}
+	if tag == "" {
+		io.Copy(ioutil.Discard, response.Body)
+		return nil, nil
+	}
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	return parseResponse(data, tag)
+}
+
+// LoadLibrary reads the tracks and playlists of the first database of the share. The
+// locations of the tracks are their daap:// stream URLs.
+func (client *Client) LoadLibrary(ctx context.Context) (*itunes.Library, error) {
+	databases, err := client.get(ctx, "/databases", nil, "avdb")
+	if err != nil {
+		return nil, err
+	}
+	items, err := databases.listing()
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("DAAP share %v has no database", client.address)
+	}
+	database := "/databases/" + strconv.FormatInt(items[0].int("miid"), 10)
+
+	library := &itunes.Library{Tracks: make(map[string]itunes.Track), Date: time.Now()}
+	songs, err := client.get(ctx, database+"/items", url.Values{"type": {"music"}, "meta": {trackMeta}}, "adbs")
+	if err != nil {
+		return nil, err
+	}
+	if items, err = songs.listing(); err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		track := client.track(database, item)
+		library.Tracks[strconv.Itoa(track.TrackId)] = track
+	}
+
+	containers, err := client.get(ctx, database+"/containers", url.Values{"meta": {playlistMeta}}, "aply")
+	if err != nil {
+		return nil, err
+	}
+	if items, err = containers.listing(); err != nil {
+		return nil, err
+	}
+	persistentIds := make(map[int64]string)
+	for _, item := range items {
+		persistentIds[item.int("miid")] = persistentId(item)
+	}
+	for _, item := range items {
+		playlist := itunes.Playlist{
+			Name:                 item.string("minm"),
+			Master:               item.int("abpl") != 0,
+			PlaylistId:           int(item.int("miid")),
+			PlaylistPersistentId: persistentIds[item.int("miid")],
+			ParentPersistentId:   persistentIds[item.int("mpco")],
+			Visible:              true,
+			AllItems:             true,
+		}
+		if item.int("aeSP") != 0 {
+			// The rules of smart playlists are not shared, only their tracks.
+			playlist.SmartInfo = []byte{}
+		}
+		if playlist.Master {
+			// The base playlist is named after the share, iTunes names it "Library".
+			playlist.Name = "Library"
+		}
+
+		path := fmt.Sprintf("%v/containers/%v/items", database, playlist.PlaylistId)
+		entries, err := client.get(ctx, path, url.Values{"meta": {"dmap.itemid,dmap.containeritemid"}}, "apso")
+		if err != nil {
+			return nil, err
+		}
+		tracks, err := entries.listing()
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range tracks {
+			if _, ok := library.Tracks[strconv.FormatInt(entry.int("miid"), 10)]; ok {
+				playlist.PlaylistItems = append(playlist.PlaylistItems, itunes.PlaylistItem{TrackId: int(entry.int("miid"))})
+			}
+		}
+		library.Playlists = append(library.Playlists, playlist)
+	}
+	// Folders are the parents of other playlists.
+	for i, playlist := range library.Playlists {
+		for _, child := range library.Playlists {
+			if child.ParentPersistentId != "" && child.ParentPersistentId == playlist.PlaylistPersistentId {
+				library.Playlists[i].Folder = true
+				break
+			}
+		}
+	}
+	library.BuildPlaylistMaps()
+	return library, nil
+}
+
+// track returns the track of an item of the database.
+func (client *Client) track(database string, item fields) itunes.Track {
+	format := item.string("asfm")
+	if format == "" {
+		format = "mp3"
+	}
+	id := item.int("miid")
+	return itunes.Track{
+		TrackId:      int(id),
+		Name:         item.string("minm"),
+		Artist:       item.string("asar"),
+		AlbumArtist:  item.string("asaa"),
+		Album:        item.string("asal"),
+		Composer:     item.string("ascp"),
+		Genre:        item.string("asgn"),
+		Kind:         format,
+		Size:         int(item.int("assz")),
+		TotalTime:    int(item.int("astm")),
+		TrackNumber:  int(item.int("astn")),
+		TrackCount:   int(item.int("astc")),
+		DiscNumber:   int(item.int("asdn")),
+		DiscCount:    int(item.int("asdc")),
+		Year:         int(item.int("asyr")),
+		BitRate:      int(item.int("asbr")),
+		SampleRate:   int(item.int("assr")),
+		Rating:       int(item.int("asur")),
+		DateAdded:    dmapTime(item.int("asda")),
+		DateModified: dmapTime(item.int("asdm")),
+		PersistentId: persistentId(item),
+		TrackType:    "URL",
+		Location:     fmt.Sprintf("daap://%v%v/items/%v.%v", client.address, database, id, format),
+	}
+}
+
+// persistentId returns the persistent id of the item in the hexadecimal form of iTunes.
+func persistentId(item fields) string {
+	if _, ok := item["mper"]; !ok {
+		return ""
+	}
+	return fmt.Sprintf("%016X", uint64(item.int("mper")))
+}
+
+// dmapTime returns the time of a DMAP date, seconds since 1970.
+func dmapTime(seconds int64) time.Time {
+	if seconds == 0 {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0).UTC()
+}
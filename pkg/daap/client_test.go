@@ -0,0 +1,131 @@
+package daap
+
+import (
+	"context"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+// encode returns the DMAP chunk with the tag and the value, which is a string, an integer
+// or a list of chunks.
+func encode(tag string, value interface{}) []byte {
+	var data []byte
+	switch value := value.(type) {
+	case string:
+		data = []byte(value)
+	case int:
+		data = make([]byte, 4)
+		binary.BigEndian.PutUint32(data, uint32(value))
+	case uint64:
+		data = make([]byte, 8)
+		binary.BigEndian.PutUint64(data, value)
+	case [][]byte:
+		for _, chunk := range value {
+			data = append(data, chunk...)
+		}
+	}
+	header := make([]byte, 8)
+	copy(header, tag)
+	binary.BigEndian.PutUint32(header[4:], uint32(len(data)))
+	return append(header, data...)
+}
+
+// listing returns a response with the tag listing the items.
+func listing(tag string, items ...[][]byte) []byte {
+	var list [][]byte
+	for _, item := range items {
+		list = append(list, encode("mlit", item))
+	}
+	return encode(tag, [][]byte{encode("mstt", 200), encode("mlcl", list)})
+}
+
+func TestLoadLibrary(t *testing.T) {
+	responses := map[string][]byte{
+		"/login":     encode("mlog", [][]byte{encode("mstt", 200), encode("mlid", 42)}),
+		"/databases": listing("avdb", [][]byte{encode("miid", 1), encode("minm", "Share")}),
+		"/databases/1/items": listing("adbs",
+			[][]byte{encode("miid", 10), encode("minm", "Song"), encode("asar", "Artist"), encode("asal", "Album"), encode("astm", 180000), encode("asfm", "m4a"), encode("mper", uint64(0xABCDEF))},
+			[][]byte{encode("miid", 11), encode("minm", "Other Song"), encode("asar", "Other Artist")},
+		),
+		"/databases/1/containers": listing("aply",
+			[][]byte{encode("miid", 100), encode("minm", "Share"), encode("abpl", 1), encode("mper", uint64(1))},
+			[][]byte{encode("miid", 101), encode("minm", "Folder"), encode("mper", uint64(2))},
+			[][]byte{encode("miid", 102), encode("minm", "Mix"), encode("mpco", 101), encode("aeSP", 1), encode("mper", uint64(3))},
+		),
+		"/databases/1/containers/100/items": listing("apso", [][]byte{encode("miid", 10)}, [][]byte{encode("miid", 11)}),
+		"/databases/1/containers/101/items": listing("apso"),
+		"/databases/1/containers/102/items": listing("apso", [][]byte{encode("miid", 11)}, [][]byte{encode("miid", 99)}),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, _ := r.BasicAuth(); password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		} else if r.URL.Path != "/login" && r.URL.Query().Get("session-id") != "42" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		} else if user == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		response, ok := responses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-dmap-tagged")
+		w.Write(response)
+	}))
+	defer server.Close()
+	address := strings.TrimPrefix(server.URL, "http://")
+
+	if _, err := Open(context.Background(), address, "wrong"); err == nil {
+		t.Fatal("expected login with the wrong password to fail")
+	}
+	client, err := Open(context.Background(), address, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	library, err := client.LoadLibrary(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	song := library.Tracks["10"]
+	expected := itunes.Track{
+		TrackId:      10,
+		Name:         "Song",
+		Artist:       "Artist",
+		Album:        "Album",
+		Kind:         "m4a",
+		TotalTime:    180000,
+		PersistentId: "0000000000ABCDEF",
+		TrackType:    "URL",
+		Location:     "daap://" + address + "/databases/1/items/10.m4a",
+	}
+	if !reflect.DeepEqual(song, expected) {
+		t.Fatalf("expected track %+v, got %+v", expected, song)
+	}
+	if len(library.Tracks) != 2 || library.Tracks["11"].Location != "daap://"+address+"/databases/1/items/11.mp3" {
+		t.Fatalf("unexpected tracks %+v", library.Tracks)
+	}
+
+	var playlists []string
+	for _, playlist := range library.Playlists {
+		playlists = append(playlists, playlist.Name)
+	}
+	if !reflect.DeepEqual(playlists, []string{"Library", "Folder", "Mix"}) {
+		t.Fatalf("unexpected playlists %v", playlists)
+	}
+	mix := library.PlaylistMap["Mix"]
+	if !library.Playlists[0].Master || !library.Playlists[1].Folder || mix.Folder || mix.SmartInfo == nil ||
+		mix.ParentPersistentId != library.Playlists[1].PlaylistPersistentId ||
+		!reflect.DeepEqual(mix.PlaylistItems, []itunes.PlaylistItem{{TrackId: 11}}) {
+		t.Fatalf("unexpected playlists %+v", library.Playlists)
+	}
+}
@@ -0,0 +1,106 @@
+// Package daap reads the library of an iTunes or forked-daapd share with the Digital Audio
+// Access Protocol.
+package daap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// chunk is an element of a DMAP response: a four letter tag and its content, which is an
+// integer, a string or a list of chunks depending on the tag.
+type chunk struct {
+	tag  string
+	data []byte
+}
+
+// parseChunks splits data into the chunks it contains.
+func parseChunks(data []byte) ([]chunk, error) {
+	var chunks []chunk
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return nil, errors.New("truncated DMAP chunk")
+		}
+		length := binary.BigEndian.Uint32(data[4:8])
+		if uint64(length) > uint64(len(data)-8) {
+			return nil, errors.New("truncated DMAP chunk " + string(data[:4]))
+		}
+		chunks = append(chunks, chunk{tag: string(data[:4]), data: data[8 : 8+length]})
+		data = data[8+length:]
+	}
+	return chunks, nil
+}
+
+// fields are the chunks contained in a chunk by tag. Fields occurring more than once keep
+// the first value.
+type fields map[string][]byte
+
+// parseFields returns the fields contained in data.
+func parseFields(data []byte) (fields, error) {
+	chunks, err := parseChunks(data)
+	if err != nil {
+		return nil, err
+	}
+	result := make(fields)
+	for _, chunk := range chunks {
+		if _, ok := result[chunk.tag]; !ok {
+			result[chunk.tag] = chunk.data
+		}
+	}
+	return result, nil
+}
+
+// parseResponse returns the fields of the response, which must be a single chunk with the
+// tag, e.g. "mlog" for a login.
+func parseResponse(data []byte, tag string) (fields, error) {
+	chunks, err := parseChunks(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(chunks) != 1 || chunks[0].tag != tag {
+		return nil, errors.New("unexpected DAAP response, expected " + tag)
+	}
+	response, err := parseFields(chunks[0].data)
+	if err != nil {
+		return nil, err
+	}
+	if status := response.int("mstt"); status != 0 && status != 200 {
+		return nil, fmt.Errorf("DAAP request failed with status %v", status)
+	}
+	return response, nil
+}
+
+// listing returns the fields of the items ("mlit") of the listing ("mlcl") of a response.
+func (f fields) listing() ([]fields, error) {
+	chunks, err := parseChunks(f["mlcl"])
+	if err != nil {
+		return nil, err
+	}
+	var items []fields
+	for _, chunk := range chunks {
+		if chunk.tag != "mlit" {
+			continue
+		}
+		item, err := parseFields(chunk.data)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// int returns the unsigned integer value of the field, or 0 if it is missing.
+func (f fields) int(tag string) int64 {
+	var value uint64
+	for _, b := range f[tag] {
+		value = value<<8 | uint64(b)
+	}
+	return int64(value)
+}
+
+// string returns the value of the field as a string.
+func (f fields) string(tag string) string {
+	return string(f[tag])
+}
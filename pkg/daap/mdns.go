@@ -0,0 +1,45 @@
+package daap
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/mdns"
+)
+
+// daapService is the Bonjour service type of DAAP shares.
+const daapService = "_daap._tcp.local."
+
+// Share is a DAAP share announced on the local network.
+type Share struct {
+	// Name is the name of the share shown in iTunes.
+	Name string
+	// Address is the host:port of the share.
+	Address string
+}
+
+// Discover queries the local network for DAAP shares with Bonjour and returns the shares
+// which answered within timeout, sorted by name.
+func Discover(ctx context.Context, timeout time.Duration) ([]Share, error) {
+	instances, err := mdns.Browse(ctx, daapService, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return shares(instances), nil
+}
+
+// shares returns the shares of the instances of the DAAP service, named by the machine
+// name of their TXT record, if any.
+func shares(instances []mdns.Instance) []Share {
+	var shares []Share
+	for _, instance := range instances {
+		name := instance.Text["Machine Name"]
+		if name == "" {
+			name = instance.Name
+		}
+		shares = append(shares, Share{Name: name, Address: instance.Address})
+	}
+	sort.Slice(shares, func(i, j int) bool { return shares[i].Name < shares[j].Name })
+	return shares
+}
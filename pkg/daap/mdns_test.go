@@ -0,0 +1,19 @@
+package daap
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/mdns"
+)
+
+func TestShares(t *testing.T) {
+	instances := []mdns.Instance{
+		{Name: "Music Server", Address: "192.168.1.5:3689", Text: map[string]string{"Machine Name": "My NAS"}},
+		{Name: "Library", Address: "192.168.1.6:3689"},
+	}
+	expected := []Share{{Name: "Library", Address: "192.168.1.6:3689"}, {Name: "My NAS", Address: "192.168.1.5:3689"}}
+	if shares := shares(instances); !reflect.DeepEqual(shares, expected) {
+		t.Fatalf("expected shares %+v, got %+v", expected, shares)
+	}
+}
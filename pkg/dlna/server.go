@@ -0,0 +1,363 @@
+// Package dlna serves playlists of an iTunes library as a UPnP/DLNA media server.
+package dlna
+
+import (
+	"crypto/md5"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+// Server is a media server with a container for each of its playlists.
+type Server struct {
+	// Name is the name the server is shown with on the network.
+	Name string
+	// TrackPath returns the file of a track. Defaults to the path of its location.
+	TrackPath func(track *itunes.Track) string
+
+	library   *itunes.Library
+	playlists []itunes.Playlist
+	tracks    map[int]bool
+	uuid      string
+}
+
+// NewServer returns a media server named name for the playlists of the library.
+func NewServer(name string, library *itunes.Library, playlists []itunes.Playlist) *Server {
+	server := &Server{Name: name, library: library, tracks: make(map[int]bool)}
+	for _, playlist := range playlists {
+		if playlist.Container() {
+			continue
+		}
+		server.playlists = append(server.playlists, playlist)
+		for _, item := range playlist.PlaylistItems {
+			server.tracks[item.TrackId] = true
+		}
+	}
+	// The id of the device stays the same for the library and name, so clients recognize it.
+	hash := md5.Sum([]byte(name + "\x00" + library.LibraryPersistentId))
+	server.uuid = fmt.Sprintf("uuid:%x-%x-%x-%x-%x", hash[0:4], hash[4:6], hash[6:8], hash[8:10], hash[10:16])
+	return server
+}
+
+// ServeHTTP serves the description, control and media requests of the media server.
+func (server *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/rootDesc.xml":
+		server.serveXML(w, fmt.Sprintf(deviceDescription, xmlEscape(server.Name), server.uuid))
+	case r.URL.Path == "/ContentDirectory.xml":
+		server.serveXML(w, contentDirectorySCPD)
+	case r.URL.Path == "/ConnectionManager.xml":
+		server.serveXML(w, connectionManagerSCPD)
+	case r.URL.Path == "/ctl/ContentDirectory" || r.URL.Path == "/ctl/ConnectionManager":
+		server.serveControl(w, r)
+	case strings.HasPrefix(r.URL.Path, "/evt/"):
+		// Clients may subscribe to events, which are never sent as the content does not change.
+		w.Header().Set("SID", server.uuid+"-"+path.Base(r.URL.Path))
+		w.Header().Set("TIMEOUT", "Second-1800")
+	case strings.HasPrefix(r.URL.Path, "/media/"):
+		server.serveMedia(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (server *Server) serveXML(w http.ResponseWriter, content string) {
+	w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	io.WriteString(w, content)
+}
+
+// serveControl handles a SOAP action.
+func (server *Server) serveControl(w http.ResponseWriter, r *http.Request) {
+	soapAction := strings.Trim(r.Header.Get("SOAPACTION"), `"`)
+	i := strings.LastIndex(soapAction, "#")
+	if r.Method != http.MethodPost || i < 0 {
+		http.Error(w, "Invalid SOAP action", http.StatusBadRequest)
+		return
+	}
+	serviceType, action := soapAction[:i], soapAction[i+1:]
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		return
+	}
+	args := soapArguments(body)
+
+	var results [][2]string
+	switch action {
+	case "Browse":
+		results, err = server.browse(r.Host, args)
+	case "GetSystemUpdateID":
+		results = [][2]string{{"Id", "1"}}
+	case "GetSearchCapabilities":
+		results = [][2]string{{"SearchCaps", ""}}
+	case "GetSortCapabilities":
+		results = [][2]string{{"SortCaps", ""}}
+	case "GetProtocolInfo":
+		results = [][2]string{{"Source", "http-get:*:audio/*:*"}, {"Sink", ""}}
+	case "GetCurrentConnectionIDs":
+		results = [][2]string{{"ConnectionIDs", "0"}}
+	default:
+		err = fmt.Errorf("Invalid Action %v", action)
+	}
+
+	w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, soapFault, xmlEscape(err.Error()))
+		return
+	}
+	var response strings.Builder
+	fmt.Fprintf(&response, `<u:%vResponse xmlns:u="%v">`, action, xmlEscape(serviceType))
+	for _, result := range results {
+		fmt.Fprintf(&response, "<%v>%v</%v>", result[0], xmlEscape(result[1]), result[0])
+	}
+	fmt.Fprintf(&response, "</u:%vResponse>", action)
+	fmt.Fprintf(w, soapEnvelope, response.String())
+}
+
+// soapArguments returns the arguments of the action in a SOAP request by name.
+func soapArguments(body []byte) map[string]string {
+	args := make(map[string]string)
+	decoder := xml.NewDecoder(strings.NewReader(string(body)))
+	depth := 0
+	name := ""
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return args
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			depth++
+			// Envelope, Body and the action contain the arguments.
+			if depth == 4 {
+				name = token.Name.Local
+				args[name] = ""
+			}
+		case xml.CharData:
+			if depth == 4 {
+				args[name] += string(token)
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+}
+
+// browse returns the children or the metadata of an object of the content directory.
+// The root "0" contains the playlists, "p<id>" the tracks of the playlist with the id,
+// which are "p<id>/<index>".
+func (server *Server) browse(host string, args map[string]string) ([][2]string, error) {
+	start, _ := strconv.Atoi(args["StartingIndex"])
+	count, _ := strconv.Atoi(args["RequestedCount"])
+	id := args["ObjectID"]
+
+	var objects []string
+	switch args["BrowseFlag"] {
+	case "BrowseMetadata":
+		object, ok := server.object(host, id)
+		if !ok {
+			return nil, fmt.Errorf("No such object %v", id)
+		}
+		objects = []string{object}
+	case "BrowseDirectChildren":
+		children, ok := server.children(host, id)
+		if !ok {
+			return nil, fmt.Errorf("No such container %v", id)
+		}
+		objects = children
+	default:
+		return nil, fmt.Errorf("Invalid BrowseFlag %v", args["BrowseFlag"])
+	}
+
+	total := len(objects)
+	if start < 0 {
+		start = 0
+	}
+	if start > len(objects) {
+		start = len(objects)
+	}
+	if count < 0 {
+		count = 0
+	}
+	objects = objects[start:]
+	if count > 0 && count < len(objects) {
+		objects = objects[:count]
+	}
+	result := didlHeader + strings.Join(objects, "") + "</DIDL-Lite>"
+	return [][2]string{
+		{"Result", result},
+		{"NumberReturned", strconv.Itoa(len(objects))},
+		{"TotalMatches", strconv.Itoa(total)},
+		{"UpdateID", "1"},
+	}, nil
+}
+
+// object returns the DIDL-Lite element of the object with the id.
+func (server *Server) object(host, id string) (string, bool) {
+	if id == "0" {
+		return fmt.Sprintf(`<container id="0" parentID="-1" restricted="1" childCount="%v"><dc:title>%v</dc:title><upnp:class>object.container</upnp:class></container>`,
+			len(server.playlists), xmlEscape(server.Name)), true
+	}
+	playlistId, index := id, -1
+	if i := strings.Index(id, "/"); i >= 0 {
+		var err error
+		if index, err = strconv.Atoi(id[i+1:]); err != nil {
+			return "", false
+		}
+		playlistId = id[:i]
+	}
+	playlist, ok := server.playlist(playlistId)
+	if !ok {
+		return "", false
+	}
+	if index < 0 {
+		return server.container(playlistId, playlist), true
+	}
+	tracks := playlist.Tracks(server.library)
+	if index >= len(tracks) {
+		return "", false
+	}
+	return server.item(host, playlistId, index, &tracks[index]), true
+}
+
+// children returns the DIDL-Lite elements of the children of the container with the id.
+func (server *Server) children(host, id string) ([]string, bool) {
+	var children []string
+	if id == "0" {
+		for _, playlist := range server.playlists {
+			children = append(children, server.container("p"+strconv.Itoa(playlist.PlaylistId), playlist))
+		}
+		return children, true
+	}
+	playlist, ok := server.playlist(id)
+	if !ok {
+		return nil, false
+	}
+	tracks := playlist.Tracks(server.library)
+	for i := range tracks {
+		children = append(children, server.item(host, id, i, &tracks[i]))
+	}
+	return children, true
+}
+
+// playlist returns the playlist with the object id "p<id>".
+func (server *Server) playlist(id string) (itunes.Playlist, bool) {
+	for _, playlist := range server.playlists {
+		if id == "p"+strconv.Itoa(playlist.PlaylistId) {
+			return playlist, true
+		}
+	}
+	return itunes.Playlist{}, false
+}
+
+func (server *Server) container(id string, playlist itunes.Playlist) string {
+	return fmt.Sprintf(`<container id="%v" parentID="0" restricted="1" childCount="%v"><dc:title>%v</dc:title><upnp:class>object.container.playlistContainer</upnp:class></container>`,
+		id, len(playlist.Tracks(server.library)), xmlEscape(playlist.Name))
+}
+
+func (server *Server) item(host, parentId string, index int, track *itunes.Track) string {
+	extension := strings.ToLower(path.Ext(server.trackPath(track)))
+	var item strings.Builder
+	fmt.Fprintf(&item, `<item id="%v/%v" parentID="%v" restricted="1">`, parentId, index, parentId)
+	fmt.Fprintf(&item, "<dc:title>%v</dc:title>", xmlEscape(track.Title()))
+	if track.Artist != "" {
+		fmt.Fprintf(&item, "<dc:creator>%v</dc:creator><upnp:artist>%v</upnp:artist>", xmlEscape(track.Artist), xmlEscape(track.Artist))
+	}
+	if track.Album != "" {
+		fmt.Fprintf(&item, "<upnp:album>%v</upnp:album>", xmlEscape(track.Album))
+	}
+	if track.Genre != "" {
+		fmt.Fprintf(&item, "<upnp:genre>%v</upnp:genre>", xmlEscape(track.Genre))
+	}
+	if track.TrackNumber > 0 {
+		fmt.Fprintf(&item, "<upnp:originalTrackNumber>%v</upnp:originalTrackNumber>", track.TrackNumber)
+	}
+	item.WriteString("<upnp:class>object.item.audioItem.musicTrack</upnp:class>")
+	fmt.Fprintf(&item, `<res protocolInfo="http-get:*:%v:%v"`, MimeType(extension), contentFeatures)
+	if track.Size > 0 {
+		fmt.Fprintf(&item, ` size="%v"`, track.Size)
+	}
+	if track.TotalTime > 0 {
+		duration := time.Duration(track.TotalTime) * time.Millisecond
+		fmt.Fprintf(&item, ` duration="%d:%02d:%02d.%03d"`, int(duration.Hours()), int(duration.Minutes())%60, int(duration.Seconds())%60, track.TotalTime%1000)
+	}
+	fmt.Fprintf(&item, ">http://%v/media/%v%v</res></item>", xmlEscape(host), track.TrackId, extension)
+	return item.String()
+}
+
+// serveMedia serves the file of a track of the playlists, /media/<track id>.<extension>.
+func (server *Server) serveMedia(w http.ResponseWriter, r *http.Request) {
+	name := path.Base(r.URL.Path)
+	id, err := strconv.Atoi(strings.TrimSuffix(name, path.Ext(name)))
+	if err != nil || !server.tracks[id] {
+		http.NotFound(w, r)
+		return
+	}
+	track, ok := server.library.Tracks[strconv.Itoa(id)]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	file, err := os.Open(server.trackPath(&track))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", MimeType(strings.ToLower(path.Ext(name))))
+	w.Header().Set("transferMode.dlna.org", "Streaming")
+	w.Header().Set("contentFeatures.dlna.org", contentFeatures)
+	http.ServeContent(w, r, name, info.ModTime(), file)
+}
+
+func (server *Server) trackPath(track *itunes.Track) string {
+	if server.TrackPath != nil {
+		return server.TrackPath(track)
+	}
+	return itunes.LocationPath(track.Location)
+}
+
+// contentFeatures tells DLNA clients that the files support seeking by byte ranges.
+const contentFeatures = "DLNA.ORG_OP=01;DLNA.ORG_CI=0;DLNA.ORG_FLAGS=01700000000000000000000000000000"
+
+// MimeType returns the MIME type of audio files with the extension, e.g. .mp3.
+func MimeType(extension string) string {
+	switch extension {
+	case ".mp3":
+		return "audio/mpeg"
+	case ".m4a", ".m4b", ".m4p", ".aac", ".mp4":
+		return "audio/mp4"
+	case ".flac":
+		return "audio/flac"
+	case ".wav":
+		return "audio/wav"
+	case ".aif", ".aiff":
+		return "audio/aiff"
+	case ".ogg", ".oga":
+		return "audio/ogg"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+func xmlEscape(s string) string {
+	var escaped strings.Builder
+	xml.EscapeText(&escaped, []byte(s))
+	return escaped.String()
+}
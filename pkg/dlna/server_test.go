@@ -0,0 +1,142 @@
+package dlna
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+func testServer(t *testing.T) (*Server, string) {
+	dir, err := ioutil.TempDir("", "dlna")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	song := filepath.Join(dir, "song.mp3")
+	if err := ioutil.WriteFile(song, []byte("0123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	library := &itunes.Library{Tracks: map[string]itunes.Track{
+		"1": {TrackId: 1, Name: "Song & Dance", Artist: "Artist", Album: "Album", TotalTime: 185500, Size: 10, Location: "file://" + filepath.ToSlash(song)},
+		"2": {TrackId: 2, Name: "Other", Location: "file:///missing.m4a"},
+		"3": {TrackId: 3, Name: "Not Shared", Location: "file://" + filepath.ToSlash(song)},
+	}}
+	playlists := []itunes.Playlist{
+		{Name: "Folder", PlaylistId: 5, Folder: true},
+		{Name: "Mix", PlaylistId: 7, PlaylistItems: []itunes.PlaylistItem{{TrackId: 1}, {TrackId: 2}}},
+		{Name: "Empty", PlaylistId: 8},
+	}
+	return NewServer("Test <Server>", library, playlists), dir
+}
+
+// soap calls the action of the service of the server and returns the response.
+func soap(t *testing.T, server *Server, service, action, args string) (int, string) {
+	body := `<?xml version="1.0"?><s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body>` +
+		`<u:` + action + ` xmlns:u="urn:schemas-upnp-org:service:` + service + `:1">` + args + `</u:` + action + `></s:Body></s:Envelope>`
+	request := httptest.NewRequest(http.MethodPost, "/ctl/"+service, strings.NewReader(body))
+	request.Host = "192.168.1.2:8200"
+	request.Header.Set("SOAPACTION", `"urn:schemas-upnp-org:service:`+service+`:1#`+action+`"`)
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+	return recorder.Code, recorder.Body.String()
+}
+
+func TestBrowse(t *testing.T) {
+	server, _ := testServer(t)
+
+	code, response := soap(t, server, "ContentDirectory", "Browse", "<ObjectID>0</ObjectID><BrowseFlag>BrowseDirectChildren</BrowseFlag><StartingIndex>0</StartingIndex><RequestedCount>0</RequestedCount>")
+	if code != http.StatusOK {
+		t.Fatalf("Browse failed: %v", response)
+	}
+	for _, expected := range []string{
+		`&lt;container id=&#34;p7&#34; parentID=&#34;0&#34; restricted=&#34;1&#34; childCount=&#34;2&#34;&gt;&lt;dc:title&gt;Mix&lt;/dc:title&gt;`,
+		`&lt;dc:title&gt;Empty&lt;/dc:title&gt;`,
+		"<NumberReturned>2</NumberReturned><TotalMatches>2</TotalMatches>",
+	} {
+		if !strings.Contains(response, expected) {
+			t.Errorf("expected %v in %v", expected, response)
+		}
+	}
+	if strings.Contains(response, "Folder") {
+		t.Errorf("expected folders not to be served: %v", response)
+	}
+
+	code, response = soap(t, server, "ContentDirectory", "Browse", "<ObjectID>p7</ObjectID><BrowseFlag>BrowseDirectChildren</BrowseFlag><StartingIndex>1</StartingIndex><RequestedCount>5</RequestedCount>")
+	if code != http.StatusOK || !strings.Contains(response, "<NumberReturned>1</NumberReturned><TotalMatches>2</TotalMatches>") ||
+		!strings.Contains(response, `http-get:*:audio/mp4:`) || !strings.Contains(response, "http://192.168.1.2:8200/media/2.m4a") {
+		t.Fatalf("unexpected second page of playlist: %v", response)
+	}
+
+	code, response = soap(t, server, "ContentDirectory", "Browse", "<ObjectID>p7</ObjectID><BrowseFlag>BrowseDirectChildren</BrowseFlag><StartingIndex>-1</StartingIndex><RequestedCount>-1</RequestedCount>")
+	if code != http.StatusOK || !strings.Contains(response, "<NumberReturned>2</NumberReturned><TotalMatches>2</TotalMatches>") {
+		t.Fatalf("expected a negative index and count to return the whole playlist: %v", response)
+	}
+
+	code, response = soap(t, server, "ContentDirectory", "Browse", "<ObjectID>p7/0</ObjectID><BrowseFlag>BrowseMetadata</BrowseFlag>")
+	for _, expected := range []string{
+		`&lt;item id=&#34;p7/0&#34; parentID=&#34;p7&#34;`,
+		`&lt;dc:title&gt;Song &amp;amp; Dance&lt;/dc:title&gt;`,
+		`&lt;upnp:album&gt;Album&lt;/upnp:album&gt;`,
+		`size=&#34;10&#34; duration=&#34;0:03:05.500&#34;&gt;http://192.168.1.2:8200/media/1.mp3&lt;/res&gt;`,
+	} {
+		if code != http.StatusOK || !strings.Contains(response, expected) {
+			t.Errorf("expected %v in %v", expected, response)
+		}
+	}
+
+	if code, response = soap(t, server, "ContentDirectory", "Browse", "<ObjectID>p5</ObjectID><BrowseFlag>BrowseMetadata</BrowseFlag>"); code != http.StatusInternalServerError || !strings.Contains(response, "<errorCode>701</errorCode>") {
+		t.Errorf("expected folder not to be found: %v", response)
+	}
+	if code, response = soap(t, server, "ConnectionManager", "GetProtocolInfo", ""); code != http.StatusOK || !strings.Contains(response, "<Source>http-get:*:audio/*:*</Source>") {
+		t.Errorf("unexpected protocol info: %v", response)
+	}
+}
+
+func TestServeMedia(t *testing.T) {
+	server, _ := testServer(t)
+
+	request := httptest.NewRequest(http.MethodGet, "/media/1.mp3", nil)
+	request.Header.Set("Range", "bytes=2-5")
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusPartialContent || recorder.Body.String() != "2345" || recorder.Header().Get("Content-Type") != "audio/mpeg" {
+		t.Fatalf("unexpected response %v %v %v", recorder.Code, recorder.Header(), recorder.Body.String())
+	}
+
+	for _, path := range []string{"/media/3.mp3", "/media/2.m4a", "/media/x.mp3"} {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		if recorder.Code != http.StatusNotFound {
+			t.Errorf("%v: expected not found, got %v", path, recorder.Code)
+		}
+	}
+}
+
+func TestDescriptionAndSearch(t *testing.T) {
+	server, _ := testServer(t)
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/rootDesc.xml", nil))
+	if !strings.Contains(recorder.Body.String(), "<friendlyName>Test &lt;Server&gt;</friendlyName>") || !strings.Contains(recorder.Body.String(), "<UDN>"+server.uuid+"</UDN>") {
+		t.Fatalf("unexpected description %v", recorder.Body.String())
+	}
+
+	if responses := server.searchResponses("ssdp:all", "http://host/rootDesc.xml"); len(responses) != 5 {
+		t.Fatalf("expected 5 responses to ssdp:all, got %v", len(responses))
+	}
+	responses := server.searchResponses("urn:schemas-upnp-org:device:MediaServer:1", "http://host/rootDesc.xml")
+	if len(responses) != 1 || !strings.Contains(responses[0], "USN: "+server.uuid+"::urn:schemas-upnp-org:device:MediaServer:1\r\n") ||
+		!strings.Contains(responses[0], "LOCATION: http://host/rootDesc.xml\r\n") {
+		t.Fatalf("unexpected responses %v", responses)
+	}
+	if responses := server.searchResponses("urn:schemas-upnp-org:device:InternetGatewayDevice:1", "http://host/rootDesc.xml"); len(responses) != 0 {
+		t.Fatalf("expected no responses for other devices, got %v", responses)
+	}
+}
@@ -0,0 +1,136 @@
+package dlna
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ssdpAddress is the multicast address of SSDP discovery.
+var ssdpAddress = &net.UDPAddr{IP: net.IPv4(239, 255, 255, 250), Port: 1900}
+
+// ssdpMaxAge is how long clients remember the server after an announcement, in seconds.
+const ssdpMaxAge = 1800
+
+// serverHeader identifies the server in SSDP messages.
+const serverHeader = "itunesexport/1.0 UPnP/1.0 DLNADOC/1.50"
+
+// notificationTypes returns the types the server announces, with the USN of each.
+func (server *Server) notificationTypes() [][2]string {
+	types := [][2]string{{"upnp:rootdevice", server.uuid + "::upnp:rootdevice"}, {server.uuid, server.uuid}}
+	for _, urn := range []string{
+		"urn:schemas-upnp-org:device:MediaServer:1",
+		"urn:schemas-upnp-org:service:ContentDirectory:1",
+		"urn:schemas-upnp-org:service:ConnectionManager:1",
+	} {
+		types = append(types, [2]string{urn, server.uuid + "::" + urn})
+	}
+	return types
+}
+
+// searchResponses returns the responses to an M-SEARCH request for the search target st.
+func (server *Server) searchResponses(st, location string) []string {
+	var responses []string
+	for _, nt := range server.notificationTypes() {
+		if st != "ssdp:all" && st != nt[0] {
+			continue
+		}
+		responses = append(responses, fmt.Sprintf("HTTP/1.1 200 OK\r\nCACHE-CONTROL: max-age=%v\r\nEXT:\r\nLOCATION: %v\r\nSERVER: %v\r\nST: %v\r\nUSN: %v\r\n\r\n",
+			ssdpMaxAge, location, serverHeader, nt[0], nt[1]))
+	}
+	return responses
+}
+
+// notifications returns the NOTIFY messages announcing the server, or its leaving if alive
+// is false.
+func (server *Server) notifications(location string, alive bool) []string {
+	var messages []string
+	for _, nt := range server.notificationTypes() {
+		if alive {
+			messages = append(messages, fmt.Sprintf("NOTIFY * HTTP/1.1\r\nHOST: %v\r\nCACHE-CONTROL: max-age=%v\r\nLOCATION: %v\r\nNT: %v\r\nNTS: ssdp:alive\r\nSERVER: %v\r\nUSN: %v\r\n\r\n",
+				ssdpAddress, ssdpMaxAge, location, nt[0], serverHeader, nt[1]))
+		} else {
+			messages = append(messages, fmt.Sprintf("NOTIFY * HTTP/1.1\r\nHOST: %v\r\nNT: %v\r\nNTS: ssdp:byebye\r\nUSN: %v\r\n\r\n", ssdpAddress, nt[0], nt[1]))
+		}
+	}
+	return messages
+}
+
+// ListenAndServe serves the media server on address, e.g. ":8200", and announces it on the
+// local network until ctx is done.
+func (server *Server) ListenAndServe(ctx context.Context, address string) error {
+	listener, err := net.Listen("tcp4", address)
+	if err != nil {
+		return err
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	httpServer := &http.Server{Handler: server}
+	errs := make(chan error, 2)
+	go func() { errs <- httpServer.Serve(listener) }()
+	defer httpServer.Close()
+
+	multicast, err := net.ListenMulticastUDP("udp4", nil, ssdpAddress)
+	if err != nil {
+		return err
+	}
+	defer multicast.Close()
+	sender, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return err
+	}
+	defer sender.Close()
+
+	// The location is reachable at the address of the interface used to reach the network.
+	location := func(remote *net.UDPAddr) string {
+		host := "127.0.0.1"
+		if conn, err := net.DialUDP("udp4", nil, remote); err == nil {
+			host = conn.LocalAddr().(*net.UDPAddr).IP.String()
+			conn.Close()
+		}
+		return fmt.Sprintf("http://%v/rootDesc.xml", net.JoinHostPort(host, fmt.Sprint(port)))
+	}
+	announce := func(alive bool) {
+		for _, message := range server.notifications(location(ssdpAddress), alive) {
+			sender.WriteToUDP([]byte(message), ssdpAddress)
+		}
+	}
+
+	go func() {
+		buffer := make([]byte, 2048)
+		for {
+			n, remote, err := multicast.ReadFromUDP(buffer)
+			if err != nil {
+				errs <- err
+				return
+			}
+			request, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(buffer[:n])))
+			if err != nil || request.Method != "M-SEARCH" || strings.Trim(request.Header.Get("MAN"), `"`) != "ssdp:discover" {
+				continue
+			}
+			for _, response := range server.searchResponses(request.Header.Get("ST"), location(remote)) {
+				sender.WriteToUDP([]byte(response), remote)
+			}
+		}
+	}()
+
+	announce(true)
+	ticker := time.NewTicker(ssdpMaxAge / 3 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			announce(true)
+		case err := <-errs:
+			announce(false)
+			return err
+		case <-ctx.Done():
+			announce(false)
+			return nil
+		}
+	}
+}
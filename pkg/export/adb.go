@@ -0,0 +1,185 @@
+package export
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ADBFS writes the export to a directory of an Android device by running adb. If several
+// devices are attached, the device is chosen by the ANDROID_SERIAL environment variable.
+type ADBFS struct {
+	root string
+	// run runs adb with the arguments and returns its standard output.
+	run func(args ...string) ([]byte, error)
+}
+
+// FindADB returns the path of the adb binary, which is searched in the PATH and in the
+// platform-tools of the Android SDK named by ANDROID_HOME or ANDROID_SDK_ROOT.
+func FindADB() (string, error) {
+	if adb, err := exec.LookPath("adb"); err == nil {
+		return adb, nil
+	}
+
+	name := "adb"
+	if runtime.GOOS == "windows" {
+		name = "adb.exe"
+	}
+	for _, variable := range []string{"ANDROID_HOME", "ANDROID_SDK_ROOT"} {
+		if sdk := os.Getenv(variable); sdk != "" {
+			adb := filepath.Join(sdk, "platform-tools", name)
+			if _, err := os.Stat(adb); err == nil {
+				return adb, nil
+			}
+		}
+	}
+	return "", errors.New("adb not found, install the Android platform tools and add them to the PATH")
+}
+
+// NewADBFS returns an FS writing to the directory root, e.g. /sdcard/Music, of the device
+// using the adb binary.
+func NewADBFS(adb, root string) *ADBFS {
+	return &ADBFS{root: root, run: func(args ...string) ([]byte, error) {
+		var stderr bytes.Buffer
+		command := exec.Command(adb, args...)
+		command.Stderr = &stderr
+		output, err := command.Output()
+		if err != nil {
+			if message := strings.TrimSpace(stderr.String() + string(output)); message != "" {
+				err = fmt.Errorf("adb %v: %v", args[0], message)
+			}
+		}
+		return output, err
+	}}
+}
+
+func (fsys *ADBFS) path(name string) string {
+	return path.Join(fsys.root, name)
+}
+
+// shell runs a command in the shell of the device. Errors reporting a missing file are
+// returned as os.ErrNotExist.
+func (fsys *ADBFS) shell(op, name, command string) ([]byte, error) {
+	// The exit status of the command is printed, as old versions of adb do not return it.
+	output, err := fsys.run("shell", command+"; echo \"exit:$?\"")
+	if err != nil {
+		return nil, err
+	}
+	i := bytes.LastIndex(output, []byte("exit:"))
+	if i < 0 {
+		return nil, &os.PathError{Op: op, Path: name, Err: errors.New("no exit status from adb shell")}
+	}
+	status, message := strings.TrimSpace(string(output[i+len("exit:"):])), strings.TrimSpace(string(output[:i]))
+	switch {
+	case status == "0":
+		return output[:i], nil
+	case strings.Contains(message, "No such file"):
+		return nil, &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+	default:
+		return nil, &os.PathError{Op: op, Path: name, Err: errors.New(message)}
+	}
+}
+
+// shellQuote quotes s for the shell of the device.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// adbTempCounter makes the names of temporary files unique.
+var adbTempCounter int64
+
+// Create writes to a local temporary file which is pushed to the device on Close.
+func (fsys *ADBFS) Create(name string) (File, error) {
+	local, err := ioutil.TempFile("", "itunesexport-adb-*")
+	if err != nil {
+		return nil, err
+	}
+	return &adbFile{File: local, fsys: fsys, name: fsys.path(name)}, nil
+}
+
+// Open reads the file from the device.
+func (fsys *ADBFS) Open(name string) (io.ReadCloser, error) {
+	if _, err := fsys.Stat(name); err != nil {
+		return nil, err
+	}
+	content, err := fsys.run("exec-out", "cat "+shellQuote(fsys.path(name)))
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(content)), nil
+}
+
+func (fsys *ADBFS) Stat(name string) (os.FileInfo, error) {
+	fileName := fsys.path(name)
+	output, err := fsys.shell("stat", fileName, "stat -c '%s %Y %F' "+shellQuote(fileName))
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.SplitN(strings.TrimSpace(string(output)), " ", 3)
+	if len(fields) != 3 {
+		return nil, &os.PathError{Op: "stat", Path: fileName, Err: errors.New("unexpected stat output " + string(output))}
+	}
+	size, _ := strconv.ParseInt(fields[0], 10, 64)
+	modTime, _ := strconv.ParseInt(fields[1], 10, 64)
+	info := &remoteFileInfo{name: path.Base(fileName), size: size, mode: 0644, modTime: time.Unix(modTime, 0)}
+	if fields[2] == "directory" {
+		info.mode = os.ModeDir | 0755
+	}
+	return info, nil
+}
+
+func (fsys *ADBFS) MkdirAll(name string) error {
+	fileName := fsys.path(name)
+	_, err := fsys.shell("mkdir", fileName, "mkdir -p "+shellQuote(fileName))
+	return err
+}
+
+func (fsys *ADBFS) Remove(name string) error {
+	fileName := fsys.path(name)
+	_, err := fsys.shell("remove", fileName, "rm "+shellQuote(fileName))
+	return err
+}
+
+// adbFile is a file being written to an ADBFS.
+type adbFile struct {
+	*os.File
+	fsys *ADBFS
+	name string
+}
+
+// Close pushes the file to a temporary file on the device, which is then moved into place.
+func (file *adbFile) Close() error {
+	defer os.Remove(file.File.Name())
+	if err := file.File.Close(); err != nil {
+		return err
+	}
+
+	fsys := file.fsys
+	tempName := path.Join(path.Dir(file.name), fmt.Sprintf(".%v.%v.tmp", path.Base(file.name), atomic.AddInt64(&adbTempCounter, 1)))
+	if _, err := fsys.shell("mkdir", file.name, "mkdir -p "+shellQuote(path.Dir(file.name))); err != nil {
+		return err
+	}
+	if _, err := fsys.run("push", file.File.Name(), tempName); err != nil {
+		fsys.shell("remove", tempName, "rm -f "+shellQuote(tempName))
+		return err
+	}
+	_, err := fsys.shell("rename", file.name, "mv -f "+shellQuote(tempName)+" "+shellQuote(file.name))
+	return err
+}
+
+func (file *adbFile) Abort() error {
+	file.File.Close()
+	return os.Remove(file.File.Name())
+}
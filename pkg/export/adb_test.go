@@ -0,0 +1,195 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// shellSplit splits a command quoted by shellQuote into its words.
+func shellSplit(command string) []string {
+	var words []string
+	var word strings.Builder
+	inWord, quoted := false, false
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+		switch {
+		case c == '\'':
+			quoted, inWord = !quoted, true
+		case c == '\\' && !quoted && i+1 < len(command):
+			i++
+			word.WriteByte(command[i])
+		case c == ' ' && !quoted:
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words
+}
+
+// newFakeADBFS returns an ADBFS writing to dir, running the commands sent to adb locally.
+func newFakeADBFS(dir string) (*ADBFS, *[]string) {
+	var commands []string
+	device := func(name string) string {
+		return filepath.Join(dir, filepath.FromSlash(name))
+	}
+
+	run := func(args ...string) ([]byte, error) {
+		commands = append(commands, strings.Join(args, " "))
+		switch args[0] {
+		case "push":
+			content, err := ioutil.ReadFile(args[1])
+			if err != nil {
+				return nil, err
+			}
+			return nil, ioutil.WriteFile(device(args[2]), content, 0644)
+		case "exec-out":
+			return ioutil.ReadFile(device(shellSplit(args[1])[1]))
+		case "shell":
+			command := strings.TrimSuffix(args[1], `; echo "exit:$?"`)
+			words := shellSplit(command)
+			var err error
+			var output string
+			switch words[0] {
+			case "stat":
+				var info os.FileInfo
+				if info, err = os.Stat(device(words[3])); err == nil {
+					kind := "regular file"
+					if info.IsDir() {
+						kind = "directory"
+					}
+					output = fmt.Sprintf("%v %v %v\n", info.Size(), info.ModTime().Unix(), kind)
+				}
+			case "mkdir":
+				err = os.MkdirAll(device(words[2]), 0777)
+			case "rm":
+				if err = os.Remove(device(words[len(words)-1])); err != nil && words[1] == "-f" {
+					err = nil
+				}
+			case "mv":
+				err = os.Rename(device(words[2]), device(words[3]))
+			default:
+				err = errors.New("unsupported command")
+			}
+			if os.IsNotExist(err) {
+				return []byte(words[0] + ": No such file or directory\nexit:1\n"), nil
+			} else if err != nil {
+				return []byte(err.Error() + "\nexit:1\n"), nil
+			}
+			return []byte(output + "exit:0\n"), nil
+		}
+		return nil, errors.New("unsupported adb command " + args[0])
+	}
+	return &ADBFS{root: "/sdcard/Music", run: run}, &commands
+}
+
+func TestShellQuote(t *testing.T) {
+	name := "/sdcard/Music/Guns N' Roses/$money & more.mp3"
+	if quoted := shellQuote(name); quoted != `'/sdcard/Music/Guns N'\'' Roses/$money & more.mp3'` {
+		t.Fatalf("unexpected quoting %v", quoted)
+	}
+	if words := shellSplit("stat " + shellQuote(name)); len(words) != 2 || words[1] != name {
+		t.Fatalf("unexpected words %q", words)
+	}
+}
+
+func TestADBFSWritesAndReadsFiles(t *testing.T) {
+	dir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(dir)
+	fsys, _ := newFakeADBFS(dir)
+
+	for _, content := range []string{"old", "new"} {
+		err := writeFile(fsys, "Guns N' Roses/My Playlist.m3u", func(w io.Writer) error {
+			_, err := io.WriteString(w, content)
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	musicDir := filepath.Join(dir, "sdcard", "Music", "Guns N' Roses")
+	if content := readFile(t, filepath.Join(musicDir, "My Playlist.m3u")); content != "new" {
+		t.Fatalf("expected new content, got %q", content)
+	}
+	files, _ := ioutil.ReadDir(musicDir)
+	if len(files) != 1 {
+		t.Fatalf("expected temporary files to be removed, found %v files", len(files))
+	}
+
+	info, err := fsys.Stat("Guns N' Roses/My Playlist.m3u")
+	if err != nil || info.Size() != 3 || info.IsDir() {
+		t.Fatalf("unexpected file info %v, error %v", info, err)
+	}
+	if info, err := fsys.Stat("Guns N' Roses"); err != nil || !info.IsDir() {
+		t.Fatalf("expected directory, got %v, error %v", info, err)
+	}
+	if _, err := fsys.Stat("Other.m3u"); !os.IsNotExist(err) {
+		t.Fatalf("expected not exist error, got %v", err)
+	}
+
+	file, err := fsys.Open("Guns N' Roses/My Playlist.m3u")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := ioutil.ReadAll(file)
+	file.Close()
+	if string(content) != "new" {
+		t.Fatalf("expected new content, got %q", content)
+	}
+
+	if err := fsys.Remove("Guns N' Roses/My Playlist.m3u"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsys.Remove("Guns N' Roses/My Playlist.m3u"); !os.IsNotExist(err) {
+		t.Fatalf("expected not exist error, got %v", err)
+	}
+}
+
+func TestADBFSSkipsFilesWithMatchingSize(t *testing.T) {
+	dir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(dir)
+	fsys, commands := newFakeADBFS(dir)
+
+	source := filepath.Join(dir, "song.mp3")
+	writeLocalFile(t, source, "music")
+	settings := &ExportSettings{Output: fsys, Overwrite: OVERWRITE_IF_SIZE_DIFFERS}
+
+	for i := 0; i < 2; i++ {
+		if err := copyFile(context.Background(), settings, "My Playlist", source, "My Playlist/song.mp3"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pushes := 0
+	for _, command := range *commands {
+		if strings.HasPrefix(command, "push ") {
+			pushes++
+		}
+	}
+	if pushes != 1 {
+		t.Fatalf("expected file with matching size to be skipped, got %v pushes", pushes)
+	}
+
+	writeLocalFile(t, source, "other music")
+	if err := copyFile(context.Background(), settings, "My Playlist", source, "My Playlist/song.mp3"); err != nil {
+		t.Fatal(err)
+	}
+	if content := readFile(t, filepath.Join(dir, "sdcard", "Music", "My Playlist", "song.mp3")); content != "other music" {
+		t.Fatalf("expected file with different size to be pushed, got %q", content)
+	}
+}
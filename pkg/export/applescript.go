@@ -0,0 +1,63 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// APPLESCRIPT is an AppleScript recreating the playlist in the Music app of macOS, adding its
+// music files to the library. Run it with osascript.
+const APPLESCRIPT = "APPLESCRIPT"
+
+// appleScriptTemplate is the script recreating a playlist. Its parameters are the playlist
+// name, the name and version of the export, the list of music files and the playlist name
+// again. Relative paths are relative to the folder of the script.
+const appleScriptTemplate = `-- Recreates the playlist %v in the Music app: osascript <this file>
+-- Written by iTunes Export v. %v (http://www.ericdaugherty.com/dev/itunesexport/)
+set baseFolder to POSIX path of ((path to me as text) & "::")
+set trackFiles to {%v}
+set missingFiles to {}
+
+tell application "Music" to set thePlaylist to make new user playlist with properties {name:%v}
+repeat with trackFile in trackFiles
+	set filePath to trackFile as text
+	if filePath does not start with "/" then set filePath to baseFolder & filePath
+	try
+		set theFile to POSIX file filePath
+		tell application "Music" to add theFile to thePlaylist
+	on error
+		set end of missingFiles to filePath
+	end try
+end repeat
+
+if (count of missingFiles) > 0 then
+	set AppleScript's text item delimiters to linefeed
+	return "Unable to add " & (count of missingFiles) & " files:" & linefeed & (missingFiles as text)
+end if
+return "Added " & (count of trackFiles) & " tracks."
+`
+
+// exportAppleScript writes an AppleScript recreating playlist in the Music app. Streams are
+// left out, as the Music app cannot add them by script.
+func exportAppleScript(w io.Writer, playlist Playlist, _ Options) error {
+	var files []string
+	for _, entry := range playlist.Entries {
+		if entry.Track.TrackType == "URL" || strings.Contains(entry.Location, "://") {
+			continue
+		}
+		files = append(files, appleScriptString(strings.Replace(entry.Location, "\\", "/", -1)))
+	}
+
+	var out bytes.Buffer
+	name := appleScriptString(playlist.Name)
+	fmt.Fprintf(&out, appleScriptTemplate, name, Version, strings.Join(files, ", "), name)
+	_, err := out.WriteTo(w)
+	return err
+}
+
+// appleScriptString returns s as AppleScript string literal.
+func appleScriptString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", " ", "\r", " ").Replace(s) + `"`
+}
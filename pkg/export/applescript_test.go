@@ -0,0 +1,36 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+func TestExportAppleScript(t *testing.T) {
+	song := itunes.Track{Name: "Some Song"}
+	stream := itunes.Track{Name: "Radio", TrackType: "URL"}
+	playlist := Playlist{Name: `Rock "n" Roll`, Entries: []Entry{
+		{Track: &song, Location: "/Users/me/Music/Some Song.mp3"},
+		{Track: &song, Location: `Rock n Roll\Some "Song".mp3`},
+		{Track: &stream, Location: "http://radio.example.com/live.mp3"},
+	}}
+
+	var buffer bytes.Buffer
+	if err := formats[APPLESCRIPT].exporter.Export(&buffer, playlist, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	script := buffer.String()
+	for _, expected := range []string{
+		`set trackFiles to {"/Users/me/Music/Some Song.mp3", "Rock n Roll/Some \"Song\".mp3"}`,
+		`make new user playlist with properties {name:"Rock \"n\" Roll"}`,
+	} {
+		if !strings.Contains(script, expected) {
+			t.Fatalf("expected %v in script:\n%v", expected, script)
+		}
+	}
+	if strings.Contains(script, "radio.example.com") {
+		t.Fatalf("expected streams to be left out:\n%v", script)
+	}
+}
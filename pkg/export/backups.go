@@ -0,0 +1,68 @@
+package export
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+)
+
+// BackupsDir is the directory of the output previous versions of playlist files are kept in.
+const BackupsDir = ".backups"
+
+// backupPlaylist keeps the playlist file name, before it is overwritten, as the newest of at
+// most keep versions in BackupsDir. The versions are named after the file with a number
+// appended, 1 being the newest. Nothing is kept if the file does not exist yet or is the
+// same as the newest version.
+func backupPlaylist(fsys FS, name string, keep int) error {
+	current, err := readAll(fsys, name)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	backup := path.Join(BackupsDir, name)
+	newest, err := readAll(fsys, backupVersion(backup, 1))
+	if err == nil && bytes.Equal(newest, current) {
+		return nil
+	} else if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	for version := keep; version > 1; version-- {
+		previous, err := readAll(fsys, backupVersion(backup, version-1))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if err := writeContent(fsys, backupVersion(backup, version), previous); err != nil {
+			return err
+		}
+	}
+	return writeContent(fsys, backupVersion(backup, 1), current)
+}
+
+// backupVersion returns the name of the version of the backup.
+func backupVersion(backup string, version int) string {
+	return backup + "." + strconv.Itoa(version)
+}
+
+func readAll(fsys FS, name string) ([]byte, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ioutil.ReadAll(file)
+}
+
+func writeContent(fsys FS, name string, content []byte) error {
+	return writeFile(fsys, name, func(file io.Writer) error {
+		_, err := file.Write(content)
+		return err
+	})
+}
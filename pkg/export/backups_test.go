@@ -0,0 +1,61 @@
+package export
+
+import (
+	"path"
+	"testing"
+)
+
+func TestExportKeepsPlaylistVersions(t *testing.T) {
+	library := testLibrary()
+	fsys := NewMemFS()
+	settings := &ExportSettings{Library: library, Playlists: library.Playlists, Output: fsys, PlaylistVersions: 2}
+	ParseExportType(settings, M3U)
+
+	const playlistFile = "My Playlist.m3u"
+	backup := path.Join(BackupsDir, playlistFile)
+	write := func(name, content string) {
+		if err := writeContent(fsys, name, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(name string) string {
+		content, err := readAll(fsys, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(content)
+	}
+	export := func() string {
+		if err := ExportPlaylists(settings, library); err != nil {
+			t.Fatal(err)
+		}
+		return read(playlistFile)
+	}
+
+	write(playlistFile, "hand tweaked")
+	exported := export()
+	if content := read(backup + ".1"); content != "hand tweaked" {
+		t.Fatalf("expected the overwritten playlist to be kept, got %q", content)
+	}
+
+	export()
+	export()
+	if content := read(backup + ".1"); content != exported {
+		t.Fatalf("expected the exported playlist as the newest version, got %q", content)
+	}
+	if content := read(backup + ".2"); content != "hand tweaked" {
+		t.Fatalf("expected unchanged exports not to rotate the versions, got %q", content)
+	}
+
+	write(playlistFile, "tweaked again")
+	export()
+	if content := read(backup + ".1"); content != "tweaked again" {
+		t.Fatalf("expected the latest tweak as the newest version, got %q", content)
+	}
+	if content := read(backup + ".2"); content != exported {
+		t.Fatalf("expected the versions to rotate, got %q", content)
+	}
+	if _, err := fsys.Stat(backup + ".3"); err == nil {
+		t.Fatal("expected no more than 2 versions to be kept")
+	}
+}
@@ -0,0 +1,50 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// BEETS is a configuration of the smartplaylist plugin of beets, with a query matching the
+// music files of the playlist in a beets library the files were imported into in place.
+// Update the playlist with beet -c <file> splupdate.
+const BEETS = "BEETS"
+
+// exportBeets writes the smartplaylist configuration of playlist. The playlist beets writes
+// is named after the playlist, with the m3u extension. Streams are left out, as they are
+// not part of a beets library.
+func exportBeets(w io.Writer, playlist Playlist, _ Options) error {
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "# Playlist %v for the smartplaylist plugin of beets: beet -c <this file> splupdate\n", playlist.Name)
+	fmt.Fprintf(&out, "# Written by iTunes Export v. %v (http://www.ericdaugherty.com/dev/itunesexport/)\n", Version)
+	out.WriteString("smartplaylist:\n")
+	out.WriteString("  playlists:\n")
+	fmt.Fprintf(&out, "    - name: %v\n", yamlString(playlist.Name+".m3u"))
+	out.WriteString("      query:\n")
+	queries := 0
+	for _, entry := range playlist.Entries {
+		if entry.Track.TrackType == "URL" || strings.Contains(entry.Location, "://") {
+			continue
+		}
+		fmt.Fprintf(&out, "        - %v\n", yamlString("path:"+beetsQuoted(entry.Location)))
+		queries++
+	}
+	if queries == 0 {
+		// An empty list of queries would match the whole library.
+		out.WriteString("        - 'path:/nonexistent'\n")
+	}
+	_, err := out.WriteTo(w)
+	return err
+}
+
+// beetsQuoted quotes s for a beets query string, which is split like a shell command line.
+func beetsQuoted(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// yamlString returns s as single quoted YAML string.
+func yamlString(s string) string {
+	return "'" + strings.NewReplacer("'", "''", "\n", " ", "\r", " ").Replace(s) + "'"
+}
@@ -0,0 +1,37 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+func TestExportBeets(t *testing.T) {
+	song := itunes.Track{Name: "Some Song"}
+	stream := itunes.Track{Name: "Radio", TrackType: "URL"}
+	playlist := Playlist{Name: "Rock 'n' Roll", Entries: []Entry{
+		{Track: &song, Location: "/music/Some Song.mp3"},
+		{Track: &song, Location: `/music/Say "Hi".mp3`},
+		{Track: &stream, Location: "http://radio.example.com/live.mp3"},
+	}}
+
+	var buffer bytes.Buffer
+	if err := formats[BEETS].exporter.Export(&buffer, playlist, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	config := buffer.String()
+	for _, expected := range []string{
+		"smartplaylist:\n  playlists:\n    - name: 'Rock ''n'' Roll.m3u'\n      query:\n",
+		`        - 'path:"/music/Some Song.mp3"'`,
+		`        - 'path:"/music/Say \"Hi\".mp3"'`,
+	} {
+		if !strings.Contains(config, expected) {
+			t.Fatalf("expected %v in configuration:\n%v", expected, config)
+		}
+	}
+	if strings.Contains(config, "radio.example.com") {
+		t.Fatalf("expected streams to be left out:\n%v", config)
+	}
+}
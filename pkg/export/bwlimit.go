@@ -0,0 +1,64 @@
+package export
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// bandwidthLimiter limits the rate of all copies of an export, also when playlists are
+// exported in parallel.
+type bandwidthLimiter struct {
+	mutex sync.Mutex
+	// bytesPerSecond is the rate allowed.
+	bytesPerSecond int64
+	// next is the time the bytes transferred so far are allowed at the rate.
+	next time.Time
+}
+
+func newBandwidthLimiter(bytesPerSecond int64) *bandwidthLimiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	return &bandwidthLimiter{bytesPerSecond: bytesPerSecond}
+}
+
+// wait blocks until n more bytes are allowed at the rate of the limiter, or ctx is done.
+func (limiter *bandwidthLimiter) wait(ctx context.Context, n int) error {
+	limiter.mutex.Lock()
+	now := time.Now()
+	if limiter.next.Before(now) {
+		limiter.next = now
+	}
+	limiter.next = limiter.next.Add(time.Duration(int64(n) * int64(time.Second) / limiter.bytesPerSecond))
+	delay := limiter.next.Sub(now)
+	limiter.mutex.Unlock()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// limitedReader limits the rate of the bytes read from the Reader. A nil limiter does not
+// limit the rate.
+type limitedReader struct {
+	ctx     context.Context
+	limiter *bandwidthLimiter
+	io.Reader
+}
+
+func (reader limitedReader) Read(p []byte) (int, error) {
+	n, err := reader.Reader.Read(p)
+	if n > 0 && reader.limiter != nil {
+		if waitErr := reader.limiter.wait(reader.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
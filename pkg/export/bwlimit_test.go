@@ -0,0 +1,44 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestParseBandwidthLimit(t *testing.T) {
+	for limit, expected := range map[string]int64{"10MB/s": 10 << 20, "512k": 512 << 10, "1.5M": 3 << 19, "2048": 2048, "0": 0} {
+		settings := &ExportSettings{}
+		if err := ParseBandwidthLimit(settings, limit); err != nil {
+			t.Fatal(err)
+		}
+		if settings.BandwidthLimit != expected {
+			t.Fatalf("expected %v for %v, got %v", expected, limit, settings.BandwidthLimit)
+		}
+	}
+	for _, limit := range []string{"fast", "10TB/s", "-1M", ""} {
+		if err := ParseBandwidthLimit(&ExportSettings{}, limit); err == nil {
+			t.Fatalf("expected error for %q", limit)
+		}
+	}
+}
+
+func TestLimitedReader(t *testing.T) {
+	limiter := newBandwidthLimiter(100 * 1024)
+	start := time.Now()
+	n, err := ioutil.ReadAll(limitedReader{context.Background(), limiter, bytes.NewReader(make([]byte, 20*1024))})
+	if err != nil || len(n) != 20*1024 {
+		t.Fatalf("unexpected result %v bytes, %v", len(n), err)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Fatalf("expected 20KB at 100KB/s to take about 200ms, took %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ioutil.ReadAll(limitedReader{ctx, newBandwidthLimiter(1), bytes.NewReader(make([]byte, 1024))}); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
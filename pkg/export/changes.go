@@ -0,0 +1,193 @@
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+// ManifestFileName is the file in the output recording the playlists and tracks of the
+// previous export with ExportSettings.Changes set.
+const ManifestFileName = ".itunesexport-manifest.json"
+
+// ErrNotConfirmed is returned by an export whose changes were not confirmed.
+var ErrNotConfirmed = errors.New("export not confirmed")
+
+// exportManifest records the playlists of an export.
+type exportManifest struct {
+	Playlists []manifestPlaylist `json:"playlists"`
+}
+
+type manifestPlaylist struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+	// Tracks are the persistent ids, or locations, of the tracks of the playlist.
+	Tracks []string `json:"tracks"`
+}
+
+// ExportChanges summarizes the differences of an export to the previous one.
+type ExportChanges struct {
+	// First is set if there is no previous export to compare with.
+	First            bool
+	AddedTracks      int
+	RemovedTracks    int
+	AddedPlaylists   int
+	RemovedPlaylists int
+	RenamedPlaylists int
+}
+
+// Destructive reports whether playlists or tracks are removed by the export.
+func (changes ExportChanges) Destructive() bool {
+	return changes.RemovedTracks > 0 || changes.RemovedPlaylists > 0
+}
+
+// String returns a summary like "+12 tracks, -3 tracks, 2 playlists renamed".
+func (changes ExportChanges) String() string {
+	if changes.First {
+		return "first export"
+	}
+	var parts []string
+	if changes.AddedTracks > 0 {
+		parts = append(parts, "+"+count(changes.AddedTracks, "track"))
+	}
+	if changes.RemovedTracks > 0 {
+		parts = append(parts, "-"+count(changes.RemovedTracks, "track"))
+	}
+	if changes.AddedPlaylists > 0 {
+		parts = append(parts, count(changes.AddedPlaylists, "playlist")+" added")
+	}
+	if changes.RemovedPlaylists > 0 {
+		parts = append(parts, count(changes.RemovedPlaylists, "playlist")+" removed")
+	}
+	if changes.RenamedPlaylists > 0 {
+		parts = append(parts, count(changes.RenamedPlaylists, "playlist")+" renamed")
+	}
+	if len(parts) == 0 {
+		return "no changes"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func count(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return strconv.Itoa(n) + " " + noun + "s"
+}
+
+// checkChanges compares the export with the manifest of the previous export in the output
+// and prints the changes. If confirmation is required for destructive changes, it asks on
+// the console and returns ErrNotConfirmed unless confirmed. It returns the manifest of the
+// export, to be saved once the export is complete.
+func checkChanges(exportSettings *ExportSettings) (*exportManifest, error) {
+	manifest := newManifest(exportSettings)
+	previous, err := loadManifest(exportSettings.Output)
+	if err != nil {
+		Log(LogEntry{Level: LOG_WARNING, Message: fmt.Sprintf("Unable to read the manifest of the previous export %v: %v\n", ManifestFileName, err), Path: ManifestFileName, Error: err.Error()})
+	}
+
+	changes := ExportChanges{First: previous == nil}
+	if previous != nil {
+		changes = compareManifests(previous, manifest)
+	}
+	Logf(LOG_INFO, "Changes since the previous export: %v\n", changes)
+
+	if exportSettings.Confirm && changes.Destructive() {
+		confirmed, err := prompt("The export removes playlists or tracks. Continue?")
+		if err != nil {
+			return nil, err
+		}
+		if !confirmed {
+			return nil, ErrNotConfirmed
+		}
+	}
+	return manifest, nil
+}
+
+// newManifest returns the manifest of the playlists exported with exportSettings.
+func newManifest(exportSettings *ExportSettings) *exportManifest {
+	manifest := &exportManifest{Playlists: []manifestPlaylist{}}
+	for _, playlist := range exportSettings.Playlists {
+		if playlist.Container() {
+			continue
+		}
+		entry := manifestPlaylist{Id: playlist.PlaylistPersistentId, Name: playlist.Name, Tracks: []string{}}
+		for _, track := range playlist.Tracks(exportSettings.Library) {
+			key := track.PersistentId
+			if key == "" {
+				key = track.Location
+			}
+			entry.Tracks = append(entry.Tracks, key)
+		}
+		manifest.Playlists = append(manifest.Playlists, entry)
+	}
+	return manifest
+}
+
+// loadManifest reads the manifest of the previous export from fsys. It returns nil if there
+// is none.
+func loadManifest(fsys FS) (*exportManifest, error) {
+	file, err := fsys.Open(ManifestFileName)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var manifest exportManifest
+	if err := json.NewDecoder(file).Decode(&manifest); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+func (manifest *exportManifest) save(fsys FS) error {
+	return writeFile(fsys, ManifestFileName, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(manifest)
+	})
+}
+
+// compareManifests compares the manifests like itunes.DiffLibraries compares libraries.
+func compareManifests(previous, current *exportManifest) ExportChanges {
+	diff := itunes.DiffLibraries(previous.library(), current.library())
+
+	var changes ExportChanges
+	changes.AddedPlaylists = len(diff.AddedPlaylists)
+	changes.RemovedPlaylists = len(diff.RemovedPlaylists)
+	changes.RenamedPlaylists = len(diff.RenamedPlaylists)
+	for _, playlists := range [][]itunes.PlaylistDiff{diff.AddedPlaylists, diff.RemovedPlaylists, diff.RenamedPlaylists, diff.ChangedPlaylists} {
+		for _, playlist := range playlists {
+			changes.AddedTracks += len(playlist.AddedTracks)
+			changes.RemovedTracks += len(playlist.RemovedTracks)
+		}
+	}
+	return changes
+}
+
+// library returns a library of the playlists of the manifest, whose tracks only have
+// their persistent id.
+func (manifest *exportManifest) library() *itunes.Library {
+	library := &itunes.Library{Tracks: make(map[string]itunes.Track)}
+	trackIds := make(map[string]int)
+	for _, entry := range manifest.Playlists {
+		playlist := itunes.Playlist{Name: entry.Name, PlaylistPersistentId: entry.Id}
+		for _, key := range entry.Tracks {
+			id, ok := trackIds[key]
+			if !ok {
+				id = len(trackIds) + 1
+				trackIds[key] = id
+				library.Tracks[strconv.Itoa(id)] = itunes.Track{TrackId: id, PersistentId: key}
+			}
+			playlist.PlaylistItems = append(playlist.PlaylistItems, itunes.PlaylistItem{TrackId: id})
+		}
+		library.Playlists = append(library.Playlists, playlist)
+	}
+	return library
+}
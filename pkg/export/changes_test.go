@@ -0,0 +1,52 @@
+package export
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+func TestExportChanges(t *testing.T) {
+	library := testLibrary()
+	library.Tracks["2"] = itunes.Track{TrackId: 2, Name: "Other Song", Artist: "Some Artist", PersistentId: "T2"}
+	library.Playlists[0].PlaylistItems = append(library.Playlists[0].PlaylistItems, itunes.PlaylistItem{TrackId: 2})
+
+	fsys := NewMemFS()
+	settings := &ExportSettings{Library: library, Playlists: library.Playlists, Output: fsys, Changes: true}
+	ParseExportType(settings, M3U)
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+	previous, err := loadManifest(fsys)
+	if err != nil || previous == nil || len(previous.Playlists) != 1 || len(previous.Playlists[0].Tracks) != 2 {
+		t.Fatalf("unexpected manifest %+v: %v", previous, err)
+	}
+
+	renamed := library.Playlists[0]
+	renamed.Name = "Renamed Playlist"
+	renamed.PlaylistItems = renamed.PlaylistItems[1:]
+	added := itunes.Playlist{Name: "New Playlist", PlaylistPersistentId: "C0FFEE", PlaylistItems: []itunes.PlaylistItem{{TrackId: 1}}}
+	settings.Playlists = []itunes.Playlist{renamed, added}
+
+	changes := compareManifests(previous, newManifest(settings))
+	expected := ExportChanges{AddedTracks: 1, RemovedTracks: 1, AddedPlaylists: 1, RenamedPlaylists: 1}
+	if changes != expected {
+		t.Fatalf("expected %+v, got %+v", expected, changes)
+	}
+	if summary := changes.String(); summary != "+1 track, -1 track, 1 playlist added, 1 playlist renamed" {
+		t.Fatalf("unexpected summary %q", summary)
+	}
+
+	realInput, realReader := promptInput, promptReader
+	defer func() { promptInput, promptReader = realInput, realReader }()
+	promptInput, promptReader = strings.NewReader("n\n"), nil
+
+	settings.Confirm = true
+	if err := ExportPlaylists(settings, library); err != ErrNotConfirmed {
+		t.Fatalf("expected the export to be cancelled, got %v", err)
+	}
+	if _, err := fsys.Stat("New Playlist.m3u"); err == nil {
+		t.Fatal("expected nothing to be written without confirmation")
+	}
+}
@@ -0,0 +1,172 @@
+package export
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ChecksumsFileName is the file in the output listing the SHA-256 checksums of the files of
+// an export with ExportSettings.Checksums set, in the format of sha256sum. Use
+// "sha256sum -c SHA256SUMS" in the output directory to verify the export.
+const ChecksumsFileName = "SHA256SUMS"
+
+// checksums collects the checksums of the playlists and music files of an export.
+type checksums struct {
+	mutex sync.Mutex
+	fsys  FS
+
+	// files are the names of the files of the export.
+	files map[string]bool
+	// sums are the checksums of the files written by the export.
+	sums map[string]string
+	// previous are the checksums listed by the previous export.
+	previous map[string]string
+}
+
+// loadChecksums reads the checksums of the previous export from fsys, which are used for
+// files that are not written again.
+func loadChecksums(fsys FS) *checksums {
+	checksums := &checksums{
+		fsys:     fsys,
+		files:    make(map[string]bool),
+		sums:     make(map[string]string),
+		previous: make(map[string]string),
+	}
+
+	file, err := fsys.Open(ChecksumsFileName)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			Log(LogEntry{Level: LOG_WARNING, Message: fmt.Sprintf("Unable to read checksums %v: %v\n", ChecksumsFileName, err), Path: ChecksumsFileName, Error: err.Error()})
+		}
+		return checksums
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), " ", 2)
+		if len(fields) != 2 || len(fields[0]) != sha256.Size*2 {
+			continue
+		}
+		name := strings.TrimPrefix(strings.TrimPrefix(fields[1], " "), "*")
+		checksums.previous[name] = fields[0]
+	}
+	return checksums
+}
+
+// add records name as a file of the export.
+func (checksums *checksums) add(name string) {
+	checksums.mutex.Lock()
+	defer checksums.mutex.Unlock()
+	checksums.files[name] = true
+}
+
+func (checksums *checksums) written(name, sum string) {
+	checksums.mutex.Lock()
+	defer checksums.mutex.Unlock()
+	checksums.sums[name] = sum
+}
+
+// save writes the checksums of all files of the export. Files not written by the export
+// keep the checksum of the previous export or are read again if there is none. Files of
+// previous exports that still exist stay listed, e.g. those of skipped playlists.
+func (checksums *checksums) save() error {
+	checksums.mutex.Lock()
+	defer checksums.mutex.Unlock()
+
+	for name := range checksums.previous {
+		if _, err := checksums.fsys.Stat(name); err == nil {
+			checksums.files[name] = true
+		}
+	}
+	names := make([]string, 0, len(checksums.files))
+	for name := range checksums.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var lines strings.Builder
+	for _, name := range names {
+		sum, ok := checksums.sums[name]
+		if !ok {
+			sum, ok = checksums.previous[name]
+		}
+		if !ok {
+			var err error
+			if sum, err = checksums.hashFile(name); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(&lines, "%v  %v\n", sum, name)
+	}
+
+	return writeFile(checksums.fsys, ChecksumsFileName, func(w io.Writer) error {
+		_, err := io.WriteString(w, lines.String())
+		return err
+	})
+}
+
+func (checksums *checksums) hashFile(name string) (string, error) {
+	file, err := checksums.fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// checksumFS computes the checksums of the files written to an FS.
+type checksumFS struct {
+	FS
+	checksums *checksums
+}
+
+func (fsys *checksumFS) Create(name string) (File, error) {
+	file, err := fsys.FS.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	return &checksumFile{File: file, name: name, hash: sha256.New(), checksums: fsys.checksums}, nil
+}
+
+func (fsys *checksumFS) Symlink(target, name string) error {
+	linker, ok := fsys.FS.(Linker)
+	if !ok {
+		return errSymlinksUnsupported
+	}
+	return linker.Symlink(target, name)
+}
+
+type checksumFile struct {
+	File
+	name      string
+	hash      hash.Hash
+	checksums *checksums
+}
+
+func (file *checksumFile) Write(p []byte) (int, error) {
+	n, err := file.File.Write(p)
+	file.hash.Write(p[:n])
+	return n, err
+}
+
+func (file *checksumFile) Close() error {
+	if err := file.File.Close(); err != nil {
+		return err
+	}
+	file.checksums.written(file.name, hex.EncodeToString(file.hash.Sum(nil)))
+	return nil
+}
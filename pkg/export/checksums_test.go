@@ -0,0 +1,53 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportChecksums(t *testing.T) {
+	outputDir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(outputDir)
+
+	musicFile, musicFileName := prepareMusicFile(t)
+	defer os.Remove(musicFile)
+
+	library := testLibrary()
+	track := library.Tracks["1"]
+	track.Location = "file://" + filepath.ToSlash(musicFile)
+	library.Tracks["1"] = track
+
+	settings := &ExportSettings{Library: library, Playlists: library.Playlists, CopyType: COPY_FLAT, OutputPath: outputDir, Checksums: true}
+	ParseExportType(settings, M3U)
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+
+	// sha256 of FileContent
+	musicSum := "73475cb40a568e8da8a045ced110137e159f890ac4da883b6b17dc651b3a8049  " + musicFileName + "\n"
+	sums := readFile(t, filepath.Join(outputDir, ChecksumsFileName))
+	if !strings.HasSuffix(sums, musicSum) || !strings.Contains(sums, "  My Playlist.m3u\n") || strings.Count(sums, "\n") != 2 {
+		t.Fatalf("unexpected checksums %q", sums)
+	}
+
+	// Nothing is written again, the checksums are kept.
+	settings.Overwrite = OVERWRITE_NEVER
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+	if content := readFile(t, filepath.Join(outputDir, ChecksumsFileName)); content != sums {
+		t.Fatalf("expected checksums %q, got %q", sums, content)
+	}
+
+	// The playlist is written again, the existing music file is read.
+	os.Remove(filepath.Join(outputDir, ChecksumsFileName))
+	settings.Overwrite = OVERWRITE_DEFAULT
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+	if content := readFile(t, filepath.Join(outputDir, ChecksumsFileName)); !strings.HasSuffix(content, musicSum) {
+		t.Fatalf("expected checksum of the existing music file %q, got %q", musicSum, content)
+	}
+}
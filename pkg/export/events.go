@@ -0,0 +1,77 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// EventType identifies the kind of an Event.
+type EventType string
+
+const (
+	EVENT_FILE_STARTED     EventType = "fileStarted"
+	EVENT_FILE_FINISHED    EventType = "fileFinished"
+	EVENT_PLAYLIST_WRITTEN EventType = "playlistWritten"
+	EVENT_PLAYLIST_SKIPPED EventType = "playlistSkipped"
+	EVENT_ERROR            EventType = "error"
+)
+
+// Event reports the progress of an export.
+type Event struct {
+	Type     EventType `json:"type"`
+	Time     time.Time `json:"time"`
+	Playlist string    `json:"playlist,omitempty"`
+	// Source and File are the source and the destination of a copied music file.
+	Source string `json:"source,omitempty"`
+	File   string `json:"file,omitempty"`
+	// Bytes is the size of a copied music file.
+	Bytes int64 `json:"bytes,omitempty"`
+	// Done and Total are the number of playlists written so far and to be written in total.
+	Done  int    `json:"done,omitempty"`
+	Total int    `json:"total,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// EventHandler receives the events of an export. Events are delivered one at a time,
+// even when playlists are exported in parallel.
+type EventHandler interface {
+	HandleEvent(event Event)
+}
+
+// EventHandlerFunc adapts a function to an EventHandler.
+type EventHandlerFunc func(event Event)
+
+func (f EventHandlerFunc) HandleEvent(event Event) {
+	f(event)
+}
+
+// NewJSONLinesEventHandler returns an EventHandler writing every event as a line of JSON to w.
+func NewJSONLinesEventHandler(w io.Writer) EventHandler {
+	encoder := json.NewEncoder(w)
+	return EventHandlerFunc(func(event Event) {
+		encoder.Encode(event)
+	})
+}
+
+// serialEventHandler delivers events to handler one at a time.
+type serialEventHandler struct {
+	mutex   sync.Mutex
+	handler EventHandler
+}
+
+func (handler *serialEventHandler) HandleEvent(event Event) {
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+	handler.handler.HandleEvent(event)
+}
+
+// emit sends event to the Events handler of exportSettings, if any.
+func (exportSettings *ExportSettings) emit(event Event) {
+	if exportSettings.Events == nil {
+		return
+	}
+	event.Time = time.Now()
+	exportSettings.Events.HandleEvent(event)
+}
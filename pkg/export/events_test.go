@@ -0,0 +1,67 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportEvents(t *testing.T) {
+	musicFile, musicFileName := prepareMusicFile(t)
+	defer os.Remove(musicFile)
+
+	library := testLibrary()
+	track := library.Tracks["1"]
+	track.Location = "file://" + filepath.ToSlash(musicFile)
+	library.Tracks["1"] = track
+
+	var events []Event
+	settings := &ExportSettings{
+		Library:   library,
+		Playlists: library.Playlists,
+		Output:    NewMemFS(),
+		CopyType:  COPY_FLAT,
+		Events:    EventHandlerFunc(func(event Event) { events = append(events, event) }),
+	}
+	ParseExportType(settings, "M3U")
+
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+
+	var types []string
+	for _, event := range events {
+		types = append(types, string(event.Type))
+	}
+	if strings.Join(types, ",") != "fileStarted,fileFinished,playlistWritten" {
+		t.Fatalf("unexpected events %v", types)
+	}
+	if events[1].File != musicFileName || events[1].Bytes == 0 || events[1].Playlist != "My Playlist" {
+		t.Fatalf("unexpected file event %+v", events[1])
+	}
+	if events[2].Done != 1 || events[2].Total != 1 {
+		t.Fatalf("unexpected playlist event %+v", events[2])
+	}
+}
+
+func TestJSONLinesEventHandler(t *testing.T) {
+	var out bytes.Buffer
+	handler := NewJSONLinesEventHandler(&out)
+	handler.HandleEvent(Event{Type: EVENT_PLAYLIST_WRITTEN, Playlist: "My Playlist", Done: 1, Total: 2})
+	handler.HandleEvent(Event{Type: EVENT_ERROR, Error: "failed"})
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", out.String())
+	}
+	var event Event
+	if err := json.Unmarshal([]byte(lines[0]), &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Type != EVENT_PLAYLIST_WRITTEN || event.Playlist != "My Playlist" || event.Done != 1 || event.Total != 2 {
+		t.Fatalf("unexpected event %+v", event)
+	}
+}
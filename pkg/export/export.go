@@ -0,0 +1,995 @@
+// Package export writes playlists of an iTunes library to playlist files, optionally
+// copying the music files along with them.
+package export
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+// Version is written to the playlist files created. It is set by the command line tool.
+var Version = "DEV"
+
+const (
+	COPY_NONE = iota
+	COPY_PLAYLIST
+	COPY_ITUNES
+	COPY_FLAT
+	// COPY_STORE copies every music file once to StoreDir, named by its checksum, and links
+	// it into a folder for each playlist where the output supports symbolic links.
+	COPY_STORE
+	// COPY_TEMPLATE copies the music files to the paths given by CopyTemplate.
+	COPY_TEMPLATE
+	// COPY_VIDEO copies videos into the folders media servers like Plex, Jellyfin and Kodi
+	// expect: Movies, TV Shows by series and season, Music Videos and Home Videos.
+	COPY_VIDEO
+)
+
+// ExportSettings configures an export. Use ParseExportType, ParseCopyType and ParseOverwrite
+// to set the corresponding fields from their names.
+type ExportSettings struct {
+	Library   *itunes.Library
+	Playlists []itunes.Playlist
+	// ExportType is the name of a registered playlist format, e.g. M3U.
+	ExportType string
+	// OutputPath is the directory the export is written to, unless Output is set.
+	// The locations of copied music files written to the playlists are based on it.
+	OutputPath string
+	// Output is the file system the export is written to. Defaults to the OutputPath directory.
+	Output FS
+	// PlaylistDir is the directory of Output the playlist files are written to, e.g.
+	// "Playlists". Defaults to the root of Output.
+	PlaylistDir string
+	// MusicDir is the directory of Output the music files are copied to, e.g. "Music".
+	// Defaults to the root of Output.
+	MusicDir          string
+	Extension         string
+	CopyType          int
+	OriginalMusicPath string
+	NewMusicPath      string
+	IncludeFolders    bool
+	Overwrite         int
+	// PlaylistInFolder writes the playlist files of COPY_PLAYLIST into the folder their music
+	// files are copied to instead of PlaylistDir, with entries relative to it, for players
+	// that only find playlists next to the music.
+	PlaylistInFolder bool
+	// CopyTemplate is the path of the copied music files of COPY_TEMPLATE, with placeholders
+	// like {album}. Use ParseCopyTemplate to set it.
+	CopyTemplate string
+	// ExtinfTemplate, if set, is the text of the #EXTINF lines of extended M3U playlists, with
+	// the placeholders of CopyTemplate, e.g. "{artist} - {title} ({album})". Use
+	// ParseExtinfTemplate to set it.
+	ExtinfTemplate string
+	// AlbumDirectives writes #EXTALB, #EXTART and #EXTGENRE lines to extended M3U playlists
+	// before each run of tracks of the same album, for players that show them.
+	AlbumDirectives bool
+	// Parallel is the number of playlists exported concurrently, at least 1.
+	Parallel int
+	// CopyInOrder exports one playlist at a time, so the music files are copied in the order
+	// of the playlists, as many car stereos play files in the order they were written.
+	CopyInOrder bool
+	// ReorderDirectories rewrites the directories of the music files after the export, so
+	// their entries are in the order of the playlists also for files copied before. It
+	// implies CopyInOrder and requires an Output that implements Renamer.
+	ReorderDirectories bool
+	// SortDirectories rewrites the directories of the playlists and music files after the
+	// export, so their entries are sorted by name with subdirectories first, like fatsort
+	// does, for players that play in the order of the entries. It requires an Output that
+	// implements Renamer.
+	SortDirectories bool
+	// Incremental skips playlists that did not change since the previous incremental export.
+	Incremental bool
+	// Validate checks every playlist file before it is written if its format implements Validator.
+	Validate bool
+	// PathStyle is how the locations of copied music files are written to the playlists,
+	// one of the PATH_STYLE constants.
+	PathStyle int
+	// Encoding is the character encoding of the playlist files, one of the ENCODING constants.
+	Encoding int
+	// MaxFilenameLength, if set, limits the length of the names of the files and directories
+	// created and replaces characters not allowed on FAT file systems.
+	MaxFilenameLength int
+	// MaxFolderDepth, if set, limits the number of nested directories created by joining
+	// the innermost ones.
+	MaxFolderDepth int
+	// MaxFilesPerDir, if set, limits the number of files and folders in each folder of the
+	// copied music by moving them into subfolders named by the range of their names, e.g. A-M.
+	MaxFilesPerDir int
+	// StripSymbols leaves emoji, other symbols, zero-width and control characters out of the
+	// names of the copied files, the playlist files and the playlists.
+	StripSymbols bool
+	// NameCase is the case of the names of the copied files and folders and of the playlist
+	// files, one of the NAME_CASE constants.
+	NameCase int
+	// Replacements replace text in the names of the copied files and folders and of the
+	// playlist files, e.g. ":" by " -", before the characters file systems cannot store are
+	// replaced by underscores.
+	Replacements map[string]string
+	// MaxPlaylistEntries, if set, is the maximum number of tracks written to a playlist.
+	MaxPlaylistEntries int
+	// MinDuration and MaxDuration, if set, leave out the tracks shorter or longer than them.
+	MinDuration time.Duration
+	MaxDuration time.Duration
+	// MaxFileSize, if set, leaves out the tracks with larger music files. Use
+	// ParseMaxFileSize to set it from e.g. 100MB.
+	MaxFileSize int64
+	// MinBitrate, if set, leaves out the tracks with a lower bit rate in kbit/s.
+	MinBitrate int
+	// Codecs, if set, leaves out the tracks of other codecs, e.g. mp3 or alac. Use ParseCodecs
+	// to set them.
+	Codecs []string
+	// Audiobooks is how the audiobooks of the playlists are exported, one of the AUDIOBOOKS
+	// constants. By default they are left out of the music exports.
+	Audiobooks int
+	// Videos is how the videos of the playlists are exported, one of the VIDEOS constants.
+	// By default they are left out of the music exports.
+	Videos int
+	// Podcasts exports the episodes of podcasts only and leaves the music out.
+	Podcasts bool
+	// FeedURL is the URL the output is hosted at, which the enclosures of RSS playlists link
+	// to the copied files below. If set with Podcasts, an RSS feed of each playlist is also
+	// written next to it, so podcast apps can subscribe to it.
+	FeedURL string
+	// CleanOnly replaces the tracks marked explicit with their clean versions from the
+	// library, or leaves them out if the library has none.
+	CleanOnly bool
+	// Changes compares the export with the previous one before writing anything and prints
+	// a summary of the changes. The export is recorded in the ManifestFileName file.
+	Changes bool
+	// Confirm asks on the console before an export that removes playlists or tracks compared
+	// with the previous export. It implies Changes.
+	Confirm bool
+	// StrictTemplate checks the paths the copy template gives for all tracks before copying,
+	// and fails the export if placeholders are empty or tracks are copied to the same path.
+	StrictTemplate bool
+	// Precheck opens the music files of all tracks to be copied before copying any, and
+	// fails the export with a list of the files that can not be read.
+	Precheck bool
+	// Ignore, if set, lists tracks known to be broken, which are left out of the export
+	// without notice.
+	Ignore *itunes.IgnoreList
+	// Checksums lists the SHA-256 checksums of the playlists and music files of the export in
+	// the ChecksumsFileName file.
+	Checksums bool
+	// Ids maps the persistent ids of the playlists and tracks exported to their files in the
+	// IdsFileName file.
+	Ids bool
+	// ShortNames gives the files and directories created unique DOS 8.3 names for players
+	// that cannot read long names. The names are listed in the ShortNamesManifest file.
+	ShortNames bool
+	// Retries is the number of times copying a music file is retried after an error writing
+	// it, e.g. a network problem while writing to a share.
+	Retries int
+	// RetryWait is the time waited before the first retry. It doubles with every further
+	// retry. Defaults to a second.
+	RetryWait time.Duration
+	// BandwidthLimit, if set, is the maximum number of bytes per second copied, shared by all
+	// music files copied concurrently. Use ParseBandwidthLimit to set it from e.g. 10MB/s.
+	BandwidthLimit int64
+	// Reserve, if set, is the number of bytes kept free on the volume of Output, as some
+	// players misbehave on full media. Tracks whose copies would use the reserve are left
+	// out, so the tracks exported last go first. Use ParseReserve to set it from e.g. 500MB.
+	Reserve int64
+	// PlaylistVersions, if set, is the number of previous versions of each playlist file kept
+	// in BackupsDir of Output when the file is overwritten with different content.
+	PlaylistVersions int
+	// Snapshots records the playlist files of the export and the music files copied for
+	// them in SnapshotsDir of Output, so the output can be rolled back with Rollback.
+	Snapshots bool
+	// PlaylistSettings override the settings above for the playlists with the given names.
+	PlaylistSettings map[string]PlaylistSettings
+
+	// Progress, if set, is called after each playlist has been written.
+	Progress func(done, total int, playlistName string)
+	// Events, if set, receives the events of the export as it progresses.
+	Events EventHandler
+
+	shortNames *shortNamer
+	checksums  *checksums
+	ids        *ids
+	store      *store
+	limiter    *bandwidthLimiter
+	space      *spaceBudget
+	copyOrder  *copyOrder
+	snapshots  *snapshots
+	layout     *folderLayout
+	replacer   *strings.Replacer
+	skipped    *skippedFiles
+	// cleanVersions are the clean tracks of the library by cleanVersionKey, if CleanOnly is set.
+	cleanVersions map[string]itunes.Track
+}
+
+// ExportPlaylists writes all playlists in exportSettings to the output path.
+func ExportPlaylists(exportSettings *ExportSettings, library *itunes.Library) error {
+	return ExportPlaylistsContext(context.Background(), exportSettings, library)
+}
+
+// ExportPlaylistsContext is like ExportPlaylists, but stops once ctx is done. A file being
+// copied or written when ctx is done is removed again; playlists and files completed
+// before are kept. The error returned is then the error of ctx.
+func ExportPlaylistsContext(ctx context.Context, exportSettings *ExportSettings, library *itunes.Library) error {
+	settings := *exportSettings
+	if settings.Output == nil {
+		settings.Output = NewDirFS(settings.OutputPath)
+	}
+	if settings.Events != nil {
+		settings.Events = &serialEventHandler{handler: settings.Events}
+	}
+
+	err := exportPlaylists(ctx, &settings, library)
+	metrics.exportFinished(err)
+	return err
+}
+
+func exportPlaylists(ctx context.Context, exportSettings *ExportSettings, library *itunes.Library) error {
+	start := time.Now()
+	exportSettings.replacer = newReplacer(exportSettings.Replacements)
+	exportSettings.skipped = newSkippedFiles()
+
+	if locker, ok := exportSettings.Output.(Locker); ok {
+		unlock, err := locker.Lock()
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+
+	exportSettings.limiter = newBandwidthLimiter(exportSettings.BandwidthLimit)
+	space, err := newSpaceBudget(exportSettings.Output, exportSettings.Reserve)
+	if err != nil {
+		return fmt.Errorf("unable to keep the reserve free: %v", err)
+	}
+	exportSettings.space = space
+	if exportSettings.CleanOnly {
+		exportSettings.cleanVersions = cleanVersions(exportSettings.Library)
+	}
+
+	if exportSettings.Checksums {
+		exportSettings.checksums = loadChecksums(exportSettings.Output)
+		exportSettings.Output = &checksumFS{FS: exportSettings.Output, checksums: exportSettings.checksums}
+	}
+
+	if exportSettings.usesCopyType(COPY_STORE) {
+		exportSettings.store = loadStore(exportSettings.Output, path.Join(exportSettings.MusicDir, StoreDir))
+		defer func() {
+			if err := exportSettings.store.save(); err != nil {
+				Log(LogEntry{Level: LOG_ERROR, Message: fmt.Sprintf("Unable to save the index of the store: %v\n", err), Error: err.Error()})
+			}
+		}()
+	}
+
+	if exportSettings.Ids {
+		exportSettings.ids = loadIds(exportSettings.Output)
+	}
+
+	if exportSettings.Snapshots {
+		exportSettings.snapshots = loadSnapshots(exportSettings.Output, start)
+	}
+
+	if exportSettings.StrictTemplate {
+		if err := checkCopyTemplates(exportSettings); err != nil {
+			return err
+		}
+	}
+	if exportSettings.Precheck {
+		if err := precheckSources(exportSettings); err != nil {
+			return err
+		}
+	}
+
+	var manifest *exportManifest
+	if exportSettings.Changes || exportSettings.Confirm {
+		var err error
+		manifest, err = checkChanges(exportSettings)
+		if err != nil {
+			return err
+		}
+	}
+
+	var state *exportState
+	if exportSettings.Incremental {
+		state = loadExportState(exportSettings.Output)
+		defer func() {
+			if err := state.save(); err != nil {
+				Log(LogEntry{Level: LOG_ERROR, Message: fmt.Sprintf("Unable to save export state: %v\n", err), Error: err.Error()})
+			}
+		}()
+	}
+
+	if exportSettings.ShortNames {
+		exportSettings.shortNames = loadShortNames(exportSettings.Output)
+		defer func() {
+			if err := exportSettings.shortNames.save(); err != nil {
+				Log(LogEntry{Level: LOG_ERROR, Message: fmt.Sprintf("Unable to save short names: %v\n", err), Error: err.Error()})
+			}
+		}()
+	}
+
+	layout, err := planFolderLayout(exportSettings)
+	if err != nil {
+		return err
+	}
+	exportSettings.layout = layout
+
+	total := 0
+	for _, playlist := range exportSettings.Playlists {
+		if !playlist.Container() {
+			total++
+		}
+	}
+
+	parallel := exportSettings.Parallel
+	if parallel < 1 || exportSettings.CopyInOrder || exportSettings.ReorderDirectories {
+		parallel = 1
+	}
+	if exportSettings.ReorderDirectories || exportSettings.SortDirectories {
+		exportSettings.copyOrder = newCopyOrder()
+	}
+
+	var (
+		mutex    sync.Mutex
+		done     int
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	semaphore := make(chan struct{}, parallel)
+
+	for _, playlist := range exportSettings.Playlists {
+		// Folders and other containers have no tracks of their own. With IncludeFolders,
+		// folders are written as directories only.
+		if playlist.Container() {
+			if playlist.Folder && exportSettings.IncludeFolders {
+				dir := limitPath(exportSettings, path.Join(exportSettings.PlaylistDir, buildPlaylistPath(exportSettings, playlist, library)))
+				if err := exportSettings.Output.MkdirAll(dir); err != nil {
+					mutex.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mutex.Unlock()
+					break
+				}
+			}
+			continue
+		}
+
+		mutex.Lock()
+		failed := firstErr != nil
+		mutex.Unlock()
+		if failed {
+			break
+		}
+
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(playlist itunes.Playlist) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+
+			settings, err := exportSettings.forPlaylist(playlist.Name)
+			written := false
+			if err == nil {
+				written, err = exportPlaylist(ctx, settings, library, playlist, state, start)
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				if ctx.Err() == nil {
+					exportSettings.emit(Event{Type: EVENT_ERROR, Playlist: playlist.Name, Error: err.Error()})
+				}
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			done++
+			if written {
+				metrics.playlistExported()
+				exportSettings.emit(Event{Type: EVENT_PLAYLIST_WRITTEN, Playlist: playlist.Name, Done: done, Total: total})
+			} else {
+				exportSettings.emit(Event{Type: EVENT_PLAYLIST_SKIPPED, Playlist: playlist.Name, Done: done, Total: total})
+			}
+			if exportSettings.Progress != nil {
+				exportSettings.Progress(done, total, playlist.Name)
+			}
+		}(playlist)
+	}
+	wg.Wait()
+	exportSettings.skipped.print("music files were skipped")
+
+	if err := ctx.Err(); err != nil {
+		Log(LogEntry{Level: LOG_WARNING, Message: fmt.Sprintf("\n\nExport stopped: %v. %v of %v playlists were completed.\n", err, done, total), Error: err.Error()})
+		return err
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+	if exportSettings.copyOrder != nil {
+		if err := exportSettings.copyOrder.reorderDirectories(exportSettings.Output, exportSettings.SortDirectories); err != nil {
+			return fmt.Errorf("unable to reorder directories: %v", err)
+		}
+	}
+	if exportSettings.snapshots != nil {
+		if err := exportSettings.snapshots.save(); err != nil {
+			return fmt.Errorf("unable to save the snapshot of the export: %v", err)
+		}
+	}
+	if manifest != nil {
+		if err := manifest.save(exportSettings.Output); err != nil {
+			Log(LogEntry{Level: LOG_ERROR, Message: fmt.Sprintf("Unable to save the manifest of the export: %v\n", err), Error: err.Error()})
+		}
+	}
+	if exportSettings.ids != nil {
+		if err := exportSettings.ids.save(); err != nil {
+			return fmt.Errorf("unable to write ids: %v", err)
+		}
+	}
+	if exportSettings.checksums != nil {
+		if err := exportSettings.checksums.save(); err != nil {
+			return fmt.Errorf("unable to write checksums: %v", err)
+		}
+	}
+
+	Logf(LOG_INFO, "\n\nExport Complete.\n")
+	Logf(LOG_INFO, "%v\n", time.Since(start))
+	return nil
+}
+
+// exportPlaylist writes a single playlist file, copying its tracks as configured.
+// It returns false if the playlist was skipped because of the overwrite policy or,
+// if state is not nil, because it did not change since the previous export.
+func exportPlaylist(ctx context.Context, exportSettings *ExportSettings, library *itunes.Library, playlist itunes.Playlist, state *exportState, started time.Time) (bool, error) {
+	Log(LogEntry{Level: LOG_INFO, Message: fmt.Sprintf("Exporting Playlist %v\n", playlist.Name), Playlist: playlist.Name})
+
+	filePath := ""
+	if exportSettings.IncludeFolders && playlist.ParentPersistentId != "" {
+		filePath = buildPlaylistPath(exportSettings, playlist, library)
+	}
+
+	fileName := limitPath(exportSettings, path.Join(exportSettings.PlaylistDir, filePath, exportSettings.safeName(playlist)+"."+exportSettings.Extension))
+	if exportSettings.PlaylistInFolder && exportSettings.CopyType == COPY_PLAYLIST {
+		fileName = limitPath(exportSettings, path.Join(exportSettings.MusicDir, filePath, exportSettings.safeName(playlist), exportSettings.safeName(playlist)+"."+exportSettings.Extension))
+		if exportSettings.PathStyle == PATH_STYLE_ABSOLUTE {
+			settings := *exportSettings
+			settings.PathStyle = PATH_STYLE_RELATIVE
+			exportSettings = &settings
+		}
+	}
+	if exportSettings.checksums != nil {
+		exportSettings.checksums.add(fileName)
+	}
+	if exportSettings.snapshots != nil {
+		exportSettings.snapshots.addPlaylist(fileName)
+	}
+
+	var hash string
+	if state != nil {
+		hash = playlistHash(exportSettings, &playlist)
+		if state.unchanged(fileName, hash) {
+			Log(LogEntry{Level: LOG_INFO, Message: fmt.Sprintf("Skipping unchanged Playlist %v\n", playlist.Name), Playlist: playlist.Name})
+			return false, nil
+		}
+	}
+
+	write, err := shouldWritePlaylist(exportSettings, fileName)
+	if err != nil {
+		return false, err
+	}
+	if !write {
+		Log(LogEntry{Level: LOG_INFO, Message: fmt.Sprintf("Skipping Playlist %v because %v already exists.\n", playlist.Name, fileName), Playlist: playlist.Name, Path: fileName})
+		return false, nil
+	}
+
+	format, ok := formats[exportSettings.ExportType]
+	if !ok {
+		return false, errors.New("export type not implemented")
+	}
+
+	exportedPlaylist := Playlist{Name: playlist.Name, Source: &playlist}
+	if exportSettings.StripSymbols {
+		exportedPlaylist.Name = stripSymbols(playlist.Name)
+	}
+	filtered := 0
+	reserved := 0
+	failed := 0
+	for _, track := range playlist.Tracks(exportSettings.Library) {
+		track := track
+
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		if exportSettings.Ignore.Ignores(track) {
+			continue
+		}
+		track, clean := cleanTrack(exportSettings, track)
+		if !clean || !includeTrack(exportSettings, &track) {
+			filtered++
+			continue
+		}
+		if exportSettings.MaxPlaylistEntries > 0 && len(exportedPlaylist.Entries) == exportSettings.MaxPlaylistEntries {
+			Log(LogEntry{Level: LOG_WARNING, Message: fmt.Sprintf("Playlist %v has more than %v tracks, the remaining tracks are left out.\n", playlist.Name, exportSettings.MaxPlaylistEntries), Playlist: playlist.Name})
+			break
+		}
+
+		sourceFileLocation := itunes.LocationPath(track.Location)
+
+		destFileLocation, err := copyTrack(ctx, library, exportSettings, &playlist, &track, sourceFileLocation)
+		if err == errReserve {
+			reserved++
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return false, ctx.Err()
+			}
+			Log(LogEntry{Level: LOG_ERROR, Message: fmt.Sprintf("Unable to copy file %v: %v\n", sourceFileLocation, err.Error()), Playlist: playlist.Name, Track: track.Title(), Path: sourceFileLocation, Error: err.Error()})
+			if exportSettings.skipped != nil {
+				exportSettings.skipped.add(sourceFileLocation, err)
+			}
+			metrics.errorOccurred()
+			exportSettings.emit(Event{Type: EVENT_ERROR, Playlist: playlist.Name, Source: sourceFileLocation, Error: err.Error()})
+			failed++
+			continue
+		}
+
+		if exportSettings.ids != nil {
+			exportSettings.ids.addTrack(&track, destFileLocation)
+		}
+		if exportSettings.snapshots != nil && exportSettings.CopyType != COPY_NONE {
+			exportSettings.snapshots.addTrack(fileName, destFileLocation, musicSource(exportSettings, sourceFileLocation))
+		}
+		entry := Entry{Track: &track, Location: destFileLocation}
+		if exportSettings.CopyType != COPY_NONE {
+			entry.Path = destFileLocation
+			entry.Location = entryLocation(exportSettings, fileName, destFileLocation)
+		}
+		exportedPlaylist.Entries = append(exportedPlaylist.Entries, entry)
+	}
+
+	if filtered > 0 {
+		Log(LogEntry{Level: LOG_INFO, Message: fmt.Sprintf("Left out %v tracks of Playlist %v because of the track filters.\n", filtered, playlist.Name), Playlist: playlist.Name})
+	}
+	if reserved > 0 {
+		Log(LogEntry{Level: LOG_WARNING, Message: fmt.Sprintf("Left out %v tracks of Playlist %v to keep the reserve free.\n", reserved, playlist.Name), Playlist: playlist.Name})
+	}
+
+	if exportSettings.PlaylistVersions > 0 {
+		if err := backupPlaylist(exportSettings.Output, fileName, exportSettings.PlaylistVersions); err != nil {
+			return false, fmt.Errorf("unable to keep the previous version of %v: %v", fileName, err)
+		}
+	}
+
+	options := Options{Settings: exportSettings, Started: started}
+	err = writeFile(exportSettings.Output, fileName, func(file io.Writer) error {
+		encoding := exportSettings.Encoding
+		if utf8Only(exportSettings.ExportType) {
+			encoding = ENCODING_UTF8
+		}
+		if encoding == ENCODING_UTF8 {
+			return writePlaylist(file, format, exportedPlaylist, options)
+		}
+		var content bytes.Buffer
+		if err := writePlaylist(&content, format, exportedPlaylist, options); err != nil {
+			return err
+		}
+		_, err := file.Write(encodePlaylist(content.Bytes(), encoding))
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	if exportSettings.Podcasts && exportSettings.FeedURL != "" && exportSettings.ExportType != RSS {
+		feedName := strings.TrimSuffix(fileName, path.Ext(fileName)) + ".rss"
+		if exportSettings.checksums != nil {
+			exportSettings.checksums.add(feedName)
+		}
+		err = writeFile(exportSettings.Output, feedName, func(file io.Writer) error {
+			return writeFeed(file, exportedPlaylist, exportSettings.FeedURL)
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+
+	// A playlist missing tracks which failed to copy or were left out for the reserve is
+	// exported again by the next run, which may be able to copy them.
+	if state != nil && failed == 0 && reserved == 0 {
+		state.update(fileName, hash)
+	} else if state != nil {
+		state.forget(fileName)
+	}
+	if exportSettings.ids != nil {
+		exportSettings.ids.addPlaylist(&playlist, fileName)
+	}
+	if exportSettings.copyOrder != nil {
+		exportSettings.copyOrder.add(fileName)
+	}
+	if exportSettings.snapshots != nil {
+		exportSettings.snapshots.playlistWritten(fileName)
+	}
+	return true, nil
+}
+
+// utf8Only reports whether the playlists of exportType are always written in UTF-8 without
+// byte order mark, as their format requires it: media playlists, XML files, scripts and
+// configuration files.
+func utf8Only(exportType string) bool {
+	switch exportType {
+	case HLS, XSPF, RSS, APPLESCRIPT, BEETS:
+		return true
+	}
+	return false
+}
+
+// writePlaylist writes playlist in format to w. If validation is enabled and the exporter
+// is a Validator, nothing is written unless the playlist passes validation.
+func writePlaylist(w io.Writer, format format, playlist Playlist, options Options) error {
+	validator, ok := format.exporter.(Validator)
+	if !options.Settings.Validate || !ok {
+		return format.exporter.Export(w, playlist, options)
+	}
+
+	var content bytes.Buffer
+	if err := format.exporter.Export(&content, playlist, options); err != nil {
+		return err
+	}
+	if err := validator.Validate(content.Bytes()); err != nil {
+		return fmt.Errorf("invalid %v playlist %v: %v", options.Settings.ExportType, playlist.Name, err)
+	}
+	_, err := content.WriteTo(w)
+	return err
+}
+
+// writeFile writes the named file to fsys, discarding it if write fails.
+func writeFile(fsys FS, name string, write func(io.Writer) error) error {
+	file, err := fsys.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Abort()
+		return err
+	}
+	return file.Close()
+}
+
+// copyTrack copies a file from the provided sourceFileLocation to another location and returns the
+// path of the copy in the output. The new location depends on the CopyType selected in exportSettings.
+// If COPY_NONE is selected, the sourceFileLocation is returned.
+func copyTrack(ctx context.Context, library *itunes.Library, exportSettings *ExportSettings, playlist *itunes.Playlist, track *itunes.Track, sourceFileLocation string) (string, error) {
+	sourceFileLocation = musicSource(exportSettings, sourceFileLocation)
+
+	switch exportSettings.CopyType {
+	case COPY_STORE:
+		storePath, err := exportSettings.store.path(strings.Replace(sourceFileLocation, "file://", "", 1))
+		if err != nil {
+			return "", err
+		}
+		dest := limitPath(exportSettings, storePath)
+		if err := copyFile(ctx, exportSettings, playlist.Name, sourceFileLocation, dest); err != nil {
+			return "", err
+		}
+		filePath := ""
+		if exportSettings.IncludeFolders && playlist.ParentPersistentId != "" {
+			filePath = buildPlaylistPath(exportSettings, *playlist, library)
+		}
+		exportSettings.store.link(dest, limitPath(exportSettings, path.Join(exportSettings.MusicDir, filePath, exportSettings.safeName(*playlist), filepath.Base(sourceFileLocation))))
+		return dest, nil
+	case COPY_NONE:
+		return sourceFileLocation, nil
+	}
+
+	dest, err := copyDestination(library, exportSettings, playlist, track, sourceFileLocation)
+	if err != nil {
+		return "", err
+	}
+	if exportSettings.layout != nil {
+		dest = exportSettings.layout.path(dest)
+	}
+	if err := copyFile(ctx, exportSettings, playlist.Name, sourceFileLocation, dest); err != nil {
+		return "", err
+	}
+	return dest, nil
+}
+
+// copyDestination returns the path in the output the music file of track at
+// sourceFileLocation is copied to for playlist, for the copy types other than COPY_STORE and
+// COPY_NONE.
+func copyDestination(library *itunes.Library, exportSettings *ExportSettings, playlist *itunes.Playlist, track *itunes.Track, sourceFileLocation string) (string, error) {
+	var destinationPath string
+	fileName := filepath.Base(sourceFileLocation)
+
+	switch exportSettings.CopyType {
+	case COPY_PLAYLIST:
+		filePath := ""
+		if exportSettings.IncludeFolders && playlist.ParentPersistentId != "" {
+			filePath = buildPlaylistPath(exportSettings, *playlist, library)
+		}
+		destinationPath = path.Join(filePath, exportSettings.safeName(*playlist))
+	case COPY_ITUNES:
+		destinationPath = path.Join(exportSettings.replace(albumArtistFolder(track)), exportSettings.replace(track.Album))
+	case COPY_FLAT:
+		destinationPath = ""
+	case COPY_TEMPLATE, COPY_VIDEO:
+		template, _ := trackCopyTemplate(exportSettings, track)
+		destinationPath, fileName = path.Split(expandCopyTemplate(exportSettings, template, track, playlist, sourceFileLocation))
+		return limitPath(exportSettings, path.Join(exportSettings.MusicDir, destinationPath, fileName)), nil
+	default:
+		return "", errors.New("unknown copy type")
+	}
+	fileName = exportSettings.replace(fileName)
+	return limitPath(exportSettings, path.Join(exportSettings.MusicDir, destinationPath, fileName)), nil
+}
+
+// musicSource returns the location of the music file at sourceFileLocation on this
+// computer, moved from OriginalMusicPath to NewMusicPath if set.
+func musicSource(exportSettings *ExportSettings, sourceFileLocation string) string {
+	if exportSettings.NewMusicPath != "" {
+		return strings.Replace(sourceFileLocation, filepath.FromSlash(exportSettings.OriginalMusicPath), exportSettings.NewMusicPath, 1)
+	}
+	return sourceFileLocation
+}
+
+// CompilationsFolder is the folder COPY_ITUNES copies the albums of compilations to,
+// instead of a folder for every artist, like iTunes does.
+const CompilationsFolder = "Compilations"
+
+// albumArtistFolder returns the artist folder of the album of track: CompilationsFolder for
+// compilations, else the album artist, which keeps albums with guest artists together, or
+// the artist.
+func albumArtistFolder(track *itunes.Track) string {
+	switch {
+	case track.Compilation:
+		return CompilationsFolder
+	case track.AlbumArtist != "":
+		return track.AlbumArtist
+	default:
+		return track.Artist
+	}
+}
+
+// entryLocation returns the location written to the playlist file playlistFile for the copied
+// music file dest, both paths of the output.
+func entryLocation(exportSettings *ExportSettings, playlistFile, dest string) string {
+	switch exportSettings.PathStyle {
+	case PATH_STYLE_RELATIVE, PATH_STYLE_RELATIVE_BACKSLASH:
+		location, err := filepath.Rel(filepath.FromSlash(path.Dir(playlistFile)), filepath.FromSlash(dest))
+		if err != nil {
+			location = dest
+		}
+		location = filepath.ToSlash(location)
+		if exportSettings.PathStyle == PATH_STYLE_RELATIVE_BACKSLASH {
+			location = strings.Replace(location, "/", "\\", -1)
+		}
+		return location
+	default:
+		return filepath.Join(exportSettings.OutputPath, filepath.FromSlash(dest))
+	}
+}
+
+func copyFile(ctx context.Context, exportSettings *ExportSettings, playlistName, src, dest string) error {
+	src, sourceFileInfo, err := itunes.StatPath(strings.Replace(src, "file://", "", 1))
+	if err != nil {
+		return err
+	}
+
+	if !sourceFileInfo.Mode().IsRegular() {
+		return errors.New("source file is not a regular file")
+	}
+	if sourceFileInfo.Size() == 0 {
+		return errEmptySource
+	}
+
+	unlock := lockCopyDestination(dest)
+	defer unlock()
+
+	doCopy, err := shouldCopyFile(exportSettings, sourceFileInfo, dest)
+	if err != nil {
+		return err
+	}
+	if doCopy && exportSettings.space != nil {
+		size := sourceFileInfo.Size()
+		if existing, err := exportSettings.Output.Stat(dest); err == nil {
+			size -= existing.Size()
+		}
+		if !exportSettings.space.take(size) {
+			return errReserve
+		}
+	}
+	if exportSettings.checksums != nil {
+		exportSettings.checksums.add(dest)
+	}
+	if exportSettings.copyOrder != nil {
+		exportSettings.copyOrder.add(dest)
+	}
+	if !doCopy {
+		// No need to copy.
+		return nil
+	}
+
+	exportSettings.emit(Event{Type: EVENT_FILE_STARTED, Playlist: playlistName, Source: src, File: dest})
+	if err := copyFileRetrying(ctx, exportSettings, src, dest); err != nil {
+		return err
+	}
+	exportSettings.emit(Event{Type: EVENT_FILE_FINISHED, Playlist: playlistName, Source: src, File: dest, Bytes: sourceFileInfo.Size()})
+	return nil
+}
+
+// copyLocks serializes copies to the same destination, as the same track may be
+// part of several playlists exported in parallel. A lock is removed once no copy holds
+// or waits for it, so long running processes do not keep one for every file copied.
+var copyLocks = struct {
+	sync.Mutex
+	paths map[string]*copyLock
+}{paths: make(map[string]*copyLock)}
+
+// copyLock is the lock of a destination and the number of copies holding or waiting for it.
+type copyLock struct {
+	sync.Mutex
+	users int
+}
+
+func lockCopyDestination(dest string) func() {
+	copyLocks.Lock()
+	lock, ok := copyLocks.paths[dest]
+	if !ok {
+		lock = &copyLock{}
+		copyLocks.paths[dest] = lock
+	}
+	lock.users++
+	copyLocks.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+		copyLocks.Lock()
+		lock.users--
+		if lock.users == 0 {
+			delete(copyLocks.paths, dest)
+		}
+		copyLocks.Unlock()
+	}
+}
+
+// maxRetryWait limits the time waited between two retries of a copy.
+const maxRetryWait = time.Minute
+
+// copyFileRetrying copies src to dest, retrying up to exportSettings.Retries times with
+// increasing delays if the copy fails for another reason than a missing or unreadable source.
+func copyFileRetrying(ctx context.Context, exportSettings *ExportSettings, src, dest string) error {
+	wait := exportSettings.RetryWait
+	if wait <= 0 {
+		wait = time.Second
+	}
+	for attempt := 1; ; attempt++ {
+		err := copyFileData(ctx, exportSettings.Output, exportSettings.limiter, src, dest)
+		var sourceErr sourceError
+		if err == nil || attempt > exportSettings.Retries || ctx.Err() != nil || errors.As(err, &sourceErr) {
+			return err
+		}
+		Log(LogEntry{Level: LOG_WARNING, Message: fmt.Sprintf("Unable to copy %v, retrying in %v (%v of %v): %v\n", dest, wait, attempt, exportSettings.Retries, err), Path: dest, Error: err.Error()})
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		if wait *= 2; wait > maxRetryWait {
+			wait = maxRetryWait
+		}
+	}
+}
+
+// sourceError is an error opening or reading the source of a copy, which is not retried.
+type sourceError struct {
+	err error
+}
+
+func (err sourceError) Error() string {
+	return err.err.Error()
+}
+
+func (err sourceError) Unwrap() error {
+	return err.err
+}
+
+// copyFileData copies src to dest in fsys at the rate allowed by limiter, if any. If ctx is
+// done during the copy or src changes while it is copied, the partially written file is
+// discarded.
+func copyFileData(ctx context.Context, fsys FS, limiter *bandwidthLimiter, src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return sourceError{err}
+	}
+	defer in.Close()
+	before, err := in.Stat()
+	if err != nil {
+		return sourceError{err}
+	}
+
+	var written int64
+	err = writeFile(fsys, dest, func(out io.Writer) error {
+		written, err = io.Copy(out, limitedReader{ctx, limiter, contextReader{ctx, sourceReader{in}}})
+		if err != nil {
+			return err
+		}
+		after, err := os.Stat(src)
+		if err != nil || written != before.Size() || after.Size() != before.Size() || !after.ModTime().Equal(before.ModTime()) {
+			return sourceError{errSourceChanged}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	metrics.fileCopied(written)
+	return nil
+}
+
+// sourceReader marks the read errors of the source of a copy as sourceError.
+type sourceReader struct {
+	io.Reader
+}
+
+func (reader sourceReader) Read(p []byte) (int, error) {
+	n, err := reader.Reader.Read(p)
+	if err != nil && err != io.EOF {
+		err = sourceError{err}
+	}
+	return n, err
+}
+
+// contextReader fails reads once its context is done.
+type contextReader struct {
+	ctx context.Context
+	io.Reader
+}
+
+func (reader contextReader) Read(p []byte) (int, error) {
+	if err := reader.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return reader.Reader.Read(p)
+}
+
+// buildPlaylistPath checks to see if the playlist has any parent folders.
+// If so, it returns the full path of those folders.
+func buildPlaylistPath(exportSettings *ExportSettings, playlist itunes.Playlist, library *itunes.Library) string {
+	if playlist.ParentPersistentId == "" {
+		if playlist.Folder {
+			return exportSettings.safeName(playlist)
+		}
+		return ""
+	}
+
+	parent, ok := library.PlaylistIdMap[playlist.ParentPersistentId]
+	if !ok {
+		return ""
+	}
+	pathSeg := ""
+	if playlist.Folder {
+		pathSeg = exportSettings.safeName(playlist)
+	}
+	return path.Join(buildPlaylistPath(exportSettings, parent, library), pathSeg)
+}
@@ -0,0 +1,316 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+func TestExportPlaylistsInParallel(t *testing.T) {
+	outputDir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(outputDir)
+
+	musicFile, musicFileName := prepareMusicFile(t)
+	defer os.Remove(musicFile)
+
+	library := &itunes.Library{
+		Tracks: map[string]itunes.Track{
+			"1": {TrackId: 1, Name: "Some Song", Location: "file://" + filepath.ToSlash(musicFile)},
+		},
+	}
+	for i := 0; i < 20; i++ {
+		library.Playlists = append(library.Playlists, itunes.Playlist{Name: fmt.Sprintf("Playlist %v", i), PlaylistItems: []itunes.PlaylistItem{{TrackId: 1}}})
+	}
+
+	progressCalls := 0
+	settings := &ExportSettings{
+		Library:    library,
+		Playlists:  library.Playlists,
+		ExportType: M3U,
+		Extension:  "m3u",
+		CopyType:   COPY_FLAT,
+		OutputPath: outputDir,
+		Parallel:   4,
+		Progress:   func(done, total int, _ string) { progressCalls++ },
+	}
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+
+	if progressCalls != 20 {
+		t.Errorf("expected 20 progress calls, got %v", progressCalls)
+	}
+	for _, playlist := range library.Playlists {
+		assertPlaylistFileCorrectlyWritten(t, filepath.Join(outputDir, playlist.Name+".m3u"), filepath.Join(outputDir, musicFileName))
+	}
+}
+
+func TestExportPlaylistsStopsWhenCancelled(t *testing.T) {
+	fsys := NewMemFS()
+	library := testLibrary()
+	settings := &ExportSettings{Library: library, Playlists: library.Playlists, Output: fsys}
+	ParseExportType(settings, "M3U")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := ExportPlaylistsContext(ctx, settings, library)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if files := fsys.Files(); len(files) != 0 {
+		t.Fatalf("expected no files to be written, found %v", files)
+	}
+}
+
+func TestCopyFileDataRemovesPartialFileWhenCancelled(t *testing.T) {
+	outputDir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(outputDir)
+
+	src := filepath.Join(outputDir, "source.mp3")
+	writeLocalFile(t, src, strings.Repeat("x", 1<<20))
+	fsys := NewDirFS(filepath.Join(outputDir, "out"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := copyFileData(ctx, fsys, nil, src, "song.mp3"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	files, _ := ioutil.ReadDir(filepath.Join(outputDir, "out"))
+	if len(files) != 0 {
+		t.Fatalf("expected partial file to be removed, found %v files", len(files))
+	}
+}
+
+func TestCopyFileFindsOtherNormalizationForm(t *testing.T) {
+	dir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(dir)
+
+	// The file name is decomposed (NFD), the library location precomposed (NFC).
+	writeLocalFile(t, filepath.Join(dir, "Cafe\u0301.mp3"), FileContent)
+
+	fsys := NewMemFS()
+	settings := &ExportSettings{Output: fsys}
+	if err := copyFile(context.Background(), settings, "My Playlist", filepath.Join(dir, "Caf\u00e9.mp3"), "Caf\u00e9.mp3"); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := fsys.ReadFile("Caf\u00e9.mp3"); string(content) != FileContent {
+		t.Fatalf("expected file to be copied, got %q", content)
+	}
+}
+
+// failingFS fails the first failures files created.
+type failingFS struct {
+	FS
+	failures int
+}
+
+func (fsys *failingFS) Create(name string) (File, error) {
+	if fsys.failures > 0 {
+		fsys.failures--
+		return nil, errors.New("connection reset")
+	}
+	return fsys.FS.Create(name)
+}
+
+func TestCopyFileRetries(t *testing.T) {
+	dir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "song.mp3")
+	writeLocalFile(t, src, FileContent)
+
+	fsys := &failingFS{FS: NewMemFS(), failures: 2}
+	settings := &ExportSettings{Output: fsys, Retries: 2, RetryWait: time.Millisecond}
+	if err := copyFile(context.Background(), settings, "My Playlist", src, "song.mp3"); err != nil {
+		t.Fatal(err)
+	}
+
+	fsys.failures = 3
+	if err := copyFile(context.Background(), settings, "My Playlist", src, "other.mp3"); err == nil {
+		t.Fatal("expected error once all retries failed")
+	}
+
+	// A missing source is not retried.
+	fsys.failures = 0
+	if err := copyFileRetrying(context.Background(), settings, filepath.Join(dir, "missing.mp3"), "missing.mp3"); !os.IsNotExist(errors.Unwrap(err)) {
+		t.Fatalf("expected the missing source file to fail, got %v", err)
+	}
+}
+
+func TestCopyItunesKeepsAlbumsTogether(t *testing.T) {
+	outputDir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(outputDir)
+
+	musicFile, musicFileName := prepareMusicFile(t)
+	defer os.Remove(musicFile)
+
+	library := testLibrary()
+	track := library.Tracks["1"]
+	track.Album = "Now 42"
+	track.AlbumArtist = "Various Artists"
+	track.Compilation = true
+	track.Location = "file://" + filepath.ToSlash(musicFile)
+	library.Tracks["1"] = track
+
+	settings := &ExportSettings{Library: library, Playlists: library.Playlists, CopyType: COPY_ITUNES, OutputPath: outputDir}
+	ParseExportType(settings, M3U)
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+	assertPathExists(t, filepath.Join(outputDir, CompilationsFolder, "Now 42", musicFileName))
+
+	for _, test := range []struct {
+		track  itunes.Track
+		folder string
+	}{
+		{itunes.Track{Artist: "Guest", AlbumArtist: "Band"}, "Band"},
+		{itunes.Track{Artist: "Band"}, "Band"},
+		{itunes.Track{Artist: "Guest", AlbumArtist: "Band", Compilation: true}, CompilationsFolder},
+	} {
+		if folder := albumArtistFolder(&test.track); folder != test.folder {
+			t.Fatalf("expected folder %v for %v, got %v", test.folder, test.track, folder)
+		}
+	}
+}
+
+func TestExportPlaylistInFolder(t *testing.T) {
+	outputDir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(outputDir)
+
+	musicFile, musicFileName := prepareMusicFile(t)
+	defer os.Remove(musicFile)
+
+	library := testLibrary()
+	track := library.Tracks["1"]
+	track.Location = "file://" + filepath.ToSlash(musicFile)
+	library.Tracks["1"] = track
+
+	settings := &ExportSettings{Library: library, Playlists: library.Playlists, CopyType: COPY_PLAYLIST, OutputPath: outputDir,
+		PlaylistDir: "Playlists", PlaylistInFolder: true}
+	ParseExportType(settings, M3U)
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+	assertPlaylistFileCorrectlyWritten(t, filepath.Join(outputDir, "My Playlist", "My Playlist.m3u"), musicFileName)
+	assertPathExists(t, filepath.Join(outputDir, "My Playlist", musicFileName))
+}
+
+func TestExportSkipsContainers(t *testing.T) {
+	fsys := NewMemFS()
+	library := testLibrary()
+	library.Playlists = append(library.Playlists,
+		itunes.Playlist{Name: "Mixes", PlaylistPersistentId: "F01DE2", Folder: true},
+		itunes.Playlist{Name: "Genius Mixes", PlaylistPersistentId: "6E41U5", DistinguishedKind: itunes.KIND_GENIUS_MIXES})
+	library.BuildPlaylistMaps()
+
+	settings := &ExportSettings{Library: library, Playlists: library.Playlists, Output: fsys, IncludeFolders: true}
+	ParseExportType(settings, M3U)
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+	if files := fsys.Files(); len(files) != 1 || files[0] != "My Playlist.m3u" {
+		t.Fatalf("expected only the playlist with tracks to be written, got %v", files)
+	}
+	if info, err := fsys.Stat("Mixes"); err != nil || !info.IsDir() {
+		t.Fatalf("expected a directory for the folder, got %v", err)
+	}
+}
+
+// changingFS changes the source file while its copy is written.
+type changingFS struct {
+	FS
+	src string
+}
+
+type changingFile struct {
+	File
+	src     string
+	changed bool
+}
+
+func (fsys *changingFS) Create(name string) (File, error) {
+	file, err := fsys.FS.Create(name)
+	return &changingFile{File: file, src: fsys.src}, err
+}
+
+func (file *changingFile) Write(p []byte) (int, error) {
+	if !file.changed {
+		file.changed = true
+		if err := ioutil.WriteFile(file.src, []byte(FileContent+" more"), 0644); err != nil {
+			return 0, err
+		}
+	}
+	return file.File.Write(p)
+}
+
+func TestCopyFileSkipsEmptyAndChangingSources(t *testing.T) {
+	dir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(dir)
+	empty := filepath.Join(dir, "empty.mp3")
+	writeLocalFile(t, empty, "")
+	src := filepath.Join(dir, "song.mp3")
+	writeLocalFile(t, src, FileContent)
+
+	fsys := &changingFS{FS: NewMemFS(), src: src}
+	settings := &ExportSettings{Output: fsys}
+	if err := copyFile(context.Background(), settings, "My Playlist", empty, "empty.mp3"); err != errEmptySource {
+		t.Fatalf("expected the empty file to be skipped, got %v", err)
+	}
+	if err := copyFile(context.Background(), settings, "My Playlist", src, "song.mp3"); !errors.Is(err, errSourceChanged) {
+		t.Fatalf("expected the changing file to be skipped, got %v", err)
+	}
+	for _, name := range []string{"empty.mp3", "song.mp3"} {
+		if _, err := fsys.Stat(name); err == nil {
+			t.Fatalf("expected no copy of %v", name)
+		}
+	}
+
+	skipped := newSkippedFiles()
+	skipped.add(empty, errEmptySource)
+	skipped.add(src, &os.PathError{Op: "open", Path: src, Err: os.ErrPermission})
+	if reason := skipped.reasons[src]; !strings.Contains(reason, "not readable") {
+		t.Fatalf("expected a clear reason for unreadable files, got %v", reason)
+	}
+}
+
+func TestLockCopyDestination(t *testing.T) {
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	holding, maxHolding := 0, 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock := lockCopyDestination("Music/song.mp3")
+			mutex.Lock()
+			holding++
+			if holding > maxHolding {
+				maxHolding = holding
+			}
+			mutex.Unlock()
+			time.Sleep(time.Millisecond)
+			mutex.Lock()
+			holding--
+			mutex.Unlock()
+			unlock()
+		}()
+	}
+	wg.Wait()
+
+	if maxHolding != 1 {
+		t.Fatalf("expected copies to the same destination one at a time, got %v at once", maxHolding)
+	}
+	copyLocks.Lock()
+	defer copyLocks.Unlock()
+	if len(copyLocks.paths) != 0 {
+		t.Fatalf("expected the locks to be removed after the copies, got %v", copyLocks.paths)
+	}
+}
@@ -0,0 +1,88 @@
+package export
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+// Playlist is a playlist ready to be exported. Its tracks have been resolved and copied
+// as configured, and Location of each entry is the path to write to the playlist file.
+type Playlist struct {
+	Name    string
+	Source  *itunes.Playlist
+	Entries []Entry
+}
+
+// Entry is a single track of an exported playlist.
+type Entry struct {
+	Track    *itunes.Track
+	Location string
+	// Path is the path of the copied music file in the output, if it was copied.
+	Path string
+}
+
+// Options are passed to an Exporter along with each playlist.
+type Options struct {
+	// Settings of the running export.
+	Settings *ExportSettings
+	// Started is the time the export started.
+	Started time.Time
+}
+
+// Exporter writes a playlist in a specific playlist file format.
+type Exporter interface {
+	Export(w io.Writer, playlist Playlist, options Options) error
+}
+
+// Validator is implemented by exporters which can check the playlist files they wrote.
+// It is used when ExportSettings.Validate is set.
+type Validator interface {
+	Validate(content []byte) error
+}
+
+// ExporterFunc adapts a function to the Exporter interface.
+type ExporterFunc func(w io.Writer, playlist Playlist, options Options) error
+
+func (f ExporterFunc) Export(w io.Writer, playlist Playlist, options Options) error {
+	return f(w, playlist, options)
+}
+
+type format struct {
+	exporter  Exporter
+	extension string
+}
+
+var formats = map[string]format{
+	M3U: {ExporterFunc(exportM3U), "m3u"},
+	EXT: {ExporterFunc(exportEXT), "m3u"},
+	// M3U8 is the extended M3U format with the extension of UTF-8 encoded playlists.
+	M3U8:        {ExporterFunc(exportEXT), "m3u8"},
+	WPL:         {wplExporter, "wpl"},
+	ZPL:         {zplExporter, "zpl"},
+	HLS:         {ExporterFunc(exportHLS), "m3u8"},
+	XSPF:        {xspfExporter{}, "xspf"},
+	RSS:         {rssExporter{}, "rss"},
+	APPLESCRIPT: {ExporterFunc(exportAppleScript), "applescript"},
+	BEETS:       {ExporterFunc(exportBeets), "yaml"},
+}
+
+// RegisterExporter makes a playlist format available under name (case insensitive),
+// writing files with the given extension. It replaces any format registered under the
+// same name and must not be called while exports are running, typically from init.
+func RegisterExporter(name string, extension string, exporter Exporter) {
+	formats[strings.ToUpper(name)] = format{exporter: exporter, extension: extension}
+}
+
+// ExportTypes returns the names of all registered playlist formats.
+func ExportTypes() []string {
+	var names []string
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
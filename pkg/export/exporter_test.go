@@ -0,0 +1,261 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+func TestRegisterExporter(t *testing.T) {
+	outputDir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(outputDir)
+
+	RegisterExporter("names", "txt", ExporterFunc(func(w io.Writer, playlist Playlist, _ Options) error {
+		for _, entry := range playlist.Entries {
+			fmt.Fprintln(w, entry.Track.Name)
+		}
+		return nil
+	}))
+	defer delete(formats, "NAMES")
+
+	library := testLibrary()
+	settings := &ExportSettings{Library: library, Playlists: library.Playlists, OutputPath: outputDir}
+	if err := ParseExportType(settings, "Names"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+
+	if content := readFile(t, filepath.Join(outputDir, "My Playlist.txt")); content != "Some Song\n" {
+		t.Fatalf("unexpected playlist content %q", content)
+	}
+}
+
+func TestExportEXT(t *testing.T) {
+	track := itunes.Track{Name: "Some Song", Artist: "Some Artist", TotalTime: 210500}
+	playlist := Playlist{Name: "My Playlist", Entries: []Entry{{Track: &track, Location: "/music/song.mp3"}}}
+
+	var buffer bytes.Buffer
+	if err := formats[EXT].exporter.Export(&buffer, playlist, Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "#EXTM3U\n#EXTINF:210,Some Artist - Some Song\n/music/song.mp3\n"
+	if buffer.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, buffer.String())
+	}
+}
+
+func TestExportEXTNamesMovements(t *testing.T) {
+	track := itunes.Track{Name: "Andante", Artist: "Orchestra", TotalTime: 540000, Work: "Symphony No. 5", MovementName: "Andante con moto", MovementNumber: 2}
+	playlist := Playlist{Name: "Classics", Entries: []Entry{{Track: &track, Location: "/music/2.mp3"}}}
+
+	var buffer bytes.Buffer
+	if err := formats[EXT].exporter.Export(&buffer, playlist, Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "#EXTM3U\n#EXTINF:540,Orchestra - Symphony No. 5: II. Andante con moto\n/music/2.mp3\n"
+	if buffer.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, buffer.String())
+	}
+}
+
+func TestExportEXTWithTemplate(t *testing.T) {
+	track := itunes.Track{Name: "Some Song", Artist: "Some Artist", Album: "Some\nAlbum", TrackNumber: 3, TotalTime: 210500}
+	playlist := Playlist{Name: "My Playlist", Entries: []Entry{{Track: &track, Location: "/music/song.mp3"}}}
+
+	settings := &ExportSettings{}
+	if err := ParseExtinfTemplate(settings, "{track}. {title} ({album})"); err != nil {
+		t.Fatal(err)
+	}
+	var buffer bytes.Buffer
+	if err := formats[M3U8].exporter.Export(&buffer, playlist, Options{Settings: settings}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "#EXTM3U\n#EXTINF:210,03. Some Song (Some Album)\n/music/song.mp3\n"
+	if buffer.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, buffer.String())
+	}
+	if err := ParseExtinfTemplate(settings, "{title} {bogus}"); err == nil {
+		t.Fatal("expected error for an unknown placeholder")
+	}
+}
+
+func TestExportEXTWithAlbumDirectives(t *testing.T) {
+	first := itunes.Track{Name: "One", Artist: "Some Artist", Album: "Some Album", Genre: "Rock", TotalTime: 60000}
+	second := itunes.Track{Name: "Two", Artist: "Some Artist", Album: "Some Album", Genre: "Rock", TotalTime: 60000}
+	other := itunes.Track{Name: "Three", Artist: "Guest", AlbumArtist: "Various Artists", Album: "Hits", TotalTime: 60000}
+	playlist := Playlist{Name: "My Playlist", Entries: []Entry{
+		{Track: &first, Location: "1.mp3"}, {Track: &second, Location: "2.mp3"}, {Track: &other, Location: "3.mp3"},
+	}}
+
+	var buffer bytes.Buffer
+	if err := formats[EXT].exporter.Export(&buffer, playlist, Options{Settings: &ExportSettings{AlbumDirectives: true}}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "#EXTM3U\n" +
+		"#EXTALB:Some Album\n#EXTART:Some Artist\n#EXTGENRE:Rock\n" +
+		"#EXTINF:60,Some Artist - One\n1.mp3\n#EXTINF:60,Some Artist - Two\n2.mp3\n" +
+		"#EXTALB:Hits\n#EXTART:Various Artists\n" +
+		"#EXTINF:60,Guest - Three\n3.mp3\n"
+	if buffer.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, buffer.String())
+	}
+}
+
+func TestExportHLS(t *testing.T) {
+	first := itunes.Track{Name: "Some Song", Artist: "Some Artist", TotalTime: 210500}
+	second := itunes.Track{Name: "Other", Artist: "Some Artist", TotalTime: 60000}
+	playlist := Playlist{Name: "My Playlist", Entries: []Entry{
+		{Track: &first, Location: "My Playlist\\Some Song #1.mp3"},
+		{Track: &second, Location: "/music/other song.mp3"},
+	}}
+
+	var buffer bytes.Buffer
+	if err := formats[HLS].exporter.Export(&buffer, playlist, Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:211\n#EXT-X-PLAYLIST-TYPE:VOD\n" +
+		"#EXTINF:211,Some Artist - Some Song\nMy%20Playlist/Some%20Song%20%231.mp3\n" +
+		"#EXTINF:60,Some Artist - Other\nfile://localhost/music/other%20song.mp3\n" +
+		"#EXT-X-ENDLIST\n"
+	if buffer.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, buffer.String())
+	}
+}
+
+func TestExportXSPF(t *testing.T) {
+	track := itunes.Track{Name: "Clip", Artist: "Some Artist", Album: "Live", TrackNumber: 2, TotalTime: 210500}
+	playlist := Playlist{Name: "Videos & More", Entries: []Entry{{Track: &track, Location: "Music Videos/Some Artist/Clip #1.m4v"}}}
+
+	var buffer bytes.Buffer
+	if err := formats[XSPF].exporter.Export(&buffer, playlist, Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<playlist xmlns="http://xspf.org/ns/0/" version="1">
+  <title>Videos &amp; More</title>
+  <trackList>
+    <track>
+      <location>Music%20Videos/Some%20Artist/Clip%20%231.m4v</location>
+      <title>Clip</title>
+      <creator>Some Artist</creator>
+      <album>Live</album>
+      <trackNum>2</trackNum>
+      <duration>210500</duration>
+    </track>
+  </trackList>
+</playlist>
+`
+	if buffer.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, buffer.String())
+	}
+	if err := formats[XSPF].exporter.(Validator).Validate(buffer.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateXSPF(t *testing.T) {
+	for _, content := range []string{
+		`<playlist xmlns="http://xspf.org/ns/0/" version="1"><trackList><track><location>a.mp3</track></trackList></playlist>`,
+		`<playlist xmlns="http://xspf.org/ns/0/" version="1"><trackList><track><title>a</title></track></trackList></playlist>`,
+		`<playlist xmlns="http://xspf.org/ns/0/" version="1"><title>a</title></playlist>`,
+		`<playlist version="1"><trackList></trackList></playlist>`,
+		`<smil><body><seq></seq></body></smil>`,
+	} {
+		if err := formats[XSPF].exporter.(Validator).Validate([]byte(content)); err == nil {
+			t.Errorf("expected %q to be invalid", content)
+		}
+	}
+}
+
+func TestExportWPL(t *testing.T) {
+	track := itunes.Track{Name: "Some Song"}
+	playlist := Playlist{Name: "Rock & Roll", Entries: []Entry{{Track: &track, Location: `/music/"Live" <1>.mp3`}}}
+
+	var buffer bytes.Buffer
+	if err := wplExporter.Export(&buffer, playlist, Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<?wpl version="1.0"?>
+<smil>
+  <head>
+    <author />
+    <title>Rock &amp; Roll</title>
+  </head>
+  <body>
+    <seq>
+      <media src="/music/&#34;Live&#34; &lt;1&gt;.mp3" />
+    </seq>
+  </body>
+</smil>
+`
+	if buffer.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, buffer.String())
+	}
+	if err := wplExporter.Validate(buffer.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if locations, _ := readSmilLocations(&buffer); len(locations) != 1 || locations[0] != `/music/"Live" <1>.mp3` {
+		t.Fatalf("unexpected locations %q", locations)
+	}
+}
+
+func TestValidateSmil(t *testing.T) {
+	for _, content := range []string{
+		`<?wpl version="1.0"?><smil><head><title>a</title></head><body><seq><media src=/a.mp3></media></seq></body></smil>`,
+		`<?wpl version="1.0"?><smil><head><title>a</title></head><body><seq><media /></seq></body></smil>`,
+		`<?wpl version="1.0"?><smil><head><title>R&B</title></head><body><seq></seq></body></smil>`,
+		`<?wpl version="1.0"?><smil><head></head><body><seq></seq></body></smil>`,
+		`<?wpl version="1.0"?><playlist></playlist>`,
+	} {
+		if err := wplExporter.Validate([]byte(content)); err == nil {
+			t.Errorf("expected %q to be invalid", content)
+		}
+	}
+}
+
+type invalidExporter struct{}
+
+func (invalidExporter) Export(w io.Writer, playlist Playlist, _ Options) error {
+	_, err := io.WriteString(w, "broken")
+	return err
+}
+
+func (invalidExporter) Validate(content []byte) error {
+	return fmt.Errorf("always invalid")
+}
+
+func TestExportValidatesPlaylists(t *testing.T) {
+	RegisterExporter("invalid", "txt", invalidExporter{})
+	defer delete(formats, "INVALID")
+
+	fsys := NewMemFS()
+	library := testLibrary()
+	settings := &ExportSettings{Library: library, Playlists: library.Playlists, Output: fsys, Validate: true}
+	ParseExportType(settings, "invalid")
+
+	if err := ExportPlaylists(settings, library); err == nil {
+		t.Fatal("expected invalid playlist to fail the export")
+	}
+	if files := fsys.Files(); len(files) != 0 {
+		t.Fatalf("expected no playlist to be written, found %v", files)
+	}
+
+	settings.Validate = false
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,171 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/dlna"
+)
+
+// rssFeed is an RSS 2.0 feed with the iTunes podcast extensions podcast apps read.
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	ITunes  string     `xml:"xmlns:itunes,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	Author      string    `xml:"itunes:author,omitempty"`
+	Items       []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string       `xml:"title"`
+	Description string       `xml:"description,omitempty"`
+	Enclosure   rssEnclosure `xml:"enclosure"`
+	GUID        rssGUID      `xml:"guid"`
+	PubDate     string       `xml:"pubDate,omitempty"`
+	Duration    int          `xml:"itunes:duration,omitempty"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int    `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+type rssGUID struct {
+	PermaLink bool   `xml:"isPermaLink,attr"`
+	Value     string `xml:",chardata"`
+}
+
+// rssExporter writes playlists as podcast feeds, their enclosures linking to the copied music
+// files below the FeedURL of the export.
+type rssExporter struct{}
+
+func (rssExporter) Export(w io.Writer, playlist Playlist, options Options) error {
+	if options.Settings == nil || options.Settings.FeedURL == "" {
+		return errors.New("RSS playlists require the URL the output is hosted at")
+	}
+	return writeFeed(w, playlist, options.Settings.FeedURL)
+}
+
+// Validate checks that content is a well-formed RSS feed with a channel title and an
+// enclosure URL for every item.
+func (rssExporter) Validate(content []byte) error {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+
+	var elements []string
+	seenTitle, seenEnclosure := false, false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			if len(elements) == 0 && token.Name.Local != "rss" {
+				return fmt.Errorf("unexpected root element %v", token.Name.Local)
+			}
+			elements = append(elements, token.Name.Local)
+			switch strings.Join(elements, "/") {
+			case "rss/channel/title":
+				seenTitle = true
+			case "rss/channel/item":
+				seenEnclosure = false
+			case "rss/channel/item/enclosure":
+				if xmlAttr(token, "url") == "" {
+					return fmt.Errorf("enclosure element without url at offset %v", decoder.InputOffset())
+				}
+				seenEnclosure = true
+			}
+		case xml.EndElement:
+			if strings.Join(elements, "/") == "rss/channel/item" && !seenEnclosure {
+				return fmt.Errorf("item element without enclosure at offset %v", decoder.InputOffset())
+			}
+			elements = elements[:len(elements)-1]
+		}
+	}
+
+	if !seenTitle {
+		return errors.New("missing channel title element")
+	}
+	return nil
+}
+
+// writeFeed writes the entries of playlist as RSS feed to w, their enclosures linking to the
+// copied music files below baseURL. Entries which were not copied are left out, unless they
+// are streams.
+func writeFeed(w io.Writer, playlist Playlist, baseURL string) error {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	feed := rssFeed{
+		Version: "2.0",
+		ITunes:  "http://www.itunes.com/dtds/podcast-1.0.dtd",
+		Channel: rssChannel{Title: playlist.Name, Link: baseURL + "/", Description: playlist.Name},
+	}
+	for _, entry := range playlist.Entries {
+		track := entry.Track
+		var location, extension string
+		switch {
+		case entry.Path != "":
+			location = baseURL + "/" + (&url.URL{Path: entry.Path}).EscapedPath()
+			extension = path.Ext(entry.Path)
+		case track.TrackType == "URL":
+			location = track.Location
+			if parsed, err := url.Parse(location); err == nil {
+				extension = path.Ext(parsed.Path)
+			}
+		default:
+			continue
+		}
+		if feed.Channel.Author == "" {
+			feed.Channel.Author = track.Artist
+		}
+
+		item := rssItem{
+			Title:       track.Title(),
+			Description: track.Comments,
+			Enclosure: rssEnclosure{
+				URL:    location,
+				Length: track.Size,
+				Type:   dlna.MimeType(strings.ToLower(extension)),
+			},
+			GUID:     rssGUID{Value: track.PersistentId},
+			Duration: track.TotalTime / 1000,
+		}
+		if item.GUID.Value == "" {
+			item.GUID.Value = strconv.Itoa(track.TrackId)
+		}
+		if published := track.Published(); !published.IsZero() {
+			item.PubDate = published.UTC().Format(time.RFC1123Z)
+		}
+		feed.Channel.Items = append(feed.Channel.Items, item)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(feed); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,110 @@
+package export
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+func TestExportPodcastsWithFeed(t *testing.T) {
+	outputDir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(outputDir)
+
+	musicFile, _ := prepareMusicFile(t)
+	defer os.Remove(musicFile)
+
+	library := testLibrary()
+	library.Tracks["2"] = itunes.Track{TrackId: 2, Name: "Pilot", Artist: "Host", Album: "The Show", Podcast: true, Size: 2,
+		TotalTime: 1800000, PersistentId: "0123456789ABCDEF", ReleaseDate: time.Date(2024, 5, 2, 8, 0, 0, 0, time.UTC),
+		Location: "file://" + filepath.ToSlash(musicFile)}
+	library.Playlists[0].PlaylistItems = append(library.Playlists[0].PlaylistItems, itunes.PlaylistItem{TrackId: 2})
+
+	settings := &ExportSettings{Library: library, Playlists: library.Playlists, OutputPath: outputDir, PathStyle: PATH_STYLE_RELATIVE,
+		Podcasts: true, FeedURL: "https://home.example.com/podcasts/"}
+	ParseExportType(settings, M3U)
+	if err := ParseCopyTemplate(settings, PodcastCopyTemplate); err != nil {
+		t.Fatal(err)
+	}
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+
+	assertPathExists(t, filepath.Join(outputDir, "The Show", "2024-05-02 Pilot.mp3"))
+	assertPlaylistFileCorrectlyWritten(t, filepath.Join(outputDir, "My Playlist.m3u"), "The Show/2024-05-02 Pilot.mp3")
+	feed, err := ioutil.ReadFile(filepath.Join(outputDir, "My Playlist.rss"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		`<title>My Playlist</title>`,
+		`<itunes:author>Host</itunes:author>`,
+		`<enclosure url="https://home.example.com/podcasts/The%20Show/2024-05-02%20Pilot.mp3" length="2" type="audio/mpeg"></enclosure>`,
+		`<guid isPermaLink="false">0123456789ABCDEF</guid>`,
+		`<pubDate>Thu, 02 May 2024 08:00:00 +0000</pubDate>`,
+		`<itunes:duration>1800</itunes:duration>`,
+	} {
+		if !strings.Contains(string(feed), expected) {
+			t.Fatalf("expected %v in feed:\n%s", expected, feed)
+		}
+	}
+	if strings.Contains(string(feed), "Some Song") {
+		t.Fatalf("expected the music to be left out of the feed:\n%s", feed)
+	}
+}
+
+func TestExportRSS(t *testing.T) {
+	song := itunes.Track{Name: "Some Song", Artist: "Some Artist", TotalTime: 210500, Size: 42, PersistentId: "0123456789ABCDEF"}
+	local := itunes.Track{TrackId: 7, Name: "Not Copied", Location: "file:///music/other.mp3"}
+	stream := itunes.Track{TrackId: 8, Name: "Radio", TrackType: "URL", Location: "http://radio.example.com/live.mp3"}
+	playlist := Playlist{Name: "Road Trip", Entries: []Entry{
+		{Track: &song, Location: "Road Trip/Some Song.mp3", Path: "Road Trip/Some Song.mp3"},
+		{Track: &local, Location: "/music/other.mp3"},
+		{Track: &stream, Location: stream.Location},
+	}}
+
+	var buffer bytes.Buffer
+	if err := formats[RSS].exporter.Export(&buffer, playlist, Options{Settings: &ExportSettings{}}); err == nil {
+		t.Fatal("expected an error without feed URL")
+	}
+	buffer.Reset()
+	if err := formats[RSS].exporter.Export(&buffer, playlist, Options{Settings: &ExportSettings{FeedURL: "https://home.example.com/music"}}); err != nil {
+		t.Fatal(err)
+	}
+	feed := buffer.String()
+	for _, expected := range []string{
+		`<link>https://home.example.com/music/</link>`,
+		`<enclosure url="https://home.example.com/music/Road%20Trip/Some%20Song.mp3" length="42" type="audio/mpeg"></enclosure>`,
+		`<enclosure url="http://radio.example.com/live.mp3" length="0" type="audio/mpeg"></enclosure>`,
+		`<guid isPermaLink="false">8</guid>`,
+	} {
+		if !strings.Contains(feed, expected) {
+			t.Fatalf("expected %v in feed:\n%s", expected, feed)
+		}
+	}
+	if strings.Contains(feed, "Not Copied") {
+		t.Fatalf("expected tracks which were not copied to be left out:\n%s", feed)
+	}
+	if err := formats[RSS].exporter.(Validator).Validate(buffer.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateRSS(t *testing.T) {
+	for _, content := range []string{
+		`<rss version="2.0"><channel><title>a</title><item><enclosure url="a.mp3"></item></channel></rss>`,
+		`<rss version="2.0"><channel><title>a</title><item><title>b</title></item></channel></rss>`,
+		`<rss version="2.0"><channel><title>a</title><item><enclosure length="1"></enclosure></item></channel></rss>`,
+		`<rss version="2.0"><channel></channel></rss>`,
+		`<playlist xmlns="http://xspf.org/ns/0/"><trackList></trackList></playlist>`,
+	} {
+		if err := formats[RSS].exporter.(Validator).Validate([]byte(content)); err == nil {
+			t.Errorf("expected %q to be invalid", content)
+		}
+	}
+}
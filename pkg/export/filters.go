@@ -0,0 +1,227 @@
+package export
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+const (
+	// AUDIOBOOKS_EXCLUDE leaves the audiobooks out of the playlists.
+	AUDIOBOOKS_EXCLUDE = iota
+	// AUDIOBOOKS_INCLUDE exports audiobooks like music.
+	AUDIOBOOKS_INCLUDE
+	// AUDIOBOOKS_ONLY exports the audiobooks only and leaves the music out.
+	AUDIOBOOKS_ONLY
+)
+
+const (
+	// VIDEOS_EXCLUDE leaves the videos out of the playlists.
+	VIDEOS_EXCLUDE = iota
+	// VIDEOS_INCLUDE exports videos like music.
+	VIDEOS_INCLUDE
+	// VIDEOS_ONLY exports the videos only and leaves the music out.
+	VIDEOS_ONLY
+)
+
+// ParseVideos sets how the videos are exported with settings: EXCLUDE, INCLUDE or ONLY.
+func ParseVideos(settings *ExportSettings, mode string) error {
+	switch strings.ToUpper(mode) {
+	case "EXCLUDE":
+		settings.Videos = VIDEOS_EXCLUDE
+	case "INCLUDE":
+		settings.Videos = VIDEOS_INCLUDE
+	case "ONLY":
+		settings.Videos = VIDEOS_ONLY
+	default:
+		return errors.New("Unknown Videos Mode: " + mode)
+	}
+	return nil
+}
+
+// AudiobookCopyTemplate is the copy template of audiobooks: a folder for every book, with its
+// parts named by chapter so they sort in the order they are listened to.
+const AudiobookCopyTemplate = "{albumartist}/{album}/{chapter} {title}"
+
+// PodcastCopyTemplate is the copy template of podcast episodes: a folder for every show, with
+// its episodes named by the date they were published.
+const PodcastCopyTemplate = "{show}/{date} {title}"
+
+// ParseAudiobooks sets how the audiobooks are exported with settings: EXCLUDE, INCLUDE or ONLY.
+func ParseAudiobooks(settings *ExportSettings, mode string) error {
+	switch strings.ToUpper(mode) {
+	case "EXCLUDE":
+		settings.Audiobooks = AUDIOBOOKS_EXCLUDE
+	case "INCLUDE":
+		settings.Audiobooks = AUDIOBOOKS_INCLUDE
+	case "ONLY":
+		settings.Audiobooks = AUDIOBOOKS_ONLY
+	default:
+		return errors.New("Unknown Audiobooks Mode: " + mode)
+	}
+	return nil
+}
+
+// includeTrack reports whether track passes the track filters of exportSettings. Tracks
+// whose duration or size is not known pass the corresponding filters.
+func includeTrack(exportSettings *ExportSettings, track *itunes.Track) bool {
+	if exportSettings.Ignore.Ignores(*track) {
+		return false
+	}
+	if exportSettings.Podcasts && !track.PodcastEpisode() {
+		return false
+	}
+	switch exportSettings.Videos {
+	case VIDEOS_EXCLUDE:
+		if track.Video() {
+			return false
+		}
+	case VIDEOS_ONLY:
+		if !track.Video() {
+			return false
+		}
+	}
+	switch exportSettings.Audiobooks {
+	case AUDIOBOOKS_EXCLUDE:
+		if track.Audiobook() {
+			return false
+		}
+	case AUDIOBOOKS_ONLY:
+		if !track.Audiobook() {
+			return false
+		}
+	}
+	duration := time.Duration(track.TotalTime) * time.Millisecond
+	if exportSettings.MinDuration > 0 && track.TotalTime > 0 && duration < exportSettings.MinDuration {
+		return false
+	}
+	if exportSettings.MaxDuration > 0 && duration > exportSettings.MaxDuration {
+		return false
+	}
+	if exportSettings.MaxFileSize > 0 && int64(track.Size) > exportSettings.MaxFileSize {
+		return false
+	}
+	if exportSettings.MinBitrate > 0 && track.BitRate > 0 && track.BitRate < exportSettings.MinBitrate {
+		return false
+	}
+	if len(exportSettings.Codecs) > 0 && !containsCodec(exportSettings.Codecs, trackCodec(track)) {
+		return false
+	}
+	return true
+}
+
+// codecGroups are the names of groups of codecs accepted by ParseCodecs.
+var codecGroups = map[string][]string{
+	"lossy":    {"mp3", "aac"},
+	"lossless": {"alac", "flac", "wav", "aiff"},
+}
+
+// codecKinds are the codecs of the kinds of tracks iTunes shows, by a part of the kind.
+var codecKinds = []struct {
+	kind, codec string
+}{
+	{"mpeg audio", "mp3"},
+	{"aac audio", "aac"},
+	{"apple lossless", "alac"},
+	{"flac", "flac"},
+	{"wav audio", "wav"},
+	{"aiff audio", "aiff"},
+}
+
+// codecExtensions are the codecs of music files by their extension, for kinds not known.
+var codecExtensions = map[string]string{
+	".mp3":  "mp3",
+	".aac":  "aac",
+	".flac": "flac",
+	".wav":  "wav",
+	".aif":  "aiff",
+	".aiff": "aiff",
+}
+
+// ParseCodecs sets the codecs of settings from a comma separated list: mp3, aac, alac, flac,
+// wav, aiff, or lossy and lossless for several of them.
+func ParseCodecs(settings *ExportSettings, codecs string) error {
+	settings.Codecs = nil
+	for _, name := range strings.Split(codecs, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch {
+		case name == "":
+		case codecGroups[name] != nil:
+			settings.Codecs = append(settings.Codecs, codecGroups[name]...)
+		case containsCodec(codecGroups["lossy"], name) || containsCodec(codecGroups["lossless"], name):
+			settings.Codecs = append(settings.Codecs, name)
+		default:
+			return errors.New("Unknown Codec: " + name)
+		}
+	}
+	return nil
+}
+
+// trackCodec returns the codec of track from its kind, or the extension of its file if the
+// kind is not known, e.g. because iTunes runs in another language.
+func trackCodec(track *itunes.Track) string {
+	kind := strings.ToLower(track.Kind)
+	for _, codecKind := range codecKinds {
+		if strings.Contains(kind, codecKind.kind) {
+			return codecKind.codec
+		}
+	}
+	return codecExtensions[strings.ToLower(filepath.Ext(track.Location))]
+}
+
+func containsCodec(codecs []string, codec string) bool {
+	for _, c := range codecs {
+		if c == codec {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanVersionSuffixes are appended to the names of tracks to tell their clean and explicit
+// versions apart.
+var cleanVersionSuffixes = []string{"(clean)", "[clean]", "(clean version)", "(explicit)", "[explicit]", "(explicit version)"}
+
+// cleanVersionKey identifies the versions of a track by artist and name.
+func cleanVersionKey(track *itunes.Track) string {
+	name := strings.ToLower(strings.TrimSpace(track.Name))
+	for _, suffix := range cleanVersionSuffixes {
+		name = strings.TrimSpace(strings.TrimSuffix(name, suffix))
+	}
+	return strings.ToLower(track.Artist) + "\x00" + name
+}
+
+// cleanVersions returns the tracks of library marked clean by their cleanVersionKey.
+func cleanVersions(library *itunes.Library) map[string]itunes.Track {
+	versions := make(map[string]itunes.Track)
+	for _, track := range library.Tracks {
+		track := track
+		if track.Clean {
+			versions[cleanVersionKey(&track)] = track
+		}
+	}
+	return versions
+}
+
+// IncludesTrack reports whether track passes the track filters of exportSettings. With
+// CleanOnly set, explicit tracks are not included as their clean versions may be exported
+// in their place.
+func (exportSettings *ExportSettings) IncludesTrack(track itunes.Track) bool {
+	if exportSettings.CleanOnly && track.Explicit {
+		return false
+	}
+	return includeTrack(exportSettings, &track)
+}
+
+// cleanTrack returns track, or its clean version if track is explicit and CleanOnly is set.
+// It reports false for explicit tracks without a clean version in the library.
+func cleanTrack(exportSettings *ExportSettings, track itunes.Track) (itunes.Track, bool) {
+	if !exportSettings.CleanOnly || !track.Explicit {
+		return track, true
+	}
+	clean, ok := exportSettings.cleanVersions[cleanVersionKey(&track)]
+	return clean, ok
+}
@@ -0,0 +1,143 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+func TestIncludeTrack(t *testing.T) {
+	settings := &ExportSettings{MinDuration: 30 * time.Second, MaxDuration: 10 * time.Minute}
+	if err := ParseMaxFileSize(settings, "100MB"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		track    itunes.Track
+		expected bool
+	}{
+		{itunes.Track{TotalTime: 210000, Size: 5 << 20}, true},
+		{itunes.Track{TotalTime: 10000}, false},
+		{itunes.Track{TotalTime: 3600000}, false},
+		{itunes.Track{TotalTime: 300000, Size: 200 << 20}, false},
+		{itunes.Track{}, true},
+	} {
+		if included := includeTrack(settings, &test.track); included != test.expected {
+			t.Fatalf("expected %v for %+v, got %v", test.expected, test.track, included)
+		}
+	}
+
+	if err := ParseMaxFileSize(settings, "huge"); err == nil {
+		t.Fatal("expected error for unknown file size")
+	}
+}
+
+func TestExportLeavesOutFilteredTracks(t *testing.T) {
+	outputDir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(outputDir)
+
+	library := testLibrary()
+	library.Tracks["2"] = itunes.Track{TrackId: 2, Name: "DJ Mix", Artist: "Some DJ", TotalTime: 7200000, Location: "file:///music/mix.mp3"}
+	playlist := library.Playlists[0]
+	playlist.PlaylistItems = append(playlist.PlaylistItems, itunes.PlaylistItem{TrackId: 2})
+
+	settings := &ExportSettings{Library: library, Playlists: []itunes.Playlist{playlist}, OutputPath: outputDir, MaxDuration: 10 * time.Minute}
+	ParseExportType(settings, EXT)
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+	if content := readFile(t, filepath.Join(outputDir, "My Playlist.m3u")); strings.Contains(content, "DJ Mix") || !strings.Contains(content, "Some Song") {
+		t.Fatalf("expected the long mix to be left out, got %q", content)
+	}
+}
+
+func TestExportCleanOnly(t *testing.T) {
+	outputDir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(outputDir)
+
+	library := testLibrary()
+	library.Tracks["1"] = itunes.Track{TrackId: 1, Name: "Some Song", Artist: "Some Artist", Explicit: true, Location: "file:///music/explicit.mp3"}
+	library.Tracks["2"] = itunes.Track{TrackId: 2, Name: "Some Song (Clean)", Artist: "Some Artist", Clean: true, Location: "file:///music/clean.mp3"}
+	library.Tracks["3"] = itunes.Track{TrackId: 3, Name: "Other Song", Artist: "Some Artist", Explicit: true, Location: "file:///music/other.mp3"}
+	playlist := library.Playlists[0]
+	playlist.PlaylistItems = []itunes.PlaylistItem{{TrackId: 1}, {TrackId: 3}}
+
+	settings := &ExportSettings{Library: library, Playlists: []itunes.Playlist{playlist}, OutputPath: outputDir, CleanOnly: true}
+	ParseExportType(settings, M3U)
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+	content := readFile(t, filepath.Join(outputDir, "My Playlist.m3u"))
+	if !strings.Contains(content, "clean.mp3") || strings.Contains(content, "explicit.mp3") || strings.Contains(content, "other.mp3") {
+		t.Fatalf("expected only the clean version, got %q", content)
+	}
+}
+
+func TestCodecAndBitrateFilters(t *testing.T) {
+	settings := &ExportSettings{MinBitrate: 192}
+	if err := ParseCodecs(settings, "lossless, mp3"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		track    itunes.Track
+		expected bool
+	}{
+		{itunes.Track{Kind: "MPEG audio file", BitRate: 320}, true},
+		{itunes.Track{Kind: "MPEG audio file", BitRate: 128}, false},
+		{itunes.Track{Kind: "Purchased AAC audio file", BitRate: 256}, false},
+		{itunes.Track{Kind: "Apple Lossless audio file", BitRate: 1411}, true},
+		{itunes.Track{Kind: "Fichier audio FLAC", Location: "file:///music/song.flac"}, true},
+		{itunes.Track{Kind: "Fichier audio AAC", Location: "file:///music/song.m4a"}, false},
+	} {
+		if included := includeTrack(settings, &test.track); included != test.expected {
+			t.Fatalf("expected %v for %+v, got %v", test.expected, test.track, included)
+		}
+	}
+
+	if err := ParseCodecs(settings, "mp3,ogg"); err == nil {
+		t.Fatal("expected error for unknown codec")
+	}
+}
+
+func TestAudiobooksFilter(t *testing.T) {
+	music := itunes.Track{Kind: "MPEG audio file", Location: "file:///music/song.mp3"}
+	book := itunes.Track{Kind: "AAC audio file", Location: "file:///books/dune.m4b"}
+
+	settings := &ExportSettings{}
+	if !includeTrack(settings, &music) || includeTrack(settings, &book) {
+		t.Fatal("expected audiobooks to be left out by default")
+	}
+	if err := ParseAudiobooks(settings, "include"); err != nil || !includeTrack(settings, &music) || !includeTrack(settings, &book) {
+		t.Fatalf("expected audiobooks to be included: %v", err)
+	}
+	if err := ParseAudiobooks(settings, "ONLY"); err != nil || includeTrack(settings, &music) || !includeTrack(settings, &book) {
+		t.Fatalf("expected only audiobooks to be included: %v", err)
+	}
+	if err := ParseAudiobooks(settings, "some"); err == nil {
+		t.Fatal("expected error for unknown audiobooks mode")
+	}
+}
+
+func TestVideosFilter(t *testing.T) {
+	music := itunes.Track{Kind: "MPEG audio file", Location: "file:///music/song.mp3"}
+	video := itunes.Track{Kind: "MPEG-4 video file", HasVideo: true, Location: "file:///videos/clip.m4v"}
+
+	settings := &ExportSettings{}
+	if !includeTrack(settings, &music) || includeTrack(settings, &video) {
+		t.Fatal("expected videos to be left out by default")
+	}
+	if err := ParseVideos(settings, "include"); err != nil || !includeTrack(settings, &music) || !includeTrack(settings, &video) {
+		t.Fatalf("expected videos to be included: %v", err)
+	}
+	if err := ParseVideos(settings, "ONLY"); err != nil || includeTrack(settings, &music) || !includeTrack(settings, &video) {
+		t.Fatalf("expected only videos to be included: %v", err)
+	}
+	if err := ParseVideos(settings, "some"); err == nil {
+		t.Fatal("expected error for unknown videos mode")
+	}
+}
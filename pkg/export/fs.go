@@ -0,0 +1,416 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FS is a writable file system an export is written to. Names are slash separated and
+// relative to the root of the file system, e.g. "My Playlist/song.mp3".
+type FS interface {
+	// Create creates or replaces the named file. Parent directories are created as needed.
+	Create(name string) (File, error)
+	// Open opens the named file for reading.
+	Open(name string) (io.ReadCloser, error)
+	Stat(name string) (os.FileInfo, error)
+	MkdirAll(name string) error
+	Remove(name string) error
+}
+
+// File is a file being written to an FS. Where the file system supports it, the file
+// only replaces an existing file of the same name once Close succeeds.
+type File interface {
+	io.Writer
+	// Close completes the file.
+	Close() error
+	// Abort discards the file, keeping any previous file of the same name.
+	Abort() error
+}
+
+// Locker is implemented by file systems that can be locked against concurrent exports.
+type Locker interface {
+	Lock() (unlock func() error, err error)
+}
+
+// DirFS writes to a directory of the local file system.
+type DirFS struct {
+	root string
+}
+
+// NewDirFS returns an FS writing to the directory root.
+func NewDirFS(root string) *DirFS {
+	if root == "" {
+		root = "."
+	}
+	return &DirFS{root: root}
+}
+
+func (fsys *DirFS) path(name string) string {
+	return filepath.Join(fsys.root, filepath.FromSlash(name))
+}
+
+// Create writes to a temporary file in the same directory which is renamed into place
+// on Close, so an interrupted export never leaves a partially written file behind.
+func (fsys *DirFS) Create(name string) (File, error) {
+	fileName := fsys.path(name)
+	if err := os.MkdirAll(filepath.Dir(fileName), 0777); err != nil {
+		return nil, err
+	}
+	file, err := ioutil.TempFile(filepath.Dir(fileName), "."+filepath.Base(fileName)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	return &dirFile{File: file, name: fileName}, nil
+}
+
+func (fsys *DirFS) Open(name string) (io.ReadCloser, error) {
+	return os.Open(fsys.path(name))
+}
+
+func (fsys *DirFS) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(fsys.path(name))
+}
+
+func (fsys *DirFS) MkdirAll(name string) error {
+	return os.MkdirAll(fsys.path(name), 0777)
+}
+
+func (fsys *DirFS) Remove(name string) error {
+	return os.Remove(fsys.path(name))
+}
+
+func (fsys *DirFS) Symlink(target, name string) error {
+	linkName := fsys.path(name)
+	if err := os.MkdirAll(filepath.Dir(linkName), 0777); err != nil {
+		return err
+	}
+	if existing, err := os.Readlink(linkName); err == nil && existing == filepath.FromSlash(target) {
+		return nil
+	}
+	if err := os.Remove(linkName); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Symlink(filepath.FromSlash(target), linkName)
+}
+
+// Lock locks the directory with a lock file.
+func (fsys *DirFS) Lock() (func() error, error) {
+	lock, err := lockOutput(fsys.root)
+	if err != nil {
+		return nil, err
+	}
+	return lock.unlock, nil
+}
+
+type dirFile struct {
+	*os.File
+	name string
+}
+
+func (file *dirFile) Close() error {
+	err := file.File.Sync()
+	if closeErr := file.File.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(file.File.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(file.File.Name(), file.name)
+	}
+	if err != nil {
+		os.Remove(file.File.Name())
+	}
+	return err
+}
+
+func (file *dirFile) Abort() error {
+	file.File.Close()
+	return os.Remove(file.File.Name())
+}
+
+// MemFS is an FS keeping all files in memory, e.g. for tests.
+type MemFS struct {
+	mutex sync.Mutex
+	files map[string]*memFileInfo
+}
+
+// NewMemFS returns an empty in-memory file system.
+func NewMemFS() *MemFS {
+	return &MemFS{files: make(map[string]*memFileInfo)}
+}
+
+type memFileInfo struct {
+	name    string
+	data    []byte
+	modTime time.Time
+	dir     bool
+}
+
+func (info *memFileInfo) Name() string       { return path.Base(info.name) }
+func (info *memFileInfo) Size() int64        { return int64(len(info.data)) }
+func (info *memFileInfo) ModTime() time.Time { return info.modTime }
+func (info *memFileInfo) IsDir() bool        { return info.dir }
+func (info *memFileInfo) Sys() interface{}   { return nil }
+func (info *memFileInfo) Mode() os.FileMode {
+	if info.dir {
+		return os.ModeDir | 0777
+	}
+	return 0644
+}
+
+func (fsys *MemFS) Create(name string) (File, error) {
+	return &memFile{fsys: fsys, name: path.Clean(name)}, nil
+}
+
+func (fsys *MemFS) Open(name string) (io.ReadCloser, error) {
+	fsys.mutex.Lock()
+	defer fsys.mutex.Unlock()
+
+	info, ok := fsys.files[path.Clean(name)]
+	if !ok || info.dir {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	return ioutil.NopCloser(bytes.NewReader(info.data)), nil
+}
+
+func (fsys *MemFS) Stat(name string) (os.FileInfo, error) {
+	fsys.mutex.Lock()
+	defer fsys.mutex.Unlock()
+
+	info, ok := fsys.files[path.Clean(name)]
+	if !ok {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+	}
+	infoCopy := *info
+	return &infoCopy, nil
+}
+
+func (fsys *MemFS) MkdirAll(name string) error {
+	fsys.mutex.Lock()
+	defer fsys.mutex.Unlock()
+	fsys.mkdirAll(path.Clean(name))
+	return nil
+}
+
+func (fsys *MemFS) mkdirAll(name string) {
+	for name != "." && name != "/" {
+		if _, ok := fsys.files[name]; !ok {
+			fsys.files[name] = &memFileInfo{name: name, modTime: time.Now(), dir: true}
+		}
+		name = path.Dir(name)
+	}
+}
+
+func (fsys *MemFS) Remove(name string) error {
+	fsys.mutex.Lock()
+	defer fsys.mutex.Unlock()
+
+	name = path.Clean(name)
+	if _, ok := fsys.files[name]; !ok {
+		return &os.PathError{Op: "remove", Path: name, Err: os.ErrNotExist}
+	}
+	delete(fsys.files, name)
+	return nil
+}
+
+// Files returns the names of all files, excluding directories, in sorted order.
+func (fsys *MemFS) Files() []string {
+	fsys.mutex.Lock()
+	defer fsys.mutex.Unlock()
+
+	var names []string
+	for name, info := range fsys.files {
+		if !info.dir {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ReadFile returns the content of the named file.
+func (fsys *MemFS) ReadFile(name string) ([]byte, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ioutil.ReadAll(file)
+}
+
+type memFile struct {
+	bytes.Buffer
+	fsys *MemFS
+	name string
+}
+
+func (file *memFile) Close() error {
+	file.fsys.mutex.Lock()
+	defer file.fsys.mutex.Unlock()
+
+	file.fsys.mkdirAll(path.Dir(file.name))
+	file.fsys.files[file.name] = &memFileInfo{name: file.name, data: file.Bytes(), modTime: time.Now()}
+	return nil
+}
+
+func (file *memFile) Abort() error {
+	return nil
+}
+
+// ZipFS writes the export into a zip archive. Files are written to the archive one at a
+// time; a file that is aborted can not be removed from the archive again.
+type ZipFS struct {
+	writer *zip.Writer
+
+	// fileMutex is held while a file is being written.
+	fileMutex sync.Mutex
+
+	mutex sync.Mutex
+	files map[string]*memFileInfo
+}
+
+// NewZipFS returns an FS writing a zip archive to w. Close must be called once the
+// export is complete.
+func NewZipFS(w io.Writer) *ZipFS {
+	return &ZipFS{writer: zip.NewWriter(w), files: make(map[string]*memFileInfo)}
+}
+
+func (fsys *ZipFS) Create(name string) (File, error) {
+	name = strings.TrimPrefix(path.Clean(name), "/")
+
+	fsys.fileMutex.Lock()
+	w, err := fsys.writer.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		fsys.fileMutex.Unlock()
+		return nil, err
+	}
+	return &zipFile{Writer: w, fsys: fsys, name: name}, nil
+}
+
+func (fsys *ZipFS) Open(name string) (io.ReadCloser, error) {
+	return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+}
+
+func (fsys *ZipFS) Stat(name string) (os.FileInfo, error) {
+	fsys.mutex.Lock()
+	defer fsys.mutex.Unlock()
+
+	info, ok := fsys.files[strings.TrimPrefix(path.Clean(name), "/")]
+	if !ok {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+	}
+	infoCopy := *info
+	return &infoCopy, nil
+}
+
+// MkdirAll does nothing, directories are implied by the names of the files in the archive.
+func (fsys *ZipFS) MkdirAll(name string) error {
+	return nil
+}
+
+func (fsys *ZipFS) Remove(name string) error {
+	return errors.New("can not remove files from a zip archive")
+}
+
+// Close writes the central directory of the archive. It does not close the underlying writer.
+func (fsys *ZipFS) Close() error {
+	return fsys.writer.Close()
+}
+
+type zipFile struct {
+	io.Writer
+	fsys *ZipFS
+	name string
+}
+
+func (file *zipFile) Close() error {
+	file.fsys.mutex.Lock()
+	file.fsys.files[file.name] = &memFileInfo{name: file.name, modTime: time.Now()}
+	file.fsys.mutex.Unlock()
+
+	file.fsys.fileMutex.Unlock()
+	return nil
+}
+
+func (file *zipFile) Abort() error {
+	file.fsys.fileMutex.Unlock()
+	return nil
+}
+
+// WriterFS writes the files of an export one after the other to a writer, e.g. a single
+// playlist to standard output. Files can not be read back, so music can not be copied to it.
+type WriterFS struct {
+	mutex sync.Mutex
+	w     io.Writer
+}
+
+// NewWriterFS returns an FS writing the content of all files created to w.
+func NewWriterFS(w io.Writer) *WriterFS {
+	return &WriterFS{w: w}
+}
+
+func (fsys *WriterFS) Create(name string) (File, error) {
+	return &writerFile{fsys: fsys}, nil
+}
+
+func (fsys *WriterFS) Open(name string) (io.ReadCloser, error) {
+	return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+}
+
+func (fsys *WriterFS) Stat(name string) (os.FileInfo, error) {
+	return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+}
+
+// MkdirAll does nothing, the writer has no directories.
+func (fsys *WriterFS) MkdirAll(name string) error {
+	return nil
+}
+
+// Remove does nothing, files written can not be taken back.
+func (fsys *WriterFS) Remove(name string) error {
+	return nil
+}
+
+// writerFile keeps the content of a file until it is complete, so files written
+// concurrently are not mixed up.
+type writerFile struct {
+	bytes.Buffer
+	fsys *WriterFS
+}
+
+func (file *writerFile) Close() error {
+	file.fsys.mutex.Lock()
+	defer file.fsys.mutex.Unlock()
+	_, err := file.fsys.w.Write(file.Bytes())
+	return err
+}
+
+func (file *writerFile) Abort() error {
+	return nil
+}
+
+// remoteFileInfo describes a file on a server.
+type remoteFileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func (info *remoteFileInfo) Name() string       { return info.name }
+func (info *remoteFileInfo) Size() int64        { return info.size }
+func (info *remoteFileInfo) Mode() os.FileMode  { return info.mode }
+func (info *remoteFileInfo) ModTime() time.Time { return info.modTime }
+func (info *remoteFileInfo) IsDir() bool        { return info.mode.IsDir() }
+func (info *remoteFileInfo) Sys() interface{}   { return nil }
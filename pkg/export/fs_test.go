@@ -0,0 +1,113 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDirFSKeepsOldFileOnAbort(t *testing.T) {
+	dir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(dir)
+
+	fsys := NewDirFS(dir)
+	writeLocalFile(t, filepath.Join(dir, "My Playlist.m3u"), "old")
+
+	err := writeFile(fsys, "My Playlist.m3u", func(w io.Writer) error {
+		io.WriteString(w, "half written")
+		return errors.New("failed")
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	if content := readFile(t, filepath.Join(dir, "My Playlist.m3u")); content != "old" {
+		t.Fatalf("expected old content, got %q", content)
+	}
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 1 {
+		t.Fatalf("expected temporary file to be removed, found %v files", len(files))
+	}
+
+	err = writeFile(fsys, "Folder/My Playlist.m3u", func(w io.Writer) error {
+		_, err := io.WriteString(w, "new")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content := readFile(t, filepath.Join(dir, "Folder", "My Playlist.m3u")); content != "new" {
+		t.Fatalf("expected new content, got %q", content)
+	}
+}
+
+func TestExportToMemFS(t *testing.T) {
+	fsys := NewMemFS()
+	library := testLibrary()
+	settings := &ExportSettings{Library: library, Playlists: library.Playlists, Output: fsys}
+	ParseExportType(settings, "EXT")
+
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := fsys.ReadFile("My Playlist.m3u")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "#EXTM3U\n#EXTINF:210,Some Artist - Some Song\n\n" {
+		t.Fatalf("unexpected playlist %q", content)
+	}
+}
+
+func TestExportToWriterFS(t *testing.T) {
+	var out bytes.Buffer
+	library := testLibrary()
+	settings := &ExportSettings{Library: library, Playlists: library.Playlists, Output: NewWriterFS(&out)}
+	ParseExportType(settings, "EXT")
+
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "#EXTM3U\n#EXTINF:210,Some Artist - Some Song\n\n" {
+		t.Fatalf("unexpected playlist %q", out.String())
+	}
+}
+
+func TestExportToZipFS(t *testing.T) {
+	musicFile, musicFileName := prepareMusicFile(t)
+	defer os.Remove(musicFile)
+
+	library := testLibrary()
+	track := library.Tracks["1"]
+	track.Location = "file://" + filepath.ToSlash(musicFile)
+	library.Tracks["1"] = track
+
+	var archive bytes.Buffer
+	fsys := NewZipFS(&archive)
+	settings := &ExportSettings{Library: library, Playlists: library.Playlists, Output: fsys, CopyType: COPY_PLAYLIST}
+	ParseExportType(settings, "M3U")
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsys.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(archive.Bytes()), int64(archive.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]bool{}
+	for _, file := range reader.File {
+		names[file.Name] = true
+	}
+	if !names["My Playlist.m3u"] || !names["My Playlist/"+musicFileName] || len(names) != 2 {
+		t.Fatalf("unexpected archive content %v", names)
+	}
+}
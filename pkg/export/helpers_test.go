@@ -0,0 +1,80 @@
+package export
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+const FileContent string = "42"
+
+func testLibrary() *itunes.Library {
+	library := &itunes.Library{
+		Tracks: map[string]itunes.Track{
+			"1": {TrackId: 1, Name: "Some Song", Artist: "Some Artist", TotalTime: 210000},
+		},
+		Playlists: []itunes.Playlist{
+			{Name: "My Playlist", PlaylistPersistentId: "BA9D3C2EAB361B84", PlaylistItems: []itunes.PlaylistItem{{TrackId: 1}}},
+		},
+	}
+	library.PlaylistMap = map[string]itunes.Playlist{"My Playlist": library.Playlists[0]}
+	library.PlaylistIdMap = map[string]itunes.Playlist{"BA9D3C2EAB361B84": library.Playlists[0]}
+	return library
+}
+
+func assertPathExists(t *testing.T, path string) {
+	_, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("File '%s' does not exist: %v", path, err)
+	}
+}
+
+func createTempDir(t *testing.T, pattern string) string {
+	tmpDirPath, err := ioutil.TempDir("", pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tmpDirPath
+}
+
+func writeLocalFile(t *testing.T, filePath string, content string) {
+	err := ioutil.WriteFile(filePath, []byte(content), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write '%s' to file '%s': %v", content, filePath, err)
+	}
+}
+
+func readFile(t *testing.T, filePath string) string {
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("Failed to read file '%s': %v", filePath, err)
+	}
+	return string(content)
+}
+
+func prepareMusicFile(t *testing.T) (string, string) {
+	tmpFile, err := ioutil.TempFile("", "Some_Song_*.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpFile.Close()
+	writeLocalFile(t, tmpFile.Name(), FileContent)
+	return tmpFile.Name(), filepath.Base(tmpFile.Name())
+}
+
+func assertPlaylistFileCorrectlyWritten(t *testing.T, playlistPath string, singleLineContent string) {
+	assertPathExists(t, playlistPath)
+
+	playlistFileContents := readFile(t, playlistPath)
+	re := regexp.MustCompile("\r?\n" + regexp.QuoteMeta(singleLineContent) + "\r?\n")
+	matches := re.FindAllString(playlistFileContents, -1)
+
+	if len(matches) != 1 {
+		t.Errorf("Expected playlist to contain '%s' exactly once, but found %d", singleLineContent, len(matches))
+	}
+}
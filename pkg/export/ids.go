@@ -0,0 +1,106 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+// IdsFileName is the file in the output mapping the persistent ids of the playlists and
+// tracks of an export with ExportSettings.Ids set to the files they were exported to, so
+// other tools can find the iTunes entries of the files.
+const IdsFileName = "itunes-ids.json"
+
+// idManifest is the content of the IdsFileName file.
+type idManifest struct {
+	Playlists []idEntry `json:"playlists"`
+	Tracks    []idEntry `json:"tracks"`
+}
+
+// idEntry maps the persistent id of a playlist or track to a file of the export. For
+// exports without copies, the files of tracks are their locations in the library.
+type idEntry struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+	File string `json:"file"`
+}
+
+// ids collects the persistent ids of the files of an export.
+type ids struct {
+	mutex     sync.Mutex
+	fsys      FS
+	playlists map[string]idEntry
+	tracks    map[string]idEntry
+	previous  idManifest
+}
+
+// loadIds reads the ids of the previous export from fsys, which stay listed for files that
+// still exist, e.g. those of skipped playlists.
+func loadIds(fsys FS) *ids {
+	ids := &ids{fsys: fsys, playlists: make(map[string]idEntry), tracks: make(map[string]idEntry)}
+
+	file, err := fsys.Open(IdsFileName)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			Log(LogEntry{Level: LOG_WARNING, Message: fmt.Sprintf("Unable to read ids %v: %v\n", IdsFileName, err), Path: IdsFileName, Error: err.Error()})
+		}
+		return ids
+	}
+	defer file.Close()
+	if err := json.NewDecoder(file).Decode(&ids.previous); err != nil {
+		Log(LogEntry{Level: LOG_WARNING, Message: fmt.Sprintf("Unable to read ids %v: %v\n", IdsFileName, err), Path: IdsFileName, Error: err.Error()})
+	}
+	return ids
+}
+
+func (ids *ids) addPlaylist(playlist *itunes.Playlist, name string) {
+	ids.mutex.Lock()
+	defer ids.mutex.Unlock()
+	ids.playlists[name] = idEntry{Id: playlist.PlaylistPersistentId, Name: playlist.Name, File: name}
+}
+
+func (ids *ids) addTrack(track *itunes.Track, name string) {
+	ids.mutex.Lock()
+	defer ids.mutex.Unlock()
+	ids.tracks[name] = idEntry{Id: track.PersistentId, Name: track.Title(), File: name}
+}
+
+// save writes the ids of the export, sorted by file.
+func (ids *ids) save() error {
+	ids.mutex.Lock()
+	defer ids.mutex.Unlock()
+
+	manifest := idManifest{
+		Playlists: ids.entries(ids.playlists, ids.previous.Playlists),
+		Tracks:    ids.entries(ids.tracks, ids.previous.Tracks),
+	}
+	return writeFile(ids.fsys, IdsFileName, func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(manifest)
+	})
+}
+
+// entries returns the entries of the export followed by the previous entries whose files
+// still exist, sorted by file.
+func (ids *ids) entries(current map[string]idEntry, previous []idEntry) []idEntry {
+	entries := []idEntry{}
+	for _, entry := range current {
+		entries = append(entries, entry)
+	}
+	for _, entry := range previous {
+		if _, ok := current[entry.File]; ok {
+			continue
+		}
+		if _, err := ids.fsys.Stat(entry.File); err == nil {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].File < entries[j].File })
+	return entries
+}
@@ -0,0 +1,54 @@
+package export
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExportWritesIds(t *testing.T) {
+	outputDir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(outputDir)
+
+	musicFile, musicFileName := prepareMusicFile(t)
+	defer os.Remove(musicFile)
+
+	library := testLibrary()
+	track := library.Tracks["1"]
+	track.PersistentId = "5E8AFBB3C16B2D0F"
+	track.Location = "file://" + filepath.ToSlash(musicFile)
+	library.Tracks["1"] = track
+
+	settings := &ExportSettings{Library: library, Playlists: library.Playlists, CopyType: COPY_FLAT, OutputPath: outputDir, Ids: true}
+	ParseExportType(settings, M3U)
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+
+	var manifest idManifest
+	if err := json.Unmarshal([]byte(readFile(t, filepath.Join(outputDir, IdsFileName))), &manifest); err != nil {
+		t.Fatal(err)
+	}
+	expectedPlaylist := idEntry{Id: "BA9D3C2EAB361B84", Name: "My Playlist", File: "My Playlist.m3u"}
+	if len(manifest.Playlists) != 1 || manifest.Playlists[0] != expectedPlaylist {
+		t.Fatalf("unexpected playlists %v", manifest.Playlists)
+	}
+	expectedTrack := idEntry{Id: "5E8AFBB3C16B2D0F", Name: "Some Song", File: musicFileName}
+	if len(manifest.Tracks) != 1 || manifest.Tracks[0] != expectedTrack {
+		t.Fatalf("unexpected tracks %v", manifest.Tracks)
+	}
+
+	// Files of playlists skipped by the next export stay listed.
+	settings.Playlists = nil
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+	manifest = idManifest{}
+	if err := json.Unmarshal([]byte(readFile(t, filepath.Join(outputDir, IdsFileName))), &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Playlists) != 1 || len(manifest.Tracks) != 1 {
+		t.Fatalf("expected the previous ids to stay listed, got %v", manifest)
+	}
+}
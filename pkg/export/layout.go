@@ -0,0 +1,177 @@
+package export
+
+import (
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+// folderLayout moves the copied music files into subfolders, so no folder of the output has
+// more than MaxFilesPerDir entries. The entries of a folder with too many are split in name
+// order into subfolders named by the range of their names, e.g. "A-M" and "N-Z".
+type folderLayout struct {
+	// moved are the paths of the music files in the output by the path they are copied to
+	// without the layout.
+	moved map[string]string
+}
+
+// path returns the path in the output the music file copied to dest is moved to.
+func (layout *folderLayout) path(dest string) string {
+	if moved, ok := layout.moved[dest]; ok {
+		return moved
+	}
+	return dest
+}
+
+// layoutNode is a folder or file of the output planned by planFolderLayout.
+type layoutNode struct {
+	// children are the entries of a folder by name. They are nil for files.
+	children map[string]*layoutNode
+}
+
+// planFolderLayout returns the layout of the music files copied for the playlists exported
+// with exportSettings. It returns nil unless MaxFilesPerDir is set.
+func planFolderLayout(exportSettings *ExportSettings) (*folderLayout, error) {
+	if exportSettings.MaxFilesPerDir <= 0 {
+		return nil, nil
+	}
+
+	root := &layoutNode{children: make(map[string]*layoutNode)}
+	for _, playlist := range exportSettings.Playlists {
+		if playlist.Container() {
+			continue
+		}
+		playlist := playlist
+		settings, err := exportSettings.forPlaylist(playlist.Name)
+		if err != nil {
+			return nil, err
+		}
+		if settings.CopyType == COPY_NONE || settings.CopyType == COPY_STORE {
+			continue
+		}
+		for _, track := range playlist.Tracks(settings.Library) {
+			track, clean := cleanTrack(settings, track)
+			if !clean || !includeTrack(settings, &track) {
+				continue
+			}
+			dest, err := copyDestination(settings.Library, settings, &playlist, &track, musicSource(settings, itunes.LocationPath(track.Location)))
+			if err != nil {
+				return nil, err
+			}
+			node := root
+			for _, name := range strings.Split(dest, "/") {
+				if node.children == nil {
+					node.children = make(map[string]*layoutNode)
+				}
+				if node.children[name] == nil {
+					node.children[name] = &layoutNode{}
+				}
+				node = node.children[name]
+			}
+		}
+	}
+
+	layout := &folderLayout{moved: make(map[string]string)}
+	layout.plan(root, "", "", exportSettings.MaxFilesPerDir)
+	return layout, nil
+}
+
+// plan records where the files below node, the folder at oldPath without the layout, are
+// moved to, with node moved to newPath.
+func (layout *folderLayout) plan(node *layoutNode, oldPath, newPath string, max int) {
+	var names []string
+	for name := range node.children {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return strings.ToLower(names[i]) < strings.ToLower(names[j]) })
+
+	buckets := layoutBuckets(names, max)
+	for _, name := range names {
+		oldChild := path.Join(oldPath, name)
+		newChild := path.Join(newPath, path.Join(buckets[name]...), name)
+		if child := node.children[name]; child.children != nil {
+			layout.plan(child, oldChild, newChild, max)
+		} else if newChild != oldChild {
+			layout.moved[oldChild] = newChild
+		}
+	}
+}
+
+// layoutBuckets splits the names of the entries of a folder, in order, into evenly sized
+// subfolders of at most max entries and returns the subfolders of each name. Subfolders
+// themselves are split again if there are more than max of them. It returns nil if there
+// are at most max names.
+func layoutBuckets(names []string, max int) map[string][]string {
+	if len(names) <= max {
+		return nil
+	}
+	count := (len(names) + max - 1) / max
+	size := (len(names) + count - 1) / count
+	var groups [][]string
+	for start := 0; start < len(names); start += size {
+		end := start + size
+		if end > len(names) {
+			end = len(names)
+		}
+		groups = append(groups, names[start:end])
+	}
+
+	labels := bucketLabels(groups)
+	outer := layoutBuckets(labels, max)
+	buckets := make(map[string][]string)
+	for i, group := range groups {
+		for _, name := range group {
+			buckets[name] = append(append([]string{}, outer[labels[i]]...), labels[i])
+		}
+	}
+	return buckets
+}
+
+// bucketLabels returns the names of the subfolders of the groups of names, the range of the
+// first letters of their names, e.g. "A-M". More letters are used until the names are
+// distinct, e.g. "MA-MI" and "MO-MY".
+func bucketLabels(groups [][]string) []string {
+	labels := make([]string, len(groups))
+	for letters := 1; letters <= 8; letters++ {
+		seen := make(map[string]bool)
+		distinct := true
+		for i, group := range groups {
+			labels[i] = bucketLabel(group[0], group[len(group)-1], letters)
+			distinct = distinct && !seen[labels[i]]
+			seen[labels[i]] = true
+		}
+		if distinct {
+			return labels
+		}
+	}
+	for i := range labels {
+		labels[i] += " " + strconv.Itoa(i+1)
+	}
+	return labels
+}
+
+// bucketLabel returns the name of the subfolder with the names from first to last, the first
+// letters of both, e.g. "A-M", or once if they are the same.
+func bucketLabel(first, last string, letters int) string {
+	from, to := namePrefix(first, letters), namePrefix(last, letters)
+	if from == to {
+		return from
+	}
+	return from + "-" + to
+}
+
+// namePrefix returns the first letters of name in upper case.
+func namePrefix(name string, letters int) string {
+	runes := []rune(strings.ToUpper(name))
+	if len(runes) > letters {
+		runes = runes[:letters]
+	}
+	prefix := strings.TrimRight(string(runes), ". ")
+	if prefix == "" {
+		return "_"
+	}
+	return prefix
+}
@@ -0,0 +1,225 @@
+package export
+
+import (
+	"fmt"
+	"hash/crc32"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+const (
+	// PATH_STYLE_ABSOLUTE writes copied music files with their path in the output,
+	// prefixed with OutputPath.
+	PATH_STYLE_ABSOLUTE = iota
+	// PATH_STYLE_RELATIVE writes copied music files relative to the playlist file.
+	PATH_STYLE_RELATIVE
+	// PATH_STYLE_RELATIVE_BACKSLASH is like PATH_STYLE_RELATIVE, but separates directories
+	// with backslashes as many car stereos expect.
+	PATH_STYLE_RELATIVE_BACKSLASH
+)
+
+const (
+	// ENCODING_UTF8 writes the playlist files in UTF-8 without byte order mark.
+	ENCODING_UTF8 = iota
+	// ENCODING_UTF8_BOM starts the playlist files with a byte order mark.
+	ENCODING_UTF8_BOM
+	// ENCODING_CP1252 writes the playlist files in the Windows-1252 code page. Characters it
+	// cannot represent are written without accents or replaced by a question mark.
+	ENCODING_CP1252
+)
+
+const (
+	// NAME_CASE_KEEP keeps the case of the names of the copied files and the playlist files.
+	NAME_CASE_KEEP = iota
+	// NAME_CASE_LOWER writes the names in lower case.
+	NAME_CASE_LOWER
+	// NAME_CASE_UPPER writes the names in upper case.
+	NAME_CASE_UPPER
+)
+
+// fatIllegalChars are the characters not allowed in names on FAT file systems.
+var fatIllegalChars = regexp.MustCompile(`[\x00-\x1f"*:<>?\\|]`)
+
+// newReplacer returns the replacer of the replacements, longer texts first, or nil if there
+// are none.
+func newReplacer(replacements map[string]string) *strings.Replacer {
+	if len(replacements) == 0 {
+		return nil
+	}
+	var texts []string
+	for text := range replacements {
+		texts = append(texts, text)
+	}
+	sort.Slice(texts, func(i, j int) bool {
+		if len(texts[i]) != len(texts[j]) {
+			return len(texts[i]) > len(texts[j])
+		}
+		return texts[i] < texts[j]
+	})
+	var pairs []string
+	for _, text := range texts {
+		pairs = append(pairs, text, replacements[text])
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+// replace applies the Replacements of exportSettings to name, the name of a file or folder of
+// the output.
+func (exportSettings *ExportSettings) replace(name string) string {
+	if exportSettings.replacer == nil {
+		return name
+	}
+	return exportSettings.replacer.Replace(name)
+}
+
+// safeName returns the name of the files and folders of playlist, with the Replacements of
+// exportSettings applied.
+func (exportSettings *ExportSettings) safeName(playlist itunes.Playlist) string {
+	playlist.Name = exportSettings.replace(playlist.Name)
+	return playlist.SafeName()
+}
+
+// limitPath applies the file name and folder depth limits of exportSettings to name, a path
+// of the output.
+func limitPath(exportSettings *ExportSettings, name string) string {
+	if exportSettings.MaxFilenameLength <= 0 && exportSettings.MaxFolderDepth <= 0 && exportSettings.Encoding != ENCODING_CP1252 && !exportSettings.StripSymbols &&
+		exportSettings.NameCase == NAME_CASE_KEEP {
+		return exportSettings.shorten(name)
+	}
+
+	parts := strings.Split(name, "/")
+	if max := exportSettings.MaxFolderDepth; max > 0 && len(parts)-1 > max {
+		dirs := parts[:len(parts)-1]
+		merged := strings.Join(dirs[max-1:], " - ")
+		parts = append(append(dirs[:max-1:max-1], merged), parts[len(parts)-1])
+	}
+	for i, part := range parts {
+		parts[i] = limitName(exportSettings, part, i == len(parts)-1)
+	}
+	return exportSettings.shorten(strings.Join(parts, "/"))
+}
+
+// limitName returns name with the characters the output cannot store replaced and shortened
+// to the maximum length. The extension of files is kept. Shortened names end with a checksum
+// of the full name, so names with the same beginning stay distinct.
+func limitName(exportSettings *ExportSettings, name string, file bool) string {
+	if exportSettings.StripSymbols {
+		if name = stripSymbols(name); name == "" {
+			name = "_"
+		}
+	}
+	switch exportSettings.NameCase {
+	case NAME_CASE_LOWER:
+		name = strings.ToLower(name)
+	case NAME_CASE_UPPER:
+		name = strings.ToUpper(name)
+	}
+	if exportSettings.Encoding == ENCODING_CP1252 {
+		name = cp1252Safe(name, '_')
+	}
+
+	max := exportSettings.MaxFilenameLength
+	if max <= 0 {
+		return name
+	}
+	name = fatIllegalChars.ReplaceAllString(name, "_")
+	name = strings.TrimRight(name, ". ")
+	if name == "" {
+		name = "_"
+	}
+	if utf8.RuneCountInString(name) <= max {
+		return name
+	}
+
+	ext := ""
+	if file {
+		ext = path.Ext(name)
+	}
+	base := []rune(strings.TrimSuffix(name, ext))
+	suffix := fmt.Sprintf("~%04x", crc32.ChecksumIEEE([]byte(name))&0xffff)
+	keep := max - len(suffix) - utf8.RuneCountInString(ext)
+	if keep < 1 {
+		keep = 1
+	}
+	if keep > len(base) {
+		keep = len(base)
+	}
+	return strings.TrimRight(string(base[:keep]), ". ") + suffix + ext
+}
+
+// stripSymbols returns s without emoji, other symbols, zero-width and control characters and
+// code points not assigned to a character, which some players fail to display. Runs of spaces
+// left behind are joined.
+func stripSymbols(s string) string {
+	var stripped strings.Builder
+	for _, r := range s {
+		switch {
+		case unicode.In(r, unicode.Cc, unicode.Cf, unicode.Co, unicode.So, unicode.Me, unicode.Variation_Selector):
+		case r >= 0x1f3fb && r <= 0x1f3ff:
+			// Skin tone modifiers of emoji.
+		case !unicode.In(r, unicode.L, unicode.M, unicode.N, unicode.P, unicode.S, unicode.Zs):
+		default:
+			stripped.WriteRune(r)
+		}
+	}
+	return strings.Join(strings.Fields(stripped.String()), " ")
+}
+
+// encodePlaylist converts content, a playlist written in UTF-8, to encoding.
+func encodePlaylist(content []byte, encoding int) []byte {
+	switch encoding {
+	case ENCODING_UTF8_BOM:
+		return append([]byte("\xef\xbb\xbf"), content...)
+	case ENCODING_CP1252:
+		encoded := make([]byte, 0, len(content))
+		for _, r := range cp1252Safe(string(content), '?') {
+			b, _ := cp1252Byte(r)
+			encoded = append(encoded, b)
+		}
+		return encoded
+	default:
+		return content
+	}
+}
+
+// cp1252Safe returns s with the characters the Windows-1252 code page cannot represent
+// written without accents or, if that does not help, replaced by replacement.
+func cp1252Safe(s string, replacement rune) string {
+	var safe strings.Builder
+	for _, r := range s {
+		if _, ok := cp1252Byte(r); ok {
+			safe.WriteRune(r)
+			continue
+		}
+		for _, r := range itunes.FoldAccents(string(r)) {
+			if _, ok := cp1252Byte(r); !ok {
+				r = replacement
+			}
+			safe.WriteRune(r)
+		}
+	}
+	return safe.String()
+}
+
+// cp1252Specials are the characters of the Windows-1252 code page at 0x80 to 0x9F.
+var cp1252Specials = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87, 'ˆ': 0x88,
+	'‰': 0x89, 'Š': 0x8a, '‹': 0x8b, 'Œ': 0x8c, 'Ž': 0x8e, '‘': 0x91, '’': 0x92, '“': 0x93,
+	'”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '˜': 0x98, '™': 0x99, 'š': 0x9a, '›': 0x9b,
+	'œ': 0x9c, 'ž': 0x9e, 'Ÿ': 0x9f,
+}
+
+// cp1252Byte returns the Windows-1252 code of r, if it has one.
+func cp1252Byte(r rune) (byte, bool) {
+	if r < 0x80 || (r >= 0xa0 && r <= 0xff) {
+		return byte(r), true
+	}
+	b, ok := cp1252Specials[r]
+	return b, ok
+}
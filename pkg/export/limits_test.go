@@ -0,0 +1,181 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+func TestLimitPath(t *testing.T) {
+	settings := &ExportSettings{MaxFilenameLength: 20, MaxFolderDepth: 2}
+
+	if name := limitPath(settings, "a/b/c/d/song.mp3"); name != "a/b - c - d/song.mp3" {
+		t.Fatalf("unexpected name with joined folders %q", name)
+	}
+	if name := limitPath(settings, "AC:DC/Who Made Who?/song.mp3"); name != "AC_DC/Who Made Who_/song.mp3" {
+		t.Fatalf("unexpected name with replaced characters %q", name)
+	}
+
+	long := limitPath(settings, "A very long song title, part 1.mp3")
+	other := limitPath(settings, "A very long song title, part 2.mp3")
+	if utf8.RuneCountInString(long) != 20 || !strings.HasSuffix(long, ".mp3") || !strings.HasPrefix(long, "A very long") {
+		t.Fatalf("unexpected shortened name %q", long)
+	}
+	if long == other {
+		t.Fatalf("expected shortened names to stay distinct, got %q twice", long)
+	}
+
+	if name := limitPath(&ExportSettings{}, "a/b/c/d/Motörhead?.mp3"); name != "a/b/c/d/Motörhead?.mp3" {
+		t.Fatalf("expected path to be kept without limits, got %q", name)
+	}
+	if name := limitPath(&ExportSettings{NameCase: NAME_CASE_LOWER}, "Music/AC_DC/Hells Bells.MP3"); name != "music/ac_dc/hells bells.mp3" {
+		t.Fatalf("unexpected name in lower case %q", name)
+	}
+	if name := limitPath(&ExportSettings{NameCase: NAME_CASE_UPPER}, "AC_DC/Hells Bells.mp3"); name != "AC_DC/HELLS BELLS.MP3" {
+		t.Fatalf("unexpected name in upper case %q", name)
+	}
+	if name := limitPath(&ExportSettings{Encoding: ENCODING_CP1252}, "Sigur Rós/Ágætis byrjun/Ǽ日.mp3"); name != "Sigur Rós/Ágætis byrjun/Æ_.mp3" {
+		t.Fatalf("unexpected name for CP1252 %q", name)
+	}
+}
+
+func TestStripSymbols(t *testing.T) {
+	for name, expected := range map[string]string{
+		"Party 🎉🎉 Mix":              "Party Mix",
+		"Thumbs 👍🏽 Up":              "Thumbs Up",
+		"Zero\u200bWidth\u200d.mp3": "ZeroWidth.mp3",
+		"Line\nBreak ❤️":            "LineBreak",
+		"Sigur Rós – Ágætis byrjun": "Sigur Rós – Ágætis byrjun",
+		"東京事変 (Live) #1 & Co.":      "東京事変 (Live) #1 & Co.",
+	} {
+		if stripped := stripSymbols(name); stripped != expected {
+			t.Fatalf("expected %q for %q, got %q", expected, name, stripped)
+		}
+	}
+	if name := limitPath(&ExportSettings{StripSymbols: true}, "🎸/Rock 🤘/Song.mp3"); name != "_/Rock/Song.mp3" {
+		t.Fatalf("unexpected path without symbols %q", name)
+	}
+}
+
+func TestEncodePlaylist(t *testing.T) {
+	content := []byte("Motörhead – Ace of Spades €5 Ǹ 日本\n")
+
+	if encoded := encodePlaylist(content, ENCODING_UTF8_BOM); string(encoded) != "\xef\xbb\xbf"+string(content) {
+		t.Fatalf("unexpected UTF-8 with BOM %q", encoded)
+	}
+	if encoded := encodePlaylist(content, ENCODING_CP1252); string(encoded) != "Mot\xf6rhead \x96 Ace of Spades \x805 N ??\n" {
+		t.Fatalf("unexpected CP1252 %q", encoded)
+	}
+}
+
+func TestExportWithDeviceProfile(t *testing.T) {
+	outputDir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(outputDir)
+
+	musicFile, musicFileName := prepareMusicFile(t)
+	defer os.Remove(musicFile)
+
+	library := testLibrary()
+	track := library.Tracks["1"]
+	track.Artist = "Motörhead"
+	track.Album = "Ace of Spades"
+	track.Location = "file://" + filepath.ToSlash(musicFile)
+	library.Tracks["1"] = track
+	library.Playlists[0].PlaylistItems = append(library.Playlists[0].PlaylistItems, library.Playlists[0].PlaylistItems[0])
+
+	settings := &ExportSettings{Library: library, Playlists: library.Playlists, OutputPath: outputDir, PlaylistDir: "Playlists"}
+	if err := ApplyProfile(settings, "KENWOOD"); err != nil {
+		t.Fatal(err)
+	}
+	settings.MaxPlaylistEntries = 1
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+
+	assertPathExists(t, filepath.Join(outputDir, "Motörhead", "Ace of Spades", musicFileName))
+	expected := "#EXTM3U\n#EXTINF:210,Mot\xf6rhead - Some Song\n..\\Mot\xf6rhead\\Ace of Spades\\" + musicFileName + "\n"
+	if content := readFile(t, filepath.Join(outputDir, "Playlists", "My Playlist.m3u")); content != expected {
+		t.Fatalf("expected playlist %q, got %q", expected, content)
+	}
+
+	if err := ApplyProfile(settings, "walkman"); err == nil {
+		t.Fatal("expected an unknown profile to fail")
+	}
+}
+
+func TestLayoutBuckets(t *testing.T) {
+	names := []string{"Abba", "Adele", "Beck", "Blur", "Cher", "Coldplay", "Muse", "Nena", "Oasis", "Queen"}
+	if buckets := layoutBuckets(names, 10); buckets != nil {
+		t.Fatalf("expected no subfolders for 10 names, got %v", buckets)
+	}
+
+	buckets := layoutBuckets(names, 4)
+	for name, expected := range map[string]string{"Abba": "A-B", "Blur": "A-B", "Cher": "C-N", "Nena": "C-N", "Oasis": "O-Q"} {
+		if folders := strings.Join(buckets[name], "/"); folders != expected {
+			t.Fatalf("expected %v in %v, got %v", name, expected, folders)
+		}
+	}
+
+	buckets = layoutBuckets(names, 2)
+	if folders := buckets["Queen"]; len(folders) != 3 || folders[2] != "O-Q" {
+		t.Fatalf("expected subfolders of subfolders, got %v", folders)
+	}
+
+	buckets = layoutBuckets([]string{"Madonna", "Metallica", "Moby", "Muse"}, 2)
+	if a, b := strings.Join(buckets["Madonna"], "/"), strings.Join(buckets["Muse"], "/"); a != "MA-ME" || b != "MO-MU" {
+		t.Fatalf("expected more letters for distinct names, got %v and %v", a, b)
+	}
+}
+
+func TestExportWithMaxFilesPerDir(t *testing.T) {
+	outputDir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(outputDir)
+
+	musicFile, _ := prepareMusicFile(t)
+	defer os.Remove(musicFile)
+
+	library := testLibrary()
+	var items []itunes.PlaylistItem
+	for id, artist := range []string{"Abba", "Beck", "Cher"} {
+		library.Tracks[strconv.Itoa(id+1)] = itunes.Track{TrackId: id + 1, Name: "Song", Artist: artist, Album: "Hits", Location: "file://" + filepath.ToSlash(musicFile)}
+		items = append(items, itunes.PlaylistItem{TrackId: id + 1})
+	}
+	library.Playlists[0].PlaylistItems = items
+
+	settings := &ExportSettings{Library: library, Playlists: library.Playlists, OutputPath: outputDir, PathStyle: PATH_STYLE_RELATIVE, CopyType: COPY_ITUNES, MaxFilesPerDir: 2}
+	ParseExportType(settings, M3U)
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+	assertPathExists(t, filepath.Join(outputDir, "A-B", "Abba", "Hits", filepath.Base(musicFile)))
+	assertPathExists(t, filepath.Join(outputDir, "C", "Cher", "Hits", filepath.Base(musicFile)))
+	if content := readFile(t, filepath.Join(outputDir, "My Playlist.m3u")); !strings.Contains(content, "C/Cher/Hits/") {
+		t.Fatalf("expected the playlist to reference the moved files, got %v", content)
+	}
+}
+
+func TestExportWithReplacements(t *testing.T) {
+	outputDir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(outputDir)
+
+	musicFile, _ := prepareMusicFile(t)
+	defer os.Remove(musicFile)
+
+	library := testLibrary()
+	library.Tracks["1"] = itunes.Track{TrackId: 1, Name: "Song", Artist: "AC/DC", Album: "Live: 1984", Location: "file://" + filepath.ToSlash(musicFile)}
+	library.Playlists[0].Name = "Rock: Best"
+
+	settings := &ExportSettings{Library: library, Playlists: library.Playlists, OutputPath: outputDir, PathStyle: PATH_STYLE_RELATIVE, CopyType: COPY_ITUNES,
+		Replacements: map[string]string{":": " -", "/": "_"}}
+	ParseExportType(settings, M3U)
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+	assertPathExists(t, filepath.Join(outputDir, "AC_DC", "Live - 1984", filepath.Base(musicFile)))
+	assertPlaylistFileCorrectlyWritten(t, filepath.Join(outputDir, "Rock - Best.m3u"), "AC_DC/Live - 1984/"+filepath.Base(musicFile))
+}
@@ -0,0 +1,97 @@
+package export
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LockFileName is created in the output directory while an export is running.
+const LockFileName = ".itunesexport.lock"
+
+// staleLockAge is the age after which a lock held by another host is considered stale,
+// as we can not check whether its process is still running.
+const staleLockAge = 24 * time.Hour
+
+type outputLock struct {
+	path string
+}
+
+// lockOutput acquires the lock for the output directory, replacing a stale lock left
+// behind by a process that no longer runs. It fails if another export holds the lock.
+func lockOutput(outputPath string) (*outputLock, error) {
+	if outputPath == "" {
+		outputPath = "."
+	}
+	if err := os.MkdirAll(outputPath, 0777); err != nil {
+		return nil, err
+	}
+
+	lockPath := filepath.Join(outputPath, LockFileName)
+	hostname, _ := os.Hostname()
+	content := fmt.Sprintf("%v\n%v\n%v\n", os.Getpid(), hostname, time.Now().Format(time.RFC3339))
+
+	for attempt := 0; attempt < 2; attempt++ {
+		file, err := os.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+		if err == nil {
+			_, err = file.WriteString(content)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(lockPath)
+				return nil, err
+			}
+			return &outputLock{path: lockPath}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		stale, owner := isStaleLock(lockPath, hostname)
+		if !stale {
+			return nil, fmt.Errorf("output %v is locked by another export (%v). Remove %v if that export is no longer running", outputPath, owner, lockPath)
+		}
+		Log(LogEntry{Level: LOG_WARNING, Message: fmt.Sprintf("Removing stale lock %v (%v)\n", lockPath, owner), Path: lockPath})
+		if err := os.Remove(lockPath); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("unable to lock output %v", outputPath)
+}
+
+// isStaleLock reports whether the lock at lockPath was left behind by a process that is
+// no longer running, along with a description of the lock owner.
+func isStaleLock(lockPath string, hostname string) (bool, string) {
+	data, err := ioutil.ReadFile(lockPath)
+	if err != nil {
+		// The lock was removed in the meantime, or is unreadable.
+		return os.IsNotExist(err), "unknown"
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) < 3 {
+		// Half written lock file.
+		return true, "invalid lock file"
+	}
+	pid, pidErr := strconv.Atoi(lines[0])
+	lockHost := lines[1]
+	created, timeErr := time.Parse(time.RFC3339, lines[2])
+	owner := fmt.Sprintf("pid %v on %v since %v", lines[0], lockHost, lines[2])
+
+	if pidErr != nil || timeErr != nil {
+		return true, owner
+	}
+	if lockHost == hostname {
+		return !processExists(pid), owner
+	}
+	return time.Since(created) > staleLockAge, owner
+}
+
+func (lock *outputLock) unlock() error {
+	return os.Remove(lock.path)
+}
@@ -0,0 +1,51 @@
+package export
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLockOutput(t *testing.T) {
+	dir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(dir)
+
+	lock, err := lockOutput(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lockOutput(dir); err == nil {
+		t.Fatal("expected second lock to fail")
+	}
+	if err := lock.unlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	lock, err = lockOutput(dir)
+	if err != nil {
+		t.Fatalf("expected lock after unlock to succeed: %v", err)
+	}
+	lock.unlock()
+}
+
+func TestLockOutputReplacesStaleLock(t *testing.T) {
+	dir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(dir)
+
+	hostname, _ := os.Hostname()
+	for _, content := range []string{
+		fmt.Sprintf("999999999\n%v\n%v\n", hostname, time.Now().Format(time.RFC3339)),
+		fmt.Sprintf("1\nsome-other-host\n%v\n", time.Now().Add(-48*time.Hour).Format(time.RFC3339)),
+		"garbage",
+	} {
+		writeLocalFile(t, filepath.Join(dir, LockFileName), content)
+
+		lock, err := lockOutput(dir)
+		if err != nil {
+			t.Fatalf("expected stale lock %q to be replaced: %v", content, err)
+		}
+		lock.unlock()
+	}
+}
@@ -0,0 +1,114 @@
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	LOG_FORMAT_TEXT = iota
+	LOG_FORMAT_JSON
+)
+
+// Levels of the messages of an export.
+const (
+	LOG_INFO    = "info"
+	LOG_WARNING = "warning"
+	LOG_ERROR   = "error"
+)
+
+// LogEntry is a message of an export, e.g. that a playlist was skipped or a music file
+// could not be copied.
+type LogEntry struct {
+	Time     time.Time `json:"time"`
+	Level    string    `json:"level"`
+	Message  string    `json:"message"`
+	Playlist string    `json:"playlist,omitempty"`
+	Track    string    `json:"track,omitempty"`
+	Path     string    `json:"path,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// LogHandler receives every message in addition to the console, e.g. to send it to the
+// system log.
+type LogHandler interface {
+	HandleLog(entry LogEntry)
+}
+
+// LogHandlerFunc adapts a function to a LogHandler.
+type LogHandlerFunc func(entry LogEntry)
+
+func (f LogHandlerFunc) HandleLog(entry LogEntry) {
+	f(entry)
+}
+
+var logger = struct {
+	sync.Mutex
+	format  int
+	output  io.Writer
+	handler LogHandler
+}{}
+
+// ParseLogFormat parses the name of a log format, TEXT or JSON.
+func ParseLogFormat(format string) (int, error) {
+	switch strings.ToUpper(format) {
+	case "", "TEXT":
+		return LOG_FORMAT_TEXT, nil
+	case "JSON":
+		return LOG_FORMAT_JSON, nil
+	default:
+		return LOG_FORMAT_TEXT, errors.New("Unknown log format: " + format)
+	}
+}
+
+// SetLogFormat sets how messages are written. With LOG_FORMAT_JSON every message is
+// written to w as a line of JSON. A nil w writes to standard output.
+func SetLogFormat(format int, w io.Writer) {
+	logger.Lock()
+	defer logger.Unlock()
+	logger.format = format
+	logger.output = w
+}
+
+// SetLogHandler sets the handler receiving every message in addition to the console. A nil
+// handler removes it.
+func SetLogHandler(handler LogHandler) {
+	logger.Lock()
+	defer logger.Unlock()
+	logger.handler = handler
+}
+
+// Log writes entry in the format set with SetLogFormat. In TEXT format only the message
+// is written, as is.
+func Log(entry LogEntry) {
+	logger.Lock()
+	defer logger.Unlock()
+	output := logger.output
+	if output == nil {
+		output = os.Stdout
+	}
+	if logger.format != LOG_FORMAT_JSON {
+		fmt.Fprint(output, entry.Message)
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	entry.Message = strings.TrimSpace(entry.Message)
+	if logger.format == LOG_FORMAT_JSON {
+		json.NewEncoder(output).Encode(entry)
+	}
+	if logger.handler != nil && entry.Message != "" {
+		logger.handler.HandleLog(entry)
+	}
+}
+
+// Logf writes a message without further details at level.
+func Logf(level string, format string, args ...interface{}) {
+	Log(LogEntry{Level: level, Message: fmt.Sprintf(format, args...)})
+}
@@ -0,0 +1,97 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+func TestParseLogFormat(t *testing.T) {
+	if format, err := ParseLogFormat("json"); err != nil || format != LOG_FORMAT_JSON {
+		t.Fatalf("expected JSON, got %v, %v", format, err)
+	}
+	if format, err := ParseLogFormat(""); err != nil || format != LOG_FORMAT_TEXT {
+		t.Fatalf("expected TEXT, got %v, %v", format, err)
+	}
+	if _, err := ParseLogFormat("xml"); err == nil {
+		t.Fatal("expected an error for an unknown log format")
+	}
+}
+
+func TestLogFormatJSON(t *testing.T) {
+	outputDir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(outputDir)
+
+	var output bytes.Buffer
+	SetLogFormat(LOG_FORMAT_JSON, &output)
+	defer SetLogFormat(LOG_FORMAT_TEXT, nil)
+
+	library := testLibrary()
+	library.Tracks["1"] = itunes.Track{TrackId: 1, Name: "Missing", Location: "file:///nonexistent/missing.mp3"}
+	library.Playlists[0].PlaylistItems = []itunes.PlaylistItem{{TrackId: 1}}
+
+	settings := &ExportSettings{Library: library, Playlists: library.Playlists, OutputPath: outputDir, CopyType: COPY_FLAT, MusicDir: "Music"}
+	ParseExportType(settings, M3U)
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+
+	var entries []LogEntry
+	scanner := bufio.NewScanner(&output)
+	for scanner.Scan() {
+		var entry LogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("expected a JSON object per line, got %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+
+	var copyError *LogEntry
+	for i, entry := range entries {
+		if entry.Time.IsZero() || entry.Level == "" || entry.Message == "" {
+			t.Fatalf("expected time, level and message, got %+v", entry)
+		}
+		if entry.Level == LOG_ERROR && entry.Track == "Missing" {
+			copyError = &entries[i]
+		}
+	}
+	if copyError == nil {
+		t.Fatalf("expected an error about the missing track, got %+v", entries)
+	}
+	if copyError.Playlist != library.Playlists[0].Name || copyError.Path != "/nonexistent/missing.mp3" || copyError.Error == "" {
+		t.Fatalf("expected the playlist, path and error of the missing track, got %+v", *copyError)
+	}
+}
+
+func TestLogHandler(t *testing.T) {
+	var output bytes.Buffer
+	SetLogFormat(LOG_FORMAT_TEXT, &output)
+	defer SetLogFormat(LOG_FORMAT_TEXT, nil)
+
+	var entries []LogEntry
+	SetLogHandler(LogHandlerFunc(func(entry LogEntry) {
+		entries = append(entries, entry)
+	}))
+	defer SetLogHandler(nil)
+
+	Logf(LOG_INFO, "\n\nExport Complete.\n")
+	Logf(LOG_INFO, "\n")
+	Log(LogEntry{Level: LOG_ERROR, Message: "Unable to copy file a.mp3\n", Path: "a.mp3"})
+
+	if output.String() != "\n\nExport Complete.\n\nUnable to copy file a.mp3\n" {
+		t.Fatalf("expected the messages on the console as is, got %q", output.String())
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected the two messages which are not blank, got %+v", entries)
+	}
+	if entries[0].Message != "Export Complete." || entries[0].Level != LOG_INFO || entries[0].Time.IsZero() {
+		t.Fatalf("expected the trimmed message with level and time, got %+v", entries[0])
+	}
+	if entries[1].Level != LOG_ERROR || entries[1].Path != "a.mp3" {
+		t.Fatalf("expected the error with its path, got %+v", entries[1])
+	}
+}
@@ -0,0 +1,73 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// exportMetrics collects counters about the exports run by this process.
+// All fields are accessed atomically as exports may run in the background.
+type exportMetrics struct {
+	exportsRun         int64
+	exportsFailed      int64
+	playlistsExported  int64
+	filesCopied        int64
+	bytesCopied        int64
+	errors             int64
+	lastSuccessSeconds int64
+}
+
+var metrics exportMetrics
+
+func (m *exportMetrics) exportFinished(err error) {
+	atomic.AddInt64(&m.exportsRun, 1)
+	if err != nil {
+		atomic.AddInt64(&m.exportsFailed, 1)
+		atomic.AddInt64(&m.errors, 1)
+		return
+	}
+	atomic.StoreInt64(&m.lastSuccessSeconds, time.Now().Unix())
+}
+
+func (m *exportMetrics) playlistExported() {
+	atomic.AddInt64(&m.playlistsExported, 1)
+}
+
+func (m *exportMetrics) fileCopied(bytes int64) {
+	atomic.AddInt64(&m.filesCopied, 1)
+	atomic.AddInt64(&m.bytesCopied, bytes)
+}
+
+func (m *exportMetrics) errorOccurred() {
+	atomic.AddInt64(&m.errors, 1)
+}
+
+// WriteMetrics writes the metrics of all exports run by this process in the
+// Prometheus text exposition format.
+func WriteMetrics(w io.Writer) error {
+	return metrics.writeTo(w)
+}
+
+// writeTo writes the metrics in the Prometheus text exposition format.
+func (m *exportMetrics) writeTo(w io.Writer) error {
+	for _, metric := range []struct {
+		name, kind, help string
+		value            *int64
+	}{
+		{"itunesexport_exports_total", "counter", "Number of exports run.", &m.exportsRun},
+		{"itunesexport_export_failures_total", "counter", "Number of exports that failed.", &m.exportsFailed},
+		{"itunesexport_playlists_exported_total", "counter", "Number of playlist files written.", &m.playlistsExported},
+		{"itunesexport_files_copied_total", "counter", "Number of music files copied.", &m.filesCopied},
+		{"itunesexport_copied_bytes_total", "counter", "Number of bytes copied.", &m.bytesCopied},
+		{"itunesexport_errors_total", "counter", "Number of errors, including tracks that could not be exported.", &m.errors},
+		{"itunesexport_last_success_timestamp_seconds", "gauge", "Unix time of the last successful export, 0 if none.", &m.lastSuccessSeconds},
+	} {
+		_, err := fmt.Fprintf(w, "# HELP %v %v\n# TYPE %v %v\n%v %v\n", metric.name, metric.help, metric.name, metric.kind, metric.name, atomic.LoadInt64(metric.value))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
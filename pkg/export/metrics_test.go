@@ -0,0 +1,36 @@
+package export
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMetricsExposition(t *testing.T) {
+	var m exportMetrics
+	m.exportFinished(nil)
+	m.exportFinished(errors.New("failed"))
+	m.fileCopied(42)
+
+	var buffer bytes.Buffer
+	if err := m.writeTo(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	output := buffer.String()
+
+	for _, expected := range []string{
+		"# TYPE itunesexport_exports_total counter\nitunesexport_exports_total 2\n",
+		"itunesexport_export_failures_total 1\n",
+		"itunesexport_files_copied_total 1\n",
+		"itunesexport_copied_bytes_total 42\n",
+		"itunesexport_errors_total 1\n",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected metrics to contain %q, got:\n%v", expected, output)
+		}
+	}
+	if strings.Contains(output, "itunesexport_last_success_timestamp_seconds 0\n") {
+		t.Error("last success timestamp not set")
+	}
+}
@@ -0,0 +1,144 @@
+package export
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MTPDevice is a phone or music player attached using MTP, as mounted by gvfs.
+type MTPDevice struct {
+	Name string
+	// Path is the directory the device is mounted at.
+	Path string
+}
+
+// FindMTPDevices returns the MTP devices currently attached. Devices are found through
+// their gvfs mounts, so this is only supported on Linux desktops running gvfs.
+func FindMTPDevices() ([]MTPDevice, error) {
+	dir := gvfsDir()
+	if dir == "" {
+		return nil, errors.New("MTP devices are only supported on Linux with gvfs")
+	}
+	return findMTPDevices(dir)
+}
+
+// findMTPDevices returns the MTP devices mounted in the gvfs directory dir.
+func findMTPDevices(dir string) ([]MTPDevice, error) {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var devices []MTPDevice
+	for _, file := range files {
+		if !file.IsDir() || !strings.HasPrefix(file.Name(), "mtp:host=") {
+			continue
+		}
+		name := strings.TrimPrefix(file.Name(), "mtp:host=")
+		if unescaped, err := url.PathUnescape(name); err == nil {
+			name = unescaped
+		}
+		devices = append(devices, MTPDevice{Name: strings.Replace(name, "_", " ", -1), Path: filepath.Join(dir, file.Name())})
+	}
+	return devices, nil
+}
+
+// MusicFolder returns the Music folder of the device. Devices can have several storages,
+// e.g. the internal storage and a SD card; the first storage with a Music folder is used,
+// otherwise a Music folder is created on the first storage.
+func (device MTPDevice) MusicFolder() (string, error) {
+	storages, err := ioutil.ReadDir(device.Path)
+	if err != nil {
+		return "", err
+	}
+
+	var first string
+	for _, storage := range storages {
+		if !storage.IsDir() {
+			continue
+		}
+		storagePath := filepath.Join(device.Path, storage.Name())
+		if first == "" {
+			first = storagePath
+		}
+		if info, err := os.Stat(filepath.Join(storagePath, "Music")); err == nil && info.IsDir() {
+			return filepath.Join(storagePath, "Music"), nil
+		}
+	}
+
+	if first == "" {
+		return "", errors.New("no storage found on " + device.Name + ", unlock it and allow file transfer")
+	}
+	musicFolder := filepath.Join(first, "Music")
+	return musicFolder, os.Mkdir(musicFolder, 0777)
+}
+
+// MTPFS is an FS writing to the gvfs mount of an MTP device. gvfs can neither change the
+// mode of a file on the device nor rename a file over an existing one, so unlike DirFS it
+// writes files in place.
+type MTPFS struct {
+	dir *DirFS
+}
+
+// NewMTPFS returns an FS writing to the directory root on an MTP device.
+func NewMTPFS(root string) *MTPFS {
+	return &MTPFS{dir: NewDirFS(root)}
+}
+
+// Create writes the file in place, removing an existing file first.
+func (fsys *MTPFS) Create(name string) (File, error) {
+	fileName := fsys.dir.path(name)
+	if err := os.MkdirAll(filepath.Dir(fileName), 0777); err != nil {
+		return nil, err
+	}
+	if err := os.Remove(fileName); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	file, err := os.Create(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return &mtpFile{file}, nil
+}
+
+func (fsys *MTPFS) Open(name string) (io.ReadCloser, error) {
+	return fsys.dir.Open(name)
+}
+
+func (fsys *MTPFS) Stat(name string) (os.FileInfo, error) {
+	return fsys.dir.Stat(name)
+}
+
+func (fsys *MTPFS) MkdirAll(name string) error {
+	return fsys.dir.MkdirAll(name)
+}
+
+func (fsys *MTPFS) Remove(name string) error {
+	return fsys.dir.Remove(name)
+}
+
+// Rename renames a file to a name which is not taken, as when reordering the files.
+func (fsys *MTPFS) Rename(oldName, newName string) error {
+	return fsys.dir.Rename(oldName, newName)
+}
+
+func (fsys *MTPFS) FreeSpace() (int64, error) {
+	return fsys.dir.FreeSpace()
+}
+
+type mtpFile struct {
+	*os.File
+}
+
+func (file *mtpFile) Abort() error {
+	file.File.Close()
+	return os.Remove(file.File.Name())
+}
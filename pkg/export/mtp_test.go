@@ -0,0 +1,85 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindMTPDevices(t *testing.T) {
+	dir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(dir)
+
+	phone := filepath.Join(dir, "mtp:host=SAMSUNG_Android_R58M")
+	os.MkdirAll(filepath.Join(phone, "Internal shared storage", "Music"), 0777)
+	player := filepath.Join(dir, "mtp:host=%5Busb%3A001%2C007%5D")
+	os.MkdirAll(filepath.Join(player, "Storage"), 0777)
+	os.MkdirAll(filepath.Join(dir, "smb-share:server=nas,share=music"), 0777)
+
+	devices, err := findMTPDevices(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 2 || devices[0].Name != "[usb:001,007]" || devices[1].Name != "SAMSUNG Android R58M" {
+		t.Fatalf("unexpected devices %v", devices)
+	}
+
+	if musicFolder, err := devices[1].MusicFolder(); err != nil || musicFolder != filepath.Join(phone, "Internal shared storage", "Music") {
+		t.Fatalf("unexpected music folder %v, error %v", musicFolder, err)
+	}
+	if musicFolder, err := devices[0].MusicFolder(); err != nil || musicFolder != filepath.Join(player, "Storage", "Music") {
+		t.Fatalf("expected music folder to be created, got %v, error %v", musicFolder, err)
+	}
+
+	if devices, err := findMTPDevices(filepath.Join(dir, "missing")); err != nil || len(devices) != 0 {
+		t.Fatalf("expected no devices, got %v, error %v", devices, err)
+	}
+}
+
+func TestMTPFS(t *testing.T) {
+	musicFolder := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(musicFolder)
+	musicFile, musicFileName := prepareMusicFile(t)
+	defer os.Remove(musicFile)
+
+	library := testLibrary()
+	track := library.Tracks["1"]
+	track.Location = "file://" + filepath.ToSlash(musicFile)
+	library.Tracks["1"] = track
+
+	playlistFile := filepath.Join(musicFolder, "My Playlist.m3u")
+	writeLocalFile(t, playlistFile, "outdated")
+
+	settings := &ExportSettings{Library: library, Playlists: library.Playlists, Output: NewMTPFS(musicFolder),
+		PathStyle: PATH_STYLE_RELATIVE, CopyType: COPY_PLAYLIST}
+	ParseExportType(settings, M3U)
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+
+	assertPathExists(t, filepath.Join(musicFolder, "My Playlist", musicFileName))
+	assertPlaylistFileCorrectlyWritten(t, playlistFile, "My Playlist/"+musicFileName)
+
+	file, err := NewMTPFS(musicFolder).Create("Written.m3u")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Write([]byte("#EXTM3U\n"))
+	if content := readFile(t, filepath.Join(musicFolder, "Written.m3u")); content != "#EXTM3U\n" {
+		t.Fatalf("expected the file to be written in place, got %q", content)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err = NewMTPFS(musicFolder).Create("Aborted.m3u")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Abort(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(musicFolder, "Aborted.m3u")); !os.IsNotExist(err) {
+		t.Fatalf("expected the aborted file to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package export
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// OVERWRITE_DEFAULT rewrites playlist files but keeps music files that already exist.
+	OVERWRITE_DEFAULT = iota
+	OVERWRITE_ALWAYS
+	OVERWRITE_NEVER
+	OVERWRITE_IF_NEWER
+	OVERWRITE_PROMPT
+	// OVERWRITE_IF_SIZE_DIFFERS overwrites files whose size differs from the source, e.g.
+	// music files left incomplete by an interrupted copy.
+	OVERWRITE_IF_SIZE_DIFFERS
+)
+
+var (
+	promptMutex  sync.Mutex
+	promptInput  io.Reader = os.Stdin
+	promptReader *bufio.Reader
+)
+
+// ParseOverwrite sets the overwrite policy of settings from its name: ALWAYS, NEVER, IFNEWER,
+// IFSIZEDIFFERS, PROMPT or the empty string for the default policy.
+func ParseOverwrite(settings *ExportSettings, overwrite string) error {
+	switch strings.ToUpper(overwrite) {
+	case "":
+		settings.Overwrite = OVERWRITE_DEFAULT
+	case "ALWAYS":
+		settings.Overwrite = OVERWRITE_ALWAYS
+	case "NEVER":
+		settings.Overwrite = OVERWRITE_NEVER
+	case "IFNEWER":
+		settings.Overwrite = OVERWRITE_IF_NEWER
+	case "IFSIZEDIFFERS":
+		settings.Overwrite = OVERWRITE_IF_SIZE_DIFFERS
+	case "PROMPT":
+		settings.Overwrite = OVERWRITE_PROMPT
+	default:
+		return errors.New("Unknown Overwrite Policy: " + overwrite)
+	}
+	return nil
+}
+
+// shouldWritePlaylist decides whether the playlist file at dest should be (re)written.
+// The library date is used as the modification time of the playlist source, its size is unknown.
+func shouldWritePlaylist(exportSettings *ExportSettings, dest string) (bool, error) {
+	if exportSettings.Overwrite == OVERWRITE_DEFAULT {
+		return true, nil
+	}
+	var sourceModTime time.Time
+	if exportSettings.Library != nil {
+		sourceModTime = exportSettings.Library.Date
+	}
+	return shouldOverwrite(exportSettings.Output, exportSettings.Overwrite, dest, sourceModTime, -1)
+}
+
+// shouldCopyFile decides whether the music file src should be copied over dest.
+func shouldCopyFile(exportSettings *ExportSettings, sourceFileInfo os.FileInfo, dest string) (bool, error) {
+	policy := exportSettings.Overwrite
+	if policy == OVERWRITE_DEFAULT {
+		policy = OVERWRITE_NEVER
+	}
+	return shouldOverwrite(exportSettings.Output, policy, dest, sourceFileInfo.ModTime(), sourceFileInfo.Size())
+}
+
+// shouldOverwrite applies the overwrite policy to dest. Files that do not exist yet are always written.
+// For OVERWRITE_IF_NEWER a zero sourceModTime is treated as newer, for OVERWRITE_IF_SIZE_DIFFERS
+// a negative sourceSize is treated as different.
+func shouldOverwrite(fsys FS, policy int, dest string, sourceModTime time.Time, sourceSize int64) (bool, error) {
+	destFileInfo, err := fsys.Stat(dest)
+	if os.IsNotExist(err) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	switch policy {
+	case OVERWRITE_ALWAYS:
+		return true, nil
+	case OVERWRITE_NEVER:
+		return false, nil
+	case OVERWRITE_IF_NEWER:
+		return sourceModTime.IsZero() || sourceModTime.After(destFileInfo.ModTime()), nil
+	case OVERWRITE_IF_SIZE_DIFFERS:
+		return sourceSize < 0 || sourceSize != destFileInfo.Size(), nil
+	case OVERWRITE_PROMPT:
+		return promptOverwrite(dest)
+	default:
+		return false, errors.New("unknown overwrite policy")
+	}
+}
+
+// promptOverwrite asks on the console whether dest should be overwritten.
+func promptOverwrite(dest string) (bool, error) {
+	return prompt(fmt.Sprintf("%v already exists. Overwrite?", dest))
+}
+
+// prompt asks the yes or no question on the console. Anything but yes is taken as no.
+func prompt(question string) (bool, error) {
+	promptMutex.Lock()
+	defer promptMutex.Unlock()
+
+	if promptReader == nil {
+		promptReader = bufio.NewReader(promptInput)
+	}
+
+	fmt.Printf("%v [y/N] ", question)
+	answer, err := promptReader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
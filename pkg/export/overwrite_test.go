@@ -0,0 +1,62 @@
+package export
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShouldOverwrite(t *testing.T) {
+	fsys := NewMemFS()
+	writeFile(fsys, "existing.m3u", func(w io.Writer) error { return nil })
+	writeFile(fsys, "song.mp3", func(w io.Writer) error {
+		_, err := io.WriteString(w, "music")
+		return err
+	})
+	modTime := time.Now()
+
+	for _, test := range []struct {
+		policy        int
+		dest          string
+		sourceModTime time.Time
+		sourceSize    int64
+		expected      bool
+	}{
+		{OVERWRITE_NEVER, "missing.m3u", time.Time{}, -1, true},
+		{OVERWRITE_ALWAYS, "existing.m3u", time.Time{}, -1, true},
+		{OVERWRITE_NEVER, "existing.m3u", modTime.Add(time.Hour), -1, false},
+		{OVERWRITE_IF_NEWER, "existing.m3u", modTime.Add(time.Hour), -1, true},
+		{OVERWRITE_IF_NEWER, "existing.m3u", modTime.Add(-time.Hour), -1, false},
+		{OVERWRITE_IF_SIZE_DIFFERS, "song.mp3", modTime, 5, false},
+		{OVERWRITE_IF_SIZE_DIFFERS, "song.mp3", modTime, 3, true},
+		{OVERWRITE_IF_SIZE_DIFFERS, "song.mp3", modTime, -1, true},
+	} {
+		result, err := shouldOverwrite(fsys, test.policy, test.dest, test.sourceModTime, test.sourceSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result != test.expected {
+			t.Errorf("policy %v for %v: expected %v, got %v", test.policy, test.dest, test.expected, result)
+		}
+	}
+}
+
+func TestPromptOverwrite(t *testing.T) {
+	fsys := NewMemFS()
+	writeFile(fsys, "existing.m3u", func(w io.Writer) error { return nil })
+
+	realInput, realReader := promptInput, promptReader
+	defer func() { promptInput, promptReader = realInput, realReader }()
+	promptInput, promptReader = strings.NewReader("y\nn\n"), nil
+
+	for _, expected := range []bool{true, false, false} {
+		result, err := shouldOverwrite(fsys, OVERWRITE_PROMPT, "existing.m3u", time.Time{}, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result != expected {
+			t.Errorf("expected %v, got %v", expected, result)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package export
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// processExists reports whether a process with the given pid is running.
+func processExists(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
+
+// gvfsDir returns the directory gvfs mounts MTP devices in.
+func gvfsDir() string {
+	return fmt.Sprintf("/run/user/%v/gvfs", os.Getuid())
+}
+
+// freeSpace returns the space available to the user on the volume of dir.
+func freeSpace(dir string) (int64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, err
+	}
+	return int64(stat.Bavail) * int64(stat.Bsize), nil
+}
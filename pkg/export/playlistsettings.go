@@ -0,0 +1,83 @@
+package export
+
+import (
+	"path"
+	"strings"
+)
+
+// PlaylistSettings overrides the settings of an export for a single playlist, e.g. to copy
+// one playlist flat into the root of a device while the others keep their folders.
+type PlaylistSettings struct {
+	// Dir, if set, is the directory of Output the playlist file and its copied music files
+	// are written to instead of PlaylistDir and MusicDir. "/" is the root of Output.
+	Dir string
+	// ExportType, if set, is the name of the playlist type, as for ParseExportType.
+	ExportType string
+	// CopyType, if set, is the name of the copy type, as for ParseCopyType.
+	CopyType string
+	// Audiobooks, if set, is how the audiobooks of the playlist are exported, as for
+	// ParseAudiobooks.
+	Audiobooks string
+	// Videos, if set, is how the videos of the playlist are exported, as for ParseVideos.
+	Videos string
+}
+
+// Validate reports an error if the playlist or copy type of playlistSettings is unknown.
+func (playlistSettings PlaylistSettings) Validate() error {
+	_, err := playlistSettings.apply(&ExportSettings{})
+	return err
+}
+
+// apply returns a copy of exportSettings with the settings of playlistSettings.
+func (playlistSettings PlaylistSettings) apply(exportSettings *ExportSettings) (*ExportSettings, error) {
+	settings := *exportSettings
+	if playlistSettings.Dir != "" {
+		dir := strings.Trim(path.Clean("/"+strings.Replace(playlistSettings.Dir, "\\", "/", -1)), "/")
+		settings.PlaylistDir = dir
+		settings.MusicDir = dir
+	}
+	if playlistSettings.ExportType != "" {
+		if err := ParseExportType(&settings, playlistSettings.ExportType); err != nil {
+			return nil, err
+		}
+	}
+	if playlistSettings.CopyType != "" {
+		if err := ParseCopyType(&settings, playlistSettings.CopyType); err != nil {
+			return nil, err
+		}
+	}
+	if playlistSettings.Audiobooks != "" {
+		if err := ParseAudiobooks(&settings, playlistSettings.Audiobooks); err != nil {
+			return nil, err
+		}
+	}
+	if playlistSettings.Videos != "" {
+		if err := ParseVideos(&settings, playlistSettings.Videos); err != nil {
+			return nil, err
+		}
+	}
+	return &settings, nil
+}
+
+// forPlaylist returns the settings playlistName is exported with: exportSettings itself or,
+// if PlaylistSettings has an entry for the playlist, a copy with its settings.
+func (exportSettings *ExportSettings) forPlaylist(playlistName string) (*ExportSettings, error) {
+	playlistSettings, ok := exportSettings.PlaylistSettings[playlistName]
+	if !ok {
+		return exportSettings, nil
+	}
+	return playlistSettings.apply(exportSettings)
+}
+
+// usesCopyType reports whether the export or the settings of any playlist use copyType.
+func (exportSettings *ExportSettings) usesCopyType(copyType int) bool {
+	if exportSettings.CopyType == copyType {
+		return true
+	}
+	for _, playlistSettings := range exportSettings.PlaylistSettings {
+		if settings, err := playlistSettings.apply(exportSettings); err == nil && settings.CopyType == copyType {
+			return true
+		}
+	}
+	return false
+}
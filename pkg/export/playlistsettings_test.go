@@ -0,0 +1,40 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+func TestExportWithPlaylistSettings(t *testing.T) {
+	outputDir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(outputDir)
+
+	musicFile, musicFileName := prepareMusicFile(t)
+	defer os.Remove(musicFile)
+
+	library := testLibrary()
+	track := library.Tracks["1"]
+	track.Album = "Some Album"
+	track.Location = "file://" + filepath.ToSlash(musicFile)
+	library.Tracks["1"] = track
+	library.Playlists = append(library.Playlists, itunes.Playlist{Name: "Road Trip", PlaylistPersistentId: "0AD7419", PlaylistItems: []itunes.PlaylistItem{{TrackId: 1}}})
+
+	settings := &ExportSettings{Library: library, Playlists: library.Playlists, CopyType: COPY_ITUNES, OutputPath: outputDir,
+		PlaylistDir: "Playlists", MusicDir: "Music", PathStyle: PATH_STYLE_RELATIVE,
+		PlaylistSettings: map[string]PlaylistSettings{"Road Trip": {Dir: "/", ExportType: "ext", CopyType: "flat"}}}
+	ParseExportType(settings, M3U)
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+
+	assertPlaylistFileCorrectlyWritten(t, filepath.Join(outputDir, "Playlists", "My Playlist.m3u"), "../Music/Some Artist/Some Album/"+musicFileName)
+	assertPlaylistFileCorrectlyWritten(t, filepath.Join(outputDir, "Road Trip.m3u"), musicFileName)
+	assertPathExists(t, filepath.Join(outputDir, musicFileName))
+
+	if err := (PlaylistSettings{CopyType: "sideways"}).Validate(); err == nil {
+		t.Fatal("expected error for unknown copy type")
+	}
+}
@@ -0,0 +1,334 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+const (
+	M3U  = "M3U"
+	EXT  = "EXT"
+	M3U8 = "M3U8"
+	WPL  = "WPL"
+	ZPL  = "ZPL"
+	// HLS is an HTTP Live Streaming media playlist (RFC 8216) with the extension m3u8.
+	HLS = "HLS"
+	// XSPF is the XML Shareable Playlist Format, which VLC and many video players read.
+	XSPF = "XSPF"
+	// RSS is a podcast feed with the tracks as episodes, linking to the copied music files
+	// below ExportSettings.FeedURL.
+	RSS = "RSS"
+)
+
+func exportM3U(w io.Writer, playlist Playlist, options Options) error {
+
+	const headerString = "# M3U Playlist '%v' exported %v by iTunes Export v. %v (http://www.ericdaugherty.com/dev/itunesexport/)\n"
+	const entryString = "%v\n"
+
+	_, err := fmt.Fprintf(w, headerString, playlist.Name, options.Started.Format("2006-01-02 3:04PM"), Version)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range playlist.Entries {
+		_, err := fmt.Fprintf(w, entryString, entry.Location)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func exportEXT(w io.Writer, playlist Playlist, options Options) error {
+
+	const headerString = "#EXTM3U\n"
+	const entryString = "#EXTINF:%v,%v - %v\n%v\n"
+	const templateEntryString = "#EXTINF:%v,%v\n%v\n"
+
+	_, err := io.WriteString(w, headerString)
+	if err != nil {
+		return err
+	}
+
+	var album string
+	for i, entry := range playlist.Entries {
+		if options.Settings != nil && options.Settings.AlbumDirectives {
+			if key := entry.Track.AlbumArtist + "\x00" + entry.Track.Album; i == 0 || key != album {
+				album = key
+				if err := writeAlbumDirectives(w, entry.Track); err != nil {
+					return err
+				}
+			}
+		}
+
+		var err error
+		if options.Settings != nil && options.Settings.ExtinfTemplate != "" {
+			_, err = fmt.Fprintf(w, templateEntryString, entry.Track.TotalTime/1000, expandExtinfTemplate(options.Settings.ExtinfTemplate, entry, playlist), entry.Location)
+		} else {
+			_, err = fmt.Fprintf(w, entryString, entry.Track.TotalTime/1000, entry.Track.Artist, entry.Track.Title(), entry.Location)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportHLS writes playlist as an RFC 8216 media playlist, which browsers and AVPlayer can
+// stream from a web server: each track is a segment with its duration, and the entries are
+// URI references.
+func exportHLS(w io.Writer, playlist Playlist, _ Options) error {
+	targetDuration := 1
+	for _, entry := range playlist.Entries {
+		if seconds := hlsDuration(entry.Track); seconds > targetDuration {
+			targetDuration = seconds
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:%v\n#EXT-X-PLAYLIST-TYPE:VOD\n", targetDuration)
+	for _, entry := range playlist.Entries {
+		title := strings.Join(strings.Fields(entry.Track.Artist+" - "+entry.Track.Title()), " ")
+		fmt.Fprintf(&out, "#EXTINF:%v,%v\n%v\n", hlsDuration(entry.Track), title, uriReference(entry.Location))
+	}
+	out.WriteString("#EXT-X-ENDLIST\n")
+	_, err := out.WriteTo(w)
+	return err
+}
+
+// hlsDuration returns the duration of track in whole seconds, rounded up, as the target
+// duration of a media playlist must not be less than the duration of any segment.
+func hlsDuration(track *itunes.Track) int {
+	return (track.TotalTime + 999) / 1000
+}
+
+// uriReference returns the location of an entry as URI reference: relative paths are
+// percent-encoded with slashes, absolute paths become file URLs and URLs are kept.
+func uriReference(location string) string {
+	if strings.Contains(location, "://") {
+		return location
+	}
+	slashed := strings.Replace(location, "\\", "/", -1)
+	if strings.HasPrefix(slashed, "/") || (len(slashed) >= 2 && slashed[1] == ':') {
+		return itunes.LocationURL(slashed)
+	}
+	return (&url.URL{Path: slashed}).String()
+}
+
+type xspfPlaylist struct {
+	XMLName   xml.Name `xml:"http://xspf.org/ns/0/ playlist"`
+	Version   string   `xml:"version,attr"`
+	Title     string   `xml:"title"`
+	TrackList struct {
+		Tracks []xspfTrack `xml:"track"`
+	} `xml:"trackList"`
+}
+
+type xspfTrack struct {
+	Location string `xml:"location"`
+	Title    string `xml:"title,omitempty"`
+	Creator  string `xml:"creator,omitempty"`
+	Album    string `xml:"album,omitempty"`
+	TrackNum int    `xml:"trackNum,omitempty"`
+	// Duration is in milliseconds.
+	Duration int `xml:"duration,omitempty"`
+}
+
+// xspfNamespace is the XML namespace of XSPF playlists.
+const xspfNamespace = "http://xspf.org/ns/0/"
+
+// xspfExporter writes XSPF playlists, the entries as URI references.
+type xspfExporter struct{}
+
+func (xspfExporter) Export(w io.Writer, playlist Playlist, _ Options) error {
+	xspf := xspfPlaylist{Version: "1", Title: playlist.Name}
+	for _, entry := range playlist.Entries {
+		track := entry.Track
+		xspf.TrackList.Tracks = append(xspf.TrackList.Tracks, xspfTrack{
+			Location: uriReference(entry.Location),
+			Title:    track.Title(),
+			Creator:  track.Artist,
+			Album:    track.Album,
+			TrackNum: track.TrackNumber,
+			Duration: track.TotalTime,
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(xspf); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// Validate checks that content is a well-formed XSPF playlist with a track list and a
+// location for every track.
+func (xspfExporter) Validate(content []byte) error {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+
+	var elements []string
+	seenTrackList, seenLocation := false, false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			if len(elements) == 0 && (token.Name.Local != "playlist" || token.Name.Space != xspfNamespace) {
+				return fmt.Errorf("unexpected root element %v", token.Name.Local)
+			}
+			elements = append(elements, token.Name.Local)
+			switch strings.Join(elements, "/") {
+			case "playlist/trackList":
+				seenTrackList = true
+			case "playlist/trackList/track":
+				seenLocation = false
+			case "playlist/trackList/track/location":
+				seenLocation = true
+			}
+		case xml.EndElement:
+			if strings.Join(elements, "/") == "playlist/trackList/track" && !seenLocation {
+				return fmt.Errorf("track element without location at offset %v", decoder.InputOffset())
+			}
+			elements = elements[:len(elements)-1]
+		}
+	}
+
+	if !seenTrackList {
+		return errors.New("missing trackList element")
+	}
+	return nil
+}
+
+// writeAlbumDirectives writes the #EXTALB, #EXTART and #EXTGENRE lines of the album of
+// track. Lines without a value are left out.
+func writeAlbumDirectives(w io.Writer, track *itunes.Track) error {
+	artist := track.AlbumArtist
+	if artist == "" {
+		artist = track.Artist
+	}
+	for _, directive := range []struct{ name, value string }{
+		{"#EXTALB", track.Album},
+		{"#EXTART", artist},
+		{"#EXTGENRE", track.Genre},
+	} {
+		value := strings.Join(strings.Fields(directive.value), " ")
+		if value == "" {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%v:%v\n", directive.name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// smilExporter writes the SMIL based WPL and ZPL formats.
+type smilExporter struct {
+	// processingInstruction identifies the format, e.g. wpl.
+	processingInstruction string
+	// meta is written to the head of the playlist.
+	meta string
+}
+
+var (
+	wplExporter = smilExporter{processingInstruction: "wpl"}
+	zplExporter = smilExporter{processingInstruction: "zpl", meta: `<meta name="Generator" content="Zune -- 1.3.5728.0" />`}
+)
+
+func (exporter smilExporter) Export(w io.Writer, playlist Playlist, _ Options) error {
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "<?%v version=\"1.0\"?>\n<smil>\n  <head>\n", exporter.processingInstruction)
+	if exporter.meta != "" {
+		fmt.Fprintf(&out, "    %v\n", exporter.meta)
+	}
+	fmt.Fprintf(&out, "    <author />\n    <title>%v</title>\n  </head>\n  <body>\n    <seq>\n", xmlEscape(playlist.Name))
+
+	for _, entry := range playlist.Entries {
+		fmt.Fprintf(&out, "      <media src=\"%v\" />\n", xmlEscape(entry.Location))
+	}
+
+	out.WriteString("    </seq>\n  </body>\n</smil>\n")
+	_, err := out.WriteTo(w)
+	return err
+}
+
+// Validate checks that content is a well-formed SMIL playlist with a title and a
+// source for every media element.
+func (exporter smilExporter) Validate(content []byte) error {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+
+	var elements []string
+	seenTitle, seenSeq := false, false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		switch token := token.(type) {
+		case xml.ProcInst:
+			if len(elements) == 0 && token.Target != "xml" && token.Target != exporter.processingInstruction {
+				return fmt.Errorf("unexpected processing instruction %v", token.Target)
+			}
+		case xml.StartElement:
+			elements = append(elements, token.Name.Local)
+			path := strings.Join(elements, "/")
+			switch path {
+			case "smil/head/title":
+				seenTitle = true
+			case "smil/body/seq":
+				seenSeq = true
+			case "smil/body/seq/media":
+				if src := xmlAttr(token, "src"); src == "" {
+					return fmt.Errorf("media element without src at offset %v", decoder.InputOffset())
+				}
+			}
+			if elements[0] != "smil" {
+				return fmt.Errorf("unexpected root element %v", elements[0])
+			}
+		case xml.EndElement:
+			elements = elements[:len(elements)-1]
+		}
+	}
+
+	if !seenTitle || !seenSeq {
+		return errors.New("missing title or seq element")
+	}
+	return nil
+}
+
+func xmlAttr(element xml.StartElement, name string) string {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+func xmlEscape(s string) string {
+	var escaped strings.Builder
+	xml.EscapeText(&escaped, []byte(s))
+	return escaped.String()
+}
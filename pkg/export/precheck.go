@@ -0,0 +1,73 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+// precheckSources opens the music files of the tracks of all playlists copied with
+// exportSettings, each once, before anything is copied. It prints the files that can not be
+// read with the reason and returns an error if there are any.
+func precheckSources(exportSettings *ExportSettings) error {
+	unreadable := newSkippedFiles()
+	checked := make(map[string]bool)
+	for _, playlist := range exportSettings.Playlists {
+		if playlist.Container() {
+			continue
+		}
+		settings, err := exportSettings.forPlaylist(playlist.Name)
+		if err != nil {
+			return err
+		}
+		if settings.CopyType == COPY_NONE {
+			continue
+		}
+		for _, track := range playlist.Tracks(settings.Library) {
+			track, clean := cleanTrack(settings, track)
+			if !clean || track.TrackType == "URL" || !includeTrack(settings, &track) {
+				continue
+			}
+			source := musicSource(settings, itunes.LocationPath(track.Location))
+			if checked[source] {
+				continue
+			}
+			checked[source] = true
+			if err := checkSource(source); err != nil {
+				unreadable.add(source, err)
+			}
+		}
+	}
+
+	Logf(LOG_INFO, "Checked %v music files.\n", len(checked))
+	if unreadable.count() > 0 {
+		unreadable.print("music files can not be read")
+		return fmt.Errorf("%v music files can not be read, nothing was copied", unreadable.count())
+	}
+	return nil
+}
+
+// checkSource returns an error unless the music file at source can be copied: it must be a
+// regular, non-empty file whose content can be read.
+func checkSource(source string) error {
+	name, info, err := itunes.StatPath(strings.Replace(source, "file://", "", 1))
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return errors.New("source file is not a regular file")
+	}
+	if info.Size() == 0 {
+		return errEmptySource
+	}
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Read(make([]byte, 1))
+	return err
+}
@@ -0,0 +1,43 @@
+package export
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+func TestPrecheckSources(t *testing.T) {
+	outputDir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(outputDir)
+
+	musicFile, musicFileName := prepareMusicFile(t)
+	defer os.Remove(musicFile)
+	empty := filepath.Join(outputDir, "empty.mp3")
+	writeLocalFile(t, empty, "")
+
+	library := testLibrary()
+	library.Tracks["1"] = itunes.Track{TrackId: 1, Name: "Song", Location: "file://" + filepath.ToSlash(musicFile)}
+	library.Tracks["2"] = itunes.Track{TrackId: 2, Name: "Empty", Location: "file://" + filepath.ToSlash(empty)}
+	library.Tracks["3"] = itunes.Track{TrackId: 3, Name: "Missing", Location: "file:///nonexistent/missing.mp3"}
+	library.Tracks["4"] = itunes.Track{TrackId: 4, Name: "Radio", TrackType: "URL", Location: "http://radio.example.com/live.mp3"}
+	library.Playlists[0].PlaylistItems = []itunes.PlaylistItem{{TrackId: 1}, {TrackId: 2}, {TrackId: 3}, {TrackId: 4}}
+
+	settings := &ExportSettings{Library: library, Playlists: library.Playlists, OutputPath: outputDir, CopyType: COPY_FLAT, MusicDir: "Music", Precheck: true}
+	ParseExportType(settings, M3U)
+	if err := ExportPlaylists(settings, library); err == nil {
+		t.Fatal("expected the export to fail for the unreadable files")
+	}
+	if files, _ := ioutil.ReadDir(filepath.Join(outputDir, "Music")); len(files) > 0 {
+		t.Fatalf("expected nothing to be copied, got %v", files)
+	}
+
+	library.Playlists[0].PlaylistItems = []itunes.PlaylistItem{{TrackId: 1}, {TrackId: 4}}
+	settings.Playlists = library.Playlists
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+	assertPathExists(t, filepath.Join(outputDir, "Music", musicFileName))
+}
@@ -0,0 +1,64 @@
+package export
+
+import (
+	"errors"
+	"strings"
+)
+
+// Profile is a combination of settings known to work with a kind of device, most of all car
+// stereos that play music from USB sticks or SD cards.
+type Profile struct {
+	Name               string
+	ExportType         string
+	CopyType           int
+	PathStyle          int
+	Encoding           int
+	MaxFilenameLength  int
+	MaxFolderDepth     int
+	MaxPlaylistEntries int
+	StripSymbols       bool
+}
+
+// Profiles are the known device profiles, collected from the manuals and forum reports of
+// their users. Their limits are conservative, newer models often support more.
+var Profiles = []Profile{
+	// Kenwood head units read M3U playlists with Windows paths in the code page of the
+	// display, show 64 characters of names and stop reading playlists after 999 entries.
+	// Like other head units, some models crash on emoji in names.
+	{Name: "kenwood", ExportType: EXT, CopyType: COPY_ITUNES, PathStyle: PATH_STYLE_RELATIVE_BACKSLASH,
+		Encoding: ENCODING_CP1252, MaxFilenameLength: 64, MaxFolderDepth: 8, MaxPlaylistEntries: 999, StripSymbols: true},
+	// Pioneer head units play up to eight folder levels and skip playlists with entries
+	// they cannot read, which includes UTF-8 names.
+	{Name: "pioneer", ExportType: EXT, CopyType: COPY_ITUNES, PathStyle: PATH_STYLE_RELATIVE_BACKSLASH,
+		Encoding: ENCODING_CP1252, MaxFilenameLength: 64, MaxFolderDepth: 8, StripSymbols: true},
+	// The Volkswagen RNS navigation systems only read plain M3U playlists of up to 1000
+	// entries and files at most seven folders deep.
+	{Name: "vwRNS", ExportType: M3U, CopyType: COPY_ITUNES, PathStyle: PATH_STYLE_RELATIVE_BACKSLASH,
+		Encoding: ENCODING_CP1252, MaxFilenameLength: 64, MaxFolderDepth: 7, MaxPlaylistEntries: 1000, StripSymbols: true},
+	// Any other device reading a FAT32 formatted medium needs relative paths and names
+	// the file system can store.
+	{Name: "genericFat32", ExportType: EXT, CopyType: COPY_ITUNES, PathStyle: PATH_STYLE_RELATIVE,
+		Encoding: ENCODING_UTF8, MaxFilenameLength: 255, MaxFolderDepth: 8},
+}
+
+// ApplyProfile sets the fields of settings that make up the profile with the given name
+// (case insensitive).
+func ApplyProfile(settings *ExportSettings, name string) error {
+	for _, profile := range Profiles {
+		if !strings.EqualFold(profile.Name, name) {
+			continue
+		}
+		if err := ParseExportType(settings, profile.ExportType); err != nil {
+			return err
+		}
+		settings.CopyType = profile.CopyType
+		settings.PathStyle = profile.PathStyle
+		settings.Encoding = profile.Encoding
+		settings.MaxFilenameLength = profile.MaxFilenameLength
+		settings.MaxFolderDepth = profile.MaxFolderDepth
+		settings.MaxPlaylistEntries = profile.MaxPlaylistEntries
+		settings.StripSymbols = profile.StripSymbols
+		return nil
+	}
+	return errors.New("Unknown Profile: " + name)
+}
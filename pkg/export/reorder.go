@@ -0,0 +1,130 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Renamer is implemented by file systems that can rename files.
+type Renamer interface {
+	Rename(oldName, newName string) error
+}
+
+// reorderDir is the directory files are moved to while the entries of their directory are
+// rewritten.
+const reorderDir = ".reorder"
+
+var errRenameUnsupported = errors.New("the output can not rename files")
+
+// copyOrder records the music files of an export in the order they are exported.
+type copyOrder struct {
+	mutex sync.Mutex
+	files []string
+	seen  map[string]bool
+}
+
+func newCopyOrder() *copyOrder {
+	return &copyOrder{seen: make(map[string]bool)}
+}
+
+func (order *copyOrder) add(name string) {
+	order.mutex.Lock()
+	defer order.mutex.Unlock()
+	if !order.seen[name] {
+		order.seen[name] = true
+		order.files = append(order.files, name)
+	}
+}
+
+// reorderDirectories rewrites the entries of the directories of the files recorded, in the
+// order they were exported or, if byName is set, sorted by name with subdirectories first.
+// The entries of each directory are moved out of it and back one by one, so file systems
+// like FAT, which fill the first free entry, list them in that order.
+func (order *copyOrder) reorderDirectories(fsys FS, byName bool) error {
+	renamer, ok := fsys.(Renamer)
+	if !ok {
+		return errRenameUnsupported
+	}
+
+	order.mutex.Lock()
+	defer order.mutex.Unlock()
+
+	var dirs []string
+	entries := make(map[string][]string)
+	added := make(map[string]bool)
+	add := func(name string) {
+		dir := path.Dir(name)
+		if entries[dir] == nil {
+			dirs = append(dirs, dir)
+		}
+		entries[dir] = append(entries[dir], name)
+		added[name] = true
+	}
+	for _, name := range order.files {
+		if _, err := fsys.Stat(name); err != nil {
+			// The copy failed.
+			continue
+		}
+		add(name)
+		if byName {
+			for dir := path.Dir(name); dir != "." && dir != "/" && !added[dir]; dir = path.Dir(dir) {
+				add(dir)
+			}
+		}
+	}
+
+	for _, dir := range dirs {
+		names := entries[dir]
+		if len(names) < 2 {
+			continue
+		}
+		if byName {
+			sort.SliceStable(names, func(i, j int) bool {
+				iDir, jDir := entries[names[i]] != nil, entries[names[j]] != nil
+				if iDir != jDir {
+					return iDir
+				}
+				return strings.ToLower(names[i]) < strings.ToLower(names[j])
+			})
+		}
+		temp := path.Join(dir, reorderDir)
+		if err := fsys.MkdirAll(temp); err != nil {
+			return err
+		}
+		for _, name := range names {
+			if err := renamer.Rename(name, path.Join(temp, path.Base(name))); err != nil {
+				return err
+			}
+		}
+		for _, name := range names {
+			if err := renamer.Rename(path.Join(temp, path.Base(name)), name); err != nil {
+				return fmt.Errorf("unable to move %v back from %v: %v", name, temp, err)
+			}
+		}
+		if err := fsys.Remove(temp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (fsys *DirFS) Rename(oldName, newName string) error {
+	if err := os.MkdirAll(filepath.Dir(fsys.path(newName)), 0777); err != nil {
+		return err
+	}
+	return os.Rename(fsys.path(oldName), fsys.path(newName))
+}
+
+func (fsys *checksumFS) Rename(oldName, newName string) error {
+	renamer, ok := fsys.FS.(Renamer)
+	if !ok {
+		return errRenameUnsupported
+	}
+	return renamer.Rename(oldName, newName)
+}
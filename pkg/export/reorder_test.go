@@ -0,0 +1,86 @@
+package export
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// recordingFS is a DirFS recording the files renamed.
+type recordingFS struct {
+	*DirFS
+	renamed []string
+}
+
+func (fsys *recordingFS) Rename(oldName, newName string) error {
+	fsys.renamed = append(fsys.renamed, newName)
+	return fsys.DirFS.Rename(oldName, newName)
+}
+
+func TestReorderDirectories(t *testing.T) {
+	outputDir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(outputDir)
+
+	for _, name := range []string{"b.mp3", "a.mp3", "single/c.mp3"} {
+		os.MkdirAll(filepath.Dir(filepath.Join(outputDir, name)), 0777)
+		writeLocalFile(t, filepath.Join(outputDir, name), FileContent)
+	}
+
+	order := newCopyOrder()
+	for _, name := range []string{"b.mp3", "a.mp3", "b.mp3", "missing.mp3", "single/c.mp3"} {
+		order.add(name)
+	}
+	fsys := &recordingFS{DirFS: NewDirFS(outputDir)}
+	if err := order.reorderDirectories(fsys, false); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{".reorder/b.mp3", ".reorder/a.mp3", "b.mp3", "a.mp3"}
+	if len(fsys.renamed) != len(expected) {
+		t.Fatalf("expected renames %v, got %v", expected, fsys.renamed)
+	}
+	for i, name := range expected {
+		if fsys.renamed[i] != name {
+			t.Fatalf("expected renames %v, got %v", expected, fsys.renamed)
+		}
+	}
+	files, err := ioutil.ReadDir(outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("expected the temporary directory to be removed, got %v", files)
+	}
+
+	if err := order.reorderDirectories(NewMemFS(), false); err != errRenameUnsupported {
+		t.Fatalf("expected error for a file system without renames, got %v", err)
+	}
+}
+
+func TestSortDirectories(t *testing.T) {
+	outputDir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(outputDir)
+
+	order := newCopyOrder()
+	for _, name := range []string{"b.mp3", "Zed/c.mp3", "Alpha/d.mp3", "A.m3u"} {
+		os.MkdirAll(filepath.Dir(filepath.Join(outputDir, name)), 0777)
+		writeLocalFile(t, filepath.Join(outputDir, name), FileContent)
+		order.add(name)
+	}
+	fsys := &recordingFS{DirFS: NewDirFS(outputDir)}
+	if err := order.reorderDirectories(fsys, true); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{".reorder/Alpha", ".reorder/Zed", ".reorder/A.m3u", ".reorder/b.mp3", "Alpha", "Zed", "A.m3u", "b.mp3"}
+	if len(fsys.renamed) != len(expected) {
+		t.Fatalf("expected renames %v, got %v", expected, fsys.renamed)
+	}
+	for i, name := range expected {
+		if fsys.renamed[i] != name {
+			t.Fatalf("expected renames %v, got %v", expected, fsys.renamed)
+		}
+	}
+	assertPathExists(t, filepath.Join(outputDir, "Zed", "c.mp3"))
+}
@@ -0,0 +1,65 @@
+package export
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// SpaceReporter is implemented by file systems that know the free space of their volume.
+type SpaceReporter interface {
+	FreeSpace() (int64, error)
+}
+
+// errReserve is returned for copies that would use the space reserved by ExportSettings.Reserve.
+var errReserve = errors.New("not enough free space above the reserve")
+
+// spaceBudget is the space the copies of an export may use, shared by all playlists.
+type spaceBudget struct {
+	mutex     sync.Mutex
+	available int64
+}
+
+// newSpaceBudget returns the budget of the free space of fsys above reserve, or nil
+// without a reserve.
+func newSpaceBudget(fsys FS, reserve int64) (*spaceBudget, error) {
+	if reserve <= 0 {
+		return nil, nil
+	}
+	reporter, ok := fsys.(SpaceReporter)
+	if !ok {
+		return nil, errors.New("the output does not report its free space")
+	}
+	free, err := reporter.FreeSpace()
+	if err != nil {
+		return nil, err
+	}
+	return &spaceBudget{available: free - reserve}, nil
+}
+
+// take uses n bytes of the budget. It returns false, using nothing, if less is left.
+func (budget *spaceBudget) take(n int64) bool {
+	budget.mutex.Lock()
+	defer budget.mutex.Unlock()
+	if n > budget.available {
+		return false
+	}
+	budget.available -= n
+	return true
+}
+
+// FreeSpace returns the space available on the volume of the directory, or of its closest
+// existing parent if it does not exist yet.
+func (fsys *DirFS) FreeSpace() (int64, error) {
+	dir, err := filepath.Abs(fsys.root)
+	if err != nil {
+		return 0, err
+	}
+	for {
+		if _, err := os.Stat(dir); err == nil || filepath.Dir(dir) == dir {
+			return freeSpace(dir)
+		}
+		dir = filepath.Dir(dir)
+	}
+}
@@ -0,0 +1,52 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// reportingFS is a MemFS with a fixed amount of free space.
+type reportingFS struct {
+	*MemFS
+	free int64
+}
+
+func (fsys *reportingFS) FreeSpace() (int64, error) {
+	return fsys.free, nil
+}
+
+func TestExportKeepsReserveFree(t *testing.T) {
+	musicFile, musicFileName := prepareMusicFile(t)
+	defer os.Remove(musicFile)
+
+	library := testLibrary()
+	track := library.Tracks["1"]
+	track.Location = "file://" + filepath.ToSlash(musicFile)
+	library.Tracks["1"] = track
+
+	fsys := &reportingFS{MemFS: NewMemFS(), free: 1000 + int64(len(FileContent)) - 1}
+	settings := &ExportSettings{Library: library, Playlists: library.Playlists, Output: fsys, CopyType: COPY_FLAT, Reserve: 1000}
+	ParseExportType(settings, M3U)
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fsys.Stat(musicFileName); err == nil {
+		t.Fatal("expected the track not to be copied into the reserve")
+	}
+
+	fsys.free++
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fsys.Stat(musicFileName); err != nil {
+		t.Fatalf("expected the track to fit above the reserve: %v", err)
+	}
+
+	if err := ExportPlaylists(&ExportSettings{Library: library, Playlists: library.Playlists, Output: NewMemFS(), Reserve: 1000}, library); err == nil {
+		t.Fatal("expected error for an output without free space information")
+	}
+	if free, err := NewDirFS(filepath.Join(os.TempDir(), "does", "not", "exist")).FreeSpace(); err != nil || free <= 0 {
+		t.Fatalf("expected the free space of the closest existing directory, got %v, %v", free, err)
+	}
+}
@@ -0,0 +1,58 @@
+package export
+
+import (
+	"bufio"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// RockboxMusicDir is the directory music is copied to on a Rockbox device.
+	RockboxMusicDir = "Music"
+	// RockboxPlaylistDir is the directory Rockbox opens playlists from by default.
+	RockboxPlaylistDir = "Playlists"
+)
+
+// rockboxDir is the directory of the Rockbox installation and configuration.
+const rockboxDir = ".rockbox"
+
+// CheckRockbox returns an error unless root is the root directory of a device with Rockbox
+// installed, e.g. the mount point of an iPod.
+func CheckRockbox(root string) error {
+	info, err := os.Stat(filepath.Join(root, rockboxDir))
+	if err != nil || !info.IsDir() {
+		return errors.New("Rockbox is not installed on " + root + ", expected a " + rockboxDir + " directory")
+	}
+	return nil
+}
+
+// EnableRockboxDatabaseUpdate turns on the database auto update in the configuration of
+// the Rockbox device at root, so the database is updated with the copied music the next
+// time the device starts.
+func EnableRockboxDatabaseUpdate(root string) error {
+	config := filepath.Join(root, rockboxDir, "config.cfg")
+	content, err := ioutil.ReadFile(config)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	const setting = "tagcache_autoupdate"
+	var lines []string
+	found := false
+	scanner := bufio.NewScanner(strings.NewReader(string(content)))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if name := strings.SplitN(line, ":", 2)[0]; strings.TrimSpace(name) == setting {
+			line = setting + ": on"
+			found = true
+		}
+		lines = append(lines, line)
+	}
+	if !found {
+		lines = append(lines, setting+": on")
+	}
+	return ioutil.WriteFile(config, []byte(strings.Join(lines, "\n")+"\n"), 0666)
+}
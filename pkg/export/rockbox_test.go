@@ -0,0 +1,76 @@
+package export
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnableRockboxDatabaseUpdate(t *testing.T) {
+	root, err := ioutil.TempDir("", "rockbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	if err := CheckRockbox(root); err == nil {
+		t.Fatal("expected a directory without .rockbox not to be a Rockbox device")
+	}
+	if err := os.Mkdir(filepath.Join(root, ".rockbox"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckRockbox(root); err != nil {
+		t.Fatal(err)
+	}
+
+	config := filepath.Join(root, ".rockbox", "config.cfg")
+	if err := EnableRockboxDatabaseUpdate(root); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := ioutil.ReadFile(config); string(content) != "tagcache_autoupdate: on\n" {
+		t.Fatalf("unexpected new configuration %q", content)
+	}
+
+	ioutil.WriteFile(config, []byte("volume: -25\ntagcache_autoupdate: off\ntagcache_ram: on\n"), 0666)
+	if err := EnableRockboxDatabaseUpdate(root); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := ioutil.ReadFile(config); string(content) != "volume: -25\ntagcache_autoupdate: on\ntagcache_ram: on\n" {
+		t.Fatalf("unexpected updated configuration %q", content)
+	}
+}
+
+func TestExportToRockboxLayout(t *testing.T) {
+	outputDir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(outputDir)
+
+	musicFile, musicFileName := prepareMusicFile(t)
+	defer os.Remove(musicFile)
+
+	library := testLibrary()
+	track := library.Tracks["1"]
+	track.Album = "Some Album"
+	track.Location = "file://" + filepath.ToSlash(musicFile)
+	library.Tracks["1"] = track
+
+	settings := &ExportSettings{
+		Library:     library,
+		Playlists:   library.Playlists,
+		CopyType:    COPY_ITUNES,
+		Output:      NewDirFS(outputDir),
+		OutputPath:  "/",
+		PlaylistDir: RockboxPlaylistDir,
+		MusicDir:    RockboxMusicDir,
+	}
+	ParseExportType(settings, M3U8)
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+
+	assertPathExists(t, filepath.Join(outputDir, "Music", "Some Artist", "Some Album", musicFileName))
+	expected := "#EXTM3U\n#EXTINF:210,Some Artist - Some Song\n" + filepath.FromSlash("/Music/Some Artist/Some Album/"+musicFileName) + "\n"
+	if content := readFile(t, filepath.Join(outputDir, "Playlists", "My Playlist.m3u8")); content != expected {
+		t.Fatalf("expected playlist %q, got %q", expected, content)
+	}
+}
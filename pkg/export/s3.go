@@ -0,0 +1,376 @@
+package export
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// s3PartSize is the size of the parts of a multipart upload. Smaller files are uploaded
+	// with a single request.
+	s3PartSize = 8 * 1024 * 1024
+	// s3Attempts is the number of times a request is tried before giving up.
+	s3Attempts = 4
+)
+
+// S3FS writes the export to a bucket of Amazon S3 or a compatible object storage, e.g.
+// MinIO or Backblaze B2.
+type S3FS struct {
+	bucket    string
+	prefix    string
+	endpoint  *url.URL
+	region    string
+	accessKey string
+	secretKey string
+	token     string
+	client    *http.Client
+	partSize  int
+	retryWait time.Duration
+	now       func() time.Time
+}
+
+// NewS3FS returns an FS writing below the prefix of an s3://bucket/prefix URL. The
+// credentials are read from the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and optional
+// AWS_SESSION_TOKEN environment variables, the region from AWS_REGION or AWS_DEFAULT_REGION
+// and defaults to us-east-1.
+//
+// If endpoint is set, e.g. http://localhost:9000 for MinIO, requests are sent to it using
+// path style URLs instead of to Amazon S3.
+func NewS3FS(target *url.URL, endpoint string) (*S3FS, error) {
+	fsys := &S3FS{
+		bucket:    target.Host,
+		prefix:    strings.Trim(target.Path, "/"),
+		region:    os.Getenv("AWS_REGION"),
+		accessKey: os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		token:     os.Getenv("AWS_SESSION_TOKEN"),
+		client:    &http.Client{},
+		partSize:  s3PartSize,
+		retryWait: time.Second,
+		now:       time.Now,
+	}
+	if fsys.bucket == "" {
+		return nil, errors.New("missing bucket in S3 URL " + target.String())
+	}
+	if fsys.region == "" {
+		fsys.region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if fsys.region == "" {
+		fsys.region = "us-east-1"
+	}
+	if fsys.accessKey == "" || fsys.secretKey == "" {
+		return nil, errors.New("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set to write to S3")
+	}
+
+	if endpoint != "" {
+		endpointURL, err := url.Parse(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		if endpointURL.Scheme == "" || endpointURL.Host == "" {
+			return nil, errors.New("invalid S3 endpoint " + endpoint)
+		}
+		fsys.endpoint = endpointURL
+	}
+	return fsys, nil
+}
+
+// objectURL returns the URL of the object with the key, with the query parameters.
+func (fsys *S3FS) objectURL(key string, query url.Values) *url.URL {
+	objectURL := &url.URL{Scheme: "https", Host: fsys.bucket + ".s3." + fsys.region + ".amazonaws.com", Path: "/" + key}
+	if fsys.endpoint != nil {
+		objectURL = &url.URL{Scheme: fsys.endpoint.Scheme, Host: fsys.endpoint.Host,
+			Path: path.Join("/", fsys.endpoint.Path, fsys.bucket) + "/" + key}
+	}
+	objectURL.RawPath = s3EscapePath(objectURL.Path)
+	objectURL.RawQuery = query.Encode()
+	return objectURL
+}
+
+// s3EscapePath escapes all characters of a path except unreserved characters and slashes,
+// as required by the signature.
+func s3EscapePath(p string) string {
+	var escaped strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-._~/", c) >= 0 {
+			escaped.WriteByte(c)
+		} else {
+			fmt.Fprintf(&escaped, "%%%02X", c)
+		}
+	}
+	return escaped.String()
+}
+
+func (fsys *S3FS) key(name string) string {
+	return strings.TrimPrefix(path.Join(fsys.prefix, name), "/")
+}
+
+// request sends a signed request for the object with the key. Requests failing because of a
+// network problem or a server error are retried with increasing delays. Responses with a
+// status other than 2xx are returned as error, after closing the body.
+func (fsys *S3FS) request(method, key string, query url.Values, body []byte) (*http.Response, error) {
+	wait := fsys.retryWait
+	for attempt := 1; ; attempt++ {
+		request, err := http.NewRequest(method, fsys.objectURL(key, query).String(), bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		if body == nil {
+			request.Body, request.GetBody = nil, nil
+			request.ContentLength = 0
+		}
+		fsys.sign(request, body)
+
+		response, err := fsys.client.Do(request)
+		if err == nil && response.StatusCode >= 200 && response.StatusCode <= 299 {
+			return response, nil
+		}
+		retry := err != nil || response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests
+		if err == nil {
+			err = s3Error(method, key, response)
+		}
+		if !retry || attempt == s3Attempts {
+			return nil, err
+		}
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+// s3Error returns the error of a failed response and closes its body.
+func s3Error(method, key string, response *http.Response) error {
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotFound {
+		return &os.PathError{Op: strings.ToLower(method), Path: key, Err: os.ErrNotExist}
+	}
+
+	var s3Err struct {
+		Code    string
+		Message string
+	}
+	content, _ := ioutil.ReadAll(io.LimitReader(response.Body, 64*1024))
+	if xml.Unmarshal(content, &s3Err) == nil && s3Err.Code != "" {
+		return &os.PathError{Op: strings.ToLower(method), Path: key, Err: fmt.Errorf("S3 error %v: %v", s3Err.Code, s3Err.Message)}
+	}
+	return &os.PathError{Op: strings.ToLower(method), Path: key, Err: fmt.Errorf("S3 responded %v", response.Status)}
+}
+
+// sign adds the AWS Signature Version 4 authorization to a request. All headers set on the
+// request are signed.
+func (fsys *S3FS) sign(request *http.Request, body []byte) {
+	now := fsys.now().UTC()
+	date, amzDate := now.Format("20060102"), now.Format("20060102T150405Z")
+	payloadHash := sha256.Sum256(body)
+
+	request.Header.Set("X-Amz-Date", amzDate)
+	request.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+	if fsys.token != "" {
+		request.Header.Set("X-Amz-Security-Token", fsys.token)
+	}
+
+	headers := map[string]string{"host": request.URL.Host}
+	for name, values := range request.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		request.URL.EscapedPath(),
+		strings.Replace(request.URL.Query().Encode(), "+", "%20", -1),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := date + "/" + fsys.region + "/s3/aws4_request"
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	key := []byte("AWS4" + fsys.secretKey)
+	for _, part := range []string{date, fsys.region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	request.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+fsys.accessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// Create buffers the file and uploads it on Close. Files larger than the part size are
+// uploaded in parts while they are written.
+func (fsys *S3FS) Create(name string) (File, error) {
+	return &s3File{fsys: fsys, key: fsys.key(name)}, nil
+}
+
+func (fsys *S3FS) Open(name string) (io.ReadCloser, error) {
+	response, err := fsys.request(http.MethodGet, fsys.key(name), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return response.Body, nil
+}
+
+func (fsys *S3FS) Stat(name string) (os.FileInfo, error) {
+	response, err := fsys.request(http.MethodHead, fsys.key(name), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	response.Body.Close()
+
+	info := &remoteFileInfo{name: path.Base(name), size: response.ContentLength, mode: 0644}
+	info.modTime, _ = time.Parse(http.TimeFormat, response.Header.Get("Last-Modified"))
+	return info, nil
+}
+
+// MkdirAll does nothing, as object storage has no directories.
+func (fsys *S3FS) MkdirAll(name string) error {
+	return nil
+}
+
+func (fsys *S3FS) Remove(name string) error {
+	response, err := fsys.request(http.MethodDelete, fsys.key(name), nil, nil)
+	if err != nil {
+		return err
+	}
+	return response.Body.Close()
+}
+
+// s3File is a file being uploaded to an S3FS.
+type s3File struct {
+	fsys     *S3FS
+	key      string
+	buffer   []byte
+	uploadId string
+	parts    []s3Part
+}
+
+type s3Part struct {
+	PartNumber int
+	ETag       string
+}
+
+func (file *s3File) Write(p []byte) (int, error) {
+	file.buffer = append(file.buffer, p...)
+	for len(file.buffer) > file.fsys.partSize {
+		if err := file.uploadPart(file.buffer[:file.fsys.partSize]); err != nil {
+			return 0, err
+		}
+		file.buffer = append(file.buffer[:0], file.buffer[file.fsys.partSize:]...)
+	}
+	return len(p), nil
+}
+
+// uploadPart uploads the next part of a multipart upload, starting the upload if needed.
+func (file *s3File) uploadPart(data []byte) error {
+	if file.uploadId == "" {
+		response, err := file.fsys.request(http.MethodPost, file.key, url.Values{"uploads": {""}}, nil)
+		if err != nil {
+			return err
+		}
+		var result struct {
+			UploadId string
+		}
+		err = xml.NewDecoder(response.Body).Decode(&result)
+		response.Body.Close()
+		if err != nil {
+			return err
+		}
+		file.uploadId = result.UploadId
+	}
+
+	partNumber := len(file.parts) + 1
+	query := url.Values{"partNumber": {strconv.Itoa(partNumber)}, "uploadId": {file.uploadId}}
+	response, err := file.fsys.request(http.MethodPut, file.key, query, data)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	file.parts = append(file.parts, s3Part{PartNumber: partNumber, ETag: response.Header.Get("ETag")})
+	return nil
+}
+
+// Close uploads the file, or its last part and completes the multipart upload.
+func (file *s3File) Close() error {
+	if file.uploadId == "" {
+		response, err := file.fsys.request(http.MethodPut, file.key, nil, file.buffer)
+		if err != nil {
+			return err
+		}
+		return response.Body.Close()
+	}
+
+	if len(file.buffer) > 0 {
+		if err := file.uploadPart(file.buffer); err != nil {
+			file.Abort()
+			return err
+		}
+	}
+	complete, err := xml.Marshal(struct {
+		XMLName xml.Name `xml:"CompleteMultipartUpload"`
+		Parts   []s3Part `xml:"Part"`
+	}{Parts: file.parts})
+	if err != nil {
+		return err
+	}
+	response, err := file.fsys.request(http.MethodPost, file.key, url.Values{"uploadId": {file.uploadId}}, complete)
+	if err != nil {
+		file.Abort()
+		return err
+	}
+	// S3 may report an error of the completion with status 200.
+	content, err := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return err
+	}
+	if bytes.Contains(content, []byte("<Error>")) {
+		file.Abort()
+		return &os.PathError{Op: "put", Path: file.key, Err: errors.New("S3 failed to complete the upload")}
+	}
+	return nil
+}
+
+// Abort discards the buffer and the parts uploaded already.
+func (file *s3File) Abort() error {
+	file.buffer = nil
+	if file.uploadId == "" {
+		return nil
+	}
+	response, err := file.fsys.request(http.MethodDelete, file.key, url.Values{"uploadId": {file.uploadId}}, nil)
+	if err != nil {
+		return err
+	}
+	return response.Body.Close()
+}
@@ -0,0 +1,154 @@
+package export
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ParseExportType sets the playlist type and file extension of settings from the name
+// of a registered type, e.g. M3U, EXT, WPL or ZPL.
+func ParseExportType(settings *ExportSettings, exportType string) error {
+	name := strings.ToUpper(exportType)
+	format, ok := formats[name]
+	if !ok {
+		return errors.New("Unknown Export Type: " + exportType)
+	}
+	settings.ExportType = name
+	settings.Extension = format.extension
+	return nil
+}
+
+// ParseCopyType sets the copy type of settings from its name: NONE, PLAYLIST, ITUNES, FLAT or STORE.
+func ParseCopyType(settings *ExportSettings, copyType string) error {
+	switch strings.ToUpper(copyType) {
+	case "NONE":
+		settings.CopyType = COPY_NONE
+	case "PLAYLIST":
+		settings.CopyType = COPY_PLAYLIST
+	case "ITUNES":
+		settings.CopyType = COPY_ITUNES
+	case "FLAT":
+		settings.CopyType = COPY_FLAT
+	case "STORE":
+		settings.CopyType = COPY_STORE
+	case "VIDEO":
+		settings.CopyType = COPY_VIDEO
+	default:
+		return errors.New("Unknown Copy Type: " + copyType)
+	}
+	return nil
+}
+
+// ParsePathStyle sets the path style of settings from its name: ABSOLUTE, RELATIVE or
+// RELATIVEBACKSLASH.
+func ParsePathStyle(settings *ExportSettings, pathStyle string) error {
+	switch strings.ToUpper(pathStyle) {
+	case "ABSOLUTE":
+		settings.PathStyle = PATH_STYLE_ABSOLUTE
+	case "RELATIVE":
+		settings.PathStyle = PATH_STYLE_RELATIVE
+	case "RELATIVEBACKSLASH":
+		settings.PathStyle = PATH_STYLE_RELATIVE_BACKSLASH
+	default:
+		return errors.New("Unknown Path Style: " + pathStyle)
+	}
+	return nil
+}
+
+// ParseEncoding sets the encoding of the playlist files of settings from its name: UTF8,
+// UTF8BOM or CP1252.
+func ParseEncoding(settings *ExportSettings, encoding string) error {
+	switch strings.ToUpper(strings.Replace(encoding, "-", "", -1)) {
+	case "UTF8":
+		settings.Encoding = ENCODING_UTF8
+	case "UTF8BOM":
+		settings.Encoding = ENCODING_UTF8_BOM
+	case "CP1252", "WINDOWS1252":
+		settings.Encoding = ENCODING_CP1252
+	default:
+		return errors.New("Unknown Encoding: " + encoding)
+	}
+	return nil
+}
+
+// ParseNameCase sets the case of the names of the files of settings from its name: KEEP,
+// LOWER or UPPER.
+func ParseNameCase(settings *ExportSettings, nameCase string) error {
+	switch strings.ToUpper(nameCase) {
+	case "KEEP":
+		settings.NameCase = NAME_CASE_KEEP
+	case "LOWER":
+		settings.NameCase = NAME_CASE_LOWER
+	case "UPPER":
+		settings.NameCase = NAME_CASE_UPPER
+	default:
+		return errors.New("Unknown Name Case: " + nameCase)
+	}
+	return nil
+}
+
+// byteUnits are the multipliers of the units of byte sizes.
+var byteUnits = map[string]int64{
+	"":  1,
+	"B": 1,
+	"K": 1 << 10, "KB": 1 << 10,
+	"M": 1 << 20, "MB": 1 << 20,
+	"G": 1 << 30, "GB": 1 << 30,
+}
+
+// parseByteSize parses a number of bytes with an optional unit, e.g. 100MB. Units are
+// powers of 1024.
+func parseByteSize(size string) (int64, bool) {
+	value := strings.ToUpper(strings.TrimSpace(size))
+	number := strings.TrimRight(value, "BKMG")
+	unit, ok := byteUnits[value[len(number):]]
+	amount, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if !ok || err != nil || amount < 0 {
+		return 0, false
+	}
+	return int64(amount * float64(unit)), true
+}
+
+// ParseSize returns the number of bytes of a size with an optional unit, e.g. 16GB. Units are
+// powers of 1024.
+func ParseSize(size string) (int64, error) {
+	bytes, ok := parseByteSize(size)
+	if !ok {
+		return 0, errors.New("Unknown Size: " + size)
+	}
+	return bytes, nil
+}
+
+// ParseBandwidthLimit sets the bandwidth limit of settings from a number of bytes per second
+// with an optional unit, e.g. 512KB/s or 10MB/s. Units are powers of 1024. 0 removes the limit.
+func ParseBandwidthLimit(settings *ExportSettings, limit string) error {
+	bytesPerSecond, ok := parseByteSize(strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(limit)), "/S"))
+	if !ok {
+		return errors.New("Unknown Bandwidth Limit: " + limit)
+	}
+	settings.BandwidthLimit = bytesPerSecond
+	return nil
+}
+
+// ParseMaxFileSize sets the maximum size of the music files of settings from a number of
+// bytes with an optional unit, e.g. 100MB. 0 removes the limit.
+func ParseMaxFileSize(settings *ExportSettings, size string) error {
+	maxFileSize, ok := parseByteSize(size)
+	if !ok {
+		return errors.New("Unknown File Size: " + size)
+	}
+	settings.MaxFileSize = maxFileSize
+	return nil
+}
+
+// ParseReserve sets the space kept free on the output from a number of bytes with an
+// optional unit, e.g. 500MB. 0 keeps no space free.
+func ParseReserve(settings *ExportSettings, size string) error {
+	reserve, ok := parseByteSize(size)
+	if !ok {
+		return errors.New("Unknown Reserve: " + size)
+	}
+	settings.Reserve = reserve
+	return nil
+}
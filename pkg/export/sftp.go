@@ -0,0 +1,227 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// SFTPFS writes the export to a directory on a server using SFTP.
+type SFTPFS struct {
+	root    string
+	client  *sftpClient
+	closers []io.Closer
+}
+
+// NewSFTPFS connects to the server of an sftp://[user@]host[:port]/path URL. A path starting
+// with /~/ is relative to the home directory on the server.
+//
+// It authenticates with the keys of the running SSH agent and the unencrypted default keys in
+// ~/.ssh, and verifies the key of the server against ~/.ssh/known_hosts. Close must be called
+// once the export is complete.
+func NewSFTPFS(target *url.URL) (*SFTPFS, error) {
+	userName := target.User.Username()
+	if userName == "" {
+		current, err := user.Current()
+		if err != nil {
+			return nil, err
+		}
+		userName = current.Username
+	}
+
+	config, err := sshClientConfig(userName)
+	if err != nil {
+		return nil, err
+	}
+
+	address := target.Host
+	if target.Port() == "" {
+		address = net.JoinHostPort(target.Hostname(), "22")
+	}
+	connection, err := ssh.Dial("tcp", address, config)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := connection.NewSession()
+	if err != nil {
+		connection.Close()
+		return nil, err
+	}
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		connection.Close()
+		return nil, err
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		connection.Close()
+		return nil, err
+	}
+	if err := session.RequestSubsystem("sftp"); err != nil {
+		connection.Close()
+		return nil, err
+	}
+
+	client, err := newSFTPClient(stdout, stdin)
+	if err != nil {
+		connection.Close()
+		return nil, err
+	}
+
+	root := target.Path
+	if strings.HasPrefix(root, "/~/") || root == "/~" {
+		root = strings.TrimPrefix(strings.TrimPrefix(root, "/~"), "/")
+	}
+	return &SFTPFS{root: root, client: client, closers: []io.Closer{session, connection}}, nil
+}
+
+// sshClientConfig returns the configuration to connect to a server as userName.
+func sshClientConfig(userName string) (*ssh.ClientConfig, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+
+	hostKeyCallback, err := knownhosts.New(filepath.Join(home, ".ssh", "known_hosts"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to verify the key of the server: %v", err)
+	}
+
+	var auth []ssh.AuthMethod
+	if socket := os.Getenv("SSH_AUTH_SOCK"); socket != "" {
+		if connection, err := net.Dial("unix", socket); err == nil {
+			auth = append(auth, ssh.PublicKeysCallback(agent.NewClient(connection).Signers))
+		}
+	}
+
+	var signers []ssh.Signer
+	for _, name := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+		key, err := ioutil.ReadFile(filepath.Join(home, ".ssh", name))
+		if err != nil {
+			continue
+		}
+		// Encrypted keys can only be used through the agent.
+		if signer, err := ssh.ParsePrivateKey(key); err == nil {
+			signers = append(signers, signer)
+		}
+	}
+	if len(signers) > 0 {
+		auth = append(auth, ssh.PublicKeys(signers...))
+	}
+
+	if len(auth) == 0 {
+		return nil, errors.New("no SSH key found, add a key to the SSH agent or ~/.ssh")
+	}
+	return &ssh.ClientConfig{User: userName, Auth: auth, HostKeyCallback: hostKeyCallback, Timeout: 30 * time.Second}, nil
+}
+
+func (fsys *SFTPFS) path(name string) string {
+	return path.Join(fsys.root, name)
+}
+
+// tempFileCounter makes the names of temporary files unique.
+var tempFileCounter int64
+
+// Create writes to a temporary file which is renamed into place on Close.
+func (fsys *SFTPFS) Create(name string) (File, error) {
+	fileName := fsys.path(name)
+	if err := fsys.client.mkdirAll(path.Dir(fileName)); err != nil {
+		return nil, err
+	}
+
+	tempName := path.Join(path.Dir(fileName), fmt.Sprintf(".%v.%v.tmp", path.Base(fileName), atomic.AddInt64(&tempFileCounter, 1)))
+	handle, err := fsys.client.open(tempName, sftpOpenWrite|sftpOpenCreat|sftpOpenTrunc)
+	if err != nil {
+		return nil, err
+	}
+	return &sftpFile{client: fsys.client, handle: handle, tempName: tempName, name: fileName}, nil
+}
+
+func (fsys *SFTPFS) Open(name string) (io.ReadCloser, error) {
+	handle, err := fsys.client.open(fsys.path(name), sftpOpenRead)
+	if err != nil {
+		return nil, err
+	}
+	return &sftpFile{client: fsys.client, handle: handle}, nil
+}
+
+func (fsys *SFTPFS) Stat(name string) (os.FileInfo, error) {
+	return fsys.client.stat(fsys.path(name))
+}
+
+func (fsys *SFTPFS) MkdirAll(name string) error {
+	return fsys.client.mkdirAll(fsys.path(name))
+}
+
+func (fsys *SFTPFS) Remove(name string) error {
+	return fsys.client.remove(fsys.path(name))
+}
+
+// Close closes the connection to the server. Closing it again has no effect.
+func (fsys *SFTPFS) Close() error {
+	var err error
+	for _, closer := range fsys.closers {
+		if closeErr := closer.Close(); err == nil && closeErr != io.EOF {
+			err = closeErr
+		}
+	}
+	fsys.closers = nil
+	return err
+}
+
+// sftpFile is a file being written to or read from an SFTPFS.
+type sftpFile struct {
+	client   *sftpClient
+	handle   string
+	offset   uint64
+	tempName string
+	name     string
+}
+
+func (file *sftpFile) Write(p []byte) (int, error) {
+	if err := file.client.write(file.handle, file.offset, p); err != nil {
+		return 0, err
+	}
+	file.offset += uint64(len(p))
+	return len(p), nil
+}
+
+func (file *sftpFile) Read(p []byte) (int, error) {
+	n, err := file.client.read(file.handle, file.offset, p)
+	file.offset += uint64(n)
+	return n, err
+}
+
+func (file *sftpFile) Close() error {
+	err := file.client.close(file.handle)
+	if file.tempName == "" {
+		return err
+	}
+	if err == nil {
+		err = file.client.rename(file.tempName, file.name)
+	}
+	if err != nil {
+		file.client.remove(file.tempName)
+	}
+	return err
+}
+
+func (file *sftpFile) Abort() error {
+	file.client.close(file.handle)
+	return file.client.remove(file.tempName)
+}
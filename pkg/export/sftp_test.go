@@ -0,0 +1,221 @@
+package export
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// fakeSFTPServer serves the requests of sftpClient from a local directory.
+type fakeSFTPServer struct {
+	dir         string
+	r           io.Reader
+	w           io.Writer
+	posixRename bool
+	files       map[string]*os.File
+}
+
+// newFakeSFTPFS returns an SFTPFS writing to dir through a fake server.
+func newFakeSFTPFS(t *testing.T, dir string, posixRename bool) *SFTPFS {
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+	server := &fakeSFTPServer{dir: dir, r: serverReader, w: serverWriter, posixRename: posixRename, files: make(map[string]*os.File)}
+	go server.serve()
+
+	client, err := newSFTPClient(clientReader, clientWriter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &SFTPFS{root: "/export", client: client, closers: []io.Closer{clientWriter}}
+}
+
+func (server *fakeSFTPServer) serve() {
+	defer server.w.(io.Closer).Close()
+	helper := &sftpClient{r: server.r, w: server.w}
+
+	packetType, _, err := helper.readPacket()
+	if err != nil || packetType != sftpInit {
+		return
+	}
+	var version sftpPacket
+	version.uint32(3)
+	if server.posixRename {
+		version.string("posix-rename@openssh.com")
+		version.string("1")
+	}
+	helper.writePacket(sftpVersion, version)
+
+	for {
+		packetType, data, err := helper.readPacket()
+		if err != nil {
+			return
+		}
+		request := sftpReader{data: data}
+		var response sftpPacket
+		response.uint32(request.uint32())
+		responseType := server.handle(packetType, &request, &response)
+		helper.writePacket(responseType, response)
+	}
+}
+
+func (server *fakeSFTPServer) path(name string) string {
+	return filepath.Join(server.dir, filepath.FromSlash(name))
+}
+
+func (server *fakeSFTPServer) handle(packetType byte, request *sftpReader, response *sftpPacket) byte {
+	var err error
+	switch packetType {
+	case sftpOpen:
+		name, flags := request.string(), request.uint32()
+		mode := os.O_RDONLY
+		if flags&sftpOpenWrite != 0 {
+			mode = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		}
+		var file *os.File
+		if file, err = os.OpenFile(server.path(name), mode, 0644); err == nil {
+			handle := strconv.Itoa(len(server.files))
+			server.files[handle] = file
+			response.string(handle)
+			return sftpHandle
+		}
+	case sftpClose:
+		handle := request.string()
+		err = server.files[handle].Close()
+		delete(server.files, handle)
+	case sftpWrite:
+		handle, offset, data := request.string(), request.uint64(), request.string()
+		_, err = server.files[handle].WriteAt([]byte(data), int64(offset))
+	case sftpRead:
+		handle, offset, length := request.string(), request.uint64(), request.uint32()
+		data := make([]byte, length)
+		var n int
+		n, err = server.files[handle].ReadAt(data, int64(offset))
+		if n > 0 {
+			response.string(string(data[:n]))
+			return sftpData
+		}
+	case sftpStat:
+		var info os.FileInfo
+		if info, err = os.Stat(server.path(request.string())); err == nil {
+			permissions := uint32(info.Mode().Perm())
+			if info.IsDir() {
+				permissions |= 0040000
+			}
+			response.uint32(sftpAttrSize | sftpAttrPermissions | sftpAttrModTime)
+			response.uint64(uint64(info.Size()))
+			response.uint32(permissions)
+			response.uint32(uint32(info.ModTime().Unix()))
+			response.uint32(uint32(info.ModTime().Unix()))
+			return sftpAttrs
+		}
+	case sftpMkdir:
+		err = os.Mkdir(server.path(request.string()), 0755)
+	case sftpRemove:
+		err = os.Remove(server.path(request.string()))
+	case sftpRename:
+		oldName, newName := request.string(), request.string()
+		if _, statErr := os.Stat(server.path(newName)); statErr == nil {
+			err = errors.New("file exists")
+		} else {
+			err = os.Rename(server.path(oldName), server.path(newName))
+		}
+	case sftpExtended:
+		if request.string() == "posix-rename@openssh.com" && server.posixRename {
+			err = os.Rename(server.path(request.string()), server.path(request.string()))
+		} else {
+			err = errors.New("unsupported")
+		}
+	default:
+		err = errors.New("unsupported")
+	}
+
+	switch {
+	case err == nil:
+		response.uint32(sftpStatusOk)
+	case err == io.EOF:
+		response.uint32(sftpStatusEOF)
+	case os.IsNotExist(err):
+		response.uint32(sftpStatusNoSuchFile)
+	default:
+		response.uint32(4)
+	}
+	response.string("")
+	response.string("")
+	return sftpStatus
+}
+
+func TestSFTPFSWritesAndReadsFiles(t *testing.T) {
+	for _, posixRename := range []bool{true, false} {
+		dir := createTempDir(t, "itunes-exporter-test")
+		defer os.RemoveAll(dir)
+		fsys := newFakeSFTPFS(t, dir, posixRename)
+
+		for _, content := range []string{"old", "new"} {
+			err := writeFile(fsys, "Folder/My Playlist.m3u", func(w io.Writer) error {
+				_, err := io.WriteString(w, content)
+				return err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if content := readFile(t, filepath.Join(dir, "export", "Folder", "My Playlist.m3u")); content != "new" {
+			t.Fatalf("expected new content, got %q", content)
+		}
+		files, _ := ioutil.ReadDir(filepath.Join(dir, "export", "Folder"))
+		if len(files) != 1 {
+			t.Fatalf("expected temporary files to be removed, found %v files", len(files))
+		}
+
+		info, err := fsys.Stat("Folder/My Playlist.m3u")
+		if err != nil || info.Size() != 3 || info.IsDir() {
+			t.Fatalf("unexpected file info %v, error %v", info, err)
+		}
+		if info, err := fsys.Stat("Folder"); err != nil || !info.IsDir() {
+			t.Fatalf("expected directory, got %v, error %v", info, err)
+		}
+		if _, err := fsys.Stat("Other.m3u"); !os.IsNotExist(err) {
+			t.Fatalf("expected not exist error, got %v", err)
+		}
+
+		file, err := fsys.Open("Folder/My Playlist.m3u")
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadAll(file)
+		file.Close()
+		if err != nil || string(content) != "new" {
+			t.Fatalf("expected new content, got %q, error %v", content, err)
+		}
+
+		if err := fsys.Remove("Folder/My Playlist.m3u"); err != nil {
+			t.Fatal(err)
+		}
+		fsys.Close()
+	}
+}
+
+func TestSFTPFSRemovesTemporaryFileOnAbort(t *testing.T) {
+	dir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(dir)
+	fsys := newFakeSFTPFS(t, dir, true)
+	defer fsys.Close()
+
+	err := writeFile(fsys, "My Playlist.m3u", func(w io.Writer) error {
+		io.WriteString(w, "half written")
+		return errors.New("failed")
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	files, _ := ioutil.ReadDir(filepath.Join(dir, "export"))
+	if len(files) != 0 {
+		t.Fatalf("expected temporary file to be removed, found %v files", len(files))
+	}
+}
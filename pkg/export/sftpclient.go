@@ -0,0 +1,402 @@
+package export
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sync"
+	"time"
+)
+
+// SFTP version 3 packet types, see draft-ietf-secsh-filexfer-02.
+const (
+	sftpInit     = 1
+	sftpVersion  = 2
+	sftpOpen     = 3
+	sftpClose    = 4
+	sftpRead     = 5
+	sftpWrite    = 6
+	sftpRemove   = 13
+	sftpMkdir    = 14
+	sftpStat     = 17
+	sftpRename   = 18
+	sftpStatus   = 101
+	sftpHandle   = 102
+	sftpData     = 103
+	sftpAttrs    = 105
+	sftpExtended = 200
+)
+
+const (
+	sftpOpenRead  = 0x01
+	sftpOpenWrite = 0x02
+	sftpOpenCreat = 0x08
+	sftpOpenTrunc = 0x10
+)
+
+const (
+	sftpAttrSize        = 0x01
+	sftpAttrUidGid      = 0x02
+	sftpAttrPermissions = 0x04
+	sftpAttrModTime     = 0x08
+)
+
+const (
+	sftpStatusOk         = 0
+	sftpStatusEOF        = 1
+	sftpStatusNoSuchFile = 2
+)
+
+const (
+	// sftpChunkSize is the size of the data read or written with a single request.
+	sftpChunkSize = 32 * 1024
+	// sftpMaxPacket limits the size of the packets accepted from the server.
+	sftpMaxPacket = 1 << 20
+)
+
+// sftpClient is a minimal SFTP version 3 client supporting the requests needed to write an
+// export. Requests are sent one at a time.
+type sftpClient struct {
+	mutex       sync.Mutex
+	r           io.Reader
+	w           io.Writer
+	nextId      uint32
+	posixRename bool
+}
+
+// newSFTPClient initializes the SFTP session on the connection to the server.
+func newSFTPClient(r io.Reader, w io.Writer) (*sftpClient, error) {
+	client := &sftpClient{r: r, w: w}
+
+	var init sftpPacket
+	init.uint32(3)
+	if err := client.writePacket(sftpInit, init); err != nil {
+		return nil, err
+	}
+	packetType, data, err := client.readPacket()
+	if err != nil {
+		return nil, err
+	}
+	if packetType != sftpVersion {
+		return nil, fmt.Errorf("unexpected SFTP packet %v, expected version", packetType)
+	}
+
+	version := sftpReader{data: data}
+	if version.uint32() < 3 {
+		return nil, errors.New("SFTP server does not support version 3")
+	}
+	for len(version.data) > 0 && version.err == nil {
+		name, _ := version.string(), version.string()
+		if name == "posix-rename@openssh.com" {
+			client.posixRename = true
+		}
+	}
+	return client, version.err
+}
+
+// sftpPacket builds the payload of a packet.
+type sftpPacket []byte
+
+func (packet *sftpPacket) uint32(v uint32) {
+	*packet = append(*packet, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func (packet *sftpPacket) uint64(v uint64) {
+	packet.uint32(uint32(v >> 32))
+	packet.uint32(uint32(v))
+}
+
+func (packet *sftpPacket) string(s string) {
+	packet.uint32(uint32(len(s)))
+	*packet = append(*packet, s...)
+}
+
+// sftpReader parses the payload of a packet. The first error is kept in err.
+type sftpReader struct {
+	data []byte
+	err  error
+}
+
+func (reader *sftpReader) uint32() uint32 {
+	if len(reader.data) < 4 {
+		reader.err = io.ErrUnexpectedEOF
+		reader.data = nil
+		return 0
+	}
+	v := binary.BigEndian.Uint32(reader.data)
+	reader.data = reader.data[4:]
+	return v
+}
+
+func (reader *sftpReader) uint64() uint64 {
+	return uint64(reader.uint32())<<32 | uint64(reader.uint32())
+}
+
+func (reader *sftpReader) string() string {
+	length := reader.uint32()
+	if uint32(len(reader.data)) < length {
+		reader.err = io.ErrUnexpectedEOF
+		reader.data = nil
+		return ""
+	}
+	s := string(reader.data[:length])
+	reader.data = reader.data[length:]
+	return s
+}
+
+func (client *sftpClient) writePacket(packetType byte, payload []byte) error {
+	packet := make([]byte, 5, 5+len(payload))
+	binary.BigEndian.PutUint32(packet, uint32(1+len(payload)))
+	packet[4] = packetType
+	_, err := client.w.Write(append(packet, payload...))
+	return err
+}
+
+func (client *sftpClient) readPacket() (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(client.r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:4])
+	if length < 1 || length > sftpMaxPacket {
+		return 0, nil, fmt.Errorf("invalid SFTP packet length %v", length)
+	}
+	data := make([]byte, length-1)
+	if _, err := io.ReadFull(client.r, data); err != nil {
+		return 0, nil, err
+	}
+	return header[4], data, nil
+}
+
+// request sends a request and returns the type and the payload, following the request id,
+// of the response.
+func (client *sftpClient) request(packetType byte, payload sftpPacket) (byte, []byte, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	client.nextId++
+	id := client.nextId
+
+	var packet sftpPacket
+	packet.uint32(id)
+	if err := client.writePacket(packetType, append(packet, payload...)); err != nil {
+		return 0, nil, err
+	}
+
+	responseType, data, err := client.readPacket()
+	if err != nil {
+		return 0, nil, err
+	}
+	response := sftpReader{data: data}
+	if response.uint32() != id || response.err != nil {
+		return 0, nil, errors.New("unexpected SFTP response id")
+	}
+	return responseType, response.data, nil
+}
+
+// sftpStatusError is an error status returned by the server.
+type sftpStatusError struct {
+	code    uint32
+	message string
+}
+
+func (err *sftpStatusError) Error() string {
+	return fmt.Sprintf("sftp: %v (status %v)", err.message, err.code)
+}
+
+// statusError returns the error of a response, which is nil for the OK status.
+func statusError(op, name string, responseType byte, data []byte) error {
+	if responseType != sftpStatus {
+		return fmt.Errorf("unexpected SFTP packet %v", responseType)
+	}
+	status := sftpReader{data: data}
+	code, message := status.uint32(), status.string()
+	switch {
+	case status.err != nil:
+		return status.err
+	case code == sftpStatusOk:
+		return nil
+	case code == sftpStatusEOF:
+		return io.EOF
+	case code == sftpStatusNoSuchFile:
+		return &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+	default:
+		return &os.PathError{Op: op, Path: name, Err: &sftpStatusError{code: code, message: message}}
+	}
+}
+
+func (client *sftpClient) pathRequest(packetType byte, op, name string, withAttrs bool) error {
+	var payload sftpPacket
+	payload.string(name)
+	if withAttrs {
+		payload.uint32(0)
+	}
+	responseType, data, err := client.request(packetType, payload)
+	if err != nil {
+		return err
+	}
+	return statusError(op, name, responseType, data)
+}
+
+func (client *sftpClient) open(name string, flags uint32) (string, error) {
+	var payload sftpPacket
+	payload.string(name)
+	payload.uint32(flags)
+	payload.uint32(0)
+	responseType, data, err := client.request(sftpOpen, payload)
+	if err != nil {
+		return "", err
+	}
+	if responseType != sftpHandle {
+		if err := statusError("open", name, responseType, data); err != nil {
+			return "", err
+		}
+		return "", errors.New("unexpected SFTP response")
+	}
+	handle := sftpReader{data: data}
+	return handle.string(), handle.err
+}
+
+func (client *sftpClient) close(handle string) error {
+	return client.pathRequest(sftpClose, "close", handle, false)
+}
+
+func (client *sftpClient) write(handle string, offset uint64, data []byte) error {
+	for len(data) > 0 {
+		chunk := data
+		if len(chunk) > sftpChunkSize {
+			chunk = chunk[:sftpChunkSize]
+		}
+
+		var payload sftpPacket
+		payload.string(handle)
+		payload.uint64(offset)
+		payload.string(string(chunk))
+		responseType, response, err := client.request(sftpWrite, payload)
+		if err != nil {
+			return err
+		}
+		if err := statusError("write", handle, responseType, response); err != nil {
+			return err
+		}
+
+		offset += uint64(len(chunk))
+		data = data[len(chunk):]
+	}
+	return nil
+}
+
+func (client *sftpClient) read(handle string, offset uint64, p []byte) (int, error) {
+	length := len(p)
+	if length > sftpChunkSize {
+		length = sftpChunkSize
+	}
+
+	var payload sftpPacket
+	payload.string(handle)
+	payload.uint64(offset)
+	payload.uint32(uint32(length))
+	responseType, data, err := client.request(sftpRead, payload)
+	if err != nil {
+		return 0, err
+	}
+	if responseType != sftpData {
+		if err := statusError("read", handle, responseType, data); err != nil {
+			return 0, err
+		}
+		return 0, io.EOF
+	}
+	response := sftpReader{data: data}
+	return copy(p, response.string()), response.err
+}
+
+func (client *sftpClient) stat(name string) (os.FileInfo, error) {
+	var payload sftpPacket
+	payload.string(name)
+	responseType, data, err := client.request(sftpStat, payload)
+	if err != nil {
+		return nil, err
+	}
+	if responseType != sftpAttrs {
+		if err := statusError("stat", name, responseType, data); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("unexpected SFTP response")
+	}
+
+	attrs := sftpReader{data: data}
+	info := &remoteFileInfo{name: path.Base(name)}
+	flags := attrs.uint32()
+	if flags&sftpAttrSize != 0 {
+		info.size = int64(attrs.uint64())
+	}
+	if flags&sftpAttrUidGid != 0 {
+		attrs.uint32()
+		attrs.uint32()
+	}
+	if flags&sftpAttrPermissions != 0 {
+		permissions := attrs.uint32()
+		info.mode = os.FileMode(permissions & 0777)
+		if permissions&0170000 == 0040000 {
+			info.mode |= os.ModeDir
+		}
+	}
+	if flags&sftpAttrModTime != 0 {
+		attrs.uint32()
+		info.modTime = time.Unix(int64(attrs.uint32()), 0)
+	}
+	return info, attrs.err
+}
+
+func (client *sftpClient) mkdir(name string) error {
+	return client.pathRequest(sftpMkdir, "mkdir", name, true)
+}
+
+func (client *sftpClient) remove(name string) error {
+	return client.pathRequest(sftpRemove, "remove", name, false)
+}
+
+// rename renames oldName to newName, replacing an existing file. Servers without the
+// posix-rename extension refuse to replace a file, so it is removed first.
+func (client *sftpClient) rename(oldName, newName string) error {
+	var payload sftpPacket
+	packetType := byte(sftpRename)
+	if client.posixRename {
+		packetType = sftpExtended
+		payload.string("posix-rename@openssh.com")
+	} else if err := client.remove(newName); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	payload.string(oldName)
+	payload.string(newName)
+
+	responseType, data, err := client.request(packetType, payload)
+	if err != nil {
+		return err
+	}
+	return statusError("rename", newName, responseType, data)
+}
+
+// mkdirAll creates the directory name and any missing parents.
+func (client *sftpClient) mkdirAll(name string) error {
+	if name == "" || name == "." || name == "/" {
+		return nil
+	}
+	info, err := client.stat(name)
+	if err == nil {
+		if !info.IsDir() {
+			return &os.PathError{Op: "mkdir", Path: name, Err: errors.New("not a directory")}
+		}
+		return nil
+	}
+	if !os.IsNotExist(err) {
+		return err
+	}
+	if err := client.mkdirAll(path.Dir(name)); err != nil {
+		return err
+	}
+	return client.mkdir(name)
+}
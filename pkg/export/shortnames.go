@@ -0,0 +1,172 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+// ShortNamesManifest is the file in the output listing the DOS 8.3 names given to the files
+// and directories of an export with ExportSettings.ShortNames set, one per line as the
+// short and the full path separated by a tab.
+const ShortNamesManifest = "NAMES.TXT"
+
+// shortNameIllegalChars are the characters not allowed in DOS 8.3 names.
+var shortNameIllegalChars = regexp.MustCompile("[^A-Z0-9!#$%&'()@^_`{}~-]")
+
+// shortNamer assigns unique 8.3 names to the paths of an export. The names given by the
+// previous export are read from the manifest and kept.
+type shortNamer struct {
+	mutex sync.Mutex
+	fsys  FS
+
+	// names maps full paths to their short paths.
+	names map[string]string
+	// taken contains the short paths in use.
+	taken   map[string]bool
+	changed bool
+}
+
+// loadShortNames reads the short names of the previous export from fsys. A missing or
+// unreadable manifest results in no names.
+func loadShortNames(fsys FS) *shortNamer {
+	namer := &shortNamer{
+		fsys:  fsys,
+		names: make(map[string]string),
+		taken: make(map[string]bool),
+	}
+
+	file, err := fsys.Open(ShortNamesManifest)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			Log(LogEntry{Level: LOG_WARNING, Message: fmt.Sprintf("Unable to read short names %v: %v\n", ShortNamesManifest, err), Path: ShortNamesManifest, Error: err.Error()})
+		}
+		return namer
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), "\t", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		namer.names[fields[1]] = fields[0]
+		namer.taken[fields[0]] = true
+	}
+	if err := scanner.Err(); err != nil {
+		Log(LogEntry{Level: LOG_WARNING, Message: fmt.Sprintf("Unable to read short names %v: %v\n", ShortNamesManifest, err), Path: ShortNamesManifest, Error: err.Error()})
+	}
+	return namer
+}
+
+// shorten returns the short path for name, a path of the output, assigning new short
+// names to the directories and the file not named before.
+func (namer *shortNamer) shorten(name string) string {
+	namer.mutex.Lock()
+	defer namer.mutex.Unlock()
+
+	parts := strings.Split(name, "/")
+	long, short := "", ""
+	for i, part := range parts {
+		long = path.Join(long, part)
+		shortPath, ok := namer.names[long]
+		if !ok {
+			parent := short
+			shortPath = path.Join(parent, shortName(part, i == len(parts)-1, func(candidate string) bool {
+				return namer.taken[path.Join(parent, candidate)]
+			}))
+			namer.names[long] = shortPath
+			namer.taken[shortPath] = true
+			namer.changed = true
+		}
+		short = shortPath
+	}
+	return short
+}
+
+func (namer *shortNamer) save() error {
+	namer.mutex.Lock()
+	defer namer.mutex.Unlock()
+
+	if !namer.changed {
+		return nil
+	}
+	longNames := make([]string, 0, len(namer.names))
+	for long := range namer.names {
+		longNames = append(longNames, long)
+	}
+	sort.Strings(longNames)
+
+	return writeFile(namer.fsys, ShortNamesManifest, func(w io.Writer) error {
+		for _, long := range longNames {
+			if _, err := fmt.Fprintf(w, "%v\t%v\n", namer.names[long], long); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// shorten returns the short path of name if short names are enabled, name otherwise.
+func (exportSettings *ExportSettings) shorten(name string) string {
+	if exportSettings.shortNames == nil {
+		return name
+	}
+	return exportSettings.shortNames.shorten(name)
+}
+
+// shortName returns a 8.3 name for name that taken reports as free. Names that are valid
+// 8.3 names already are kept in upper case, others are shortened to end with ~ and a number
+// like Windows does. The extension of files is shortened to three characters.
+func shortName(name string, file bool, taken func(string) bool) string {
+	base, ext := name, ""
+	if file {
+		ext = path.Ext(name)
+		base = strings.TrimSuffix(name, ext)
+		ext = strings.TrimPrefix(ext, ".")
+	}
+
+	clean := func(s string) string {
+		s = strings.ToUpper(itunes.FoldAccents(s))
+		s = strings.NewReplacer(" ", "", ".", "").Replace(s)
+		return shortNameIllegalChars.ReplaceAllString(s, "_")
+	}
+	shortBase, shortExt := clean(base), clean(ext)
+	if len(shortExt) > 3 {
+		shortExt = shortExt[:3]
+	}
+	join := func(base string) string {
+		if shortExt == "" {
+			return base
+		}
+		return base + "." + shortExt
+	}
+
+	if shortBase != "" && shortBase == strings.ToUpper(base) && len(shortBase) <= 8 && shortExt == strings.ToUpper(ext) {
+		if candidate := join(shortBase); !taken(candidate) {
+			return candidate
+		}
+	}
+	if shortBase == "" {
+		shortBase = "_"
+	}
+	for n := 1; ; n++ {
+		suffix := fmt.Sprintf("~%d", n)
+		prefix := shortBase
+		if len(prefix) > 8-len(suffix) {
+			prefix = prefix[:8-len(suffix)]
+		}
+		if candidate := join(prefix + suffix); !taken(candidate) {
+			return candidate
+		}
+	}
+}
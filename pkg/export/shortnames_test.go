@@ -0,0 +1,83 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestShortName(t *testing.T) {
+	taken := map[string]bool{}
+	name := func(name string, file bool) string {
+		short := shortName(name, file, func(candidate string) bool { return taken[candidate] })
+		taken[short] = true
+		return short
+	}
+
+	if short := name("readme.txt", true); short != "README.TXT" {
+		t.Fatalf("expected a valid 8.3 name to be kept, got %v", short)
+	}
+	if short := name("Readme.txt", true); short != "README~1.TXT" {
+		t.Fatalf("expected a unique name for an existing name, got %v", short)
+	}
+	if short := name("01 Ace of Spades.flac", true); short != "01ACEO~1.FLA" {
+		t.Fatalf("unexpected short name %v", short)
+	}
+	if short := name("01 Ace of Spades (Live).flac", true); short != "01ACEO~2.FLA" {
+		t.Fatalf("unexpected second short name %v", short)
+	}
+	if short := name("Motörhead", false); short != "MOTORH~1" {
+		t.Fatalf("unexpected directory name %v", short)
+	}
+	if short := name("v1.0", false); short != "V10~1" {
+		t.Fatalf("unexpected directory name with dot %v", short)
+	}
+}
+
+func TestExportWithShortNames(t *testing.T) {
+	outputDir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(outputDir)
+
+	musicFile, musicFileName := prepareMusicFile(t)
+	defer os.Remove(musicFile)
+
+	library := testLibrary()
+	track := library.Tracks["1"]
+	track.Artist = "Motörhead"
+	track.Album = "Ace of Spades"
+	track.Location = "file://" + filepath.ToSlash(musicFile)
+	library.Tracks["1"] = track
+
+	settings := &ExportSettings{
+		Library:    library,
+		Playlists:  library.Playlists,
+		CopyType:   COPY_ITUNES,
+		PathStyle:  PATH_STYLE_RELATIVE_BACKSLASH,
+		OutputPath: outputDir,
+		ShortNames: true,
+	}
+	ParseExportType(settings, M3U)
+	for i := 0; i < 2; i++ {
+		if err := ExportPlaylists(settings, library); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	assertPathExists(t, filepath.Join(outputDir, "MOTORH~1", "ACEOFS~1", "SOME_S~1.MP3"))
+	assertPlaylistFileCorrectlyWritten(t, filepath.Join(outputDir, "MYPLAY~1.M3U"), "MOTORH~1\\ACEOFS~1\\SOME_S~1.MP3")
+
+	manifest := readFile(t, filepath.Join(outputDir, ShortNamesManifest))
+	for _, line := range []string{
+		"MOTORH~1\tMotörhead\n",
+		"MOTORH~1/ACEOFS~1/SOME_S~1.MP3\tMotörhead/Ace of Spades/" + musicFileName + "\n",
+		"MYPLAY~1.M3U\tMy Playlist.m3u\n",
+	} {
+		if !strings.Contains(manifest, line) {
+			t.Fatalf("expected manifest to contain %q, got %q", line, manifest)
+		}
+	}
+	if strings.Contains(manifest, "~2") {
+		t.Fatalf("expected the names of the previous export to be reused, got %q", manifest)
+	}
+}
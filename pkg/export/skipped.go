@@ -0,0 +1,68 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+)
+
+var (
+	// errEmptySource is returned for music files without content, which would be copied as
+	// files no player can read.
+	errEmptySource = errors.New("the music file is empty")
+	// errSourceChanged is returned for music files that changed while they were copied. The
+	// incomplete copy is discarded.
+	errSourceChanged = errors.New("the music file changed while it was copied")
+)
+
+// skippedFiles collects the music files that were not copied and why, to be reported at the
+// end of an export.
+type skippedFiles struct {
+	mutex sync.Mutex
+	// reasons are the reasons by source file.
+	reasons map[string]string
+}
+
+func newSkippedFiles() *skippedFiles {
+	return &skippedFiles{reasons: make(map[string]string)}
+}
+
+// add records that source was not copied because of err.
+func (skipped *skippedFiles) add(source string, err error) {
+	reason := err.Error()
+	if os.IsPermission(err) {
+		reason = "permission denied, the music file is not readable"
+	}
+	skipped.mutex.Lock()
+	defer skipped.mutex.Unlock()
+	skipped.reasons[source] = reason
+}
+
+// count returns the number of skipped files.
+func (skipped *skippedFiles) count() int {
+	skipped.mutex.Lock()
+	defer skipped.mutex.Unlock()
+	return len(skipped.reasons)
+}
+
+// print lists the skipped files with their reasons below the title, if any, e.g.
+// "music files were skipped".
+func (skipped *skippedFiles) print(title string) {
+	skipped.mutex.Lock()
+	defer skipped.mutex.Unlock()
+	if len(skipped.reasons) == 0 {
+		return
+	}
+	var sources []string
+	for source := range skipped.reasons {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	Logf(LOG_WARNING, "\n%v %v:\n", len(sources), title)
+	for _, source := range sources {
+		reason := skipped.reasons[source]
+		Log(LogEntry{Level: LOG_WARNING, Message: fmt.Sprintf("  %v: %v\n", source, reason), Path: source, Error: reason})
+	}
+}
@@ -0,0 +1,175 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync/atomic"
+
+	"github.com/hirochachacha/go-smb2"
+)
+
+// SMBFS writes the export to a directory of a SMB/CIFS share, e.g. on a NAS, without mounting it.
+type SMBFS struct {
+	root    string
+	share   *smb2.Share
+	session *smb2.Session
+	conn    net.Conn
+}
+
+// smbTarget is the server, share and credentials of an smb:// URL.
+type smbTarget struct {
+	address  string
+	share    string
+	root     string
+	user     string
+	password string
+	domain   string
+}
+
+// parseSMBTarget parses an smb://[[domain;]user[:password]@]server[:port]/share/path URL.
+// If the URL has a user but no password, the password is read from the
+// ITUNESEXPORT_SMB_PASSWORD environment variable.
+func parseSMBTarget(target *url.URL) (*smbTarget, error) {
+	parts := strings.SplitN(strings.Trim(target.Path, "/"), "/", 2)
+	if target.Hostname() == "" || parts[0] == "" {
+		return nil, errors.New("SMB URL must contain server and share: " + target.String())
+	}
+
+	smb := &smbTarget{address: target.Host, share: parts[0]}
+	if target.Port() == "" {
+		smb.address = net.JoinHostPort(target.Hostname(), "445")
+	}
+	if len(parts) > 1 {
+		smb.root = parts[1]
+	}
+
+	if target.User != nil {
+		smb.user = target.User.Username()
+		if i := strings.Index(smb.user, ";"); i >= 0 {
+			smb.domain, smb.user = smb.user[:i], smb.user[i+1:]
+		}
+		password, ok := target.User.Password()
+		if !ok {
+			password = os.Getenv("ITUNESEXPORT_SMB_PASSWORD")
+		}
+		smb.password = password
+	}
+	return smb, nil
+}
+
+// NewSMBFS connects to the share of an smb://[[domain;]user[:password]@]server[:port]/share/path
+// URL. Without a user, the share is accessed as guest. Close must be called once the export
+// is complete.
+func NewSMBFS(target *url.URL) (*SMBFS, error) {
+	smb, err := parseSMBTarget(target)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.Dial("tcp", smb.address)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &smb2.Dialer{Initiator: &smb2.NTLMInitiator{User: smb.user, Password: smb.password, Domain: smb.domain}}
+	session, err := dialer.Dial(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	share, err := session.Mount(smb.share)
+	if err != nil {
+		session.Logoff()
+		conn.Close()
+		return nil, err
+	}
+	return &SMBFS{root: smb.root, share: share, session: session, conn: conn}, nil
+}
+
+func (fsys *SMBFS) path(name string) string {
+	return path.Join(fsys.root, name)
+}
+
+// smbTempCounter makes the names of temporary files unique.
+var smbTempCounter int64
+
+// Create writes to a temporary file which replaces the file on Close.
+func (fsys *SMBFS) Create(name string) (File, error) {
+	fileName := fsys.path(name)
+	if err := fsys.share.MkdirAll(path.Dir(fileName), 0755); err != nil {
+		return nil, err
+	}
+
+	tempName := path.Join(path.Dir(fileName), fmt.Sprintf(".%v.%v.tmp", path.Base(fileName), atomic.AddInt64(&smbTempCounter, 1)))
+	file, err := fsys.share.Create(tempName)
+	if err != nil {
+		return nil, err
+	}
+	return &smbFile{File: file, fsys: fsys, tempName: tempName, name: fileName}, nil
+}
+
+func (fsys *SMBFS) Open(name string) (io.ReadCloser, error) {
+	return fsys.share.Open(fsys.path(name))
+}
+
+func (fsys *SMBFS) Stat(name string) (os.FileInfo, error) {
+	return fsys.share.Stat(fsys.path(name))
+}
+
+func (fsys *SMBFS) MkdirAll(name string) error {
+	return fsys.share.MkdirAll(fsys.path(name), 0755)
+}
+
+func (fsys *SMBFS) Remove(name string) error {
+	return fsys.share.Remove(fsys.path(name))
+}
+
+// Close disconnects from the share. Closing it again has no effect.
+func (fsys *SMBFS) Close() error {
+	if fsys.conn == nil {
+		return nil
+	}
+	err := fsys.share.Umount()
+	if logoffErr := fsys.session.Logoff(); err == nil {
+		err = logoffErr
+	}
+	fsys.conn.Close()
+	fsys.conn = nil
+	return err
+}
+
+// smbFile is a file being written to an SMBFS.
+type smbFile struct {
+	*smb2.File
+	fsys     *SMBFS
+	tempName string
+	name     string
+}
+
+// Close completes the file. SMB does not replace existing files on rename, so the previous
+// file is removed first.
+func (file *smbFile) Close() error {
+	err := file.File.Close()
+	if err == nil {
+		if removeErr := file.fsys.share.Remove(file.name); removeErr != nil && !os.IsNotExist(removeErr) {
+			err = removeErr
+		}
+	}
+	if err == nil {
+		err = file.fsys.share.Rename(file.tempName, file.name)
+	}
+	if err != nil {
+		file.fsys.share.Remove(file.tempName)
+	}
+	return err
+}
+
+func (file *smbFile) Abort() error {
+	file.File.Close()
+	return file.fsys.share.Remove(file.tempName)
+}
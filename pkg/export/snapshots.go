@@ -0,0 +1,295 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SnapshotsDir is the directory of the output the snapshots of the exports with
+// ExportSettings.Snapshots set are kept in.
+const SnapshotsDir = ".snapshots"
+
+// SnapshotsKept is the number of snapshots kept in SnapshotsDir. Older snapshots are removed.
+const SnapshotsKept = 10
+
+// snapshotsFileName is the file listing the snapshots.
+var snapshotsFileName = path.Join(SnapshotsDir, "snapshots.json")
+
+// Snapshot records the playlist files of an export and the music files copied for them, so
+// the output can be rolled back to it. The playlist files are kept in SnapshotsDir/Id.
+type Snapshot struct {
+	Id        string             `json:"id"`
+	Time      time.Time          `json:"time"`
+	Playlists []SnapshotPlaylist `json:"playlists"`
+}
+
+// SnapshotPlaylist is a playlist file of a Snapshot.
+type SnapshotPlaylist struct {
+	File   string          `json:"file"`
+	Tracks []SnapshotTrack `json:"tracks,omitempty"`
+}
+
+// SnapshotTrack is a music file copied for a playlist and the file it was copied from.
+type SnapshotTrack struct {
+	File   string `json:"file"`
+	Source string `json:"source"`
+}
+
+// RollbackResult summarizes a rollback.
+type RollbackResult struct {
+	Snapshot string
+	// Restored is the number of playlist files restored from the snapshot.
+	Restored int
+	// Removed is the number of playlist files of later exports removed.
+	Removed int
+	// Recopied is the number of missing music files copied again.
+	Recopied int
+	// Missing are the sources of missing music files that could not be copied again.
+	Missing []string
+}
+
+// LoadSnapshots returns the snapshots of the exports to fsys, oldest first.
+func LoadSnapshots(fsys FS) ([]Snapshot, error) {
+	file, err := fsys.Open(snapshotsFileName)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var snapshots []Snapshot
+	if err := json.NewDecoder(file).Decode(&snapshots); err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+// Rollback restores the playlist files of fsys to the snapshot with the given id, or to the
+// one before the latest if id is empty. Playlist files written by later exports only are
+// removed. With recopy, music files of the snapshot missing in fsys are copied again from
+// their source.
+func Rollback(ctx context.Context, fsys FS, id string, recopy bool) (*RollbackResult, error) {
+	if locker, ok := fsys.(Locker); ok {
+		unlock, err := locker.Lock()
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+
+	snapshots, err := LoadSnapshots(fsys)
+	if err != nil {
+		return nil, err
+	}
+	target := -1
+	for i, snapshot := range snapshots {
+		if snapshot.Id == id || (id == "" && i == len(snapshots)-2) {
+			target = i
+		}
+	}
+	if target < 0 {
+		if id == "" {
+			return nil, errors.New("there is no previous export to roll back to")
+		}
+		return nil, errors.New("Unknown Snapshot: " + id)
+	}
+	snapshot := snapshots[target]
+	result := &RollbackResult{Snapshot: snapshot.Id}
+
+	kept := make(map[string]bool)
+	for _, playlist := range snapshot.Playlists {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		content, err := readAll(fsys, path.Join(SnapshotsDir, snapshot.Id, playlist.File))
+		if err != nil {
+			return result, err
+		}
+		if err := writeContent(fsys, playlist.File, content); err != nil {
+			return result, err
+		}
+		kept[playlist.File] = true
+		result.Restored++
+	}
+
+	for _, later := range snapshots[target+1:] {
+		for _, playlist := range later.Playlists {
+			if kept[playlist.File] {
+				continue
+			}
+			kept[playlist.File] = true
+			err := fsys.Remove(playlist.File)
+			if err == nil {
+				result.Removed++
+			} else if !os.IsNotExist(err) {
+				return result, err
+			}
+		}
+	}
+
+	if !recopy {
+		return result, nil
+	}
+	copied := make(map[string]bool)
+	for _, playlist := range snapshot.Playlists {
+		for _, track := range playlist.Tracks {
+			if err := ctx.Err(); err != nil {
+				return result, err
+			}
+			if copied[track.File] {
+				continue
+			}
+			copied[track.File] = true
+			if _, err := fsys.Stat(track.File); err == nil {
+				continue
+			} else if !os.IsNotExist(err) {
+				return result, err
+			}
+			if err := recopyTrack(fsys, track); os.IsNotExist(err) {
+				result.Missing = append(result.Missing, track.Source)
+				continue
+			} else if err != nil {
+				return result, err
+			}
+			result.Recopied++
+		}
+	}
+	return result, nil
+}
+
+// recopyTrack copies the music file of track from its source.
+func recopyTrack(fsys FS, track SnapshotTrack) error {
+	source, err := os.Open(track.Source)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	return writeFile(fsys, track.File, func(w io.Writer) error {
+		_, err := io.Copy(w, source)
+		return err
+	})
+}
+
+// snapshots records the snapshot of an export.
+type snapshots struct {
+	mutex     sync.Mutex
+	fsys      FS
+	previous  []Snapshot
+	snapshot  Snapshot
+	playlists map[string]*SnapshotPlaylist
+	// written are the playlist files written by the export. The tracks of the others are
+	// taken from the previous snapshots.
+	written map[string]bool
+}
+
+func loadSnapshots(fsys FS, started time.Time) *snapshots {
+	previous, err := LoadSnapshots(fsys)
+	if err != nil {
+		Log(LogEntry{Level: LOG_WARNING, Message: fmt.Sprintf("Unable to read the snapshots of the previous exports: %v\n", err), Error: err.Error()})
+	}
+
+	taken := make(map[string]bool)
+	for _, snapshot := range previous {
+		taken[snapshot.Id] = true
+	}
+	id := started.Format("20060102-150405")
+	for base, n := id, 2; taken[id]; n++ {
+		id = base + "-" + strconv.Itoa(n)
+	}
+
+	return &snapshots{
+		fsys:      fsys,
+		previous:  previous,
+		snapshot:  Snapshot{Id: id, Time: started.UTC().Truncate(time.Second)},
+		playlists: make(map[string]*SnapshotPlaylist),
+		written:   make(map[string]bool),
+	}
+}
+
+// addPlaylist records the playlist file name of the export, whether it is written or not.
+func (snapshots *snapshots) addPlaylist(name string) {
+	snapshots.mutex.Lock()
+	defer snapshots.mutex.Unlock()
+	if snapshots.playlists[name] == nil {
+		snapshots.playlists[name] = &SnapshotPlaylist{File: name}
+	}
+}
+
+// addTrack records the music file copied from source for the playlist file name.
+func (snapshots *snapshots) addTrack(name, file, source string) {
+	snapshots.mutex.Lock()
+	defer snapshots.mutex.Unlock()
+	playlist := snapshots.playlists[name]
+	playlist.Tracks = append(playlist.Tracks, SnapshotTrack{File: file, Source: source})
+}
+
+// playlistWritten records that the playlist file name was written.
+func (snapshots *snapshots) playlistWritten(name string) {
+	snapshots.mutex.Lock()
+	defer snapshots.mutex.Unlock()
+	snapshots.written[name] = true
+}
+
+// save keeps a copy of the playlist files of the export and adds the snapshot to the list,
+// removing the oldest snapshots beyond SnapshotsKept.
+func (snapshots *snapshots) save() error {
+	snapshots.mutex.Lock()
+	defer snapshots.mutex.Unlock()
+
+	snapshot := snapshots.snapshot
+	snapshot.Playlists = []SnapshotPlaylist{}
+	for name, playlist := range snapshots.playlists {
+		content, err := readAll(snapshots.fsys, name)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if err := writeContent(snapshots.fsys, path.Join(SnapshotsDir, snapshot.Id, name), content); err != nil {
+			return err
+		}
+		if !snapshots.written[name] {
+			playlist.Tracks = snapshots.previousTracks(name)
+		}
+		snapshot.Playlists = append(snapshot.Playlists, *playlist)
+	}
+	sort.Slice(snapshot.Playlists, func(i, j int) bool {
+		return snapshot.Playlists[i].File < snapshot.Playlists[j].File
+	})
+
+	list := append(snapshots.previous, snapshot)
+	for len(list) > SnapshotsKept {
+		for _, playlist := range list[0].Playlists {
+			snapshots.fsys.Remove(path.Join(SnapshotsDir, list[0].Id, playlist.File))
+		}
+		list = list[1:]
+	}
+	return writeFile(snapshots.fsys, snapshotsFileName, func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(list)
+	})
+}
+
+// previousTracks returns the tracks of the playlist file name in the latest previous
+// snapshot that has it.
+func (snapshots *snapshots) previousTracks(name string) []SnapshotTrack {
+	for i := len(snapshots.previous) - 1; i >= 0; i-- {
+		for _, playlist := range snapshots.previous[i].Playlists {
+			if playlist.File == name {
+				return playlist.Tracks
+			}
+		}
+	}
+	return nil
+}
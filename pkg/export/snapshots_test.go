@@ -0,0 +1,72 @@
+package export
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRollbackToSnapshot(t *testing.T) {
+	musicFile, musicFileName := prepareMusicFile(t)
+	defer os.Remove(musicFile)
+
+	library := testLibrary()
+	track := library.Tracks["1"]
+	track.Location = "file://" + filepath.ToSlash(musicFile)
+	library.Tracks["1"] = track
+
+	fsys := NewMemFS()
+	settings := &ExportSettings{Library: library, Playlists: library.Playlists, Output: fsys, CopyType: COPY_FLAT, Snapshots: true}
+	ParseExportType(settings, M3U)
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+	exported, err := readAll(fsys, "My Playlist.m3u")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	settings.Playlists = append(settings.Playlists, library.Playlists[0])
+	settings.Playlists[1].Name = "Other Playlist"
+	settings.Playlists[1].PlaylistPersistentId = "0C1D2E3F40516273"
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+	snapshots, err := LoadSnapshots(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 2 || snapshots[0].Id == snapshots[1].Id || len(snapshots[1].Playlists) != 2 {
+		t.Fatalf("unexpected snapshots %v", snapshots)
+	}
+
+	if err := writeContent(fsys, "My Playlist.m3u", []byte("broken")); err != nil {
+		t.Fatal(err)
+	}
+	fsys.Remove(musicFileName)
+
+	result, err := Rollback(context.Background(), fsys, "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Snapshot != snapshots[0].Id || result.Restored != 1 || result.Removed != 1 || result.Recopied != 1 || len(result.Missing) != 0 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if content, err := readAll(fsys, "My Playlist.m3u"); err != nil || string(content) != string(exported) {
+		t.Fatalf("expected the playlist of the first export, got %q, %v", content, err)
+	}
+	if _, err := fsys.Stat("Other Playlist.m3u"); err == nil {
+		t.Fatal("expected the playlist of the later export to be removed")
+	}
+	if content, err := readAll(fsys, musicFileName); err != nil || string(content) != FileContent {
+		t.Fatalf("expected the music file to be copied again, got %q, %v", content, err)
+	}
+
+	if _, err := Rollback(context.Background(), fsys, "unknown", false); err == nil {
+		t.Fatal("expected error for an unknown snapshot")
+	}
+	if _, err := Rollback(context.Background(), NewMemFS(), "", false); err == nil {
+		t.Fatal("expected error without snapshots")
+	}
+}
@@ -0,0 +1,110 @@
+package export
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+// StateFileName is the file in the output directory recording the playlists written by
+// previous incremental exports.
+const StateFileName = ".itunesexport-state.json"
+
+// exportState records a content hash for every playlist file written, so unchanged
+// playlists can be skipped by the next incremental export.
+type exportState struct {
+	mutex sync.Mutex
+	fsys  FS
+
+	// Playlists maps the name of the playlist file to its content hash.
+	Playlists map[string]string `json:"playlists"`
+}
+
+// loadExportState reads the state of the previous export from fsys. A missing
+// or unreadable state file results in an empty state.
+func loadExportState(fsys FS) *exportState {
+	state := &exportState{
+		fsys:      fsys,
+		Playlists: make(map[string]string),
+	}
+
+	file, err := fsys.Open(StateFileName)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			Log(LogEntry{Level: LOG_WARNING, Message: fmt.Sprintf("Unable to read export state %v, exporting all playlists: %v\n", StateFileName, err), Path: StateFileName, Error: err.Error()})
+		}
+		return state
+	}
+	defer file.Close()
+
+	if err := json.NewDecoder(file).Decode(state); err != nil {
+		Log(LogEntry{Level: LOG_WARNING, Message: fmt.Sprintf("Unable to read export state %v, exporting all playlists: %v\n", StateFileName, err), Path: StateFileName, Error: err.Error()})
+		state.Playlists = make(map[string]string)
+	}
+	return state
+}
+
+// unchanged reports whether fileName was written by a previous export with the same hash
+// and still exists.
+func (state *exportState) unchanged(fileName string, hash string) bool {
+	state.mutex.Lock()
+	previousHash, ok := state.Playlists[fileName]
+	state.mutex.Unlock()
+
+	if !ok || previousHash != hash {
+		return false
+	}
+	_, err := state.fsys.Stat(fileName)
+	return err == nil
+}
+
+func (state *exportState) update(fileName string, hash string) {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	state.Playlists[fileName] = hash
+}
+
+// forget removes the hash of fileName, so the playlist is written by the next export.
+func (state *exportState) forget(fileName string) {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	delete(state.Playlists, fileName)
+}
+
+func (state *exportState) save() error {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	return writeFile(state.fsys, StateFileName, func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(state)
+	})
+}
+
+// playlistHash hashes everything that influences the content of the exported playlist
+// and the copied music files.
+func playlistHash(exportSettings *ExportSettings, playlist *itunes.Playlist) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%v\n%v\n%v\n%v\n%v\n%v\n%v\n%v\n", Version, exportSettings.ExportType, exportSettings.CopyType,
+		exportSettings.CopyTemplate, exportSettings.OriginalMusicPath, exportSettings.NewMusicPath, exportSettings.IncludeFolders, playlist.Name)
+	fmt.Fprintf(hash, "%v\n%v\n%v\n%v\n%v\n%v\n%v\n", exportSettings.MinDuration, exportSettings.MaxDuration, exportSettings.MaxFileSize,
+		exportSettings.CleanOnly, exportSettings.MinBitrate, exportSettings.Codecs, exportSettings.Audiobooks)
+	fmt.Fprintf(hash, "%v\n%v\n%v\n%v\n", exportSettings.Podcasts, exportSettings.FeedURL, exportSettings.Videos, exportSettings.Replacements)
+	fmt.Fprintf(hash, "%v\n%v\n%v\n%v\n%v\n", exportSettings.PathStyle, exportSettings.Encoding, exportSettings.MaxFilenameLength,
+		exportSettings.MaxFolderDepth, exportSettings.MaxPlaylistEntries)
+	fmt.Fprintf(hash, "%v\n%v\n%v\n%v\n%v\n%v\n%v\n", exportSettings.ExtinfTemplate, exportSettings.AlbumDirectives, exportSettings.ShortNames,
+		exportSettings.PlaylistInFolder, exportSettings.MaxFilesPerDir, exportSettings.StripSymbols, exportSettings.NameCase)
+	for _, track := range playlist.Tracks(exportSettings.Library) {
+		// Whether a track is ignored is hashed rather than the -ignore file, as its paths
+		// only match tracks once resolved.
+		fmt.Fprintf(hash, "%v\n%v\n%v\n%v\n%v\n%v\n%v\n%v\n%v\n", track.TrackId, track.Location, track.Title(), track.Artist,
+			track.Album, track.TotalTime, track.Size, track.DateModified.Unix(), exportSettings.Ignore.Ignores(track))
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil))
+}
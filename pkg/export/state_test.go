@@ -0,0 +1,165 @@
+package export
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+func TestIncrementalExportSkipsUnchangedPlaylists(t *testing.T) {
+	fsys := NewMemFS()
+	library := testLibrary()
+	settings := &ExportSettings{
+		Library:     library,
+		Playlists:   library.Playlists,
+		ExportType:  M3U,
+		Extension:   "m3u",
+		Output:      fsys,
+		Incremental: true,
+	}
+
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fsys.Stat(StateFileName); err != nil {
+		t.Fatalf("state not written: %v", err)
+	}
+
+	// An unchanged playlist is not written again.
+	writeFile(fsys, "My Playlist.m3u", func(w io.Writer) error {
+		_, err := io.WriteString(w, "marker")
+		return err
+	})
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := fsys.ReadFile("My Playlist.m3u"); string(content) != "marker" {
+		t.Fatalf("expected unchanged playlist to be skipped, got %q", content)
+	}
+
+	// Changing a track changes the playlist.
+	track := library.Tracks["1"]
+	track.Location = "file:///music/other.mp3"
+	library.Tracks["1"] = track
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := fsys.ReadFile("My Playlist.m3u"); !strings.Contains(string(content), "\n"+filepath.FromSlash("/music/other.mp3")+"\n") {
+		t.Fatalf("expected changed playlist to be written, got %q", content)
+	}
+}
+
+func TestIncrementalExportRewritesPlaylistsAfterSettingsChange(t *testing.T) {
+	fsys := NewMemFS()
+	library := testLibrary()
+	settings := &ExportSettings{
+		Library:     library,
+		Playlists:   library.Playlists,
+		ExportType:  M3U,
+		Extension:   "m3u",
+		Output:      fsys,
+		Incremental: true,
+	}
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(fsys, "My Playlist.m3u", func(w io.Writer) error {
+		_, err := io.WriteString(w, "marker")
+		return err
+	})
+
+	settings.PathStyle = PATH_STYLE_RELATIVE_BACKSLASH
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := fsys.ReadFile("My Playlist.m3u"); string(content) == "marker" {
+		t.Fatal("expected the playlist to be written again after -pathStyle changed")
+	}
+}
+
+func TestPlaylistHashCoversSettings(t *testing.T) {
+	library := testLibrary()
+	changes := map[string]func(settings *ExportSettings){
+		"PathStyle":          func(settings *ExportSettings) { settings.PathStyle = PATH_STYLE_RELATIVE },
+		"Encoding":           func(settings *ExportSettings) { settings.Encoding = ENCODING_CP1252 },
+		"MaxFilenameLength":  func(settings *ExportSettings) { settings.MaxFilenameLength = 64 },
+		"MaxFolderDepth":     func(settings *ExportSettings) { settings.MaxFolderDepth = 2 },
+		"MaxPlaylistEntries": func(settings *ExportSettings) { settings.MaxPlaylistEntries = 100 },
+		"ExtinfTemplate":     func(settings *ExportSettings) { settings.ExtinfTemplate = "{artist} - {title}" },
+		"AlbumDirectives":    func(settings *ExportSettings) { settings.AlbumDirectives = true },
+		"ShortNames":         func(settings *ExportSettings) { settings.ShortNames = true },
+		"PlaylistInFolder":   func(settings *ExportSettings) { settings.PlaylistInFolder = true },
+		"MaxFilesPerDir":     func(settings *ExportSettings) { settings.MaxFilesPerDir = 500 },
+		"StripSymbols":       func(settings *ExportSettings) { settings.StripSymbols = true },
+		"NameCase":           func(settings *ExportSettings) { settings.NameCase = NAME_CASE_LOWER },
+	}
+	for name, change := range changes {
+		settings := &ExportSettings{Library: library, ExportType: M3U}
+		before := playlistHash(settings, &library.Playlists[0])
+		change(settings)
+		if playlistHash(settings, &library.Playlists[0]) == before {
+			t.Errorf("expected changing %v to change the playlist hash", name)
+		}
+	}
+}
+
+func TestPlaylistHashCoversIgnoredTracks(t *testing.T) {
+	dir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(dir)
+	ignoreFile := filepath.Join(dir, "ignore.txt")
+	writeLocalFile(t, ignoreFile, "# broken\n/music/song.mp3\n")
+	ignore, err := itunes.LoadIgnoreList(ignoreFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	library := testLibrary()
+	track := library.Tracks["1"]
+	track.Location = "file:///music/song.mp3"
+	library.Tracks["1"] = track
+	settings := &ExportSettings{Library: library, ExportType: M3U}
+	before := playlistHash(settings, &library.Playlists[0])
+
+	settings.Ignore = ignore
+	if playlistHash(settings, &library.Playlists[0]) == before {
+		t.Fatal("expected ignoring a track of the playlist to change the playlist hash")
+	}
+}
+
+func TestIncrementalExportRetriesFailedCopies(t *testing.T) {
+	outputDir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(outputDir)
+	sourceDir := createTempDir(t, "itunes-exporter-source")
+	defer os.RemoveAll(sourceDir)
+
+	source := filepath.Join(sourceDir, "song.mp3")
+	library := testLibrary()
+	track := library.Tracks["1"]
+	track.Location = "file://" + filepath.ToSlash(source)
+	library.Tracks["1"] = track
+	settings := &ExportSettings{Library: library, Playlists: library.Playlists, OutputPath: outputDir, CopyType: COPY_FLAT, MusicDir: "Music", Incremental: true}
+	ParseExportType(settings, M3U)
+
+	// The source is missing, the playlist is written without it.
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+
+	writeLocalFile(t, source, "music")
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+	assertPathExists(t, filepath.Join(outputDir, "Music", "song.mp3"))
+	content, err := ioutil.ReadFile(filepath.Join(outputDir, "My Playlist.m3u"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "song.mp3") {
+		t.Fatalf("expected the playlist to list the track copied by the second run, got %q", content)
+	}
+}
@@ -0,0 +1,136 @@
+package export
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+// StoreDir is the directory of the output COPY_STORE copies the music files to, named by
+// the SHA-256 checksum of their content.
+const StoreDir = ".store"
+
+// storeIndexFile is the file of the store recording the checksums of the source files, so
+// unchanged files do not need to be read again by the next export.
+const storeIndexFile = "index.json"
+
+// Linker is implemented by file systems that can create symbolic links.
+type Linker interface {
+	// Symlink creates name as a symbolic link to target, replacing an existing file.
+	Symlink(target, name string) error
+}
+
+var errSymlinksUnsupported = errors.New("symbolic links are not supported by the output")
+
+// store copies music files into the content addressed store of an export.
+type store struct {
+	mutex sync.Mutex
+	fsys  FS
+	dir   string
+
+	// Sources maps source files with their size and modification time to their checksum.
+	Sources map[string]string `json:"sources"`
+	changed bool
+	// linkErr is the error of creating a symbolic link, which is reported once.
+	linkErr error
+}
+
+// loadStore reads the index of the store in dir of fsys. A missing or unreadable index
+// results in an empty index.
+func loadStore(fsys FS, dir string) *store {
+	store := &store{fsys: fsys, dir: dir, Sources: make(map[string]string)}
+
+	file, err := fsys.Open(path.Join(dir, storeIndexFile))
+	if err != nil {
+		return store
+	}
+	defer file.Close()
+	if err := json.NewDecoder(file).Decode(store); err != nil {
+		Log(LogEntry{Level: LOG_WARNING, Message: fmt.Sprintf("Unable to read the index of the store, the music files are read again: %v\n", err), Error: err.Error()})
+		store.Sources = make(map[string]string)
+	}
+	return store
+}
+
+// path returns the path in the store of the source file.
+func (store *store) path(src string) (string, error) {
+	src, info, err := itunes.StatPath(src)
+	if err != nil {
+		return "", err
+	}
+	key := fmt.Sprintf("%v|%v|%v", src, info.Size(), info.ModTime().UnixNano())
+
+	store.mutex.Lock()
+	sum, ok := store.Sources[key]
+	store.mutex.Unlock()
+	if !ok {
+		if sum, err = hashSourceFile(src); err != nil {
+			return "", err
+		}
+		store.mutex.Lock()
+		store.Sources[key] = sum
+		store.changed = true
+		store.mutex.Unlock()
+	}
+	return path.Join(store.dir, sum[:2], sum+strings.ToLower(filepath.Ext(src))), nil
+}
+
+// link creates name as a symbolic link to the file dest of the store. Failures are reported
+// once, as the output probably does not support symbolic links at all.
+func (store *store) link(dest, name string) {
+	target, err := filepath.Rel(filepath.FromSlash(path.Dir(name)), filepath.FromSlash(dest))
+	if err == nil {
+		linker, ok := store.fsys.(Linker)
+		if !ok {
+			err = errSymlinksUnsupported
+		} else {
+			err = linker.Symlink(filepath.ToSlash(target), name)
+		}
+	}
+	if err == nil {
+		return
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if store.linkErr == nil {
+		store.linkErr = err
+		Log(LogEntry{Level: LOG_WARNING, Message: fmt.Sprintf("Unable to link the music files into the playlist folders, the playlists still reference the store: %v\n", err), Error: err.Error()})
+	}
+}
+
+func (store *store) save() error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if !store.changed {
+		return nil
+	}
+	return writeFile(store.fsys, path.Join(store.dir, storeIndexFile), func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(store)
+	})
+}
+
+func hashSourceFile(name string) (string, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,72 @@
+package export
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+func TestExportStore(t *testing.T) {
+	outputDir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(outputDir)
+
+	musicFile, musicFileName := prepareMusicFile(t)
+	defer os.Remove(musicFile)
+
+	library := testLibrary()
+	track := library.Tracks["1"]
+	track.Location = "file://" + filepath.ToSlash(musicFile)
+	library.Tracks["1"] = track
+	other := itunes.Playlist{Name: "Other Playlist", PlaylistPersistentId: "0C1E2A3B4D5F6071", PlaylistItems: []itunes.PlaylistItem{{TrackId: 1}}}
+	library.Playlists = append(library.Playlists, other)
+	library.PlaylistMap[other.Name] = other
+	library.PlaylistIdMap[other.PlaylistPersistentId] = other
+
+	settings := &ExportSettings{Library: library, Playlists: library.Playlists, CopyType: COPY_STORE, OutputPath: outputDir}
+	ParseExportType(settings, M3U)
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+
+	// sha256 of FileContent
+	storeFile := filepath.Join(StoreDir, "73", "73475cb40a568e8da8a045ced110137e159f890ac4da883b6b17dc651b3a8049.mp3")
+	if content := readFile(t, filepath.Join(outputDir, storeFile)); content != FileContent {
+		t.Fatalf("unexpected content of the stored music file %q", content)
+	}
+	files, err := ioutil.ReadDir(filepath.Join(outputDir, StoreDir, "73"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected the music file to be stored once, got %v files", len(files))
+	}
+	assertPathExists(t, filepath.Join(outputDir, StoreDir, storeIndexFile))
+
+	for _, name := range []string{"My Playlist", "Other Playlist"} {
+		assertPlaylistFileCorrectlyWritten(t, filepath.Join(outputDir, name+".m3u"), filepath.Join(outputDir, storeFile))
+
+		link := filepath.Join(outputDir, name, musicFileName)
+		target, err := os.Readlink(link)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := filepath.Join("..", storeFile); target != expected {
+			t.Fatalf("expected link to %v, got %v", expected, target)
+		}
+		if content := readFile(t, link); content != FileContent {
+			t.Fatalf("unexpected content of the linked music file %q", content)
+		}
+	}
+
+	// The checksum is taken from the index, the existing links are kept.
+	store := loadStore(NewDirFS(outputDir), StoreDir)
+	if len(store.Sources) != 1 {
+		t.Fatalf("expected one indexed music file, got %v", store.Sources)
+	}
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,276 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+// templatePlaceholder matches the placeholders of a copy template, e.g. {album}, with an
+// optional fallback for empty values, e.g. {album|Unknown Album}.
+var templatePlaceholder = regexp.MustCompile(`\{[A-Za-z]+(\|[^{}]*)?\}`)
+
+// templateIllegalChars are the characters replaced in the values of placeholders, as they
+// separate directories or are not allowed in names on common file systems.
+var templateIllegalChars = regexp.MustCompile(`[\\/:*?"<>|]`)
+
+// templateValue returns the value of the placeholder of a copy template, e.g. {album}, for
+// the track copied from the source file for playlist, or its fallback if the value is empty,
+// e.g. "Unknown Album" for {album|Unknown Album}. It reports false for unknown placeholders.
+func templateValue(placeholder string, track *itunes.Track, playlist *itunes.Playlist, source string) (string, bool) {
+	name, fallback := splitPlaceholder(placeholder)
+	value, ok := placeholderValue(name, track, playlist, source)
+	if ok && strings.TrimSpace(value) == "" {
+		value = fallback
+	}
+	return value, ok
+}
+
+// splitPlaceholder splits a placeholder with a fallback, e.g. {album|Unknown Album}, into the
+// placeholder, {album}, and its fallback.
+func splitPlaceholder(placeholder string) (string, string) {
+	i := strings.Index(placeholder, "|")
+	if i < 0 {
+		return placeholder, ""
+	}
+	return placeholder[:i] + "}", strings.TrimSuffix(placeholder[i+1:], "}")
+}
+
+// placeholderValue returns the value of a placeholder without fallback for templateValue.
+func placeholderValue(placeholder string, track *itunes.Track, playlist *itunes.Playlist, source string) (string, bool) {
+	switch strings.ToLower(placeholder) {
+	case "{artist}":
+		return track.Artist, true
+	case "{albumartist}":
+		return albumArtistFolder(track), true
+	case "{album}":
+		return track.Album, true
+	case "{title}":
+		return track.Name, true
+	case "{composer}":
+		return track.Composer, true
+	case "{work}":
+		return track.Work, true
+	case "{movement}":
+		return track.MovementName, true
+	case "{movementnumber}":
+		return positive(track.MovementNumber, "%02d"), true
+	case "{genre}":
+		return track.Genre, true
+	case "{year}":
+		return positive(track.Year, "%d"), true
+	case "{track}":
+		return positive(track.TrackNumber, "%02d"), true
+	case "{disc}":
+		return positive(track.DiscNumber, "%d"), true
+	case "{cd}":
+		if !multiDisc(track) {
+			return "", true
+		}
+		return "CD" + strconv.Itoa(track.DiscNumber), true
+	case "{disctrack}":
+		if !multiDisc(track) {
+			return positive(track.TrackNumber, "%02d"), true
+		}
+		return fmt.Sprintf("%d-%02d", track.DiscNumber, track.TrackNumber), true
+	case "{chapter}":
+		if !multiDisc(track) {
+			return positive(track.TrackNumber, "%03d"), true
+		}
+		return fmt.Sprintf("%d-%03d", track.DiscNumber, track.TrackNumber), true
+	case "{show}":
+		return itunes.PodcastShow(*track), true
+	case "{date}":
+		if published := track.Published(); !published.IsZero() {
+			return published.Format("2006-01-02"), true
+		}
+		return "", true
+	case "{series}":
+		if track.Series != "" {
+			return track.Series, true
+		}
+		return track.Album, true
+	case "{season}":
+		return positive(track.Season, "%02d"), true
+	case "{episode}":
+		if track.EpisodeOrder > 0 {
+			return positive(track.EpisodeOrder, "%02d"), true
+		}
+		return positive(track.TrackNumber, "%02d"), true
+	case "{playlist}":
+		return playlist.Name, true
+	case "{filename}":
+		return filepath.Base(source), true
+	case "{ext}":
+		return strings.ToLower(filepath.Ext(source)), true
+	default:
+		return "", false
+	}
+}
+
+// ParseCopyTemplate sets the copy type of settings to COPY_TEMPLATE with the template, the
+// path the music files are copied to, e.g. "{albumartist}/{album}/{cd}/{track} {title}".
+func ParseCopyTemplate(settings *ExportSettings, template string) error {
+	if strings.TrimSpace(template) == "" {
+		return errors.New("Empty Copy Template")
+	}
+	for _, placeholder := range templatePlaceholder.FindAllString(template, -1) {
+		if _, ok := templateValue(placeholder, &itunes.Track{}, &itunes.Playlist{}, ""); !ok {
+			return errors.New("Unknown Copy Template Placeholder: " + placeholder)
+		}
+	}
+	settings.CopyType = COPY_TEMPLATE
+	settings.CopyTemplate = template
+	return nil
+}
+
+// ParseExtinfTemplate sets the text of the #EXTINF lines of extended M3U playlists written
+// with settings, e.g. "{artist} - {title} ({album})".
+func ParseExtinfTemplate(settings *ExportSettings, template string) error {
+	for _, placeholder := range templatePlaceholder.FindAllString(template, -1) {
+		if _, ok := templateValue(placeholder, &itunes.Track{}, &itunes.Playlist{}, ""); !ok {
+			return errors.New("Unknown EXTINF Template Placeholder: " + placeholder)
+		}
+	}
+	settings.ExtinfTemplate = template
+	return nil
+}
+
+// expandExtinfTemplate returns the text of the #EXTINF line of the entry of playlist
+// according to template. Line breaks in the values of placeholders are replaced by spaces.
+func expandExtinfTemplate(template string, entry Entry, playlist Playlist) string {
+	return templatePlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		value, ok := templateValue(placeholder, entry.Track, &itunes.Playlist{Name: playlist.Name}, entry.Location)
+		if !ok {
+			return placeholder
+		}
+		return strings.Join(strings.Fields(value), " ")
+	})
+}
+
+// expandCopyTemplate returns the path of the copy of the source file of track according to
+// template, with the replacements of exportSettings applied to the values of placeholders.
+// Directories expanding to an empty name, e.g. {cd} of a single disc album, are left out.
+// The extension of the source file is added unless the template contains {ext} or
+// {filename}.
+func expandCopyTemplate(exportSettings *ExportSettings, template string, track *itunes.Track, playlist *itunes.Playlist, source string) string {
+	expanded := templatePlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		value, ok := templateValue(placeholder, track, playlist, source)
+		if !ok {
+			return placeholder
+		}
+		return templateIllegalChars.ReplaceAllString(exportSettings.replace(value), "_")
+	})
+
+	var parts []string
+	for _, part := range strings.Split(expanded, "/") {
+		if part = strings.TrimSpace(part); part != "" && part != "." && part != ".." {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		parts = []string{strings.TrimSuffix(filepath.Base(source), filepath.Ext(source))}
+	}
+
+	lower := strings.ToLower(template)
+	if !strings.Contains(lower, "{ext}") && !strings.Contains(lower, "{filename}") {
+		parts[len(parts)-1] += strings.ToLower(filepath.Ext(source))
+	}
+	return path.Join(parts...)
+}
+
+// trackCopyTemplate returns the copy template of track with exportSettings, if it is copied
+// by one.
+func trackCopyTemplate(exportSettings *ExportSettings, track *itunes.Track) (string, bool) {
+	switch exportSettings.CopyType {
+	case COPY_TEMPLATE:
+		return exportSettings.CopyTemplate, true
+	case COPY_VIDEO:
+		return videoCopyTemplate(track), true
+	}
+	return "", false
+}
+
+// checkCopyTemplates expands the copy templates for the tracks of all playlists exported with
+// exportSettings before anything is copied. It prints the tracks with placeholders expanding
+// to an empty value without fallback, other than {cd}, and the tracks copied to the same path
+// as another music file, and returns an error if there are any.
+func checkCopyTemplates(exportSettings *ExportSettings) error {
+	problems := 0
+	sources := make(map[string]string)
+	for _, playlist := range exportSettings.Playlists {
+		if playlist.Container() {
+			continue
+		}
+		settings, err := exportSettings.forPlaylist(playlist.Name)
+		if err != nil {
+			return err
+		}
+		for _, track := range playlist.Tracks(settings.Library) {
+			track, clean := cleanTrack(settings, track)
+			template, ok := trackCopyTemplate(settings, &track)
+			if !clean || !ok || !includeTrack(settings, &track) {
+				continue
+			}
+
+			source := musicSource(settings, itunes.LocationPath(track.Location))
+			for _, placeholder := range templatePlaceholder.FindAllString(template, -1) {
+				value, _ := templateValue(placeholder, &track, &playlist, source)
+				if strings.TrimSpace(value) == "" && !strings.EqualFold(placeholder, "{cd}") {
+					Log(LogEntry{Level: LOG_ERROR, Message: fmt.Sprintf("%v of %v in Playlist %v is empty, add a fallback like {album|Unknown Album}.\n", placeholder, source, playlist.Name), Playlist: playlist.Name, Track: track.Title(), Path: source})
+					problems++
+				}
+			}
+
+			dest := path.Join(settings.MusicDir, expandCopyTemplate(settings, template, &track, &playlist, source))
+			key := strings.ToLower(dest)
+			if other, ok := sources[key]; ok && other != source {
+				Log(LogEntry{Level: LOG_ERROR, Message: fmt.Sprintf("%v and %v would both be copied to %v.\n", other, source, dest), Playlist: playlist.Name, Track: track.Title(), Path: source})
+				problems++
+			}
+			sources[key] = source
+		}
+	}
+	if problems > 0 {
+		return fmt.Errorf("copy template gives %v ambiguous paths, nothing was copied", problems)
+	}
+	return nil
+}
+
+// videoCopyTemplate returns the copy template of the video track for COPY_VIDEO, named like
+// media servers expect, e.g. "TV Shows/{series}/Season {season}/{series} - S{season}E{episode} - {title}".
+func videoCopyTemplate(track *itunes.Track) string {
+	switch {
+	case track.TVShow && track.Season > 0:
+		return "TV Shows/{series}/Season {season}/{series} - S{season}E{episode} - {title}"
+	case track.TVShow:
+		return "TV Shows/{series}/{series} - {episode} {title}"
+	case track.MusicVideo:
+		return "Music Videos/{artist}/{title}"
+	case track.Movie && track.Year > 0:
+		return "Movies/{title} ({year})/{title} ({year})"
+	case track.Movie:
+		return "Movies/{title}/{title}"
+	default:
+		return "Home Videos/{title}"
+	}
+}
+
+// multiDisc reports whether the album of track has several discs.
+func multiDisc(track *itunes.Track) bool {
+	return track.DiscCount > 1 || track.DiscNumber > 1
+}
+
+// positive formats value if it is set.
+func positive(value int, format string) string {
+	if value <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(format, value)
+}
@@ -0,0 +1,126 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+func TestExpandCopyTemplate(t *testing.T) {
+	playlist := &itunes.Playlist{Name: "Road Trip"}
+	single := &itunes.Track{Name: "Song: Live", Artist: "AC/DC", Album: "Live", TrackNumber: 3, DiscNumber: 1, DiscCount: 1}
+	multi := &itunes.Track{Name: "Song", Artist: "Band", Album: "Box", TrackNumber: 7, DiscNumber: 2, DiscCount: 3}
+	movement := &itunes.Track{Name: "Andante", Composer: "Beethoven", Work: "Symphony No. 5", MovementName: "Andante con moto", MovementNumber: 2}
+
+	for _, test := range []struct {
+		template string
+		track    *itunes.Track
+		expected string
+	}{
+		{"{albumartist}/{album}/{cd}/{track} {title}", single, "AC_DC/Live/03 Song_ Live.mp3"},
+		{"{albumartist}/{album}/{cd}/{track} {title}", multi, "Band/Box/CD2/07 Song.mp3"},
+		{"{artist}/{album}/{disctrack} {title}", single, "AC_DC/Live/03 Song_ Live.mp3"},
+		{"{artist}/{album}/{disctrack} {title}", multi, "Band/Box/2-07 Song.mp3"},
+		{"{playlist}/{filename}", multi, "Road Trip/song.MP3"},
+		{"{ARTIST}/{title}{ext}", multi, "Band/Song.mp3"},
+		{"{composer}/{work}/{movementnumber} {movement}", movement, "Beethoven/Symphony No. 5/02 Andante con moto.mp3"},
+		{AudiobookCopyTemplate, single, "AC_DC/Live/003 Song_ Live.mp3"},
+		{AudiobookCopyTemplate, multi, "Band/Box/2-007 Song.mp3"},
+		{"{genre|Unknown Genre}/{album|Unknown Album}/{title}", single, "Unknown Genre/Live/Song_ Live.mp3"},
+	} {
+		if dest := expandCopyTemplate(&ExportSettings{}, test.template, test.track, playlist, "/music/song.MP3"); dest != test.expected {
+			t.Fatalf("expected %v for %v, got %v", test.expected, test.template, dest)
+		}
+	}
+}
+
+func TestParseCopyTemplate(t *testing.T) {
+	settings := &ExportSettings{}
+	if err := ParseCopyTemplate(settings, "{artist}/{album}/{track} {title}"); err != nil {
+		t.Fatal(err)
+	}
+	if settings.CopyType != COPY_TEMPLATE {
+		t.Fatalf("expected COPY_TEMPLATE, got %v", settings.CopyType)
+	}
+	for _, template := range []string{"{artist}/{conductor}", "{conductor|Unknown}", " "} {
+		if err := ParseCopyTemplate(&ExportSettings{}, template); err == nil {
+			t.Fatalf("expected error for %q", template)
+		}
+	}
+}
+
+func TestExportWithCopyTemplate(t *testing.T) {
+	outputDir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(outputDir)
+
+	musicFile, _ := prepareMusicFile(t)
+	defer os.Remove(musicFile)
+
+	library := testLibrary()
+	track := library.Tracks["1"]
+	track.Album = "Box"
+	track.DiscNumber, track.DiscCount, track.TrackNumber = 2, 2, 5
+	track.Location = "file://" + filepath.ToSlash(musicFile)
+	library.Tracks["1"] = track
+
+	settings := &ExportSettings{Library: library, Playlists: library.Playlists, OutputPath: outputDir, PathStyle: PATH_STYLE_RELATIVE}
+	ParseExportType(settings, M3U)
+	if err := ParseCopyTemplate(settings, "{albumartist}/{album}/{cd}/{track} {title}"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+	assertPathExists(t, filepath.Join(outputDir, "Some Artist", "Box", "CD2", "05 Some Song.mp3"))
+	assertPlaylistFileCorrectlyWritten(t, filepath.Join(outputDir, "My Playlist.m3u"), "Some Artist/Box/CD2/05 Some Song.mp3")
+}
+
+func TestStrictTemplate(t *testing.T) {
+	outputDir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(outputDir)
+
+	library := testLibrary()
+	track := library.Tracks["1"]
+	track.Location = "file:///music/some.mp3"
+	library.Tracks["1"] = track
+	library.Tracks["2"] = itunes.Track{TrackId: 2, Name: "Some Song", Artist: "Some Artist", Location: "file:///music/other.mp3"}
+	library.Playlists[0].PlaylistItems = append(library.Playlists[0].PlaylistItems, itunes.PlaylistItem{TrackId: 2})
+
+	settings := &ExportSettings{Library: library, Playlists: library.Playlists, OutputPath: outputDir, StrictTemplate: true}
+	ParseExportType(settings, M3U)
+	for template, ambiguous := range map[string]bool{
+		"{artist}/{album}/{title}":                  true,
+		"{artist}/{album|Unknown Album}/{title}":    true,
+		"{artist}/{album|Unknown Album}/{filename}": false,
+	} {
+		if err := ParseCopyTemplate(settings, template); err != nil {
+			t.Fatal(err)
+		}
+		err := checkCopyTemplates(settings)
+		if ambiguous && err == nil {
+			t.Fatalf("expected ambiguous paths for %v", template)
+		}
+		if !ambiguous && err != nil {
+			t.Fatalf("expected no ambiguous paths for %v, got %v", template, err)
+		}
+	}
+}
+
+func TestVideoCopyTemplate(t *testing.T) {
+	playlist := &itunes.Playlist{Name: "Videos"}
+	for _, test := range []struct {
+		track    *itunes.Track
+		expected string
+	}{
+		{&itunes.Track{Name: "Pilot", Series: "The Show", Season: 1, EpisodeOrder: 2, TVShow: true}, "TV Shows/The Show/Season 01/The Show - S01E02 - Pilot.m4v"},
+		{&itunes.Track{Name: "Heist", Year: 1995, Movie: true}, "Movies/Heist (1995)/Heist (1995).m4v"},
+		{&itunes.Track{Name: "Clip", Artist: "Band", MusicVideo: true}, "Music Videos/Band/Clip.m4v"},
+		{&itunes.Track{Name: "Birthday", HasVideo: true}, "Home Videos/Birthday.m4v"},
+	} {
+		if dest := expandCopyTemplate(&ExportSettings{}, videoCopyTemplate(test.track), test.track, playlist, "/videos/video.m4v"); dest != test.expected {
+			t.Fatalf("expected %v, got %v", test.expected, dest)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package export
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"html"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+const (
+	PROBLEM_MISSING        = "missing"
+	PROBLEM_CHECKSUM       = "checksum mismatch"
+	PROBLEM_NOT_IN_LIBRARY = "playlist not in library"
+	PROBLEM_TRACKS_DIFFER  = "tracks differ from library"
+	PROBLEM_UNREADABLE     = "unreadable"
+)
+
+// VerifyProblem is a problem found by VerifyExport.
+type VerifyProblem struct {
+	// Playlist is the playlist file, relative to the output directory.
+	Playlist string
+	// Location is the referenced file the problem was found with, if any.
+	Location string
+	Problem  string
+}
+
+// VerifyReport is the result of VerifyExport.
+type VerifyReport struct {
+	Playlists int
+	Files     int
+	Problems  []VerifyProblem
+}
+
+// playlistReaders return the locations referenced by a playlist file, by file extension.
+var playlistReaders = map[string]func(io.Reader) ([]string, error){
+	"m3u":  readM3ULocations,
+	"m3u8": readM3ULocations,
+	"wpl":  readSmilLocations,
+	"zpl":  readSmilLocations,
+}
+
+// VerifyExport checks the playlist files in the directory outputPath and reports every file
+// referenced by a playlist which does not exist. Relative locations are resolved against the
+// directory of the playlist.
+//
+// If library is set, playlists which are not part of the library or whose number of tracks
+// differs from the library are reported as well. With checksum, copied music files are
+// compared to their source in the library.
+func VerifyExport(ctx context.Context, outputPath string, library *itunes.Library, checksum bool) (*VerifyReport, error) {
+	report := &VerifyReport{}
+
+	err := filepath.Walk(outputPath, func(fileName string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// Snapshots, backups and the store are kept in hidden directories.
+		if info.IsDir() && fileName != outputPath && strings.HasPrefix(info.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".") {
+			return nil
+		}
+		read, ok := playlistReaders[strings.ToLower(strings.TrimPrefix(filepath.Ext(fileName), "."))]
+		if !ok {
+			return nil
+		}
+
+		relativeName, err := filepath.Rel(outputPath, fileName)
+		if err != nil {
+			return err
+		}
+		report.Playlists++
+
+		locations, err := readLocations(fileName, read)
+		if err != nil {
+			report.Problems = append(report.Problems, VerifyProblem{Playlist: relativeName, Problem: PROBLEM_UNREADABLE + ": " + err.Error()})
+			return nil
+		}
+
+		var sources map[string]string
+		if library != nil {
+			sources = verifyPlaylistAgainstLibrary(report, library, relativeName, len(locations))
+		}
+
+		for _, location := range locations {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			report.Files++
+
+			location = strings.TrimPrefix(location, "file://")
+			if !filepath.IsAbs(location) {
+				location = filepath.Join(filepath.Dir(fileName), filepath.FromSlash(location))
+			}
+
+			location, _, err = itunes.StatPath(location)
+			if err != nil {
+				report.Problems = append(report.Problems, VerifyProblem{Playlist: relativeName, Location: location, Problem: PROBLEM_MISSING})
+				continue
+			}
+
+			source, ok := sources[filepath.Base(location)]
+			if !checksum || !ok || source == location {
+				continue
+			}
+			same, err := sameContent(location, source)
+			if err != nil {
+				report.Problems = append(report.Problems, VerifyProblem{Playlist: relativeName, Location: location, Problem: PROBLEM_UNREADABLE + ": " + err.Error()})
+			} else if !same {
+				report.Problems = append(report.Problems, VerifyProblem{Playlist: relativeName, Location: location, Problem: PROBLEM_CHECKSUM})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// verifyPlaylistAgainstLibrary looks up the library playlist written to the playlist file
+// and reports it if it is missing or has a different number of tracks. It returns the
+// source locations of the tracks of the playlist by file name.
+func verifyPlaylistAgainstLibrary(report *VerifyReport, library *itunes.Library, playlistFile string, entries int) map[string]string {
+	name := strings.TrimSuffix(filepath.Base(playlistFile), filepath.Ext(playlistFile))
+
+	for _, playlist := range library.Playlists {
+		if playlist.Container() || playlist.SafeName() != name {
+			continue
+		}
+
+		tracks := playlist.Tracks(library)
+		if len(tracks) != entries {
+			report.Problems = append(report.Problems, VerifyProblem{Playlist: playlistFile, Problem: PROBLEM_TRACKS_DIFFER})
+		}
+
+		sources := make(map[string]string)
+		for _, track := range tracks {
+			location := itunes.LocationPath(track.Location)
+			sources[filepath.Base(location)] = location
+		}
+		return sources
+	}
+
+	report.Problems = append(report.Problems, VerifyProblem{Playlist: playlistFile, Problem: PROBLEM_NOT_IN_LIBRARY})
+	return nil
+}
+
+func readLocations(fileName string, read func(io.Reader) ([]string, error)) ([]string, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return read(file)
+}
+
+// readM3ULocations returns the locations of a M3U or extended M3U playlist.
+func readM3ULocations(r io.Reader) ([]string, error) {
+	var locations []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		locations = append(locations, line)
+	}
+	return locations, scanner.Err()
+}
+
+var smilMediaSource = regexp.MustCompile(`<media\s+src=(?:"([^"]*)"|'([^']*)'|([^\s>]*))`)
+
+// readSmilLocations returns the locations of a WPL or ZPL playlist.
+func readSmilLocations(r io.Reader) ([]string, error) {
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var locations []string
+	for _, match := range smilMediaSource.FindAllStringSubmatch(string(content), -1) {
+		locations = append(locations, html.UnescapeString(match[1]+match[2]+match[3]))
+	}
+	return locations, nil
+}
+
+// sameContent reports whether both files have the same content.
+func sameContent(fileName, otherFileName string) (bool, error) {
+	hash, err := fileHash(fileName)
+	if err != nil {
+		return false, err
+	}
+	otherHash, err := fileHash(otherFileName)
+	if err != nil {
+		return false, err
+	}
+	return string(hash) == string(otherHash), nil
+}
+
+func fileHash(fileName string) ([]byte, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return nil, err
+	}
+	return hash.Sum(nil), nil
+}
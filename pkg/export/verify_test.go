@@ -0,0 +1,85 @@
+package export
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerifyExport(t *testing.T) {
+	outputDir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(outputDir)
+
+	musicFile, musicFileName := prepareMusicFile(t)
+	defer os.Remove(musicFile)
+
+	library := testLibrary()
+	track := library.Tracks["1"]
+	track.Location = "file://" + filepath.ToSlash(musicFile)
+	library.Tracks["1"] = track
+
+	settings := &ExportSettings{Library: library, Playlists: library.Playlists, OutputPath: outputDir, CopyType: COPY_PLAYLIST}
+	ParseExportType(settings, "EXT")
+	if err := ExportPlaylists(settings, library); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := VerifyExport(context.Background(), outputDir, library, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Playlists != 1 || report.Files != 1 || len(report.Problems) != 0 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	copiedFile := filepath.Join(outputDir, "My Playlist", musicFileName)
+	writeLocalFile(t, copiedFile, "changed")
+	report, err = VerifyExport(context.Background(), outputDir, library, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Problems) != 1 || report.Problems[0].Problem != PROBLEM_CHECKSUM || report.Problems[0].Playlist != "My Playlist.m3u" {
+		t.Fatalf("expected checksum mismatch, got %+v", report.Problems)
+	}
+
+	os.Remove(copiedFile)
+	report, err = VerifyExport(context.Background(), outputDir, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Problems) != 1 || report.Problems[0].Problem != PROBLEM_MISSING || report.Problems[0].Location != copiedFile {
+		t.Fatalf("expected missing file, got %+v", report.Problems)
+	}
+}
+
+func TestVerifyExportReportsDriftFromLibrary(t *testing.T) {
+	outputDir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(outputDir)
+
+	writeLocalFile(t, filepath.Join(outputDir, "My Playlist.m3u"), "#EXTM3U\n")
+	writeLocalFile(t, filepath.Join(outputDir, "Old Playlist.m3u"), "#EXTM3U\n")
+
+	report, err := VerifyExport(context.Background(), outputDir, testLibrary(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Problems) != 2 || report.Problems[0].Problem != PROBLEM_TRACKS_DIFFER || report.Problems[1].Problem != PROBLEM_NOT_IN_LIBRARY {
+		t.Fatalf("unexpected problems %+v", report.Problems)
+	}
+}
+
+func TestReadSmilLocations(t *testing.T) {
+	locations, err := readSmilLocations(strings.NewReader(`<smil><body><seq>
+      <media src=/music/a.mp3></media>
+      <media src="/music/b &amp; c.mp3"/>
+      <media src='/music/d.mp3'/>
+</seq></body></smil>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(locations, "|") != "/music/a.mp3|/music/b & c.mp3|/music/d.mp3" {
+		t.Fatalf("unexpected locations %q", locations)
+	}
+}
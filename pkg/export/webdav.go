@@ -0,0 +1,263 @@
+package export
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WebDAVFS writes the export to a collection on a WebDAV server, e.g. Nextcloud or a NAS.
+type WebDAVFS struct {
+	root     url.URL
+	user     string
+	password string
+	client   *http.Client
+
+	mutex       sync.Mutex
+	collections map[string]bool
+}
+
+// NewWebDAVFS returns an FS writing to the collection of a webdav:// or webdavs:// URL, which
+// are accessed using HTTP and HTTPS. The user and password in the URL are used for basic
+// authentication. If the URL has a user but no password, the password is read from the
+// ITUNESEXPORT_WEBDAV_PASSWORD environment variable.
+func NewWebDAVFS(target *url.URL) (*WebDAVFS, error) {
+	root := *target
+	switch strings.ToLower(root.Scheme) {
+	case "webdav":
+		root.Scheme = "http"
+	case "webdavs":
+		root.Scheme = "https"
+	case "http", "https":
+	default:
+		return nil, fmt.Errorf("unsupported WebDAV URL scheme %v", root.Scheme)
+	}
+
+	fsys := &WebDAVFS{client: &http.Client{}, collections: make(map[string]bool)}
+	if root.User != nil {
+		fsys.user = root.User.Username()
+		password, ok := root.User.Password()
+		if !ok {
+			password = os.Getenv("ITUNESEXPORT_WEBDAV_PASSWORD")
+		}
+		fsys.password = password
+		root.User = nil
+	}
+	root.Path = strings.TrimSuffix(root.Path, "/")
+	root.RawPath = ""
+	fsys.root = root
+	return fsys, nil
+}
+
+// url returns the URL of the named file.
+func (fsys *WebDAVFS) url(name string) string {
+	fileURL := fsys.root
+	fileURL.Path = path.Join("/", fsys.root.Path, name)
+	return fileURL.String()
+}
+
+func (fsys *WebDAVFS) request(method, fileURL string, body io.Reader, header http.Header) (*http.Response, error) {
+	request, err := http.NewRequest(method, fileURL, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	if fsys.user != "" {
+		request.SetBasicAuth(fsys.user, fsys.password)
+	}
+	return fsys.client.Do(request)
+}
+
+// webDAVError returns the error for an unexpected response status of a request on name.
+func webDAVError(op, name string, response *http.Response) error {
+	if response.StatusCode == http.StatusNotFound {
+		return &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+	}
+	return &os.PathError{Op: op, Path: name, Err: fmt.Errorf("WebDAV server responded %v", response.Status)}
+}
+
+// simpleRequest sends a request and checks that it succeeded.
+func (fsys *WebDAVFS) simpleRequest(method, op, name, fileURL string, body io.Reader, header http.Header) error {
+	response, err := fsys.request(method, fileURL, body, header)
+	if err != nil {
+		return err
+	}
+	io.Copy(ioutil.Discard, response.Body)
+	response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return webDAVError(op, name, response)
+	}
+	return nil
+}
+
+// tempUploadCounter makes the names of uploads in progress unique.
+var tempUploadCounter int64
+
+// Create uploads to a temporary file while the file is written, which is moved into place
+// on Close.
+func (fsys *WebDAVFS) Create(name string) (File, error) {
+	name = path.Clean("/" + name)
+	if err := fsys.MkdirAll(path.Dir(name)); err != nil {
+		return nil, err
+	}
+
+	tempName := path.Join(path.Dir(name), fmt.Sprintf(".%v.%v.tmp", path.Base(name), atomic.AddInt64(&tempUploadCounter, 1)))
+	reader, writer := io.Pipe()
+	file := &webDAVFile{PipeWriter: writer, fsys: fsys, name: name, tempName: tempName, done: make(chan error, 1)}
+	go func() {
+		err := fsys.simpleRequest(http.MethodPut, "create", name, fsys.url(tempName), reader, nil)
+		reader.CloseWithError(err)
+		file.done <- err
+	}()
+	return file, nil
+}
+
+func (fsys *WebDAVFS) Open(name string) (io.ReadCloser, error) {
+	response, err := fsys.request(http.MethodGet, fsys.url(name), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		return nil, webDAVError("open", name, response)
+	}
+	return response.Body, nil
+}
+
+// webDAVMultistatus is the part of a PROPFIND response used by Stat.
+type webDAVMultistatus struct {
+	Responses []struct {
+		Prop struct {
+			ContentLength string `xml:"getcontentlength"`
+			LastModified  string `xml:"getlastmodified"`
+			ResourceType  struct {
+				Collection *struct{} `xml:"collection"`
+			} `xml:"resourcetype"`
+		} `xml:"propstat>prop"`
+	} `xml:"response"`
+}
+
+const webDAVPropfind = `<?xml version="1.0" encoding="utf-8"?>
+<propfind xmlns="DAV:"><prop><getcontentlength/><getlastmodified/><resourcetype/></prop></propfind>`
+
+func (fsys *WebDAVFS) Stat(name string) (os.FileInfo, error) {
+	header := http.Header{"Depth": {"0"}, "Content-Type": {"application/xml; charset=utf-8"}}
+	response, err := fsys.request("PROPFIND", fsys.url(name), strings.NewReader(webDAVPropfind), header)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusMultiStatus {
+		return nil, webDAVError("stat", name, response)
+	}
+
+	var multistatus webDAVMultistatus
+	if err := xml.NewDecoder(response.Body).Decode(&multistatus); err != nil {
+		return nil, err
+	}
+	if len(multistatus.Responses) == 0 {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+	}
+
+	prop := multistatus.Responses[0].Prop
+	info := &remoteFileInfo{name: path.Base(name), mode: 0644}
+	if prop.ResourceType.Collection != nil {
+		info.mode = os.ModeDir | 0755
+	}
+	info.size, _ = strconv.ParseInt(prop.ContentLength, 10, 64)
+	info.modTime, _ = time.Parse(http.TimeFormat, prop.LastModified)
+	return info, nil
+}
+
+// MkdirAll creates the collection name and any missing parents, including the collection
+// of the URL itself. Collections known to exist are not created again.
+func (fsys *WebDAVFS) MkdirAll(name string) error {
+	return fsys.mkdirAll(path.Join("/", fsys.root.Path, name))
+}
+
+// mkdirAll creates the collection with the path collectionPath on the server.
+func (fsys *WebDAVFS) mkdirAll(collectionPath string) error {
+	if collectionPath == "/" {
+		return nil
+	}
+
+	fsys.mutex.Lock()
+	exists := fsys.collections[collectionPath]
+	fsys.mutex.Unlock()
+	if exists {
+		return nil
+	}
+
+	if err := fsys.mkdirAll(path.Dir(collectionPath)); err != nil {
+		return err
+	}
+	collectionURL := fsys.root
+	collectionURL.Path = collectionPath + "/"
+	response, err := fsys.request("MKCOL", collectionURL.String(), nil, nil)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	// 405 Method Not Allowed is the response if the collection already exists.
+	if response.StatusCode != http.StatusCreated && response.StatusCode != http.StatusMethodNotAllowed {
+		return webDAVError("mkdir", collectionPath, response)
+	}
+
+	fsys.mutex.Lock()
+	fsys.collections[collectionPath] = true
+	fsys.mutex.Unlock()
+	return nil
+}
+
+func (fsys *WebDAVFS) Remove(name string) error {
+	return fsys.simpleRequest(http.MethodDelete, "remove", name, fsys.url(name), nil, nil)
+}
+
+// webDAVFile is a file being uploaded to a WebDAVFS.
+type webDAVFile struct {
+	*io.PipeWriter
+	fsys     *WebDAVFS
+	name     string
+	tempName string
+	done     chan error
+}
+
+func (file *webDAVFile) Close() error {
+	file.PipeWriter.Close()
+	if err := <-file.done; err != nil {
+		return err
+	}
+
+	header := http.Header{"Destination": {file.fsys.url(file.name)}, "Overwrite": {"T"}}
+	err := file.fsys.simpleRequest("MOVE", "rename", file.name, file.fsys.url(file.tempName), nil, header)
+	if err != nil {
+		file.fsys.Remove(file.tempName)
+	}
+	return err
+}
+
+// errAborted cancels the upload of an aborted file.
+var errAborted = errors.New("file aborted")
+
+// Abort cancels the upload and removes anything the server kept of it.
+func (file *webDAVFile) Abort() error {
+	file.PipeWriter.CloseWithError(errAborted)
+	<-file.done
+	if err := file.fsys.Remove(file.tempName); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
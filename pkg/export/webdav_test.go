@@ -0,0 +1,161 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeWebDAVHandler serves the requests of WebDAVFS from a local directory.
+func fakeWebDAVHandler(dir string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, _ := r.BasicAuth(); user != "user" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fileName := filepath.Join(dir, filepath.FromSlash(r.URL.Path))
+
+		var err error
+		switch r.Method {
+		case http.MethodPut:
+			var content []byte
+			if content, err = ioutil.ReadAll(r.Body); err == nil {
+				err = ioutil.WriteFile(fileName, content, 0644)
+			}
+		case http.MethodGet:
+			var content []byte
+			if content, err = ioutil.ReadFile(fileName); err == nil {
+				w.Write(content)
+				return
+			}
+		case "PROPFIND":
+			var info os.FileInfo
+			if info, err = os.Stat(fileName); err == nil {
+				resourceType := ""
+				if info.IsDir() {
+					resourceType = "<D:collection/>"
+				}
+				w.WriteHeader(http.StatusMultiStatus)
+				fmt.Fprintf(w, `<?xml version="1.0"?><D:multistatus xmlns:D="DAV:"><D:response><D:href>%v</D:href>`+
+					`<D:propstat><D:prop><D:getcontentlength>%v</D:getcontentlength><D:resourcetype>%v</D:resourcetype></D:prop>`+
+					`<D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response></D:multistatus>`, r.URL.Path, info.Size(), resourceType)
+				return
+			}
+		case "MKCOL":
+			if err = os.Mkdir(fileName, 0755); os.IsExist(err) {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			if err == nil {
+				w.WriteHeader(http.StatusCreated)
+				return
+			}
+		case "MOVE":
+			var destination *url.URL
+			if destination, err = url.Parse(r.Header.Get("Destination")); err == nil {
+				err = os.Rename(fileName, filepath.Join(dir, filepath.FromSlash(destination.Path)))
+			}
+		case http.MethodDelete:
+			err = os.Remove(fileName)
+		default:
+			err = errors.New("unsupported")
+		}
+
+		switch {
+		case err == nil:
+			w.WriteHeader(http.StatusNoContent)
+		case os.IsNotExist(err):
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+}
+
+func newFakeWebDAVFS(t *testing.T, dir string) (*WebDAVFS, func()) {
+	server := httptest.NewServer(fakeWebDAVHandler(dir))
+	target, _ := url.Parse(strings.Replace(server.URL, "http://", "webdav://user:secret@", 1) + "/My%20Music")
+	fsys, err := NewWebDAVFS(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fsys, server.Close
+}
+
+func TestWebDAVFSWritesAndReadsFiles(t *testing.T) {
+	dir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(dir)
+	fsys, closeServer := newFakeWebDAVFS(t, dir)
+	defer closeServer()
+
+	for _, content := range []string{"old", "new"} {
+		err := writeFile(fsys, "Folder/My Playlist.m3u", func(w io.Writer) error {
+			_, err := io.WriteString(w, content)
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if content := readFile(t, filepath.Join(dir, "My Music", "Folder", "My Playlist.m3u")); content != "new" {
+		t.Fatalf("expected new content, got %q", content)
+	}
+	files, _ := ioutil.ReadDir(filepath.Join(dir, "My Music", "Folder"))
+	if len(files) != 1 {
+		t.Fatalf("expected temporary files to be removed, found %v files", len(files))
+	}
+
+	info, err := fsys.Stat("Folder/My Playlist.m3u")
+	if err != nil || info.Size() != 3 || info.IsDir() {
+		t.Fatalf("unexpected file info %v, error %v", info, err)
+	}
+	if info, err := fsys.Stat("Folder"); err != nil || !info.IsDir() {
+		t.Fatalf("expected directory, got %v, error %v", info, err)
+	}
+	if _, err := fsys.Stat("Other.m3u"); !os.IsNotExist(err) {
+		t.Fatalf("expected not exist error, got %v", err)
+	}
+
+	file, err := fsys.Open("Folder/My Playlist.m3u")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadAll(file)
+	file.Close()
+	if err != nil || string(content) != "new" {
+		t.Fatalf("expected new content, got %q, error %v", content, err)
+	}
+
+	if err := fsys.Remove("Folder/My Playlist.m3u"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWebDAVFSRemovesTemporaryFileOnAbort(t *testing.T) {
+	dir := createTempDir(t, "itunes-exporter-test")
+	defer os.RemoveAll(dir)
+	fsys, closeServer := newFakeWebDAVFS(t, dir)
+	defer closeServer()
+
+	err := writeFile(fsys, "My Playlist.m3u", func(w io.Writer) error {
+		io.WriteString(w, "half written")
+		return errors.New("failed")
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	files, _ := ioutil.ReadDir(filepath.Join(dir, "My Music"))
+	if len(files) != 0 {
+		t.Fatalf("expected temporary file to be removed, found %v files", len(files))
+	}
+}
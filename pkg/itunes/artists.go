@@ -0,0 +1,77 @@
+package itunes
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ArtistPlaylistOptions configures ArtistPlaylists.
+type ArtistPlaylistOptions struct {
+	// MinTracks is the number of tracks an artist needs to get a playlist.
+	MinTracks int
+}
+
+// defaultArtistMinTracks is the MinTracks of ParseArtistPlaylistOptions if not given.
+const defaultArtistMinTracks = 10
+
+// ParseArtistPlaylistOptions parses comma separated options of ArtistPlaylists, e.g.
+// "minTracks=15". A number alone is taken as minTracks.
+func ParseArtistPlaylistOptions(options string) (ArtistPlaylistOptions, error) {
+	parsed := ArtistPlaylistOptions{MinTracks: defaultArtistMinTracks}
+	for _, option := range strings.Split(options, ",") {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			continue
+		}
+		name, value := "mintracks", option
+		if i := strings.Index(option, "="); i >= 0 {
+			name, value = strings.ToLower(strings.TrimSpace(option[:i])), strings.TrimSpace(option[i+1:])
+		}
+		switch name {
+		case "mintracks":
+			minTracks, err := strconv.Atoi(value)
+			if err != nil || minTracks < 1 {
+				return parsed, errors.New("Invalid minTracks: " + value)
+			}
+			parsed.MinTracks = minTracks
+		default:
+			return parsed, errors.New("Unknown artist playlist option: " + option)
+		}
+	}
+	return parsed, nil
+}
+
+// ArtistPlaylists generates a playlist for every artist with at least options.MinTracks
+// tracks in the source playlists, in the playlist folder "Artists". The tracks are sorted
+// by rating and then play count, the best first. The folder and playlists are added to
+// library and returned, the folder first.
+func ArtistPlaylists(library *Library, source []Playlist, options ArtistPlaylistOptions) []Playlist {
+	var playlists []autoPlaylist
+	for _, playlist := range groupTracks(uniqueTracks(library, source), trackArtist) {
+		if len(playlist.tracks) < options.MinTracks {
+			continue
+		}
+		sort.SliceStable(playlist.tracks, func(i, j int) bool {
+			a, b := playlist.tracks[i], playlist.tracks[j]
+			if a.Rating != b.Rating {
+				return a.Rating > b.Rating
+			}
+			return a.PlayCount > b.PlayCount
+		})
+		playlists = append(playlists, playlist)
+	}
+
+	created := addAutoPlaylists(library, "ARTIST", "Artists", playlists)
+	library.BuildPlaylistMaps()
+	return created
+}
+
+// trackArtist returns the artist of the track, or the album artist if it has none.
+func trackArtist(track Track) string {
+	if track.Artist != "" {
+		return track.Artist
+	}
+	return track.AlbumArtist
+}
@@ -0,0 +1,40 @@
+package itunes
+
+import (
+	"testing"
+)
+
+func TestArtistPlaylists(t *testing.T) {
+	library := autoTestLibrary()
+	library.Tracks["1"] = Track{TrackId: 1, Name: "Hit", Artist: "Zed", Rating: 100}
+	library.Tracks["2"] = Track{TrackId: 2, Name: "Played", Artist: "Zed", PlayCount: 9}
+	library.Tracks["3"] = Track{TrackId: 3, Name: "Single", Artist: "Abba"}
+	library.Tracks["4"] = Track{TrackId: 4, Name: "Deep Cut", AlbumArtist: "Zed", PlayCount: 2}
+
+	options, err := ParseArtistPlaylistOptions("minTracks=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	playlists := ArtistPlaylists(library, library.Playlists[:1], options)
+	if len(playlists) != 2 || playlists[0].Name != "Artists" || playlists[1].Name != "Zed" {
+		t.Fatalf("expected the Artists folder with a playlist of Zed, got %v", playlists)
+	}
+	items := playlists[1].PlaylistItems
+	if len(items) != 3 || items[0].TrackId != 1 || items[1].TrackId != 2 || items[2].TrackId != 4 {
+		t.Fatalf("expected the tracks by rating and play count, got %v", items)
+	}
+}
+
+func TestParseArtistPlaylistOptions(t *testing.T) {
+	if options, err := ParseArtistPlaylistOptions(""); err != nil || options.MinTracks != defaultArtistMinTracks {
+		t.Fatalf("unexpected default options %v, %v", options, err)
+	}
+	if options, err := ParseArtistPlaylistOptions("15"); err != nil || options.MinTracks != 15 {
+		t.Fatalf("unexpected options %v, %v", options, err)
+	}
+	for _, options := range []string{"minTracks=0", "minTracks=many", "maxTracks=3"} {
+		if _, err := ParseArtistPlaylistOptions(options); err == nil {
+			t.Fatalf("expected error for %v", options)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package itunes
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// audiobookExtensions are the extensions of the files of audiobooks.
+var audiobookExtensions = map[string]bool{".m4b": true, ".aa": true, ".aax": true}
+
+// Audiobook reports whether the track is (a part of) an audiobook: an .m4b or Audible file,
+// or a track of the genre Audiobooks as iTunes files them.
+func (track Track) Audiobook() bool {
+	if audiobookExtensions[strings.ToLower(filepath.Ext(track.Location))] {
+		return true
+	}
+	kind := strings.ToLower(track.Kind)
+	if strings.Contains(kind, "audiobook") || strings.Contains(kind, "audible") {
+		return true
+	}
+	genre := strings.ToLower(strings.TrimSpace(track.Genre))
+	return genre == "audiobook" || genre == "audiobooks"
+}
+
+// AudiobookPlaylists generates a playlist for every audiobook in the source playlists, in
+// the playlist folder "Audiobooks". The book of a track is its album, or its name for books
+// of a single file. The parts of each book are in disc and track order, the order they are
+// listened to. The folder and playlists are added to library and returned, the folder
+// first.
+func AudiobookPlaylists(library *Library, source []Playlist) []Playlist {
+	var books []Track
+	for _, track := range uniqueTracks(library, source) {
+		if track.Audiobook() {
+			books = append(books, track)
+		}
+	}
+	created := addAutoPlaylists(library, "AUDIOBOOK", "Audiobooks", groupTracks(books, bookName))
+	library.BuildPlaylistMaps()
+	return created
+}
+
+// bookName returns the name of the audiobook of track.
+func bookName(track Track) string {
+	if track.Album != "" {
+		return track.Album
+	}
+	return track.Name
+}
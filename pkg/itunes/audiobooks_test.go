@@ -0,0 +1,39 @@
+package itunes
+
+import (
+	"testing"
+)
+
+func TestAudiobook(t *testing.T) {
+	for _, track := range []Track{
+		{Location: "file:///Books/Dune.M4B"},
+		{Kind: "Audible file", Location: "file:///Books/Dune.aax"},
+		{Kind: "AAC audio file", Genre: "Audiobooks", Location: "file:///Books/Dune.m4a"},
+	} {
+		if !track.Audiobook() {
+			t.Fatalf("expected %v to be an audiobook", track)
+		}
+	}
+	if (Track{Kind: "AAC audio file", Genre: "Spoken Word", Location: "file:///Music/Poem.m4a"}).Audiobook() {
+		t.Fatal("expected a music file not to be an audiobook")
+	}
+}
+
+func TestAudiobookPlaylists(t *testing.T) {
+	library := autoTestLibrary()
+	library.Tracks["1"] = Track{TrackId: 1, Name: "Part 2", Artist: "Herbert", Album: "Dune", TrackNumber: 2, Location: "file:///Books/Dune%202.m4b"}
+	library.Tracks["2"] = Track{TrackId: 2, Name: "Part 1", Artist: "Herbert", Album: "Dune", TrackNumber: 1, Location: "file:///Books/Dune%201.m4b"}
+	library.Tracks["3"] = Track{TrackId: 3, Name: "Emma", Artist: "Austen", Genre: "Audiobook", Location: "file:///Books/Emma.m4a"}
+
+	playlists := AudiobookPlaylists(library, library.Playlists[:1])
+	if len(playlists) != 3 || playlists[0].Name != "Audiobooks" || playlists[1].Name != "Dune" || playlists[2].Name != "Emma" {
+		t.Fatalf("expected the Audiobooks folder with a playlist of each book, got %v", playlists)
+	}
+	items := playlists[1].PlaylistItems
+	if len(items) != 2 || items[0].TrackId != 2 || items[1].TrackId != 1 {
+		t.Fatalf("expected the parts of the book in order, got %v", items)
+	}
+	if _, ok := library.PlaylistMap["Emma"]; !ok {
+		t.Fatal("expected the playlists to be added to the library")
+	}
+}
@@ -0,0 +1,305 @@
+package itunes
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// autoPlaylist is a playlist created by an auto playlist generator.
+type autoPlaylist struct {
+	name   string
+	tracks []Track
+}
+
+// autoGenerator creates the playlists of an auto playlist kind from tracks. Playlists based on
+// the age of the tracks are relative to now.
+type autoGenerator struct {
+	// folder is the name of the playlist folder the playlists are put into, if any.
+	folder   string
+	generate func(tracks []Track, now time.Time) []autoPlaylist
+}
+
+var autoGenerators = map[string]autoGenerator{
+	"GENRE":         {folder: "Genres", generate: genrePlaylists},
+	"DECADE":        {folder: "Decades", generate: decadePlaylists},
+	"YEAR":          {folder: "Years", generate: yearPlaylists},
+	"RATING":        {folder: "Ratings", generate: ratingPlaylists},
+	"MOSTPLAYED":    {generate: mostPlayedPlaylist},
+	"RECENTLYADDED": {generate: recentlyAddedPlaylist},
+}
+
+// autoPlaylistOrder is the order the playlists of several kinds are created in.
+var autoPlaylistOrder = []string{"GENRE", "DECADE", "YEAR", "RATING", "MOSTPLAYED", "RECENTLYADDED"}
+
+const (
+	// mostPlayedCount is the number of tracks of the most played playlist.
+	mostPlayedCount = 100
+	// recentlyAddedDays is the age in days of the tracks of the recently added playlist.
+	recentlyAddedDays = 90
+)
+
+// ParseAutoPlaylists parses a comma separated list of auto playlist kinds, e.g. "genre,decade"
+// or "rating,most-played". Case, spaces and dashes are ignored.
+func ParseAutoPlaylists(names string) ([]string, error) {
+	var kinds []string
+	for _, name := range strings.Split(names, ",") {
+		kind := strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(name))
+		if kind == "" {
+			continue
+		}
+		if _, ok := autoGenerators[kind]; !ok {
+			return nil, errors.New("Unknown auto playlist: " + name)
+		}
+		kinds = append(kinds, kind)
+	}
+	return kinds, nil
+}
+
+// AutoPlaylists generates playlists of the given kinds from the tracks of the source
+// playlists, e.g. a playlist for every genre. The playlists of most kinds are put into a
+// playlist folder, e.g. "Genres". The folders and playlists are added to library and
+// returned, every folder before its playlists. Playlists of recently added tracks are
+// relative to the date of the library.
+func AutoPlaylists(library *Library, source []Playlist, kinds []string) []Playlist {
+	tracks := uniqueTracks(library, source)
+	now := library.Date
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	var created []Playlist
+	for _, kind := range autoPlaylistOrder {
+		if containsString(kinds, kind) {
+			generator := autoGenerators[kind]
+			created = append(created, addAutoPlaylists(library, kind, generator.folder, generator.generate(tracks, now))...)
+		}
+	}
+	library.BuildPlaylistMaps()
+	return created
+}
+
+// addAutoPlaylists adds the playlists generated for kind to library, in a folder if its name
+// is given, and returns them. The playlist maps of library have to be built again.
+func addAutoPlaylists(library *Library, kind, folderName string, playlists []autoPlaylist) []Playlist {
+	if len(playlists) == 0 {
+		return nil
+	}
+	nextId := nextPlaylistId(library)
+
+	var created []Playlist
+	parent := ""
+	if folderName != "" {
+		folder := Playlist{
+			Name:                 folderName,
+			PlaylistId:           nextId,
+			PlaylistPersistentId: persistentId("auto", kind),
+			Folder:               true,
+			Visible:              true,
+		}
+		nextId++
+		parent = folder.PlaylistPersistentId
+		created = append(created, folder)
+	}
+
+	for _, auto := range playlists {
+		playlist := Playlist{
+			Name:                 auto.name,
+			PlaylistId:           nextId,
+			PlaylistPersistentId: persistentId("auto", kind+":"+auto.name),
+			ParentPersistentId:   parent,
+			Visible:              true,
+		}
+		nextId++
+		for _, track := range auto.tracks {
+			playlist.PlaylistItems = append(playlist.PlaylistItems, PlaylistItem{TrackId: track.TrackId})
+		}
+		created = append(created, playlist)
+	}
+	library.Playlists = append(library.Playlists, created...)
+	return created
+}
+
+// nextPlaylistId returns the lowest playlist id above the ids of the playlists of library.
+func nextPlaylistId(library *Library) int {
+	nextId := 1
+	for _, playlist := range library.Playlists {
+		if playlist.PlaylistId >= nextId {
+			nextId = playlist.PlaylistId + 1
+		}
+	}
+	return nextId
+}
+
+// uniqueTracks returns the tracks of the playlists, each once, in the order of their ids.
+func uniqueTracks(library *Library, playlists []Playlist) []Track {
+	seen := make(map[int]bool)
+	var tracks []Track
+	for _, playlist := range playlists {
+		for _, track := range playlist.Tracks(library) {
+			if !seen[track.TrackId] {
+				seen[track.TrackId] = true
+				tracks = append(tracks, track)
+			}
+		}
+	}
+	sort.Slice(tracks, func(i, j int) bool { return tracks[i].TrackId < tracks[j].TrackId })
+	return tracks
+}
+
+// groupTracks returns a playlist for every key of the tracks, sorted by name. Tracks with an
+// empty key are left out. The tracks of each playlist are in album order.
+func groupTracks(tracks []Track, key func(track Track) string) []autoPlaylist {
+	groups := make(map[string]*autoPlaylist)
+	var names []string
+	for _, track := range tracks {
+		name := strings.TrimSpace(key(track))
+		if name == "" {
+			continue
+		}
+		// Genres differing in case only, e.g. "Hip-Hop" and "Hip-hop", are put together.
+		id := strings.ToLower(name)
+		group, ok := groups[id]
+		if !ok {
+			group = &autoPlaylist{name: name}
+			groups[id] = group
+			names = append(names, id)
+		}
+		group.tracks = append(group.tracks, track)
+	}
+	sort.Strings(names)
+
+	playlists := make([]autoPlaylist, 0, len(names))
+	for _, id := range names {
+		group := groups[id]
+		sortAlbumOrder(group.tracks)
+		playlists = append(playlists, *group)
+	}
+	return playlists
+}
+
+// sortAlbumOrder sorts tracks by artist, album, disc and track number.
+func sortAlbumOrder(tracks []Track) {
+	sort.SliceStable(tracks, func(i, j int) bool {
+		a, b := tracks[i], tracks[j]
+		if artistA, artistB := sortArtist(a), sortArtist(b); artistA != artistB {
+			return artistA < artistB
+		}
+		if albumA, albumB := sortKey(a.SortAlbum, a.Album), sortKey(b.SortAlbum, b.Album); albumA != albumB {
+			return albumA < albumB
+		}
+		if a.DiscNumber != b.DiscNumber {
+			return a.DiscNumber < b.DiscNumber
+		}
+		if a.TrackNumber != b.TrackNumber {
+			return a.TrackNumber < b.TrackNumber
+		}
+		return sortKey(a.SortName, a.Name) < sortKey(b.SortName, b.Name)
+	})
+}
+
+func sortArtist(track Track) string {
+	if track.AlbumArtist != "" || track.SortAlbumArtist != "" {
+		return sortKey(track.SortAlbumArtist, track.AlbumArtist)
+	}
+	return sortKey(track.SortArtist, track.Artist)
+}
+
+func sortKey(sortValue, value string) string {
+	if sortValue != "" {
+		return strings.ToLower(sortValue)
+	}
+	return strings.ToLower(value)
+}
+
+func genrePlaylists(tracks []Track, now time.Time) []autoPlaylist {
+	return groupTracks(tracks, func(track Track) string { return track.Genre })
+}
+
+// decadePlaylists returns a playlist for every decade, e.g. "1980s", of the year of the tracks.
+func decadePlaylists(tracks []Track, now time.Time) []autoPlaylist {
+	return groupTracks(tracks, func(track Track) string {
+		if track.Year <= 0 {
+			return ""
+		}
+		return strconv.Itoa(track.Year/10*10) + "s"
+	})
+}
+
+func yearPlaylists(tracks []Track, now time.Time) []autoPlaylist {
+	return groupTracks(tracks, func(track Track) string {
+		if track.Year <= 0 {
+			return ""
+		}
+		return strconv.Itoa(track.Year)
+	})
+}
+
+// ratingPlaylists returns a playlist for every star rating, e.g. "5 Star". iTunes stores
+// ratings as 20 per star.
+func ratingPlaylists(tracks []Track, now time.Time) []autoPlaylist {
+	playlists := groupTracks(tracks, func(track Track) string {
+		if track.Rating < 20 {
+			return ""
+		}
+		return strconv.Itoa(track.Rating/20) + " Star"
+	})
+	// The best rated first.
+	for i, j := 0, len(playlists)-1; i < j; i, j = i+1, j-1 {
+		playlists[i], playlists[j] = playlists[j], playlists[i]
+	}
+	return playlists
+}
+
+// mostPlayedPlaylist returns the played tracks with the highest play counts, the most
+// played first.
+func mostPlayedPlaylist(tracks []Track, now time.Time) []autoPlaylist {
+	var played []Track
+	for _, track := range tracks {
+		if track.PlayCount > 0 {
+			played = append(played, track)
+		}
+	}
+	if len(played) == 0 {
+		return nil
+	}
+	sort.SliceStable(played, func(i, j int) bool {
+		if played[i].PlayCount != played[j].PlayCount {
+			return played[i].PlayCount > played[j].PlayCount
+		}
+		return played[i].PlayDateUTC.After(played[j].PlayDateUTC)
+	})
+	if len(played) > mostPlayedCount {
+		played = played[:mostPlayedCount]
+	}
+	return []autoPlaylist{{name: fmt.Sprintf("Top %v Most Played", mostPlayedCount), tracks: played}}
+}
+
+// recentlyAddedPlaylist returns the tracks added to the library in the last days, the
+// newest first.
+func recentlyAddedPlaylist(tracks []Track, now time.Time) []autoPlaylist {
+	since := now.AddDate(0, 0, -recentlyAddedDays)
+	var added []Track
+	for _, track := range tracks {
+		if !track.DateAdded.IsZero() && track.DateAdded.After(since) {
+			added = append(added, track)
+		}
+	}
+	if len(added) == 0 {
+		return nil
+	}
+	sort.SliceStable(added, func(i, j int) bool { return added[i].DateAdded.After(added[j].DateAdded) })
+	return []autoPlaylist{{name: fmt.Sprintf("Recently Added (%v days)", recentlyAddedDays), tracks: added}}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
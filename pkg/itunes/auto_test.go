@@ -0,0 +1,104 @@
+package itunes
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func autoTestLibrary() *Library {
+	library := &Library{
+		Tracks: map[string]Track{
+			"1": {TrackId: 1, Name: "B Side", Artist: "Zed", Album: "Two", Genre: "Rock", TrackNumber: 2},
+			"2": {TrackId: 2, Name: "A Side", Artist: "Zed", Album: "Two", Genre: "rock", TrackNumber: 1},
+			"3": {TrackId: 3, Name: "Intro", Artist: "Abba", Album: "One", Genre: "Pop"},
+			"4": {TrackId: 4, Name: "Untagged", Artist: "Nobody"},
+			"5": {TrackId: 5, Name: "Filtered", Artist: "Abba", Genre: "Jazz"},
+		},
+		Playlists: []Playlist{
+			{Name: "Mix", PlaylistId: 7, PlaylistPersistentId: "AAAA", PlaylistItems: []PlaylistItem{{TrackId: 1}, {TrackId: 3}, {TrackId: 2}, {TrackId: 4}, {TrackId: 1}}},
+			{Name: "Other", PlaylistId: 8, PlaylistPersistentId: "BBBB", PlaylistItems: []PlaylistItem{{TrackId: 5}}},
+		},
+	}
+	library.BuildPlaylistMaps()
+	return library
+}
+
+func TestGenreAutoPlaylists(t *testing.T) {
+	library := autoTestLibrary()
+	kinds, err := ParseAutoPlaylists("genre")
+	if err != nil {
+		t.Fatal(err)
+	}
+	playlists := AutoPlaylists(library, library.Playlists[:1], kinds)
+
+	if len(playlists) != 3 || !playlists[0].Folder || playlists[0].Name != "Genres" || playlists[0].PlaylistId != 9 {
+		t.Fatalf("expected the Genres folder and two playlists, got %v", playlists)
+	}
+	pop, rock := playlists[1], playlists[2]
+	if pop.Name != "Pop" || rock.Name != "Rock" || rock.ParentPersistentId != playlists[0].PlaylistPersistentId {
+		t.Fatalf("unexpected genre playlists %v and %v", pop, rock)
+	}
+	if len(rock.PlaylistItems) != 2 || rock.PlaylistItems[0].TrackId != 2 || rock.PlaylistItems[1].TrackId != 1 {
+		t.Fatalf("expected the rock tracks in album order, got %v", rock.PlaylistItems)
+	}
+	if _, ok := library.PlaylistIdMap[rock.PlaylistPersistentId]; !ok || len(library.Playlists) != 5 {
+		t.Fatal("expected the playlists to be added to the library")
+	}
+
+	if _, err := ParseAutoPlaylists("genre,mood"); err == nil {
+		t.Fatal("expected error for unknown auto playlist")
+	}
+}
+
+func TestDecadeAndYearAutoPlaylists(t *testing.T) {
+	library := autoTestLibrary()
+	library.Tracks["1"] = Track{TrackId: 1, Name: "Old", Year: 1984}
+	library.Tracks["2"] = Track{TrackId: 2, Name: "Older", Year: 1979}
+	library.Tracks["3"] = Track{TrackId: 3, Name: "New", Year: 1989}
+	kinds, err := ParseAutoPlaylists("year, Decade")
+	if err != nil {
+		t.Fatal(err)
+	}
+	playlists := AutoPlaylists(library, library.Playlists[:1], kinds)
+
+	var names []string
+	for _, playlist := range playlists {
+		names = append(names, playlist.Name)
+	}
+	if strings.Join(names, ",") != "Decades,1970s,1980s,Years,1979,1984,1989" {
+		t.Fatalf("unexpected playlists %v", names)
+	}
+	if len(playlists[2].PlaylistItems) != 2 {
+		t.Fatalf("expected two tracks of the 1980s, got %v", playlists[2].PlaylistItems)
+	}
+}
+
+func TestRatingAndPlayAutoPlaylists(t *testing.T) {
+	now := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	library := autoTestLibrary()
+	library.Date = now
+	library.Tracks["1"] = Track{TrackId: 1, Name: "Loved", Rating: 100, PlayCount: 3, DateAdded: now.AddDate(0, 0, -10)}
+	library.Tracks["2"] = Track{TrackId: 2, Name: "Good", Rating: 80, PlayCount: 7, DateAdded: now.AddDate(0, 0, -100)}
+	library.Tracks["3"] = Track{TrackId: 3, Name: "Fine", Rating: 60, DateAdded: now.AddDate(0, 0, -1)}
+	kinds, err := ParseAutoPlaylists("rating,most-played,Recently Added")
+	if err != nil {
+		t.Fatal(err)
+	}
+	playlists := AutoPlaylists(library, library.Playlists[:1], kinds)
+
+	var names []string
+	for _, playlist := range playlists {
+		names = append(names, playlist.Name)
+	}
+	if strings.Join(names, ",") != "Ratings,5 Star,4 Star,3 Star,Top 100 Most Played,Recently Added (90 days)" {
+		t.Fatalf("unexpected playlists %v", names)
+	}
+	mostPlayed, recent := playlists[4], playlists[5]
+	if mostPlayed.ParentPersistentId != "" || len(mostPlayed.PlaylistItems) != 2 || mostPlayed.PlaylistItems[0].TrackId != 2 {
+		t.Fatalf("unexpected most played playlist %v", mostPlayed)
+	}
+	if len(recent.PlaylistItems) != 2 || recent.PlaylistItems[0].TrackId != 3 || recent.PlaylistItems[1].TrackId != 1 {
+		t.Fatalf("unexpected recently added playlist %v", recent)
+	}
+}
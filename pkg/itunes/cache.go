@@ -0,0 +1,152 @@
+package itunes
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/gob"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// cacheVersion must be incremented whenever the Library, Playlist or Track types change.
+const cacheVersion = 6
+
+// Logf writes the messages about the library cache. Replace it to send them elsewhere,
+// e.g. to the log of an export.
+var Logf = func(format string, args ...interface{}) {
+	fmt.Printf(format, args...)
+}
+
+// libraryCache is the content of a library cache file.
+type libraryCache struct {
+	Version     int
+	LibraryPath string
+	ModTime     time.Time
+	Size        int64
+	Library     Library
+}
+
+// LoadLibraryCached loads the library from the cache in cacheDir if the library file has
+// not changed since the cache was written. Otherwise the library file is parsed and the
+// cache is updated. Problems with the cache itself are reported but never fatal.
+func LoadLibraryCached(fileLocation string, cacheDir string) (*Library, error) {
+	return LoadLibraryCachedContext(context.Background(), fileLocation, cacheDir)
+}
+
+// LoadLibraryCachedContext is like LoadLibraryCached, but stops parsing once ctx is done.
+func LoadLibraryCachedContext(ctx context.Context, fileLocation string, cacheDir string) (*Library, error) {
+	fileInfo, err := os.Stat(fileLocation)
+	if err != nil {
+		return nil, err
+	}
+
+	library, err := CachedLibrary(fileLocation, cacheDir)
+	if err == nil {
+		return library, nil
+	}
+
+	library, err = LoadLibraryContext(ctx, fileLocation)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheFile := libraryCacheFile(fileLocation, cacheDir)
+	if err := writeLibraryCache(cacheFile, fileLocation, fileInfo, library); err != nil {
+		Logf("Unable to write library cache %v: %v\n", cacheFile, err)
+	}
+	return library, nil
+}
+
+// CachedLibrary returns the library from the cache in cacheDir. It returns an error if
+// there is no cached copy of the library or the library file changed since it was cached.
+func CachedLibrary(fileLocation string, cacheDir string) (*Library, error) {
+	fileInfo, err := os.Stat(fileLocation)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheFile := libraryCacheFile(fileLocation, cacheDir)
+	library, err := readLibraryCache(cacheFile, fileInfo)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			Logf("Ignoring library cache %v: %v\n", cacheFile, err)
+		}
+		return nil, err
+	}
+	Logf("Library loaded from cache %v\n", cacheFile)
+	return library, nil
+}
+
+// DefaultCacheDir returns the directory the library cache is stored in.
+func DefaultCacheDir() (string, error) {
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(userCacheDir, "itunesexport"), nil
+}
+
+func libraryCacheFile(fileLocation string, cacheDir string) string {
+	absolutePath, err := filepath.Abs(fileLocation)
+	if err != nil {
+		absolutePath = fileLocation
+	}
+	return filepath.Join(cacheDir, fmt.Sprintf("library-%x.gob", sha1.Sum([]byte(absolutePath))))
+}
+
+func readLibraryCache(cacheFile string, fileInfo os.FileInfo) (*Library, error) {
+	file, err := os.Open(cacheFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var cache libraryCache
+	if err := gob.NewDecoder(file).Decode(&cache); err != nil {
+		return nil, err
+	}
+	if cache.Version != cacheVersion || !cache.ModTime.Equal(fileInfo.ModTime()) || cache.Size != fileInfo.Size() {
+		return nil, os.ErrNotExist
+	}
+
+	library := cache.Library
+	library.BuildPlaylistMaps()
+	return &library, nil
+}
+
+func writeLibraryCache(cacheFile string, fileLocation string, fileInfo os.FileInfo, library *Library) error {
+	if err := os.MkdirAll(filepath.Dir(cacheFile), 0777); err != nil {
+		return err
+	}
+
+	cache := libraryCache{
+		Version:     cacheVersion,
+		LibraryPath: fileLocation,
+		ModTime:     fileInfo.ModTime(),
+		Size:        fileInfo.Size(),
+		Library:     *library,
+	}
+	// The playlist maps are rebuilt when reading the cache.
+	cache.Library.PlaylistMap = nil
+	cache.Library.PlaylistIdMap = nil
+
+	// Write to a temporary file first, so a concurrent run never reads a partial cache.
+	file, err := ioutil.TempFile(filepath.Dir(cacheFile), filepath.Base(cacheFile)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	err = gob.NewEncoder(file).Encode(&cache)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), cacheFile)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+	}
+	return err
+}
@@ -0,0 +1,87 @@
+package itunes
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadLibraryCached(t *testing.T) {
+	cacheDir := createTempDir(t, "itunes-exporter-cache")
+	defer os.RemoveAll(cacheDir)
+
+	itunesDbFile := prepareItunesDbFile(t, "/music/song.mp3")
+	defer os.Remove(itunesDbFile)
+
+	library, err := LoadLibraryCached(itunesDbFile, cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertPathExists(t, libraryCacheFile(itunesDbFile, cacheDir))
+
+	// Replace the library with garbage without changing size and modification time,
+	// so it can only be loaded from the cache.
+	fileInfo, _ := os.Stat(itunesDbFile)
+	writeFile(t, itunesDbFile, string(make([]byte, fileInfo.Size())))
+	os.Chtimes(itunesDbFile, fileInfo.ModTime(), fileInfo.ModTime())
+
+	cached, err := LoadLibraryCached(itunesDbFile, cacheDir)
+	if err != nil {
+		t.Fatalf("expected library to be loaded from cache: %v", err)
+	}
+	if len(cached.Tracks) != len(library.Tracks) || cached.Tracks["1"].Location != "file:///music/song.mp3" {
+		t.Fatalf("unexpected cached tracks: %+v", cached.Tracks)
+	}
+	if _, ok := cached.PlaylistMap["My Playlist"]; !ok {
+		t.Fatal("playlist map not rebuilt")
+	}
+
+	// Once the library changes, it is parsed again.
+	later := fileInfo.ModTime().Add(time.Minute)
+	os.Chtimes(itunesDbFile, later, later)
+	if _, err := LoadLibraryCached(itunesDbFile, cacheDir); err == nil {
+		t.Fatal("expected changed library to be parsed again")
+	}
+}
+
+// cachedLayouts are the checksums of the layout of the cached types by cacheVersion.
+var cachedLayouts = map[int]string{
+	6: "3768c681843209d9fe4e06c3e26237ff2f20d78b",
+}
+
+func TestLibraryCacheVersion(t *testing.T) {
+	layout := fmt.Sprintf("%x", sha1.Sum([]byte(typeLayout(reflect.TypeOf(libraryCache{}), map[reflect.Type]bool{}))))
+	if cachedLayouts[cacheVersion] != layout {
+		t.Fatalf("the layout of the cached library changed, increment cacheVersion and add %v: %q to cachedLayouts", cacheVersion+1, layout)
+	}
+}
+
+// typeLayout describes the fields of typ and the types they contain, as far as they are
+// written to the cache.
+func typeLayout(typ reflect.Type, seen map[reflect.Type]bool) string {
+	switch typ.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return typ.Kind().String() + " " + typeLayout(typ.Elem(), seen)
+	case reflect.Map:
+		return "map " + typeLayout(typ.Key(), seen) + " " + typeLayout(typ.Elem(), seen)
+	case reflect.Struct:
+		if seen[typ] || typ.PkgPath() != reflect.TypeOf(Library{}).PkgPath() {
+			return typ.String()
+		}
+		seen[typ] = true
+		var fields []string
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			if field.PkgPath == "" {
+				fields = append(fields, field.Name+" "+typeLayout(field.Type, seen))
+			}
+		}
+		return typ.Name() + " {" + strings.Join(fields, "; ") + "}"
+	default:
+		return typ.Kind().String()
+	}
+}
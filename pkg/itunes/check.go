@@ -0,0 +1,101 @@
+package itunes
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	PROBLEM_MISSING      = "missing"
+	PROBLEM_EMPTY        = "empty"
+	PROBLEM_OTHER_VOLUME = "other volume"
+)
+
+// TrackProblem is a problem with the file of a track found by CheckTracks.
+type TrackProblem struct {
+	TrackId  int    `json:"trackId"`
+	Name     string `json:"name"`
+	Artist   string `json:"artist"`
+	Album    string `json:"album"`
+	Location string `json:"location"`
+	Problem  string `json:"problem"`
+}
+
+// CheckTracks checks the files of all tracks of the library. It reports tracks whose file
+// is missing or empty, and tracks stored on another volume than the Music Folder of the
+// library. Tracks without a location or with a location other than a file, e.g. streams,
+// are not checked. Problems are sorted by track id.
+func CheckTracks(ctx context.Context, library *Library) ([]TrackProblem, error) {
+	musicVolume := ""
+	if library.MusicFolder != "" {
+		musicVolume = volumeOf(LocationPath(library.MusicFolder))
+	}
+
+	var problems []TrackProblem
+	for _, track := range library.Tracks {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(track.Location, "file://") {
+			continue
+		}
+
+		path, info, err := StatPath(LocationPath(track.Location))
+		problem := TrackProblem{TrackId: track.TrackId, Name: track.Name, Artist: track.Artist, Album: track.Album, Location: path}
+
+		switch {
+		case err != nil:
+			problem.Problem = PROBLEM_MISSING
+		case info.Mode().IsRegular() && info.Size() == 0:
+			problem.Problem = PROBLEM_EMPTY
+		case musicVolume != "" && volumeOf(path) != musicVolume:
+			problem.Problem = PROBLEM_OTHER_VOLUME
+		default:
+			continue
+		}
+		problems = append(problems, problem)
+	}
+
+	sort.Slice(problems, func(i, j int) bool {
+		return problems[i].TrackId < problems[j].TrackId
+	})
+	return problems, nil
+}
+
+// volumeOf returns the volume a path is stored on: the drive on Windows, the mount point
+// of removable media on macOS and Linux and the root directory otherwise.
+func volumeOf(path string) string {
+	if volume := filepath.VolumeName(path); volume != "" {
+		return strings.ToUpper(volume)
+	}
+
+	parts := strings.Split(filepath.ToSlash(path), "/")
+	mountDepth := 0
+	if len(parts) > 1 {
+		switch parts[1] {
+		case "Volumes", "mnt":
+			mountDepth = 3
+		case "media":
+			mountDepth = 4
+		case "run":
+			if len(parts) > 2 && parts[2] == "media" {
+				mountDepth = 5
+			}
+		}
+	}
+	if mountDepth == 0 || len(parts) <= mountDepth {
+		return "/"
+	}
+	return strings.Join(parts[:mountDepth], "/")
+}
+
+// TrackProblemFields are the CSV column names of a TrackProblem.
+var TrackProblemFields = []string{"Track ID", "Name", "Artist", "Album", "Location", "Problem"}
+
+// Fields returns the values of the CSV columns named by TrackProblemFields.
+func (problem TrackProblem) Fields() []string {
+	return []string{strconv.Itoa(problem.TrackId), problem.Name, problem.Artist, problem.Album, problem.Location, problem.Problem}
+}
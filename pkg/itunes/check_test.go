@@ -0,0 +1,58 @@
+package itunes
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckTracks(t *testing.T) {
+	dir := createTempDir(t, "itunes-check-test")
+	defer os.RemoveAll(dir)
+
+	writeFile(t, filepath.Join(dir, "good.mp3"), "42")
+	writeFile(t, filepath.Join(dir, "empty.mp3"), "")
+
+	location := func(name string) string {
+		return "file://" + filepath.ToSlash(filepath.Join(dir, name))
+	}
+	library := &Library{
+		Tracks: map[string]Track{
+			"1": {TrackId: 1, Location: location("good.mp3")},
+			"2": {TrackId: 2, Location: location("empty.mp3")},
+			"3": {TrackId: 3, Location: location("missing%20song.mp3")},
+			"4": {TrackId: 4, Location: "http://example.com/stream"},
+			"5": {TrackId: 5},
+		},
+	}
+
+	problems, err := CheckTracks(context.Background(), library)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 2 {
+		t.Fatalf("expected 2 problems, got %+v", problems)
+	}
+	if problems[0].TrackId != 2 || problems[0].Problem != PROBLEM_EMPTY {
+		t.Errorf("expected empty file, got %+v", problems[0])
+	}
+	if problems[1].TrackId != 3 || problems[1].Problem != PROBLEM_MISSING || problems[1].Location != filepath.Join(dir, "missing song.mp3") {
+		t.Errorf("expected missing file, got %+v", problems[1])
+	}
+}
+
+func TestVolumeOf(t *testing.T) {
+	for path, volume := range map[string]string{
+		"/Users/me/Music/iTunes/song.mp3":  "/",
+		"/Volumes/External/Music/song.mp3": "/Volumes/External",
+		"/media/me/USB/song.mp3":           "/media/me/USB",
+		"/run/media/me/USB/song.mp3":       "/run/media/me/USB",
+		"/mnt/nas/song.mp3":                "/mnt/nas",
+		"/home/me/Music/song.mp3":          "/",
+	} {
+		if got := volumeOf(path); got != volume {
+			t.Errorf("expected volume %q for %q, got %q", volume, path, got)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package itunes
+
+import (
+	"strings"
+)
+
+// Title returns the name of the track as iTunes shows it. For a movement of a classical
+// work that is the work with the number and name of the movement, e.g.
+// "Symphony No. 5: II. Andante con moto", as the names of movements alone are hardly
+// telling. Other tracks have their Name.
+func (track Track) Title() string {
+	if track.Work == "" || track.MovementName == "" {
+		return track.Name
+	}
+	movement := track.MovementName
+	if numeral := romanNumeral(track.MovementNumber); numeral != "" {
+		movement = numeral + ". " + movement
+	}
+	return track.Work + ": " + movement
+}
+
+// romanNumeral returns n as Roman numeral, or an empty string if n is not between 1 and 3999.
+func romanNumeral(n int) string {
+	if n < 1 || n > 3999 {
+		return ""
+	}
+	var numeral strings.Builder
+	for _, symbol := range []struct {
+		value  int
+		symbol string
+	}{
+		{1000, "M"}, {900, "CM"}, {500, "D"}, {400, "CD"}, {100, "C"}, {90, "XC"},
+		{50, "L"}, {40, "XL"}, {10, "X"}, {9, "IX"}, {5, "V"}, {4, "IV"}, {1, "I"},
+	} {
+		for ; n >= symbol.value; n -= symbol.value {
+			numeral.WriteString(symbol.symbol)
+		}
+	}
+	return numeral.String()
+}
@@ -0,0 +1,27 @@
+package itunes
+
+import (
+	"testing"
+)
+
+func TestTrackTitle(t *testing.T) {
+	for _, test := range []struct {
+		track    Track
+		expected string
+	}{
+		{Track{Name: "Song"}, "Song"},
+		{Track{Name: "Andante", Work: "Symphony No. 5", MovementName: "Andante con moto", MovementNumber: 2}, "Symphony No. 5: II. Andante con moto"},
+		{Track{Name: "Aria", Work: "Goldberg Variations", MovementName: "Aria"}, "Goldberg Variations: Aria"},
+		{Track{Name: "Live", Work: "Tour"}, "Live"},
+	} {
+		if title := test.track.Title(); title != test.expected {
+			t.Fatalf("expected %q, got %q", test.expected, title)
+		}
+	}
+
+	for n, expected := range map[int]string{1: "I", 4: "IV", 9: "IX", 14: "XIV", 1994: "MCMXCIV", 0: ""} {
+		if numeral := romanNumeral(n); numeral != expected {
+			t.Fatalf("expected %v for %v, got %v", expected, n, numeral)
+		}
+	}
+}
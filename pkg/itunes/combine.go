@@ -0,0 +1,135 @@
+package itunes
+
+import (
+	"errors"
+	"strings"
+)
+
+// Combination is a playlist combined from other playlists by set operations, parsed by
+// ParseCombinations.
+type Combination struct {
+	Name     string
+	Operands []CombinationOperand
+}
+
+// CombinationOperand is a playlist of a Combination and the operation combining it with
+// the playlists before it: '+' for the union, '-' for the difference and '&' for the
+// intersection. The operation of the first playlist is always '+'.
+type CombinationOperand struct {
+	Operation byte
+	Playlist  string
+}
+
+// ParseCombinations parses comma separated combinations of playlists, e.g.
+// "Gym=Workout+Running-Chill". The operations are applied from left to right. Names
+// containing operators or commas are written in double quotes, e.g. `Mix="Rock & Roll"&Live`.
+func ParseCombinations(spec string) ([]Combination, error) {
+	var combinations []Combination
+	for _, definition := range splitUnquoted(spec, ",") {
+		if strings.TrimSpace(definition) == "" {
+			continue
+		}
+		parts := splitUnquoted(definition, "=")
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, errors.New("Invalid playlist combination, expected <name>=<playlist>+<playlist>: " + definition)
+		}
+		combination := Combination{Name: unquote(parts[0])}
+		operation := byte('+')
+		start := 0
+		expression := parts[1]
+		quoted := false
+		for i := 0; i <= len(expression); i++ {
+			if i < len(expression) {
+				if expression[i] == '"' {
+					quoted = !quoted
+				}
+				if quoted || !strings.ContainsRune("+-&", rune(expression[i])) {
+					continue
+				}
+			}
+			name := unquote(expression[start:i])
+			if name == "" {
+				return nil, errors.New("Invalid playlist combination, missing playlist name: " + definition)
+			}
+			combination.Operands = append(combination.Operands, CombinationOperand{Operation: operation, Playlist: name})
+			if i < len(expression) {
+				operation = expression[i]
+			}
+			start = i + 1
+		}
+		combinations = append(combinations, combination)
+	}
+	return combinations, nil
+}
+
+// splitUnquoted splits s at every separator outside of double quotes.
+func splitUnquoted(s, separator string) []string {
+	var parts []string
+	quoted := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '"':
+			quoted = !quoted
+		case !quoted && strings.HasPrefix(s[i:], separator):
+			parts = append(parts, s[start:i])
+			start = i + len(separator)
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// unquote trims s and removes the double quotes around it, if any.
+func unquote(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		s = strings.TrimSpace(s[1 : len(s)-1])
+	}
+	return s
+}
+
+// Combine creates the playlists of the combinations and adds them to library. Tracks keep
+// the order of the playlists they are taken from, each track once. Combinations can use
+// the combinations before them. Playlists which do not exist are treated as empty and
+// their names returned as missing.
+func Combine(library *Library, combinations []Combination) (created []Playlist, missing []string) {
+	for _, combination := range combinations {
+		var tracks []Track
+		for _, operand := range combination.Operands {
+			playlist, ok := library.PlaylistMap[operand.Playlist]
+			if !ok {
+				missing = append(missing, operand.Playlist)
+			}
+			members := playlist.Tracks(library)
+			ids := make(map[int]bool)
+			for _, track := range members {
+				ids[track.TrackId] = true
+			}
+
+			if operand.Operation == '+' {
+				seen := make(map[int]bool)
+				for _, track := range tracks {
+					seen[track.TrackId] = true
+				}
+				for _, track := range members {
+					if !seen[track.TrackId] {
+						seen[track.TrackId] = true
+						tracks = append(tracks, track)
+					}
+				}
+				continue
+			}
+			// The difference keeps the tracks not in the playlist, the intersection those in it.
+			var kept []Track
+			for _, track := range tracks {
+				if ids[track.TrackId] == (operand.Operation == '&') {
+					kept = append(kept, track)
+				}
+			}
+			tracks = kept
+		}
+		created = append(created, addAutoPlaylists(library, "COMBINE", "", []autoPlaylist{{name: combination.Name, tracks: tracks}})...)
+		library.BuildPlaylistMaps()
+	}
+	return created, missing
+}
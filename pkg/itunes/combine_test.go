@@ -0,0 +1,72 @@
+package itunes
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestParseCombinations(t *testing.T) {
+	combinations, err := ParseCombinations(`Gym=Workout+Running-Chill, Mix = "Rock & Roll, Live" & "Hip-Hop"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Combination{
+		{Name: "Gym", Operands: []CombinationOperand{{'+', "Workout"}, {'+', "Running"}, {'-', "Chill"}}},
+		{Name: "Mix", Operands: []CombinationOperand{{'+', "Rock & Roll, Live"}, {'&', "Hip-Hop"}}},
+	}
+	if !reflect.DeepEqual(combinations, expected) {
+		t.Fatalf("expected %v, got %v", expected, combinations)
+	}
+
+	for _, invalid := range []string{"Gym", "=Workout", "Gym=Workout+", "Gym=Workout--Chill"} {
+		if _, err := ParseCombinations(invalid); err == nil {
+			t.Fatalf("expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestCombine(t *testing.T) {
+	library := &Library{Tracks: make(map[string]Track)}
+	for id := 1; id <= 5; id++ {
+		library.Tracks[strconv.Itoa(id)] = Track{TrackId: id}
+	}
+	items := func(ids ...int) []PlaylistItem {
+		var items []PlaylistItem
+		for _, id := range ids {
+			items = append(items, PlaylistItem{TrackId: id})
+		}
+		return items
+	}
+	library.Playlists = []Playlist{
+		{Name: "Workout", PlaylistId: 1, PlaylistPersistentId: "W", PlaylistItems: items(3, 1, 2)},
+		{Name: "Running", PlaylistId: 2, PlaylistPersistentId: "R", PlaylistItems: items(2, 4, 5)},
+		{Name: "Chill", PlaylistId: 3, PlaylistPersistentId: "C", PlaylistItems: items(1, 5)},
+	}
+	library.BuildPlaylistMaps()
+
+	combinations, err := ParseCombinations("Gym=Workout+Running-Chill,Both=Gym&Running,Other=Gym+Unknown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, missing := Combine(library, combinations)
+	if !reflect.DeepEqual(missing, []string{"Unknown"}) {
+		t.Fatalf("expected the unknown playlist to be missing, got %v", missing)
+	}
+	expected := map[string][]PlaylistItem{
+		"Gym":   items(3, 2, 4),
+		"Both":  items(2, 4),
+		"Other": items(3, 2, 4),
+	}
+	if len(created) != 3 {
+		t.Fatalf("expected 3 playlists, got %v", created)
+	}
+	for _, playlist := range created {
+		if !reflect.DeepEqual(playlist.PlaylistItems, expected[playlist.Name]) {
+			t.Fatalf("expected %v in %v, got %v", expected[playlist.Name], playlist.Name, playlist.PlaylistItems)
+		}
+		if _, ok := library.PlaylistMap[playlist.Name]; !ok {
+			t.Fatalf("expected %v to be added to the library", playlist.Name)
+		}
+	}
+}
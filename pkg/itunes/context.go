@@ -0,0 +1,20 @@
+package itunes
+
+import (
+	"context"
+	"io"
+)
+
+// contextReader fails reads once its context is done, which stops the plist decoder
+// in the middle of a large library.
+type contextReader struct {
+	ctx context.Context
+	io.ReadSeeker
+}
+
+func (reader contextReader) Read(p []byte) (int, error) {
+	if err := reader.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return reader.ReadSeeker.Read(p)
+}
@@ -0,0 +1,155 @@
+package itunes
+
+import (
+	"sort"
+)
+
+// LibraryDiff are the differences between the playlists of two libraries found by
+// DiffLibraries. Each list is sorted by playlist name.
+type LibraryDiff struct {
+	// AddedPlaylists are the playlists only in the new library, with all their tracks added.
+	AddedPlaylists []PlaylistDiff `json:"addedPlaylists"`
+	// RemovedPlaylists are the playlists only in the old library, with all their tracks removed.
+	RemovedPlaylists []PlaylistDiff `json:"removedPlaylists"`
+	// RenamedPlaylists are the playlists whose name changed. Their tracks are compared as well.
+	RenamedPlaylists []PlaylistDiff `json:"renamedPlaylists"`
+	// ChangedPlaylists are the playlists in both libraries whose tracks changed.
+	ChangedPlaylists []PlaylistDiff `json:"changedPlaylists"`
+}
+
+// PlaylistDiff is a playlist added, removed, renamed or changed.
+type PlaylistDiff struct {
+	Name string `json:"name"`
+	// OldName is the name of a renamed playlist in the old library.
+	OldName       string      `json:"oldName,omitempty"`
+	AddedTracks   []DiffTrack `json:"addedTracks,omitempty"`
+	RemovedTracks []DiffTrack `json:"removedTracks,omitempty"`
+}
+
+// DiffTrack is a track added to or removed from a playlist.
+type DiffTrack struct {
+	Name     string `json:"name"`
+	Artist   string `json:"artist"`
+	Album    string `json:"album"`
+	Location string `json:"location"`
+}
+
+// Empty reports whether no differences were found.
+func (diff *LibraryDiff) Empty() bool {
+	return len(diff.AddedPlaylists) == 0 && len(diff.RemovedPlaylists) == 0 &&
+		len(diff.RenamedPlaylists) == 0 && len(diff.ChangedPlaylists) == 0
+}
+
+// DiffLibraries compares the playlists of two libraries, e.g. two versions of the library
+// XML file. Playlists are identified by their persistent id, so renamed playlists are found,
+// and otherwise by their name. Tracks are identified by their persistent id or location.
+// Playlist folders and the master playlist are not compared.
+func DiffLibraries(old, new *Library) LibraryDiff {
+	var diff LibraryDiff
+
+	oldPlaylists := diffPlaylists(old)
+	matched := make(map[int]bool)
+	for _, playlist := range diffPlaylists(new) {
+		oldIndex := -1
+		for i, oldPlaylist := range oldPlaylists {
+			if !matched[i] && playlist.PlaylistPersistentId != "" && oldPlaylist.PlaylistPersistentId == playlist.PlaylistPersistentId {
+				oldIndex = i
+				break
+			}
+		}
+		if oldIndex < 0 {
+			for i, oldPlaylist := range oldPlaylists {
+				if !matched[i] && oldPlaylist.Name == playlist.Name {
+					oldIndex = i
+					break
+				}
+			}
+		}
+
+		if oldIndex < 0 {
+			diff.AddedPlaylists = append(diff.AddedPlaylists, PlaylistDiff{Name: playlist.Name, AddedTracks: diffTracks(new, playlist)})
+			continue
+		}
+		matched[oldIndex] = true
+		oldPlaylist := oldPlaylists[oldIndex]
+
+		added, removed := compareTracks(old, oldPlaylist, new, playlist)
+		if oldPlaylist.Name != playlist.Name {
+			diff.RenamedPlaylists = append(diff.RenamedPlaylists, PlaylistDiff{Name: playlist.Name, OldName: oldPlaylist.Name,
+				AddedTracks: added, RemovedTracks: removed})
+		} else if len(added) > 0 || len(removed) > 0 {
+			diff.ChangedPlaylists = append(diff.ChangedPlaylists, PlaylistDiff{Name: playlist.Name, AddedTracks: added, RemovedTracks: removed})
+		}
+	}
+	for i, playlist := range oldPlaylists {
+		if !matched[i] {
+			diff.RemovedPlaylists = append(diff.RemovedPlaylists, PlaylistDiff{Name: playlist.Name, RemovedTracks: diffTracks(old, playlist)})
+		}
+	}
+
+	for _, playlists := range [][]PlaylistDiff{diff.AddedPlaylists, diff.RemovedPlaylists, diff.RenamedPlaylists, diff.ChangedPlaylists} {
+		sort.SliceStable(playlists, func(i, j int) bool { return playlists[i].Name < playlists[j].Name })
+	}
+	return diff
+}
+
+// diffPlaylists returns the playlists of library that are compared.
+func diffPlaylists(library *Library) []Playlist {
+	var playlists []Playlist
+	for _, playlist := range library.Playlists {
+		if !playlist.Container() && !playlist.Master {
+			playlists = append(playlists, playlist)
+		}
+	}
+	return playlists
+}
+
+// compareTracks returns the tracks of newPlaylist not in oldPlaylist and the tracks of
+// oldPlaylist not in newPlaylist. Tracks contained several times are counted.
+func compareTracks(old *Library, oldPlaylist Playlist, new *Library, newPlaylist Playlist) ([]DiffTrack, []DiffTrack) {
+	counts := make(map[string]int)
+	for _, track := range oldPlaylist.Tracks(old) {
+		counts[trackKey(track)]++
+	}
+	var added []DiffTrack
+	for _, track := range newPlaylist.Tracks(new) {
+		key := trackKey(track)
+		if counts[key] > 0 {
+			counts[key]--
+			continue
+		}
+		added = append(added, diffTrack(track))
+	}
+	var removed []DiffTrack
+	for _, track := range oldPlaylist.Tracks(old) {
+		key := trackKey(track)
+		if counts[key] > 0 {
+			counts[key]--
+			removed = append(removed, diffTrack(track))
+		}
+	}
+	return added, removed
+}
+
+func diffTracks(library *Library, playlist Playlist) []DiffTrack {
+	var tracks []DiffTrack
+	for _, track := range playlist.Tracks(library) {
+		tracks = append(tracks, diffTrack(track))
+	}
+	return tracks
+}
+
+func diffTrack(track Track) DiffTrack {
+	return DiffTrack{Name: track.Name, Artist: track.Artist, Album: track.Album, Location: track.Location}
+}
+
+// trackKey identifies a track across versions of a library.
+func trackKey(track Track) string {
+	if track.PersistentId != "" {
+		return track.PersistentId
+	}
+	if track.Location != "" {
+		return track.Location
+	}
+	return track.Artist + "\x00" + track.Album + "\x00" + track.Name
+}
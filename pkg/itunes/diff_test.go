@@ -0,0 +1,59 @@
+package itunes
+
+import (
+	"reflect"
+	"testing"
+)
+
+func diffLibrary(playlists ...Playlist) *Library {
+	library := &Library{
+		Tracks: map[string]Track{
+			"1": {TrackId: 1, Name: "One", Artist: "U2", PersistentId: "A1"},
+			"2": {TrackId: 2, Name: "Two", Artist: "Blur", PersistentId: "A2"},
+			"3": {TrackId: 3, Name: "Three", Artist: "Muse", PersistentId: "A3"},
+		},
+		Playlists: append([]Playlist{{Name: "Library", Master: true}, {Name: "Folder", Folder: true, PlaylistPersistentId: "F"}}, playlists...),
+	}
+	library.BuildPlaylistMaps()
+	return library
+}
+
+func items(ids ...int) []PlaylistItem {
+	var items []PlaylistItem
+	for _, id := range ids {
+		items = append(items, PlaylistItem{TrackId: id})
+	}
+	return items
+}
+
+func TestDiffLibraries(t *testing.T) {
+	old := diffLibrary(
+		Playlist{Name: "Unchanged", PlaylistPersistentId: "P1", PlaylistItems: items(1, 2)},
+		Playlist{Name: "Chill", PlaylistPersistentId: "P2", PlaylistItems: items(1)},
+		Playlist{Name: "Gone", PlaylistPersistentId: "P3", PlaylistItems: items(3)},
+		Playlist{Name: "Favorites", PlaylistPersistentId: "P4", PlaylistItems: items(1, 2, 2)},
+	)
+	new := diffLibrary(
+		Playlist{Name: "Unchanged", PlaylistPersistentId: "P1", PlaylistItems: items(2, 1)},
+		Playlist{Name: "Chill Out", PlaylistPersistentId: "P2", PlaylistItems: items(1)},
+		Playlist{Name: "Favorites", PlaylistPersistentId: "P5", PlaylistItems: items(2, 3)},
+		Playlist{Name: "Road Trip", PlaylistPersistentId: "P6", PlaylistItems: items(3)},
+	)
+	one := DiffTrack{Name: "One", Artist: "U2"}
+	two := DiffTrack{Name: "Two", Artist: "Blur"}
+	three := DiffTrack{Name: "Three", Artist: "Muse"}
+
+	expected := LibraryDiff{
+		AddedPlaylists:   []PlaylistDiff{{Name: "Road Trip", AddedTracks: []DiffTrack{three}}},
+		RemovedPlaylists: []PlaylistDiff{{Name: "Gone", RemovedTracks: []DiffTrack{three}}},
+		RenamedPlaylists: []PlaylistDiff{{Name: "Chill Out", OldName: "Chill"}},
+		ChangedPlaylists: []PlaylistDiff{{Name: "Favorites", AddedTracks: []DiffTrack{three}, RemovedTracks: []DiffTrack{one, two}}},
+	}
+	if diff := DiffLibraries(old, new); !reflect.DeepEqual(diff, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, diff)
+	}
+
+	if diff := DiffLibraries(old, old); !diff.Empty() {
+		t.Fatalf("expected no differences, got %+v", diff)
+	}
+}
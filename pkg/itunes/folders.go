@@ -0,0 +1,56 @@
+package itunes
+
+// FolderPlaylists creates a playlist for every playlist folder of source with the tracks of
+// all playlists in the folder and its subfolders, without duplicates, like iTunes plays a
+// folder. Each playlist has the name of its folder and is put next to it. The playlists are
+// added to library and returned.
+func FolderPlaylists(library *Library, source []Playlist) []Playlist {
+	children := make(map[string][]Playlist)
+	for _, playlist := range library.Playlists {
+		if playlist.ParentPersistentId != "" {
+			children[playlist.ParentPersistentId] = append(children[playlist.ParentPersistentId], playlist)
+		}
+	}
+
+	nextId := nextPlaylistId(library)
+	var created []Playlist
+	for _, folder := range source {
+		if !folder.Folder {
+			continue
+		}
+		playlist := Playlist{
+			Name:                 folder.Name,
+			PlaylistId:           nextId,
+			PlaylistPersistentId: persistentId("folder", folder.PlaylistPersistentId),
+			ParentPersistentId:   folder.ParentPersistentId,
+			Visible:              true,
+		}
+		nextId++
+		seen := make(map[int]bool)
+		for _, item := range folderItems(children, folder) {
+			if !seen[item.TrackId] {
+				seen[item.TrackId] = true
+				playlist.PlaylistItems = append(playlist.PlaylistItems, item)
+			}
+		}
+		created = append(created, playlist)
+	}
+
+	library.Playlists = append(library.Playlists, created...)
+	library.BuildPlaylistMaps()
+	return created
+}
+
+// folderItems returns the items of the playlists in folder and its subfolders, in the order
+// of the library.
+func folderItems(children map[string][]Playlist, folder Playlist) []PlaylistItem {
+	var items []PlaylistItem
+	for _, child := range children[folder.PlaylistPersistentId] {
+		if child.Folder {
+			items = append(items, folderItems(children, child)...)
+		} else if !child.Container() {
+			items = append(items, child.PlaylistItems...)
+		}
+	}
+	return items
+}
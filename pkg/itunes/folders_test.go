@@ -0,0 +1,35 @@
+package itunes
+
+import (
+	"testing"
+)
+
+func TestFolderPlaylists(t *testing.T) {
+	library := &Library{
+		Playlists: []Playlist{
+			{Name: "Mixes", PlaylistId: 1, PlaylistPersistentId: "F1", Folder: true},
+			{Name: "Summer", PlaylistId: 2, PlaylistPersistentId: "P1", ParentPersistentId: "F1", PlaylistItems: []PlaylistItem{{TrackId: 3}, {TrackId: 1}}},
+			{Name: "Old", PlaylistId: 3, PlaylistPersistentId: "F2", ParentPersistentId: "F1", Folder: true},
+			{Name: "1999", PlaylistId: 4, PlaylistPersistentId: "P2", ParentPersistentId: "F2", PlaylistItems: []PlaylistItem{{TrackId: 1}, {TrackId: 2}}},
+			{Name: "Jazz", PlaylistId: 5, PlaylistPersistentId: "P3", PlaylistItems: []PlaylistItem{{TrackId: 4}}},
+		},
+	}
+	library.BuildPlaylistMaps()
+
+	created := FolderPlaylists(library, library.Playlists)
+	if len(created) != 2 || created[0].Name != "Mixes" || created[1].Name != "Old" || created[1].ParentPersistentId != "F1" {
+		t.Fatalf("unexpected playlists %v", created)
+	}
+	expected := []PlaylistItem{{TrackId: 3}, {TrackId: 1}, {TrackId: 2}}
+	if len(created[0].PlaylistItems) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, created[0].PlaylistItems)
+	}
+	for i, item := range expected {
+		if created[0].PlaylistItems[i] != item {
+			t.Fatalf("expected %v, got %v", expected, created[0].PlaylistItems)
+		}
+	}
+	if created[0].PlaylistId != 6 || len(library.Playlists) != 7 {
+		t.Fatalf("expected the playlists to be added to the library, got %v", library.Playlists)
+	}
+}
@@ -0,0 +1,48 @@
+package itunes
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func assertPathExists(t *testing.T, path string) {
+	_, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("File '%s' does not exist: %v", path, err)
+	}
+}
+
+func createTempDir(t *testing.T, pattern string) string {
+	tmpDirPath, err := ioutil.TempDir("", pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tmpDirPath
+}
+
+func writeFile(t *testing.T, filePath string, content string) {
+	err := ioutil.WriteFile(filePath, []byte(content), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write '%s' to file '%s': %v", content, filePath, err)
+	}
+}
+
+func prepareItunesDbFile(t *testing.T, musicFilePath string) string {
+	itunesDbContent, err := ioutil.ReadFile("../../fixture/example-itunes-db.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	itunesDbContentAdjusted := strings.ReplaceAll(string(itunesDbContent), "REPLACE_ME_EXAMPLE_SONG_LOCATION", "file://"+musicFilePath)
+
+	itunesDbFile, err := ioutil.TempFile("", "testItunesDb_*.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	itunesDbFile.Close()
+	writeFile(t, itunesDbFile.Name(), itunesDbContentAdjusted)
+
+	return itunesDbFile.Name()
+}
@@ -0,0 +1,52 @@
+package itunes
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// IgnoreList are tracks known to be broken, e.g. songs whose files were deleted long ago,
+// which are left out of exports and reports without notice.
+type IgnoreList struct {
+	// entries are the persistent ids, in upper case, and the paths of the ignored tracks.
+	entries map[string]bool
+}
+
+// LoadIgnoreList reads the ignore list file at name. Every line is the persistent id of a
+// track or the path or file URL of its music file. Empty lines and lines starting with #
+// are skipped.
+func LoadIgnoreList(name string) (*IgnoreList, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	list := &IgnoreList{entries: make(map[string]bool)}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !strings.ContainsAny(line, "/\\") {
+			// Persistent ids are hexadecimal numbers, which iTunes writes in upper case.
+			line = strings.ToUpper(line)
+		}
+		list.entries[LocationPath(line)] = true
+	}
+	return list, scanner.Err()
+}
+
+// Ignores reports whether track is on the list, by its persistent id or the path of its
+// music file. A nil list ignores no tracks.
+func (list *IgnoreList) Ignores(track Track) bool {
+	if list == nil {
+		return false
+	}
+	if track.PersistentId != "" && list.entries[strings.ToUpper(track.PersistentId)] {
+		return true
+	}
+	return track.Location != "" && list.entries[LocationPath(track.Location)]
+}
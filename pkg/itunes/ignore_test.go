@@ -0,0 +1,40 @@
+package itunes
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestIgnoreList(t *testing.T) {
+	file, err := ioutil.TempFile("", "itunesexport-ignore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString("# Deleted songs\n\n/Music/Band/Song.mp3\n5a1b2c3d4e5f6a7b\nfile:///Music/Other%20Song.mp3\n")
+	file.Close()
+
+	list, err := LoadIgnoreList(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		track    Track
+		expected bool
+	}{
+		{Track{PersistentId: "0000000000000001", Location: "file:///Music/Band/Song.mp3"}, true},
+		{Track{PersistentId: "5A1B2C3D4E5F6A7B", Location: "file:///Music/Band/Fine.mp3"}, true},
+		{Track{Location: "file:///Music/Other%20Song.mp3"}, true},
+		{Track{PersistentId: "0000000000000002", Location: "file:///Music/Band/Fine.mp3"}, false},
+	} {
+		if ignored := list.Ignores(test.track); ignored != test.expected {
+			t.Fatalf("expected %v for %v, got %v", test.expected, test.track.Location, ignored)
+		}
+	}
+
+	var none *IgnoreList
+	if none.Ignores(Track{PersistentId: "5A1B2C3D4E5F6A7B"}) {
+		t.Fatal("expected a nil list to ignore nothing")
+	}
+}
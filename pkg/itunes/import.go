@@ -0,0 +1,306 @@
+package itunes
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/tags"
+)
+
+// importKinds are the kinds of tracks by the extension of their file.
+var importKinds = map[string]string{
+	".mp3":  "MPEG audio file",
+	".m4a":  "AAC audio file",
+	".m4p":  "Protected AAC audio file",
+	".aac":  "AAC audio file",
+	".flac": "FLAC audio file",
+	".wav":  "WAV audio file",
+	".aif":  "AIFF audio file",
+	".aiff": "AIFF audio file",
+}
+
+// ImportPlaylists builds a library of the M3U and M3U8 playlists in dir. Playlists in its
+// subdirectories are put into playlist folders of the same name. The tracks are described
+// by the tags of their files, or by the extended M3U information of the playlist for files
+// without supported tags.
+func ImportPlaylists(ctx context.Context, dir string) (*Library, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var playlistFiles []string
+	err = filepath.Walk(dir, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		switch strings.ToLower(filepath.Ext(name)) {
+		case ".m3u", ".m3u8":
+			if !info.IsDir() {
+				playlistFiles = append(playlistFiles, name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(playlistFiles)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	importer := &importer{
+		library: &Library{
+			MajorVersion:        1,
+			MinorVersion:        1,
+			Date:                now,
+			Features:            5,
+			ShowContentRating:   true,
+			MusicFolder:         LocationURL(dir) + "/",
+			LibraryPersistentId: persistentId("library", dir),
+			Tracks:              make(map[string]Track),
+		},
+		trackIds: make(map[string]int),
+		folders:  make(map[string]string),
+		added:    now,
+	}
+	master := Playlist{Name: "Library", Master: true, AllItems: true, PlaylistId: 1,
+		PlaylistPersistentId: persistentId("playlist", dir)}
+	importer.library.Playlists = append(importer.library.Playlists, master)
+
+	for _, playlistFile := range playlistFiles {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := importer.importPlaylist(dir, playlistFile); err != nil {
+			return nil, fmt.Errorf("unable to import %v: %v", playlistFile, err)
+		}
+	}
+
+	for id := 1; id <= len(importer.library.Tracks); id++ {
+		importer.library.Playlists[0].PlaylistItems = append(importer.library.Playlists[0].PlaylistItems, PlaylistItem{TrackId: id})
+	}
+	importer.library.BuildPlaylistMaps()
+	return importer.library, nil
+}
+
+type importer struct {
+	library *Library
+	// trackIds maps the file paths or URLs of the tracks imported to their id.
+	trackIds map[string]int
+	// folders maps the relative paths of directories to the persistent id of their folder.
+	folders map[string]string
+	added   time.Time
+}
+
+// importPlaylist adds the playlist file and its tracks to the library.
+func (importer *importer) importPlaylist(dir, playlistFile string) error {
+	relative, err := filepath.Rel(dir, playlistFile)
+	if err != nil {
+		return err
+	}
+	name := strings.TrimSuffix(filepath.Base(playlistFile), filepath.Ext(playlistFile))
+	playlist := Playlist{
+		Name:                 name,
+		PlaylistId:           len(importer.library.Playlists) + 1,
+		PlaylistPersistentId: persistentId("playlist", relative),
+		ParentPersistentId:   importer.folder(filepath.Dir(relative)),
+		Visible:              true,
+		AllItems:             true,
+	}
+
+	file, err := os.Open(playlistFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var info extendedInfo
+	scanner := bufio.NewScanner(file)
+	for first := true; scanner.Scan(); first = false {
+		line := scanner.Text()
+		if first {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if !utf8.ValidString(line) {
+			line = latin1String(line)
+		}
+		line = strings.TrimSpace(line)
+
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#EXTINF:"):
+			info = parseExtendedInfo(line[len("#EXTINF:"):])
+		case strings.HasPrefix(line, "#"):
+		default:
+			id := importer.track(resolveLocation(filepath.Dir(playlistFile), line), info)
+			playlist.PlaylistItems = append(playlist.PlaylistItems, PlaylistItem{TrackId: id})
+			info = extendedInfo{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	importer.library.Playlists = append(importer.library.Playlists, playlist)
+	return nil
+}
+
+// folder returns the persistent id of the playlist folder of the relative directory,
+// adding it and its parents to the library as needed.
+func (importer *importer) folder(relative string) string {
+	if relative == "." {
+		return ""
+	}
+	if id, ok := importer.folders[relative]; ok {
+		return id
+	}
+	parent := importer.folder(filepath.Dir(relative))
+	id := persistentId("folder", relative)
+	importer.library.Playlists = append(importer.library.Playlists, Playlist{
+		Name:                 filepath.Base(relative),
+		PlaylistId:           len(importer.library.Playlists) + 1,
+		PlaylistPersistentId: id,
+		ParentPersistentId:   parent,
+		Folder:               true,
+		Visible:              true,
+		AllItems:             true,
+	})
+	importer.folders[relative] = id
+	return id
+}
+
+// track returns the id of the track at location, adding it to the library if it was not
+// imported before.
+func (importer *importer) track(location string, info extendedInfo) int {
+	if id, ok := importer.trackIds[location]; ok {
+		return id
+	}
+	id := len(importer.trackIds) + 1
+	importer.trackIds[location] = id
+
+	track := Track{
+		TrackId:      id,
+		Name:         info.title,
+		Artist:       info.artist,
+		TotalTime:    info.seconds * 1000,
+		DateAdded:    importer.added,
+		PersistentId: persistentId("track", location),
+	}
+
+	if strings.Contains(location, "://") {
+		track.Kind = "Internet audio stream"
+		track.TrackType = "URL"
+		track.Location = location
+		if track.Name == "" {
+			track.Name = location
+		}
+		importer.library.Tracks[strconv.Itoa(id)] = track
+		return id
+	}
+
+	track.TrackType = "File"
+	track.Location = LocationURL(location)
+	track.Kind = importKinds[strings.ToLower(filepath.Ext(location))]
+	if fileInfo, err := os.Stat(location); err == nil {
+		track.Size = int(fileInfo.Size())
+		track.DateModified = fileInfo.ModTime().UTC().Truncate(time.Second)
+	}
+	if fileTags, err := tags.Read(location); err == nil {
+		setIfNotEmpty(&track.Name, fileTags.Title)
+		setIfNotEmpty(&track.Artist, fileTags.Artist)
+		track.AlbumArtist = fileTags.AlbumArtist
+		track.Album = fileTags.Album
+		track.Composer = fileTags.Composer
+		track.Genre = fileTags.Genre
+		track.Comments = fileTags.Comment
+		track.Year = fileTags.Year
+		track.TrackNumber = fileTags.TrackNumber
+		track.TrackCount = fileTags.TrackCount
+		track.DiscNumber = fileTags.DiscNumber
+		track.DiscCount = fileTags.DiscCount
+		track.BitRate = fileTags.BitRate
+		track.SampleRate = fileTags.SampleRate
+		if fileTags.Duration > 0 {
+			track.TotalTime = int(fileTags.Duration.Milliseconds())
+		}
+	}
+	if track.Name == "" {
+		track.Name = strings.TrimSuffix(filepath.Base(location), filepath.Ext(location))
+	}
+
+	importer.library.Tracks[strconv.Itoa(id)] = track
+	return id
+}
+
+// extendedInfo is the information of an #EXTINF line of an extended M3U playlist.
+type extendedInfo struct {
+	seconds int
+	artist  string
+	title   string
+}
+
+// parseExtendedInfo parses the value of an #EXTINF line, e.g. "210,Artist - Title".
+func parseExtendedInfo(value string) extendedInfo {
+	var info extendedInfo
+	parts := strings.SplitN(value, ",", 2)
+	if seconds, err := strconv.Atoi(strings.TrimSpace(parts[0])); err == nil && seconds > 0 {
+		info.seconds = seconds
+	}
+	if len(parts) == 2 {
+		info.title = strings.TrimSpace(parts[1])
+		if i := strings.Index(info.title, " - "); i >= 0 {
+			info.artist, info.title = info.title[:i], info.title[i+3:]
+		}
+	}
+	return info
+}
+
+// resolveLocation returns the file path of a location of a playlist in dir: a file URL, an
+// absolute path or a path relative to dir, with slashes or backslashes. Other URLs are
+// returned unchanged.
+func resolveLocation(dir, location string) string {
+	if strings.HasPrefix(strings.ToLower(location), "file://") {
+		return LocationPath(location)
+	}
+	if strings.Contains(location, "://") {
+		return location
+	}
+	if runtime.GOOS != "windows" {
+		location = strings.Replace(location, "\\", "/", -1)
+	}
+	if !filepath.IsAbs(location) {
+		location = filepath.Join(dir, location)
+	}
+	return filepath.Clean(location)
+}
+
+// persistentId returns a persistent id that is the same for every import of the same name.
+func persistentId(kind, name string) string {
+	hash := sha1.Sum([]byte(kind + ":" + filepath.ToSlash(name)))
+	return fmt.Sprintf("%016X", hash[:8])
+}
+
+func setIfNotEmpty(field *string, value string) {
+	if value != "" {
+		*field = value
+	}
+}
+
+// latin1String returns s, which is not valid UTF-8, decoded as Latin-1.
+func latin1String(s string) string {
+	runes := make([]rune, len(s))
+	for i := 0; i < len(s); i++ {
+		runes[i] = rune(s[i])
+	}
+	return string(runes)
+}
@@ -0,0 +1,76 @@
+package itunes
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestImportPlaylists(t *testing.T) {
+	dir := createTempDir(t, "itunes-import-test")
+	defer os.RemoveAll(dir)
+
+	// ID3v2.3 tag with title and artist, followed by a 1 second MPEG frame at 128 kbit/s
+	mp3 := "ID3\x03\x00\x00\x00\x00\x00\x29" +
+		"TIT2\x00\x00\x00\x09\x00\x00\x00Hey Jude" +
+		"TPE1\x00\x00\x00\x0c\x00\x00\x00The Beatles"
+	mp3 += "\xff\xfb\x90\x00" + string(make([]byte, 15996))
+	os.Mkdir(filepath.Join(dir, "Music"), 0777)
+	writeFile(t, filepath.Join(dir, "Music", "01 hey jude.mp3"), mp3)
+	writeFile(t, filepath.Join(dir, "Music", "song.ogg"), "42")
+
+	writeFile(t, filepath.Join(dir, "Favorites.m3u"), "#EXTM3U\n#EXTINF:100,Someone - Something\nMusic\\01 hey jude.mp3\n"+
+		"#EXTINF:180,Nina Simone - Feeling Good\nMusic/song.ogg\n")
+	os.MkdirAll(filepath.Join(dir, "Moods", "Calm"), 0777)
+	writeFile(t, filepath.Join(dir, "Moods", "Calm", "Evening.m3u8"), "\ufeff"+filepath.Join(dir, "Music", "song.ogg")+"\n")
+
+	library, err := ImportPlaylists(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	libraryFile := filepath.Join(dir, "Library.xml")
+	file, err := os.Create(libraryFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteLibrary(file, library); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	library, err = LoadLibrary(libraryFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(library.Tracks) != 2 || len(library.Playlists) != 5 {
+		t.Fatalf("expected 2 tracks and 5 playlists, got %+v", library)
+	}
+
+	favorites := library.PlaylistMap["Favorites"]
+	tracks := favorites.Tracks(library)
+	if len(tracks) != 2 {
+		t.Fatalf("expected 2 tracks in Favorites, got %+v", favorites)
+	}
+	if track := tracks[0]; track.Name != "Hey Jude" || track.Artist != "The Beatles" || track.TotalTime != 1000 ||
+		track.Kind != "MPEG audio file" || LocationPath(track.Location) != filepath.Join(dir, "Music", "01 hey jude.mp3") {
+		t.Fatalf("unexpected tagged track %+v", track)
+	}
+	if track := tracks[1]; track.Name != "Feeling Good" || track.Artist != "Nina Simone" || track.TotalTime != 180000 || track.Size != 2 {
+		t.Fatalf("unexpected untagged track %+v", track)
+	}
+
+	evening := library.PlaylistMap["Evening"]
+	calm := library.PlaylistIdMap[evening.ParentPersistentId]
+	moods := library.PlaylistIdMap[calm.ParentPersistentId]
+	if !calm.Folder || calm.Name != "Calm" || !moods.Folder || moods.Name != "Moods" || moods.ParentPersistentId != "" {
+		t.Fatalf("unexpected folders %+v and %+v", calm, moods)
+	}
+	if items := evening.PlaylistItems; len(items) != 1 || items[0].TrackId != tracks[1].TrackId {
+		t.Fatalf("expected Evening to reference the same track, got %+v", items)
+	}
+	if master := library.Playlists[0]; !master.Master || len(master.PlaylistItems) != 2 {
+		t.Fatalf("unexpected master playlist %+v", master)
+	}
+}
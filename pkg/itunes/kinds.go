@@ -0,0 +1,83 @@
+package itunes
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Distinguished kinds of the playlists maintained by iTunes itself.
+const (
+	KIND_NONE             = 0
+	KIND_MOVIES           = 2
+	KIND_TV_SHOWS         = 3
+	KIND_MUSIC            = 4
+	KIND_AUDIOBOOKS       = 5
+	KIND_PODCASTS         = 10
+	KIND_PURCHASED        = 19
+	KIND_ITUNES_DJ        = 22
+	KIND_GENIUS           = 26
+	KIND_ITUNES_U         = 31
+	KIND_GENIUS_MIXES     = 32
+	KIND_MUSIC_VIDEOS     = 47
+	KIND_HOME_VIDEOS      = 48
+	KIND_DOWNLOADED       = 65
+	KIND_DOWNLOADED_MUSIC = 66
+)
+
+var playlistKinds = map[string]int{
+	"MOVIES":          KIND_MOVIES,
+	"TVSHOWS":         KIND_TV_SHOWS,
+	"MUSIC":           KIND_MUSIC,
+	"AUDIOBOOKS":      KIND_AUDIOBOOKS,
+	"PODCASTS":        KIND_PODCASTS,
+	"PURCHASED":       KIND_PURCHASED,
+	"ITUNESDJ":        KIND_ITUNES_DJ,
+	"GENIUS":          KIND_GENIUS,
+	"ITUNESU":         KIND_ITUNES_U,
+	"GENIUSMIXES":     KIND_GENIUS_MIXES,
+	"MUSICVIDEOS":     KIND_MUSIC_VIDEOS,
+	"HOMEVIDEOS":      KIND_HOME_VIDEOS,
+	"DOWNLOADED":      KIND_DOWNLOADED,
+	"DOWNLOADEDMUSIC": KIND_DOWNLOADED_MUSIC,
+}
+
+// ParsePlaylistKind returns the distinguished kind with the given name, e.g. "Genius Mixes"
+// or "GeniusMixes". Case and spaces are ignored. Kinds without a name can be given by number.
+func ParsePlaylistKind(name string) (int, error) {
+	key := strings.ToUpper(strings.Replace(strings.TrimSpace(name), " ", "", -1))
+	if kind, ok := playlistKinds[key]; ok {
+		return kind, nil
+	}
+	if kind, err := strconv.Atoi(key); err == nil && kind > 0 {
+		return kind, nil
+	}
+	return 0, errors.New("Unknown playlist kind: " + name)
+}
+
+// ParsePlaylistKinds parses a comma separated list of distinguished kinds.
+func ParsePlaylistKinds(names string) ([]int, error) {
+	var kinds []int
+	for _, name := range strings.Split(names, ",") {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		kind, err := ParsePlaylistKind(name)
+		if err != nil {
+			return nil, err
+		}
+		kinds = append(kinds, kind)
+	}
+	return kinds, nil
+}
+
+// PlaylistKindNames returns the names accepted by ParsePlaylistKind.
+func PlaylistKindNames() []string {
+	var names []string
+	for name := range playlistKinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,17 @@
+package itunes
+
+import "testing"
+
+func TestParsePlaylistKinds(t *testing.T) {
+	kinds, err := ParsePlaylistKinds("Genius, genius mixes,Downloaded,,70")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kinds) != 4 || kinds[0] != KIND_GENIUS || kinds[1] != KIND_GENIUS_MIXES || kinds[2] != KIND_DOWNLOADED || kinds[3] != 70 {
+		t.Fatalf("unexpected kinds %v", kinds)
+	}
+
+	if _, err := ParsePlaylistKinds("Genius,Nonsense"); err == nil {
+		t.Fatal("expected unknown kind to be rejected")
+	}
+}
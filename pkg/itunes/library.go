@@ -1,6 +1,8 @@
-package main
+// Package itunes reads the iTunes Music Library XML file.
+package itunes
 
 import (
+	"context"
 	"os"
 	"regexp"
 	"strconv"
@@ -23,8 +25,8 @@ type Library struct {
 	LibraryPersistentId string `plist:"Library Persistent ID"`
 	Tracks              map[string]Track
 	Playlists           []Playlist
-	PlaylistMap         map[string]Playlist
-	PlaylistIdMap       map[string]Playlist
+	PlaylistMap         map[string]Playlist `plist:"-"`
+	PlaylistIdMap       map[string]Playlist `plist:"-"`
 }
 
 type Track struct {
@@ -65,6 +67,9 @@ type Track struct {
 	LibraryFolderCount  int `plist:"Library Folder Count"`
 	Loved               bool
 	Disabled            bool
+	Compilation         bool
+	Explicit            bool
+	Clean               bool
 	Comments            string
 	SortName            string `plist:"Sort Name"`
 	SortAlbum           string `plist:"Sort Album"`
@@ -72,8 +77,20 @@ type Track struct {
 	SortArtist          string `plist:"Sort Artist"`
 	SortComposer        string `plist:"Sort Composer"`
 	Work                string
+	MovementName        string `plist:"Movement Name"`
+	MovementNumber      int    `plist:"Movement Number"`
+	MovementCount       int    `plist:"Movement Count"`
 	Grouping            string
 	VolumeAdjustment    int `plist:"Volume Adjustment"`
+	Podcast             bool
+	ReleaseDate         time.Time `plist:"Release Date"`
+	HasVideo            bool      `plist:"Has Video"`
+	Movie               bool
+	MusicVideo          bool `plist:"Music Video"`
+	TVShow              bool `plist:"TV Show"`
+	Series              string
+	Season              int
+	EpisodeOrder        int `plist:"Episode Order"`
 }
 
 type Playlist struct {
@@ -95,11 +112,23 @@ func (p Playlist) SafeName() string {
 	return illegalChars.ReplaceAllString(p.Name, "_")
 }
 
+// Container reports whether the playlist only groups other playlists and has no tracks to
+// export of its own: a playlist folder or the Genius Mixes playlist, whose mixes are not in
+// the library file.
+func (p Playlist) Container() bool {
+	return p.Folder || p.DistinguishedKind == KIND_GENIUS_MIXES && len(p.PlaylistItems) == 0
+}
+
 type PlaylistItem struct {
 	TrackId int `plist:"Track ID"`
 }
 
 func LoadLibrary(fileLocation string) (*Library, error) {
+	return LoadLibraryContext(context.Background(), fileLocation)
+}
+
+// LoadLibraryContext is like LoadLibrary, but stops parsing once ctx is done.
+func LoadLibraryContext(ctx context.Context, fileLocation string) (*Library, error) {
 	if _, statErr := os.Stat(fileLocation); os.IsNotExist(statErr) {
 		return nil, statErr
 	}
@@ -109,7 +138,9 @@ func LoadLibrary(fileLocation string) (*Library, error) {
 		return nil, pathErr
 	}
 
-	decoder := plist.NewDecoder(file)
+	defer file.Close()
+
+	decoder := plist.NewDecoder(contextReader{ctx, file})
 
 	var library Library
 	decodeErr := decoder.Decode(&library)
@@ -117,14 +148,85 @@ func LoadLibrary(fileLocation string) (*Library, error) {
 		return nil, decodeErr
 	}
 
+	library.BuildPlaylistMaps()
+
+	return &library, nil
+}
+
+// lazyTrack defers decoding a track until it is known to be needed.
+type lazyTrack struct {
+	unmarshal func(interface{}) error
+}
+
+func (track *lazyTrack) UnmarshalPlist(unmarshal func(interface{}) error) error {
+	track.unmarshal = unmarshal
+	return nil
+}
+
+// lazyLibrary is a Library whose tracks have not been decoded yet.
+type lazyLibrary struct {
+	Library
+	Tracks map[string]lazyTrack
+}
+
+// LoadLibraryForPlaylists loads the library like LoadLibrary, but only decodes the tracks
+// of the playlists returned by selectPlaylists, which is passed the library before any
+// track has been decoded. The library file is still parsed completely, so reading it
+// takes about as much time and memory as LoadLibrary; only the tracks outside the
+// selected playlists are neither converted to Track values nor kept afterwards.
+func LoadLibraryForPlaylists(fileLocation string, selectPlaylists func(*Library) []Playlist) (*Library, error) {
+	return LoadLibraryForPlaylistsContext(context.Background(), fileLocation, selectPlaylists)
+}
+
+// LoadLibraryForPlaylistsContext is like LoadLibraryForPlaylists, but stops parsing once ctx is done.
+func LoadLibraryForPlaylistsContext(ctx context.Context, fileLocation string, selectPlaylists func(*Library) []Playlist) (*Library, error) {
+	file, err := os.Open(fileLocation)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lazy lazyLibrary
+	if err := plist.NewDecoder(contextReader{ctx, file}).Decode(&lazy); err != nil {
+		return nil, err
+	}
+
+	library := lazy.Library
+	library.BuildPlaylistMaps()
+	library.Tracks = make(map[string]Track)
+	for _, playlist := range selectPlaylists(&library) {
+		for _, item := range playlist.PlaylistItems {
+			key := strconv.FormatInt(int64(item.TrackId), 10)
+			if _, ok := library.Tracks[key]; ok {
+				continue
+			}
+			lazyTrack, ok := lazy.Tracks[key]
+			if !ok || lazyTrack.unmarshal == nil {
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			var track Track
+			if err := lazyTrack.unmarshal(&track); err != nil {
+				return nil, err
+			}
+			library.Tracks[key] = track
+		}
+	}
+
+	return &library, nil
+}
+
+// BuildPlaylistMaps indexes the playlists by name and persistent id. It must be called again
+// after the playlists were changed.
+func (library *Library) BuildPlaylistMaps() {
 	library.PlaylistMap = make(map[string]Playlist)
 	library.PlaylistIdMap = make(map[string]Playlist)
 	for _, value := range library.Playlists {
 		library.PlaylistMap[value.Name] = value
 		library.PlaylistIdMap[value.PlaylistPersistentId] = value
 	}
-
-	return &library, nil
 }
 
 func (playlist *Playlist) Tracks(library *Library) []Track {
@@ -0,0 +1,45 @@
+package itunes
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestLoadLibraryForPlaylists(t *testing.T) {
+	itunesDbFile := prepareItunesDbFile(t, "/music/song.mp3")
+	defer os.Remove(itunesDbFile)
+
+	library, err := LoadLibraryForPlaylists(itunesDbFile, func(library *Library) []Playlist {
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(library.Playlists) != 1 || len(library.Tracks) != 0 {
+		t.Fatalf("expected all playlists but no tracks, got %v playlists and %v tracks", len(library.Playlists), len(library.Tracks))
+	}
+
+	library, err = LoadLibraryForPlaylists(itunesDbFile, func(library *Library) []Playlist {
+		return []Playlist{library.PlaylistMap["My Playlist"]}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	playlist := library.PlaylistMap["My Playlist"]
+	tracks := playlist.Tracks(library)
+	if len(tracks) != 1 || tracks[0].Name != "Some Song" || tracks[0].Location != "file:///music/song.mp3" {
+		t.Fatalf("unexpected tracks: %+v", tracks)
+	}
+}
+
+func TestLoadLibraryContextStopsWhenCancelled(t *testing.T) {
+	itunesDbFile := prepareItunesDbFile(t, "/music/song.mp3")
+	defer os.Remove(itunesDbFile)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := LoadLibraryContext(ctx, itunesDbFile); err == nil {
+		t.Fatal("expected loading to fail with a cancelled context")
+	}
+}
@@ -0,0 +1,132 @@
+package itunes
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// LocationPath returns the local file path of a track location or the Music Folder of the
+// library, which are file URLs. It handles the quirks of the URLs found in iTunes libraries:
+//   - "file://localhost/" and "file:///" prefixes, Windows drive letters and network shares
+//   - "+" characters, which are not encoded spaces in a path
+//   - "%" characters which do not start an escape sequence, which are kept as they are
+//   - percent-encoded Latin-1 instead of UTF-8
+//   - doubly-encoded escape sequences, if only the doubly decoded file exists
+//
+// Locations which are not file URLs, e.g. streams, are returned unchanged.
+func LocationPath(location string) string {
+	const scheme = "file://"
+	if len(location) < len(scheme) || !strings.EqualFold(location[:len(scheme)], scheme) {
+		return location
+	}
+
+	host, path := location[len(scheme):], ""
+	if i := strings.Index(host, "/"); i >= 0 {
+		host, path = host[:i], host[i:]
+	}
+	switch {
+	case strings.EqualFold(host, "localhost"):
+		host = ""
+	case len(host) == 2 && host[1] == ':' && isLetter(host[0]):
+		// A drive letter in place of the host, e.g. file://C:/Music/song.mp3
+		host, path = "", "/"+host+path
+	}
+
+	path = unescapePath(path)
+	if decoded := unescapePath(path); decoded != path {
+		if _, err := os.Stat(localPath(host, path)); err != nil {
+			if _, err := os.Stat(localPath(host, decoded)); err == nil {
+				path = decoded
+			}
+		}
+	}
+	return localPath(host, path)
+}
+
+// LocationURL returns the file URL of the local file path as iTunes writes it to the library,
+// e.g. file://localhost/Users/me/Music/song.mp3 or file://localhost/C:/Music/song.mp3.
+func LocationURL(path string) string {
+	path = filepath.ToSlash(path)
+	host := "localhost"
+	if strings.HasPrefix(path, "//") {
+		// A network share, e.g. //server/share/song.mp3
+		path = path[2:]
+		host = path
+		if i := strings.Index(path, "/"); i >= 0 {
+			host, path = path[:i], path[i:]
+		} else {
+			path = "/"
+		}
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return "file://" + host + (&url.URL{Path: path}).EscapedPath()
+}
+
+// localPath returns the file path of the decoded path of a file URL on host.
+func localPath(host, path string) string {
+	if host != "" {
+		return filepath.FromSlash("//" + host + path)
+	}
+	if len(path) >= 3 && path[0] == '/' && path[2] == ':' && isLetter(path[1]) {
+		path = path[1:]
+	}
+	return filepath.FromSlash(path)
+}
+
+// unescapePath decodes the escape sequences of path. Invalid escape sequences are kept.
+// If the decoded bytes are not valid UTF-8 they are taken to be Latin-1.
+func unescapePath(path string) string {
+	if !strings.Contains(path, "%") {
+		return path
+	}
+
+	decoded := make([]byte, 0, len(path))
+	escaped := make([]bool, 0, len(path))
+	for i := 0; i < len(path); i++ {
+		if path[i] == '%' && i+2 < len(path) && isHex(path[i+1]) && isHex(path[i+2]) {
+			decoded = append(decoded, unhex(path[i+1])<<4|unhex(path[i+2]))
+			escaped = append(escaped, true)
+			i += 2
+			continue
+		}
+		decoded = append(decoded, path[i])
+		escaped = append(escaped, false)
+	}
+	if utf8.Valid(decoded) {
+		return string(decoded)
+	}
+
+	var latin1 strings.Builder
+	for i, b := range decoded {
+		if escaped[i] {
+			latin1.WriteRune(rune(b))
+		} else {
+			latin1.WriteByte(b)
+		}
+	}
+	return latin1.String()
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case c <= '9':
+		return c - '0'
+	case c <= 'F':
+		return c - 'A' + 10
+	default:
+		return c - 'a' + 10
+	}
+}
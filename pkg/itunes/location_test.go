@@ -0,0 +1,61 @@
+package itunes
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocationPath(t *testing.T) {
+	for location, path := range map[string]string{
+		"file://localhost/Users/me/Music/song.mp3": "/Users/me/Music/song.mp3",
+		"file:///Users/me/Music/song.mp3":          "/Users/me/Music/song.mp3",
+		"FILE:///Users/me/Music/song.mp3":          "/Users/me/Music/song.mp3",
+		"file://localhost/C:/Music/song.mp3":       "C:/Music/song.mp3",
+		"file:///C:/Music/song.mp3":                "C:/Music/song.mp3",
+		"file://C:/Music/song.mp3":                 "C:/Music/song.mp3",
+		"file://nas/music/song.mp3":                "//nas/music/song.mp3",
+		"file:///music/Beyonc%C3%A9.mp3":           "/music/Beyoncé.mp3",
+		"file:///music/Beyonc%E9.mp3":              "/music/Beyoncé.mp3",
+		"file:///music/C++%20for%20you.mp3":        "/music/C++ for you.mp3",
+		"file:///music/Track%20%2301.mp3":          "/music/Track #01.mp3",
+		"file:///music/100%25%20Hits.mp3":          "/music/100% Hits.mp3",
+		"file:///music/100% Hits.mp3":              "/music/100% Hits.mp3",
+		"file:///music/50%2520Cent.mp3":            "/music/50%20Cent.mp3",
+		"http://example.com/stream.mp3":            "http://example.com/stream.mp3",
+	} {
+		if location[:4] == "file" {
+			path = filepath.FromSlash(path)
+		}
+		if got := LocationPath(location); got != path {
+			t.Errorf("expected %q for %q, got %q", path, location, got)
+		}
+	}
+}
+
+func TestLocationPathDoublyEncoded(t *testing.T) {
+	dir := createTempDir(t, "itunes-location-test")
+	defer os.RemoveAll(dir)
+	writeFile(t, filepath.Join(dir, "50 Cent.mp3"), "42")
+
+	location := "file://" + filepath.ToSlash(dir) + "/50%2520Cent.mp3"
+	if got := LocationPath(location); got != filepath.Join(dir, "50 Cent.mp3") {
+		t.Fatalf("expected doubly decoded path, got %q", got)
+	}
+}
+
+func TestLocationURL(t *testing.T) {
+	for path, location := range map[string]string{
+		"/Users/me/Music/song.mp3":  "file://localhost/Users/me/Music/song.mp3",
+		"C:/Music/Beyoncé.mp3":      "file://localhost/C:/Music/Beyonc%C3%A9.mp3",
+		"//nas/music/Track #01.mp3": "file://nas/music/Track%20%2301.mp3",
+		"/music/100% Hits.mp3":      "file://localhost/music/100%25%20Hits.mp3",
+	} {
+		if got := LocationURL(filepath.FromSlash(path)); got != location {
+			t.Errorf("expected %q for %q, got %q", location, path, got)
+		}
+		if got := LocationPath(location); got != filepath.FromSlash(path) {
+			t.Errorf("expected %q to be read back as %q, got %q", location, path, got)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package itunes
+
+import (
+	"os"
+	"strings"
+)
+
+// compositions maps canonical decompositions back to the precomposed letters.
+var compositions = make(map[string]rune, len(decompositions))
+
+func init() {
+	for r, decomposition := range decompositions {
+		compositions[decomposition] = r
+	}
+}
+
+// StatPath returns the file info of path. If path does not exist, its NFC and NFD Unicode
+// normalization forms are tried as well, as the accented file names of libraries moved
+// between macOS and other systems often differ in their normalization. It returns the
+// path of the file found.
+func StatPath(path string) (string, os.FileInfo, error) {
+	info, err := os.Stat(path)
+	if err == nil || !os.IsNotExist(err) {
+		return path, info, err
+	}
+
+	for _, alternative := range []string{toNFC(path), toNFD(path)} {
+		if alternative == path {
+			continue
+		}
+		if info, alternativeErr := os.Stat(alternative); alternativeErr == nil {
+			return alternative, info, nil
+		}
+	}
+	return path, nil, err
+}
+
+// toNFD decomposes the precomposed letters of s.
+func toNFD(s string) string {
+	var decomposed strings.Builder
+	for _, r := range s {
+		if decomposition, ok := decompositions[r]; ok {
+			decomposed.WriteString(decomposition)
+		} else {
+			decomposed.WriteRune(r)
+		}
+	}
+	return decomposed.String()
+}
+
+// toNFC composes letters followed by combining marks into precomposed letters.
+func toNFC(s string) string {
+	runes := []rune(toNFD(s))
+
+	var composed strings.Builder
+	for i := 0; i < len(runes); {
+		end := i + 1
+		for end < len(runes) && isCombiningMark(runes[end]) {
+			end++
+		}
+
+		found := false
+		for length := end; length >= i+2; length-- {
+			if r, ok := compositions[string(runes[i:length])]; ok {
+				composed.WriteRune(r)
+				composed.WriteString(string(runes[length:end]))
+				found = true
+				break
+			}
+		}
+		if found {
+			i = end
+		} else {
+			composed.WriteRune(runes[i])
+			i++
+		}
+	}
+	return composed.String()
+}
+
+func isCombiningMark(r rune) bool {
+	return r >= 0x0300 && r <= 0x036F
+}
+
+// FoldAccents removes the accents of the letters of s, e.g. "Beyoncé" becomes "Beyonce", to
+// compare names regardless of their spelling with or without accents.
+func FoldAccents(s string) string {
+	var folded strings.Builder
+	for _, r := range toNFD(s) {
+		if !isCombiningMark(r) {
+			folded.WriteRune(r)
+		}
+	}
+	return folded.String()
+}
@@ -0,0 +1,44 @@
+package itunes
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalizationForms(t *testing.T) {
+	nfc, nfd := "Beyonc\u00e9 - Caf\u00e9 \u1ea4", "Beyonce\u0301 - Cafe\u0301 A\u0302\u0301"
+
+	if got := toNFD(nfc); got != nfd {
+		t.Errorf("expected %q, got %q", nfd, got)
+	}
+	if got := toNFC(nfd); got != nfc {
+		t.Errorf("expected %q, got %q", nfc, got)
+	}
+	if got := toNFC("plain"); got != "plain" {
+		t.Errorf("expected unchanged string, got %q", got)
+	}
+	if got := FoldAccents(nfc); got != "Beyonce - Cafe A" {
+		t.Errorf("expected accents to be removed, got %q", got)
+	}
+}
+
+func TestStatPath(t *testing.T) {
+	dir := createTempDir(t, "itunes-normalize-test")
+	defer os.RemoveAll(dir)
+
+	nfdName := filepath.Join(dir, "Beyonce\u0301.mp3")
+	writeFile(t, nfdName, "42")
+
+	path, info, err := StatPath(filepath.Join(dir, "Beyonc\u00e9.mp3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != nfdName || info.Size() != 2 {
+		t.Fatalf("expected %q, got %q", nfdName, path)
+	}
+
+	if _, _, err := StatPath(filepath.Join(dir, "missing.mp3")); !os.IsNotExist(err) {
+		t.Fatalf("expected missing file, got %v", err)
+	}
+}
@@ -0,0 +1,10 @@
+package itunes
+
+import "strings"
+
+// TrimLocationPrefix removes the file://localhost/ prefix from a decoded track location.
+//
+// Deprecated: use LocationPath, which decodes the complete location.
+func TrimLocationPrefix(path string) string {
+	return strings.TrimPrefix(path, "file://localhost/")
+}
@@ -0,0 +1,53 @@
+package itunes
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// PodcastEpisode reports whether the track is an episode of a podcast.
+func (track Track) PodcastEpisode() bool {
+	return track.Podcast || strings.EqualFold(strings.TrimSpace(track.Genre), "podcast")
+}
+
+// Published returns the date the track was released, or the date it was added to the library
+// if iTunes does not know it.
+func (track Track) Published() time.Time {
+	if !track.ReleaseDate.IsZero() {
+		return track.ReleaseDate
+	}
+	return track.DateAdded
+}
+
+// PodcastPlaylists generates a playlist for every podcast show in the source playlists, in
+// the playlist folder "Podcasts". The show of an episode is its album, or its artist if it
+// has no album. The episodes of each show are sorted by the date they were published, the
+// oldest first. The folder and playlists are added to library and returned, the folder
+// first.
+func PodcastPlaylists(library *Library, source []Playlist) []Playlist {
+	var episodes []Track
+	for _, track := range uniqueTracks(library, source) {
+		if track.PodcastEpisode() {
+			episodes = append(episodes, track)
+		}
+	}
+	shows := groupTracks(episodes, PodcastShow)
+	for _, show := range shows {
+		tracks := show.tracks
+		sort.SliceStable(tracks, func(i, j int) bool {
+			return tracks[i].Published().Before(tracks[j].Published())
+		})
+	}
+	created := addAutoPlaylists(library, "PODCAST", "Podcasts", shows)
+	library.BuildPlaylistMaps()
+	return created
+}
+
+// PodcastShow returns the name of the show of a podcast episode.
+func PodcastShow(track Track) string {
+	if track.Album != "" {
+		return track.Album
+	}
+	return track.Artist
+}
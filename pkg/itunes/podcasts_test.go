@@ -0,0 +1,25 @@
+package itunes
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPodcastPlaylists(t *testing.T) {
+	library := autoTestLibrary()
+	library.Tracks["1"] = Track{TrackId: 1, Name: "Episode 2", Album: "The Show", Podcast: true, ReleaseDate: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)}
+	library.Tracks["2"] = Track{TrackId: 2, Name: "Episode 1", Album: "The Show", Podcast: true, ReleaseDate: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)}
+	library.Tracks["3"] = Track{TrackId: 3, Name: "Interview", Artist: "Radio", Genre: "Podcast", DateAdded: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	playlists := PodcastPlaylists(library, library.Playlists[:1])
+	if len(playlists) != 3 || playlists[0].Name != "Podcasts" || playlists[1].Name != "Radio" || playlists[2].Name != "The Show" {
+		t.Fatalf("expected the Podcasts folder with a playlist of each show, got %v", playlists)
+	}
+	items := playlists[2].PlaylistItems
+	if len(items) != 2 || items[0].TrackId != 2 || items[1].TrackId != 1 {
+		t.Fatalf("expected the episodes oldest first, got %v", items)
+	}
+	if published := library.Tracks["3"].Published(); !published.Equal(library.Tracks["3"].DateAdded) {
+		t.Fatalf("expected the date added for episodes without release date, got %v", published)
+	}
+}
@@ -0,0 +1,36 @@
+package itunes
+
+import (
+	"math/rand"
+)
+
+// RandomFillName is the name of the playlist created by RandomFill.
+const RandomFillName = "Random Fill"
+
+// RandomFill creates a playlist of tracks of the source playlists sampled at random until
+// their files fill budget bytes. Tracks for which include returns false and tracks without
+// a known size are left out. The same seed gives the same playlist for the same library.
+// The playlist is added to library and returned.
+func RandomFill(library *Library, source []Playlist, budget int64, seed int64, include func(track Track) bool) Playlist {
+	var candidates []Track
+	for _, track := range uniqueTracks(library, source) {
+		if track.Size > 0 && (include == nil || include(track)) {
+			candidates = append(candidates, track)
+		}
+	}
+	random := rand.New(rand.NewSource(seed))
+	random.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+
+	var tracks []Track
+	remaining := budget
+	for _, track := range candidates {
+		if int64(track.Size) <= remaining {
+			tracks = append(tracks, track)
+			remaining -= int64(track.Size)
+		}
+	}
+
+	created := addAutoPlaylists(library, "RANDOMFILL", "", []autoPlaylist{{name: RandomFillName, tracks: tracks}})
+	library.BuildPlaylistMaps()
+	return created[0]
+}
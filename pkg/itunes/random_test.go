@@ -0,0 +1,38 @@
+package itunes
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestRandomFill(t *testing.T) {
+	library := &Library{Tracks: make(map[string]Track)}
+	var items []PlaylistItem
+	for id := 1; id <= 20; id++ {
+		library.Tracks[strconv.Itoa(id)] = Track{TrackId: id, Size: 3 << 20, Explicit: id == 1}
+		items = append(items, PlaylistItem{TrackId: id})
+	}
+	library.Playlists = []Playlist{{Name: "Favorites", PlaylistId: 1, PlaylistPersistentId: "FAV", PlaylistItems: items}}
+	library.BuildPlaylistMaps()
+
+	clean := func(track Track) bool { return !track.Explicit }
+	playlist := RandomFill(library, library.Playlists[:1], 10<<20, 42, clean)
+	if playlist.Name != RandomFillName || len(playlist.PlaylistItems) != 3 {
+		t.Fatalf("expected 3 tracks of 3MB to fill 10MB, got %v", playlist.PlaylistItems)
+	}
+	for _, item := range playlist.PlaylistItems {
+		if item.TrackId == 1 {
+			t.Fatal("expected the excluded track to be left out")
+		}
+	}
+	if _, ok := library.PlaylistMap[RandomFillName]; !ok {
+		t.Fatal("expected the playlist to be added to the library")
+	}
+
+	again := RandomFill(library, library.Playlists[:1], 10<<20, 42, clean)
+	for i, item := range again.PlaylistItems {
+		if item != playlist.PlaylistItems[i] {
+			t.Fatalf("expected the same tracks for the same seed, got %v and %v", playlist.PlaylistItems, again.PlaylistItems)
+		}
+	}
+}
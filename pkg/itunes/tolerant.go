@@ -0,0 +1,122 @@
+package itunes
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"unicode/utf8"
+
+	plist "howett.net/plist"
+)
+
+// Repair is a problem in a library file repaired by LoadLibraryTolerant.
+type Repair struct {
+	Line    int
+	Column  int
+	Problem string
+}
+
+func (repair Repair) String() string {
+	return fmt.Sprintf("line %v, column %v: %v", repair.Line, repair.Column, repair.Problem)
+}
+
+// LoadLibraryTolerant loads the library like LoadLibraryContext, but repairs problems which
+// make the XML invalid instead of failing: control characters and invalid UTF-8 are removed
+// and "&" characters which do not start a valid entity are escaped. It returns the repairs
+// made, in the order they occurred.
+func LoadLibraryTolerant(ctx context.Context, fileLocation string) (*Library, []Repair, error) {
+	content, err := ioutil.ReadFile(fileLocation)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	content, repairs := sanitizeXML(content)
+
+	var library Library
+	if err := plist.NewDecoder(contextReader{ctx, bytes.NewReader(content)}).Decode(&library); err != nil {
+		return nil, repairs, err
+	}
+	library.BuildPlaylistMaps()
+
+	return &library, repairs, nil
+}
+
+var validEntity = regexp.MustCompile(`^&(?:amp|lt|gt|quot|apos|#[0-9]+|#x[0-9a-fA-F]+);`)
+
+// sanitizeXML removes characters which are not allowed in XML documents and escapes "&"
+// characters which do not start a valid entity.
+func sanitizeXML(content []byte) ([]byte, []Repair) {
+	var repairs []Repair
+	sanitized := make([]byte, 0, len(content))
+	line, lineStart := 1, 0
+	column := func(i int) int {
+		return utf8.RuneCount(content[lineStart:i]) + 1
+	}
+
+	for i := 0; i < len(content); {
+		c := content[i]
+
+		switch {
+		case c == '\n':
+			sanitized = append(sanitized, c)
+			i++
+			line, lineStart = line+1, i
+		case c < 0x20 && c != '\t' && c != '\r':
+			repairs = append(repairs, Repair{line, column(i), fmt.Sprintf("removed control character %#02x", c)})
+			i++
+		case c == '&':
+			entity := validEntity.Find(content[i:])
+			if entity == nil {
+				repairs = append(repairs, Repair{line, column(i), "escaped invalid entity"})
+				sanitized = append(sanitized, "&amp;"...)
+				i++
+				continue
+			}
+			if code, ok := characterReference(entity); ok && !isXMLChar(code) {
+				repairs = append(repairs, Repair{line, column(i), "removed invalid character reference " + string(entity)})
+			} else {
+				sanitized = append(sanitized, entity...)
+			}
+			i += len(entity)
+		case c >= utf8.RuneSelf:
+			r, size := utf8.DecodeRune(content[i:])
+			if r == utf8.RuneError && size == 1 || !isXMLChar(r) {
+				repairs = append(repairs, Repair{line, column(i), "removed invalid character"})
+			} else {
+				sanitized = append(sanitized, content[i:i+size]...)
+			}
+			i += size
+		default:
+			sanitized = append(sanitized, c)
+			i++
+		}
+	}
+	return sanitized, repairs
+}
+
+// characterReference returns the character referenced by a numeric entity like &#10;.
+func characterReference(entity []byte) (rune, bool) {
+	if len(entity) < 4 || entity[1] != '#' {
+		return 0, false
+	}
+	digits, base := string(entity[2:len(entity)-1]), 10
+	if digits[0] == 'x' {
+		digits, base = digits[1:], 16
+	}
+	code, err := strconv.ParseInt(digits, base, 32)
+	if err != nil {
+		return -1, true
+	}
+	return rune(code), true
+}
+
+// isXMLChar reports whether r is allowed in XML documents.
+func isXMLChar(r rune) bool {
+	return r == '\t' || r == '\n' || r == '\r' ||
+		r >= 0x20 && r <= 0xD7FF ||
+		r >= 0xE000 && r <= 0xFFFD ||
+		r >= 0x10000 && r <= 0x10FFFF
+}
@@ -0,0 +1,52 @@
+package itunes
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeXML(t *testing.T) {
+	content, repairs := sanitizeXML([]byte("<a>ok &amp; &#233;</a>\n<b>R&B\x01 &#1; \xff</b>"))
+
+	if string(content) != "<a>ok &amp; &#233;</a>\n<b>R&amp;B  </b>" {
+		t.Fatalf("unexpected content %q", content)
+	}
+	if len(repairs) != 4 {
+		t.Fatalf("expected 4 repairs, got %v", repairs)
+	}
+	if repairs[0].Line != 2 || repairs[0].Column != 5 || repairs[0].Problem != "escaped invalid entity" {
+		t.Errorf("unexpected repair %v", repairs[0])
+	}
+	if repairs[1].Column != 7 || !strings.Contains(repairs[1].Problem, "control character") {
+		t.Errorf("unexpected repair %v", repairs[1])
+	}
+}
+
+func TestLoadLibraryTolerant(t *testing.T) {
+	itunesDbFile := prepareItunesDbFile(t, "/music/song.mp3")
+	defer os.Remove(itunesDbFile)
+
+	content, err := ioutil.ReadFile(itunesDbFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, itunesDbFile, strings.Replace(string(content), "My Playlist", "My \x02Playlist & More", 1))
+
+	if _, err := LoadLibrary(itunesDbFile); err == nil {
+		t.Fatal("expected strict parsing to fail")
+	}
+
+	library, repairs, err := LoadLibraryTolerant(context.Background(), itunesDbFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repairs) != 2 {
+		t.Fatalf("expected 2 repairs, got %v", repairs)
+	}
+	if _, ok := library.PlaylistMap["My Playlist & More"]; !ok {
+		t.Fatalf("expected repaired playlist, got %v", library.PlaylistMap)
+	}
+}
@@ -0,0 +1,21 @@
+package itunes
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// videoExtensions are the extensions of files which are videos, whatever their kind.
+var videoExtensions = map[string]bool{".m4v": true, ".mov": true, ".mkv": true, ".avi": true}
+
+// Video reports whether the track is a video: a movie, TV show, music video or home video.
+func (track Track) Video() bool {
+	if track.HasVideo || track.Movie || track.MusicVideo || track.TVShow {
+		return true
+	}
+	if videoExtensions[strings.ToLower(filepath.Ext(track.Location))] {
+		return true
+	}
+	kind := strings.ToLower(track.Kind)
+	return strings.Contains(kind, "video") || strings.Contains(kind, "movie")
+}
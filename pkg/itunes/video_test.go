@@ -0,0 +1,22 @@
+package itunes
+
+import (
+	"testing"
+)
+
+func TestVideo(t *testing.T) {
+	for _, track := range []Track{
+		{HasVideo: true, Location: "file:///Movies/Film.mp4"},
+		{TVShow: true},
+		{Kind: "MPEG-4 video file", Location: "file:///Movies/Clip.mp4"},
+		{Kind: "QuickTime movie file"},
+		{Location: "file:///Home%20Videos/Birthday.M4V"},
+	} {
+		if !track.Video() {
+			t.Fatalf("expected %+v to be a video", track)
+		}
+	}
+	if (Track{Kind: "AAC audio file", Location: "file:///Music/Song.m4a"}).Video() {
+		t.Fatal("expected a music file not to be a video")
+	}
+}
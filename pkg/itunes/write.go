@@ -0,0 +1,74 @@
+package itunes
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	plist "howett.net/plist"
+)
+
+// WriteLibrary writes library to w as iTunes Music Library XML file, which can be read by
+// LoadLibrary and imported into iTunes or Music. Fields with zero values are left out.
+func WriteLibrary(w io.Writer, library *Library) error {
+	encoder := plist.NewEncoderForFormat(w, plist.XMLFormat)
+	encoder.Indent("\t")
+	if err := encoder.Encode(plistValue(reflect.ValueOf(*library))); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// plistValue converts v to the values the plist encoder writes, omitting zero values of
+// struct fields. Structs are converted to dictionaries keyed like LoadLibrary expects.
+func plistValue(v reflect.Value) interface{} {
+	switch {
+	case v.Type() == timeType:
+		return v.Interface()
+	case v.Kind() == reflect.Struct:
+		dict := make(map[string]interface{})
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			key := strings.Split(field.Tag.Get("plist"), ",")[0]
+			if field.PkgPath != "" || key == "-" || isZero(v.Field(i)) {
+				continue
+			}
+			if key == "" {
+				key = field.Name
+			}
+			dict[key] = plistValue(v.Field(i))
+		}
+		return dict
+	case v.Kind() == reflect.Map:
+		dict := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			dict[iter.Key().String()] = plistValue(iter.Value())
+		}
+		return dict
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Struct:
+		array := make([]interface{}, v.Len())
+		for i := range array {
+			array[i] = plistValue(v.Index(i))
+		}
+		return array
+	default:
+		return v.Interface()
+	}
+}
+
+func isZero(v reflect.Value) bool {
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).IsZero()
+	}
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice:
+		return v.Len() == 0
+	default:
+		return v.IsZero()
+	}
+}
@@ -0,0 +1,250 @@
+// Package mdns discovers the services announced on the local network with multicast DNS,
+// also known as Bonjour or Zeroconf.
+package mdns
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// mdnsAddress is the multicast address of mDNS queries.
+var mdnsAddress = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// DNS record types used by mDNS service discovery.
+const (
+	typeA   = 1
+	typePTR = 12
+	typeTXT = 16
+	typeSRV = 33
+)
+
+// Instance is an instance of a service announced on the local network.
+type Instance struct {
+	// Name is the name of the instance, e.g. "Music Server".
+	Name string
+	// Address is the host:port of the instance.
+	Address string
+	// Text are the key value pairs of the TXT record of the instance.
+	Text map[string]string
+}
+
+// Browse queries the local network for the instances of service, e.g. "_daap._tcp.local.",
+// and returns the instances which answered within timeout, sorted by name.
+func Browse(ctx context.Context, service string, timeout time.Duration) ([]Instance, error) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	conn.SetDeadline(deadline)
+	go func() {
+		<-ctx.Done()
+		conn.SetDeadline(time.Now())
+	}()
+
+	// Queries from a port other than 5353 are answered directly to the port (RFC 6762 6.7).
+	if _, err := conn.WriteToUDP(mdnsQuery(service, typePTR), mdnsAddress); err != nil {
+		return nil, err
+	}
+
+	records := &mdnsRecords{service: strings.ToLower(service)}
+	buffer := make([]byte, 9000)
+	for {
+		n, _, err := conn.ReadFromUDP(buffer)
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				break
+			}
+			return nil, err
+		}
+		// Ignore invalid packets of other responders.
+		records.parse(buffer[:n])
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return records.instanceList(), nil
+}
+
+// mdnsQuery returns a DNS query for the records of the type of name.
+func mdnsQuery(name string, recordType uint16) []byte {
+	message := make([]byte, 12)
+	binary.BigEndian.PutUint16(message[4:], 1)
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		message = append(message, byte(len(label)))
+		message = append(message, label...)
+	}
+	message = append(message, 0)
+	question := make([]byte, 4)
+	binary.BigEndian.PutUint16(question, recordType)
+	binary.BigEndian.PutUint16(question[2:], 1)
+	return append(message, question...)
+}
+
+// mdnsRecords collects the records of the mDNS responses needed to find the instances of
+// service.
+type mdnsRecords struct {
+	service   string
+	instances []string
+	services  map[string]srvRecord
+	texts     map[string]map[string]string
+	addresses map[string]net.IP
+}
+
+// srvRecord is the host and port of a service instance.
+type srvRecord struct {
+	target string
+	port   uint16
+}
+
+// parse adds the records of the DNS message to records.
+func (records *mdnsRecords) parse(message []byte) error {
+	if records.services == nil {
+		records.services = make(map[string]srvRecord)
+		records.texts = make(map[string]map[string]string)
+		records.addresses = make(map[string]net.IP)
+	}
+	if len(message) < 12 {
+		return errors.New("truncated DNS message")
+	}
+	questions := int(binary.BigEndian.Uint16(message[4:]))
+	count := int(binary.BigEndian.Uint16(message[6:])) + int(binary.BigEndian.Uint16(message[8:])) + int(binary.BigEndian.Uint16(message[10:]))
+
+	offset := 12
+	for i := 0; i < questions; i++ {
+		_, next, err := readName(message, offset)
+		if err != nil {
+			return err
+		}
+		offset = next + 4
+	}
+	for i := 0; i < count; i++ {
+		name, next, err := readName(message, offset)
+		if err != nil {
+			return err
+		}
+		if next+10 > len(message) {
+			return errors.New("truncated DNS record")
+		}
+		recordType := binary.BigEndian.Uint16(message[next:])
+		length := int(binary.BigEndian.Uint16(message[next+8:]))
+		start := next + 10
+		if start+length > len(message) {
+			return errors.New("truncated DNS record")
+		}
+		data := message[start : start+length]
+		name = strings.ToLower(name)
+
+		switch recordType {
+		case typePTR:
+			if name == records.service {
+				instance, _, err := readName(message, start)
+				if err != nil {
+					return err
+				}
+				records.instances = append(records.instances, instance)
+			}
+		case typeSRV:
+			if length > 6 {
+				target, _, err := readName(message, start+6)
+				if err != nil {
+					return err
+				}
+				records.services[name] = srvRecord{target: strings.ToLower(target), port: binary.BigEndian.Uint16(data[4:])}
+			}
+		case typeTXT:
+			text := make(map[string]string)
+			for len(data) > 0 && int(data[0]) < len(data) {
+				entry := string(data[1 : 1+data[0]])
+				if i := strings.Index(entry, "="); i > 0 {
+					text[entry[:i]] = entry[i+1:]
+				}
+				data = data[1+data[0]:]
+			}
+			records.texts[name] = text
+		case typeA:
+			if length == 4 {
+				records.addresses[name] = net.IP(append([]byte(nil), data...))
+			}
+		}
+		offset = start + length
+	}
+	return nil
+}
+
+// instanceList returns the instances of which both the instance and its address were
+// received.
+func (records *mdnsRecords) instanceList() []Instance {
+	seen := make(map[string]bool)
+	var instances []Instance
+	for _, instance := range records.instances {
+		key := strings.ToLower(instance)
+		service, ok := records.services[key]
+		if !ok || seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		host := strings.TrimSuffix(service.target, ".")
+		if ip, ok := records.addresses[service.target]; ok {
+			host = ip.String()
+		}
+		name := instance
+		if suffix := len(instance) - len(records.service) - 1; suffix > 0 && strings.EqualFold(instance[suffix:], "."+records.service) {
+			name = instance[:suffix]
+		}
+		instances = append(instances, Instance{
+			Name:    name,
+			Address: net.JoinHostPort(host, strconv.Itoa(int(service.port))),
+			Text:    records.texts[key],
+		})
+	}
+	sort.Slice(instances, func(i, j int) bool { return instances[i].Name < instances[j].Name })
+	return instances
+}
+
+// readName reads the possibly compressed domain name at offset of the message and returns
+// it with a trailing dot and the offset following it.
+func readName(message []byte, offset int) (string, int, error) {
+	var labels []string
+	next := -1
+	for jumps := 0; ; {
+		if offset >= len(message) {
+			return "", 0, errors.New("truncated DNS name")
+		}
+		length := int(message[offset])
+		switch {
+		case length == 0:
+			if next < 0 {
+				next = offset + 1
+			}
+			return strings.Join(labels, ".") + ".", next, nil
+		case length&0xC0 == 0xC0:
+			if offset+1 >= len(message) || jumps > 10 {
+				return "", 0, errors.New("invalid DNS name pointer")
+			}
+			if next < 0 {
+				next = offset + 2
+			}
+			offset = int(binary.BigEndian.Uint16(message[offset:]) & 0x3FFF)
+			jumps++
+		default:
+			if offset+1+length > len(message) {
+				return "", 0, errors.New("truncated DNS name")
+			}
+			labels = append(labels, string(message[offset+1:offset+1+length]))
+			offset += 1 + length
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package mdns
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// dnsRecord returns a resource record of the DNS message for the name, which is a single
+// pointer or a label followed by a pointer.
+func dnsRecord(name []byte, recordType uint16, data []byte) []byte {
+	record := append([]byte(nil), name...)
+	fixed := make([]byte, 10)
+	binary.BigEndian.PutUint16(fixed, recordType)
+	binary.BigEndian.PutUint16(fixed[2:], 0x8001)
+	binary.BigEndian.PutUint32(fixed[4:], 120)
+	binary.BigEndian.PutUint16(fixed[8:], uint16(len(data)))
+	return append(append(record, fixed...), data...)
+}
+
+func TestParseMDNSResponse(t *testing.T) {
+	// The response repeats the question, as some responders do, and compresses the names.
+	message := mdnsQuery("_daap._tcp.local.", typePTR)
+	binary.BigEndian.PutUint16(message[2:], 0x8400)
+	binary.BigEndian.PutUint16(message[6:], 1)
+	binary.BigEndian.PutUint16(message[10:], 3)
+	service := []byte{0xC0, 12}
+
+	// PTR _daap._tcp.local. -> Music Server._daap._tcp.local.
+	instanceOffset := len(message) + len(service) + 10
+	message = append(message, dnsRecord(service, typePTR, append([]byte("\x0cMusic Server"), service...))...)
+	instance := []byte{0xC0 | byte(instanceOffset>>8), byte(instanceOffset)}
+
+	// SRV -> nas.local.:3689
+	srv := []byte{0, 0, 0, 0, 0x0E, 0x69}
+	srv = append(srv, "\x03nas\x05local\x00"...)
+	hostOffset := len(message) + len(instance) + 10 + 6
+	message = append(message, dnsRecord(instance, typeSRV, srv)...)
+	host := []byte{0xC0 | byte(hostOffset>>8), byte(hostOffset)}
+
+	message = append(message, dnsRecord(instance, typeTXT, []byte("\x09txtvers=1\x13Machine Name=My NAS"))...)
+	message = append(message, dnsRecord(host, typeA, []byte{192, 168, 1, 5})...)
+
+	records := &mdnsRecords{service: "_daap._tcp.local."}
+	if err := records.parse(message); err != nil {
+		t.Fatal(err)
+	}
+	expected := []Instance{{Name: "Music Server", Address: "192.168.1.5:3689", Text: map[string]string{"txtvers": "1", "Machine Name": "My NAS"}}}
+	if instances := records.instanceList(); !reflect.DeepEqual(instances, expected) {
+		t.Fatalf("expected instances %+v, got %+v", expected, instances)
+	}
+
+	if err := (&mdnsRecords{service: "_daap._tcp.local."}).parse(message[:len(message)-3]); err == nil {
+		t.Fatal("expected truncated message to fail")
+	}
+}
@@ -0,0 +1,233 @@
+package remote
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+// appleMusicBatchSize is the maximum number of tracks added to a playlist at once.
+const appleMusicBatchSize = 100
+
+// AppleMusicDeveloperToken returns a developer token for the Apple Music API signed with the
+// MusicKit private key (the contents of the AuthKey_<keyId>.p8 file) of the team, valid for
+// a day from now.
+func AppleMusicDeveloperToken(privateKey []byte, keyId, teamId string, now time.Time) (string, error) {
+	block, _ := pem.Decode(privateKey)
+	if block == nil {
+		return "", errors.New("MusicKit private key is not PEM encoded")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return "", err
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return "", errors.New("MusicKit private key is not an ECDSA key")
+	}
+
+	header, _ := json.Marshal(map[string]string{"alg": "ES256", "kid": keyId})
+	claims, _ := json.Marshal(map[string]interface{}{"iss": teamId, "iat": now.Unix(), "exp": now.Add(24 * time.Hour).Unix()})
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(signed))
+	r, s, err := ecdsa.Sign(rand.Reader, key, hash[:])
+	if err != nil {
+		return "", err
+	}
+	// ES256 signatures are the 32 byte big endian values of r and s.
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// AppleMusic creates playlists in the iCloud Music Library of an Apple Music subscriber.
+// Tracks are matched by searching the catalog of the user's storefront for their artist and
+// title.
+type AppleMusic struct {
+	oauthAPI
+
+	storefront string
+	playlists  map[string]string
+}
+
+// NewAppleMusic returns the Apple Music account of the music user token, accessed with the
+// developer token.
+func NewAppleMusic(developerToken, userToken string) *AppleMusic {
+	return &AppleMusic{oauthAPI: oauthAPI{
+		name:   "Apple Music",
+		api:    "https://api.music.apple.com",
+		token:  developerToken,
+		client: &http.Client{},
+		header: http.Header{"Music-User-Token": {userToken}},
+	}}
+}
+
+// appleMusicResource is a resource in a response of the Apple Music API.
+type appleMusicResource struct {
+	Id         string `json:"id"`
+	Attributes struct {
+		Name             string `json:"name"`
+		ArtistName       string `json:"artistName"`
+		AlbumName        string `json:"albumName"`
+		DurationInMillis int    `json:"durationInMillis"`
+		PlayParams       struct {
+			CatalogId string `json:"catalogId"`
+		} `json:"playParams"`
+	} `json:"attributes"`
+}
+
+// FindTrack searches the catalog for the artist and title of the track and returns the
+// catalog id of the best match.
+func (music *AppleMusic) FindTrack(ctx context.Context, track *itunes.Track) (string, error) {
+	if track.Name == "" {
+		return "", nil
+	}
+	if music.storefront == "" {
+		var storefront struct {
+			Data []appleMusicResource `json:"data"`
+		}
+		if err := music.request(ctx, http.MethodGet, "/v1/me/storefront", nil, &storefront); err != nil {
+			return "", err
+		}
+		if len(storefront.Data) == 0 {
+			return "", errors.New("Apple Music did not return the storefront of the user")
+		}
+		music.storefront = storefront.Data[0].Id
+	}
+
+	artist := track.Artist
+	if artist == "" {
+		artist = track.AlbumArtist
+	}
+	var search struct {
+		Results struct {
+			Songs struct {
+				Data []appleMusicResource `json:"data"`
+			} `json:"songs"`
+		} `json:"results"`
+	}
+	query := url.Values{"term": {strings.TrimSpace(artist + " " + baseTitle(track.Name))}, "types": {"songs"}, "limit": {"10"}}
+	if err := music.request(ctx, http.MethodGet, "/v1/catalog/"+music.storefront+"/search?"+query.Encode(), nil, &search); err != nil {
+		return "", err
+	}
+
+	var candidates []Candidate
+	for _, song := range search.Results.Songs.Data {
+		candidates = append(candidates, Candidate{
+			Id:       song.Id,
+			Artists:  append([]string{song.Attributes.ArtistName}, featuredArtists.Split(song.Attributes.ArtistName, -1)...),
+			Album:    song.Attributes.AlbumName,
+			Title:    song.Attributes.Name,
+			Duration: time.Duration(song.Attributes.DurationInMillis) * time.Millisecond,
+		})
+	}
+	return BestMatch(track, candidates), nil
+}
+
+// loadPlaylists reads the playlists of the library once.
+func (music *AppleMusic) loadPlaylists(ctx context.Context) error {
+	if music.playlists != nil {
+		return nil
+	}
+	playlists := make(map[string]string)
+	for next := "/v1/me/library/playlists?limit=100"; next != ""; {
+		var page struct {
+			Data []appleMusicResource `json:"data"`
+			Next string               `json:"next"`
+		}
+		if err := music.request(ctx, http.MethodGet, next, nil, &page); err != nil {
+			return err
+		}
+		for _, playlist := range page.Data {
+			playlists[playlist.Attributes.Name] = playlist.Id
+		}
+		next = page.Next
+	}
+	music.playlists = playlists
+	return nil
+}
+
+// SetPlaylist creates the playlist in the library. As the API can not remove tracks from
+// playlists, the tracks missing from an existing playlist with the name are added to it
+// instead.
+func (music *AppleMusic) SetPlaylist(ctx context.Context, name string, trackIds []string) error {
+	if err := music.loadPlaylists(ctx); err != nil {
+		return err
+	}
+
+	id, ok := music.playlists[name]
+	if !ok {
+		var created struct {
+			Data []appleMusicResource `json:"data"`
+		}
+		body := map[string]interface{}{"attributes": map[string]string{"name": name, "description": "Exported from iTunes"}}
+		if err := music.request(ctx, http.MethodPost, "/v1/me/library/playlists", body, &created); err != nil {
+			return err
+		}
+		if len(created.Data) == 0 {
+			return errors.New("Apple Music did not return the created playlist")
+		}
+		id = created.Data[0].Id
+		music.playlists[name] = id
+	} else {
+		existing, err := music.playlistTracks(ctx, id)
+		if err != nil {
+			return err
+		}
+		var missing []string
+		for _, trackId := range trackIds {
+			if !existing[trackId] {
+				missing = append(missing, trackId)
+			}
+		}
+		trackIds = missing
+	}
+
+	for _, batch := range batches(trackIds, appleMusicBatchSize) {
+		var data []map[string]string
+		for _, trackId := range batch {
+			data = append(data, map[string]string{"id": trackId, "type": "songs"})
+		}
+		if err := music.request(ctx, http.MethodPost, "/v1/me/library/playlists/"+id+"/tracks", map[string]interface{}{"data": data}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// playlistTracks returns the catalog ids of the tracks of the library playlist.
+func (music *AppleMusic) playlistTracks(ctx context.Context, playlistId string) (map[string]bool, error) {
+	tracks := make(map[string]bool)
+	for next := "/v1/me/library/playlists/" + playlistId + "/tracks?limit=100"; next != ""; {
+		var page struct {
+			Data []appleMusicResource `json:"data"`
+			Next string               `json:"next"`
+		}
+		err := music.request(ctx, http.MethodGet, next, nil, &page)
+		if err, ok := err.(*apiError); ok && err.status == http.StatusNotFound {
+			// Playlists without tracks have no tracks relationship.
+			return tracks, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, track := range page.Data {
+			tracks[track.Attributes.PlayParams.CatalogId] = true
+		}
+		next = page.Next
+	}
+	return tracks, nil
+}
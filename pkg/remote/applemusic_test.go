@@ -0,0 +1,163 @@
+package remote
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+func TestAppleMusicDeveloperToken(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+	token, err := AppleMusicDeveloperToken(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), "KEY123", "TEAM456", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("expected a JWT, got %v", token)
+	}
+	var header, claims map[string]interface{}
+	for i, value := range []*map[string]interface{}{&header, &claims} {
+		decoded, _ := base64.RawURLEncoding.DecodeString(parts[i])
+		if err := json.Unmarshal(decoded, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if header["alg"] != "ES256" || header["kid"] != "KEY123" {
+		t.Fatalf("unexpected header %v", header)
+	}
+	if claims["iss"] != "TEAM456" || claims["iat"] != float64(1700000000) || claims["exp"] != float64(1700086400) {
+		t.Fatalf("unexpected claims %v", claims)
+	}
+
+	signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+	if len(signature) != 64 || !ecdsa.Verify(&key.PublicKey, hash[:], r, s) {
+		t.Fatal("invalid signature")
+	}
+}
+
+// fakeAppleMusic serves the part of the Apple Music API used by AppleMusic.
+type fakeAppleMusic struct {
+	playlists map[string][]string
+	created   []string
+}
+
+func (server *fakeAppleMusic) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer developer" || r.Header.Get("Music-User-Token") != "user" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/v1/me/storefront":
+		io.WriteString(w, `{"data":[{"id":"de"}]}`)
+	case r.URL.Path == "/v1/catalog/de/search":
+		songs := map[string]string{
+			"Artist Song":    `{"id":"1","attributes":{"name":"Song (Live)","artistName":"Artist","albumName":"Live","durationInMillis":240000}},{"id":"2","attributes":{"name":"Song","artistName":"Artist","albumName":"Album","durationInMillis":180500}}`,
+			"Duo Other Song": `{"id":"3","attributes":{"name":"Other Song","artistName":"Duo & Guest","albumName":"Hits","durationInMillis":120000}}`,
+		}[r.URL.Query().Get("term")]
+		fmt.Fprintf(w, `{"results":{"songs":{"data":[%v]}}}`, songs)
+	case r.URL.Path == "/v1/me/library/playlists" && r.Method == http.MethodGet && r.URL.Query().Get("offset") == "":
+		io.WriteString(w, `{"data":[{"id":"p.1","attributes":{"name":"Other"}}],"next":"/v1/me/library/playlists?offset=100"}`)
+	case r.URL.Path == "/v1/me/library/playlists" && r.Method == http.MethodGet:
+		io.WriteString(w, `{"data":[{"id":"p.2","attributes":{"name":"Existing"}}]}`)
+	case r.URL.Path == "/v1/me/library/playlists" && r.Method == http.MethodPost:
+		var body struct {
+			Attributes struct {
+				Name string `json:"name"`
+			} `json:"attributes"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		server.created = append(server.created, body.Attributes.Name)
+		fmt.Fprintf(w, `{"data":[{"id":"p.%v"}]}`, body.Attributes.Name)
+	case strings.HasSuffix(r.URL.Path, "/tracks"):
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1/me/library/playlists/"), "/tracks")
+		tracks, ok := server.playlists[id]
+		if r.Method == http.MethodGet {
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			var data []string
+			for _, track := range tracks {
+				data = append(data, fmt.Sprintf(`{"id":"i.%v","attributes":{"playParams":{"catalogId":"%v"}}}`, track, track))
+			}
+			fmt.Fprintf(w, `{"data":[%v]}`, strings.Join(data, ","))
+			return
+		}
+		var body struct {
+			Data []struct {
+				Id string `json:"id"`
+			} `json:"data"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		for _, track := range body.Data {
+			server.playlists[id] = append(server.playlists[id], track.Id)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestPushPlaylistsToAppleMusic(t *testing.T) {
+	server := &fakeAppleMusic{playlists: map[string][]string{"p.2": {"3"}}}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	library := &itunes.Library{Tracks: map[string]itunes.Track{
+		"1": {TrackId: 1, Name: "Song", Artist: "Artist", Album: "Album", TotalTime: 180000},
+		"2": {TrackId: 2, Name: "Other Song", Artist: "Duo", Album: "Hits"},
+		"3": {TrackId: 3, Name: "Missing", Artist: "Nobody"},
+	}}
+	playlists := []itunes.Playlist{
+		{Name: "Existing", PlaylistItems: []itunes.PlaylistItem{{TrackId: 2}, {TrackId: 1}, {TrackId: 3}}},
+		{Name: "New", PlaylistItems: []itunes.PlaylistItem{{TrackId: 1}}},
+	}
+
+	music := NewAppleMusic("developer", "user")
+	music.api = httpServer.URL
+	results, err := PushPlaylists(context.Background(), music, library, playlists)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 2 || results[0].Matched != 2 || len(results[0].Unmatched) != 1 || results[1].Matched != 1 {
+		t.Fatalf("unexpected results %+v", results)
+	}
+	if !reflect.DeepEqual(server.created, []string{"New"}) {
+		t.Fatalf("expected only New to be created, got %v", server.created)
+	}
+	expected := map[string][]string{"p.2": {"3", "2"}, "p.New": {"2"}}
+	if !reflect.DeepEqual(server.playlists, expected) {
+		t.Fatalf("expected playlists %v, got %v", expected, server.playlists)
+	}
+}
@@ -0,0 +1,228 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+const (
+	// jellyfinPageSize is the number of items requested at once.
+	jellyfinPageSize = 1000
+	// jellyfinBatchSize limits the number of items added or removed with a single request.
+	jellyfinBatchSize = 100
+)
+
+// Jellyfin creates playlists on a Jellyfin or Emby server, which share the same API.
+type Jellyfin struct {
+	server   *url.URL
+	token    string
+	userName string
+	client   *http.Client
+
+	userId    string
+	index     *TrackIndex
+	playlists map[string]string
+}
+
+// NewJellyfin returns the Jellyfin or Emby server at serverURL, e.g. http://server:8096,
+// accessed with the API key. Playlists are created for the user userName, which may be
+// empty if the server has a single user.
+func NewJellyfin(serverURL, token, userName string) (*Jellyfin, error) {
+	server, err := url.Parse(strings.TrimSuffix(serverURL, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if server.Scheme == "" || server.Host == "" {
+		return nil, errors.New("invalid Jellyfin server URL " + serverURL)
+	}
+	if token == "" {
+		return nil, errors.New("missing Jellyfin API key")
+	}
+	return &Jellyfin{server: server, token: token, userName: userName, client: &http.Client{}}, nil
+}
+
+// jellyfinItems is a page of items returned by the API.
+type jellyfinItems struct {
+	Items []struct {
+		Id             string
+		PlaylistItemId string
+		Name           string
+		Album          string
+		AlbumArtist    string
+		Artists        []string
+		Path           string
+	}
+	TotalRecordCount int
+}
+
+// request sends a request to the API and decodes the response into result, unless it is nil.
+func (jellyfin *Jellyfin) request(ctx context.Context, method, path string, query url.Values, result interface{}) error {
+	requestURL := *jellyfin.server
+	requestURL.Path += path
+	requestURL.RawQuery = query.Encode()
+
+	request, err := http.NewRequest(method, requestURL.String(), nil)
+	if err != nil {
+		return err
+	}
+	request = request.WithContext(ctx)
+	request.Header.Set("Accept", "application/json")
+	request.Header.Set("X-Emby-Token", jellyfin.token)
+
+	response, err := jellyfin.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("server responded %v to %v %v", response.Status, method, path)
+	}
+	if result == nil {
+		_, err = io.Copy(ioutil.Discard, response.Body)
+		return err
+	}
+	return json.NewDecoder(response.Body).Decode(result)
+}
+
+// items returns all items of the user matching the query, reading them page by page.
+func (jellyfin *Jellyfin) items(ctx context.Context, path string, query url.Values) (*jellyfinItems, error) {
+	all := &jellyfinItems{}
+	for {
+		query.Set("StartIndex", strconv.Itoa(len(all.Items)))
+		query.Set("Limit", strconv.Itoa(jellyfinPageSize))
+		var page jellyfinItems
+		if err := jellyfin.request(ctx, http.MethodGet, path, query, &page); err != nil {
+			return nil, err
+		}
+		all.Items = append(all.Items, page.Items...)
+		if len(page.Items) == 0 || len(all.Items) >= page.TotalRecordCount {
+			return all, nil
+		}
+	}
+}
+
+// load looks up the user and reads the audio tracks and playlists of the user once.
+func (jellyfin *Jellyfin) load(ctx context.Context) error {
+	if jellyfin.index != nil {
+		return nil
+	}
+
+	var users []struct {
+		Id   string
+		Name string
+	}
+	if err := jellyfin.request(ctx, http.MethodGet, "/Users", nil, &users); err != nil {
+		return err
+	}
+	for _, user := range users {
+		if strings.EqualFold(user.Name, jellyfin.userName) || jellyfin.userName == "" && len(users) == 1 {
+			jellyfin.userId = user.Id
+		}
+	}
+	if jellyfin.userId == "" {
+		return errors.New("unknown user " + jellyfin.userName + ", choose the user the playlists are created for")
+	}
+
+	itemsPath := "/Users/" + jellyfin.userId + "/Items"
+	audio, err := jellyfin.items(ctx, itemsPath, url.Values{"Recursive": {"true"}, "IncludeItemTypes": {"Audio"}, "Fields": {"Path"}})
+	if err != nil {
+		return err
+	}
+	var tracks []Track
+	for _, item := range audio.Items {
+		artists := append([]string{item.AlbumArtist}, item.Artists...)
+		for i, artist := range artists {
+			track := Track{Id: item.Id, Artist: artist, Album: item.Album, Title: item.Name}
+			if i == 0 {
+				track.Path = item.Path
+			}
+			tracks = append(tracks, track)
+		}
+	}
+
+	playlists, err := jellyfin.items(ctx, itemsPath, url.Values{"Recursive": {"true"}, "IncludeItemTypes": {"Playlist"}})
+	if err != nil {
+		return err
+	}
+	jellyfin.playlists = make(map[string]string)
+	for _, playlist := range playlists.Items {
+		jellyfin.playlists[playlist.Name] = playlist.Id
+	}
+
+	jellyfin.index = NewTrackIndex(tracks)
+	return nil
+}
+
+func (jellyfin *Jellyfin) FindTrack(ctx context.Context, track *itunes.Track) (string, error) {
+	if err := jellyfin.load(ctx); err != nil {
+		return "", err
+	}
+	return jellyfin.index.Find(track), nil
+}
+
+// SetPlaylist removes all entries of an existing playlist and adds the tracks, or creates
+// the playlist.
+func (jellyfin *Jellyfin) SetPlaylist(ctx context.Context, name string, trackIds []string) error {
+	if err := jellyfin.load(ctx); err != nil {
+		return err
+	}
+
+	id, exists := jellyfin.playlists[name]
+	if exists {
+		entries, err := jellyfin.items(ctx, "/Playlists/"+id+"/Items", url.Values{"UserId": {jellyfin.userId}})
+		if err != nil {
+			return err
+		}
+		var entryIds []string
+		for _, entry := range entries.Items {
+			entryIds = append(entryIds, entry.PlaylistItemId)
+		}
+		for _, batch := range batches(entryIds, jellyfinBatchSize) {
+			query := url.Values{"EntryIds": {strings.Join(batch, ",")}}
+			if err := jellyfin.request(ctx, http.MethodDelete, "/Playlists/"+id+"/Items", query, nil); err != nil {
+				return err
+			}
+		}
+	} else {
+		var created struct {
+			Id string
+		}
+		query := url.Values{"Name": {name}, "UserId": {jellyfin.userId}, "MediaType": {"Audio"}}
+		if err := jellyfin.request(ctx, http.MethodPost, "/Playlists", query, &created); err != nil {
+			return err
+		}
+		id = created.Id
+		jellyfin.playlists[name] = id
+	}
+
+	for _, batch := range batches(trackIds, jellyfinBatchSize) {
+		query := url.Values{"Ids": {strings.Join(batch, ",")}, "UserId": {jellyfin.userId}}
+		if err := jellyfin.request(ctx, http.MethodPost, "/Playlists/"+id+"/Items", query, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// batches splits ids into batches of at most size ids.
+func batches(ids []string, size int) [][]string {
+	var result [][]string
+	for start := 0; start < len(ids); start += size {
+		end := start + size
+		if end > len(ids) {
+			end = len(ids)
+		}
+		result = append(result, ids[start:end])
+	}
+	return result
+}
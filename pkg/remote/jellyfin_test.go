@@ -0,0 +1,91 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+// fakeJellyfin serves the part of the Jellyfin API used by Jellyfin.
+type fakeJellyfin struct {
+	playlists map[string][]string
+}
+
+func (server *fakeJellyfin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Emby-Token") != "key" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	query := r.URL.Query()
+
+	switch {
+	case r.URL.Path == "/Users":
+		io.WriteString(w, `[{"Id":"u1","Name":"Alice"},{"Id":"u2","Name":"Bob"}]`)
+	case r.URL.Path == "/Users/u2/Items" && query.Get("IncludeItemTypes") == "Audio":
+		// The tracks are returned in two pages.
+		if query.Get("StartIndex") == "0" {
+			io.WriteString(w, `{"Items":[{"Id":"a1","Name":"Song","Album":"Album","AlbumArtist":"Artist","Path":"/media/Artist/Album/song.flac"}],"TotalRecordCount":2}`)
+		} else {
+			io.WriteString(w, `{"Items":[{"Id":"a2","Name":"Other Song","Album":"Hits","AlbumArtist":"Various Artists","Artists":["Singer"]}],"TotalRecordCount":2}`)
+		}
+	case r.URL.Path == "/Users/u2/Items" && query.Get("IncludeItemTypes") == "Playlist":
+		io.WriteString(w, `{"Items":[{"Id":"p1","Name":"Existing"}],"TotalRecordCount":1}`)
+	case r.URL.Path == "/Playlists/p1/Items" && r.Method == http.MethodGet:
+		fmt.Fprintf(w, `{"Items":[{"Id":"a9","PlaylistItemId":"e1"}],"TotalRecordCount":1}`)
+	case r.URL.Path == "/Playlists/p1/Items" && r.Method == http.MethodDelete:
+		if query.Get("EntryIds") == "e1" {
+			server.playlists["Existing"] = nil
+		}
+	case r.URL.Path == "/Playlists" && r.Method == http.MethodPost:
+		server.playlists[query.Get("Name")] = []string{}
+		io.WriteString(w, `{"Id":"p2"}`)
+	case strings.HasPrefix(r.URL.Path, "/Playlists/") && r.Method == http.MethodPost:
+		name := map[string]string{"/Playlists/p1/Items": "Existing", "/Playlists/p2/Items": "New"}[r.URL.Path]
+		server.playlists[name] = append(server.playlists[name], strings.Split(query.Get("Ids"), ",")...)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestPushPlaylistsToJellyfin(t *testing.T) {
+	server := &fakeJellyfin{playlists: map[string][]string{"Existing": {"a9"}}}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	library := &itunes.Library{Tracks: map[string]itunes.Track{
+		"1": {TrackId: 1, Name: "Song", Location: "file:///Users/me/Music/Artist/Album/song.flac"},
+		"2": {TrackId: 2, Name: "Other Song", Artist: "Singer", Album: "Hits"},
+		"3": {TrackId: 3, Name: "Missing", Artist: "Nobody"},
+	}}
+	playlists := []itunes.Playlist{
+		{Name: "Existing", PlaylistItems: []itunes.PlaylistItem{{TrackId: 2}, {TrackId: 1}}},
+		{Name: "New", PlaylistItems: []itunes.PlaylistItem{{TrackId: 3}, {TrackId: 1}}},
+	}
+
+	jellyfin, err := NewJellyfin(httpServer.URL, "key", "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := PushPlaylists(context.Background(), jellyfin, library, playlists)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 2 || results[0].Matched != 2 || results[1].Matched != 1 || len(results[1].Unmatched) != 1 {
+		t.Fatalf("unexpected results %+v", results)
+	}
+	if fmt.Sprint(server.playlists["Existing"]) != "[a2 a1]" || fmt.Sprint(server.playlists["New"]) != "[a1]" {
+		t.Fatalf("unexpected playlists %v", server.playlists)
+	}
+
+	unknownUser, _ := NewJellyfin(httpServer.URL, "key", "")
+	if _, err := unknownUser.FindTrack(context.Background(), &itunes.Track{}); err == nil {
+		t.Fatal("expected error without user on a server with several users")
+	}
+}
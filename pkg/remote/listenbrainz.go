@@ -0,0 +1,114 @@
+package remote
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+// listenBrainzBatchSize is the number of listens submitted at once, well below the limit of
+// 1000 listens per request.
+const listenBrainzBatchSize = 500
+
+// Listen is a single play of a track.
+type Listen struct {
+	ListenedAt time.Time
+	Track      itunes.Track
+}
+
+// Listens returns the plays recorded in the library, sorted by time. iTunes only records the
+// number of plays and the time of the last play of a track, so the last play is at the
+// recorded time and earlier plays are spread evenly since the track was added, at least the
+// length of the track apart. Tracks without artist, title or last play are skipped.
+func Listens(library *itunes.Library) []Listen {
+	var listens []Listen
+	for _, track := range library.Tracks {
+		if track.PlayCount == 0 || track.PlayDateUTC.IsZero() || track.Name == "" || track.Artist == "" {
+			continue
+		}
+		last := track.PlayDateUTC
+		interval := time.Duration(0)
+		if track.PlayCount > 1 {
+			minimum := time.Duration(track.TotalTime) * time.Millisecond
+			if minimum < time.Minute {
+				minimum = time.Minute
+			}
+			if !track.DateAdded.IsZero() && track.DateAdded.Before(last) {
+				interval = last.Sub(track.DateAdded) / time.Duration(track.PlayCount-1)
+			}
+			if interval < minimum {
+				interval = minimum
+			}
+		}
+		for i := 0; i < track.PlayCount; i++ {
+			listens = append(listens, Listen{ListenedAt: last.Add(-time.Duration(i) * interval).Truncate(time.Second), Track: track})
+		}
+	}
+	sort.Slice(listens, func(i, j int) bool {
+		if !listens[i].ListenedAt.Equal(listens[j].ListenedAt) {
+			return listens[i].ListenedAt.Before(listens[j].ListenedAt)
+		}
+		return listens[i].Track.TrackId < listens[j].Track.TrackId
+	})
+	return listens
+}
+
+// ListenBrainz submits listens to a ListenBrainz server.
+type ListenBrainz struct {
+	oauthAPI
+}
+
+// NewListenBrainz returns the ListenBrainz account of the user token at the server, usually
+// https://api.listenbrainz.org.
+func NewListenBrainz(server, token string) *ListenBrainz {
+	return &ListenBrainz{oauthAPI: oauthAPI{
+		name:   "ListenBrainz",
+		api:    server,
+		client: &http.Client{},
+		// ListenBrainz expects its own authorization scheme instead of a bearer token.
+		header: http.Header{"Authorization": {"Token " + token}},
+	}}
+}
+
+// SubmitListens imports the listens in batches and calls progress with the number of listens
+// submitted so far.
+func (listenBrainz *ListenBrainz) SubmitListens(ctx context.Context, listens []Listen, progress func(submitted int)) error {
+	for start := 0; start < len(listens); start += listenBrainzBatchSize {
+		end := start + listenBrainzBatchSize
+		if end > len(listens) {
+			end = len(listens)
+		}
+
+		var payload []map[string]interface{}
+		for _, listen := range listens[start:end] {
+			additional := map[string]interface{}{"submission_client": "itunesexport", "media_player": "iTunes"}
+			if listen.Track.TotalTime > 0 {
+				additional["duration_ms"] = listen.Track.TotalTime
+			}
+			if listen.Track.TrackNumber > 0 {
+				additional["tracknumber"] = listen.Track.TrackNumber
+			}
+			metadata := map[string]interface{}{
+				"artist_name":     listen.Track.Artist,
+				"track_name":      listen.Track.Name,
+				"additional_info": additional,
+			}
+			if listen.Track.Album != "" {
+				metadata["release_name"] = listen.Track.Album
+			}
+			payload = append(payload, map[string]interface{}{"listened_at": listen.ListenedAt.Unix(), "track_metadata": metadata})
+		}
+
+		body := map[string]interface{}{"listen_type": "import", "payload": payload}
+		if err := listenBrainz.request(ctx, http.MethodPost, "/1/submit-listens", body, nil); err != nil {
+			return err
+		}
+		if progress != nil {
+			progress(end)
+		}
+	}
+	return nil
+}
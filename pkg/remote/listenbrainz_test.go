@@ -0,0 +1,87 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+func TestListens(t *testing.T) {
+	added := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	last := time.Date(2020, 1, 11, 0, 0, 0, 0, time.UTC)
+	library := &itunes.Library{Tracks: map[string]itunes.Track{
+		"1": {TrackId: 1, Name: "Often", Artist: "Artist", PlayCount: 3, DateAdded: added, PlayDateUTC: last},
+		"2": {TrackId: 2, Name: "Once", Artist: "Artist", PlayCount: 1, DateAdded: added, PlayDateUTC: added.Add(time.Hour)},
+		"3": {TrackId: 3, Name: "Recent", Artist: "Artist", PlayCount: 2, TotalTime: 300000, DateAdded: last, PlayDateUTC: last.Add(time.Minute)},
+		"4": {TrackId: 4, Name: "Never", Artist: "Artist", DateAdded: added},
+		"5": {TrackId: 5, Name: "No Artist", PlayCount: 1, PlayDateUTC: last},
+	}}
+
+	var actual []string
+	for _, listen := range Listens(library) {
+		actual = append(actual, listen.Track.Name+" "+listen.ListenedAt.Format(time.RFC3339))
+	}
+	expected := []string{
+		"Often 2020-01-01T00:00:00Z",
+		"Once 2020-01-01T01:00:00Z",
+		"Often 2020-01-06T00:00:00Z",
+		"Recent 2020-01-10T23:56:00Z",
+		"Often 2020-01-11T00:00:00Z",
+		"Recent 2020-01-11T00:01:00Z",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected listens %v, got %v", expected, actual)
+	}
+}
+
+func TestSubmitListens(t *testing.T) {
+	var submitted []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/1/submit-listens" || r.Header.Get("Authorization") != "Token secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var body struct {
+			ListenType string                   `json:"listen_type"`
+			Payload    []map[string]interface{} `json:"payload"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.ListenType != "import" || len(body.Payload) > listenBrainzBatchSize {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		submitted = append(submitted, body.Payload...)
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer server.Close()
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	var listens []Listen
+	for i := 0; i < listenBrainzBatchSize+1; i++ {
+		listens = append(listens, Listen{ListenedAt: start.Add(time.Duration(i) * time.Minute), Track: itunes.Track{Name: "Song", Artist: "Artist", Album: "Album", TotalTime: 1000}})
+	}
+
+	var progress []int
+	err := NewListenBrainz(server.URL, "secret").SubmitListens(context.Background(), listens, func(submitted int) {
+		progress = append(progress, submitted)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(progress, []int{listenBrainzBatchSize, listenBrainzBatchSize + 1}) {
+		t.Fatalf("unexpected progress %v", progress)
+	}
+	if len(submitted) != len(listens) {
+		t.Fatalf("expected %v listens, got %v", len(listens), len(submitted))
+	}
+	metadata := submitted[0]["track_metadata"].(map[string]interface{})
+	if submitted[0]["listened_at"] != float64(start.Unix()) || metadata["artist_name"] != "Artist" || metadata["track_name"] != "Song" || metadata["release_name"] != "Album" {
+		t.Fatalf("unexpected listen %v", submitted[0])
+	}
+}
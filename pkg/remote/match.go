@@ -0,0 +1,180 @@
+package remote
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+// Track is a track known to a service.
+type Track struct {
+	Id string
+	// Path is the file of the track on the server, if known.
+	Path   string
+	Artist string
+	Album  string
+	Title  string
+}
+
+// TrackIndex finds the tracks of a service matching the tracks of a library. Tracks are
+// matched by the end of their path, as the server usually stores the music in another
+// directory than the library, and otherwise by artist, album and title.
+type TrackIndex struct {
+	byPath     map[string]string
+	byMetadata map[string]string
+	byTitle    map[string]string
+}
+
+// pathComponents is the number of trailing path components compared, e.g.
+// Artist/Album/01 Song.mp3.
+const pathComponents = 3
+
+// NewTrackIndex indexes the tracks of a service.
+func NewTrackIndex(tracks []Track) *TrackIndex {
+	index := &TrackIndex{byPath: make(map[string]string), byMetadata: make(map[string]string), byTitle: make(map[string]string)}
+	for _, track := range tracks {
+		if track.Path != "" {
+			index.byPath[pathKey(track.Path)] = track.Id
+		}
+		if track.Title == "" {
+			continue
+		}
+		index.byMetadata[metadataKey(track.Artist, track.Album, track.Title)] = track.Id
+		index.byTitle[metadataKey(track.Artist, "", track.Title)] = track.Id
+	}
+	return index
+}
+
+// Find returns the id of the track matching track, or the empty string if there is none.
+func (index *TrackIndex) Find(track *itunes.Track) string {
+	if track.Location != "" {
+		if id, ok := index.byPath[pathKey(itunes.LocationPath(track.Location))]; ok {
+			return id
+		}
+	}
+	for _, artist := range []string{track.Artist, track.AlbumArtist} {
+		if artist == "" {
+			continue
+		}
+		if id, ok := index.byMetadata[metadataKey(artist, track.Album, track.Name)]; ok {
+			return id
+		}
+		if id, ok := index.byTitle[metadataKey(artist, "", track.Name)]; ok {
+			return id
+		}
+	}
+	return ""
+}
+
+// pathKey returns the trailing components of a path of any platform, ignoring case and
+// Unicode normalization.
+func pathKey(path string) string {
+	parts := strings.FieldsFunc(filepath.ToSlash(path), func(r rune) bool { return r == '/' || r == '\\' })
+	if len(parts) > pathComponents {
+		parts = parts[len(parts)-pathComponents:]
+	}
+	return strings.ToLower(itunes.FoldAccents(strings.Join(parts, "/")))
+}
+
+// metadataKey returns the key of the artist, album and title of a track, ignoring case,
+// accents, punctuation and a leading "The".
+func metadataKey(artist, album, title string) string {
+	return Normalize(strings.TrimPrefix(strings.ToLower(artist), "the ")) + "\x00" + Normalize(album) + "\x00" + Normalize(title)
+}
+
+// Normalize returns s in lower case with accents, punctuation and spaces removed, to compare
+// names as written by different services.
+func Normalize(s string) string {
+	var normalized strings.Builder
+	for _, r := range strings.ToLower(itunes.FoldAccents(s)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			normalized.WriteRune(r)
+		}
+	}
+	return normalized.String()
+}
+
+// Candidate is a track of a streaming service found when searching for a track.
+type Candidate struct {
+	Id       string
+	Artists  []string
+	Album    string
+	Title    string
+	Duration time.Duration
+}
+
+// maxDurationDifference is the largest difference in duration of tracks considered the same.
+const maxDurationDifference = 5 * time.Second
+
+// BestMatch returns the id of the candidate best matching track, or the empty string if none
+// matches. A candidate matches if its title, ignoring versions like "(Remastered)", and one
+// of its artists are the same and, if both durations are known, its duration differs by at
+// most a few seconds. Of the matching candidates the one with the same album and the closest
+// duration is chosen.
+func BestMatch(track *itunes.Track, candidates []Candidate) string {
+	duration := time.Duration(track.TotalTime) * time.Millisecond
+	best, bestScore := "", time.Duration(-1)
+	for _, candidate := range candidates {
+		if Normalize(baseTitle(candidate.Title)) != Normalize(baseTitle(track.Name)) || !matchesArtist(track, candidate.Artists) {
+			continue
+		}
+
+		score := time.Duration(0)
+		if duration > 0 && candidate.Duration > 0 {
+			score = duration - candidate.Duration
+			if score < 0 {
+				score = -score
+			}
+			if score > maxDurationDifference {
+				continue
+			}
+		}
+		if Normalize(candidate.Album) != Normalize(track.Album) {
+			score += maxDurationDifference
+		}
+		if bestScore < 0 || score < bestScore {
+			best, bestScore = candidate.Id, score
+		}
+	}
+	return best
+}
+
+// matchesArtist reports whether one of the artists is the artist or album artist of track.
+// Artists listed with "feat." or "&" in the library match the whole name or any of the
+// artists named.
+func matchesArtist(track *itunes.Track, artists []string) bool {
+	names := make(map[string]bool)
+	for _, artist := range []string{track.Artist, track.AlbumArtist} {
+		names[metadataKey(artist, "", "")] = true
+		for _, name := range featuredArtists.Split(artist, -1) {
+			names[metadataKey(name, "", "")] = true
+		}
+	}
+	for _, artist := range artists {
+		if names[metadataKey(artist, "", "")] {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	featuredArtists = regexp.MustCompile(`(?i)\s+(?:feat\.?|ft\.?|featuring|&|and|with|x)\s+|\s*[,;/]\s*`)
+	titleVersion    = regexp.MustCompile(`\s*(?:\([^)]*\)|\[[^]]*\]|\s-\s.*)$`)
+)
+
+// baseTitle returns title without a version like "(Live)", "[Remastered 2009]" or
+// "- Radio Edit" at its end.
+func baseTitle(title string) string {
+	for {
+		base := titleVersion.ReplaceAllString(title, "")
+		if base == title || base == "" {
+			return title
+		}
+		title = base
+	}
+}
@@ -0,0 +1,59 @@
+package remote
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+func TestTrackIndex(t *testing.T) {
+	index := NewTrackIndex([]Track{
+		{Id: "1", Path: "/data/music/Queen/Greatest Hits/01 Bohemian Rhapsody.mp3", Artist: "Queen", Album: "Greatest Hits", Title: "Bohemian Rhapsody"},
+		{Id: "2", Artist: "Beatles", Album: "Abbey Road", Title: "Come Together"},
+		{Id: "3", Artist: "Beyoncé", Album: "Other Album", Title: "Halo!"},
+	})
+
+	for _, test := range []struct {
+		track    itunes.Track
+		expected string
+	}{
+		{itunes.Track{Name: "Renamed", Location: "file:///Users/me/Music/iTunes/iTunes%20Media/Music/Queen/Greatest%20Hits/01%20Bohemian%20Rhapsody.mp3"}, "1"},
+		{itunes.Track{Name: "Come Together", Artist: "The Beatles", Album: "Abbey Road (Remastered)"}, "2"},
+		{itunes.Track{Name: "come together", AlbumArtist: "The Beatles", Album: "Abbey Road"}, "2"},
+		{itunes.Track{Name: "Halo", Artist: "Beyoncé", Album: "I Am... Sasha Fierce"}, "3"},
+		{itunes.Track{Name: "Unknown", Artist: "Queen"}, ""},
+	} {
+		if id := index.Find(&test.track); id != test.expected {
+			t.Errorf("%v: expected %q, got %q", test.track.Name, test.expected, id)
+		}
+	}
+}
+
+func TestBestMatch(t *testing.T) {
+	candidates := []Candidate{
+		{Id: "live", Artists: []string{"Queen"}, Album: "Live Killers", Title: "Bohemian Rhapsody - Live", Duration: 360 * time.Second},
+		{Id: "cover", Artists: []string{"Other Band"}, Album: "A Night at the Opera", Title: "Bohemian Rhapsody", Duration: 355 * time.Second},
+		{Id: "remaster", Artists: []string{"Queen"}, Album: "Greatest Hits", Title: "Bohemian Rhapsody (Remastered 2011)", Duration: 354 * time.Second},
+		{Id: "original", Artists: []string{"Queen"}, Album: "A Night at the Opera", Title: "Bohemian Rhapsody", Duration: 355 * time.Second},
+		{Id: "duet", Artists: []string{"Simon & Garfunkel"}, Title: "The Boxer"},
+		{Id: "feat", Artists: []string{"Eminem", "Rihanna"}, Title: "Love the Way You Lie", Duration: 263 * time.Second},
+	}
+
+	for _, test := range []struct {
+		track    itunes.Track
+		expected string
+	}{
+		{itunes.Track{Name: "Bohemian Rhapsody", Artist: "Queen", Album: "A Night at the Opera", TotalTime: 355000}, "original"},
+		{itunes.Track{Name: "Bohemian Rhapsody", Artist: "Queen", Album: "Greatest Hits", TotalTime: 354000}, "remaster"},
+		{itunes.Track{Name: "Bohemian Rhapsody (Live)", Artist: "Queen", Album: "Live Killers", TotalTime: 361000}, "live"},
+		{itunes.Track{Name: "Bohemian Rhapsody", Artist: "Queen", TotalTime: 300000}, ""},
+		{itunes.Track{Name: "The Boxer", Artist: "Simon & Garfunkel"}, "duet"},
+		{itunes.Track{Name: "Love The Way You Lie", Artist: "Eminem feat. Rihanna", TotalTime: 264000}, "feat"},
+		{itunes.Track{Name: "Another One Bites the Dust", Artist: "Queen"}, ""},
+	} {
+		if id := BestMatch(&test.track, candidates); id != test.expected {
+			t.Errorf("%v - %v: expected %q, got %q", test.track.Artist, test.track.Name, test.expected, id)
+		}
+	}
+}
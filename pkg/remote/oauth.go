@@ -0,0 +1,218 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OAuthConfig describes the OAuth 2.0 authorization of a streaming service.
+type OAuthConfig struct {
+	AuthURL      string
+	TokenURL     string
+	ClientId     string
+	ClientSecret string
+	Scopes       []string
+	// RedirectURL is the local URL, e.g. http://127.0.0.1:8888/callback, registered with the
+	// service to receive the authorization code.
+	RedirectURL string
+}
+
+// Authorize runs the authorization code flow with PKCE: it writes the URL to open in a
+// browser to out, waits for the browser to be redirected to the local RedirectURL and
+// exchanges the authorization code for an access token.
+func (config OAuthConfig) Authorize(ctx context.Context, out io.Writer) (string, error) {
+	redirect, err := url.Parse(config.RedirectURL)
+	if err != nil {
+		return "", err
+	}
+	verifier, state := randomString(), randomString()
+	challenge := sha256.Sum256([]byte(verifier))
+
+	authURL, err := url.Parse(config.AuthURL)
+	if err != nil {
+		return "", err
+	}
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", config.ClientId)
+	query.Set("redirect_uri", config.RedirectURL)
+	query.Set("scope", strings.Join(config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("code_challenge_method", "S256")
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	authURL.RawQuery = query.Encode()
+
+	listener, err := net.Listen("tcp", redirect.Host)
+	if err != nil {
+		return "", err
+	}
+	codes := make(chan string, 1)
+	errs := make(chan error, 1)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != redirect.Path {
+			http.NotFound(w, r)
+			return
+		}
+		switch {
+		case r.FormValue("state") != state:
+			http.Error(w, "Invalid state", http.StatusBadRequest)
+			return
+		case r.FormValue("error") != "":
+			errs <- errors.New("authorization failed: " + r.FormValue("error"))
+		default:
+			codes <- r.FormValue("code")
+		}
+		io.WriteString(w, "You can close this window and return to itunesexport.")
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	fmt.Fprintf(out, "Open this URL in your browser to authorize itunesexport:\n%v\n", authURL)
+
+	var code string
+	select {
+	case code = <-codes:
+	case err := <-errs:
+		return "", err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {config.RedirectURL},
+		"client_id":     {config.ClientId},
+		"code_verifier": {verifier},
+	}
+	if config.ClientSecret != "" {
+		form.Set("client_secret", config.ClientSecret)
+	}
+	request, err := http.NewRequest(http.MethodPost, config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	request = request.WithContext(ctx)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		Error       string `json:"error"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&token); err != nil {
+		return "", err
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("no access token received: %v %v", response.Status, token.Error)
+	}
+	return token.AccessToken, nil
+}
+
+// oauthAttempts is the number of times a rate limited request is tried.
+const oauthAttempts = 5
+
+// oauthAPI is the JSON API of a service authorized with an OAuth access token.
+type oauthAPI struct {
+	name   string
+	api    string
+	token  string
+	client *http.Client
+	// header is added to every request.
+	header http.Header
+}
+
+// request sends a JSON request to the API, path being relative to the base URL unless it is
+// a full URL, and decodes the response into result, unless it is nil. Rate limited requests
+// are retried after the time requested by the service.
+func (api *oauthAPI) request(ctx context.Context, method, path string, body interface{}, result interface{}) error {
+	requestURL := path
+	if len(path) > 0 && path[0] == '/' {
+		requestURL = api.api + path
+	}
+	var content []byte
+	if body != nil {
+		var err error
+		if content, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		request, err := http.NewRequest(method, requestURL, bytes.NewReader(content))
+		if err != nil {
+			return err
+		}
+		request = request.WithContext(ctx)
+		request.Header.Set("Authorization", "Bearer "+api.token)
+		request.Header.Set("Content-Type", "application/json")
+		for key, values := range api.header {
+			request.Header[key] = values
+		}
+
+		response, err := api.client.Do(request)
+		if err != nil {
+			return err
+		}
+		if response.StatusCode == http.StatusTooManyRequests && attempt < oauthAttempts {
+			response.Body.Close()
+			wait, err := strconv.Atoi(response.Header.Get("Retry-After"))
+			if err != nil {
+				wait, _ = strconv.Atoi(response.Header.Get("X-RateLimit-Reset-In"))
+			}
+			select {
+			case <-time.After(time.Duration(wait+1) * time.Second):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			continue
+		}
+
+		defer response.Body.Close()
+		if response.StatusCode < 200 || response.StatusCode > 299 {
+			message, _ := ioutil.ReadAll(io.LimitReader(response.Body, 1024))
+			return &apiError{service: api.name, status: response.StatusCode, request: method + " " + path, message: string(message)}
+		}
+		if result == nil {
+			return nil
+		}
+		return json.NewDecoder(response.Body).Decode(result)
+	}
+}
+
+// apiError is the error response of an API.
+type apiError struct {
+	service string
+	status  int
+	request string
+	message string
+}
+
+func (err *apiError) Error() string {
+	return fmt.Sprintf("%v responded %v %v to %v: %v", err.service, err.status, http.StatusText(err.status), err.request, err.message)
+}
+
+// randomString returns a random string to be used once.
+func randomString() string {
+	random := make([]byte, 32)
+	rand.Read(random)
+	return base64.RawURLEncoding.EncodeToString(random)
+}
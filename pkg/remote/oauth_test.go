@@ -0,0 +1,75 @@
+package remote
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// browser follows the authorization URL written by Authorize like a user approving access.
+type browser struct {
+	t         *testing.T
+	challenge chan string
+}
+
+func (b *browser) Write(p []byte) (int, error) {
+	lines := strings.Split(strings.TrimSpace(string(p)), "\n")
+	authURL, err := url.Parse(lines[len(lines)-1])
+	if err != nil {
+		b.t.Error(err)
+		return len(p), nil
+	}
+	query := authURL.Query()
+	b.challenge <- query.Get("code_challenge")
+	go func() {
+		redirect := query.Get("redirect_uri") + "?code=secret-code&state=" + url.QueryEscape(query.Get("state"))
+		response, err := http.Get(redirect)
+		if err != nil {
+			b.t.Error(err)
+			return
+		}
+		response.Body.Close()
+	}()
+	return len(p), nil
+}
+
+func TestAuthorize(t *testing.T) {
+	challenge := make(chan string, 1)
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		verifier := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+		if r.FormValue("code") != "secret-code" || base64.RawURLEncoding.EncodeToString(verifier[:]) != <-challenge {
+			io.WriteString(w, `{"error":"invalid_grant"}`)
+			return
+		}
+		io.WriteString(w, `{"access_token":"token","token_type":"Bearer"}`)
+	}))
+	defer tokenServer.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	config := OAuthConfig{
+		AuthURL:     "https://accounts.example.com/authorize",
+		TokenURL:    tokenServer.URL,
+		ClientId:    "client",
+		RedirectURL: "http://" + address + "/callback",
+	}
+	token, err := config.Authorize(context.Background(), &browser{t: t, challenge: challenge})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "token" {
+		t.Fatalf("expected token, got %q", token)
+	}
+}
@@ -0,0 +1,211 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+// plexBatchSize limits the number of tracks added to a playlist with a single request, to
+// keep the URLs short enough.
+const plexBatchSize = 100
+
+// Plex creates playlists on a Plex Media Server. Tracks are matched with the tracks of all
+// music libraries of the server.
+type Plex struct {
+	server *url.URL
+	token  string
+	client *http.Client
+
+	machineId string
+	index     *TrackIndex
+	playlists map[string]string
+}
+
+// NewPlex returns the Plex Media Server at serverURL, e.g. http://server:32400, accessed with
+// the authentication token.
+func NewPlex(serverURL, token string) (*Plex, error) {
+	server, err := url.Parse(strings.TrimSuffix(serverURL, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if server.Scheme == "" || server.Host == "" {
+		return nil, errors.New("invalid Plex server URL " + serverURL)
+	}
+	if token == "" {
+		return nil, errors.New("missing Plex token")
+	}
+	return &Plex{server: server, token: token, client: &http.Client{}}, nil
+}
+
+// plexContainer is the part of the responses of the Plex API used.
+type plexContainer struct {
+	MediaContainer struct {
+		MachineIdentifier string `json:"machineIdentifier"`
+		Directory         []struct {
+			Key  string `json:"key"`
+			Type string `json:"type"`
+		} `json:"Directory"`
+		Metadata []struct {
+			RatingKey        string `json:"ratingKey"`
+			Title            string `json:"title"`
+			ParentTitle      string `json:"parentTitle"`
+			GrandparentTitle string `json:"grandparentTitle"`
+			OriginalTitle    string `json:"originalTitle"`
+			Smart            bool   `json:"smart"`
+			Media            []struct {
+				Part []struct {
+					File string `json:"file"`
+				} `json:"Part"`
+			} `json:"Media"`
+		} `json:"Metadata"`
+	} `json:"MediaContainer"`
+}
+
+// request sends a request to the Plex API and decodes the response into result, unless it
+// is nil.
+func (plex *Plex) request(ctx context.Context, method, path string, query url.Values, result interface{}) error {
+	requestURL := *plex.server
+	requestURL.Path += path
+	requestURL.RawQuery = query.Encode()
+
+	request, err := http.NewRequest(method, requestURL.String(), nil)
+	if err != nil {
+		return err
+	}
+	request = request.WithContext(ctx)
+	request.Header.Set("Accept", "application/json")
+	request.Header.Set("X-Plex-Token", plex.token)
+	request.Header.Set("X-Plex-Client-Identifier", "itunesexport")
+	request.Header.Set("X-Plex-Product", "itunesexport")
+
+	response, err := plex.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("Plex responded %v to %v %v", response.Status, method, path)
+	}
+	if result == nil {
+		_, err = io.Copy(ioutil.Discard, response.Body)
+		return err
+	}
+	return json.NewDecoder(response.Body).Decode(result)
+}
+
+// load reads the tracks of the music libraries and the playlists of the server once.
+func (plex *Plex) load(ctx context.Context) error {
+	if plex.index != nil {
+		return nil
+	}
+
+	var identity plexContainer
+	if err := plex.request(ctx, http.MethodGet, "/identity", nil, &identity); err != nil {
+		return err
+	}
+	plex.machineId = identity.MediaContainer.MachineIdentifier
+
+	var sections plexContainer
+	if err := plex.request(ctx, http.MethodGet, "/library/sections", nil, &sections); err != nil {
+		return err
+	}
+	var tracks []Track
+	for _, section := range sections.MediaContainer.Directory {
+		if section.Type != "artist" {
+			continue
+		}
+		var sectionTracks plexContainer
+		if err := plex.request(ctx, http.MethodGet, "/library/sections/"+section.Key+"/all", url.Values{"type": {"10"}}, &sectionTracks); err != nil {
+			return err
+		}
+		for _, metadata := range sectionTracks.MediaContainer.Metadata {
+			track := Track{Id: metadata.RatingKey, Artist: metadata.GrandparentTitle, Album: metadata.ParentTitle, Title: metadata.Title}
+			for _, media := range metadata.Media {
+				for _, part := range media.Part {
+					track.Path = part.File
+					tracks = append(tracks, track)
+				}
+			}
+			if metadata.OriginalTitle != "" {
+				track.Artist, track.Path = metadata.OriginalTitle, ""
+				tracks = append(tracks, track)
+			}
+			if len(metadata.Media) == 0 {
+				tracks = append(tracks, track)
+			}
+		}
+	}
+
+	var playlists plexContainer
+	if err := plex.request(ctx, http.MethodGet, "/playlists", url.Values{"playlistType": {"audio"}}, &playlists); err != nil {
+		return err
+	}
+	plex.playlists = make(map[string]string)
+	for _, playlist := range playlists.MediaContainer.Metadata {
+		if !playlist.Smart {
+			plex.playlists[playlist.Title] = playlist.RatingKey
+		}
+	}
+
+	plex.index = NewTrackIndex(tracks)
+	return nil
+}
+
+func (plex *Plex) FindTrack(ctx context.Context, track *itunes.Track) (string, error) {
+	if err := plex.load(ctx); err != nil {
+		return "", err
+	}
+	return plex.index.Find(track), nil
+}
+
+// itemsURI returns the URI of the tracks to add to a playlist.
+func (plex *Plex) itemsURI(trackIds []string) string {
+	return "server://" + plex.machineId + "/com.plexapp.plugins.library/library/metadata/" + strings.Join(trackIds, ",")
+}
+
+// SetPlaylist replaces the tracks of an existing playlist, or creates it. As Plex can not
+// create empty playlists, a new playlist without tracks is not created.
+func (plex *Plex) SetPlaylist(ctx context.Context, name string, trackIds []string) error {
+	if err := plex.load(ctx); err != nil {
+		return err
+	}
+
+	id, exists := plex.playlists[name]
+	if exists {
+		if err := plex.request(ctx, http.MethodDelete, "/playlists/"+id+"/items", nil, nil); err != nil {
+			return err
+		}
+	}
+
+	for _, batch := range batches(trackIds, plexBatchSize) {
+		uri := plex.itemsURI(batch)
+
+		if id != "" {
+			if err := plex.request(ctx, http.MethodPut, "/playlists/"+id+"/items", url.Values{"uri": {uri}}, nil); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var created plexContainer
+		query := url.Values{"type": {"audio"}, "title": {name}, "smart": {"0"}, "uri": {uri}}
+		if err := plex.request(ctx, http.MethodPost, "/playlists", query, &created); err != nil {
+			return err
+		}
+		if len(created.MediaContainer.Metadata) == 0 {
+			return errors.New("Plex did not return the created playlist")
+		}
+		id = created.MediaContainer.Metadata[0].RatingKey
+		plex.playlists[name] = id
+	}
+	return nil
+}
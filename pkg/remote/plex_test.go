@@ -0,0 +1,99 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+// fakePlex serves the part of the Plex API used by Plex.
+type fakePlex struct {
+	requests  []string
+	playlists map[string][]string
+}
+
+func (server *fakePlex) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Plex-Token") != "token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	server.requests = append(server.requests, r.Method+" "+r.URL.Path)
+
+	items := func() []string {
+		uri := r.URL.Query().Get("uri")
+		return strings.Split(uri[strings.LastIndex(uri, "/")+1:], ",")
+	}
+	switch r.Method + " " + r.URL.Path {
+	case "GET /identity":
+		io.WriteString(w, `{"MediaContainer":{"machineIdentifier":"abc"}}`)
+	case "GET /library/sections":
+		io.WriteString(w, `{"MediaContainer":{"Directory":[{"key":"1","type":"movie"},{"key":"2","type":"artist"}]}}`)
+	case "GET /library/sections/2/all":
+		io.WriteString(w, `{"MediaContainer":{"Metadata":[
+			{"ratingKey":"10","title":"Song","parentTitle":"Album","grandparentTitle":"Artist","Media":[{"Part":[{"file":"/data/Artist/Album/song.mp3"}]}]},
+			{"ratingKey":"11","title":"Other Song","parentTitle":"Album","grandparentTitle":"Various Artists","originalTitle":"Singer"}]}}`)
+	case "GET /playlists":
+		io.WriteString(w, `{"MediaContainer":{"Metadata":[{"ratingKey":"5","title":"Existing","smart":false},{"ratingKey":"6","title":"Smart","smart":true}]}}`)
+	case "POST /playlists":
+		if !strings.HasPrefix(r.URL.Query().Get("uri"), "server://abc/com.plexapp.plugins.library/library/metadata/") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		server.playlists[r.URL.Query().Get("title")] = items()
+		io.WriteString(w, `{"MediaContainer":{"Metadata":[{"ratingKey":"7"}]}}`)
+	case "DELETE /playlists/5/items":
+		server.playlists["Existing"] = nil
+	case "PUT /playlists/5/items", "PUT /playlists/7/items":
+		for name, id := range map[string]string{"Existing": "5", "New": "7"} {
+			if strings.Contains(r.URL.Path, "/"+id+"/") {
+				server.playlists[name] = append(server.playlists[name], items()...)
+			}
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestPushPlaylistsToPlex(t *testing.T) {
+	server := &fakePlex{playlists: make(map[string][]string)}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	library := &itunes.Library{Tracks: map[string]itunes.Track{
+		"1": {TrackId: 1, Name: "Song", Location: "file:///Users/me/Music/Artist/Album/song.mp3"},
+		"2": {TrackId: 2, Name: "Other Song", Artist: "Singer", Album: "Album"},
+		"3": {TrackId: 3, Name: "Missing", Artist: "Nobody"},
+	}}
+	playlists := []itunes.Playlist{
+		{Name: "Existing", PlaylistItems: []itunes.PlaylistItem{{TrackId: 2}, {TrackId: 1}}},
+		{Name: "New", PlaylistItems: []itunes.PlaylistItem{{TrackId: 1}, {TrackId: 3}}},
+		{Name: "Empty", PlaylistItems: []itunes.PlaylistItem{{TrackId: 3}}},
+		{Name: "Folder", Folder: true},
+	}
+
+	plex, err := NewPlex(httpServer.URL+"/", "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := PushPlaylists(context.Background(), plex, library, playlists)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 3 || results[0].Matched != 2 || results[1].Matched != 1 ||
+		len(results[1].Unmatched) != 1 || results[1].Unmatched[0].Name != "Missing" || results[2].Matched != 0 {
+		t.Fatalf("unexpected results %+v", results)
+	}
+	if fmt.Sprint(server.playlists["Existing"]) != "[11 10]" || fmt.Sprint(server.playlists["New"]) != "[10]" {
+		t.Fatalf("unexpected playlists %v", server.playlists)
+	}
+	if _, ok := server.playlists["Empty"]; ok {
+		t.Fatal("expected empty playlist not to be created")
+	}
+}
@@ -0,0 +1,62 @@
+// Package remote recreates the playlists of an iTunes library on media servers and streaming
+// services, matching the tracks of the library with the tracks known to the service.
+package remote
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+// Service is a media server or streaming service playlists can be created on.
+type Service interface {
+	// FindTrack returns the id of the track of the service matching track, or the empty
+	// string if there is none.
+	FindTrack(ctx context.Context, track *itunes.Track) (string, error)
+	// SetPlaylist creates the playlist name with the tracks, or replaces the tracks of the
+	// existing playlist with that name.
+	SetPlaylist(ctx context.Context, name string, trackIds []string) error
+}
+
+// Result is the result of pushing a playlist.
+type Result struct {
+	Playlist  string
+	Matched   int
+	Unmatched []itunes.Track
+}
+
+// PushPlaylists creates the playlists on the service. Tracks without a match on the service
+// are left out of the playlists and reported in the results.
+func PushPlaylists(ctx context.Context, service Service, library *itunes.Library, playlists []itunes.Playlist) ([]Result, error) {
+	var results []Result
+	for _, playlist := range playlists {
+		if playlist.Container() {
+			continue
+		}
+
+		result := Result{Playlist: playlist.Name}
+		var trackIds []string
+		for _, track := range playlist.Tracks(library) {
+			if err := ctx.Err(); err != nil {
+				return results, err
+			}
+			id, err := service.FindTrack(ctx, &track)
+			if err != nil {
+				return results, err
+			}
+			if id == "" {
+				result.Unmatched = append(result.Unmatched, track)
+				continue
+			}
+			trackIds = append(trackIds, id)
+		}
+		result.Matched = len(trackIds)
+
+		if err := service.SetPlaylist(ctx, playlist.Name, trackIds); err != nil {
+			return results, fmt.Errorf("playlist %v: %v", playlist.Name, err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
@@ -0,0 +1,215 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+const (
+	sonosAVTransport      = "urn:schemas-upnp-org:service:AVTransport:1"
+	sonosContentDirectory = "urn:schemas-upnp-org:service:ContentDirectory:1"
+	// sonosAppend is the index to add a track at the end of a saved queue.
+	sonosAppend = "4294967295"
+)
+
+// Sonos creates playlists (saved queues) of a Sonos household. Tracks are referenced by
+// their location on the share of the Sonos music library, so the music files must be
+// stored on a share added to the music library in the Sonos app.
+type Sonos struct {
+	speaker     *url.URL
+	share       string
+	musicFolder string
+	client      *http.Client
+
+	playlists map[string]string
+}
+
+// NewSonos returns the household of the Sonos speaker, given by its address or URL. The
+// music folder of the library must be shared as share, e.g. //nas/music, in the Sonos
+// music library.
+func NewSonos(speaker, share, musicFolder string) (*Sonos, error) {
+	if !strings.Contains(speaker, "://") {
+		speaker = "http://" + speaker
+	}
+	speakerURL, err := url.Parse(speaker)
+	if err != nil {
+		return nil, err
+	}
+	if speakerURL.Port() == "" {
+		speakerURL.Host = net.JoinHostPort(speakerURL.Hostname(), "1400")
+	}
+
+	share = strings.Trim(strings.Replace(share, `\`, "/", -1), "/")
+	if share == "" || !strings.Contains(share, "/") {
+		return nil, errors.New("the share of the Sonos music library must be given as //server/share")
+	}
+	if musicFolder == "" {
+		return nil, errors.New("the library has no music folder to map to the share")
+	}
+	return &Sonos{speaker: speakerURL, share: share, musicFolder: musicFolder, client: &http.Client{}}, nil
+}
+
+// call invokes the action of a UPnP service of the speaker and returns the output arguments.
+func (sonos *Sonos) call(ctx context.Context, controlPath, service, action string, arguments [][2]string) (map[string]string, error) {
+	var body bytes.Buffer
+	body.WriteString(`<?xml version="1.0" encoding="utf-8"?>` +
+		`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"><s:Body>`)
+	fmt.Fprintf(&body, `<u:%v xmlns:u="%v">`, action, service)
+	for _, argument := range arguments {
+		fmt.Fprintf(&body, "<%v>%v</%v>", argument[0], html.EscapeString(argument[1]), argument[0])
+	}
+	fmt.Fprintf(&body, "</u:%v></s:Body></s:Envelope>", action)
+
+	request, err := http.NewRequest(http.MethodPost, sonos.speaker.String()+controlPath, &body)
+	if err != nil {
+		return nil, err
+	}
+	request = request.WithContext(ctx)
+	request.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	request.Header.Set("SOAPACTION", `"`+service+"#"+action+`"`)
+
+	response, err := sonos.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Sonos responded %v to %v", response.Status, action)
+	}
+	return soapOutput(response.Body, action+"Response")
+}
+
+// soapOutput reads the output arguments, the children of the response element, of a SOAP
+// response.
+func soapOutput(r io.Reader, responseElement string) (map[string]string, error) {
+	decoder := xml.NewDecoder(r)
+	output := make(map[string]string)
+	depth, inResponse := 0, false
+	var name string
+	var value strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			if inResponse {
+				return output, nil
+			}
+			return nil, errors.New("invalid SOAP response")
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			depth++
+			if token.Name.Local == responseElement {
+				inResponse, depth = true, 0
+			} else if inResponse && depth == 1 {
+				name = token.Name.Local
+				value.Reset()
+			}
+		case xml.CharData:
+			if inResponse && depth == 1 {
+				value.Write(token)
+			}
+		case xml.EndElement:
+			if inResponse && depth == 1 {
+				output[name] = value.String()
+			}
+			if inResponse && depth == 0 {
+				return output, nil
+			}
+			depth--
+		}
+	}
+}
+
+// FindTrack returns the URI of the file of the track on the share, or the empty string if
+// the file is not stored in the music folder of the library.
+func (sonos *Sonos) FindTrack(ctx context.Context, track *itunes.Track) (string, error) {
+	if !strings.HasPrefix(track.Location, "file://") {
+		return "", nil
+	}
+	relative, err := filepath.Rel(sonos.musicFolder, itunes.LocationPath(track.Location))
+	if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return "", nil
+	}
+
+	segments := strings.Split(sonos.share+"/"+filepath.ToSlash(relative), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return "x-file-cifs://" + strings.Join(segments, "/"), nil
+}
+
+// loadPlaylists reads the saved queues of the household once.
+func (sonos *Sonos) loadPlaylists(ctx context.Context) error {
+	if sonos.playlists != nil {
+		return nil
+	}
+	output, err := sonos.call(ctx, "/MediaServer/ContentDirectory/Control", sonosContentDirectory, "Browse", [][2]string{
+		{"ObjectID", "SQ:"}, {"BrowseFlag", "BrowseDirectChildren"}, {"Filter", "dc:title"},
+		{"StartingIndex", "0"}, {"RequestedCount", "0"}, {"SortCriteria", ""},
+	})
+	if err != nil {
+		return err
+	}
+
+	var didl struct {
+		Containers []struct {
+			Id    string `xml:"id,attr"`
+			Title string `xml:"title"`
+		} `xml:"container"`
+	}
+	if err := xml.Unmarshal([]byte(output["Result"]), &didl); err != nil {
+		return err
+	}
+	sonos.playlists = make(map[string]string)
+	for _, container := range didl.Containers {
+		sonos.playlists[container.Title] = container.Id
+	}
+	return nil
+}
+
+// SetPlaylist deletes an existing playlist with the name and creates it with the tracks.
+func (sonos *Sonos) SetPlaylist(ctx context.Context, name string, trackIds []string) error {
+	if err := sonos.loadPlaylists(ctx); err != nil {
+		return err
+	}
+
+	if id, ok := sonos.playlists[name]; ok {
+		if _, err := sonos.call(ctx, "/MediaServer/ContentDirectory/Control", sonosContentDirectory, "DestroyObject", [][2]string{{"ObjectID", id}}); err != nil {
+			return err
+		}
+		delete(sonos.playlists, name)
+	}
+
+	output, err := sonos.call(ctx, "/MediaRenderer/AVTransport/Control", sonosAVTransport, "CreateSavedQueue", [][2]string{
+		{"InstanceID", "0"}, {"Title", name}, {"EnqueuedURI", ""}, {"EnqueuedURIMetaData", ""},
+	})
+	if err != nil {
+		return err
+	}
+	id, updateId := output["AssignedObjectID"], output["NewUpdateID"]
+	sonos.playlists[name] = id
+
+	for _, uri := range trackIds {
+		output, err := sonos.call(ctx, "/MediaRenderer/AVTransport/Control", sonosAVTransport, "AddURIToSavedQueue", [][2]string{
+			{"InstanceID", "0"}, {"UpdateID", updateId}, {"ObjectID", id}, {"EnqueuedURI", uri},
+			{"EnqueuedURIMetaData", ""}, {"AddAtIndex", sonosAppend},
+		})
+		if err != nil {
+			return err
+		}
+		updateId = output["NewUpdateID"]
+	}
+	return nil
+}
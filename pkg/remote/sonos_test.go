@@ -0,0 +1,97 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+// fakeSonos serves the UPnP actions used by Sonos.
+type fakeSonos struct {
+	actions []string
+	queues  map[string][]string
+}
+
+var soapArgument = regexp.MustCompile(`<(\w+)>([^<]*)</\w+>`)
+
+func (server *fakeSonos) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	arguments := make(map[string]string)
+	for _, match := range soapArgument.FindAllStringSubmatch(string(body), -1) {
+		arguments[match[1]] = html.UnescapeString(match[2])
+	}
+	action := strings.Trim(r.Header.Get("SOAPACTION"), `"`)
+	action = action[strings.Index(action, "#")+1:]
+	server.actions = append(server.actions, action)
+
+	output := ""
+	switch action {
+	case "Browse":
+		didl := `<DIDL-Lite xmlns="urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/" xmlns:dc="http://purl.org/dc/elements/1.1/">` +
+			`<container id="SQ:3"><dc:title>Existing</dc:title></container></DIDL-Lite>`
+		output = "<Result>" + html.EscapeString(didl) + "</Result><NumberReturned>1</NumberReturned>"
+	case "DestroyObject":
+		delete(server.queues, arguments["ObjectID"])
+	case "CreateSavedQueue":
+		server.queues["SQ:4"] = []string{}
+		output = "<AssignedObjectID>SQ:4</AssignedObjectID><NewUpdateID>1</NewUpdateID>"
+	case "AddURIToSavedQueue":
+		if arguments["UpdateID"] != fmt.Sprint(len(server.queues[arguments["ObjectID"]])+1) {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		server.queues[arguments["ObjectID"]] = append(server.queues[arguments["ObjectID"]], arguments["EnqueuedURI"])
+		output = fmt.Sprintf("<NewUpdateID>%v</NewUpdateID>", len(server.queues[arguments["ObjectID"]])+1)
+	}
+	fmt.Fprintf(w, `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><u:%vResponse xmlns:u="urn:x">%v</u:%vResponse></s:Body></s:Envelope>`,
+		action, output, action)
+}
+
+func TestPushPlaylistsToSonos(t *testing.T) {
+	server := &fakeSonos{queues: map[string][]string{"SQ:3": {"old"}}}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	library := &itunes.Library{MusicFolder: "file:///C:/Music/", Tracks: map[string]itunes.Track{
+		"1": {TrackId: 1, Name: "Song", Location: "file:///C:/Music/Artist/Album/01%20Song%20%231.mp3"},
+		"2": {TrackId: 2, Name: "Elsewhere", Location: "file:///D:/Other/song.mp3"},
+	}}
+	playlists := []itunes.Playlist{{Name: "Existing", PlaylistItems: []itunes.PlaylistItem{{TrackId: 1}, {TrackId: 2}}}}
+
+	sonos, err := NewSonos(httpServer.URL, `\\nas\music`, itunes.LocationPath(library.MusicFolder))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := PushPlaylists(context.Background(), sonos, library, playlists)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 1 || results[0].Matched != 1 || len(results[0].Unmatched) != 1 || results[0].Unmatched[0].Name != "Elsewhere" {
+		t.Fatalf("unexpected results %+v", results)
+	}
+	if _, ok := server.queues["SQ:3"]; ok {
+		t.Fatal("expected existing playlist to be replaced")
+	}
+	if fmt.Sprint(server.queues["SQ:4"]) != "[x-file-cifs://nas/music/Artist/Album/01%20Song%20%231.mp3]" {
+		t.Fatalf("unexpected playlist %v", server.queues["SQ:4"])
+	}
+}
+
+func TestNewSonos(t *testing.T) {
+	sonos, err := NewSonos("192.168.1.20", "//nas/music", "/music")
+	if err != nil || sonos.speaker.String() != "http://192.168.1.20:1400" || sonos.share != "nas/music" {
+		t.Fatalf("unexpected speaker %v, error %v", sonos, err)
+	}
+	if _, err := NewSonos("192.168.1.20", "nas", "/music"); err == nil {
+		t.Fatal("expected error for share without server")
+	}
+}
@@ -0,0 +1,167 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+// spotifyBatchSize is the maximum number of tracks added to a playlist at once.
+const spotifyBatchSize = 100
+
+// SpotifyOAuth returns the authorization of the Spotify application with the client id, whose
+// redirect URI must be http://127.0.0.1:8888/callback.
+func SpotifyOAuth(clientId string) OAuthConfig {
+	return OAuthConfig{
+		AuthURL:     "https://accounts.spotify.com/authorize",
+		TokenURL:    "https://accounts.spotify.com/api/token",
+		ClientId:    clientId,
+		Scopes:      []string{"playlist-read-private", "playlist-modify-private", "playlist-modify-public"},
+		RedirectURL: "http://127.0.0.1:8888/callback",
+	}
+}
+
+// Spotify creates playlists of a Spotify user. Tracks are matched by searching the catalog
+// for their title and artist.
+type Spotify struct {
+	oauthAPI
+
+	userId    string
+	playlists map[string]string
+}
+
+// NewSpotify returns the Spotify account authorized by the access token.
+func NewSpotify(token string) *Spotify {
+	return &Spotify{oauthAPI: oauthAPI{name: "Spotify", api: "https://api.spotify.com/v1", token: token, client: &http.Client{}}}
+}
+
+// FindTrack searches the catalog for the title and artist of the track and returns the URI
+// of the best match.
+func (spotify *Spotify) FindTrack(ctx context.Context, track *itunes.Track) (string, error) {
+	if track.Name == "" {
+		return "", nil
+	}
+	artist := track.Artist
+	if artist == "" {
+		artist = track.AlbumArtist
+	}
+	query := fmt.Sprintf("track:%v", baseTitle(track.Name))
+	if artist != "" {
+		query += fmt.Sprintf(" artist:%v", featuredArtists.Split(artist, 2)[0])
+	}
+
+	var result struct {
+		Tracks struct {
+			Items []struct {
+				URI        string `json:"uri"`
+				Name       string `json:"name"`
+				DurationMs int    `json:"duration_ms"`
+				Album      struct {
+					Name string `json:"name"`
+				} `json:"album"`
+				Artists []struct {
+					Name string `json:"name"`
+				} `json:"artists"`
+			} `json:"items"`
+		} `json:"tracks"`
+	}
+	path := "/search?" + url.Values{"q": {query}, "type": {"track"}, "limit": {"10"}}.Encode()
+	if err := spotify.request(ctx, http.MethodGet, path, nil, &result); err != nil {
+		return "", err
+	}
+
+	var candidates []Candidate
+	for _, item := range result.Tracks.Items {
+		candidate := Candidate{Id: item.URI, Album: item.Album.Name, Title: item.Name, Duration: time.Duration(item.DurationMs) * time.Millisecond}
+		for _, artist := range item.Artists {
+			candidate.Artists = append(candidate.Artists, artist.Name)
+		}
+		candidates = append(candidates, candidate)
+	}
+	return BestMatch(track, candidates), nil
+}
+
+// loadPlaylists reads the user and the playlists owned by the user once.
+func (spotify *Spotify) loadPlaylists(ctx context.Context) error {
+	if spotify.playlists != nil {
+		return nil
+	}
+
+	var user struct {
+		Id string `json:"id"`
+	}
+	if err := spotify.request(ctx, http.MethodGet, "/me", nil, &user); err != nil {
+		return err
+	}
+	spotify.userId = user.Id
+
+	playlists := make(map[string]string)
+	for next := "/me/playlists?limit=50"; next != ""; {
+		var page struct {
+			Items []struct {
+				Id    string `json:"id"`
+				Name  string `json:"name"`
+				Owner struct {
+					Id string `json:"id"`
+				} `json:"owner"`
+			} `json:"items"`
+			Next string `json:"next"`
+		}
+		if err := spotify.request(ctx, http.MethodGet, next, nil, &page); err != nil {
+			return err
+		}
+		for _, playlist := range page.Items {
+			if playlist.Owner.Id == spotify.userId {
+				playlists[playlist.Name] = playlist.Id
+			}
+		}
+		next = page.Next
+	}
+	spotify.playlists = playlists
+	return nil
+}
+
+// SetPlaylist replaces the tracks of the user's playlist with the name, or creates it as a
+// private playlist.
+func (spotify *Spotify) SetPlaylist(ctx context.Context, name string, trackIds []string) error {
+	if err := spotify.loadPlaylists(ctx); err != nil {
+		return err
+	}
+
+	id, ok := spotify.playlists[name]
+	if !ok {
+		var created struct {
+			Id string `json:"id"`
+		}
+		body := map[string]interface{}{"name": name, "public": false, "description": "Exported from iTunes"}
+		if err := spotify.request(ctx, http.MethodPost, "/users/"+url.PathEscape(spotify.userId)+"/playlists", body, &created); err != nil {
+			return err
+		}
+		if created.Id == "" {
+			return errors.New("Spotify did not return the created playlist")
+		}
+		id = created.Id
+		spotify.playlists[name] = id
+	}
+
+	// The first request replaces all tracks, the following ones add the remaining tracks.
+	batches := batches(trackIds, spotifyBatchSize)
+	if len(batches) == 0 {
+		batches = [][]string{{}}
+	}
+	for i, batch := range batches {
+		method := http.MethodPost
+		if i == 0 {
+			method = http.MethodPut
+		}
+		if err := spotify.request(ctx, method, "/playlists/"+id+"/tracks", map[string][]string{"uris": batch}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+// fakeSpotify serves the part of the Spotify Web API used by Spotify.
+type fakeSpotify struct {
+	url       string
+	playlists map[string][]string
+	created   []string
+}
+
+func (server *fakeSpotify) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/me":
+		io.WriteString(w, `{"id":"me"}`)
+	case r.URL.Path == "/search":
+		tracks := map[string]string{
+			`track:Song artist:Artist`:    `{"uri":"spotify:track:1","name":"Song - Live","duration_ms":200000,"album":{"name":"Live"},"artists":[{"name":"Artist"}]},{"uri":"spotify:track:2","name":"Song","duration_ms":181000,"album":{"name":"Album"},"artists":[{"name":"Artist"}]}`,
+			`track:Other Song artist:Duo`: `{"uri":"spotify:track:3","name":"Other Song","duration_ms":0,"album":{"name":"Hits"},"artists":[{"name":"Duo"},{"name":"Guest"}]}`,
+		}[r.URL.Query().Get("q")]
+		fmt.Fprintf(w, `{"tracks":{"items":[%v]}}`, tracks)
+	case r.URL.Path == "/me/playlists" && r.URL.Query().Get("offset") == "":
+		fmt.Fprintf(w, `{"items":[{"id":"p1","name":"Followed","owner":{"id":"other"}}],"next":"%v/me/playlists?offset=50"}`, server.url)
+	case r.URL.Path == "/me/playlists":
+		io.WriteString(w, `{"items":[{"id":"p2","name":"Existing","owner":{"id":"me"}}],"next":null}`)
+	case r.URL.Path == "/users/me/playlists" && r.Method == http.MethodPost:
+		var body struct {
+			Name string `json:"name"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		server.created = append(server.created, body.Name)
+		fmt.Fprintf(w, `{"id":"new-%v"}`, body.Name)
+	case strings.HasPrefix(r.URL.Path, "/playlists/") && strings.HasSuffix(r.URL.Path, "/tracks"):
+		var body struct {
+			URIs []string `json:"uris"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/playlists/"), "/tracks")
+		switch r.Method {
+		case http.MethodPut:
+			server.playlists[id] = body.URIs
+		case http.MethodPost:
+			server.playlists[id] = append(server.playlists[id], body.URIs...)
+		}
+		io.WriteString(w, `{"snapshot_id":"1"}`)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestPushPlaylistsToSpotify(t *testing.T) {
+	server := &fakeSpotify{playlists: map[string][]string{"p2": {"spotify:track:9"}}}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+	server.url = httpServer.URL
+
+	library := &itunes.Library{Tracks: map[string]itunes.Track{
+		"1": {TrackId: 1, Name: "Song", Artist: "Artist", Album: "Album", TotalTime: 180000},
+		"2": {TrackId: 2, Name: "Other Song", Artist: "Duo feat. Guest", Album: "Hits"},
+		"3": {TrackId: 3, Name: "Missing", Artist: "Nobody"},
+	}}
+	playlists := []itunes.Playlist{
+		{Name: "Existing", PlaylistItems: []itunes.PlaylistItem{{TrackId: 2}, {TrackId: 3}}},
+		{Name: "New", PlaylistItems: []itunes.PlaylistItem{{TrackId: 1}, {TrackId: 2}}},
+	}
+
+	spotify := NewSpotify("token")
+	spotify.api = httpServer.URL
+	results, err := PushPlaylists(context.Background(), spotify, library, playlists)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 2 || results[0].Matched != 1 || len(results[0].Unmatched) != 1 || results[1].Matched != 2 {
+		t.Fatalf("unexpected results %+v", results)
+	}
+	if !reflect.DeepEqual(server.created, []string{"New"}) {
+		t.Fatalf("expected only New to be created, got %v", server.created)
+	}
+	expected := map[string][]string{
+		"p2":      {"spotify:track:3"},
+		"new-New": {"spotify:track:2", "spotify:track:3"},
+	}
+	if !reflect.DeepEqual(server.playlists, expected) {
+		t.Fatalf("expected playlists %v, got %v", expected, server.playlists)
+	}
+}
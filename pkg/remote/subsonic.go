@@ -0,0 +1,168 @@
+package remote
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+// subsonicSearchResults is the number of songs requested when searching for a track.
+const subsonicSearchResults = 20
+
+// Subsonic creates playlists on a server implementing the Subsonic API, e.g. Navidrome or
+// Airsonic. Tracks are matched by searching for their title.
+type Subsonic struct {
+	server   *url.URL
+	user     string
+	password string
+	client   *http.Client
+
+	playlists map[string]string
+}
+
+// NewSubsonic returns the Subsonic API server at serverURL, e.g. http://server:4533,
+// accessed as user with the password.
+func NewSubsonic(serverURL, user, password string) (*Subsonic, error) {
+	server, err := url.Parse(strings.TrimSuffix(serverURL, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if server.Scheme == "" || server.Host == "" {
+		return nil, errors.New("invalid Subsonic server URL " + serverURL)
+	}
+	if user == "" || password == "" {
+		return nil, errors.New("missing Subsonic user or password")
+	}
+	return &Subsonic{server: server, user: user, password: password, client: &http.Client{}}, nil
+}
+
+// subsonicSong is a song returned by the API.
+type subsonicSong struct {
+	Id     string `json:"id"`
+	Title  string `json:"title"`
+	Artist string `json:"artist"`
+	Album  string `json:"album"`
+	Path   string `json:"path"`
+}
+
+// subsonicResponse is the part of the responses of the API used.
+type subsonicResponse struct {
+	Response struct {
+		Status string `json:"status"`
+		Error  struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+		SearchResult3 struct {
+			Song []subsonicSong `json:"song"`
+		} `json:"searchResult3"`
+		Playlists struct {
+			Playlist []struct {
+				Id   string `json:"id"`
+				Name string `json:"name"`
+			} `json:"playlist"`
+		} `json:"playlists"`
+		Playlist struct {
+			Id string `json:"id"`
+		} `json:"playlist"`
+	} `json:"subsonic-response"`
+}
+
+// request calls a method of the API. The parameters are sent as form, as the ids of the
+// songs of a playlist can exceed the length of a URL.
+func (subsonic *Subsonic) request(ctx context.Context, method string, parameters url.Values) (*subsonicResponse, error) {
+	salt := make([]byte, 8)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	token := md5.Sum([]byte(subsonic.password + hex.EncodeToString(salt)))
+	parameters.Set("u", subsonic.user)
+	parameters.Set("t", hex.EncodeToString(token[:]))
+	parameters.Set("s", hex.EncodeToString(salt))
+	parameters.Set("v", "1.16.1")
+	parameters.Set("c", "itunesexport")
+	parameters.Set("f", "json")
+
+	requestURL := *subsonic.server
+	requestURL.Path += "/rest/" + method
+	request, err := http.NewRequest(http.MethodPost, requestURL.String(), strings.NewReader(parameters.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	request = request.WithContext(ctx)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	response, err := subsonic.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server responded %v to %v", response.Status, method)
+	}
+
+	var result subsonicResponse
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	if result.Response.Status != "ok" {
+		return nil, fmt.Errorf("%v failed: %v (error %v)", method, result.Response.Error.Message, result.Response.Error.Code)
+	}
+	return &result, nil
+}
+
+// FindTrack searches for songs with the title of the track and returns the one matching it.
+func (subsonic *Subsonic) FindTrack(ctx context.Context, track *itunes.Track) (string, error) {
+	if track.Name == "" {
+		return "", nil
+	}
+	parameters := url.Values{"query": {track.Name}, "songCount": {fmt.Sprint(subsonicSearchResults)}, "artistCount": {"0"}, "albumCount": {"0"}}
+	result, err := subsonic.request(ctx, "search3", parameters)
+	if err != nil {
+		return "", err
+	}
+
+	var tracks []Track
+	for _, song := range result.Response.SearchResult3.Song {
+		tracks = append(tracks, Track{Id: song.Id, Path: song.Path, Artist: song.Artist, Album: song.Album, Title: song.Title})
+	}
+	return NewTrackIndex(tracks).Find(track), nil
+}
+
+// SetPlaylist replaces the songs of an existing playlist, or creates it.
+func (subsonic *Subsonic) SetPlaylist(ctx context.Context, name string, trackIds []string) error {
+	if subsonic.playlists == nil {
+		result, err := subsonic.request(ctx, "getPlaylists", url.Values{})
+		if err != nil {
+			return err
+		}
+		subsonic.playlists = make(map[string]string)
+		for _, playlist := range result.Response.Playlists.Playlist {
+			subsonic.playlists[playlist.Name] = playlist.Id
+		}
+	}
+
+	parameters := url.Values{"songId": trackIds}
+	if id, ok := subsonic.playlists[name]; ok {
+		parameters.Set("playlistId", id)
+	} else {
+		parameters.Set("name", name)
+	}
+	result, err := subsonic.request(ctx, "createPlaylist", parameters)
+	if err != nil {
+		return err
+	}
+	if result.Response.Playlist.Id != "" {
+		subsonic.playlists[name] = result.Response.Playlist.Id
+	}
+	return nil
+}
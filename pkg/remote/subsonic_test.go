@@ -0,0 +1,85 @@
+package remote
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+// fakeSubsonic serves the part of the Subsonic API used by Subsonic.
+type fakeSubsonic struct {
+	playlists map[string][]string
+}
+
+func (server *fakeSubsonic) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	token := md5.Sum([]byte("secret" + r.Form.Get("s")))
+	if r.Form.Get("u") != "me" || r.Form.Get("t") != hex.EncodeToString(token[:]) {
+		io.WriteString(w, `{"subsonic-response":{"status":"failed","error":{"code":40,"message":"Wrong username or password"}}}`)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/rest/search3":
+		songs := map[string]string{
+			"Song":       `{"id":"s1","title":"Song","artist":"Other Artist","album":"Album","path":"Other Artist/Album/song.mp3"},{"id":"s2","title":"Song","artist":"Artist","album":"Album","path":"Artist/Album/song.mp3"}`,
+			"Other Song": `{"id":"s3","title":"Other Song (Remastered)","artist":"Singer","album":"Hits"},{"id":"s4","title":"Other Song","artist":"Singer","album":"Hits"}`,
+		}[r.Form.Get("query")]
+		fmt.Fprintf(w, `{"subsonic-response":{"status":"ok","searchResult3":{"song":[%v]}}}`, songs)
+	case "/rest/getPlaylists":
+		io.WriteString(w, `{"subsonic-response":{"status":"ok","playlists":{"playlist":[{"id":"1","name":"Existing"}]}}}`)
+	case "/rest/createPlaylist":
+		name := r.Form.Get("name")
+		if r.Form.Get("playlistId") == "1" {
+			name = "Existing"
+		}
+		server.playlists[name] = r.Form["songId"]
+		io.WriteString(w, `{"subsonic-response":{"status":"ok","playlist":{"id":"2"}}}`)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestPushPlaylistsToSubsonic(t *testing.T) {
+	server := &fakeSubsonic{playlists: map[string][]string{"Existing": {"s9"}}}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	library := &itunes.Library{Tracks: map[string]itunes.Track{
+		"1": {TrackId: 1, Name: "Song", Location: "file:///Users/me/Music/Artist/Album/song.mp3"},
+		"2": {TrackId: 2, Name: "Other Song", Artist: "Singer", Album: "Hits"},
+		"3": {TrackId: 3, Name: "Missing", Artist: "Nobody"},
+	}}
+	playlists := []itunes.Playlist{
+		{Name: "Existing", PlaylistItems: []itunes.PlaylistItem{{TrackId: 2}, {TrackId: 1}}},
+		{Name: "New", PlaylistItems: []itunes.PlaylistItem{{TrackId: 3}, {TrackId: 1}}},
+	}
+
+	subsonic, err := NewSubsonic(httpServer.URL, "me", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := PushPlaylists(context.Background(), subsonic, library, playlists)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 2 || results[0].Matched != 2 || results[1].Matched != 1 || len(results[1].Unmatched) != 1 {
+		t.Fatalf("unexpected results %+v", results)
+	}
+	if fmt.Sprint(server.playlists["Existing"]) != "[s4 s2]" || fmt.Sprint(server.playlists["New"]) != "[s2]" {
+		t.Fatalf("unexpected playlists %v", server.playlists)
+	}
+
+	wrongPassword, _ := NewSubsonic(httpServer.URL, "me", "wrong")
+	if _, err := wrongPassword.FindTrack(context.Background(), &itunes.Track{Name: "Song"}); err == nil {
+		t.Fatal("expected authentication error")
+	}
+}
@@ -0,0 +1,243 @@
+package remote
+
+import (
+	"context"
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+// YouTubeOAuth returns the authorization of the Google desktop app with the client id and
+// secret to manage the user's YouTube playlists.
+func YouTubeOAuth(clientId, clientSecret string) OAuthConfig {
+	return OAuthConfig{
+		AuthURL:      "https://accounts.google.com/o/oauth2/v2/auth",
+		TokenURL:     "https://oauth2.googleapis.com/token",
+		ClientId:     clientId,
+		ClientSecret: clientSecret,
+		Scopes:       []string{"https://www.googleapis.com/auth/youtube"},
+		RedirectURL:  "http://127.0.0.1:8888/callback",
+	}
+}
+
+// YouTube creates playlists of a YouTube Music user through the YouTube Data API, which
+// YouTube Music shares its playlists with. Tracks are matched by searching the music videos
+// for their artist and title, preferring the auto-generated "Artist - Topic" tracks.
+type YouTube struct {
+	oauthAPI
+
+	playlists map[string]string
+}
+
+// NewYouTube returns the YouTube account authorized by the access token.
+func NewYouTube(token string) *YouTube {
+	return &YouTube{oauthAPI: oauthAPI{name: "YouTube", api: "https://www.googleapis.com/youtube/v3", token: token, client: &http.Client{}}}
+}
+
+// youTubeTopic is the suffix of the channels YouTube generates for the tracks of an artist.
+const youTubeTopic = " - Topic"
+
+// FindTrack searches the music videos for the artist and title of the track and returns the
+// id of the best match.
+func (youtube *YouTube) FindTrack(ctx context.Context, track *itunes.Track) (string, error) {
+	if track.Name == "" {
+		return "", nil
+	}
+	artist := track.Artist
+	if artist == "" {
+		artist = track.AlbumArtist
+	}
+
+	var search struct {
+		Items []struct {
+			Id struct {
+				VideoId string `json:"videoId"`
+			} `json:"id"`
+			Snippet struct {
+				Title        string `json:"title"`
+				ChannelTitle string `json:"channelTitle"`
+			} `json:"snippet"`
+		} `json:"items"`
+	}
+	query := url.Values{
+		"part":            {"snippet"},
+		"type":            {"video"},
+		"videoCategoryId": {"10"},
+		"maxResults":      {"10"},
+		"q":               {strings.TrimSpace(artist + " " + baseTitle(track.Name))},
+	}
+	if err := youtube.request(ctx, http.MethodGet, "/search?"+query.Encode(), nil, &search); err != nil {
+		return "", err
+	}
+	if len(search.Items) == 0 {
+		return "", nil
+	}
+
+	var ids []string
+	for _, item := range search.Items {
+		ids = append(ids, item.Id.VideoId)
+	}
+	var videos struct {
+		Items []struct {
+			Id             string `json:"id"`
+			ContentDetails struct {
+				Duration string `json:"duration"`
+			} `json:"contentDetails"`
+		} `json:"items"`
+	}
+	query = url.Values{"part": {"contentDetails"}, "id": {strings.Join(ids, ",")}}
+	if err := youtube.request(ctx, http.MethodGet, "/videos?"+query.Encode(), nil, &videos); err != nil {
+		return "", err
+	}
+	durations := make(map[string]time.Duration)
+	for _, video := range videos.Items {
+		durations[video.Id] = parseISODuration(video.ContentDetails.Duration)
+	}
+
+	var topics, others []Candidate
+	for _, item := range search.Items {
+		candidate := Candidate{Id: item.Id.VideoId, Duration: durations[item.Id.VideoId]}
+		// Titles in search results are HTML escaped.
+		title := html.UnescapeString(item.Snippet.Title)
+		channel := html.UnescapeString(item.Snippet.ChannelTitle)
+		if strings.HasSuffix(channel, youTubeTopic) {
+			candidate.Artists = []string{strings.TrimSuffix(channel, youTubeTopic)}
+			candidate.Title = title
+			topics = append(topics, candidate)
+			continue
+		}
+		// Other music videos are usually titled "Artist - Title (Official Video)".
+		candidate.Artists = []string{channel}
+		candidate.Title = title
+		if parts := strings.SplitN(title, " - ", 2); len(parts) == 2 {
+			candidate.Artists = append(candidate.Artists, parts[0])
+			candidate.Title = parts[1]
+		}
+		others = append(others, candidate)
+	}
+	if id := BestMatch(track, topics); id != "" {
+		return id, nil
+	}
+	return BestMatch(track, others), nil
+}
+
+// loadPlaylists reads the playlists of the user once.
+func (youtube *YouTube) loadPlaylists(ctx context.Context) error {
+	if youtube.playlists != nil {
+		return nil
+	}
+	playlists := make(map[string]string)
+	pageToken := ""
+	for {
+		var page struct {
+			Items []struct {
+				Id      string `json:"id"`
+				Snippet struct {
+					Title string `json:"title"`
+				} `json:"snippet"`
+			} `json:"items"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		query := url.Values{"part": {"snippet"}, "mine": {"true"}, "maxResults": {"50"}, "pageToken": {pageToken}}
+		if err := youtube.request(ctx, http.MethodGet, "/playlists?"+query.Encode(), nil, &page); err != nil {
+			return err
+		}
+		for _, playlist := range page.Items {
+			playlists[playlist.Snippet.Title] = playlist.Id
+		}
+		if pageToken = page.NextPageToken; pageToken == "" {
+			break
+		}
+	}
+	youtube.playlists = playlists
+	return nil
+}
+
+// SetPlaylist replaces the videos of the user's playlist with the name, or creates it as a
+// private playlist. The API only adds and removes one video per request.
+func (youtube *YouTube) SetPlaylist(ctx context.Context, name string, trackIds []string) error {
+	if err := youtube.loadPlaylists(ctx); err != nil {
+		return err
+	}
+
+	id, ok := youtube.playlists[name]
+	if ok {
+		itemIds, err := youtube.playlistItems(ctx, id)
+		if err != nil {
+			return err
+		}
+		for _, itemId := range itemIds {
+			if err := youtube.request(ctx, http.MethodDelete, "/playlistItems?"+url.Values{"id": {itemId}}.Encode(), nil, nil); err != nil {
+				return err
+			}
+		}
+	} else {
+		var created struct {
+			Id string `json:"id"`
+		}
+		body := map[string]interface{}{
+			"snippet": map[string]string{"title": name, "description": "Exported from iTunes"},
+			"status":  map[string]string{"privacyStatus": "private"},
+		}
+		if err := youtube.request(ctx, http.MethodPost, "/playlists?part=snippet,status", body, &created); err != nil {
+			return err
+		}
+		id = created.Id
+		youtube.playlists[name] = id
+	}
+
+	for _, videoId := range trackIds {
+		body := map[string]interface{}{"snippet": map[string]interface{}{
+			"playlistId": id,
+			"resourceId": map[string]string{"kind": "youtube#video", "videoId": videoId},
+		}}
+		if err := youtube.request(ctx, http.MethodPost, "/playlistItems?part=snippet", body, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// playlistItems returns the ids of the items of the playlist.
+func (youtube *YouTube) playlistItems(ctx context.Context, playlistId string) ([]string, error) {
+	var ids []string
+	pageToken := ""
+	for {
+		var page struct {
+			Items []struct {
+				Id string `json:"id"`
+			} `json:"items"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		query := url.Values{"part": {"id"}, "playlistId": {playlistId}, "maxResults": {"50"}, "pageToken": {pageToken}}
+		if err := youtube.request(ctx, http.MethodGet, "/playlistItems?"+query.Encode(), nil, &page); err != nil {
+			return nil, err
+		}
+		for _, item := range page.Items {
+			ids = append(ids, item.Id)
+		}
+		if pageToken = page.NextPageToken; pageToken == "" {
+			return ids, nil
+		}
+	}
+}
+
+var isoDuration = regexp.MustCompile(`^PT(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?$`)
+
+// parseISODuration parses an ISO 8601 duration like PT4M13S, returning 0 if it is invalid.
+func parseISODuration(duration string) time.Duration {
+	match := isoDuration.FindStringSubmatch(duration)
+	if match == nil {
+		return 0
+	}
+	hours, _ := strconv.Atoi(match[1])
+	minutes, _ := strconv.Atoi(match[2])
+	seconds, _ := strconv.Atoi(match[3])
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second
+}
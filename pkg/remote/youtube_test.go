@@ -0,0 +1,129 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ericdaugherty/itunesexport-go/pkg/itunes"
+)
+
+// fakeYouTube serves the part of the YouTube Data API used by YouTube.
+type fakeYouTube struct {
+	playlists map[string][]string
+	created   []string
+}
+
+func (server *fakeYouTube) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	switch {
+	case r.URL.Path == "/search":
+		items := map[string]string{
+			"Artist Song":           `{"id":{"videoId":"v1"},"snippet":{"title":"Artist - Song (Official Video)","channelTitle":"ArtistVEVO"}},{"id":{"videoId":"v2"},"snippet":{"title":"Song","channelTitle":"Artist - Topic"}}`,
+			"Duo Other Song & More": `{"id":{"videoId":"v3"},"snippet":{"title":"Duo - Other Song &amp; More","channelTitle":"Some Uploader"}}`,
+		}[query.Get("q")]
+		fmt.Fprintf(w, `{"items":[%v]}`, items)
+	case r.URL.Path == "/videos":
+		io.WriteString(w, `{"items":[{"id":"v1","contentDetails":{"duration":"PT4M"}},{"id":"v2","contentDetails":{"duration":"PT3M1S"}},{"id":"v3","contentDetails":{"duration":"PT2M"}}]}`)
+	case r.URL.Path == "/playlists" && r.Method == http.MethodGet && query.Get("pageToken") == "":
+		io.WriteString(w, `{"items":[{"id":"p1","snippet":{"title":"Other"}}],"nextPageToken":"next"}`)
+	case r.URL.Path == "/playlists" && r.Method == http.MethodGet:
+		io.WriteString(w, `{"items":[{"id":"p2","snippet":{"title":"Existing"}}]}`)
+	case r.URL.Path == "/playlists" && r.Method == http.MethodPost:
+		var body struct {
+			Snippet struct {
+				Title string `json:"title"`
+			} `json:"snippet"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		server.created = append(server.created, body.Snippet.Title)
+		fmt.Fprintf(w, `{"id":"new-%v"}`, body.Snippet.Title)
+	case r.URL.Path == "/playlistItems" && r.Method == http.MethodGet:
+		var items []string
+		for _, id := range server.playlists[query.Get("playlistId")] {
+			items = append(items, fmt.Sprintf(`{"id":"item-%v"}`, id))
+		}
+		fmt.Fprintf(w, `{"items":[%v]}`, strings.Join(items, ","))
+	case r.URL.Path == "/playlistItems" && r.Method == http.MethodDelete:
+		for playlist, ids := range server.playlists {
+			for i, id := range ids {
+				if "item-"+id == query.Get("id") {
+					server.playlists[playlist] = append(ids[:i], ids[i+1:]...)
+				}
+			}
+		}
+	case r.URL.Path == "/playlistItems" && r.Method == http.MethodPost:
+		var body struct {
+			Snippet struct {
+				PlaylistId string `json:"playlistId"`
+				ResourceId struct {
+					VideoId string `json:"videoId"`
+				} `json:"resourceId"`
+			} `json:"snippet"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		server.playlists[body.Snippet.PlaylistId] = append(server.playlists[body.Snippet.PlaylistId], body.Snippet.ResourceId.VideoId)
+		io.WriteString(w, `{}`)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestPushPlaylistsToYouTube(t *testing.T) {
+	server := &fakeYouTube{playlists: map[string][]string{"p2": {"v9"}}}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	library := &itunes.Library{Tracks: map[string]itunes.Track{
+		"1": {TrackId: 1, Name: "Song", Artist: "Artist", TotalTime: 180000},
+		"2": {TrackId: 2, Name: "Other Song & More", Artist: "Duo", TotalTime: 121000},
+		"3": {TrackId: 3, Name: "Missing", Artist: "Nobody"},
+	}}
+	playlists := []itunes.Playlist{
+		{Name: "Existing", PlaylistItems: []itunes.PlaylistItem{{TrackId: 1}, {TrackId: 3}}},
+		{Name: "New", PlaylistItems: []itunes.PlaylistItem{{TrackId: 2}}},
+	}
+
+	youtube := NewYouTube("token")
+	youtube.api = httpServer.URL
+	results, err := PushPlaylists(context.Background(), youtube, library, playlists)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 2 || results[0].Matched != 1 || len(results[0].Unmatched) != 1 || results[1].Matched != 1 {
+		t.Fatalf("unexpected results %+v", results)
+	}
+	if !reflect.DeepEqual(server.created, []string{"New"}) {
+		t.Fatalf("expected only New to be created, got %v", server.created)
+	}
+	expected := map[string][]string{"p2": {"v2"}, "new-New": {"v3"}}
+	if !reflect.DeepEqual(server.playlists, expected) {
+		t.Fatalf("expected playlists %v, got %v", expected, server.playlists)
+	}
+}
+
+func TestParseISODuration(t *testing.T) {
+	for duration, expected := range map[string]time.Duration{
+		"PT4M13S": 4*time.Minute + 13*time.Second,
+		"PT1H2S":  time.Hour + 2*time.Second,
+		"PT45S":   45 * time.Second,
+		"P1D":     0,
+	} {
+		if parsed := parseISODuration(duration); parsed != expected {
+			t.Errorf("%v: expected %v, got %v", duration, expected, parsed)
+		}
+	}
+}
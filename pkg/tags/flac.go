@@ -0,0 +1,117 @@
+package tags
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// readFLAC reads the stream info and Vorbis comment blocks of a FLAC file.
+func readFLAC(r io.Reader, tags *Tags) error {
+	marker := make([]byte, 4)
+	if _, err := io.ReadFull(r, marker); err != nil {
+		return err
+	}
+
+	header := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return err
+		}
+		last, blockType := header[0]&0x80 != 0, header[0]&0x7f
+		length := int(header[1])<<16 | int(header[2])<<8 | int(header[3])
+		block := make([]byte, length)
+		if _, err := io.ReadFull(r, block); err != nil {
+			return err
+		}
+
+		switch blockType {
+		case 0:
+			if len(block) < 18 {
+				return errors.New("invalid FLAC stream info")
+			}
+			info := block[10:18]
+			sampleRate := int(info[0])<<12 | int(info[1])<<4 | int(info[2])>>4
+			samples := int64(info[3]&0x0f)<<32 | int64(binary.BigEndian.Uint32(info[4:8]))
+			tags.SampleRate = sampleRate
+			if sampleRate > 0 {
+				tags.Duration = time.Duration(samples * int64(time.Second) / int64(sampleRate))
+			}
+		case 4:
+			readVorbisComments(block, tags)
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// readVorbisComments reads a Vorbis comment block: the vendor string and a list of
+// KEY=value comments, all prefixed with their little endian length.
+func readVorbisComments(block []byte, tags *Tags) {
+	next := func() ([]byte, bool) {
+		if len(block) < 4 {
+			return nil, false
+		}
+		length := binary.LittleEndian.Uint32(block)
+		if uint64(length) > uint64(len(block)-4) {
+			return nil, false
+		}
+		value := block[4 : 4+length]
+		block = block[4+length:]
+		return value, true
+	}
+
+	if _, ok := next(); !ok || len(block) < 4 {
+		return
+	}
+	count := binary.LittleEndian.Uint32(block)
+	block = block[4:]
+	for i := uint32(0); i < count; i++ {
+		comment, ok := next()
+		if !ok {
+			return
+		}
+		parts := strings.SplitN(string(comment), "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		value := strings.TrimSpace(parts[1])
+		switch strings.ToUpper(parts[0]) {
+		case "TITLE":
+			tags.Title = value
+		case "ARTIST":
+			tags.Artist = value
+		case "ALBUMARTIST", "ALBUM ARTIST":
+			tags.AlbumArtist = value
+		case "ALBUM":
+			tags.Album = value
+		case "COMPOSER":
+			tags.Composer = value
+		case "GENRE":
+			tags.Genre = value
+		case "COMMENT", "DESCRIPTION":
+			tags.Comment = value
+		case "DATE", "YEAR":
+			tags.Year = parseYear(value)
+		case "TRACKNUMBER":
+			number, count := parseNumber(value)
+			tags.TrackNumber = number
+			if count > 0 {
+				tags.TrackCount = count
+			}
+		case "TRACKTOTAL", "TOTALTRACKS":
+			tags.TrackCount, _ = parseNumber(value)
+		case "DISCNUMBER":
+			number, count := parseNumber(value)
+			tags.DiscNumber = number
+			if count > 0 {
+				tags.DiscCount = count
+			}
+		case "DISCTOTAL", "TOTALDISCS":
+			tags.DiscCount, _ = parseNumber(value)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package tags
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func TestReadFLAC(t *testing.T) {
+	var comments bytes.Buffer
+	writeString := func(s string) {
+		binary.Write(&comments, binary.LittleEndian, uint32(len(s)))
+		comments.WriteString(s)
+	}
+	writeString("reference libFLAC")
+	values := []string{"TITLE=Hyperballad", "ARTIST=Björk", "ALBUM=Post", "DATE=1995-06-13", "TRACKNUMBER=4", "TRACKTOTAL=11", "DISCNUMBER=1/1"}
+	binary.Write(&comments, binary.LittleEndian, uint32(len(values)))
+	for _, value := range values {
+		writeString(value)
+	}
+
+	// 10 seconds at 44.1 kHz
+	info := make([]byte, 34)
+	copy(info[10:], []byte{0x0a, 0xc4, 0x42, 0xf0, 0x00, 0x06, 0xba, 0xa8})
+
+	file := []byte("fLaC")
+	file = append(file, 0, 0, 0, byte(len(info)))
+	file = append(file, info...)
+	file = append(file, 0x84, 0, byte(comments.Len()>>8), byte(comments.Len()))
+	file = append(file, comments.Bytes()...)
+	file = append(file, make([]byte, 100)...)
+
+	tags, err := ReadFrom(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Tags{Title: "Hyperballad", Artist: "Björk", Album: "Post", Year: 1995, TrackNumber: 4, TrackCount: 11,
+		DiscNumber: 1, DiscCount: 1, Duration: 10 * time.Second, BitRate: tags.BitRate, SampleRate: 44100}
+	if tags != expected {
+		t.Fatalf("expected %+v, got %+v", expected, tags)
+	}
+}
@@ -0,0 +1,375 @@
+package tags
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// readMP3 reads the ID3v2 and ID3v1 tags of a MP3 file and determines its duration from the
+// first MPEG frame.
+func readMP3(r io.ReadSeeker, size int64, tags *Tags) error {
+	audioStart := int64(0)
+	header := make([]byte, 10)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return err
+	}
+	if string(header[:3]) == "ID3" {
+		tagSize := int64(syncsafe(header[6:10]))
+		if 10+tagSize > size {
+			return errors.New("ID3v2 tag larger than the file")
+		}
+		tag := make([]byte, tagSize)
+		if _, err := io.ReadFull(r, tag); err != nil {
+			return err
+		}
+		readID3v2(header[3], header[5], tag, tags)
+		audioStart = 10 + tagSize
+		if header[5]&0x10 != 0 {
+			audioStart += 10
+		}
+	}
+
+	audioEnd := size
+	if size >= 128 {
+		if _, err := r.Seek(size-128, io.SeekStart); err != nil {
+			return err
+		}
+		tag := make([]byte, 128)
+		if _, err := io.ReadFull(r, tag); err != nil {
+			return err
+		}
+		if string(tag[:3]) == "TAG" {
+			readID3v1(tag, tags)
+			audioEnd -= 128
+		}
+	}
+
+	if _, err := r.Seek(audioStart, io.SeekStart); err != nil {
+		return err
+	}
+	start := make([]byte, 64*1024)
+	n, err := io.ReadFull(r, start)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return err
+	}
+	readMPEGDuration(start[:n], audioEnd-audioStart, tags)
+	return nil
+}
+
+// readID3v2 reads the frames of an ID3v2.2, 2.3 or 2.4 tag.
+func readID3v2(version, flags byte, tag []byte, tags *Tags) {
+	if flags&0x80 != 0 && version < 4 {
+		tag = bytes.Replace(tag, []byte{0xff, 0x00}, []byte{0xff}, -1)
+	}
+	if flags&0x40 != 0 && version >= 3 && len(tag) >= 4 {
+		extended := int(binary.BigEndian.Uint32(tag))
+		if version == 3 {
+			extended += 4
+		} else {
+			extended = syncsafe(tag[:4])
+		}
+		if extended > len(tag) {
+			return
+		}
+		tag = tag[extended:]
+	}
+
+	idLength, headerLength := 4, 10
+	if version == 2 {
+		idLength, headerLength = 3, 6
+	}
+	for len(tag) >= headerLength && tag[0] != 0 {
+		id := string(tag[:idLength])
+		var frameSize int
+		var frameFlags byte
+		switch version {
+		case 2:
+			frameSize = int(tag[3])<<16 | int(tag[4])<<8 | int(tag[5])
+		case 3:
+			frameSize = int(binary.BigEndian.Uint32(tag[4:8]))
+			frameFlags = tag[9]
+			if frameFlags&0xc0 != 0 {
+				// Compressed or encrypted
+				frameFlags = 0xff
+			}
+		default:
+			frameSize = syncsafe(tag[4:8])
+			frameFlags = tag[9]
+		}
+		if frameSize < 0 || headerLength+frameSize > len(tag) {
+			return
+		}
+		frame := tag[headerLength : headerLength+frameSize]
+		tag = tag[headerLength+frameSize:]
+
+		if version == 3 && frameFlags == 0xff || version == 4 && frameFlags&0x0c != 0 {
+			continue
+		}
+		if version == 4 && frameFlags&0x02 != 0 {
+			frame = bytes.Replace(frame, []byte{0xff, 0x00}, []byte{0xff}, -1)
+		}
+		if version == 4 && frameFlags&0x01 != 0 {
+			if len(frame) < 4 {
+				continue
+			}
+			frame = frame[4:]
+		}
+		readID3v2Frame(id, frame, tags)
+	}
+}
+
+func readID3v2Frame(id string, frame []byte, tags *Tags) {
+	switch id {
+	case "TIT2", "TT2":
+		tags.Title = id3Text(frame)
+	case "TPE1", "TP1":
+		tags.Artist = id3Text(frame)
+	case "TPE2", "TP2":
+		tags.AlbumArtist = id3Text(frame)
+	case "TALB", "TAL":
+		tags.Album = id3Text(frame)
+	case "TCOM", "TCM":
+		tags.Composer = id3Text(frame)
+	case "TCON", "TCO":
+		tags.Genre = id3Genre(id3Text(frame))
+	case "TRCK", "TRK":
+		tags.TrackNumber, tags.TrackCount = parseNumber(id3Text(frame))
+	case "TPOS", "TPA":
+		tags.DiscNumber, tags.DiscCount = parseNumber(id3Text(frame))
+	case "TYER", "TYE", "TDRC":
+		tags.Year = parseYear(id3Text(frame))
+	case "TLEN", "TLE":
+		if milliseconds, err := strconv.Atoi(id3Text(frame)); err == nil && milliseconds > 0 {
+			tags.Duration = time.Duration(milliseconds) * time.Millisecond
+		}
+	case "COMM", "COM":
+		// Encoding, language and a description before the text
+		if len(frame) < 4 {
+			return
+		}
+		encoding := frame[0]
+		_, text := splitID3Text(encoding, frame[4:])
+		tags.Comment = decodeID3Text(encoding, text)
+	}
+}
+
+// id3Text returns the first value of a text frame.
+func id3Text(frame []byte) string {
+	if len(frame) == 0 {
+		return ""
+	}
+	value, _ := splitID3Text(frame[0], frame[1:])
+	return decodeID3Text(frame[0], value)
+}
+
+// splitID3Text splits data at the first terminator of the encoding.
+func splitID3Text(encoding byte, data []byte) ([]byte, []byte) {
+	if encoding == 1 || encoding == 2 {
+		for i := 0; i+1 < len(data); i += 2 {
+			if data[i] == 0 && data[i+1] == 0 {
+				return data[:i], data[i+2:]
+			}
+		}
+		return data, nil
+	}
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return data[:i], data[i+1:]
+	}
+	return data, nil
+}
+
+// decodeID3Text decodes text in one of the ID3 encodings: Latin-1, UTF-16 with byte order
+// mark, UTF-16BE or UTF-8.
+func decodeID3Text(encoding byte, text []byte) string {
+	switch encoding {
+	case 1, 2:
+		bigEndian := encoding == 2
+		if len(text) >= 2 && text[0] == 0xff && text[1] == 0xfe {
+			bigEndian, text = false, text[2:]
+		} else if len(text) >= 2 && text[0] == 0xfe && text[1] == 0xff {
+			bigEndian, text = true, text[2:]
+		}
+		units := make([]uint16, len(text)/2)
+		for i := range units {
+			if bigEndian {
+				units[i] = binary.BigEndian.Uint16(text[2*i:])
+			} else {
+				units[i] = binary.LittleEndian.Uint16(text[2*i:])
+			}
+		}
+		return strings.TrimSpace(string(utf16.Decode(units)))
+	case 3:
+		return strings.TrimSpace(string(text))
+	default:
+		return latin1(text)
+	}
+}
+
+// id3Genre resolves references to the ID3v1 genres, e.g. "(17)" or "17", to their names.
+func id3Genre(genre string) string {
+	reference := genre
+	if strings.HasPrefix(reference, "(") {
+		end := strings.Index(reference, ")")
+		if end < 0 {
+			return genre
+		}
+		if rest := strings.TrimSpace(reference[end+1:]); rest != "" {
+			return rest
+		}
+		reference = reference[1:end]
+	}
+	if index, err := strconv.Atoi(reference); err == nil && index >= 0 && index < len(id3v1Genres) {
+		return id3v1Genres[index]
+	}
+	return genre
+}
+
+// readID3v1 reads the fields of an ID3v1 tag missing from the ID3v2 tag.
+func readID3v1(tag []byte, tags *Tags) {
+	setIfEmpty := func(field *string, value []byte) {
+		if *field == "" {
+			*field = latin1(bytes.TrimRight(value, "\x00"))
+		}
+	}
+	setIfEmpty(&tags.Title, tag[3:33])
+	setIfEmpty(&tags.Artist, tag[33:63])
+	setIfEmpty(&tags.Album, tag[63:93])
+	if tags.Year == 0 {
+		tags.Year = parseYear(latin1(tag[93:97]))
+	}
+	comment := tag[97:127]
+	if comment[28] == 0 && comment[29] != 0 {
+		if tags.TrackNumber == 0 {
+			tags.TrackNumber = int(comment[29])
+		}
+		comment = comment[:28]
+	}
+	setIfEmpty(&tags.Comment, comment)
+	if tags.Genre == "" && int(tag[127]) < len(id3v1Genres) {
+		tags.Genre = id3v1Genres[tag[127]]
+	}
+}
+
+func latin1(text []byte) string {
+	runes := make([]rune, 0, len(text))
+	for _, b := range text {
+		if b == 0 {
+			break
+		}
+		runes = append(runes, rune(b))
+	}
+	return strings.TrimSpace(string(runes))
+}
+
+func syncsafe(b []byte) int {
+	return int(b[0]&0x7f)<<21 | int(b[1]&0x7f)<<14 | int(b[2]&0x7f)<<7 | int(b[3]&0x7f)
+}
+
+var mpegBitRates = [...][16]int{
+	// MPEG 1 layer I, II and III
+	{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448},
+	{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},
+	{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+	// MPEG 2 and 2.5 layer I and layer II and III
+	{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+	{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+}
+
+var mpegSampleRates = [...][3]int{
+	{44100, 48000, 32000},
+	{22050, 24000, 16000},
+	{11025, 12000, 8000},
+}
+
+// readMPEGDuration determines the duration from the first MPEG audio frame in data, using
+// the frame count of a Xing or VBRI header if there is one and the bit rate otherwise.
+func readMPEGDuration(data []byte, audioSize int64, tags *Tags) {
+	for i := 0; i+4 <= len(data); i++ {
+		if data[i] != 0xff || data[i+1]&0xe0 != 0xe0 {
+			continue
+		}
+		version := (data[i+1] >> 3) & 0x03 // 0: MPEG 2.5, 2: MPEG 2, 3: MPEG 1
+		layer := (data[i+1] >> 1) & 0x03   // 1: III, 2: II, 3: I
+		bitRateIndex := data[i+2] >> 4
+		sampleRateIndex := (data[i+2] >> 2) & 0x03
+		if version == 1 || layer == 0 || bitRateIndex == 0 || bitRateIndex == 15 || sampleRateIndex == 3 {
+			continue
+		}
+
+		mpeg1 := version == 3
+		var bitRate, sampleRate, samples int
+		switch {
+		case mpeg1:
+			bitRate = mpegBitRates[3-layer][bitRateIndex]
+			sampleRate = mpegSampleRates[0][sampleRateIndex]
+		case layer == 3:
+			bitRate = mpegBitRates[3][bitRateIndex]
+		default:
+			bitRate = mpegBitRates[4][bitRateIndex]
+		}
+		switch version {
+		case 2:
+			sampleRate = mpegSampleRates[1][sampleRateIndex]
+		case 0:
+			sampleRate = mpegSampleRates[2][sampleRateIndex]
+		}
+		switch {
+		case layer == 3:
+			samples = 384
+		case layer == 1 && !mpeg1:
+			samples = 576
+		default:
+			samples = 1152
+		}
+		tags.SampleRate = sampleRate
+
+		mono := data[i+3]>>6 == 3
+		sideInfo := 32
+		switch {
+		case mpeg1 && mono, !mpeg1 && !mono:
+			sideInfo = 17
+		case !mpeg1 && mono:
+			sideInfo = 9
+		}
+		frame := data[i:]
+		frames := 0
+		if xing := 4 + sideInfo; len(frame) >= xing+12 && (string(frame[xing:xing+4]) == "Xing" || string(frame[xing:xing+4]) == "Info") {
+			if binary.BigEndian.Uint32(frame[xing+4:])&0x01 != 0 {
+				frames = int(binary.BigEndian.Uint32(frame[xing+8:]))
+			}
+		} else if len(frame) >= 36+18 && string(frame[36:40]) == "VBRI" {
+			frames = int(binary.BigEndian.Uint32(frame[36+14:]))
+		}
+
+		if tags.Duration > 0 {
+			return
+		}
+		if frames > 0 {
+			tags.Duration = time.Duration(int64(frames) * int64(samples) * int64(time.Second) / int64(sampleRate))
+		} else {
+			tags.BitRate = bitRate
+			tags.Duration = time.Duration((audioSize - int64(i)) * 8 * int64(time.Millisecond) / int64(bitRate))
+		}
+		return
+	}
+}
+
+// id3v1Genres are the genres of ID3v1 tags by their number.
+var id3v1Genres = []string{
+	"Blues", "Classic Rock", "Country", "Dance", "Disco", "Funk", "Grunge", "Hip-Hop", "Jazz",
+	"Metal", "New Age", "Oldies", "Other", "Pop", "R&B", "Rap", "Reggae", "Rock", "Techno",
+	"Industrial", "Alternative", "Ska", "Death Metal", "Pranks", "Soundtrack", "Euro-Techno",
+	"Ambient", "Trip-Hop", "Vocal", "Jazz+Funk", "Fusion", "Trance", "Classical", "Instrumental",
+	"Acid", "House", "Game", "Sound Clip", "Gospel", "Noise", "AlternRock", "Bass", "Soul", "Punk",
+	"Space", "Meditative", "Instrumental Pop", "Instrumental Rock", "Ethnic", "Gothic", "Darkwave",
+	"Techno-Industrial", "Electronic", "Pop-Folk", "Eurodance", "Dream", "Southern Rock", "Comedy",
+	"Cult", "Gangsta", "Top 40", "Christian Rap", "Pop/Funk", "Jungle", "Native American",
+	"Cabaret", "New Wave", "Psychadelic", "Rave", "Showtunes", "Trailer", "Lo-Fi", "Tribal",
+	"Acid Punk", "Acid Jazz", "Polka", "Retro", "Musical", "Rock & Roll", "Hard Rock",
+}
@@ -0,0 +1,85 @@
+package tags
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+	"time"
+)
+
+func id3Frame(id string, content []byte) []byte {
+	frame := append([]byte(id), 0, 0, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(frame[4:], uint32(len(content)))
+	return append(frame, content...)
+}
+
+func id3Tag(frames ...[]byte) []byte {
+	content := bytes.Join(frames, nil)
+	size := len(content)
+	header := []byte{'I', 'D', '3', 3, 0, 0, byte(size >> 21 & 0x7f), byte(size >> 14 & 0x7f), byte(size >> 7 & 0x7f), byte(size & 0x7f)}
+	return append(header, content...)
+}
+
+func TestReadMP3(t *testing.T) {
+	utf16 := []byte{1, 0xff, 0xfe, 'M', 0, 0xf6, 0, 't', 0, 0, 0}
+	file := id3Tag(
+		id3Frame("TIT2", []byte("\x00Ace of Spades")),
+		id3Frame("TPE1", utf16),
+		id3Frame("TRCK", []byte("\x003/12")),
+		id3Frame("TCON", []byte("\x00(17)")),
+		id3Frame("TYER", []byte("\x001980")),
+		id3Frame("COMM", []byte("\x00engRemark\x00Loud")),
+	)
+
+	// MPEG 1 layer III frame with 128 kbit/s at 44.1 kHz and a Xing header of 1000 frames
+	frame := make([]byte, 417)
+	copy(frame, []byte{0xff, 0xfb, 0x90, 0x00})
+	copy(frame[36:], []byte("Xing\x00\x00\x00\x01\x00\x00\x03\xe8"))
+	file = append(file, frame...)
+
+	v1 := make([]byte, 128)
+	copy(v1, "TAG")
+	copy(v1[63:], "Ace of Spades")
+	file = append(file, v1...)
+
+	tags, err := ReadFrom(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Tags{Title: "Ace of Spades", Artist: "Möt", Album: "Ace of Spades", Genre: "Rock", Comment: "Loud",
+		Year: 1980, TrackNumber: 3, TrackCount: 12, Duration: 26122448979, BitRate: tags.BitRate, SampleRate: 44100}
+	if tags != expected {
+		t.Fatalf("expected %+v, got %+v", expected, tags)
+	}
+}
+
+func TestReadMP3WithTruncatedTag(t *testing.T) {
+	file := id3Tag(id3Frame("TIT2", []byte("\x00Ace of Spades")))
+	// claims a tag of 256 MB
+	copy(file[6:10], []byte{0x7f, 0x7f, 0x7f, 0x7f})
+
+	if _, err := ReadFrom(bytes.NewReader(file)); err == nil || err == io.ErrUnexpectedEOF {
+		t.Fatalf("expected the tag to be rejected before reading it, got %v", err)
+	}
+}
+
+func TestReadMP3WithoutTags(t *testing.T) {
+	// 1 second of 128 kbit/s without a Xing header
+	file := make([]byte, 16000)
+	copy(file, []byte{0xff, 0xfb, 0x90, 0x00})
+
+	tags, err := ReadFrom(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tags.Duration != time.Second || tags.BitRate != 128 || tags.Title != "" {
+		t.Fatalf("unexpected tags %+v", tags)
+	}
+}
+
+func TestReadUnsupported(t *testing.T) {
+	if _, err := ReadFrom(bytes.NewReader([]byte("RIFF\x00\x00\x00\x00WAVE"))); err != ErrUnsupported {
+		t.Fatalf("expected ErrUnsupported, got %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+package tags
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+)
+
+// maxMovieSize limits the size of the moov atom read, which holds the metadata of a MP4 file.
+const maxMovieSize = 64 << 20
+
+// readMP4 reads the duration from the movie header and the tags from the iTunes metadata
+// list of a MP4 file.
+func readMP4(r io.ReadSeeker, size int64, tags *Tags) error {
+	offset := int64(0)
+	header := make([]byte, 16)
+	for offset+8 <= size {
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(r, header[:8]); err != nil {
+			return err
+		}
+		atomSize, headerSize := int64(binary.BigEndian.Uint32(header)), int64(8)
+		switch atomSize {
+		case 0:
+			atomSize = size - offset
+		case 1:
+			if _, err := io.ReadFull(r, header[8:16]); err != nil {
+				return err
+			}
+			atomSize, headerSize = int64(binary.BigEndian.Uint64(header[8:])), 16
+		}
+		if atomSize < headerSize {
+			return errors.New("invalid MP4 atom size")
+		}
+		if atomSize > size-offset {
+			return errors.New("MP4 atom larger than the file")
+		}
+
+		if string(header[4:8]) == "moov" {
+			if atomSize-headerSize > maxMovieSize {
+				return errors.New("MP4 movie atom too large")
+			}
+			movie := make([]byte, atomSize-headerSize)
+			if _, err := io.ReadFull(r, movie); err != nil {
+				return err
+			}
+			readMovie(movie, tags)
+			return nil
+		}
+		offset += atomSize
+	}
+	return nil
+}
+
+// atoms calls f with the type and content of every atom in data.
+func atoms(data []byte, f func(atomType string, content []byte)) {
+	for len(data) >= 8 {
+		atomSize, headerSize := uint64(binary.BigEndian.Uint32(data)), uint64(8)
+		if atomSize == 1 && len(data) >= 16 {
+			atomSize, headerSize = binary.BigEndian.Uint64(data[8:]), 16
+		} else if atomSize == 0 {
+			atomSize = uint64(len(data))
+		}
+		if atomSize < headerSize || atomSize > uint64(len(data)) {
+			return
+		}
+		f(string(data[4:8]), data[headerSize:atomSize])
+		data = data[atomSize:]
+	}
+}
+
+func readMovie(movie []byte, tags *Tags) {
+	atoms(movie, func(atomType string, content []byte) {
+		switch atomType {
+		case "mvhd":
+			readMovieHeader(content, tags)
+		case "udta":
+			atoms(content, func(atomType string, content []byte) {
+				// meta is a full atom with version and flags before its children
+				if atomType == "meta" && len(content) >= 4 {
+					atoms(content[4:], func(atomType string, content []byte) {
+						if atomType == "ilst" {
+							atoms(content, func(atomType string, content []byte) {
+								readMetadataItem(atomType, content, tags)
+							})
+						}
+					})
+				}
+			})
+		}
+	})
+}
+
+func readMovieHeader(header []byte, tags *Tags) {
+	var timeScale, duration uint64
+	switch {
+	case len(header) >= 32 && header[0] == 1:
+		timeScale = uint64(binary.BigEndian.Uint32(header[20:]))
+		duration = binary.BigEndian.Uint64(header[24:])
+	case len(header) >= 20:
+		timeScale = uint64(binary.BigEndian.Uint32(header[12:]))
+		duration = uint64(binary.BigEndian.Uint32(header[16:]))
+	}
+	if timeScale > 0 {
+		tags.Duration = time.Duration(duration * uint64(time.Second) / timeScale)
+	}
+}
+
+// readMetadataItem reads an item of the iTunes metadata list, whose value is the content of
+// its data atom after the type and locale.
+func readMetadataItem(item string, content []byte, tags *Tags) {
+	var value []byte
+	atoms(content, func(atomType string, content []byte) {
+		if atomType == "data" && len(content) >= 8 && value == nil {
+			value = content[8:]
+		}
+	})
+	if value == nil {
+		return
+	}
+
+	text := string(value)
+	switch item {
+	case "\xa9nam":
+		tags.Title = text
+	case "\xa9ART":
+		tags.Artist = text
+	case "aART":
+		tags.AlbumArtist = text
+	case "\xa9alb":
+		tags.Album = text
+	case "\xa9wrt":
+		tags.Composer = text
+	case "\xa9gen":
+		tags.Genre = text
+	case "\xa9cmt":
+		tags.Comment = text
+	case "\xa9day":
+		tags.Year = parseYear(text)
+	case "gnre":
+		if len(value) >= 2 {
+			if index := int(binary.BigEndian.Uint16(value)) - 1; index >= 0 && index < len(id3v1Genres) {
+				tags.Genre = id3v1Genres[index]
+			}
+		}
+	case "trkn", "disk":
+		if len(value) < 6 {
+			return
+		}
+		number, count := int(binary.BigEndian.Uint16(value[2:])), int(binary.BigEndian.Uint16(value[4:]))
+		if item == "trkn" {
+			tags.TrackNumber, tags.TrackCount = number, count
+		} else {
+			tags.DiscNumber, tags.DiscCount = number, count
+		}
+	}
+}
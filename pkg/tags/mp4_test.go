@@ -0,0 +1,59 @@
+package tags
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+	"time"
+)
+
+func atom(atomType string, content ...[]byte) []byte {
+	data := bytes.Join(content, nil)
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header, uint32(8+len(data)))
+	copy(header[4:], atomType)
+	return append(header, data...)
+}
+
+func metadataItem(item string, value []byte) []byte {
+	return atom(item, atom("data", []byte{0, 0, 0, 1, 0, 0, 0, 0}, value))
+}
+
+func TestReadMP4(t *testing.T) {
+	header := make([]byte, 20)
+	binary.BigEndian.PutUint32(header[12:], 1000)
+	binary.BigEndian.PutUint32(header[16:], 5500)
+
+	file := append(atom("ftyp", []byte("M4A \x00\x00\x00\x00")), atom("mdat", make([]byte, 100))...)
+	file = append(file, atom("moov",
+		atom("mvhd", header),
+		atom("udta", atom("meta", []byte{0, 0, 0, 0}, atom("ilst",
+			metadataItem("\xa9nam", []byte("Teardrop")),
+			metadataItem("\xa9ART", []byte("Massive Attack")),
+			metadataItem("\xa9day", []byte("1998-04-20T07:00:00Z")),
+			metadataItem("gnre", []byte{0, 8}),
+			metadataItem("trkn", []byte{0, 0, 0, 2, 0, 11, 0, 0}),
+		))),
+	)...)
+
+	tags, err := ReadFrom(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Tags{Title: "Teardrop", Artist: "Massive Attack", Genre: "Hip-Hop", Year: 1998, TrackNumber: 2, TrackCount: 11,
+		Duration: 5500 * time.Millisecond, BitRate: tags.BitRate}
+	if tags != expected {
+		t.Fatalf("expected %+v, got %+v", expected, tags)
+	}
+}
+
+func TestReadMP4WithTruncatedAtom(t *testing.T) {
+	moov := atom("moov", atom("mvhd", make([]byte, 20)))
+	binary.BigEndian.PutUint32(moov, 1<<20)
+	file := append(atom("ftyp", []byte("M4A \x00\x00\x00\x00")), moov...)
+
+	if _, err := ReadFrom(bytes.NewReader(file)); err == nil || err == io.ErrUnexpectedEOF {
+		t.Fatalf("expected the atom to be rejected before reading it, got %v", err)
+	}
+}
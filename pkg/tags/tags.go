@@ -0,0 +1,109 @@
+// Package tags reads the tags of audio files: ID3 tags of MP3 files, Vorbis comments of FLAC
+// files and the metadata atoms of MP4 (M4A) files.
+package tags
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrUnsupported is returned for files that are not in one of the supported formats.
+var ErrUnsupported = errors.New("unsupported audio file format")
+
+// Tags are the tags of an audio file. Fields not found in the file are left empty.
+type Tags struct {
+	Title       string
+	Artist      string
+	AlbumArtist string
+	Album       string
+	Composer    string
+	Genre       string
+	Comment     string
+	Year        int
+	TrackNumber int
+	TrackCount  int
+	DiscNumber  int
+	DiscCount   int
+	// Duration is the playing time of the file, if it could be determined.
+	Duration time.Duration
+	// BitRate is the average bit rate in kbit/s.
+	BitRate    int
+	SampleRate int
+}
+
+// Read returns the tags of the named audio file.
+func Read(fileName string) (Tags, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return Tags{}, err
+	}
+	defer file.Close()
+	return ReadFrom(file)
+}
+
+// ReadFrom returns the tags of the audio file read from r. The format is detected from the
+// content of the file.
+func ReadFrom(r io.ReadSeeker) (Tags, error) {
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return Tags{}, err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return Tags{}, err
+	}
+
+	header := make([]byte, 12)
+	n, err := io.ReadFull(r, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return Tags{}, err
+	}
+	header = header[:n]
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return Tags{}, err
+	}
+
+	var tags Tags
+	switch {
+	case bytes.HasPrefix(header, []byte("fLaC")):
+		err = readFLAC(r, &tags)
+	case len(header) >= 8 && string(header[4:8]) == "ftyp":
+		err = readMP4(r, size, &tags)
+	case bytes.HasPrefix(header, []byte("ID3")) || (len(header) >= 2 && header[0] == 0xff && header[1]&0xe0 == 0xe0):
+		err = readMP3(r, size, &tags)
+	default:
+		return Tags{}, ErrUnsupported
+	}
+	if err != nil {
+		return Tags{}, err
+	}
+	if milliseconds := tags.Duration.Milliseconds(); tags.BitRate == 0 && milliseconds > 0 {
+		tags.BitRate = int(size * 8 / milliseconds)
+	}
+	return tags, nil
+}
+
+// parseNumber parses a number of the form "3" or "3/12" and returns both numbers.
+func parseNumber(s string) (int, int) {
+	parts := strings.SplitN(strings.TrimSpace(s), "/", 2)
+	number, _ := strconv.Atoi(strings.TrimSpace(parts[0]))
+	count := 0
+	if len(parts) == 2 {
+		count, _ = strconv.Atoi(strings.TrimSpace(parts[1]))
+	}
+	return number, count
+}
+
+// parseYear returns the year at the start of a date, e.g. 2001 for "2001-05-14".
+func parseYear(s string) int {
+	s = strings.TrimSpace(s)
+	if len(s) > 4 {
+		s = s[:4]
+	}
+	year, _ := strconv.Atoi(s)
+	return year
+}